@@ -23,6 +23,25 @@ type Context struct {
 	parentCtx *Context
 	children  *safemap.SafeMap[string, *PID]
 	context   context.Context
+	// watching holds the processes that are watched by this process.
+	watching map[uint64]*PID
+	// trace is the trace context of the message that is handled, nil if
+	// it's not traced.
+	trace TraceContext
+	// parentTrace is the trace context the message that is handled was sent
+	// with.
+	parentTrace TraceContext
+	// untraced is the span of the message that is handled without being
+	// sampled, which is traced if the actor crashes handling it, see
+	// Sampler.WithErrors. Its PID is nil if there's none.
+	untraced Span
+	// kind and tags are the attributes of the logger, which is created
+	// once it's needed.
+	kind   string
+	tags   map[string]string
+	logger *slog.Logger
+	// latency is how long the message that is handled waited in the inbox.
+	latency time.Duration
 }
 
 func newContext(ctx context.Context, e *Engine, pid *PID) *Context {
@@ -48,16 +67,21 @@ func (c *Context) Receiver() Receiver {
 // See Engine.Request for information. This is just a helper function doing that
 // calls Request on the underlying Engine. c.Engine().Request().
 func (c *Context) Request(pid *PID, msg any, timeout time.Duration) *Response {
-	return c.engine.Request(pid, msg, timeout)
+	return c.engine.Request(pid, c.traced(msg), timeout)
 }
 
 // Respond will sent the given message to the sender of the current received message.
+// If the message is an error, Response.Result of the requester returns it as
+// its error. Errors for remote requesters are sent as a *ResponseError.
 func (c *Context) Respond(msg any) {
 	if c.sender == nil {
-		slog.Warn("context got no sender", "func", "Respond", "pid", c.PID())
+		c.Logger().Warn("context got no sender", "func", "Respond")
 		return
 	}
-	c.engine.Send(c.sender, msg)
+	if err, ok := msg.(error); ok && !c.engine.isLocalMessage(c.sender) {
+		msg = NewResponseError(err)
+	}
+	c.engine.Send(c.sender, c.traced(msg))
 }
 
 // SpawnChild will spawn the given Producer as a child of the current Context.
@@ -94,13 +118,13 @@ func (c *Context) SpawnChildFunc(f func(*Context), name string, opts ...OptFunc)
 // of the message can call Context.Sender() to know
 // the PID of the process that sent this message.
 func (c *Context) Send(pid *PID, msg any) {
-	c.engine.SendWithSender(pid, msg, c.pid)
+	c.engine.SendWithSender(pid, c.traced(msg), c.pid)
 }
 
 // SendPriority sends the given message with high priority to the given PID.
 // The message will be placed at the front of the recipient's mailbox.
 func (c *Context) SendPriority(pid *PID, msg any) {
-	c.engine.SendPriorityWithSender(pid, msg, c.pid)
+	c.engine.SendPriorityWithSender(pid, c.traced(msg), c.pid)
 }
 
 // SendRepeat will send the given message to the given PID each given interval.
@@ -118,10 +142,15 @@ func (c *Context) SendRepeat(pid *PID, msg any, interval time.Duration) SendRepe
 	return sr
 }
 
+// OpenStream opens a stream to the actor with the given pid, see StreamWriter.
+func (c *Context) OpenStream(pid *PID, opts ...StreamOptFunc) *StreamWriter {
+	return c.engine.OpenStream(pid, opts...)
+}
+
 // Forward will forward the current received message to the given PID.
 // This will also set the "forwarder" as the sender of the message.
 func (c *Context) Forward(pid *PID) {
-	c.engine.SendWithSender(pid, c.message, c.pid)
+	c.engine.SendWithSender(pid, c.traced(c.message), c.pid)
 }
 
 // GetPID returns the PID of the process found by the given id.
@@ -160,6 +189,44 @@ func (c *Context) Children() []*PID {
 	return pids
 }
 
+// Watch will notify the current process with a *Terminated message once the
+// process of the given PID terminates. This works for processes on other
+// engines as well, in which case the reason of the Terminated message tells
+// whether the process stopped or its engine became unreachable.
+func (c *Context) Watch(pid *PID) {
+	if c.watching == nil {
+		c.watching = make(map[uint64]*PID)
+	}
+	c.watching[pid.LookupKey()] = pid
+	if !c.engine.isLocalMessage(pid) {
+		c.engine.remoteWatches.add(pid, c.pid)
+	}
+	c.engine.SendWithSender(pid, &Watch{Watcher: c.pid}, c.pid)
+}
+
+// Unwatch stops watching the process of the given PID.
+func (c *Context) Unwatch(pid *PID) {
+	if _, ok := c.watching[pid.LookupKey()]; !ok {
+		return
+	}
+	c.unwatched(pid)
+	c.engine.SendWithSender(pid, &Unwatch{Watcher: c.pid}, c.pid)
+}
+
+// unwatched removes the given PID from the processes we are watching and
+// reports whether we were watching it.
+func (c *Context) unwatched(pid *PID) bool {
+	key := pid.LookupKey()
+	if _, ok := c.watching[key]; !ok {
+		return false
+	}
+	delete(c.watching, key)
+	if !c.engine.isLocalMessage(pid) {
+		c.engine.remoteWatches.remove(pid, c.pid)
+	}
+	return true
+}
+
 // PID returns the PID of the process that belongs to the context.
 func (c *Context) PID() *PID {
 	return c.pid
@@ -171,6 +238,12 @@ func (c *Context) Sender() *PID {
 	return c.sender
 }
 
+// TraceParent returns the trace context the message that is handled was sent
+// with, see TracedMessage, nil if it was sent outside of a trace.
+func (c *Context) TraceParent() TraceContext {
+	return c.parentTrace
+}
+
 // Engine returns a pointer to the underlying Engine.
 func (c *Context) Engine() *Engine {
 	return c.engine
@@ -181,6 +254,13 @@ func (c *Context) Message() any {
 	return c.message
 }
 
+// MailboxLatency returns how long the message that is handled waited in the
+// inbox of the actor before the actor got it, which is zero for the messages
+// of the lifecycle of the actor, like Started.
+func (c *Context) MailboxLatency() time.Duration {
+	return c.latency
+}
+
 // ClearMailbox clears all pending messages in this actor's mailbox.
 // Messages already dequeued for the current processing batch will still be processed.
 func (c *Context) ClearMailbox() {
@@ -170,3 +170,25 @@ func TestSpawnChild(t *testing.T) {
 	assert.Nil(t, e.Registry.get(NewPID("local", "child")))
 	assert.Nil(t, e.Registry.get(pid))
 }
+
+func TestMailboxLatency(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	latencies := make(chan time.Duration, 3)
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			latencies <- c.MailboxLatency()
+		case time.Duration:
+			latencies <- c.MailboxLatency()
+			time.Sleep(msg)
+		}
+	}, "latency")
+	e.Send(pid, 50*time.Millisecond)
+	e.Send(pid, time.Duration(0))
+
+	assert.Zero(t, <-latencies)
+	assert.Less(t, <-latencies, 50*time.Millisecond)
+	// The second message waited for the first one.
+	assert.GreaterOrEqual(t, <-latencies, 40*time.Millisecond)
+}
@@ -2,10 +2,12 @@ package actor
 
 import (
 	"bytes"
+	"log/slog"
 	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestDeadLetterCustom tests the custom deadletter handling.
@@ -27,6 +29,29 @@ func TestDeadLetterCustom(t *testing.T) {
 	wg.Wait()
 }
 
+func TestDeadLetterLogSampling(t *testing.T) {
+	logs := &logBuffer{}
+	e, err := NewEngine(NewEngineConfig().WithLogHandler(slog.NewJSONHandler(logs, nil)).WithDeadLetterLogSampling(3))
+	require.NoError(t, err)
+	sender := NewPID(e.Address(), "sender")
+	for i := range 7 {
+		e.SendLocal(invalidPid(), i, sender)
+	}
+	// The stream handled the deadletters once it answered.
+	e.SubscriberStats()
+
+	records := logs.records(t, "Deadletter")
+	require.Len(t, records, 3)
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, "local/squirrel", records[0]["target"])
+	assert.Equal(t, "local/sender", records[0]["sender"])
+	assert.Equal(t, "int", records[0]["type"])
+	assert.Equal(t, "0", records[0]["message"])
+	assert.Equal(t, 1.0, records[0]["deadletters"])
+	assert.Equal(t, "3", records[1]["message"])
+	assert.Equal(t, 7.0, records[2]["deadletters"])
+}
+
 // SafeBuffer is a threadsafe buffer, used for testing the that the deadletters are logged.
 type SafeBuffer struct {
 	buf bytes.Buffer
@@ -2,9 +2,12 @@ package actor
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math"
 	"math/rand"
+	"runtime"
 	"strconv"
 	"sync"
 	"time"
@@ -33,11 +36,44 @@ type Engine struct {
 	address     string
 	remote      Remoter
 	eventStream *PID
+	kinds       kinds
+	// remoteWatches holds the local actors that watch actors on other engines.
+	remoteWatches remoteWatches
+	// deadEvents is nil if the dead events are logged, see
+	// EngineConfig.WithDeadEventHandler.
+	deadEvents func(DeadEvent)
+	// timers is nil if the engine has no TimerStore.
+	timers *durableTimers
+	tracer Tracer
+	// traceSampler is nil if all the messages are traced.
+	traceSampler *Sampler
+	// logger is nil if the engine logs with the default logger of slog.
+	logger *slog.Logger
+	health healthChecks
+	stats  engineStats
+	// slowHandlers is the default threshold of the slow handlers, see
+	// EngineConfig.WithSlowHandlerThreshold.
+	slowHandlers time.Duration
+	// flows is nil if the flows are not sampled, see
+	// EngineConfig.WithFlowSampling.
+	flows *flowGraph
 }
 
 // EngineConfig holds the configuration of the engine.
 type EngineConfig struct {
-	remote Remoter
+	remote         Remoter
+	eventRetention int
+	journal        Journal
+	journalTopics  []string
+	deadEvents     func(DeadEvent)
+	slowThreshold  int
+	timerStore     TimerStore
+	tracer         Tracer
+	traceSampler   *Sampler
+	logHandler     slog.Handler
+	deadLetterLogs int
+	flowSampling   int
+	slowHandlers   time.Duration
 }
 
 // NewEngineConfig returns a new default EngineConfig.
@@ -52,20 +88,161 @@ func (config EngineConfig) WithRemote(remote Remoter) EngineConfig {
 	return config
 }
 
+// WithEventRetention sets the number of the last events the event stream
+// keeps for each topic, and for each type of the events without a topic. The
+// kept events that match a new subscription are replayed to it in the order
+// they were broadcasted, so an actor that subscribes late still gets the
+// events of the startup. Zero turns off the retention, which is the default.
+func (config EngineConfig) WithEventRetention(n int) EngineConfig {
+	config.eventRetention = n
+	return config
+}
+
+// WithEventJournal sets the journal the event stream persists the events on
+// the topics that match one of the given patterns to, see WithTopics, and all
+// the events if there are none. A subscription with SubscribeFrom gets the
+// events of the journal before the live ones. The engine does not close the
+// journal.
+func (config EngineConfig) WithEventJournal(journal Journal, topics ...string) EngineConfig {
+	config.journal = journal
+	config.journalTopics = topics
+	return config
+}
+
+// WithDeadEventHandler sets the function the event stream invokes with the
+// events that no subscriber received, with where they were published from, so
+// a subscriber that never subscribed is noticed. The function is invoked by
+// the event stream, so it needs to be fast and must not block.
+//
+// Defaults to logging the dead events at the debug level.
+func (config EngineConfig) WithDeadEventHandler(fn func(DeadEvent)) EngineConfig {
+	config.deadEvents = fn
+	return config
+}
+
+// WithSlowSubscriberThreshold sets the number of events that wait for a
+// subscriber of the event stream, in its queue or its inbox, from which it's
+// reported with a SlowSubscriberEvent. The subscriber is reported again once
+// it got below half of the threshold in between.
+//
+// Defaults to 0, which does not report the slow subscribers.
+func (config EngineConfig) WithSlowSubscriberThreshold(depth int) EngineConfig {
+	config.slowThreshold = depth
+	return config
+}
+
+// WithTimerStore sets the store the timers of ScheduleDurable are persisted
+// in. The engine arms the timers of the store when it's created, so the ones
+// that were pending when the process stopped still fire.
+func (config EngineConfig) WithTimerStore(store TimerStore) EngineConfig {
+	config.timerStore = store
+	return config
+}
+
+// WithTracer sets the tracer that traces the messages the actors handle. The
+// messages the actors send while they handle a traced message carry its trace
+// context, see TracedMessage, so a chain of actors is traced as a single
+// trace, across the engines of a cluster too.
+func (config EngineConfig) WithTracer(tracer Tracer) EngineConfig {
+	config.tracer = tracer
+	return config
+}
+
+// WithTraceSampler sets the sampler of the messages the tracer traces, see
+// WithTracer. The messages that were sent with a trace context are traced
+// regardless, so the traces that were sampled stay whole.
+//
+// Defaults to tracing all the messages.
+func (config EngineConfig) WithTraceSampler(sampler Sampler) EngineConfig {
+	config.traceSampler = &sampler
+	return config
+}
+
+// WithLogHandler sets the handler of the logs of the engine, of its event
+// stream and of the loggers of the actors, see Context.Logger. The remote,
+// cluster and persistence packages keep logging with the default logger of
+// slog.
+//
+// Defaults to the handler of the default logger of slog.
+func (config EngineConfig) WithLogHandler(h slog.Handler) EngineConfig {
+	config.logHandler = h
+	return config
+}
+
+// WithDeadLetterLogSampling sets how many deadletters the event stream logs
+// one of, with the target, the sender and the message, so a target that went
+// missing is noticed without flooding the logs: 100 logs the first deadletter
+// and every 100th one after it, at the warn level. Count them all with the
+// metrics package.
+//
+// Defaults to 0, which does not log the deadletters.
+func (config EngineConfig) WithDeadLetterLogSampling(n int) EngineConfig {
+	config.deadLetterLogs = n
+	return config
+}
+
+// WithFlowSampling sets how many messages with a sender the local actors
+// receive one of is recorded in the graph of the flows between the actors,
+// see Engine.Flows: 1 records all of them, 100 every 100th one of each actor,
+// which keeps the overhead low on a busy engine.
+//
+// Defaults to 0, which does not record the flows.
+func (config EngineConfig) WithFlowSampling(n int) EngineConfig {
+	config.flowSampling = n
+	return config
+}
+
+// WithSlowHandlerThreshold sets how long the actors may take to handle a
+// message before they are reported with a SlowHandlerEvent, with the stack of
+// their goroutine, unless an actor has a threshold of its own, see
+// WithSlowHandlerThreshold. Each message that is handled arms a timer, and
+// each report captures the goroutine profile, so keep the threshold well
+// above the usual handling times.
+//
+// Defaults to 0, which does not report the slow handlers.
+func (config EngineConfig) WithSlowHandlerThreshold(d time.Duration) EngineConfig {
+	config.slowHandlers = d
+	return config
+}
+
 // NewEngine returns a new actor Engine given an EngineConfig.
 func NewEngine(config EngineConfig) (*Engine, error) {
-	e := &Engine{}
+	e := &Engine{
+		deadEvents:   config.deadEvents,
+		tracer:       config.tracer,
+		traceSampler: config.traceSampler,
+		slowHandlers: config.slowHandlers,
+	}
+	e.stats.last = time.Now()
+	if config.flowSampling > 0 {
+		e.flows = newFlowGraph(config.flowSampling)
+	}
+	if config.logHandler != nil {
+		e.logger = slog.New(config.logHandler)
+	}
 	e.Registry = newRegistry(e) // need to init the registry in case we want a custom deadletter
 	e.address = LocalLookupAddr
 	if config.remote != nil {
 		e.remote = config.remote
 		e.address = config.remote.Address()
+	}
+	// The event stream needs to be running before the remote is started,
+	// as the remote may broadcast events from its own goroutines.
+	e.eventStream = e.Spawn(newEventStream(config), "eventstream")
+	if config.remote != nil {
+		e.Spawn(newSpawner(e), systemKind, WithID(spawnerID))
+		e.Spawn(newWatcher(e), systemKind, WithID(watcherID))
 		err := config.remote.Start(e)
 		if err != nil {
 			return nil, fmt.Errorf("failed to start remote: %w", err)
 		}
 	}
-	e.eventStream = e.Spawn(newEventStream(), "eventstream")
+	if config.timerStore != nil {
+		e.timers = newDurableTimers(e, config.timerStore)
+		if err := e.timers.start(); err != nil {
+			return nil, fmt.Errorf("failed to load timers: %w", err)
+		}
+	}
 	return e, nil
 }
 
@@ -98,6 +275,43 @@ func (e *Engine) SpawnProc(p Processer) *PID {
 	return p.PID()
 }
 
+// MailboxBacklog returns the number of messages that wait in the inboxes of
+// the local processes, which tells how far behind the actors are.
+func (e *Engine) MailboxBacklog() int {
+	return e.Registry.backlog()
+}
+
+// ProcessInfo holds information about a local process.
+type ProcessInfo struct {
+	PID *PID
+	// Kind is the kind the process was spawned with, see Engine.Spawn. The
+	// kind of a child starts with the ID of its parent.
+	Kind string
+	// The number of messages that wait in the inbox of the process.
+	MailboxLen int
+	// The number of messages the process handled.
+	Messages uint64
+	// When the process received its last message, or when it was spawned if
+	// it received none yet.
+	LastActive time.Time
+}
+
+// ProcessInfo returns information about the local process with the given PID,
+// and false if there is no such process.
+func (e *Engine) ProcessInfo(pid *PID) (ProcessInfo, bool) {
+	p, ok := e.Registry.get(pid).(*process)
+	if !ok {
+		return ProcessInfo{}, false
+	}
+	return p.info(), true
+}
+
+// Processes returns information about all the local processes, in no
+// particular order.
+func (e *Engine) Processes() []ProcessInfo {
+	return e.Registry.processes()
+}
+
 // Address returns the address of the actor engine. When there is
 // no remote configured, the "local" address will be used, otherwise
 // the listen address of the remote.
@@ -134,9 +348,44 @@ func (e *Engine) Send(pid *PID, msg any) {
 // BroadcastEvent will broadcast the given message over the eventstream, notifying all
 // actors that are subscribed.
 func (e *Engine) BroadcastEvent(msg any) {
-	if e.eventStream != nil {
-		e.send(e.eventStream, msg, nil)
+	if _, ok := msg.(DeadLetterEvent); ok {
+		e.stats.deadLetters.Add(1)
+	}
+	var topic string
+	if t, ok := msg.(EventTopic); ok {
+		topic = t.Topic()
+	}
+	e.broadcast(topicEvent{topic: topic, event: msg})
+}
+
+// Publish broadcasts the given event on the given topic of the event stream,
+// whether or not the event has a topic of its own. The subscribers receive the
+// event itself.
+//
+//	e.Publish("billing.invoice.paid", InvoicePaid{ID: "1"})
+func (e *Engine) Publish(topic string, event any) {
+	e.broadcast(topicEvent{topic: topic, event: event})
+}
+
+// PublishForwarded publishes the given event on the given topic like Publish,
+// for an event that was published on another engine and forwarded to this one,
+// like by a cluster. The subscriptions with WithLocalOnly don't receive it.
+func (e *Engine) PublishForwarded(topic string, event any) {
+	e.broadcast(topicEvent{topic: topic, event: event, forwarded: true})
+}
+
+// broadcast sends the event to the event stream, with the site of the caller
+// of the exported function if it's reported when the event is dead.
+func (e *Engine) broadcast(msg topicEvent) {
+	if e.eventStream == nil {
+		return
+	}
+	if e.deadEvents != nil || e.Logger().Enabled(context.Background(), slog.LevelDebug) {
+		if _, file, line, ok := runtime.Caller(2); ok {
+			msg.site = file + ":" + strconv.Itoa(line)
+		}
 	}
+	e.send(e.eventStream, msg, nil)
 }
 
 func (e *Engine) send(pid *PID, msg any, sender *PID) {
@@ -152,6 +401,7 @@ func (e *Engine) send(pid *PID, msg any, sender *PID) {
 		return
 	}
 	if e.remote == nil {
+		msg, _ := untrace(msg)
 		e.BroadcastEvent(EngineRemoteMissingEvent{Target: pid, Sender: sender, Message: msg})
 		return
 	}
@@ -178,6 +428,7 @@ func (e *Engine) sendPriority(pid *PID, msg any, sender *PID) {
 		return
 	}
 	if e.remote == nil {
+		msg, _ := untrace(msg)
 		e.BroadcastEvent(EngineRemoteMissingEvent{Target: pid, Sender: sender, Message: msg})
 		return
 	}
@@ -186,16 +437,14 @@ func (e *Engine) sendPriority(pid *PID, msg any, sender *PID) {
 
 // SendPriorityLocal sends a priority message to a local process.
 func (e *Engine) SendPriorityLocal(pid *PID, msg any, sender *PID) {
-	proc := e.Registry.get(pid)
-	if proc == nil {
+	if !e.TrySendLocal(pid, msg, sender, true) {
+		msg, _ := untrace(msg)
 		e.BroadcastEvent(DeadLetterEvent{
 			Target:  pid,
 			Message: msg,
 			Sender:  sender,
 		})
-		return
 	}
-	proc.SendPriority(pid, msg, sender)
 }
 
 // SendRepeater is a struct that can be used to send a repeating message to a given PID.
@@ -260,6 +509,18 @@ func (e *Engine) Poison(pid *PID) context.Context {
 	return e.sendPoisonPill(context.Background(), true, pid)
 }
 
+// ErrRestartRequested is the reason of the ActorRestartedEvent of an actor
+// that was restarted with Engine.Restart.
+var ErrRestartRequested = errors.New("restart requested")
+
+// Restart restarts the local process with the given PID as if it crashed, once
+// it handled the messages that are in its inbox before: the receiver gets
+// Stopped, and a new receiver gets Started and the messages that were sent
+// later. It counts as a restart of the process.
+func (e *Engine) Restart(pid *PID) {
+	e.SendLocal(pid, restartActor{}, nil)
+}
+
 // PoisonCtx behaves the exact same as Poison, the only difference is that it accepts
 // a context as the first argument. The context can be used for custom timeouts and manual
 // cancelation.
@@ -292,17 +553,36 @@ func (e *Engine) sendPoisonPill(ctx context.Context, graceful bool, pid *PID) co
 // registry, the message will be sent to the DeadLetter process instead. If there is no deadletter
 // process registered, the function will panic.
 func (e *Engine) SendLocal(pid *PID, msg any, sender *PID) {
-	proc := e.Registry.get(pid)
-	if proc == nil {
+	if !e.TrySendLocal(pid, msg, sender, false) {
+		msg, _ := untrace(msg)
 		// broadcast a deadLetter message
 		e.BroadcastEvent(DeadLetterEvent{
 			Target:  pid,
 			Message: msg,
 			Sender:  sender,
 		})
-		return
 	}
-	proc.Send(pid, msg, sender)
+}
+
+// TrySendLocal sends the given message to the given local PID, as a priority
+// message if priority is set. Unlike SendLocal it doesn't publish a
+// DeadLetterEvent when the recipient is not found, instead it returns false.
+func (e *Engine) TrySendLocal(pid *PID, msg any, sender *PID, priority bool) bool {
+	proc := e.Registry.get(pid)
+	if proc == nil {
+		// let the watcher know right away that there is nothing to watch.
+		if w, ok := msg.(*Watch); ok {
+			e.Send(w.Watcher, &Terminated{Pid: pid, Reason: TerminatedReason_NotFound})
+			return true
+		}
+		return false
+	}
+	if priority {
+		proc.SendPriority(pid, msg, sender)
+	} else {
+		proc.Send(pid, msg, sender)
+	}
+	return true
 }
 
 // ClearMailbox clears all pending messages in the mailbox of the given PID.
@@ -315,8 +595,24 @@ func (e *Engine) ClearMailbox(pid *PID) {
 }
 
 // Subscribe will subscribe the given PID to the event stream.
-func (e *Engine) Subscribe(pid *PID) {
-	e.Send(e.eventStream, eventSub{pid: pid})
+//
+//	e.Subscribe(pid, actor.WithFilter(func(event any) bool {
+//		_, ok := event.(actor.ActorRestartedEvent)
+//		return ok
+//	}))
+func (e *Engine) Subscribe(pid *PID, opts ...SubscribeOptFunc) {
+	e.Send(e.eventStream, eventSub{pid: pid, opts: newSubscribeOpts(opts)})
+}
+
+// SubscriberStats returns the delivery statistics of the subscribers of the
+// event stream, which tell the subscriber that falls behind.
+func (e *Engine) SubscriberStats() []SubscriberStats {
+	resp, err := e.Request(e.eventStream, subscriberStats{}, statsTimeout).Result()
+	if err != nil {
+		return nil
+	}
+	stats, _ := resp.([]SubscriberStats)
+	return stats
 }
 
 // Unsubscribe will un subscribe the given PID from the event stream.
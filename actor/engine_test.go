@@ -65,6 +65,84 @@ func TestRegistryGetPID(t *testing.T) {
 	assert.True(t, pid.Equals(expectedPID2))
 }
 
+func TestMailboxBacklog(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	block := make(chan struct{})
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(int); ok {
+			<-block
+		}
+	}, "backlog")
+	defer close(block)
+	for i := range 5 {
+		e.Send(pid, i)
+	}
+	// The first message is being processed, the others wait.
+	assert.Eventually(t, func() bool {
+		return e.MailboxBacklog() >= 4
+	}, time.Second, time.Millisecond)
+}
+
+func TestProcessInfo(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan struct{})
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(int); ok {
+			received <- struct{}{}
+		}
+	}, "info")
+	info, ok := e.ProcessInfo(pid)
+	require.True(t, ok)
+	spawned := info.LastActive
+
+	time.Sleep(5 * time.Millisecond)
+	e.Send(pid, 1)
+	<-received
+	info, ok = e.ProcessInfo(pid)
+	require.True(t, ok)
+	assert.True(t, info.LastActive.After(spawned))
+	assert.Equal(t, 0, info.MailboxLen)
+	assert.Equal(t, uint64(1), info.Messages)
+	assert.Equal(t, pid, info.PID)
+	assert.Equal(t, "info", info.Kind)
+	var kinds []string
+	for _, info := range e.Processes() {
+		kinds = append(kinds, info.Kind)
+	}
+	assert.Contains(t, kinds, "info")
+
+	<-e.Poison(pid).Done()
+	_, ok = e.ProcessInfo(pid)
+	assert.False(t, ok)
+}
+
+func TestRestart(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	restarted := make(chan ActorRestartedEvent, 1)
+	sub := SubscribeTyped(e, func(event ActorRestartedEvent) { restarted <- event })
+	defer sub.Unsubscribe()
+	var starts atomic.Int32
+	pid := e.SpawnFunc(func(c *Context) {
+		switch c.Message().(type) {
+		case Started:
+			starts.Add(1)
+		case string:
+			c.Respond(starts.Load())
+		}
+	}, "restarted", WithRestartDelay(time.Millisecond))
+
+	e.Restart(pid)
+	event := <-restarted
+	assert.Equal(t, pid, event.PID)
+	assert.Equal(t, ErrRestartRequested, event.Reason)
+	res, err := e.Request(pid, "starts", time.Second).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), res)
+}
+
 func TestSendToNilPID(t *testing.T) {
 	e, _ := NewEngine(NewEngineConfig())
 	e.Send(nil, "foo")
@@ -480,3 +558,40 @@ func TestMultipleStops(t *testing.T) {
 		<-done
 	}
 }
+
+type codedError struct{}
+
+func (codedError) Error() string { return "not found" }
+func (codedError) Code() int32   { return 404 }
+
+func TestRequestResponseError(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case string:
+			if msg == "panic" {
+				panic("oops")
+			}
+			c.Respond(codedError{})
+		}
+	}, "failing", WithMaxRestarts(1))
+
+	_, err = e.Request(pid, "error", time.Second).Result()
+	assert.ErrorIs(t, err, codedError{})
+
+	_, err = e.Request(pid, "panic", time.Second).Result()
+	var rerr *ResponseError
+	require.ErrorAs(t, err, &rerr)
+	assert.True(t, rerr.Panicked())
+	assert.Equal(t, "oops", rerr.Message)
+}
+
+func TestNewResponseError(t *testing.T) {
+	rerr := NewResponseError(fmt.Errorf("wrapped: %w", codedError{}))
+	assert.Equal(t, "*fmt.wrapError", rerr.Type)
+	assert.Equal(t, "wrapped: not found", rerr.Message)
+	assert.Equal(t, int32(404), rerr.Code)
+	assert.False(t, rerr.Panicked())
+	assert.Same(t, rerr, NewResponseError(rerr))
+}
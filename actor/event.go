@@ -1,6 +1,7 @@
 package actor
 
 import (
+	"fmt"
 	"log/slog"
 	"time"
 )
@@ -13,6 +14,13 @@ type EventLogger interface {
 	Log() (slog.Level, string, []any)
 }
 
+// EventTopic is an interface that the events implement to be published on a
+// topic of the event stream when they are broadcasted, see WithTopics. The
+// topics are dotted, from the general to the specific category.
+type EventTopic interface {
+	Topic() string
+}
+
 // ActorStartedEvent is broadcasted over the eventStream each time
 // a Receiver (Actor) is spawned and activated. This means, that at
 // the point of receiving this event the Receiver (Actor) is ready
@@ -55,18 +63,28 @@ type ActorRestartedEvent struct {
 	Stacktrace []byte
 	Reason     any
 	Restarts   int32
+	// LastMessages are the last messages the actor handled before it
+	// crashed, oldest first, if it has a flight recorder, see
+	// WithFlightRecorder.
+	LastMessages []MessageRecord
 }
 
 func (e ActorRestartedEvent) Log() (slog.Level, string, []any) {
-	return slog.LevelError, "Actor crashed and restarted",
-		[]any{"pid", e.PID.GetID(), "stack", string(e.Stacktrace),
-			"reason", e.Reason, "restarts", e.Restarts}
+	attrs := []any{"pid", e.PID.GetID(), "stack", string(e.Stacktrace),
+		"reason", e.Reason, "restarts", e.Restarts}
+	if n := len(e.LastMessages); n > 0 {
+		attrs = append(attrs, "last_message", e.LastMessages[n-1].Type)
+	}
+	return slog.LevelError, "Actor crashed and restarted", attrs
 }
 
 // ActorMaxRestartsExceededEvent gets created if an actor crashes too many times
 type ActorMaxRestartsExceededEvent struct {
 	PID       *PID
 	Timestamp time.Time
+	// LastMessages are the last messages the actor handled, see
+	// ActorRestartedEvent.
+	LastMessages []MessageRecord
 }
 
 func (e ActorMaxRestartsExceededEvent) Log() (slog.Level, string, []any) {
@@ -96,15 +114,69 @@ func (e EngineRemoteMissingEvent) Log() (slog.Level, string, []any) {
 
 // RemoteUnreachableEvent gets published when trying to send a message to
 // an remote that is not reachable. The event will be published after we
-// retry to dial it N times.
+// retry to dial it N times, or when the remote misses its heartbeats.
 type RemoteUnreachableEvent struct {
 	// The listen address of the remote we are trying to dial.
 	ListenAddr string
 }
 
+// RemoteRestoredEvent gets published when a remote that missed its heartbeats
+// responds again.
+type RemoteRestoredEvent struct {
+	// The listen address of the remote.
+	ListenAddr string
+}
+
+// SlowSubscriberEvent is broadcasted when the events that wait for a
+// subscriber of the event stream reach the threshold of the engine, see
+// EngineConfig.WithSlowSubscriberThreshold.
+type SlowSubscriberEvent struct {
+	PID        *PID
+	QueueDepth int
+	Dropped    uint64
+	Latency    time.Duration
+}
+
+func (e SlowSubscriberEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelWarn, "Slow eventstream subscriber", []any{"pid", e.PID, "depth", e.QueueDepth, "dropped", e.Dropped, "latency", e.Latency}
+}
+
+// SlowHandlerEvent is broadcasted when an actor handles a message for longer
+// than its threshold, while it still handles it, see WithSlowHandlerThreshold.
+type SlowHandlerEvent struct {
+	PID     *PID
+	Sender  *PID
+	Message any
+	// Timestamp is when the actor started handling the message, and Elapsed
+	// how long it had handled it when the stack was captured.
+	Timestamp time.Time
+	Elapsed   time.Duration
+	// Stacktrace is the stack of the goroutine of the actor once the
+	// threshold elapsed, nil if it could not be captured.
+	Stacktrace []byte
+}
+
+func (e SlowHandlerEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelWarn, "Slow message handler", []any{"pid", e.PID.GetID(), "type", fmt.Sprintf("%T", e.Message),
+		"elapsed", e.Elapsed, "stack", string(e.Stacktrace)}
+}
+
 // DeadLetterEvent is delivered to the deadletter actor when a message can't be delivered to it's recipient
 type DeadLetterEvent struct {
 	Target  *PID
 	Message any
 	Sender  *PID
 }
+
+func (ActorStartedEvent) Topic() string             { return "actor.lifecycle.started" }
+func (ActorInitializedEvent) Topic() string         { return "actor.lifecycle.initialized" }
+func (ActorStoppedEvent) Topic() string             { return "actor.lifecycle.stopped" }
+func (ActorRestartedEvent) Topic() string           { return "actor.lifecycle.restarted" }
+func (ActorMaxRestartsExceededEvent) Topic() string { return "actor.lifecycle.max_restarts_exceeded" }
+func (ActorDuplicateIdEvent) Topic() string         { return "actor.spawn.duplicate_id" }
+func (EngineRemoteMissingEvent) Topic() string      { return "actor.remote.missing" }
+func (RemoteUnreachableEvent) Topic() string        { return "remote.conn.unreachable" }
+func (RemoteRestoredEvent) Topic() string           { return "remote.conn.restored" }
+func (DeadLetterEvent) Topic() string               { return "actor.deadletter" }
+func (SlowHandlerEvent) Topic() string              { return "actor.handler.slow" }
+func (SlowSubscriberEvent) Topic() string           { return "actor.eventstream.slow_subscriber" }
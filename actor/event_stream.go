@@ -1,13 +1,24 @@
 package actor
 
 import (
+	"cmp"
 	"context"
-	"log/slog"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // eventSub is the message that will be send to subscribe to the event stream.
 type eventSub struct {
-	pid *PID
+	pid   *PID
+	opts  SubscribeOpts
+	queue *eventQueue
 }
 
 // EventUnSub is the message that will be send to unsubscribe from the event stream.
@@ -15,14 +26,462 @@ type eventUnsub struct {
 	pid *PID
 }
 
+// actorCrashed is sent to the event stream by an actor that panicked, before
+// it stops to restart.
+type actorCrashed struct {
+	pid *PID
+}
+
+// SubscribeOpts are the options of a subscription to the event stream.
+type SubscribeOpts struct {
+	// Filter is nil if the subscriber receives all the events.
+	Filter func(event any) bool
+	// Topics holds the patterns of the topics the subscriber receives the
+	// events of, all the events if there are none.
+	Topics []string
+	// Buffer is the size of the queue of a function subscription, which is
+	// unbounded if it's zero, see WithBuffer.
+	Buffer int
+	// Overflow is what happens to the events that don't fit in the queue.
+	Overflow OverflowPolicy
+	// From is the offset of the journal the subscriber gets the events from
+	// before the live ones, nil if it only gets the live ones.
+	From *uint64
+	// LocalOnly leaves out the events that were forwarded from another
+	// engine.
+	LocalOnly bool
+	// Envelope delivers the events in an EventEnvelope.
+	Envelope bool
+	// Group is the name of the group the subscriber shares the events with,
+	// empty if it receives all of them, see WithGroup.
+	Group string
+	// Resubscribe establishes the subscription of an actor again after it
+	// restarted, see WithResubscribeOnRestart.
+	Resubscribe bool
+}
+
+// EventEnvelope is an event with the topic it was published on, which the
+// subscriptions with WithEnvelope receive instead of the event itself.
+//
+// The events a publisher publishes on a topic are delivered to each subscriber
+// in the order they were published, whether the subscriber is an actor or a
+// function with a queue of its own. Seq numbers the events of a topic for
+// each subscriber, from 1, so a gap tells that the queue of the subscriber
+// dropped an event, see WithBuffer. The events the filters leave out are not
+// numbered.
+type EventEnvelope struct {
+	// Topic is empty if the event has no topic.
+	Topic string
+	Event any
+	// Forwarded is true if the event was published on another engine, see
+	// Engine.PublishForwarded.
+	Forwarded bool
+	// Seq is the number of the event among the events of its topic that were
+	// delivered to the subscriber.
+	Seq uint64
+}
+
+// OverflowPolicy decides what happens to an event that doesn't fit in the
+// queue of a subscription, see WithBuffer.
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest drops the oldest event in the queue to make room for
+	// the new one.
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowDropNewest drops the new event.
+	OverflowDropNewest
+	// OverflowBlock waits until the subscriber made room for the new event.
+	// The event stream is blocked meanwhile, which holds back the events of
+	// all the subscribers, but no event is lost.
+	OverflowBlock
+)
+
+// topicEvent is an event that is published on a topic, see Engine.Publish.
+type topicEvent struct {
+	topic string
+	event any
+	// forwarded is true if the event was published on another engine, see
+	// Engine.PublishForwarded.
+	forwarded bool
+	// site is where the event was published from, if it's known.
+	site string
+}
+
+// DeadEvent is an event that no subscriber of the event stream received, see
+// EngineConfig.WithDeadEventHandler.
+type DeadEvent struct {
+	// Topic is empty if the event has no topic.
+	Topic string
+	Event any
+	// Site is the file and line the event was published from.
+	Site string
+}
+
+type SubscribeOptFunc func(*SubscribeOpts)
+
+func newSubscribeOpts(opts []SubscribeOptFunc) SubscribeOpts {
+	var o SubscribeOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithFilter only delivers the events the given function returns true for.
+// The filter is invoked by the event stream before it delivers an event, so
+// the subscriber is not even woken up for the other events, but it needs to
+// be fast and must not block. Filters add up.
+func WithFilter(fn func(event any) bool) SubscribeOptFunc {
+	return func(opts *SubscribeOpts) {
+		if filter := opts.Filter; filter != nil {
+			opts.Filter = func(event any) bool {
+				return filter(event) && fn(event)
+			}
+			return
+		}
+		opts.Filter = fn
+	}
+}
+
+// WithTopics only delivers the events that are published on a topic that
+// matches one of the given patterns, see Engine.Publish and EventTopic. The
+// topics are dotted, and a "*" in a pattern matches a single part of a topic,
+// or all the remaining parts if it's the last one of the pattern: "remote.*"
+// matches "remote.conn.lost" and "actor.*.restarted" matches
+// "actor.lifecycle.restarted". The events without a topic never match.
+func WithTopics(patterns ...string) SubscribeOptFunc {
+	return func(opts *SubscribeOpts) {
+		opts.Topics = append(opts.Topics, patterns...)
+	}
+}
+
+// WithBuffer bounds the queue of a function subscription, see SubscribeTyped,
+// to the given number of events. The policy decides what happens once the
+// function falls behind and the queue is full. The events that are dropped
+// are lost for the subscription only.
+func WithBuffer(size int, policy OverflowPolicy) SubscribeOptFunc {
+	return func(opts *SubscribeOpts) {
+		opts.Buffer = size
+		opts.Overflow = policy
+	}
+}
+
+// WithLocalOnly only delivers the events that were published on this engine,
+// not the ones another engine forwarded with Engine.PublishForwarded. A
+// subscriber that forwards the events to the other engines uses it so the
+// events don't go back and forth.
+func WithLocalOnly() SubscribeOptFunc {
+	return func(opts *SubscribeOpts) {
+		opts.LocalOnly = true
+	}
+}
+
+// WithEnvelope delivers the events in an EventEnvelope, for the subscribers
+// that need the topic an event was published on. The filters still get the
+// events themselves.
+func WithEnvelope() SubscribeOptFunc {
+	return func(opts *SubscribeOpts) {
+		opts.Envelope = true
+	}
+}
+
+// WithGroup adds the subscriber to the group with the given name. Each event
+// is delivered to a single subscriber of the group, in turns, instead of to
+// all of them, so a pool of actors can share the work. An event goes to the
+// next subscriber of the group it matches, and the subscribers of a group can
+// have different filters. The retained events are not replayed to the
+// subscribers of a group.
+//
+//	for _, pid := range workers {
+//		e.Subscribe(pid, actor.WithGroup("indexers"), actor.WithTopics("orders.*"))
+//	}
+func WithGroup(name string) SubscribeOptFunc {
+	return func(opts *SubscribeOpts) {
+		opts.Group = name
+	}
+}
+
+// WithResubscribeOnRestart establishes the subscription of an actor again
+// after the actor restarted, if the actor unsubscribed while it stopped for
+// the restart, like actors do when they handle Stopped. The subscription of an
+// actor always ends once the actor stops for good.
+func WithResubscribeOnRestart() SubscribeOptFunc {
+	return func(opts *SubscribeOpts) {
+		opts.Resubscribe = true
+	}
+}
+
+// SubscribeFrom delivers the events of the journal of the event stream from
+// the given offset on before the live events, instead of the retained ones, see
+// EngineConfig.WithEventJournal. The journal is read by the event stream, so
+// the subscriber gets every event exactly once, but no events are delivered to
+// the other subscribers meanwhile.
+func SubscribeFrom(offset uint64) SubscribeOptFunc {
+	return func(opts *SubscribeOpts) {
+		opts.From = &offset
+	}
+}
+
+// matchTopic returns true whether the topic matches the pattern, see
+// WithTopics.
+func matchTopic(pattern, topic string) bool {
+	for {
+		p, prest, pmore := strings.Cut(pattern, ".")
+		t, trest, tmore := strings.Cut(topic, ".")
+		if p == "*" && !pmore {
+			return t != ""
+		}
+		if p != "*" && p != t {
+			return false
+		}
+		if !pmore || !tmore {
+			return pmore == tmore
+		}
+		pattern, topic = prest, trest
+	}
+}
+
+// subscription is the subscription of a subscriber to the event stream.
+type subscription struct {
+	opts SubscribeOpts
+	// queue is nil if the subscriber is an actor, which gets the events in its
+	// inbox.
+	queue     *eventQueue
+	delivered uint64
+	// seqs holds the number of the last event of each topic, for the
+	// envelopes.
+	seqs map[string]uint64
+	// slow is true once the subscriber was reported as slow, until it caught
+	// up.
+	slow bool
+}
+
+// SubscriberStats holds the delivery statistics of a subscriber of the event
+// stream, see Engine.SubscriberStats.
+type SubscriberStats struct {
+	// PID identifies the subscriber, see Subscription.PID for the function
+	// subscriptions.
+	PID *PID
+	// Delivered is the number of events that were delivered to the
+	// subscriber, or to its queue.
+	Delivered uint64
+	// Dropped is the number of events the queue of a function subscription
+	// dropped, see WithBuffer.
+	Dropped uint64
+	// QueueDepth is the number of the events that wait in the queue of a
+	// function subscription, or of the messages that wait in the inbox of a
+	// local actor.
+	QueueDepth int
+	// Latency is how long the last event a function subscription got waited
+	// in its queue, zero for the actors.
+	Latency time.Duration
+}
+
+// subscriberStats is the request for the statistics of the subscribers.
+type subscriberStats struct{}
+
+// statsTimeout is how long Engine.SubscriberStats waits for the event stream.
+const statsTimeout = 5 * time.Second
+
+// matches returns true whether the event is delivered to the subscriber.
+func (s *subscription) matches(msg topicEvent) bool {
+	if msg.forwarded && s.opts.LocalOnly {
+		return false
+	}
+	if len(s.opts.Topics) > 0 {
+		if msg.topic == "" || !slices.ContainsFunc(s.opts.Topics, func(pattern string) bool {
+			return matchTopic(pattern, msg.topic)
+		}) {
+			return false
+		}
+	}
+	return s.opts.Filter == nil || s.opts.Filter(msg.event)
+}
+
+// eventQueue holds the events of a function subscription until its goroutine
+// delivers them.
+type eventQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	events  []queuedEvent
+	size    int
+	policy  OverflowPolicy
+	dropped uint64
+	closed  bool
+	// latency is how long the last event that was delivered waited.
+	latency time.Duration
+}
+
+type queuedEvent struct {
+	event any
+	at    time.Time
+}
+
+func newEventQueue(size int, policy OverflowPolicy) *eventQueue {
+	q := &eventQueue{size: size, policy: policy}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds the event to the queue, applying the overflow policy if it's
+// full.
+func (q *eventQueue) push(event any) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.size > 0 && len(q.events) >= q.size && !q.closed {
+		switch q.policy {
+		case OverflowDropNewest:
+			q.dropped++
+			return
+		case OverflowBlock:
+			q.cond.Wait()
+		default:
+			q.events[0] = queuedEvent{}
+			q.events = q.events[1:]
+			q.dropped++
+		}
+	}
+	if q.closed {
+		return
+	}
+	q.events = append(q.events, queuedEvent{event: event, at: time.Now()})
+	q.cond.Broadcast()
+}
+
+// pop waits for the next event of the queue, and returns false once the
+// queue is closed.
+func (q *eventQueue) pop() (any, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.events) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+	event := q.events[0]
+	q.events[0] = queuedEvent{}
+	q.events = q.events[1:]
+	q.latency = time.Since(event.at)
+	q.cond.Broadcast()
+	return event.event, true
+}
+
+// popBatch waits for the next event of the queue, and the ones that follow it
+// within the given latency, up to the given number of events. It returns false
+// once the queue is closed.
+func (q *eventQueue) popBatch(maxN int, maxLatency time.Duration) ([]any, bool) {
+	first, ok := q.pop()
+	if !ok {
+		return nil, false
+	}
+	batch := []any{first}
+	deadline := time.Now().Add(maxLatency)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(batch) < maxN {
+		if q.closed {
+			return nil, false
+		}
+		if len(q.events) > 0 {
+			n := min(len(q.events), maxN-len(batch))
+			for _, queued := range q.events[:n] {
+				batch = append(batch, queued.event)
+			}
+			q.latency = time.Since(q.events[n-1].at)
+			clear(q.events[:n])
+			q.events = q.events[n:]
+			q.cond.Broadcast()
+			continue
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			break
+		}
+		timer := time.AfterFunc(wait, func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.cond.Broadcast()
+		})
+		q.cond.Wait()
+		timer.Stop()
+	}
+	return batch, true
+}
+
+// stats returns the number of the queued events, of the dropped events, and
+// how long the last delivered event waited.
+func (q *eventQueue) stats() (int, uint64, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.events), q.dropped, q.latency
+}
+
+// close drops the events that are still queued and stops the delivery.
+func (q *eventQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.events = nil
+	q.cond.Broadcast()
+}
+
+// retentionKey is what the event stream keeps the last events for, the topic
+// of the events, or their type if they have none.
+type retentionKey struct {
+	topic string
+	typ   reflect.Type
+}
+
+// retainedEvent is an event that is kept for the subscriptions to come, see
+// EngineConfig.WithEventRetention.
+type retainedEvent struct {
+	topicEvent
+	seq uint64
+}
+
+// subscriptionGroup holds the subscribers of a group in the order they
+// joined, see WithGroup.
+type subscriptionGroup struct {
+	pids []*PID
+	// next is the index of the subscriber whose turn it is.
+	next int
+}
+
 type eventStream struct {
-	subs map[*PID]bool
+	subs          map[*PID]*subscription
+	groups        map[string]*subscriptionGroup
+	slowThreshold int
+	// restarting holds the subscriptions of the actors that crashed, until
+	// they restarted, see WithResubscribeOnRestart.
+	restarting    map[*PID]*subscription
+	retention     int
+	retained      map[retentionKey][]retainedEvent
+	seq           uint64
+	journal       Journal
+	journalTopics []string
+	deadEvents    func(DeadEvent)
+	// deadLetterLogs is the sampling of the logged deadletters, see
+	// WithDeadLetterLogSampling, and deadLetters the number of deadletters
+	// so far.
+	deadLetterLogs int
+	deadLetters    uint64
 }
 
-func newEventStream() Producer {
+func newEventStream(config EngineConfig) Producer {
 	return func() Receiver {
 		return &eventStream{
-			subs: make(map[*PID]bool),
+			subs:           make(map[*PID]*subscription),
+			groups:         make(map[string]*subscriptionGroup),
+			restarting:     make(map[*PID]*subscription),
+			retention:      config.eventRetention,
+			retained:       make(map[retentionKey][]retainedEvent),
+			journal:        config.journal,
+			journalTopics:  config.journalTopics,
+			deadEvents:     config.deadEvents,
+			slowThreshold:  config.slowThreshold,
+			deadLetterLogs: config.deadLetterLogs,
 		}
 	}
 }
@@ -32,19 +491,384 @@ func newEventStream() Producer {
 // DeadletterSub, DeadletterUnSub, for subscribing to DeadLetterEvent
 func (e *eventStream) Receive(c *Context) {
 	switch msg := c.Message().(type) {
+	case Initialized, Started:
+		// The lifecycle of the event stream itself is not an event.
 	case eventSub:
-		e.subs[msg.pid] = true
+		sub := &subscription{opts: msg.opts, queue: msg.queue}
+		e.subscribe(msg.pid, sub)
+		if msg.opts.From != nil {
+			e.replayJournal(c, msg.pid, sub, *msg.opts.From)
+		} else if msg.opts.Group == "" {
+			e.replay(c, msg.pid, sub)
+		}
 	case eventUnsub:
-		delete(e.subs, msg.pid)
+		e.unsubscribe(msg.pid)
+	case subscriberStats:
+		c.Respond(e.stats(c.engine))
+	case actorCrashed:
+		if sub, ok := e.subs[msg.pid]; ok && sub.opts.Resubscribe {
+			e.restarting[msg.pid] = sub
+		}
+	case Stopped:
+		for _, sub := range e.subs {
+			if sub.queue != nil {
+				sub.queue.close()
+			}
+		}
+	case topicEvent:
+		e.publish(c, msg)
 	default:
-		// check if we should log the event, if so, log it with the relevant level, message and attributes
-		logMsg, ok := c.Message().(EventLogger)
-		if ok {
-			level, msg, attr := logMsg.Log()
-			slog.Log(context.Background(), level, msg, attr...)
+		var topic string
+		if t, ok := msg.(EventTopic); ok {
+			topic = t.Topic()
+		}
+		e.publish(c, topicEvent{topic: topic, event: msg})
+	}
+}
+
+func (e *eventStream) subscribe(pid *PID, sub *subscription) {
+	e.leaveGroup(pid)
+	e.subs[pid] = sub
+	if group := sub.opts.Group; group != "" {
+		if e.groups[group] == nil {
+			e.groups[group] = &subscriptionGroup{}
+		}
+		e.groups[group].pids = append(e.groups[group].pids, pid)
+	}
+}
+
+func (e *eventStream) unsubscribe(pid *PID) {
+	if sub, ok := e.subs[pid]; ok && sub.queue != nil {
+		sub.queue.close()
+	}
+	e.leaveGroup(pid)
+	delete(e.subs, pid)
+}
+
+// trackLifecycle ends the subscriptions of the actors that stopped, and
+// establishes the ones of the actors that restarted again.
+func (e *eventStream) trackLifecycle(event any) {
+	switch event := event.(type) {
+	case ActorStoppedEvent:
+		delete(e.restarting, event.PID)
+		e.unsubscribe(event.PID)
+	case ActorInitializedEvent:
+		sub, ok := e.restarting[event.PID]
+		if !ok {
+			return
+		}
+		delete(e.restarting, event.PID)
+		if _, ok := e.subs[event.PID]; !ok {
+			e.subscribe(event.PID, sub)
+		}
+	}
+}
+
+// logDeadLetter logs the given deadletter if it's the first of the sample.
+func (e *eventStream) logDeadLetter(c *Context, event DeadLetterEvent) {
+	e.deadLetters++
+	if (e.deadLetters-1)%uint64(e.deadLetterLogs) != 0 {
+		return
+	}
+	var sender string
+	if event.Sender != nil {
+		sender = event.Sender.String()
+	}
+	c.engine.Logger().Warn("Deadletter", "target", event.Target.String(), "sender", sender,
+		"type", fmt.Sprintf("%T", event.Message), "message", fmt.Sprintf("%+v", event.Message),
+		"deadletters", e.deadLetters, "sampling", e.deadLetterLogs)
+}
+
+func (e *eventStream) publish(c *Context, msg topicEvent) {
+	e.trackLifecycle(msg.event)
+	// check if we should log the event, if so, log it with the relevant level, message and attributes
+	logMsg, ok := msg.event.(EventLogger)
+	if ok {
+		level, msg, attr := logMsg.Log()
+		c.engine.Logger().Log(context.Background(), level, msg, attr...)
+	}
+	if event, ok := msg.event.(DeadLetterEvent); ok && e.deadLetterLogs > 0 {
+		e.logDeadLetter(c, event)
+	}
+	e.retain(msg)
+	e.record(c, msg)
+	delivered := false
+	for pid, sub := range e.subs {
+		if sub.opts.Group == "" && sub.matches(msg) {
+			e.deliver(c, pid, sub, msg)
+			delivered = true
 		}
-		for sub := range e.subs {
-			c.Forward(sub)
+	}
+	for _, group := range e.groups {
+		for i := range len(group.pids) {
+			n := (group.next + i) % len(group.pids)
+			pid := group.pids[n]
+			if sub := e.subs[pid]; sub.matches(msg) {
+				e.deliver(c, pid, sub, msg)
+				group.next = n + 1
+				delivered = true
+				break
+			}
 		}
 	}
+	if !delivered {
+		e.deadEvent(c, DeadEvent{Topic: msg.topic, Event: msg.event, Site: msg.site})
+	}
+}
+
+func (e *eventStream) deadEvent(c *Context, event DeadEvent) {
+	if e.deadEvents != nil {
+		e.deadEvents(event)
+		return
+	}
+	c.engine.Logger().Debug("event has no subscriber", "type", reflect.TypeOf(event.Event), "topic", event.Topic, "site", event.Site)
+}
+
+// leaveGroup removes the subscriber from its group, if it's in one.
+func (e *eventStream) leaveGroup(pid *PID) {
+	sub, ok := e.subs[pid]
+	if !ok || sub.opts.Group == "" {
+		return
+	}
+	group := e.groups[sub.opts.Group]
+	n := slices.Index(group.pids, pid)
+	group.pids = slices.Delete(group.pids, n, n+1)
+	if n < group.next {
+		group.next--
+	}
+	if len(group.pids) == 0 {
+		delete(e.groups, sub.opts.Group)
+	}
+}
+
+func (e *eventStream) deliver(c *Context, pid *PID, sub *subscription, msg topicEvent) {
+	event := msg.event
+	if sub.opts.Envelope {
+		if sub.seqs == nil {
+			sub.seqs = make(map[string]uint64)
+		}
+		sub.seqs[msg.topic]++
+		event = EventEnvelope{Topic: msg.topic, Event: msg.event, Forwarded: msg.forwarded, Seq: sub.seqs[msg.topic]}
+	}
+	if sub.queue != nil {
+		sub.queue.push(event)
+	} else {
+		c.engine.SendWithSender(pid, event, c.PID())
+	}
+	sub.delivered++
+	if e.slowThreshold > 0 {
+		e.checkSlow(c.engine, pid, sub)
+	}
+}
+
+func (e *eventStream) subscriberStats(engine *Engine, pid *PID, sub *subscription) SubscriberStats {
+	stats := SubscriberStats{PID: pid, Delivered: sub.delivered}
+	if sub.queue != nil {
+		stats.QueueDepth, stats.Dropped, stats.Latency = sub.queue.stats()
+	} else if info, ok := engine.ProcessInfo(pid); ok {
+		stats.QueueDepth = info.MailboxLen
+	}
+	return stats
+}
+
+func (e *eventStream) stats(engine *Engine) []SubscriberStats {
+	stats := make([]SubscriberStats, 0, len(e.subs))
+	for pid, sub := range e.subs {
+		stats = append(stats, e.subscriberStats(engine, pid, sub))
+	}
+	return stats
+}
+
+// checkSlow reports the subscriber once the events that wait for it reach the
+// threshold, and again only after it got below half of it.
+func (e *eventStream) checkSlow(engine *Engine, pid *PID, sub *subscription) {
+	stats := e.subscriberStats(engine, pid, sub)
+	switch {
+	case !sub.slow && stats.QueueDepth >= e.slowThreshold:
+		sub.slow = true
+		engine.BroadcastEvent(SlowSubscriberEvent{
+			PID:        pid,
+			QueueDepth: stats.QueueDepth,
+			Dropped:    stats.Dropped,
+			Latency:    stats.Latency,
+		})
+	case sub.slow && stats.QueueDepth < e.slowThreshold/2:
+		sub.slow = false
+	}
+}
+
+// retain keeps the event, dropping the oldest event of its topic or type once
+// there are more than the retention.
+func (e *eventStream) retain(msg topicEvent) {
+	if e.retention <= 0 {
+		return
+	}
+	key := retentionKey{topic: msg.topic}
+	if msg.topic == "" {
+		key.typ = reflect.TypeOf(msg.event)
+	}
+	e.seq++
+	events := append(e.retained[key], retainedEvent{topicEvent: msg, seq: e.seq})
+	if len(events) > e.retention {
+		events = slices.Delete(events, 0, len(events)-e.retention)
+	}
+	e.retained[key] = events
+}
+
+// record appends the event to the journal if its topic is journaled.
+func (e *eventStream) record(c *Context, msg topicEvent) {
+	if e.journal == nil {
+		return
+	}
+	if len(e.journalTopics) > 0 && !slices.ContainsFunc(e.journalTopics, func(pattern string) bool {
+		return matchTopic(pattern, msg.topic)
+	}) {
+		return
+	}
+	entry := JournalEntry{Topic: msg.topic, Time: time.Now(), Event: msg.event, Forwarded: msg.forwarded}
+	if _, err := e.journal.Append(entry); err != nil {
+		c.engine.Logger().Error("failed to journal event", "err", err, "topic", msg.topic)
+	}
+}
+
+// replayJournal delivers the events of the journal from the given offset on
+// that match the new subscription.
+func (e *eventStream) replayJournal(c *Context, pid *PID, sub *subscription, from uint64) {
+	if e.journal == nil {
+		c.engine.Logger().Warn("subscribed from an offset without an event journal", "pid", pid)
+		return
+	}
+	err := e.journal.Read(from, func(entry JournalEntry) error {
+		msg := topicEvent{topic: entry.Topic, event: entry.Event, forwarded: entry.Forwarded}
+		if sub.matches(msg) {
+			e.deliver(c, pid, sub, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		c.engine.Logger().Error("failed to read event journal", "err", err, "pid", pid, "from", from)
+	}
+}
+
+// replay delivers the kept events that match the new subscription, in the
+// order they were broadcasted.
+func (e *eventStream) replay(c *Context, pid *PID, sub *subscription) {
+	var events []retainedEvent
+	for _, retained := range e.retained {
+		for _, r := range retained {
+			if sub.matches(r.topicEvent) {
+				events = append(events, r)
+			}
+		}
+	}
+	slices.SortFunc(events, func(a, b retainedEvent) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	for _, r := range events {
+		e.deliver(c, pid, sub, r.topicEvent)
+	}
+}
+
+// Subscription is the subscription of a function to the event stream of an
+// engine, see SubscribeTyped and SubscribeBatch.
+type Subscription struct {
+	engine *Engine
+	pid    *PID
+}
+
+// PID returns the PID the subscription is identified by on the event stream.
+// No actor is spawned for it.
+func (s Subscription) PID() *PID {
+	return s.pid
+}
+
+// Unsubscribe removes the subscription from the event stream and stops its
+// goroutine. The events that were not delivered yet are dropped.
+func (s Subscription) Unsubscribe() {
+	s.engine.Unsubscribe(s.pid)
+}
+
+// SubscribeTyped subscribes the given function to the events of the event
+// stream that are assignable to T, which can be an interface, and pass the
+// filters of the options. The other events are not even delivered to the
+// subscription. The events are queued for the subscription and the function
+// is invoked by a goroutine of its own, one event at a time, so a slow
+// function does not hold back the event stream. The queue is unbounded unless
+// it's bounded with WithBuffer.
+//
+//	sub := actor.SubscribeTyped(e, func(event actor.DeadLetterEvent) {
+//		slog.Warn("deadletter", "target", event.Target)
+//	}, actor.WithBuffer(1024, actor.OverflowDropOldest))
+//	defer sub.Unsubscribe()
+func SubscribeTyped[T any](e *Engine, fn func(T), opts ...SubscribeOptFunc) Subscription {
+	return subscribeFunc[T](e, opts, func(pid *PID, q *eventQueue) {
+		for {
+			event, ok := q.pop()
+			if !ok {
+				return
+			}
+			if event, ok := event.(T); ok {
+				deliver(e, pid, fn, event)
+			}
+		}
+	})
+}
+
+// SubscribeBatch subscribes the given function to the events of the event
+// stream like SubscribeTyped, but invokes it with batches of events rather
+// than each event on its own, which is cheaper for the events that come in
+// high volumes, like metrics. A batch holds at most maxN events, and waits at
+// most maxLatency after its first event for the others.
+//
+//	sub := actor.SubscribeBatch(e, func(events []MetricSample) {
+//		exporter.Export(events)
+//	}, 512, 100*time.Millisecond)
+//	defer sub.Unsubscribe()
+func SubscribeBatch[T any](e *Engine, fn func([]T), maxN int, maxLatency time.Duration, opts ...SubscribeOptFunc) Subscription {
+	return subscribeFunc[T](e, opts, func(pid *PID, q *eventQueue) {
+		for {
+			events, ok := q.popBatch(maxN, maxLatency)
+			if !ok {
+				return
+			}
+			batch := make([]T, 0, len(events))
+			for _, event := range events {
+				if event, ok := event.(T); ok {
+					batch = append(batch, event)
+				}
+			}
+			deliver(e, pid, fn, batch)
+		}
+	})
+}
+
+// subscribeFunc subscribes a queue to the events that are assignable to T, or
+// to the envelopes of all the events with WithEnvelope, and runs the given
+// delivery of the queue on a goroutine of its own.
+func subscribeFunc[T any](e *Engine, opts []SubscribeOptFunc, run func(*PID, *eventQueue)) Subscription {
+	pid := NewPID(e.address, "subscription/"+strconv.Itoa(rand.Intn(math.MaxInt)))
+	o := newSubscribeOpts(opts)
+	if !o.Envelope {
+		o = newSubscribeOpts(append([]SubscribeOptFunc{WithFilter(isEvent[T])}, opts...))
+	}
+	q := newEventQueue(o.Buffer, o.Overflow)
+	go run(pid, q)
+	e.Send(e.eventStream, eventSub{pid: pid, opts: o, queue: q})
+	return Subscription{engine: e, pid: pid}
+}
+
+// deliver invokes the function of a subscription, which keeps going with the
+// next event if the function panics.
+func deliver[T any](e *Engine, pid *PID, fn func(T), event T) {
+	defer func() {
+		if v := recover(); v != nil {
+			e.Logger().Error("subscription panicked", "pid", pid, "err", v)
+		}
+	}()
+	fn(event)
+}
+
+func isEvent[T any](event any) bool {
+	_, ok := event.(T)
+	return ok
 }
@@ -4,6 +4,7 @@ import (
 	fmt "fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -79,3 +80,439 @@ func TestEventStreamActorStoppedEvent(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestSubscribeTyped(t *testing.T) {
+	e, _ := NewEngine(NewEngineConfig())
+	events := make(chan CustomEvent, 10)
+	loggers := make(chan EventLogger, 10)
+	sub := SubscribeTyped(e, func(event CustomEvent) {
+		events <- event
+	})
+	SubscribeTyped(e, func(event EventLogger) {
+		loggers <- event
+	})
+
+	e.BroadcastEvent("not an event")
+	e.BroadcastEvent(CustomEvent{msg: "foo"})
+	e.BroadcastEvent(ActorStoppedEvent{})
+	e.BroadcastEvent(CustomEvent{msg: "bar"})
+	assert.Equal(t, CustomEvent{msg: "foo"}, <-events)
+	assert.Equal(t, CustomEvent{msg: "bar"}, <-events)
+	assert.IsType(t, ActorStoppedEvent{}, <-loggers)
+
+	sub.Unsubscribe()
+	e.BroadcastEvent(CustomEvent{msg: "baz"})
+	e.BroadcastEvent(ActorStoppedEvent{})
+	<-loggers
+	assert.Empty(t, events)
+}
+
+func TestSubscribeBuffer(t *testing.T) {
+	for _, tc := range []struct {
+		policy OverflowPolicy
+		want   []int
+	}{
+		{OverflowDropOldest, []int{1, 4, 5}},
+		{OverflowDropNewest, []int{1, 2, 3}},
+		{OverflowBlock, []int{1, 2, 3, 4, 5}},
+	} {
+		e, _ := NewEngine(NewEngineConfig())
+		var (
+			received = make(chan int, 10)
+			seen     = make(chan int, 10)
+			release  = make(chan struct{})
+		)
+		SubscribeTyped(e, func(n int) {
+			received <- n
+			if n == 1 {
+				<-release
+			}
+		}, WithBuffer(2, tc.policy))
+		SubscribeTyped(e, func(n int) {
+			seen <- n
+		})
+
+		// The slow subscriber holds on to the first event while the others
+		// fill its queue.
+		e.BroadcastEvent(1)
+		assert.Equal(t, 1, <-received)
+		for n := 2; n <= 5; n++ {
+			e.BroadcastEvent(n)
+		}
+		for n := 1; n <= 3; n++ {
+			assert.Equal(t, n, <-seen)
+		}
+		if tc.policy == OverflowBlock {
+			// The event stream blocks on 4, which the other subscriber may
+			// have received already, but never on 5.
+			timeout := time.After(50 * time.Millisecond)
+			for blocked := true; blocked; {
+				select {
+				case n := <-seen:
+					if n != 4 {
+						t.Fatalf("the event stream delivered %d while it was blocked", n)
+					}
+				case <-timeout:
+					blocked = false
+				}
+			}
+		}
+		close(release)
+		got := []int{1}
+		for len(got) < len(tc.want) {
+			got = append(got, <-received)
+		}
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestEventRetention(t *testing.T) {
+	e, _ := NewEngine(NewEngineConfig().WithEventRetention(2))
+	for _, msg := range []string{"foo", "bar", "baz"} {
+		e.BroadcastEvent(CustomEvent{msg: msg})
+	}
+	e.Publish("orders.placed", 1)
+	e.Publish("orders.placed", 2)
+	e.Publish("orders.placed", 3)
+	e.Publish("orders.shipped", 4)
+
+	events := make(chan any, 10)
+	SubscribeTyped(e, func(event any) {
+		events <- event
+	})
+	for _, want := range []any{CustomEvent{msg: "bar"}, CustomEvent{msg: "baz"}, 2, 3, 4} {
+		assert.Equal(t, want, <-events)
+	}
+	// The replay is filtered like the events that follow it.
+	shipped := make(chan int, 10)
+	SubscribeTyped(e, func(n int) {
+		shipped <- n
+	}, WithTopics("orders.shipped"))
+	e.Publish("orders.shipped", 5)
+	assert.Equal(t, 4, <-shipped)
+	assert.Equal(t, 5, <-shipped)
+	assert.Equal(t, 5, <-events)
+	assert.Empty(t, shipped)
+}
+
+func TestSubscribeForwarded(t *testing.T) {
+	e, _ := NewEngine(NewEngineConfig())
+	// Leaves out the lifecycle events of the subscriber.
+	noLogs := WithFilter(func(event any) bool {
+		_, ok := event.(EventLogger)
+		return !ok
+	})
+	envelopes := make(chan any, 10)
+	pid := e.SpawnFunc(func(c *Context) {
+		if envelope, ok := c.Message().(EventEnvelope); ok {
+			envelopes <- envelope
+		}
+	}, "sub")
+	e.Subscribe(pid, WithEnvelope(), WithLocalOnly(), noLogs)
+	all := make(chan any, 10)
+	SubscribeTyped(e, func(event any) {
+		all <- event
+	}, noLogs)
+
+	e.PublishForwarded("orders.placed", 1)
+	e.Publish("orders.placed", 2)
+	e.BroadcastEvent(CustomEvent{msg: "foo"})
+	assert.Equal(t, EventEnvelope{Topic: "orders.placed", Event: 2, Seq: 1}, <-envelopes)
+	assert.Equal(t, EventEnvelope{Event: CustomEvent{msg: "foo"}, Seq: 1}, <-envelopes)
+	for _, want := range []any{1, 2, CustomEvent{msg: "foo"}} {
+		assert.Equal(t, want, <-all)
+	}
+	assert.Empty(t, envelopes)
+}
+
+func TestSubscribeGroup(t *testing.T) {
+	e, _ := NewEngine(NewEngineConfig())
+	var (
+		workers = make([]chan int, 3)
+		subs    = make([]Subscription, 3)
+		all     = make(chan int, 20)
+	)
+	for i := range workers {
+		ch := make(chan int, 20)
+		workers[i] = ch
+		subs[i] = SubscribeTyped(e, func(n int) {
+			ch <- n
+		}, WithGroup("workers"))
+	}
+	SubscribeTyped(e, func(n int) {
+		all <- n
+	})
+
+	for n := range 6 {
+		e.BroadcastEvent(n)
+	}
+	for n := range 6 {
+		assert.Equal(t, n, <-all)
+	}
+	// The workers take turns in the order they joined.
+	for i, ch := range workers {
+		assert.Equal(t, i, <-ch)
+		assert.Equal(t, i+3, <-ch)
+	}
+
+	subs[1].Unsubscribe()
+	for n := 6; n < 10; n++ {
+		e.BroadcastEvent(n)
+	}
+	for n := 6; n < 10; n++ {
+		assert.Equal(t, n, <-all)
+	}
+	assert.Equal(t, 6, <-workers[0])
+	assert.Equal(t, 7, <-workers[2])
+	assert.Equal(t, 8, <-workers[0])
+	assert.Equal(t, 9, <-workers[2])
+	assert.Empty(t, workers[1])
+}
+
+func TestDeadEvents(t *testing.T) {
+	dead := make(chan DeadEvent, 10)
+	e, _ := NewEngine(NewEngineConfig().WithDeadEventHandler(func(event DeadEvent) {
+		// The lifecycle events of the engine have no subscriber either.
+		if _, ok := event.Event.(EventLogger); !ok {
+			dead <- event
+		}
+	}))
+	events := make(chan CustomEvent, 10)
+	SubscribeTyped(e, func(event CustomEvent) {
+		events <- event
+	})
+
+	e.BroadcastEvent(CustomEvent{msg: "foo"})
+	e.Publish("orders.placed", 1)
+	assert.Equal(t, CustomEvent{msg: "foo"}, <-events)
+	event := <-dead
+	assert.Equal(t, "orders.placed", event.Topic)
+	assert.Equal(t, 1, event.Event)
+	assert.Contains(t, event.Site, "event_stream_test.go:")
+	assert.Empty(t, dead)
+}
+
+func TestSubscribeBatch(t *testing.T) {
+	e, _ := NewEngine(NewEngineConfig())
+	batches := make(chan []int, 10)
+	SubscribeBatch(e, func(batch []int) {
+		batches <- batch
+	}, 3, 50*time.Millisecond)
+
+	for n := range 7 {
+		e.BroadcastEvent(n)
+	}
+	var got []int
+	for len(got) < 7 {
+		batch := <-batches
+		assert.LessOrEqual(t, len(batch), 3)
+		got = append(got, batch...)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6}, got)
+
+	// A batch that is not full is delivered once its latency passed.
+	start := time.Now()
+	e.BroadcastEvent(7)
+	assert.Equal(t, []int{7}, <-batches)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	dead := make(chan string, 10)
+	e, _ := NewEngine(NewEngineConfig().WithDeadEventHandler(func(event DeadEvent) {
+		if custom, ok := event.Event.(CustomEvent); ok {
+			dead <- custom.msg
+		}
+	}))
+	received := make(chan string, 10)
+	started := make(chan *PID, 10)
+	SubscribeTyped(e, func(event ActorStartedEvent) {
+		started <- event.PID
+	})
+	spawn := func(id string, unsubscribe bool) *PID {
+		pid := e.SpawnFunc(func(c *Context) {
+			switch msg := c.Message().(type) {
+			case Stopped:
+				if unsubscribe {
+					c.Engine().Unsubscribe(c.PID())
+				}
+			case string:
+				panic(msg)
+			case CustomEvent:
+				received <- id + ":" + msg.msg
+			}
+		}, id, WithRestartDelay(time.Millisecond))
+		<-started
+		return pid
+	}
+
+	// The subscription ends once the actor stops.
+	pid := spawn("a", false)
+	e.Subscribe(pid)
+	e.BroadcastEvent(CustomEvent{msg: "1"})
+	assert.Equal(t, "a:1", <-received)
+	<-e.Poison(pid).Done()
+	e.BroadcastEvent(CustomEvent{msg: "2"})
+	assert.Equal(t, "2", <-dead)
+
+	// The actor that unsubscribes when it stops is subscribed again after
+	// its restart.
+	pid = spawn("b", true)
+	e.Subscribe(pid, WithResubscribeOnRestart())
+	e.Send(pid, "crash")
+	<-started
+	e.BroadcastEvent(CustomEvent{msg: "3"})
+	assert.Equal(t, "b:3", <-received)
+	<-e.Poison(pid).Done()
+
+	pid = spawn("c", true)
+	e.Subscribe(pid)
+	e.Send(pid, "crash")
+	<-started
+	e.BroadcastEvent(CustomEvent{msg: "4"})
+	assert.Equal(t, "4", <-dead)
+	assert.Empty(t, received)
+}
+
+func TestSubscriberStats(t *testing.T) {
+	e, _ := NewEngine(NewEngineConfig().WithSlowSubscriberThreshold(2))
+	slow := make(chan SlowSubscriberEvent, 10)
+	SubscribeTyped(e, func(event SlowSubscriberEvent) {
+		slow <- event
+	})
+	var (
+		received = make(chan int, 10)
+		release  = make(chan struct{})
+	)
+	sub := SubscribeTyped(e, func(n int) {
+		received <- n
+		<-release
+	}, WithBuffer(2, OverflowDropNewest))
+
+	e.BroadcastEvent(1)
+	<-received
+	for n := 2; n <= 4; n++ {
+		e.BroadcastEvent(n)
+	}
+	event := <-slow
+	assert.Equal(t, sub.PID(), event.PID)
+	assert.Equal(t, 2, event.QueueDepth)
+
+	var stats SubscriberStats
+	for _, s := range e.SubscriberStats() {
+		if s.PID == sub.PID() {
+			stats = s
+		}
+	}
+	assert.Equal(t, uint64(4), stats.Delivered)
+	assert.Equal(t, uint64(1), stats.Dropped)
+	assert.Equal(t, 2, stats.QueueDepth)
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	<-received
+	<-received
+	for _, s := range e.SubscriberStats() {
+		if s.PID == sub.PID() {
+			assert.Equal(t, 0, s.QueueDepth)
+			assert.GreaterOrEqual(t, s.Latency, 10*time.Millisecond)
+		}
+	}
+	assert.Empty(t, slow)
+}
+
+func TestSubscribeFilter(t *testing.T) {
+	e, _ := NewEngine(NewEngineConfig())
+	events := make(chan any, 10)
+	pid := e.SpawnFunc(func(c *Context) {
+		if event, ok := c.Message().(CustomEvent); ok {
+			events <- event
+		}
+	}, "sub")
+	e.Subscribe(pid, WithFilter(func(event any) bool {
+		custom, ok := event.(CustomEvent)
+		return ok && custom.msg != "foo"
+	}))
+	typed := make(chan CustomEvent, 10)
+	SubscribeTyped(e, func(event CustomEvent) {
+		typed <- event
+	}, WithFilter(func(event any) bool {
+		return event.(CustomEvent).msg != "bar"
+	}))
+
+	for _, msg := range []string{"foo", "bar", "baz"} {
+		e.BroadcastEvent(CustomEvent{msg: msg})
+	}
+	assert.Equal(t, CustomEvent{msg: "bar"}, <-events)
+	assert.Equal(t, CustomEvent{msg: "baz"}, <-events)
+	assert.Equal(t, CustomEvent{msg: "foo"}, <-typed)
+	assert.Equal(t, CustomEvent{msg: "baz"}, <-typed)
+}
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		match          bool
+	}{
+		{"remote.conn.lost", "remote.conn.lost", true},
+		{"remote.*", "remote.conn.lost", true},
+		{"remote.*", "remote.conn", true},
+		{"remote.*", "remote", false},
+		{"*", "actor.deadletter", true},
+		{"actor.*.restarted", "actor.lifecycle.restarted", true},
+		{"actor.*.restarted", "actor.lifecycle.stopped", false},
+		{"actor.*.restarted", "actor.lifecycle.restarted.again", false},
+		{"actor.lifecycle", "actor.lifecycle.started", false},
+		{"remote.*", "actor.remote.missing", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.match, matchTopic(test.pattern, test.topic), "%s %s", test.pattern, test.topic)
+	}
+}
+
+func TestSubscribeTopics(t *testing.T) {
+	e, _ := NewEngine(NewEngineConfig())
+	events := make(chan any, 10)
+	SubscribeTyped(e, func(event any) {
+		events <- event
+	}, WithTopics("billing.*", "actor.*.restarted"))
+
+	e.BroadcastEvent(CustomEvent{msg: "no topic"})
+	e.Publish("orders.placed", CustomEvent{msg: "orders"})
+	e.Publish("billing.invoice.paid", CustomEvent{msg: "billing"})
+	e.BroadcastEvent(ActorStoppedEvent{})
+	e.BroadcastEvent(ActorRestartedEvent{})
+	assert.Equal(t, CustomEvent{msg: "billing"}, <-events)
+	assert.IsType(t, ActorRestartedEvent{}, <-events)
+}
+
+func TestEventSequence(t *testing.T) {
+	e, _ := NewEngine(NewEngineConfig())
+	var (
+		received = make(chan EventEnvelope, 10)
+		release  = make(chan struct{})
+	)
+	SubscribeTyped(e, func(env EventEnvelope) {
+		received <- env
+		<-release
+	}, WithTopics("orders.*"), WithEnvelope(), WithBuffer(1, OverflowDropNewest),
+		WithFilter(func(event any) bool { return event != 0 }))
+
+	e.Publish("orders.placed", 1)
+	first := <-received
+	// 0 is filtered and not numbered, 3 is dropped by the full queue.
+	for _, n := range []int{0, 2, 3} {
+		e.Publish("orders.placed", n)
+	}
+	e.Publish("orders.shipped", 1)
+	// The stream handled the events once it answered.
+	e.SubscriberStats()
+	close(release)
+
+	assert.Equal(t, EventEnvelope{Topic: "orders.placed", Event: 1, Seq: 1}, first)
+	assert.Equal(t, EventEnvelope{Topic: "orders.placed", Event: 2, Seq: 2}, <-received)
+	// The next event shows the gap of the dropped one, whatever happened on
+	// the other topic.
+	e.Publish("orders.placed", 4)
+	assert.Equal(t, EventEnvelope{Topic: "orders.placed", Event: 4, Seq: 4}, <-received)
+}
@@ -0,0 +1,128 @@
+package actor
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrUnregisteredEvent is returned by EncodeEvent for the events of a type
+// that is not registered with RegisterEvent.
+var ErrUnregisteredEvent = errors.New("event type is not registered")
+
+// EventCodec encodes and decodes the events of a Go type. The codecs of the
+// remote package work as well.
+type EventCodec[T any] interface {
+	Encode(T) ([]byte, error)
+	Decode([]byte) (T, error)
+}
+
+// JSONEventCodec is an EventCodec that encodes the events as JSON.
+type JSONEventCodec[T any] struct{}
+
+func (JSONEventCodec[T]) Encode(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONEventCodec[T]) Decode(b []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
+
+// RawEvent is an encoded event of a type this node does not know, because it
+// was registered on another node or by another version. It's passed on, to
+// other members and to the journal, with its name and data as is, so it can
+// be decoded where the type is known.
+type RawEvent struct {
+	Name string
+	Data []byte
+}
+
+func init() {
+	gob.Register(RawEvent{})
+}
+
+// registeredEvent holds the wire name and the codec of a registered event
+// type.
+type registeredEvent struct {
+	typ    reflect.Type
+	name   string
+	encode func(any) ([]byte, error)
+	decode func([]byte) (any, error)
+}
+
+var eventTypes = struct {
+	sync.RWMutex
+	byName map[string]registeredEvent
+	byType map[reflect.Type]registeredEvent
+}{
+	byName: make(map[string]registeredEvent),
+	byType: make(map[reflect.Type]registeredEvent),
+}
+
+// RegisterEvent registers the event type T under the given stable name, so
+// the events of T that are forwarded to the other members of a cluster or
+// persisted in a journal can be decoded by the nodes and versions that
+// register the same name, even after the Go type was renamed. The events of
+// the names a node did not register reach it as a RawEvent.
+//
+//	actor.RegisterEvent[OrderPlaced]("orders.OrderPlaced.v1", actor.JSONEventCodec[OrderPlaced]{})
+//
+// It panics if the name is already registered for another type.
+func RegisterEvent[T any](name string, codec EventCodec[T]) {
+	t := reflect.TypeFor[T]()
+	eventTypes.Lock()
+	defer eventTypes.Unlock()
+	if other, ok := eventTypes.byName[name]; ok && other.typ != t {
+		panic(fmt.Sprintf("actor: event name %s is already registered for %s", name, other.typ))
+	}
+	re := registeredEvent{
+		typ:    t,
+		name:   name,
+		encode: func(v any) ([]byte, error) { return codec.Encode(v.(T)) },
+		decode: func(b []byte) (any, error) { return codec.Decode(b) },
+	}
+	eventTypes.byName[name] = re
+	eventTypes.byType[t] = re
+}
+
+// EncodeEvent returns the registered name of the type of the event and the
+// event encoded with its codec. A RawEvent is returned as is. It returns
+// ErrUnregisteredEvent if the type is not registered.
+func EncodeEvent(event any) (string, []byte, error) {
+	if raw, ok := event.(RawEvent); ok {
+		return raw.Name, raw.Data, nil
+	}
+	eventTypes.RLock()
+	re, ok := eventTypes.byType[reflect.TypeOf(event)]
+	eventTypes.RUnlock()
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %T", ErrUnregisteredEvent, event)
+	}
+	b, err := re.encode(event)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode event %s: %w", re.name, err)
+	}
+	return re.name, b, nil
+}
+
+// DecodeEvent decodes the event that was encoded by EncodeEvent under the
+// given name. The events of a name that is not registered are returned as a
+// RawEvent.
+func DecodeEvent(name string, data []byte) (any, error) {
+	eventTypes.RLock()
+	re, ok := eventTypes.byName[name]
+	eventTypes.RUnlock()
+	if !ok {
+		return RawEvent{Name: name, Data: data}, nil
+	}
+	event, err := re.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode event %s: %w", name, err)
+	}
+	return event, nil
+}
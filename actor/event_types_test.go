@@ -0,0 +1,62 @@
+package actor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// invoicePaid is not registered with gob, the journal uses its codec.
+type invoicePaid struct {
+	ID     string
+	Amount int
+}
+
+func init() {
+	RegisterEvent[invoicePaid]("billing.InvoicePaid.v1", JSONEventCodec[invoicePaid]{})
+}
+
+func TestEventTypes(t *testing.T) {
+	name, b, err := EncodeEvent(invoicePaid{ID: "1", Amount: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "billing.InvoicePaid.v1", name)
+	event, err := DecodeEvent(name, b)
+	require.NoError(t, err)
+	assert.Equal(t, invoicePaid{ID: "1", Amount: 10}, event)
+
+	_, _, err = EncodeEvent(orderPlaced{ID: 1})
+	assert.ErrorIs(t, err, ErrUnregisteredEvent)
+	_, err = DecodeEvent(name, []byte("not json"))
+	assert.Error(t, err)
+
+	// The events of unknown names are passed through as they are.
+	event, err = DecodeEvent("billing.InvoiceVoided.v2", []byte(`{"ID":"1"}`))
+	require.NoError(t, err)
+	assert.Equal(t, RawEvent{Name: "billing.InvoiceVoided.v2", Data: []byte(`{"ID":"1"}`)}, event)
+	name, b, err = EncodeEvent(event)
+	require.NoError(t, err)
+	assert.Equal(t, "billing.InvoiceVoided.v2", name)
+	assert.Equal(t, []byte(`{"ID":"1"}`), b)
+
+	assert.Panics(t, func() {
+		RegisterEvent[orderPlaced]("billing.InvoicePaid.v1", JSONEventCodec[orderPlaced]{})
+	})
+}
+
+func TestJournalEventTypes(t *testing.T) {
+	j, err := OpenFileJournal(filepath.Join(t.TempDir(), "events"))
+	require.NoError(t, err)
+	defer j.Close()
+	_, err = j.Append(JournalEntry{Topic: "billing.paid", Event: invoicePaid{ID: "1", Amount: 10}})
+	require.NoError(t, err)
+	raw := RawEvent{Name: "billing.InvoiceVoided.v2", Data: []byte(`{"ID":"1"}`)}
+	_, err = j.Append(JournalEntry{Topic: "billing.voided", Event: raw})
+	require.NoError(t, err)
+
+	entries := readJournal(t, j, 0)
+	require.Len(t, entries, 2)
+	assert.Equal(t, invoicePaid{ID: "1", Amount: 10}, entries[0].Event)
+	assert.Equal(t, raw, entries[1].Event)
+}
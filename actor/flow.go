@@ -0,0 +1,173 @@
+package actor
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FlowNode is a node of a FlowGraph: the actors of a kind on an engine, with
+// the children by the name they were spawned with.
+type FlowNode struct {
+	Address string `json:"address"`
+	Kind    string `json:"kind"`
+}
+
+func (n FlowNode) String() string {
+	return n.Address + pidSeparator + n.Kind
+}
+
+// FlowEdge is an edge of a FlowGraph: the messages the actors of a node sent
+// to the actors of another one.
+type FlowEdge struct {
+	From FlowNode `json:"from"`
+	To   FlowNode `json:"to"`
+	// Messages is the estimated number of messages, which is the number of
+	// the sampled messages times the sampling.
+	Messages uint64 `json:"messages"`
+}
+
+// FlowGraph is the graph of the messages between the actors, which the local
+// actors received, see EngineConfig.WithFlowSampling.
+type FlowGraph struct {
+	// Sampling is the sampling the messages were recorded with.
+	Sampling int `json:"sampling"`
+	// Edges are sorted by messages, the hottest first.
+	Edges []FlowEdge `json:"edges"`
+}
+
+// DOT returns the graph in the DOT language of Graphviz, with the messages as
+// the labels of the edges:
+//
+//	digraph flows {
+//		"local/orders" -> "local/payments" [label="1200"];
+//	}
+func (g FlowGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph flows {\n")
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "\t%s -> %s [label=\"%d\"];\n",
+			strconv.Quote(edge.From.String()), strconv.Quote(edge.To.String()), edge.Messages)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// flowKey is the key of an edge of a flowGraph.
+type flowKey struct {
+	from, to FlowNode
+}
+
+// flowGraph records the sampled messages of an engine by edge.
+type flowGraph struct {
+	sampling int
+
+	mu    sync.Mutex
+	edges map[flowKey]uint64
+}
+
+func newFlowGraph(sampling int) *flowGraph {
+	return &flowGraph{sampling: sampling, edges: make(map[flowKey]uint64)}
+}
+
+func (g *flowGraph) record(from, to *PID) {
+	key := flowKey{
+		from: FlowNode{Address: from.Address, Kind: flowKind(from)},
+		to:   FlowNode{Address: to.Address, Kind: flowKind(to)},
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.edges[key]++
+}
+
+// flowKind returns the kind of the actor with the given PID, which is the
+// segment before its ID: the name a child was spawned with.
+func flowKind(pid *PID) string {
+	kind := pid.ID
+	if i := strings.LastIndex(kind, pidSeparator); i >= 0 {
+		kind = kind[:i]
+	}
+	return kindName(kind)
+}
+
+// recordFlow records the edge of the message if it's sampled. It's invoked
+// by the process, so the sequence needs no synchronization.
+func (p *process) recordFlow(sender *PID) {
+	flows := p.context.engine.flows
+	if flows == nil || sender == nil {
+		return
+	}
+	p.flowSeq++
+	if p.flowSeq%uint64(flows.sampling) == 0 {
+		flows.record(sender, p.pid)
+	}
+}
+
+// Flows returns the graph of the messages the local actors received from the
+// actors that sent them since the engine was created or the flows were reset,
+// to see which actors talk to which and spot the hot paths. The graph is
+// empty unless the flows are sampled, see EngineConfig.WithFlowSampling. The
+// messages sent without a sender are not recorded.
+func (e *Engine) Flows() FlowGraph {
+	if e.flows == nil {
+		return FlowGraph{Edges: []FlowEdge{}}
+	}
+	e.flows.mu.Lock()
+	graph := FlowGraph{Sampling: e.flows.sampling, Edges: make([]FlowEdge, 0, len(e.flows.edges))}
+	for key, n := range e.flows.edges {
+		graph.Edges = append(graph.Edges, FlowEdge{From: key.from, To: key.to, Messages: n * uint64(e.flows.sampling)})
+	}
+	e.flows.mu.Unlock()
+	slices.SortFunc(graph.Edges, func(a, b FlowEdge) int {
+		if c := cmp.Compare(b.Messages, a.Messages); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.From.String(), b.From.String()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.To.String(), b.To.String())
+	})
+	return graph
+}
+
+// ResetFlows clears the graph of the messages, so the next one shows the
+// flows from now on.
+func (e *Engine) ResetFlows() {
+	if e.flows == nil {
+		return
+	}
+	e.flows.mu.Lock()
+	defer e.flows.mu.Unlock()
+	clear(e.flows.edges)
+}
+
+// FlowHandler returns the HTTP handler that serves the graph of the messages
+// as JSON, or in the DOT language of Graphviz with the format query
+// parameter:
+//
+//	GET /flows            the graph as JSON
+//	GET /flows?format=dot the graph as DOT
+//
+// Render it with Graphviz:
+//
+//	curl -s localhost:8080/flows?format=dot | dot -Tsvg > flows.svg
+func (e *Engine) FlowHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		graph := e.Flows()
+		switch format := r.URL.Query().Get("format"); format {
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(graph)
+		case "dot":
+			w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+			w.Write([]byte(graph.DOT()))
+		default:
+			http.Error(w, fmt.Sprintf("unknown format %q", format), http.StatusBadRequest)
+		}
+	})
+}
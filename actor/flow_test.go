@@ -0,0 +1,90 @@
+package actor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlows(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig().WithFlowSampling(2))
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	sink := func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			wg.Done()
+		}
+	}
+	payments := e.SpawnFunc(sink, "payments")
+	var audit *PID
+	orders := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			audit = c.SpawnChildFunc(sink, "audit")
+		case string:
+			c.Send(payments, msg)
+			if msg == "audit" {
+				c.Send(audit, msg)
+			}
+		}
+	}, "orders")
+
+	wg.Add(6)
+	for _, msg := range []string{"a", "b", "audit", "audit"} {
+		e.Send(orders, msg)
+	}
+	wg.Wait()
+
+	local := func(kind string) FlowNode { return FlowNode{Address: LocalLookupAddr, Kind: kind} }
+	graph := e.Flows()
+	assert.Equal(t, 2, graph.Sampling)
+	// The messages sent by the engine have no sender, and only every second
+	// message of an actor is sampled.
+	assert.Equal(t, []FlowEdge{
+		{From: local("orders"), To: local("payments"), Messages: 4},
+		{From: local("orders"), To: local("audit"), Messages: 2},
+	}, graph.Edges)
+	assert.Equal(t, "digraph flows {\n"+
+		"\t\"local/orders\" -> \"local/payments\" [label=\"4\"];\n"+
+		"\t\"local/orders\" -> \"local/audit\" [label=\"2\"];\n"+
+		"}\n", graph.DOT())
+
+	e.ResetFlows()
+	assert.Empty(t, e.Flows().Edges)
+}
+
+func TestFlowsDisabled(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	graph := e.Flows()
+	assert.Zero(t, graph.Sampling)
+	assert.Empty(t, graph.Edges)
+}
+
+func TestFlowHandler(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig().WithFlowSampling(1))
+	require.NoError(t, err)
+	e.flows.record(NewPID("a:1", "orders/1"), NewPID(LocalLookupAddr, "payments/1"))
+	handler := e.FlowHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/flows", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var graph FlowGraph
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &graph))
+	assert.Equal(t, e.Flows(), graph)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/flows?format=dot", nil))
+	assert.Equal(t, "text/vnd.graphviz; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "\"a:1/orders\" -> \"local/payments\" [label=\"1\"];")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/flows?format=svg", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
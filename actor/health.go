@@ -0,0 +1,181 @@
+package actor
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// healthCheckTimeout is how long Health waits for the checks.
+const healthCheckTimeout = time.Second
+
+// HealthChecker checks a dependency of an engine, like the remote, the cluster
+// or a store of the persistence, see Engine.AddHealthCheck.
+type HealthChecker interface {
+	// CheckHealth returns an error if the dependency is not healthy. It
+	// returns once the context is done at the latest.
+	CheckHealth(ctx context.Context) error
+}
+
+// HealthCheckFunc is a function that is a HealthChecker.
+type HealthCheckFunc func(ctx context.Context) error
+
+func (fn HealthCheckFunc) CheckHealth(ctx context.Context) error {
+	return fn(ctx)
+}
+
+// Health is the status of an engine, see Engine.Health.
+type Health struct {
+	// Running is whether the engine runs, which is the case as long as its
+	// event stream runs.
+	Running bool `json:"running"`
+	// Draining is whether the engine is draining, see Engine.SetDraining.
+	Draining bool `json:"draining"`
+	// Checks are the results of the health checks, sorted by name.
+	Checks []HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the result of a health check.
+type HealthCheck struct {
+	Name string `json:"name"`
+	// Error is the error of the check, empty if it passed.
+	Error string `json:"error,omitempty"`
+}
+
+// Live returns whether the engine is alive, which is whether it runs.
+func (h Health) Live() bool {
+	return h.Running
+}
+
+// Ready returns whether the engine is ready to handle traffic, which is
+// whether it runs, it's not draining and all the checks passed.
+func (h Health) Ready() bool {
+	if !h.Running || h.Draining {
+		return false
+	}
+	for _, check := range h.Checks {
+		if check.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// healthChecks holds the health checks of an engine.
+type healthChecks struct {
+	mu       sync.Mutex
+	checks   map[string]HealthChecker
+	draining bool
+}
+
+// AddHealthCheck adds the given check to the health of the engine with the
+// given name, replacing the check that had the name. The remote of the
+// engine and the cluster check themselves, the stores of the persistence
+// need to be added:
+//
+//	e.AddHealthCheck("journal", journal)
+func (e *Engine) AddHealthCheck(name string, check HealthChecker) {
+	e.health.mu.Lock()
+	defer e.health.mu.Unlock()
+	if e.health.checks == nil {
+		e.health.checks = make(map[string]HealthChecker)
+	}
+	e.health.checks[name] = check
+}
+
+// SetDraining sets whether the engine is draining, which makes it not ready
+// while it keeps running, so the load balancers stop sending it traffic
+// before it stops. Leaving a cluster drains the engine.
+func (e *Engine) SetDraining(draining bool) {
+	e.health.mu.Lock()
+	defer e.health.mu.Unlock()
+	e.health.draining = draining
+}
+
+// Health returns the status of the engine, with the results of its health
+// checks, which run concurrently for a second at most.
+func (e *Engine) Health() Health {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	return e.checkHealth(ctx)
+}
+
+func (e *Engine) checkHealth(ctx context.Context) Health {
+	health := e.status()
+	e.health.mu.Lock()
+	checks := make(map[string]HealthChecker, len(e.health.checks)+1)
+	for name, check := range e.health.checks {
+		checks[name] = check
+	}
+	e.health.mu.Unlock()
+	if check, ok := e.remote.(HealthChecker); ok {
+		if _, ok := checks["remote"]; !ok {
+			checks["remote"] = check
+		}
+	}
+
+	health.Checks = make([]HealthCheck, 0, len(checks))
+	results := make(chan HealthCheck, len(checks))
+	for name, check := range checks {
+		go func() {
+			result := HealthCheck{Name: name}
+			if err := check.CheckHealth(ctx); err != nil {
+				result.Error = err.Error()
+			}
+			results <- result
+		}()
+	}
+	for range len(checks) {
+		health.Checks = append(health.Checks, <-results)
+	}
+	slices.SortFunc(health.Checks, func(a, b HealthCheck) int { return cmp.Compare(a.Name, b.Name) })
+	return health
+}
+
+// status returns the health of the engine without its checks.
+func (e *Engine) status() Health {
+	e.health.mu.Lock()
+	defer e.health.mu.Unlock()
+	return Health{
+		Running:  e.Registry.get(e.eventStream) != nil,
+		Draining: e.health.draining,
+	}
+}
+
+// HealthHandler returns the HTTP handler of the probes of Kubernetes, which
+// serves the health of the engine as JSON, with the status 200 if the probe
+// passed and 503 if it didn't:
+//
+//	GET /livez  whether the engine runs, without running the checks
+//	GET /readyz whether the engine is ready, see Health.Ready
+//
+// Mount it on the server of the probes:
+//
+//	http.Handle("/livez", e.HealthHandler())
+//	http.Handle("/readyz", e.HealthHandler())
+func (e *Engine) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, r *http.Request) {
+		health := e.status()
+		writeHealth(w, health, health.Live())
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+		health := e.checkHealth(ctx)
+		writeHealth(w, health, health.Ready())
+	})
+	return mux
+}
+
+func writeHealth(w http.ResponseWriter, health Health, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(health)
+}
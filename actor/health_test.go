@@ -0,0 +1,83 @@
+package actor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	health := e.Health()
+	assert.Equal(t, Health{Running: true, Checks: []HealthCheck{}}, health)
+	assert.True(t, health.Live())
+	assert.True(t, health.Ready())
+
+	var down error
+	e.AddHealthCheck("store", HealthCheckFunc(func(ctx context.Context) error { return down }))
+	e.AddHealthCheck("cache", HealthCheckFunc(func(ctx context.Context) error { return nil }))
+	down = errors.New("connection refused")
+	health = e.Health()
+	assert.Equal(t, []HealthCheck{{Name: "cache"}, {Name: "store", Error: "connection refused"}}, health.Checks)
+	assert.True(t, health.Live())
+	assert.False(t, health.Ready())
+
+	down = nil
+	e.SetDraining(true)
+	health = e.Health()
+	assert.True(t, health.Draining)
+	assert.False(t, health.Ready())
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	e.AddHealthCheck("slow", HealthCheckFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	health := e.Health()
+	assert.Equal(t, context.DeadlineExceeded.Error(), health.Checks[0].Error)
+}
+
+func TestHealthHandler(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	srv := httptest.NewServer(e.HealthHandler())
+	defer srv.Close()
+	probe := func(path string) (int, Health) {
+		res, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var health Health
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&health))
+		return res.StatusCode, health
+	}
+
+	checked := false
+	e.AddHealthCheck("store", HealthCheckFunc(func(ctx context.Context) error {
+		checked = true
+		return nil
+	}))
+	status, _ := probe("/readyz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, checked)
+
+	e.SetDraining(true)
+	status, health := probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.True(t, health.Draining)
+	// The liveness probe does not run the checks.
+	checked = false
+	status, health = probe("/livez")
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, health.Running)
+	assert.False(t, checked)
+}
@@ -51,6 +51,10 @@ type Inbox struct {
 	proc       Processer
 	scheduler  Scheduler
 	procStatus int32
+	// batch is the number of messages that were popped to be processed, and
+	// current the messages themselves.
+	batch   atomic.Int64
+	current atomic.Pointer[[]Envelope]
 }
 
 func NewInbox(size int) *Inbox {
@@ -61,6 +65,22 @@ func NewInbox(size int) *Inbox {
 	}
 }
 
+// Len returns the number of messages that wait in the inbox, including the
+// batch of messages that is being processed until it's done.
+func (in *Inbox) Len() int {
+	return int(in.rb.Len() + in.batch.Load())
+}
+
+// envelopes returns the messages that wait in the inbox, including the batch
+// of messages that is being processed until it's done, like Len.
+func (in *Inbox) envelopes() []Envelope {
+	envs := in.rb.Items()
+	if current := in.current.Load(); current != nil {
+		envs = append(envs, *current...)
+	}
+	return envs
+}
+
 func (in *Inbox) Send(msg Envelope) {
 	in.rb.Push(msg)
 	in.schedule()
@@ -96,7 +116,11 @@ func (in *Inbox) run() {
 		i++
 
 		if msgs, ok := in.rb.PopN(messageBatchSize); ok && len(msgs) > 0 {
+			in.batch.Store(int64(len(msgs)))
+			in.current.Store(&msgs)
 			in.proc.Invoke(msgs)
+			in.current.Store(nil)
+			in.batch.Store(0)
 		} else {
 			return
 		}
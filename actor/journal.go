@@ -0,0 +1,263 @@
+package actor
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// JournalEntry is an event that is stored in a journal.
+type JournalEntry struct {
+	// Offset is the position of the entry in the journal, starting at 0.
+	Offset uint64
+	// Topic is empty if the event was broadcasted without a topic.
+	Topic string
+	// Time is when the event stream received the event.
+	Time  time.Time
+	Event any
+	// Forwarded is true if the event was published on another engine, see
+	// Engine.PublishForwarded.
+	Forwarded bool
+}
+
+// Journal persists the events of the event stream, see
+// EngineConfig.WithEventJournal. The entries are appended by the event stream
+// only, but can be read by anyone at the same time.
+type Journal interface {
+	// Append stores the entry at the end of the journal and returns its
+	// offset. The offset of the entry that is passed is ignored.
+	Append(entry JournalEntry) (uint64, error)
+	// Read invokes the given function with the entries from the given offset
+	// on, in order, until the end of the journal or the function returns an
+	// error.
+	Read(from uint64, fn func(JournalEntry) error) error
+}
+
+// encodeEntry encodes the entry with gob. The events of the types that are
+// registered with RegisterEvent are stored by their name with their codec,
+// the concrete types of the others need to be registered with gob.Register.
+func encodeEntry(entry JournalEntry) ([]byte, error) {
+	if name, b, err := EncodeEvent(entry.Event); err == nil {
+		entry.Event = RawEvent{Name: name, Data: b}
+	} else if !errors.Is(err, ErrUnregisteredEvent) {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&entry); err != nil {
+		return nil, fmt.Errorf("failed to encode event %T: %w", entry.Event, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeEntry(b []byte) (JournalEntry, error) {
+	var entry JournalEntry
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&entry); err != nil {
+		return entry, err
+	}
+	if raw, ok := entry.Event.(RawEvent); ok {
+		event, err := DecodeEvent(raw.Name, raw.Data)
+		if err != nil {
+			return entry, err
+		}
+		entry.Event = event
+	}
+	return entry, nil
+}
+
+// FileJournal is a Journal that appends the entries to a file. The events are
+// encoded with the codecs they are registered with by RegisterEvent, or else
+// with gob, so their concrete types need to be registered with gob.Register,
+// and only their exported fields are kept.
+type FileJournal struct {
+	mu   sync.Mutex
+	file *os.File
+	// positions holds where each entry starts in the file, by offset.
+	positions []int64
+	size      int64
+}
+
+// OpenFileJournal opens the journal in the file with the given path, which is
+// created if it does not exist. A last entry that was only partly written is
+// dropped.
+func OpenFileJournal(path string) (*FileJournal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	j := &FileJournal{file: file}
+	if err := j.index(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// index finds the positions of the entries in the file.
+func (j *FileJournal) index() error {
+	info, err := j.file.Stat()
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(io.NewSectionReader(j.file, 0, info.Size()))
+	var pos int64
+	for {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			break
+		}
+		header := int64(binary.PutUvarint(make([]byte, binary.MaxVarintLen64), n))
+		if pos+header+int64(n) > info.Size() {
+			break
+		}
+		if _, err := r.Discard(int(n)); err != nil {
+			break
+		}
+		j.positions = append(j.positions, pos)
+		pos += header + int64(n)
+	}
+	j.size = pos
+	return j.file.Truncate(pos)
+}
+
+// Append implements Journal.
+func (j *FileJournal) Append(entry JournalEntry) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry.Offset = uint64(len(j.positions))
+	b, err := encodeEntry(entry)
+	if err != nil {
+		return 0, err
+	}
+	record := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(b)), uint64(len(b)))
+	record = append(record, b...)
+	if _, err := j.file.WriteAt(record, j.size); err != nil {
+		return 0, err
+	}
+	j.positions = append(j.positions, j.size)
+	j.size += int64(len(record))
+	return entry.Offset, nil
+}
+
+// Read implements Journal.
+func (j *FileJournal) Read(from uint64, fn func(JournalEntry) error) error {
+	j.mu.Lock()
+	if from >= uint64(len(j.positions)) {
+		j.mu.Unlock()
+		return nil
+	}
+	start, end := j.positions[from], j.size
+	j.mu.Unlock()
+
+	r := bufio.NewReader(io.NewSectionReader(j.file, start, end-start))
+	for {
+		n, err := binary.ReadUvarint(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return err
+		}
+		entry, err := decodeEntry(b)
+		if err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// Sync commits the entries to stable storage.
+func (j *FileJournal) Sync() error {
+	return j.file.Sync()
+}
+
+// Close closes the file of the journal.
+func (j *FileJournal) Close() error {
+	return j.file.Close()
+}
+
+// SQLJournal is a Journal that stores the entries in a table of a SQL
+// database, like SQLite. The driver is up to the caller, and needs to take
+// "?" as the placeholder. The events are encoded like for a FileJournal.
+type SQLJournal struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLJournal returns a journal that stores the entries in the table with
+// the given name, which is created if it does not exist.
+//
+//	db, err := sql.Open("sqlite3", "events.db")
+//	...
+//	journal, err := actor.NewSQLJournal(db, "events")
+func NewSQLJournal(db *sql.DB, table string) (*SQLJournal, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + table + ` (
+		seq INTEGER PRIMARY KEY,
+		topic TEXT NOT NULL,
+		time INTEGER NOT NULL,
+		data BLOB NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create journal table %s: %w", table, err)
+	}
+	return &SQLJournal{db: db, table: table}, nil
+}
+
+// Append implements Journal. The offset is one past the last one of the
+// table, which needs the entries to be appended by a single writer.
+func (j *SQLJournal) Append(entry JournalEntry) (uint64, error) {
+	var last sql.NullInt64
+	if err := j.db.QueryRow(`SELECT MAX(seq) FROM ` + j.table).Scan(&last); err != nil {
+		return 0, err
+	}
+	entry.Offset = 0
+	if last.Valid {
+		entry.Offset = uint64(last.Int64) + 1
+	}
+	b, err := encodeEntry(entry)
+	if err != nil {
+		return 0, err
+	}
+	_, err = j.db.Exec(`INSERT INTO `+j.table+` (seq, topic, time, data) VALUES (?, ?, ?, ?)`,
+		int64(entry.Offset), entry.Topic, entry.Time.UnixNano(), b)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Offset, nil
+}
+
+// Read implements Journal.
+func (j *SQLJournal) Read(from uint64, fn func(JournalEntry) error) error {
+	rows, err := j.db.Query(`SELECT data FROM `+j.table+` WHERE seq >= ? ORDER BY seq`, int64(from))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return err
+		}
+		entry, err := decodeEntry(b)
+		if err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
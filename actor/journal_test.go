@@ -0,0 +1,87 @@
+package actor
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderPlaced struct {
+	ID int
+}
+
+func init() {
+	gob.Register(orderPlaced{})
+}
+
+func readJournal(t *testing.T, j Journal, from uint64) []JournalEntry {
+	var entries []JournalEntry
+	require.NoError(t, j.Read(from, func(entry JournalEntry) error {
+		entries = append(entries, entry)
+		return nil
+	}))
+	return entries
+}
+
+func TestFileJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events")
+	j, err := OpenFileJournal(path)
+	require.NoError(t, err)
+	for i := range 3 {
+		offset, err := j.Append(JournalEntry{Topic: "orders.placed", Event: orderPlaced{ID: i}})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(i), offset)
+	}
+	_, err = j.Append(JournalEntry{Event: CustomEvent{msg: "not registered"}})
+	assert.Error(t, err)
+	require.NoError(t, j.Close())
+
+	// A record that was cut off by a crash is dropped when the journal is
+	// opened again.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{100, 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	j, err = OpenFileJournal(path)
+	require.NoError(t, err)
+	defer j.Close()
+	offset, err := j.Append(JournalEntry{Topic: "orders.placed", Event: orderPlaced{ID: 3}})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), offset)
+
+	entries := readJournal(t, j, 1)
+	require.Len(t, entries, 3)
+	for i, entry := range entries {
+		assert.Equal(t, uint64(i+1), entry.Offset)
+		assert.Equal(t, "orders.placed", entry.Topic)
+		assert.Equal(t, orderPlaced{ID: i + 1}, entry.Event)
+	}
+	assert.Empty(t, readJournal(t, j, 4))
+}
+
+func TestSubscribeFrom(t *testing.T) {
+	j, err := OpenFileJournal(filepath.Join(t.TempDir(), "events"))
+	require.NoError(t, err)
+	defer j.Close()
+	e, _ := NewEngine(NewEngineConfig().WithEventJournal(j, "orders.*"))
+	e.BroadcastEvent(CustomEvent{msg: "not journaled"})
+	for i := range 3 {
+		e.Publish("orders.placed", orderPlaced{ID: i})
+	}
+
+	events := make(chan orderPlaced, 10)
+	SubscribeTyped(e, func(event orderPlaced) {
+		events <- event
+	}, SubscribeFrom(1))
+	e.Publish("orders.placed", orderPlaced{ID: 3})
+	for i := 1; i <= 3; i++ {
+		assert.Equal(t, orderPlaced{ID: i}, <-events)
+	}
+	assert.Len(t, readJournal(t, j, 0), 4)
+}
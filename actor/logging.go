@@ -0,0 +1,90 @@
+package actor
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Logger returns the logger of the engine, which has the handler of
+// EngineConfig.WithLogHandler, or the default logger of slog.
+func (e *Engine) Logger() *slog.Logger {
+	if e.logger == nil {
+		return slog.Default()
+	}
+	return e.logger
+}
+
+// Logger returns the logger of the actor, which is the logger of the engine
+// with the pid, the kind and the tags of the actor as attributes, see
+// WithTags.
+func (c *Context) Logger() *slog.Logger {
+	if c.logger == nil {
+		args := make([]any, 0, 4+2*len(c.tags))
+		args = append(args, "pid", c.pid.String(), "kind", c.kind)
+		for k, v := range c.tags {
+			args = append(args, k, v)
+		}
+		c.logger = c.engine.Logger().With(args...)
+	}
+	return c.logger
+}
+
+// LogConfig holds the configuration of the LogMessages middleware.
+type LogConfig struct {
+	level         slog.Level
+	slowThreshold time.Duration
+	slowLevel     slog.Level
+}
+
+// NewLogConfig returns a LogConfig that logs the messages at the debug level.
+func NewLogConfig() LogConfig {
+	return LogConfig{level: slog.LevelDebug, slowLevel: slog.LevelWarn}
+}
+
+// WithLevel sets the level the messages are logged at.
+func (config LogConfig) WithLevel(level slog.Level) LogConfig {
+	config.level = level
+	return config
+}
+
+// WithSlowThreshold sets the duration from which the messages are logged at
+// the given level instead, as the actor took long to handle them. Zero, the
+// default, logs all the messages at the same level.
+func (config LogConfig) WithSlowThreshold(d time.Duration, level slog.Level) LogConfig {
+	config.slowThreshold = d
+	config.slowLevel = level
+	return config
+}
+
+// LogMessages returns the middleware that logs the type of the messages the
+// actor handles, their sender and how long the actor took to handle them,
+// with the logger of the actor, see Context.Logger.
+//
+//	e.Spawn(newFoo, "foo", actor.WithMiddleware(actor.LogMessages(actor.NewLogConfig().
+//		WithSlowThreshold(100*time.Millisecond, slog.LevelWarn))))
+func LogMessages(config LogConfig) MiddlewareFunc {
+	lowest := config.level
+	if config.slowThreshold > 0 {
+		lowest = min(lowest, config.slowLevel)
+	}
+	return func(next ReceiveFunc) ReceiveFunc {
+		return func(c *Context) {
+			logger := c.Logger()
+			if !logger.Enabled(c.Context(), lowest) {
+				next(c)
+				return
+			}
+			msg, sender := c.Message(), c.Sender()
+			start := time.Now()
+			next(c)
+			d := time.Since(start)
+			level := config.level
+			if config.slowThreshold > 0 && d >= config.slowThreshold {
+				level = config.slowLevel
+			}
+			logger.Log(c.Context(), level, "handled message",
+				"type", fmt.Sprintf("%T", msg), "sender", sender, "duration", d)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package actor
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logBuffer collects the records of a JSON handler.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// records returns the records with the given message.
+func (b *logBuffer) records(t *testing.T, msg string) []map[string]any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		if record["msg"] == msg {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func TestLogMessages(t *testing.T) {
+	logs := &logBuffer{}
+	handler := slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})
+	e, err := NewEngine(NewEngineConfig().WithLogHandler(handler))
+	require.NoError(t, err)
+
+	config := NewLogConfig().WithSlowThreshold(20*time.Millisecond, slog.LevelWarn)
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case time.Duration:
+			time.Sleep(msg)
+			c.Logger().Info("slept")
+			c.Respond(msg)
+		}
+	}, "sleeper", WithID("1"), WithTags(map[string]string{"tenant": "acme"}), WithMiddleware(LogMessages(config)))
+	for _, d := range []time.Duration{0, 30 * time.Millisecond} {
+		_, err := e.Request(pid, d, time.Second).Result()
+		require.NoError(t, err)
+	}
+
+	slept := logs.records(t, "slept")
+	require.Len(t, slept, 2)
+	assert.Equal(t, "local/sleeper/1", slept[0]["pid"])
+	assert.Equal(t, "sleeper", slept[0]["kind"])
+	assert.Equal(t, "acme", slept[0]["tenant"])
+
+	var levels []any
+	for _, record := range logs.records(t, "handled message") {
+		if record["type"] == "time.Duration" {
+			levels = append(levels, record["level"])
+			assert.Equal(t, "acme", record["tenant"])
+			assert.Contains(t, record, "duration")
+		}
+	}
+	assert.Equal(t, []any{"DEBUG", "WARN"}, levels)
+
+	// The events of the engine are logged with its handler too.
+	assert.Eventually(t, func() bool {
+		return len(logs.records(t, "Actor started")) > 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestLogDeadEventSite(t *testing.T) {
+	logs := &logBuffer{}
+	handler := slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})
+	e, err := NewEngine(NewEngineConfig().WithLogHandler(handler))
+	require.NoError(t, err)
+	// The site is captured when the handler of the engine logs the debug
+	// level, whatever the level of the default logger.
+	e.Publish("orders.placed", 1)
+	assert.Eventually(t, func() bool {
+		for _, record := range logs.records(t, "event has no subscriber") {
+			if record["topic"] == "orders.placed" {
+				return strings.Contains(record["site"].(string), "logging_test.go:")
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestLogMessagesDisabled(t *testing.T) {
+	logs := &logBuffer{}
+	e, err := NewEngine(NewEngineConfig().WithLogHandler(slog.NewJSONHandler(logs, nil)))
+	require.NoError(t, err)
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			c.Respond("ok")
+		}
+	}, "quiet", WithMiddleware(LogMessages(NewLogConfig())))
+	_, err = e.Request(pid, "hi", time.Second).Result()
+	require.NoError(t, err)
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+	assert.Empty(t, logs.buf.String())
+}
@@ -25,6 +25,15 @@ type Opts struct {
 	InboxSize    int
 	Middleware   []MiddlewareFunc
 	Context      context.Context
+	Tags         map[string]string
+	// FlightRecorder is the number of messages the flight recorder keeps,
+	// see WithFlightRecorder.
+	FlightRecorder int
+	MessageSummary func(msg any) string
+	StateSize      func(Receiver) int
+	// SlowHandlerThreshold is how long the actor may take to handle a
+	// message, see WithSlowHandlerThreshold.
+	SlowHandlerThreshold time.Duration
 }
 
 type OptFunc func(*Opts)
@@ -76,3 +85,11 @@ func WithID(id string) OptFunc {
 		opts.ID = id
 	}
 }
+
+// WithTags tags the actor with the given keys and values, like the tenant it
+// serves, which are attributes of its logger, see Context.Logger.
+func WithTags(tags map[string]string) OptFunc {
+	return func(opts *Opts) {
+		opts.Tags = tags
+	}
+}
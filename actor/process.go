@@ -6,14 +6,22 @@ import (
 	"fmt"
 	"log/slog"
 	"runtime/debug"
+	"runtime/pprof"
+	"sync/atomic"
 	"time"
 
 	"github.com/DataDog/gostackparse"
+	"github.com/fertigai/hollywood/ringbuffer"
 )
 
 type Envelope struct {
 	Msg    any
 	Sender *PID
+	// Trace is the trace context the message was sent with, see
+	// TracedMessage.
+	Trace TraceContext
+	// Enqueued is when the message was put in the inbox of the process.
+	Enqueued time.Time
 }
 
 // Processer is an interface the abstracts the way a process behaves.
@@ -35,18 +43,45 @@ type process struct {
 	pid      *PID
 	restarts int32
 	mbuffer  []Envelope
+	// watchers holds the processes that watch this process.
+	watchers map[uint64]*PID
+	// lastActive is the unix time in nanoseconds of the last message the
+	// process received, or of its spawn.
+	lastActive atomic.Int64
+	// messages is the number of messages the process handled, and busy the
+	// nanoseconds its receiver took to handle them.
+	messages atomic.Uint64
+	busy     atomic.Int64
+	// stateSize is the size of the state of the receiver of WithStateSize,
+	// as of the last batch of messages.
+	stateSize atomic.Int64
+	// labels holds the pprof labels of the actor, see Invoke.
+	labels context.Context
+	// recorder holds the last messages of the actor, nil without a flight
+	// recorder, see WithFlightRecorder.
+	recorder *ringbuffer.RingBuffer[MessageRecord]
+	// flowSeq is the number of messages with a sender the process received,
+	// see recordFlow.
+	flowSeq uint64
 }
 
 func newProcess(e *Engine, opts Opts) *process {
+	if opts.SlowHandlerThreshold == 0 {
+		opts.SlowHandlerThreshold = e.slowHandlers
+	}
 	pid := NewPID(e.address, opts.Kind+pidSeparator+opts.ID)
 	ctx := newContext(opts.Context, e, pid)
+	ctx.kind, ctx.tags = opts.Kind, opts.Tags
 	p := &process{
-		pid:     pid,
-		inbox:   NewInbox(opts.InboxSize),
-		Opts:    opts,
-		context: ctx,
-		mbuffer: nil,
+		pid:      pid,
+		inbox:    NewInbox(opts.InboxSize),
+		Opts:     opts,
+		context:  ctx,
+		mbuffer:  nil,
+		labels:   pprof.WithLabels(context.Background(), pprof.Labels("actor_kind", opts.Kind, "actor_id", opts.ID)),
+		recorder: newRecorder(opts.FlightRecorder),
 	}
+	p.lastActive.Store(time.Now().UnixNano())
 	return p
 }
 
@@ -58,6 +93,10 @@ func applyMiddleware(rcv ReceiveFunc, middleware ...MiddlewareFunc) ReceiveFunc
 }
 
 func (p *process) Invoke(msgs []Envelope) {
+	// Label the goroutine while it handles the messages, so the CPU and the
+	// goroutine profiles can be broken down by actor.
+	pprof.SetGoroutineLabels(p.labels)
+	defer pprof.SetGoroutineLabels(context.Background())
 	var (
 		// numbers of msgs that need to be processed.
 		nmsg = len(msgs)
@@ -72,6 +111,16 @@ func (p *process) Invoke(msgs []Envelope) {
 		// If we recovered, we buffer up all the messages that we could not process
 		// so we can retry them on the next restart.
 		if v := recover(); v != nil {
+			// Let the requester know the request failed instead of letting
+			// it time out.
+			if IsResponsePID(p.context.sender) {
+				p.context.engine.Send(p.context.sender, &ResponseError{
+					Type:    panicErrorType,
+					Message: fmt.Sprint(v),
+				})
+			}
+			p.context.traceCrash(v)
+			p.crashed()
 			p.context.message = Stopped{}
 			p.context.receiver.Receive(p.context)
 
@@ -101,21 +150,77 @@ func (p *process) Invoke(msgs []Envelope) {
 		p.invokeMsg(msg)
 		processed++
 	}
+	p.measureState()
+}
+
+// measureState stores the size of the state of the receiver, see
+// WithStateSize.
+func (p *process) measureState() {
+	if p.Opts.StateSize != nil {
+		p.stateSize.Store(int64(p.Opts.StateSize(p.context.receiver)))
+	}
 }
 
 func (p *process) invokeMsg(msg Envelope) {
 	// suppress poison pill messages here. they're private to the actor engine.
-	if _, ok := msg.Msg.(poisonPill); ok {
+	switch m := msg.Msg.(type) {
+	case poisonPill:
+		return
+	case restartActor:
+		p.context.sender = nil
+		panic(ErrRestartRequested)
+	case *Watch:
+		if p.watchers == nil {
+			p.watchers = make(map[uint64]*PID)
+		}
+		p.watchers[m.Watcher.LookupKey()] = m.Watcher
+		return
+	case *Unwatch:
+		delete(p.watchers, m.Watcher.LookupKey())
 		return
+	case *Terminated:
+		// Drop the message if we stopped watching the process in the meantime.
+		if !p.context.unwatched(m.Pid) {
+			return
+		}
+	}
+	now := time.Now()
+	p.lastActive.Store(now.UnixNano())
+	p.messages.Add(1)
+	p.recordFlow(msg.Sender)
+	p.context.latency = 0
+	if !msg.Enqueued.IsZero() {
+		p.context.latency = now.Sub(msg.Enqueued)
 	}
 	p.context.message = msg.Msg
 	p.context.sender = msg.Sender
+	p.context.parentTrace = msg.Trace
+	if tracer := p.context.engine.tracer; tracer != nil {
+		if p.context.sampled(msg) {
+			defer p.context.startSpan(tracer, msg, now)()
+		} else if p.context.engine.traceSampler.errors {
+			p.context.untraced = p.context.newSpan(msg, now)
+		}
+	}
+	if p.recorder != nil {
+		defer p.record(msg)()
+	}
+	if threshold := p.Opts.SlowHandlerThreshold; threshold > 0 {
+		defer p.watchdog(msg, now, threshold).Stop()
+	}
 	recv := p.context.receiver
 	if len(p.Opts.Middleware) > 0 {
 		applyMiddleware(recv.Receive, p.Opts.Middleware...)(p.context)
 	} else {
 		recv.Receive(p.context)
 	}
+	p.context.untraced = Span{}
+	p.busy.Add(int64(time.Since(now)))
+	// Acknowledge the processed chunk, which allows the writer of the
+	// stream to send the next one.
+	if chunk, ok := msg.Msg.(*StreamChunk); ok {
+		p.context.engine.Send(chunk.Stream, &StreamAck{Seq: chunk.Seq})
+	}
 }
 
 func (p *process) Start() {
@@ -123,18 +228,21 @@ func (p *process) Start() {
 	p.context.receiver = recv
 	defer func() {
 		if v := recover(); v != nil {
+			p.crashed()
 			p.context.message = Stopped{}
 			p.context.receiver.Receive(p.context)
 			p.tryRestart(v)
 		}
 	}()
 	p.context.message = Initialized{}
+	p.context.latency = 0
 	applyMiddleware(recv.Receive, p.Opts.Middleware...)(p.context)
 	p.context.engine.BroadcastEvent(ActorInitializedEvent{PID: p.pid, Timestamp: time.Now()})
 
 	p.context.message = Started{}
 	applyMiddleware(recv.Receive, p.Opts.Middleware...)(p.context)
 	p.context.engine.BroadcastEvent(ActorStartedEvent{PID: p.pid, Timestamp: time.Now()})
+	p.measureState()
 	// If we have messages in our buffer, invoke them.
 	if len(p.mbuffer) > 0 {
 		p.Invoke(p.mbuffer)
@@ -144,6 +252,14 @@ func (p *process) Start() {
 	p.inbox.Start(p)
 }
 
+// crashed lets the event stream know the actor stops to restart, before it
+// handles Stopped, see WithResubscribeOnRestart.
+func (p *process) crashed() {
+	if stream := p.context.engine.eventStream; stream != nil {
+		p.context.engine.Send(stream, actorCrashed{pid: p.pid})
+	}
+}
+
 func (p *process) tryRestart(v any) {
 	// InternalError does not take the maximum restarts into account.
 	// For now, InternalError is getting triggered when we are dialing
@@ -151,7 +267,7 @@ func (p *process) tryRestart(v any) {
 	// back up. NOTE: not sure if that is the best option. What if that
 	// node never comes back up again?
 	if msg, ok := v.(*InternalError); ok {
-		slog.Error(msg.From, "err", msg.Err)
+		p.context.engine.Logger().Error(msg.From, "err", msg.Err)
 		time.Sleep(p.Opts.RestartDelay)
 		p.Start()
 		return
@@ -161,8 +277,9 @@ func (p *process) tryRestart(v any) {
 	// everything up.
 	if p.restarts == p.MaxRestarts {
 		p.context.engine.BroadcastEvent(ActorMaxRestartsExceededEvent{
-			PID:       p.pid,
-			Timestamp: time.Now(),
+			PID:          p.pid,
+			Timestamp:    time.Now(),
+			LastMessages: p.lastMessages(),
 		})
 		p.cleanup(nil)
 		return
@@ -171,18 +288,23 @@ func (p *process) tryRestart(v any) {
 	p.restarts++
 	// Restart the process after its restartDelay
 	p.context.engine.BroadcastEvent(ActorRestartedEvent{
-		PID:        p.pid,
-		Timestamp:  time.Now(),
-		Stacktrace: stackTrace,
-		Reason:     v,
-		Restarts:   p.restarts,
+		PID:          p.pid,
+		Timestamp:    time.Now(),
+		Stacktrace:   stackTrace,
+		Reason:       v,
+		Restarts:     p.restarts,
+		LastMessages: p.lastMessages(),
 	})
 	time.Sleep(p.Opts.RestartDelay)
 	p.Start()
 }
 
 func (p *process) cleanup(cancel context.CancelFunc) {
-	defer cancel()
+	// The process is cleaned up without a poison pill when it exceeded its
+	// restarts, or on shutdown.
+	if cancel != nil {
+		defer cancel()
+	}
 
 	if p.context.parentCtx != nil {
 		p.context.parentCtx.children.Delete(p.pid.ID)
@@ -197,18 +319,41 @@ func (p *process) cleanup(cancel context.CancelFunc) {
 
 	p.inbox.Stop()
 	p.context.engine.Registry.Remove(p.pid)
+	p.context.engine.stats.stopped.Add(p.messages.Load())
 	p.context.message = Stopped{}
 	applyMiddleware(p.context.receiver.Receive, p.Opts.Middleware...)(p.context)
 
+	for _, pid := range p.context.watching {
+		p.context.Unwatch(pid)
+	}
+	for _, watcher := range p.watchers {
+		p.context.engine.Send(watcher, &Terminated{Pid: p.pid, Reason: TerminatedReason_Stopped})
+	}
+
 	p.context.engine.BroadcastEvent(ActorStoppedEvent{PID: p.pid, Timestamp: time.Now()})
 }
 
 func (p *process) PID() *PID { return p.pid }
+
+func (p *process) info() ProcessInfo {
+	info := ProcessInfo{
+		PID:        p.pid,
+		Kind:       p.Opts.Kind,
+		Messages:   p.messages.Load(),
+		LastActive: time.Unix(0, p.lastActive.Load()),
+	}
+	if inbox, ok := p.inbox.(interface{ Len() int }); ok {
+		info.MailboxLen = inbox.Len()
+	}
+	return info
+}
 func (p *process) Send(_ *PID, msg any, sender *PID) {
-	p.inbox.Send(Envelope{Msg: msg, Sender: sender})
+	msg, trace := untrace(msg)
+	p.inbox.Send(Envelope{Msg: msg, Sender: sender, Trace: trace, Enqueued: time.Now()})
 }
 func (p *process) SendPriority(_ *PID, msg any, sender *PID) {
-	p.inbox.SendPriority(Envelope{Msg: msg, Sender: sender})
+	msg, trace := untrace(msg)
+	p.inbox.SendPriority(Envelope{Msg: msg, Sender: sender, Trace: trace, Enqueued: time.Now()})
 }
 func (p *process) Shutdown() {
 	p.cleanup(nil)
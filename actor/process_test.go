@@ -3,6 +3,7 @@ package actor
 import (
 	"bytes"
 	"fmt"
+	"runtime/pprof"
 	"testing"
 	"time"
 
@@ -117,3 +118,42 @@ func Test_StartupMessages(t *testing.T) {
 		return
 	}
 }
+
+func TestMaxRestartsOnStart(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	exceeded := make(chan struct{}, 1)
+	SubscribeTyped(e, func(ActorMaxRestartsExceededEvent) {
+		exceeded <- struct{}{}
+	})
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(Started); ok {
+			panic("failed to start")
+		}
+	}, "foo", WithMaxRestarts(1), WithRestartDelay(time.Millisecond))
+	select {
+	case <-exceeded:
+	case <-time.After(time.Second):
+		t.Fatal("the restarts were not exceeded")
+	}
+	require.Nil(t, e.Registry.get(pid))
+}
+
+func TestProfilerLabels(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	handling, done := make(chan struct{}), make(chan struct{})
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			close(handling)
+			<-done
+		}
+	}, "labeled", WithID("1"))
+	e.Send(pid, "block")
+	<-handling
+
+	var buf bytes.Buffer
+	require.NoError(t, pprof.Lookup("goroutine").WriteTo(&buf, 1))
+	close(done)
+	require.Contains(t, buf.String(), `labels: {"actor_id":"1", "actor_kind":"labeled"}`)
+}
@@ -0,0 +1,87 @@
+package actor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fertigai/hollywood/ringbuffer"
+)
+
+// MessageRecord is a message an actor handled, as recorded by its flight
+// recorder, see WithFlightRecorder.
+type MessageRecord struct {
+	// Type is the Go type of the message, like "*orders.PlaceOrder".
+	Type   string
+	Sender *PID
+	// Timestamp is when the actor started to handle the message.
+	Timestamp time.Time
+	// Duration is how long the actor took to handle the message, or until
+	// it crashed.
+	Duration time.Duration
+	// Summary is the summary of the message of WithMessageSummary, empty
+	// without one.
+	Summary string
+}
+
+// WithFlightRecorder keeps the last n messages the actor handled, which are
+// passed along in ActorRestartedEvent and ActorMaxRestartsExceededEvent when
+// it crashes, and returned by Engine.LastMessages. The last record of a crash
+// is the message the actor crashed on.
+func WithFlightRecorder(n int) OptFunc {
+	return func(opts *Opts) {
+		opts.FlightRecorder = n
+	}
+}
+
+// WithMessageSummary sets the function that summarizes the messages in the
+// flight recorder of the actor, see WithFlightRecorder. Keep the summaries
+// short, and leave out what must not end up in the logs.
+func WithMessageSummary(fn func(msg any) string) OptFunc {
+	return func(opts *Opts) {
+		opts.MessageSummary = fn
+	}
+}
+
+// LastMessages returns the last messages the local process with the given PID
+// handled, oldest first, if it was spawned with a flight recorder, see
+// WithFlightRecorder.
+func (e *Engine) LastMessages(pid *PID) []MessageRecord {
+	p, ok := e.Registry.get(pid).(*process)
+	if !ok || p.recorder == nil {
+		return nil
+	}
+	return p.recorder.Items()
+}
+
+// record records the given message in the flight recorder of the process, and
+// returns the function that records how long the actor took to handle it.
+func (p *process) record(msg Envelope) func() {
+	record := MessageRecord{
+		Type:      fmt.Sprintf("%T", msg.Msg),
+		Sender:    msg.Sender,
+		Timestamp: time.Now(),
+	}
+	if p.Opts.MessageSummary != nil {
+		record.Summary = p.Opts.MessageSummary(msg.Msg)
+	}
+	return func() {
+		record.Duration = time.Since(record.Timestamp)
+		p.recorder.Push(record)
+	}
+}
+
+// lastMessages returns the records of the flight recorder of the process, or
+// nil without one.
+func (p *process) lastMessages() []MessageRecord {
+	if p.recorder == nil {
+		return nil
+	}
+	return p.recorder.Items()
+}
+
+func newRecorder(n int) *ringbuffer.RingBuffer[MessageRecord] {
+	if n <= 0 {
+		return nil
+	}
+	return ringbuffer.NewOverwrite[MessageRecord](int64(n))
+}
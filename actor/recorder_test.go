@@ -0,0 +1,59 @@
+package actor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type crash struct{}
+
+func TestFlightRecorder(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	restarted := make(chan ActorRestartedEvent, 1)
+	sub := SubscribeTyped(e, func(event ActorRestartedEvent) { restarted <- event })
+	defer sub.Unsubscribe()
+
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(crash); ok {
+			panic("crash")
+		}
+	}, "recorded", WithFlightRecorder(3), WithRestartDelay(time.Millisecond),
+		WithMessageSummary(func(msg any) string { return fmt.Sprint(msg) }))
+	sender := NewPID(e.Address(), "sender")
+	for i := range 5 {
+		e.SendWithSender(pid, i, sender)
+	}
+	e.Send(pid, crash{})
+
+	event := <-restarted
+	require.Len(t, event.LastMessages, 3)
+	assert.Equal(t, "int", event.LastMessages[0].Type)
+	assert.Equal(t, "3", event.LastMessages[0].Summary)
+	assert.Equal(t, sender, event.LastMessages[0].Sender)
+	assert.Equal(t, "4", event.LastMessages[1].Summary)
+	assert.Equal(t, "actor.crash", event.LastMessages[2].Type)
+	assert.Nil(t, event.LastMessages[2].Sender)
+	assert.False(t, event.LastMessages[2].Timestamp.IsZero())
+
+	_, _, attrs := event.Log()
+	assert.Equal(t, []any{"last_message", "actor.crash"}, attrs[len(attrs)-2:])
+	assert.Equal(t, event.LastMessages, e.LastMessages(pid))
+}
+
+func TestWithoutFlightRecorder(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			c.Respond("ok")
+		}
+	}, "unrecorded")
+	_, err = e.Request(pid, "hi", time.Second).Result()
+	require.NoError(t, err)
+	assert.Nil(t, e.LastMessages(pid))
+}
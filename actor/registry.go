@@ -57,6 +57,42 @@ func (r *Registry) getByID(id string) Processer {
 	return r.lookup[id]
 }
 
+// backlog returns the number of messages that wait in the inboxes of the
+// processes, counting the inboxes that tell their length.
+func (r *Registry) backlog() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n := 0
+	for _, proc := range r.lookup {
+		if p, ok := proc.(*process); ok {
+			n += p.info().MailboxLen
+		}
+	}
+	return n
+}
+
+func (r *Registry) processes() []ProcessInfo {
+	procs := r.locals()
+	infos := make([]ProcessInfo, len(procs))
+	for i, p := range procs {
+		infos[i] = p.info()
+	}
+	return infos
+}
+
+// locals returns the local processes, in no particular order.
+func (r *Registry) locals() []*process {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	procs := make([]*process, 0, len(r.lookup))
+	for _, proc := range r.lookup {
+		if p, ok := proc.(*process); ok {
+			procs = append(procs, p)
+		}
+	}
+	return procs
+}
+
 func (r *Registry) add(proc Processer) {
 	r.mu.Lock()
 	id := proc.PID().ID
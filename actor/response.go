@@ -2,12 +2,17 @@ package actor
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math"
 	"math/rand"
 	"strconv"
+	"strings"
 	"time"
 )
 
+const responseKind = "response"
+
 type Response struct {
 	engine  *Engine
 	pid     *PID
@@ -20,10 +25,14 @@ func NewResponse(e *Engine, timeout time.Duration) *Response {
 		engine:  e,
 		result:  make(chan any, 1),
 		timeout: timeout,
-		pid:     NewPID(e.address, "response"+pidSeparator+strconv.Itoa(rand.Intn(math.MaxInt32))),
+		pid:     NewPID(e.address, responseKind+pidSeparator+strconv.Itoa(rand.Intn(math.MaxInt32))),
 	}
 }
 
+// Result blocks until the response is received or the timeout is exceeded.
+// If the receiver responded with an error, or panicked while handling the
+// request, the error is returned. For requests to remote receivers the error
+// is a *ResponseError.
 func (r *Response) Result() (any, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
 	defer func() {
@@ -33,6 +42,9 @@ func (r *Response) Result() (any, error) {
 
 	select {
 	case resp := <-r.result:
+		if err, ok := resp.(error); ok {
+			return nil, err
+		}
 		return resp, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
@@ -40,11 +52,12 @@ func (r *Response) Result() (any, error) {
 }
 
 func (r *Response) Send(_ *PID, msg any, _ *PID) {
+	msg, _ = untrace(msg)
 	r.result <- msg
 }
 
-func (r *Response) SendPriority(_ *PID, msg any, _ *PID) {
-	r.result <- msg
+func (r *Response) SendPriority(pid *PID, msg any, sender *PID) {
+	r.Send(pid, msg, sender)
 }
 
 func (r *Response) PID() *PID         { return r.pid }
@@ -52,3 +65,43 @@ func (r *Response) Shutdown()         {}
 func (r *Response) Start()            {}
 func (r *Response) Invoke([]Envelope) {}
 func (r *Response) ClearMailbox()     {}
+
+// panicErrorType is the type of the ResponseError that is sent when the
+// receiver panics while handling a request.
+const panicErrorType = "panic"
+
+// NewResponseError creates a ResponseError from the given error, which can be
+// sent over the wire. If the error has a Code() int32 method, its code is
+// included.
+func NewResponseError(err error) *ResponseError {
+	if rerr, ok := err.(*ResponseError); ok {
+		return rerr
+	}
+	re := &ResponseError{
+		Type:    fmt.Sprintf("%T", err),
+		Message: err.Error(),
+	}
+	var coder interface{ Code() int32 }
+	if errors.As(err, &coder) {
+		re.Code = coder.Code()
+	}
+	return re
+}
+
+func (e *ResponseError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("%s (%s, code %d)", e.Message, e.Type, e.Code)
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, e.Type)
+}
+
+// Panicked reports whether the receiver panicked while handling the request.
+func (e *ResponseError) Panicked() bool {
+	return e.Type == panicErrorType
+}
+
+// IsResponsePID returns whether the given PID is the one of a Response, which
+// the requests are sent with, see Engine.Request.
+func IsResponsePID(pid *PID) bool {
+	return pid != nil && strings.HasPrefix(pid.ID, responseKind+pidSeparator)
+}
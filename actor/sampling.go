@@ -0,0 +1,107 @@
+package actor
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"strings"
+)
+
+// Sampler decides which of the messages the actors handle are observed, so
+// the tracing and the metrics can run in production at a fraction of their
+// cost, see EngineConfig.WithTraceSampler and the Config of the metrics
+// package. A message is sampled with the ratio of its type, or else of the
+// kind of its actor, or else the ratio of the sampler, which makes allowlists
+// and denylists:
+//
+//	// Trace 1% of the messages, all the orders and none of the heartbeats,
+//	// and the messages the actors crash handling.
+//	actor.NewSampler().WithRatio(0.01).WithKindRatio("orders", 1).
+//		WithTypeRatio(Heartbeat{}, 0).WithErrors(true)
+//
+// The zero value samples none of the messages.
+type Sampler struct {
+	ratio  float64
+	kinds  map[string]float64
+	types  map[reflect.Type]float64
+	errors bool
+}
+
+// NewSampler returns a Sampler that samples all the messages.
+func NewSampler() Sampler {
+	return Sampler{ratio: 1}
+}
+
+// WithRatio sets the ratio of the messages that are sampled, between 0 and 1,
+// which are picked at random.
+//
+// Defaults to 1, which samples all the messages.
+func (s Sampler) WithRatio(ratio float64) Sampler {
+	s.ratio = ratio
+	return s
+}
+
+// WithKindRatio sets the ratio of the messages that are sampled for the
+// actors of the given kind, with the children by the name they were spawned
+// with.
+func (s Sampler) WithKindRatio(kind string, ratio float64) Sampler {
+	s.kinds = cloneRatios(s.kinds)
+	s.kinds[kind] = ratio
+	return s
+}
+
+// WithTypeRatio sets the ratio of the messages of the type of the given
+// message that are sampled, whichever actor handles them.
+func (s Sampler) WithTypeRatio(msg any, ratio float64) Sampler {
+	s.types = cloneRatios(s.types)
+	s.types[reflect.TypeOf(msg)] = ratio
+	return s
+}
+
+// WithErrors sets whether the spans of the messages that were not sampled are
+// traced anyway when the actor crashes handling them. Their spans are started
+// once the actor crashed, see Span.Crash, so WithRatio(0).WithErrors(true)
+// traces the crashes at the cost of nothing else. It only applies to the
+// tracing.
+//
+// Defaults to false.
+func (s Sampler) WithErrors(enabled bool) Sampler {
+	s.errors = enabled
+	return s
+}
+
+// Sample returns whether the given message that the actor of the given kind
+// handles is sampled.
+func (s Sampler) Sample(kind string, msg any) bool {
+	ratio := s.ratio
+	if r, ok := s.kinds[kind]; ok {
+		ratio = r
+	}
+	if len(s.types) > 0 {
+		if r, ok := s.types[reflect.TypeOf(msg)]; ok {
+			ratio = r
+		}
+	}
+	switch {
+	case ratio >= 1:
+		return true
+	case ratio <= 0:
+		return false
+	}
+	return rand.Float64() < ratio
+}
+
+// cloneRatios returns a copy of the given ratios, so the samplers that were
+// derived from the same one don't share them.
+func cloneRatios[K comparable](ratios map[K]float64) map[K]float64 {
+	clone := make(map[K]float64, len(ratios)+1)
+	for k, v := range ratios {
+		clone[k] = v
+	}
+	return clone
+}
+
+// kindName returns the last segment of the given kind of an actor, which is
+// the name a child was spawned with.
+func kindName(kind string) string {
+	return kind[strings.LastIndex(kind, pidSeparator)+1:]
+}
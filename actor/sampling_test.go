@@ -0,0 +1,70 @@
+package actor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampler(t *testing.T) {
+	assert.True(t, NewSampler().Sample("foo", "hi"))
+	assert.False(t, Sampler{}.Sample("foo", "hi"))
+
+	base := NewSampler().WithRatio(0).WithKindRatio("orders", 1)
+	s := base.WithTypeRatio("", 0)
+	assert.True(t, s.Sample("orders", 1))
+	// The type takes precedence over the kind.
+	assert.False(t, s.Sample("orders", "hi"))
+	assert.False(t, s.Sample("payments", 1))
+	// The derived samplers don't share their ratios.
+	assert.True(t, base.Sample("orders", "hi"))
+
+	s = NewSampler().WithRatio(0.5)
+	var sampled int
+	for range 1000 {
+		if s.Sample("foo", "hi") {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 500, sampled, 100)
+}
+
+func TestTraceSampler(t *testing.T) {
+	tracer := &testTracer{}
+	sampler := NewSampler().WithRatio(0).WithTypeRatio("", 1).WithErrors(true)
+	e, err := NewEngine(NewEngineConfig().WithTracer(tracer).WithTraceSampler(sampler))
+	require.NoError(t, err)
+
+	done := make(chan struct{}, 3)
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case string, int:
+			done <- struct{}{}
+		case bool:
+			panic(msg)
+		}
+	}, "foo")
+	e.Send(pid, "sampled")
+	e.Send(pid, 1)
+	e.Send(pid, TracedMessage{Message: 2, Trace: TraceContext{"span": "root", "trace": "root"}})
+	for range 3 {
+		<-done
+	}
+	_, ok := tracer.span("sampled")
+	assert.True(t, ok)
+	_, ok = tracer.span(1)
+	assert.False(t, ok)
+	// The messages of a trace are traced regardless.
+	s, ok := tracer.span(2)
+	assert.True(t, ok)
+	assert.False(t, s.Time.IsZero())
+
+	// The crashes are traced once the actor crashed.
+	e.Send(pid, true)
+	assert.Eventually(t, func() bool {
+		s, ok := tracer.span(true)
+		return ok && s.Crash == true && !s.Time.IsZero()
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,83 @@
+package actor
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/pprof"
+	"strings"
+	"time"
+)
+
+// WithSlowHandlerThreshold sets how long the actor may take to handle a
+// message before it's reported with a SlowHandlerEvent, with the stack of its
+// goroutine at that time, so the place the handler spends its time in is
+// found without a profiler. A negative threshold does not report the actor.
+//
+// Defaults to the threshold of the engine, see
+// EngineConfig.WithSlowHandlerThreshold.
+func WithSlowHandlerThreshold(d time.Duration) OptFunc {
+	return func(opts *Opts) {
+		opts.SlowHandlerThreshold = d
+	}
+}
+
+// watchdog returns the timer that reports the message with a SlowHandlerEvent
+// if the actor still handles it once the threshold elapsed, which is stopped
+// once the message is handled.
+func (p *process) watchdog(msg Envelope, start time.Time, threshold time.Duration) *time.Timer {
+	seq := p.messages.Load()
+	return time.AfterFunc(threshold, func() {
+		stack := p.stack()
+		// Don't report the stack of the next message.
+		if p.messages.Load() != seq {
+			return
+		}
+		p.context.engine.BroadcastEvent(SlowHandlerEvent{
+			PID:        p.pid,
+			Sender:     msg.Sender,
+			Message:    msg.Msg,
+			Timestamp:  start,
+			Elapsed:    time.Since(start),
+			Stacktrace: stack,
+		})
+	})
+}
+
+// stack returns the stack of the goroutine of the process while it handles a
+// message, which is found in the goroutine profile by the labels of the
+// process, see Invoke. It returns nil if the goroutine is not found.
+func (p *process) stack() []byte {
+	var profile bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&profile, 1); err != nil {
+		return nil
+	}
+	id, kind := fmt.Sprintf("%q:%q", "actor_id", p.Opts.ID), fmt.Sprintf("%q:%q", "actor_kind", p.Opts.Kind)
+	for _, block := range strings.Split(profile.String(), "\n\n") {
+		// The goroutines the actor started have its labels too.
+		if !strings.Contains(block, "(*process).invokeMsg") {
+			continue
+		}
+		for _, line := range strings.Split(block, "\n") {
+			if strings.HasPrefix(line, "# labels: ") && strings.Contains(line, id) && strings.Contains(line, kind) {
+				return stackFrames(block)
+			}
+		}
+	}
+	return nil
+}
+
+// stackFrames returns the frames of a stack of the goroutine profile in the
+// format of cleanTrace.
+func stackFrames(block string) []byte {
+	var buf bytes.Buffer
+	for _, line := range strings.Split(block, "\n") {
+		// #	0x4e1457	main.work+0x17		/tmp/main.go:10
+		fields := strings.Fields(line)
+		if !strings.HasPrefix(line, "#\t") || len(fields) < 4 {
+			continue
+		}
+		fn, _, _ := strings.Cut(fields[2], "+0x")
+		fmt.Fprintf(&buf, "%s\n\t%s\n", fn, strings.Join(fields[3:], " "))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,54 @@
+package actor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sleepHandler sleeps for the duration of the message.
+func sleepHandler(c *Context) {
+	if d, ok := c.Message().(time.Duration); ok {
+		time.Sleep(d)
+		c.Respond(d)
+	}
+}
+
+func TestSlowHandler(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig().WithSlowHandlerThreshold(50 * time.Millisecond))
+	require.NoError(t, err)
+	events := make(chan SlowHandlerEvent, 10)
+	sub := SubscribeTyped(e, func(event SlowHandlerEvent) { events <- event })
+	defer sub.Unsubscribe()
+
+	pid := e.SpawnFunc(sleepHandler, "sleeper", WithID("1"))
+	quiet := e.SpawnFunc(sleepHandler, "quiet", WithSlowHandlerThreshold(-1))
+	for _, d := range []time.Duration{0, 100 * time.Millisecond} {
+		_, err := e.Request(pid, d, time.Second).Result()
+		require.NoError(t, err)
+		_, err = e.Request(quiet, d, time.Second).Result()
+		require.NoError(t, err)
+	}
+
+	select {
+	case event := <-events:
+		assert.Equal(t, pid, event.PID)
+		assert.Equal(t, 100*time.Millisecond, event.Message)
+		assert.True(t, IsResponsePID(event.Sender))
+		assert.GreaterOrEqual(t, event.Elapsed, 50*time.Millisecond)
+		// The stack is the one of the goroutine of the actor while it
+		// handled the message.
+		assert.Contains(t, string(event.Stacktrace), "time.Sleep\n")
+		assert.Contains(t, string(event.Stacktrace), "actor.sleepHandler\n")
+		assert.Contains(t, string(event.Stacktrace), "slow_test.go:")
+	case <-time.After(time.Second):
+		t.Fatal("no slow handler event")
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected slow handler event of %s", event.PID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,137 @@
+package actor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// systemKind is the kind of the actors the engine spawns itself.
+	systemKind = "system"
+	spawnerID  = "spawner"
+)
+
+// spawnRemoteTimeout is the time we wait for a remote engine to spawn the
+// requested actor.
+var spawnRemoteTimeout = 5 * time.Second
+
+// kinds holds the producers that are registered with RegisterKind.
+type kinds struct {
+	mu        sync.RWMutex
+	producers map[string]Producer
+}
+
+func (k *kinds) register(kind string, p Producer) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.producers == nil {
+		k.producers = make(map[string]Producer)
+	}
+	k.producers[kind] = p
+}
+
+func (k *kinds) get(kind string) (Producer, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	p, ok := k.producers[kind]
+	return p, ok
+}
+
+// RegisterKind registers the Producer of the given kind, so the actor can be
+// spawned by other engines with SpawnRemote.
+func (e *Engine) RegisterKind(kind string, p Producer) {
+	e.kinds.register(kind, p)
+}
+
+// SpawnRemote spawns an actor of the given kind with the given name on the
+// engine that is listening on the given address. The kind needs to be
+// registered on that engine with RegisterKind. If the name is empty a random
+// one is generated.
+//
+// Only the inbox size, the maximum number of restarts and the restart delay
+// are sent along with the request, other options are ignored.
+func (e *Engine) SpawnRemote(address, kind, name string, opts ...OptFunc) (*PID, error) {
+	options := DefaultOpts(nil)
+	for _, opt := range opts {
+		opt(&options)
+	}
+	req := &SpawnRequest{
+		Kind:         kind,
+		ID:           name,
+		InboxSize:    int32(options.InboxSize),
+		MaxRestarts:  options.MaxRestarts,
+		RestartDelay: int64(options.RestartDelay),
+	}
+	if address == e.address {
+		return e.spawnKind(req)
+	}
+	if e.remote == nil {
+		return nil, fmt.Errorf("can't spawn on %s: engine has no remote", address)
+	}
+	resp, err := e.Request(NewPID(address, systemKind+pidSeparator+spawnerID), req, spawnRemoteTimeout).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to spawn %s on %s: %w", kind, address, err)
+	}
+	res, ok := resp.(*SpawnResponse)
+	if !ok {
+		return nil, fmt.Errorf("failed to spawn %s on %s: unexpected response %T", kind, address, resp)
+	}
+	if len(res.Error) > 0 {
+		return nil, errors.New(res.Error)
+	}
+	return res.Pid, nil
+}
+
+// spawnKind spawns the actor requested by a SpawnRequest.
+func (e *Engine) spawnKind(req *SpawnRequest) (*PID, error) {
+	p, ok := e.kinds.get(req.Kind)
+	if !ok {
+		return nil, fmt.Errorf("kind %s is not registered on %s", req.Kind, e.address)
+	}
+	id := req.ID
+	if len(id) == 0 {
+		id = strconv.Itoa(rand.Intn(math.MaxInt))
+	}
+	if pid := e.Registry.GetPID(req.Kind, id); pid != nil {
+		return nil, fmt.Errorf("actor %s already exists", pid)
+	}
+	opts := []OptFunc{WithID(id)}
+	if req.InboxSize > 0 {
+		opts = append(opts, WithInboxSize(int(req.InboxSize)))
+	}
+	if req.MaxRestarts > 0 {
+		opts = append(opts, WithMaxRestarts(int(req.MaxRestarts)))
+	}
+	if req.RestartDelay > 0 {
+		opts = append(opts, WithRestartDelay(time.Duration(req.RestartDelay)))
+	}
+	return e.Spawn(p, req.Kind, opts...), nil
+}
+
+// spawner spawns the actors that are requested by other engines.
+type spawner struct {
+	engine *Engine
+}
+
+func newSpawner(e *Engine) Producer {
+	return func() Receiver {
+		return &spawner{engine: e}
+	}
+}
+
+func (s *spawner) Receive(c *Context) {
+	switch msg := c.Message().(type) {
+	case *SpawnRequest:
+		pid, err := s.engine.spawnKind(msg)
+		if err != nil {
+			c.Respond(&SpawnResponse{Error: err.Error()})
+			return
+		}
+		c.Respond(&SpawnResponse{Pid: pid})
+	}
+}
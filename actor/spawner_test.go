@@ -0,0 +1,36 @@
+package actor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpawnRemoteLocalAddress(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	e.RegisterKind("foo", NewTestProducer(t, func(t *testing.T, c *Context) {}))
+
+	pid, err := e.SpawnRemote(e.Address(), "foo", "1", WithInboxSize(16))
+	require.NoError(t, err)
+	assert.Equal(t, "foo/1", pid.ID)
+	assert.NotNil(t, e.Registry.get(pid))
+
+	_, err = e.SpawnRemote(e.Address(), "foo", "1")
+	assert.Error(t, err)
+
+	_, err = e.SpawnRemote(e.Address(), "bar", "1")
+	assert.Error(t, err)
+
+	pid, err = e.SpawnRemote(e.Address(), "foo", "")
+	require.NoError(t, err)
+	assert.NotEqual(t, "foo/", pid.ID)
+}
+
+func TestSpawnRemoteWithoutRemote(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	_, err = e.SpawnRemote("127.0.0.1:4000", "foo", "1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package actor
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the local engine, see Engine.Stats.
+type Stats struct {
+	// Time is when the snapshot was taken.
+	Time time.Time
+	// Actors is the number of local actors, and ActorsByKind their number
+	// by kind, with the children by the name they were spawned with.
+	Actors       int
+	ActorsByKind map[string]int
+	// MailboxBacklog is the number of messages that wait in the inboxes of
+	// the local actors, see Engine.MailboxBacklog.
+	MailboxBacklog int
+	// Messages is the number of messages the local actors handled since the
+	// engine was created, and MessageRate the number of messages they handled
+	// per second over the interval since the previous snapshot, or since the
+	// engine was created for the first one.
+	Messages    uint64
+	MessageRate float64
+	Interval    time.Duration
+	// DeadLetters is the number of messages that could not be delivered
+	// since the engine was created, see DeadLetterEvent.
+	DeadLetters uint64
+	// Peers are the peers of the remote of the engine, sorted by address,
+	// none without a remote.
+	Peers []PeerStats
+}
+
+// PeerStats is the traffic between the remote of an engine and one of its
+// peers.
+type PeerStats struct {
+	Address          string
+	MessagesSent     uint64
+	MessagesReceived uint64
+	// QueueDepth is the number of messages that wait to be sent to the peer.
+	QueueDepth int64
+}
+
+// engineStats holds the counters of the stats of an engine.
+type engineStats struct {
+	// stopped is the number of messages the processes that stopped handled.
+	stopped     atomic.Uint64
+	deadLetters atomic.Uint64
+
+	mu       sync.Mutex
+	last     time.Time
+	messages uint64
+}
+
+// Stats returns a snapshot of the local engine, to expose it however the
+// application likes. The message rate is measured between two snapshots, so
+// take them at a regular interval from a single place, like the handler of a
+// status page.
+func (e *Engine) Stats() Stats {
+	procs := e.Registry.locals()
+	stats := Stats{
+		Time:         time.Now(),
+		Actors:       len(procs),
+		ActorsByKind: make(map[string]int),
+		Messages:     e.stats.stopped.Load(),
+		DeadLetters:  e.stats.deadLetters.Load(),
+	}
+	for _, p := range procs {
+		info := p.info()
+		stats.ActorsByKind[kindName(info.Kind)]++
+		stats.MailboxBacklog += info.MailboxLen
+		stats.Messages += info.Messages
+	}
+	if r, ok := e.remote.(interface{ PeerStats() []PeerStats }); ok {
+		stats.Peers = r.PeerStats()
+	}
+
+	e.stats.mu.Lock()
+	defer e.stats.mu.Unlock()
+	stats.Interval = stats.Time.Sub(e.stats.last)
+	// The messages of a process that stops are counted once it's gone.
+	if stats.Messages > e.stats.messages && stats.Interval > 0 {
+		stats.MessageRate = float64(stats.Messages-e.stats.messages) / stats.Interval.Seconds()
+	}
+	e.stats.last, e.stats.messages = stats.Time, stats.Messages
+	return stats
+}
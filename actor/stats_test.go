@@ -0,0 +1,53 @@
+package actor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	echo := func(c *Context) {
+		if msg, ok := c.Message().(string); ok {
+			c.Respond(msg)
+		}
+	}
+	a := e.SpawnFunc(echo, "echo")
+	e.SpawnFunc(echo, "echo")
+	b := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(Started); ok {
+			c.SpawnChildFunc(echo, "child")
+		}
+	}, "parent")
+	for range 4 {
+		_, err := e.Request(a, "hi", time.Second).Result()
+		require.NoError(t, err)
+	}
+	e.Stats()
+
+	time.Sleep(10 * time.Millisecond)
+	for range 2 {
+		_, err := e.Request(a, "hi", time.Second).Result()
+		require.NoError(t, err)
+	}
+	<-e.Poison(a).Done()
+	e.Send(a, "gone")
+	<-e.Poison(b).Done()
+
+	stats := e.Stats()
+	// The event stream is an actor too.
+	assert.Equal(t, map[string]int{"echo": 1, "eventstream": 1}, stats.ActorsByKind)
+	assert.Equal(t, 2, stats.Actors)
+	// The messages of the stopped actors still count, besides the ones of
+	// the event stream.
+	assert.Equal(t, uint64(6), e.stats.stopped.Load())
+	assert.Greater(t, stats.Messages, uint64(6))
+	assert.GreaterOrEqual(t, stats.Interval, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, stats.MessageRate, 2/stats.Interval.Seconds())
+	assert.Equal(t, uint64(1), stats.DeadLetters)
+	assert.Empty(t, stats.Peers)
+}
@@ -0,0 +1,226 @@
+package actor
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	streamWriterKind       = "streamwriter"
+	defaultStreamChunkSize = 64 * 1024
+	defaultStreamWindow    = 16
+	defaultStreamTimeout   = 30 * time.Second
+)
+
+var (
+	// ErrStreamClosed is returned when writing to a closed StreamWriter.
+	ErrStreamClosed = errors.New("stream closed")
+	// ErrStreamTimeout is returned when the receiver of a stream did not
+	// acknowledge the chunks in time.
+	ErrStreamTimeout = errors.New("stream timed out waiting for the receiver")
+)
+
+type StreamOpts struct {
+	// ChunkSize is the maximum number of bytes in a single StreamChunk. It
+	// defaults to 64KB if it's not positive.
+	ChunkSize int
+	// Window is the number of chunks that can be sent before they are
+	// acknowledged by the receiver. Writes block once the window is full.
+	// It defaults to 16 chunks if it's not positive.
+	Window int
+	// Timeout is the maximum time a write waits for the receiver to
+	// acknowledge a chunk.
+	Timeout time.Duration
+}
+
+type StreamOptFunc func(*StreamOpts)
+
+func WithStreamChunkSize(size int) StreamOptFunc {
+	return func(opts *StreamOpts) {
+		opts.ChunkSize = size
+	}
+}
+
+func WithStreamWindow(chunks int) StreamOptFunc {
+	return func(opts *StreamOpts) {
+		opts.Window = chunks
+	}
+}
+
+func WithStreamTimeout(d time.Duration) StreamOptFunc {
+	return func(opts *StreamOpts) {
+		opts.Timeout = d
+	}
+}
+
+// StreamWriter is an io.WriteCloser that sends the written data to an actor,
+// local or remote, as a sequence of StreamChunk messages followed by a
+// StreamEOF. The receiver acknowledges every chunk it processed; a write
+// blocks while the window of unacknowledged chunks is full, so a large
+// transfer never outpaces the receiver.
+type StreamWriter struct {
+	engine  *Engine
+	pid     *PID
+	target  *PID
+	opts    StreamOpts
+	credits chan struct{}
+
+	// mu serializes the writes and guards seq and closed.
+	mu     sync.Mutex
+	seq    uint64
+	closed bool
+	// acked is the seq of the last acknowledged chunk. It has a lock of its
+	// own, since the acks arrive while a write holds mu.
+	ackMu sync.Mutex
+	acked uint64
+}
+
+func newStreamWriter(e *Engine, target *PID, opts ...StreamOptFunc) *StreamWriter {
+	o := StreamOpts{
+		ChunkSize: defaultStreamChunkSize,
+		Window:    defaultStreamWindow,
+		Timeout:   defaultStreamTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// A write would never end with empty chunks, nor start without a
+	// window.
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = defaultStreamChunkSize
+	}
+	if o.Window <= 0 {
+		o.Window = defaultStreamWindow
+	}
+	s := &StreamWriter{
+		engine:  e,
+		pid:     NewPID(e.address, streamWriterKind+pidSeparator+strconv.Itoa(rand.Intn(math.MaxInt32))),
+		target:  target,
+		opts:    o,
+		credits: make(chan struct{}, o.Window),
+	}
+	e.Registry.add(s)
+	return s
+}
+
+// OpenStream opens a stream to the actor with the given pid.
+func (e *Engine) OpenStream(pid *PID, opts ...StreamOptFunc) *StreamWriter {
+	return newStreamWriter(e, pid, opts...)
+}
+
+// Write splits p in chunks and sends them to the receiver of the stream. It
+// blocks until all chunks are sent, or returns ErrStreamTimeout if the
+// receiver doesn't acknowledge them in time.
+func (s *StreamWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, ErrStreamClosed
+	}
+	n := 0
+	for n < len(p) {
+		size := min(len(p)-n, s.opts.ChunkSize)
+		if err := s.acquire(); err != nil {
+			return n, err
+		}
+		// The data is copied, since local receivers get the chunk as is
+		// and the caller may reuse p.
+		data := make([]byte, size)
+		copy(data, p[n:n+size])
+		s.seq++
+		s.engine.SendWithSender(s.target, &StreamChunk{Stream: s.pid, Seq: s.seq, Data: data}, s.pid)
+		n += size
+	}
+	return n, nil
+}
+
+// Close waits until the receiver acknowledged all chunks and sends the
+// StreamEOF.
+func (s *StreamWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStreamClosed
+	}
+	// Once we hold all credits, every chunk is acknowledged.
+	var err error
+	for i := 0; i < s.opts.Window; i++ {
+		if err = s.acquire(); err != nil {
+			break
+		}
+	}
+	s.closeLocked(err)
+	return err
+}
+
+// CloseWithError aborts the stream. The receiver gets a StreamEOF with the
+// given error.
+func (s *StreamWriter) CloseWithError(err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStreamClosed
+	}
+	s.closeLocked(err)
+	return nil
+}
+
+func (s *StreamWriter) closeLocked(err error) {
+	s.closed = true
+	eof := &StreamEOF{Stream: s.pid, Seq: s.seq}
+	if err != nil {
+		eof.Error = err.Error()
+	}
+	s.engine.SendWithSender(s.target, eof, s.pid)
+	s.engine.Registry.Remove(s.pid)
+}
+
+// acquire takes a credit for a chunk, waiting for an acknowledgement if
+// the window is full.
+func (s *StreamWriter) acquire() error {
+	select {
+	case s.credits <- struct{}{}:
+		return nil
+	default:
+	}
+	timer := time.NewTimer(s.opts.Timeout)
+	defer timer.Stop()
+	select {
+	case s.credits <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrStreamTimeout
+	}
+}
+
+func (s *StreamWriter) ack(seq uint64) {
+	s.ackMu.Lock()
+	defer s.ackMu.Unlock()
+	for ; s.acked < seq; s.acked++ {
+		select {
+		case <-s.credits:
+		default:
+		}
+	}
+}
+
+func (s *StreamWriter) Send(_ *PID, msg any, _ *PID) {
+	msg, _ = untrace(msg)
+	if ack, ok := msg.(*StreamAck); ok {
+		s.ack(ack.Seq)
+	}
+}
+
+func (s *StreamWriter) SendPriority(pid *PID, msg any, sender *PID) {
+	s.Send(pid, msg, sender)
+}
+
+func (s *StreamWriter) PID() *PID         { return s.pid }
+func (s *StreamWriter) Shutdown()         {}
+func (s *StreamWriter) Start()            {}
+func (s *StreamWriter) Invoke([]Envelope) {}
+func (s *StreamWriter) ClearMailbox()     {}
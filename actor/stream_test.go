@@ -0,0 +1,127 @@
+package actor
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamReceiver collects the data of a stream until its EOF.
+func streamReceiver(done chan<- *StreamEOF, buf *bytes.Buffer, delay time.Duration) func(*Context) {
+	return func(c *Context) {
+		switch msg := c.Message().(type) {
+		case *StreamChunk:
+			time.Sleep(delay)
+			buf.Write(msg.Data)
+		case *StreamEOF:
+			done <- msg
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+
+	var (
+		buf  bytes.Buffer
+		done = make(chan *StreamEOF, 1)
+	)
+	pid := e.SpawnFunc(streamReceiver(done, &buf, 0), "receiver")
+
+	data := make([]byte, 100_000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	s := e.OpenStream(pid, WithStreamChunkSize(1000), WithStreamWindow(4))
+	n, err := s.Write(data)
+	require.NoError(t, err)
+	assert.Equal(t, len(data), n)
+	require.NoError(t, s.Close())
+
+	eof := <-done
+	assert.Empty(t, eof.Error)
+	assert.Equal(t, uint64(100), eof.Seq)
+	assert.Equal(t, data, buf.Bytes())
+
+	_, err = s.Write(data)
+	assert.ErrorIs(t, err, ErrStreamClosed)
+}
+
+// The writer cannot get further ahead of a slow receiver than the window.
+func TestStreamBackpressure(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+
+	var (
+		buf  bytes.Buffer
+		done = make(chan *StreamEOF, 1)
+	)
+	pid := e.SpawnFunc(streamReceiver(done, &buf, 10*time.Millisecond), "receiver")
+
+	s := e.OpenStream(pid, WithStreamChunkSize(1), WithStreamWindow(2))
+	start := time.Now()
+	_, err = s.Write([]byte("abcdef"))
+	require.NoError(t, err)
+	// The first two chunks fit in the window, the others wait for acks.
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	require.NoError(t, s.Close())
+	<-done
+	assert.Equal(t, "abcdef", buf.String())
+}
+
+// The sizes that are not positive are replaced with the defaults.
+func TestStreamInvalidOpts(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+
+	var (
+		buf  bytes.Buffer
+		done = make(chan *StreamEOF, 1)
+	)
+	pid := e.SpawnFunc(streamReceiver(done, &buf, 0), "receiver")
+
+	s := e.OpenStream(pid, WithStreamChunkSize(0), WithStreamWindow(-1))
+	assert.Equal(t, defaultStreamChunkSize, s.opts.ChunkSize)
+	assert.Equal(t, defaultStreamWindow, s.opts.Window)
+	_, err = s.Write([]byte("abc"))
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+	<-done
+	assert.Equal(t, "abc", buf.String())
+}
+
+func TestStreamTimeout(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+
+	// The chunks go to an actor that doesn't exist, so they are never acknowledged.
+	s := e.OpenStream(NewPID(e.Address(), "foo"), WithStreamChunkSize(1), WithStreamWindow(1), WithStreamTimeout(20*time.Millisecond))
+	n, err := s.Write([]byte("abc"))
+	assert.ErrorIs(t, err, ErrStreamTimeout)
+	assert.Equal(t, 1, n)
+}
+
+func TestStreamCloseWithError(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+
+	var (
+		buf  bytes.Buffer
+		done = make(chan *StreamEOF, 1)
+	)
+	pid := e.SpawnFunc(streamReceiver(done, &buf, 0), "receiver")
+
+	s := e.OpenStream(pid)
+	_, err = s.Write([]byte("foo"))
+	require.NoError(t, err)
+	require.NoError(t, s.CloseWithError(errors.New("aborted")))
+
+	eof := <-done
+	assert.Equal(t, "aborted", eof.Error)
+	assert.Equal(t, uint64(1), eof.Seq)
+}
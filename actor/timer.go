@@ -0,0 +1,292 @@
+package actor
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// timerRetryDelay is how long a timer waits for its local target to be
+// spawned, when it fires before it is, like after a restart.
+const timerRetryDelay = 100 * time.Millisecond
+
+// ErrNoTimerStore is returned by the durable timers of an engine that has no
+// TimerStore, see EngineConfig.WithTimerStore.
+var ErrNoTimerStore = errors.New("no timer store configured")
+
+// DurableTimer is a message that is scheduled with Engine.ScheduleDurable.
+type DurableTimer struct {
+	ID      string
+	Target  *PID
+	At      time.Time
+	Message any
+}
+
+// TimerStore persists the durable timers of an engine, see
+// EngineConfig.WithTimerStore.
+type TimerStore interface {
+	// Save stores the timer, or replaces the one with the same ID.
+	Save(timer DurableTimer) error
+	// Delete removes the timer with the given ID, if there is one.
+	Delete(id string) error
+	// Load returns all the timers of the store.
+	Load() ([]DurableTimer, error)
+}
+
+// fileTimer is a DurableTimer in a FileTimerStore.
+type fileTimer struct {
+	ID      string
+	Address string
+	PID     string
+	At      time.Time
+	Message any
+}
+
+// FileTimerStore is a TimerStore that keeps the timers in a file, which is
+// replaced as a whole on every change, so it suits a moderate number of
+// timers. The messages are encoded like the events of a FileJournal.
+type FileTimerStore struct {
+	mu     sync.Mutex
+	path   string
+	timers map[string]fileTimer
+}
+
+// OpenFileTimerStore opens the store in the file with the given path, which
+// is created on the first change if it does not exist.
+func OpenFileTimerStore(path string) (*FileTimerStore, error) {
+	s := &FileTimerStore{path: path, timers: make(map[string]fileTimer)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var timers []fileTimer
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&timers); err != nil {
+		return nil, fmt.Errorf("failed to decode timers: %w", err)
+	}
+	for _, timer := range timers {
+		s.timers[timer.ID] = timer
+	}
+	return s, nil
+}
+
+// Save implements TimerStore.
+func (s *FileTimerStore) Save(timer DurableTimer) error {
+	msg := timer.Message
+	if name, b, err := EncodeEvent(msg); err == nil {
+		msg = RawEvent{Name: name, Data: b}
+	} else if !errors.Is(err, ErrUnregisteredEvent) {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.timers[timer.ID]
+	s.timers[timer.ID] = fileTimer{
+		ID:      timer.ID,
+		Address: timer.Target.Address,
+		PID:     timer.Target.ID,
+		At:      timer.At,
+		Message: msg,
+	}
+	err := s.write()
+	if err != nil {
+		if ok {
+			s.timers[timer.ID] = prev
+		} else {
+			delete(s.timers, timer.ID)
+		}
+	}
+	return err
+}
+
+// Delete implements TimerStore.
+func (s *FileTimerStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.timers[id]
+	if !ok {
+		return nil
+	}
+	delete(s.timers, id)
+	err := s.write()
+	if err != nil {
+		s.timers[id] = prev
+	}
+	return err
+}
+
+// Load implements TimerStore.
+func (s *FileTimerStore) Load() ([]DurableTimer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	timers := make([]DurableTimer, 0, len(s.timers))
+	for _, timer := range s.timers {
+		msg := timer.Message
+		if raw, ok := msg.(RawEvent); ok {
+			event, err := DecodeEvent(raw.Name, raw.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode the message of timer %s: %w", timer.ID, err)
+			}
+			msg = event
+		}
+		timers = append(timers, DurableTimer{
+			ID:      timer.ID,
+			Target:  NewPID(timer.Address, timer.PID),
+			At:      timer.At,
+			Message: msg,
+		})
+	}
+	return timers, nil
+}
+
+// write replaces the file with the timers, through a temporary file that is
+// renamed, so a crash leaves either the old or the new timers.
+func (s *FileTimerStore) write() error {
+	timers := make([]fileTimer, 0, len(s.timers))
+	for _, timer := range s.timers {
+		timers = append(timers, timer)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(timers); err != nil {
+		return fmt.Errorf("failed to encode timers: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// durableTimers fires the timers of the store of an engine.
+type durableTimers struct {
+	engine *Engine
+	store  TimerStore
+
+	mu sync.Mutex
+	// pending holds the timers that did not fire yet, by ID.
+	pending map[string]*time.Timer
+}
+
+func newDurableTimers(e *Engine, store TimerStore) *durableTimers {
+	return &durableTimers{engine: e, store: store, pending: make(map[string]*time.Timer)}
+}
+
+// start arms the timers of the store. The timers whose time passed while the
+// engine was not running fire right away.
+func (t *durableTimers) start() error {
+	timers, err := t.store.Load()
+	if err != nil {
+		return err
+	}
+	for _, timer := range timers {
+		t.arm(timer, time.Until(timer.At))
+	}
+	return nil
+}
+
+func (t *durableTimers) arm(timer DurableTimer, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[timer.ID] = time.AfterFunc(d, func() { t.fire(timer) })
+}
+
+// fire sends the message of the timer and deletes it from the store. The
+// message is sent again after a restart if the engine stops before the timer
+// is deleted.
+func (t *durableTimers) fire(timer DurableTimer) {
+	e := t.engine
+	t.mu.Lock()
+	if _, ok := t.pending[timer.ID]; !ok {
+		// The timer was cancelled.
+		t.mu.Unlock()
+		return
+	}
+	if e.isLocalMessage(timer.Target) && e.Registry.get(timer.Target) == nil {
+		t.pending[timer.ID] = time.AfterFunc(timerRetryDelay, func() { t.fire(timer) })
+		t.mu.Unlock()
+		return
+	}
+	delete(t.pending, timer.ID)
+	t.mu.Unlock()
+	e.Send(timer.Target, timer.Message)
+	if err := t.store.Delete(timer.ID); err != nil {
+		e.Logger().Warn("failed to delete fired timer", "err", err, "id", timer.ID)
+	}
+}
+
+// ScheduleDurable sends the message to the given PID at the given time, even
+// if the engine is restarted in between, with the TimerStore of the engine.
+// It returns the ID of the timer, which can be cancelled with CancelDurable.
+//
+// The message is sent at least once. It may be sent again if the engine stops
+// right after it was sent. A timer whose local target is not spawned, like
+// after a restart, fires once the target is. The message needs to be
+// encodable by the store, which for a FileTimerStore means that its type is
+// registered with RegisterEvent or gob.Register.
+func (e *Engine) ScheduleDurable(pid *PID, msg any, at time.Time) (string, error) {
+	if e.timers == nil {
+		return "", ErrNoTimerStore
+	}
+	timer := DurableTimer{
+		ID:      fmt.Sprintf("%016x", rand.Uint64()),
+		Target:  pid.CloneVT(),
+		At:      at,
+		Message: msg,
+	}
+	if err := e.timers.store.Save(timer); err != nil {
+		return "", fmt.Errorf("failed to save timer: %w", err)
+	}
+	e.timers.arm(timer, time.Until(at))
+	return timer.ID, nil
+}
+
+// DurableTimers returns the timers that did not fire yet, by time.
+func (e *Engine) DurableTimers() ([]DurableTimer, error) {
+	if e.timers == nil {
+		return nil, ErrNoTimerStore
+	}
+	timers, err := e.timers.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(timers, func(i, j int) bool {
+		return timers[i].At.Before(timers[j].At)
+	})
+	return timers, nil
+}
+
+// CancelDurable cancels the timer with the given ID, if it did not fire yet.
+func (e *Engine) CancelDurable(id string) error {
+	if e.timers == nil {
+		return ErrNoTimerStore
+	}
+	t := e.timers
+	t.mu.Lock()
+	if timer, ok := t.pending[id]; ok {
+		timer.Stop()
+		delete(t.pending, id)
+	}
+	t.mu.Unlock()
+	return t.store.Delete(id)
+}
@@ -0,0 +1,91 @@
+package actor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spawnOrderReceiver(e *Engine, ch chan orderPlaced) *PID {
+	return e.SpawnFunc(func(c *Context) {
+		if msg, ok := c.Message().(orderPlaced); ok {
+			ch <- msg
+		}
+	}, "orders", WithID("1"))
+}
+
+func TestScheduleDurable(t *testing.T) {
+	store, err := OpenFileTimerStore(filepath.Join(t.TempDir(), "timers"))
+	require.NoError(t, err)
+	e, err := NewEngine(NewEngineConfig().WithTimerStore(store))
+	require.NoError(t, err)
+	ch := make(chan orderPlaced, 1)
+	pid := spawnOrderReceiver(e, ch)
+
+	_, err = e.ScheduleDurable(pid, orderPlaced{ID: 1}, time.Now().Add(20*time.Millisecond))
+	require.NoError(t, err)
+	select {
+	case msg := <-ch:
+		assert.Equal(t, orderPlaced{ID: 1}, msg)
+	case <-time.After(time.Second):
+		t.Fatal("timer did not fire")
+	}
+	assert.Eventually(t, func() bool {
+		timers, err := e.DurableTimers()
+		return err == nil && len(timers) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	id, err := e.ScheduleDurable(pid, orderPlaced{ID: 2}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	timers, err := e.DurableTimers()
+	require.NoError(t, err)
+	require.Len(t, timers, 1)
+	assert.Equal(t, id, timers[0].ID)
+	assert.Equal(t, orderPlaced{ID: 2}, timers[0].Message)
+	require.NoError(t, e.CancelDurable(id))
+	timers, err = e.DurableTimers()
+	require.NoError(t, err)
+	assert.Empty(t, timers)
+
+	e, err = NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	_, err = e.ScheduleDurable(pid, orderPlaced{}, time.Now())
+	assert.ErrorIs(t, err, ErrNoTimerStore)
+}
+
+func TestScheduleDurableRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timers")
+	store, err := OpenFileTimerStore(path)
+	require.NoError(t, err)
+	// The timer was due while the process was not running.
+	require.NoError(t, store.Save(DurableTimer{
+		ID:      "1",
+		Target:  NewPID(LocalLookupAddr, "orders/1"),
+		At:      time.Now().Add(-time.Minute),
+		Message: orderPlaced{ID: 3},
+	}))
+
+	store, err = OpenFileTimerStore(path)
+	require.NoError(t, err)
+	e, err := NewEngine(NewEngineConfig().WithTimerStore(store))
+	require.NoError(t, err)
+	// The timer fires once its target is spawned.
+	time.Sleep(2 * timerRetryDelay)
+	ch := make(chan orderPlaced, 1)
+	spawnOrderReceiver(e, ch)
+	select {
+	case msg := <-ch:
+		assert.Equal(t, orderPlaced{ID: 3}, msg)
+	case <-time.After(time.Second):
+		t.Fatal("timer did not fire")
+	}
+	assert.Eventually(t, func() bool {
+		store, err := OpenFileTimerStore(path)
+		require.NoError(t, err)
+		timers, err := store.Load()
+		return err == nil && len(timers) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,136 @@
+package actor
+
+import (
+	"context"
+	"time"
+)
+
+// TraceContext is the context of the trace a message belongs to, as the
+// key/value pairs of a propagator, like the "traceparent" header of the W3C
+// trace context. It's sent along with the message, over the network too.
+type TraceContext map[string]string
+
+// TracedMessage is a message that is sent with the trace context of its
+// sender, so the actor handles it as a part of the same trace. The actors send
+// their messages as traced messages by themselves once the engine has a
+// tracer, see EngineConfig.WithTracer. The code outside of the actors sends
+// one to trace the actors as a part of its own trace:
+//
+//	resp := e.Request(pid, actor.TracedMessage{Message: msg, Trace: tracer.Inject(ctx)}, time.Second)
+//
+// The receiver gets the message itself.
+type TracedMessage struct {
+	Message any
+	Trace   TraceContext
+}
+
+// Span describes a message an actor handles, see Tracer.
+type Span struct {
+	PID     *PID
+	Sender  *PID
+	Message any
+	// Parent is the trace context the message was sent with, nil if it was
+	// sent outside of a trace.
+	Parent TraceContext
+	// Request is set when the message is a request, see Engine.Request,
+	// which the actor answers with Context.Respond. The response carries the
+	// trace context of the span.
+	Request bool
+	// Time is when the actor started handling the message.
+	Time time.Time
+	// Crash is the value the actor panicked with when the span is started
+	// after the actor crashed handling a message that was not sampled, see
+	// Sampler.WithErrors. The span is ended right away, so it starts at Time.
+	Crash any
+}
+
+// Tracer traces the messages the actors handle, see EngineConfig.WithTracer.
+// It bridges the engine to a tracing library like OpenTelemetry, whose tracer
+// starts the spans and whose propagator carries their context:
+//
+//	func (t otelTracer) Start(ctx context.Context, span actor.Span) (context.Context, func()) {
+//		ctx = t.propagator.Extract(ctx, propagation.MapCarrier(span.Parent))
+//		ctx, s := t.tracer.Start(ctx, reflect.TypeOf(span.Message).String(), trace.WithTimestamp(span.Time),
+//			trace.WithAttributes(attribute.String("actor.pid", span.PID.String())))
+//		return ctx, func() { s.End() }
+//	}
+//
+//	func (t otelTracer) Inject(ctx context.Context) actor.TraceContext {
+//		carrier := propagation.MapCarrier{}
+//		t.propagator.Inject(ctx, carrier)
+//		return actor.TraceContext(carrier)
+//	}
+type Tracer interface {
+	// Start starts the span of a message. The returned context is the
+	// context of the actor while it handles the message, see
+	// Context.Context. end is invoked once the message is handled, or once
+	// the actor crashed handling it.
+	Start(ctx context.Context, span Span) (_ context.Context, end func())
+	// Inject returns the trace context of the given context of an actor,
+	// which is sent along with the messages the actor sends while it
+	// handles the message. Nothing is sent when it's empty.
+	Inject(ctx context.Context) TraceContext
+}
+
+// untrace returns the message of a TracedMessage and its trace context, other
+// messages as they are.
+func untrace(msg any) (any, TraceContext) {
+	if m, ok := msg.(TracedMessage); ok {
+		return m.Message, m.Trace
+	}
+	return msg, nil
+}
+
+// newSpan returns the span of the given message, which the actor started
+// handling at the given time.
+func (c *Context) newSpan(msg Envelope, start time.Time) Span {
+	return Span{
+		PID:     c.pid,
+		Sender:  msg.Sender,
+		Message: msg.Msg,
+		Parent:  msg.Trace,
+		Request: IsResponsePID(msg.Sender),
+		Time:    start,
+	}
+}
+
+// sampled returns whether the given message is traced. The messages with a
+// trace context are, so the traces stay whole.
+func (c *Context) sampled(msg Envelope) bool {
+	sampler := c.engine.traceSampler
+	return sampler == nil || len(msg.Trace) > 0 || sampler.Sample(kindName(c.kind), msg.Msg)
+}
+
+// startSpan starts the span of the given message, and returns the function
+// that ends it.
+func (c *Context) startSpan(tracer Tracer, msg Envelope, start time.Time) func() {
+	base := c.context
+	ctx, end := tracer.Start(base, c.newSpan(msg, start))
+	c.context, c.trace = ctx, tracer.Inject(ctx)
+	return func() {
+		end()
+		c.context, c.trace = base, nil
+	}
+}
+
+// traceCrash traces the message that was not sampled that the actor crashed
+// handling with the given value, if any.
+func (c *Context) traceCrash(v any) {
+	span := c.untraced
+	if span.PID == nil {
+		return
+	}
+	c.untraced = Span{}
+	span.Crash = v
+	_, end := c.engine.tracer.Start(c.context, span)
+	end()
+}
+
+// traced returns the given message as a TracedMessage when the actor handles
+// a traced message.
+func (c *Context) traced(msg any) any {
+	if len(c.trace) == 0 {
+		return msg
+	}
+	return TracedMessage{Message: msg, Trace: c.trace}
+}
@@ -0,0 +1,114 @@
+package actor
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type spanKey struct{}
+
+type testSpan struct {
+	Span
+	id, trace string
+}
+
+// testTracer numbers the spans, and propagates their number and the number of
+// the first span of their trace.
+type testTracer struct {
+	mu    sync.Mutex
+	spans []testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, span Span) (context.Context, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := testSpan{Span: span, id: strconv.Itoa(len(t.spans) + 1)}
+	s.trace = span.Parent["trace"]
+	if s.trace == "" {
+		s.trace = s.id
+	}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, spanKey{}, s), func() {}
+}
+
+func (t *testTracer) Inject(ctx context.Context) TraceContext {
+	s := ctx.Value(spanKey{}).(testSpan)
+	return TraceContext{"span": s.id, "trace": s.trace}
+}
+
+// span returns the span of the given message.
+func (t *testTracer) span(msg any) (testSpan, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range t.spans {
+		if s.Message == msg {
+			return s, true
+		}
+	}
+	return testSpan{}, false
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &testTracer{}
+	e, err := NewEngine(NewEngineConfig().WithTracer(tracer))
+	require.NoError(t, err)
+
+	b := e.SpawnFunc(func(c *Context) {
+		if msg, ok := c.Message().(string); ok && msg == "ping" {
+			c.Respond("pong")
+		}
+	}, "b")
+	a := e.SpawnFunc(func(c *Context) {
+		if msg, ok := c.Message().(string); ok && msg == "start" {
+			assert.NotNil(t, c.Context().Value(spanKey{}))
+			res, err := c.Request(b, "ping", time.Second).Result()
+			assert.NoError(t, err)
+			c.Respond(res)
+		}
+	}, "a")
+
+	root := TraceContext{"span": "root", "trace": "root"}
+	res, err := e.Request(a, TracedMessage{Message: "start", Trace: root}, time.Second).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "pong", res)
+
+	start, ok := tracer.span("start")
+	require.True(t, ok)
+	assert.Equal(t, root, start.Parent)
+	assert.True(t, start.Request)
+	assert.Equal(t, a, start.PID)
+	ping, ok := tracer.span("ping")
+	require.True(t, ok)
+	assert.Equal(t, TraceContext{"span": start.id, "trace": "root"}, ping.Parent)
+	assert.True(t, ping.Request)
+	assert.Equal(t, b, ping.PID)
+
+	// The messages sent outside of a trace start one.
+	e.Send(b, "alone")
+	assert.Eventually(t, func() bool {
+		s, ok := tracer.span("alone")
+		return ok && s.Parent == nil && s.trace == s.id
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTracedDeadLetter(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	events := make(chan DeadLetterEvent, 1)
+	sub := SubscribeTyped(e, func(event DeadLetterEvent) { events <- event })
+	defer sub.Unsubscribe()
+
+	e.Send(NewPID(LocalLookupAddr, "nobody"), TracedMessage{Message: "hi", Trace: TraceContext{"span": "1"}})
+	select {
+	case event := <-events:
+		assert.Equal(t, "hi", event.Message)
+	case <-time.After(time.Second):
+		t.Fatal("no deadletter")
+	}
+}
@@ -11,6 +11,9 @@ type poisonPill struct {
 	cancel   context.CancelFunc
 	graceful bool
 }
+
+// restartActor makes the actor crash, see Engine.Restart.
+type restartActor struct{}
 type Initialized struct{}
 type Started struct{}
 type Stopped struct{}
@@ -0,0 +1,129 @@
+package actor
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Usage is an estimate of the resources a local actor uses, see
+// Engine.TopUsage.
+type Usage struct {
+	PID  *PID
+	Kind string
+	// CPUTime is the time the receiver of the actor took to handle its
+	// messages, its middleware included. It's measured around the calls of
+	// Receive, so the time the actor blocked counts too, which makes it an
+	// upper bound of the CPU time of the actor.
+	CPUTime  time.Duration
+	Messages uint64
+	// MailboxBytes is an estimate of the size of the messages that wait in
+	// the inbox of the actor: the size of the wire format of the protobuf
+	// messages, the length of strings and byte slices, and the size of the
+	// type of the other messages, without what they point to.
+	MailboxBytes int
+	// StateBytes is the size of the state of the actor of WithStateSize,
+	// zero without one.
+	StateBytes int
+}
+
+// Memory returns the memory the actor retains, its mailbox and its state.
+func (u Usage) Memory() int {
+	return u.MailboxBytes + u.StateBytes
+}
+
+// UsageOrder is the order of the report of Engine.TopUsage.
+type UsageOrder int
+
+const (
+	// ByCPUTime orders the actors by their CPU time, see Usage.CPUTime.
+	ByCPUTime UsageOrder = iota
+	// ByMemory orders the actors by the memory they retain, see
+	// Usage.Memory.
+	ByMemory
+)
+
+// WithStateSize sets the function that returns the size of the state of the
+// receiver of the actor in bytes, see Usage.StateBytes. It's invoked by the
+// actor after each batch of messages it handled, so it needs to be fast.
+//
+//	e.Spawn(newCache, "cache", actor.WithStateSize(func(r actor.Receiver) int {
+//		return r.(*cache).bytes
+//	}))
+func WithStateSize(fn func(Receiver) int) OptFunc {
+	return func(opts *Opts) {
+		opts.StateSize = fn
+	}
+}
+
+// Usage returns the estimate of the resources the local process with the
+// given PID uses, and false if there is no such process.
+func (e *Engine) Usage(pid *PID) (Usage, bool) {
+	p, ok := e.Registry.get(pid).(*process)
+	if !ok {
+		return Usage{}, false
+	}
+	return p.usage(), true
+}
+
+// TopUsage returns the n local actors that use the most of the resources of
+// the engine in the given order, the most first, to find the handful of actors
+// that burn the node. The CPU time adds up since the actors were spawned, so
+// compare two reports to see the actors that are busy now. Estimating the
+// mailboxes goes through all the messages that wait in them, so don't report
+// too often on an engine with deep mailboxes.
+func (e *Engine) TopUsage(n int, order UsageOrder) []Usage {
+	procs := e.Registry.locals()
+	usages := make([]Usage, len(procs))
+	for i, p := range procs {
+		usages[i] = p.usage()
+	}
+	slices.SortFunc(usages, func(a, b Usage) int {
+		if order == ByMemory {
+			return cmp.Compare(b.Memory(), a.Memory())
+		}
+		return cmp.Compare(b.CPUTime, a.CPUTime)
+	})
+	return usages[:min(n, len(usages))]
+}
+
+func (p *process) usage() Usage {
+	u := Usage{
+		PID:        p.pid,
+		Kind:       p.Opts.Kind,
+		CPUTime:    time.Duration(p.busy.Load()),
+		Messages:   p.messages.Load(),
+		StateBytes: int(p.stateSize.Load()),
+	}
+	if inbox, ok := p.inbox.(interface{ envelopes() []Envelope }); ok {
+		for _, env := range inbox.envelopes() {
+			u.MailboxBytes += sizeOf(env.Msg)
+		}
+	}
+	return u
+}
+
+// sizeOf returns an estimate of the size of the given message, see
+// Usage.MailboxBytes.
+func sizeOf(msg any) int {
+	switch msg := msg.(type) {
+	case nil:
+		return 0
+	case interface{ SizeVT() int }:
+		return msg.SizeVT()
+	case proto.Message:
+		return proto.Size(msg)
+	case []byte:
+		return len(msg)
+	case string:
+		return len(msg)
+	}
+	t := reflect.TypeOf(msg)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return int(t.Size())
+}
@@ -0,0 +1,76 @@
+package actor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type hog struct {
+	state []byte
+}
+
+func (h *hog) Receive(c *Context) {
+	switch msg := c.Message().(type) {
+	case time.Duration:
+		time.Sleep(msg)
+	case []byte:
+		h.state = append(h.state, msg...)
+	case chan struct{}:
+		<-msg
+	}
+}
+
+func TestTopUsage(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	busy := e.Spawn(func() Receiver { return &hog{} }, "busy")
+	big := e.Spawn(func() Receiver { return &hog{} }, "big", WithStateSize(func(r Receiver) int {
+		return len(r.(*hog).state)
+	}))
+	idle := e.Spawn(func() Receiver { return &hog{} }, "idle")
+
+	e.Send(busy, 20*time.Millisecond)
+	e.Send(big, make([]byte, 1000))
+	// Block the idle actor so the messages stay in its mailbox.
+	release := make(chan struct{})
+	e.Send(idle, release)
+	e.Send(idle, make([]byte, 300))
+	e.Send(idle, "hello")
+	defer close(release)
+
+	require.Eventually(t, func() bool {
+		u, _ := e.Usage(busy)
+		return u.Messages == 1 && u.CPUTime >= 20*time.Millisecond
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		u, _ := e.Usage(big)
+		return u.StateBytes == 1000
+	}, time.Second, 10*time.Millisecond)
+
+	top := e.TopUsage(1, ByCPUTime)
+	require.Len(t, top, 1)
+	assert.Equal(t, busy, top[0].PID)
+	assert.Equal(t, "busy", top[0].Kind)
+
+	top = e.TopUsage(2, ByMemory)
+	require.Len(t, top, 2)
+	assert.Equal(t, big, top[0].PID)
+	assert.Equal(t, 1000, top[0].Memory())
+	assert.Equal(t, idle, top[1].PID)
+	// The channel it blocks on counts for the size of its type.
+	assert.Equal(t, 8+300+5, top[1].MailboxBytes)
+
+	assert.Len(t, e.TopUsage(100, ByCPUTime), len(e.Processes()))
+}
+
+func TestSizeOf(t *testing.T) {
+	assert.Equal(t, 0, sizeOf(nil))
+	assert.Equal(t, 3, sizeOf("foo"))
+	assert.Equal(t, 8, sizeOf(int64(1)))
+	assert.Equal(t, 16, sizeOf(&struct{ a, b int64 }{}))
+	pid := NewPID("local", "foo/1")
+	assert.Equal(t, pid.SizeVT(), sizeOf(pid))
+}
@@ -0,0 +1,89 @@
+package actor
+
+import "sync"
+
+const watcherID = "watcher"
+
+type watchKey struct {
+	target  uint64
+	watcher uint64
+}
+
+type watch struct {
+	target  *PID
+	watcher *PID
+}
+
+// remoteWatches keeps track of the local actors that are watching actors on
+// other engines, so they can be notified when such an engine becomes
+// unreachable.
+type remoteWatches struct {
+	mu sync.Mutex
+	// watches is a map of remote address to the watches of the actors on it.
+	watches map[string]map[watchKey]watch
+}
+
+func (w *remoteWatches) add(target, watcher *PID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watches == nil {
+		w.watches = make(map[string]map[watchKey]watch)
+	}
+	watches, ok := w.watches[target.Address]
+	if !ok {
+		watches = make(map[watchKey]watch)
+		w.watches[target.Address] = watches
+	}
+	watches[watchKey{target.LookupKey(), watcher.LookupKey()}] = watch{target: target, watcher: watcher}
+}
+
+func (w *remoteWatches) remove(target, watcher *PID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	watches, ok := w.watches[target.Address]
+	if !ok {
+		return
+	}
+	delete(watches, watchKey{target.LookupKey(), watcher.LookupKey()})
+	if len(watches) == 0 {
+		delete(w.watches, target.Address)
+	}
+}
+
+// removeAddress removes and returns all the watches of actors on the given address.
+func (w *remoteWatches) removeAddress(address string) []watch {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	watches := w.watches[address]
+	delete(w.watches, address)
+	res := make([]watch, 0, len(watches))
+	for _, w := range watches {
+		res = append(res, w)
+	}
+	return res
+}
+
+// watcher notifies the local watchers of remote actors with a Terminated
+// message when the engine of those actors becomes unreachable.
+type watcher struct {
+	engine *Engine
+}
+
+func newWatcher(e *Engine) Producer {
+	return func() Receiver {
+		return &watcher{engine: e}
+	}
+}
+
+func (w *watcher) Receive(c *Context) {
+	switch msg := c.Message().(type) {
+	case Started:
+		c.engine.Subscribe(c.pid)
+	case Stopped:
+		c.engine.Unsubscribe(c.pid)
+	case RemoteUnreachableEvent:
+		for _, watch := range w.engine.remoteWatches.removeAddress(msg.ListenAddr) {
+			w.engine.Send(watch.watcher, &Terminated{Pid: watch.target, Reason: TerminatedReason_Unreachable})
+		}
+	}
+}
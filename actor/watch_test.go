@@ -0,0 +1,86 @@
+package actor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+
+	target := e.SpawnFunc(func(c *Context) {}, "target")
+	terminated := make(chan *Terminated, 1)
+	e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			c.Watch(target)
+		case *Terminated:
+			terminated <- msg
+		}
+	}, "watcher")
+	time.Sleep(10 * time.Millisecond)
+
+	<-e.Poison(target).Done()
+	select {
+	case msg := <-terminated:
+		assert.True(t, msg.Pid.Equals(target))
+		assert.Equal(t, TerminatedReason_Stopped, msg.Reason)
+	case <-time.After(time.Second):
+		t.Fatal("expected a Terminated message")
+	}
+}
+
+func TestWatchNotFound(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+
+	terminated := make(chan *Terminated, 1)
+	target := NewPID(e.Address(), "foo/bar")
+	e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			c.Watch(target)
+		case *Terminated:
+			terminated <- msg
+		}
+	}, "watcher")
+
+	select {
+	case msg := <-terminated:
+		assert.True(t, msg.Pid.Equals(target))
+		assert.Equal(t, TerminatedReason_NotFound, msg.Reason)
+	case <-time.After(time.Second):
+		t.Fatal("expected a Terminated message")
+	}
+}
+
+func TestUnwatch(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+
+	target := e.SpawnFunc(func(c *Context) {}, "target")
+	terminated := make(chan *Terminated, 1)
+	watcher := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			c.Watch(target)
+		case string:
+			c.Unwatch(target)
+		case *Terminated:
+			terminated <- msg
+		}
+	}, "watcher")
+	e.Send(watcher, "unwatch")
+	time.Sleep(10 * time.Millisecond)
+
+	<-e.Poison(target).Done()
+	select {
+	case <-terminated:
+		t.Fatal("expected no Terminated message after unwatching")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
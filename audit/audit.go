@@ -0,0 +1,284 @@
+// Package audit writes an append-only audit record of each message an actor
+// handles to a Sink: who sent what to whom, with the headers of the message,
+// when and with which outcome. The sensitive fields are redacted before the
+// records are written.
+//
+//	sink := audit.NewJSONSink(file)
+//	pid := e.Spawn(newAccounts, "accounts", actor.WithMiddleware(audit.Middleware(sink, audit.NewConfig().
+//		WithRedactedHeaders("authorization"))))
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+// Redacted is the value the redacted fields and headers are replaced with.
+const Redacted = "[REDACTED]"
+
+// Outcome is the outcome of the handling of a message.
+type Outcome string
+
+const (
+	// OutcomeHandled is the outcome of the messages the actor handled.
+	OutcomeHandled Outcome = "handled"
+	// OutcomeCrashed is the outcome of the messages the actor crashed
+	// handling.
+	OutcomeCrashed Outcome = "crashed"
+)
+
+// Record is the audit record of a message an actor handled.
+type Record struct {
+	// Time is when the actor started handling the message, and Duration how
+	// long it took.
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	// Sender is nil if the message was sent without a sender.
+	Sender   *actor.PID `json:"sender,omitempty"`
+	Receiver *actor.PID `json:"receiver"`
+	Type     string     `json:"type"`
+	// Message is the message once it was redacted, see Config.WithRedactor.
+	Message any `json:"message"`
+	// Headers are the trace context of the message, see actor.TracedMessage,
+	// once they were redacted, see Config.WithRedactedHeaders.
+	Headers map[string]string `json:"headers,omitempty"`
+	Outcome Outcome           `json:"outcome"`
+	// Error is the value the actor panicked with if it crashed, and Stack
+	// the stack of the goroutine where it panicked.
+	Error string `json:"error,omitempty"`
+	Stack string `json:"stack,omitempty"`
+}
+
+// Sink stores the audit records. It's invoked by the actors, concurrently, so
+// it needs to be safe for concurrent use, and fast, as the actor waits for it
+// before it handles its next message.
+type Sink interface {
+	// Write appends the record to the sink.
+	Write(record Record) error
+}
+
+// SinkFunc is a function that is a Sink.
+type SinkFunc func(record Record) error
+
+func (fn SinkFunc) Write(record Record) error {
+	return fn(record)
+}
+
+// JSONSink writes the records to a writer as JSON, one per line.
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONSink returns a JSONSink that writes to the given writer, like a file
+// opened with os.O_APPEND, which it does not close.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+func (s *JSONSink) Write(record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(record)
+}
+
+// Redactor returns the message as it's recorded, with its sensitive fields
+// redacted, or the message itself if it has none. It must not modify the
+// message, which the actor handles.
+type Redactor func(msg any) any
+
+// RedactType returns the Redactor that redacts the messages of type T with
+// the given function, and leaves the others as they are.
+//
+//	audit.RedactType(func(msg *Login) any { return &Login{User: msg.User, Password: audit.Redacted} })
+func RedactType[T any](fn func(msg T) any) Redactor {
+	return func(msg any) any {
+		if m, ok := msg.(T); ok {
+			return fn(m)
+		}
+		return msg
+	}
+}
+
+// Config holds the configuration of the audit middleware.
+type Config struct {
+	redactors []Redactor
+	headers   map[string]bool
+	filter    func(msg any) bool
+}
+
+// NewConfig returns a Config that records all the messages but the ones of
+// the lifecycle of the actors, and redacts the fields of the structs tagged
+// with `audit:"redact"`.
+func NewConfig() Config {
+	return Config{}
+}
+
+// WithRedactor adds the given redactors, which are applied to the messages in
+// order, after the fields tagged with `audit:"redact"` were redacted.
+func (config Config) WithRedactor(redactors ...Redactor) Config {
+	config.redactors = append(config.redactors[:len(config.redactors):len(config.redactors)], redactors...)
+	return config
+}
+
+// WithRedactedHeaders sets the keys of the headers whose values are redacted,
+// whatever their case.
+//
+// Defaults to none.
+func (config Config) WithRedactedHeaders(keys ...string) Config {
+	config.headers = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		config.headers[strings.ToLower(key)] = true
+	}
+	return config
+}
+
+// WithFilter sets the function that returns whether a message is recorded.
+//
+// Defaults to recording all the messages but actor.Initialized, actor.Started
+// and actor.Stopped.
+func (config Config) WithFilter(fn func(msg any) bool) Config {
+	config.filter = fn
+	return config
+}
+
+// Middleware returns the middleware that writes an audit record of each
+// message the actor handles to the sink, see actor.WithMiddleware. The record
+// is written once the actor handled the message, or crashed handling it. The
+// errors of the sink are logged with the logger of the actor.
+func Middleware(sink Sink, config Config) actor.MiddlewareFunc {
+	filter := config.filter
+	if filter == nil {
+		filter = lifecycle
+	}
+	return func(next actor.ReceiveFunc) actor.ReceiveFunc {
+		return func(c *actor.Context) {
+			msg := c.Message()
+			if !filter(msg) {
+				next(c)
+				return
+			}
+			record := Record{
+				Time:     time.Now(),
+				Sender:   c.Sender(),
+				Receiver: c.PID(),
+				Type:     fmt.Sprintf("%T", msg),
+				Message:  config.redact(msg),
+				Headers:  config.redactHeaders(c.TraceParent()),
+				Outcome:  OutcomeHandled,
+			}
+			defer func() {
+				record.Duration = time.Since(record.Time)
+				v := recover()
+				if v != nil {
+					// The stack is lost once the actor panics again.
+					record.Outcome, record.Error, record.Stack = OutcomeCrashed, fmt.Sprint(v), string(debug.Stack())
+				}
+				if err := sink.Write(record); err != nil {
+					c.Logger().Error("failed to write audit record", "type", record.Type, "err", err)
+				}
+				// Let the actor crash once the record was written.
+				if v != nil {
+					panic(v)
+				}
+			}()
+			next(c)
+		}
+	}
+}
+
+// lifecycle returns whether the message is not a message of the lifecycle of
+// the actor.
+func lifecycle(msg any) bool {
+	switch msg.(type) {
+	case actor.Initialized, actor.Started, actor.Stopped:
+		return false
+	}
+	return true
+}
+
+func (config Config) redact(msg any) any {
+	msg = redactFields(msg)
+	for _, redactor := range config.redactors {
+		msg = redactor(msg)
+	}
+	return msg
+}
+
+func (config Config) redactHeaders(headers actor.TraceContext) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	redacted := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if config.headers[strings.ToLower(k)] {
+			v = Redacted
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// redactFields returns a copy of the given struct, or pointer to a struct,
+// with the fields tagged with `audit:"redact"` redacted: the strings are
+// replaced with Redacted, and the other fields with their zero values. Other
+// messages and the structs without tagged fields are returned as they are.
+// Only the fields of the struct itself are redacted, not the ones of the
+// structs it holds.
+func redactFields(msg any) any {
+	v := reflect.ValueOf(msg)
+	ptr := v.Kind() == reflect.Pointer
+	if ptr {
+		if v.IsNil() {
+			return msg
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || !hasRedactedFields(v.Type()) {
+		return msg
+	}
+	cp := reflect.New(v.Type()).Elem()
+	cp.Set(v)
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if field.Tag.Get("audit") != "redact" || !field.IsExported() {
+			continue
+		}
+		f := cp.Field(i)
+		if f.Kind() == reflect.String {
+			f.SetString(Redacted)
+		} else {
+			f.SetZero()
+		}
+	}
+	if ptr {
+		return cp.Addr().Interface()
+	}
+	return cp.Interface()
+}
+
+// redactedTypes caches whether the struct types have fields to redact.
+var redactedTypes sync.Map
+
+func hasRedactedFields(t reflect.Type) bool {
+	if has, ok := redactedTypes.Load(t); ok {
+		return has.(bool)
+	}
+	has := false
+	for i := range t.NumField() {
+		if t.Field(i).Tag.Get("audit") == "redact" && t.Field(i).IsExported() {
+			has = true
+			break
+		}
+	}
+	redactedTypes.Store(t, has)
+	return has
+}
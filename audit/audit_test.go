@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type login struct {
+	User     string
+	Password string `audit:"redact"`
+	Attempts int    `audit:"redact"`
+}
+
+type transfer struct {
+	From, To string
+	Amount   int
+}
+
+type crash struct{}
+
+// memorySink keeps the records.
+type memorySink struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func (s *memorySink) Write(record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *memorySink) get() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Record(nil), s.records...)
+}
+
+func receive(c *actor.Context) {
+	switch c.Message().(type) {
+	case *login, transfer:
+		c.Respond("ok")
+	case crash:
+		panic("boom")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	sink := &memorySink{}
+	config := NewConfig().WithRedactedHeaders("authorization").WithRedactor(RedactType(func(msg transfer) any {
+		msg.To = Redacted
+		return msg
+	}))
+	pid := e.SpawnFunc(receive, "bank", actor.WithID("1"), actor.WithMiddleware(Middleware(sink, config)))
+
+	msg := &login{User: "alice", Password: "secret", Attempts: 3}
+	resp := e.Request(pid, actor.TracedMessage{Message: msg, Trace: actor.TraceContext{
+		"traceparent": "00-1-2-01", "Authorization": "Bearer token",
+	}}, time.Second)
+	_, err = resp.Result()
+	require.NoError(t, err)
+	_, err = e.Request(pid, transfer{From: "alice", To: "bob", Amount: 10}, time.Second).Result()
+	require.NoError(t, err)
+	e.Send(pid, crash{})
+
+	var records []Record
+	require.Eventually(t, func() bool {
+		records = sink.get()
+		return len(records) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	// The lifecycle messages are not recorded.
+	assert.Equal(t, resp.PID(), records[0].Sender)
+	assert.Equal(t, pid, records[0].Receiver)
+	assert.Equal(t, "*audit.login", records[0].Type)
+	assert.Equal(t, &login{User: "alice", Password: Redacted}, records[0].Message)
+	// The headers are redacted whatever the case of their keys.
+	assert.Equal(t, map[string]string{"traceparent": "00-1-2-01", "Authorization": Redacted}, records[0].Headers)
+	assert.Equal(t, OutcomeHandled, records[0].Outcome)
+	assert.False(t, records[0].Time.IsZero())
+	// The message the actor handled was not redacted.
+	assert.Equal(t, "secret", msg.Password)
+
+	assert.Equal(t, transfer{From: "alice", To: Redacted, Amount: 10}, records[1].Message)
+	assert.Nil(t, records[1].Headers)
+
+	assert.Nil(t, records[2].Sender)
+	assert.Equal(t, OutcomeCrashed, records[2].Outcome)
+	assert.Equal(t, "boom", records[2].Error)
+	// The stack is the one of the panic.
+	assert.Contains(t, records[2].Stack, "audit.receive")
+	assert.Empty(t, records[0].Stack)
+}
+
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONSink(&buf)
+	pid := actor.NewPID("local", "bank/1")
+	for _, outcome := range []Outcome{OutcomeHandled, OutcomeCrashed} {
+		require.NoError(t, sink.Write(Record{Receiver: pid, Type: "audit.transfer", Message: transfer{Amount: 1}, Outcome: outcome}))
+	}
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(lines[1], &record))
+	assert.Equal(t, "crashed", record["outcome"])
+	assert.Equal(t, map[string]any{"From": "", "To": "", "Amount": 1.0}, record["message"])
+	assert.NotContains(t, record, "sender")
+}
+
+func TestSinkError(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	sink := SinkFunc(func(Record) error { return errors.New("disk full") })
+	filter := NewConfig().WithFilter(func(msg any) bool {
+		_, ok := msg.(transfer)
+		return ok
+	})
+	pid := e.SpawnFunc(receive, "bank", actor.WithMiddleware(Middleware(sink, filter)))
+	// The actor handles the messages regardless.
+	res, err := e.Request(pid, transfer{}, time.Second).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "ok", res)
+}
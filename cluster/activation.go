@@ -4,12 +4,15 @@ import (
 	fmt "fmt"
 	"math"
 	"math/rand"
+
+	"github.com/fertigai/hollywood/actor"
 )
 
 // ActivationConfig...
 type ActivationConfig struct {
 	id           string
 	region       string
+	role         string
 	selectMember SelectMemberFunc
 }
 
@@ -46,6 +49,41 @@ func (config ActivationConfig) WithRegion(region string) ActivationConfig {
 	return config
 }
 
+// WithRole set's the role the member the actor is activated on needs to have,
+// see Config.WithRoles.
+//
+// Defaults to none, in which case any member can host the actor.
+func (config ActivationConfig) WithRole(role string) ActivationConfig {
+	config.role = role
+	return config
+}
+
+// ActivationRegistry records the actors that are activated across the cluster
+// in a consistent store, so an actor is activated at most once even when
+// members activate it concurrently.
+type ActivationRegistry interface {
+	// Register records the given actor, which is about to be activated on
+	// this member. It returns false if an actor with the same ID is
+	// registered already.
+	Register(pid *actor.PID) (bool, error)
+	// Unregister removes the given actor of this member, which was
+	// deactivated.
+	Unregister(pid *actor.PID) error
+	// Close removes all the actors of this member. It is called when the
+	// cluster stops.
+	Close() error
+}
+
+// FencedActivationRegistry is an ActivationRegistry that can lose the actors
+// it registered, for example when another member took over their ID. The
+// cluster deactivates the actors the registry lost, so they don't run twice.
+type FencedActivationRegistry interface {
+	ActivationRegistry
+	// OnLost sets the function that is called with each actor of this
+	// member that the registry lost. The function must not block.
+	OnLost(fn func(pid *actor.PID))
+}
+
 // SelectMemberFunc will be invoked during the activation process.
 // Given the ActivationDetails the actor will be spawned on the returned member.
 type SelectMemberFunc func(ActivationDetails) *Member
@@ -55,13 +93,117 @@ type ActivationDetails struct {
 	// Region where the actor should be activated on
 	Region string
 	// A slice of members that are pre-filtered by the kind of the actor
-	// that need to be activated
+	// that need to be activated, and by its role if it has one
 	Members []*Member
+	// The role the member needs to have, if any.
+	Role string
 	// The kind of the actor
 	Kind string
+	// The ID of the actor, without its kind.
+	ID string
+	// The member that activates the actor.
+	Local *Member
+	// The number of actors that are active on each member, by the ID of the
+	// member.
+	Load map[string]int
+	// The last statistics of each member, by the ID of the member. The
+	// statistics are sent every second, so a member that just joined has
+	// none yet.
+	Stats map[string]*MemberStats
 }
 
 // SelectRandomMember selects a random member of the cluster.
 func SelectRandomMember(details ActivationDetails) *Member {
 	return details.Members[rand.Intn(len(details.Members))]
 }
+
+// SelectLeastLoadedMember selects the member with the fewest active actors.
+func SelectLeastLoadedMember(details ActivationDetails) *Member {
+	var selected *Member
+	for _, member := range details.Members {
+		if selected == nil || details.Load[member.ID] < details.Load[selected.ID] {
+			selected = member
+		}
+	}
+	return selected
+}
+
+// SelectLocalMember selects the member that activates the actor if it has the
+// kind registered, and a random member otherwise.
+func SelectLocalMember(details ActivationDetails) *Member {
+	if details.Local != nil {
+		for _, member := range details.Members {
+			if member.ID == details.Local.ID {
+				return member
+			}
+		}
+	}
+	return SelectRandomMember(details)
+}
+
+// SelectHashMember selects the member that owns the ID of the actor on a
+// consistent hash ring of the members, so all the members select the same one
+// as long as they agree on the members. When a member joins or leaves only the
+// actors it owns move.
+func SelectHashMember(details ActivationDetails) *Member {
+	return newHashRing(details.Members).owner(details.Kind+"/"+details.ID, "")
+}
+
+// ScoreFunc scores a member for an activation, the member with the lowest
+// score fits the activation best.
+type ScoreFunc func(member *Member, details ActivationDetails) float64
+
+// SelectLowestScoreMember returns a SelectMemberFunc that selects the member
+// with the lowest score.
+func SelectLowestScoreMember(score ScoreFunc) SelectMemberFunc {
+	return func(details ActivationDetails) *Member {
+		var (
+			selected *Member
+			lowest   float64
+		)
+		for _, member := range details.Members {
+			if s := score(member, details); selected == nil || s < lowest {
+				selected, lowest = member, s
+			}
+		}
+		return selected
+	}
+}
+
+// LoadWeights weighs the load of a member when it's scored by LoadScore.
+type LoadWeights struct {
+	// The weight of the fraction of the CPU the member uses, between 0
+	// and 1.
+	CPU float64
+	// The weight of each message that waits in the inboxes of the member.
+	MailboxBacklog float64
+	// The weight of each actor that is active on the member.
+	Activations float64
+}
+
+// DefaultLoadWeights weighs each percent of CPU and each waiting message as
+// much as an active actor.
+var DefaultLoadWeights = LoadWeights{
+	CPU:            100,
+	MailboxBacklog: 1,
+	Activations:    1,
+}
+
+// LoadScore returns a ScoreFunc that scores a member with the weighted sum of
+// its load. A member that did not send its statistics yet is scored by its
+// active actors only.
+func LoadScore(weights LoadWeights) ScoreFunc {
+	return func(member *Member, details ActivationDetails) float64 {
+		score := weights.Activations * float64(details.Load[member.ID])
+		if stats, ok := details.Stats[member.ID]; ok {
+			score += weights.CPU*stats.Cpu + weights.MailboxBacklog*float64(stats.MailboxBacklog)
+		}
+		return score
+	}
+}
+
+// SelectLowestLoadMember selects the member with the lowest load, scored by
+// LoadScore with the DefaultLoadWeights.
+func SelectLowestLoadMember(details ActivationDetails) *Member {
+	return SelectLowestScoreMember(LoadScore(DefaultLoadWeights))(details)
+}
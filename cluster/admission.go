@@ -0,0 +1,120 @@
+package cluster
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type (
+	admitQueued      struct{ kind string }
+	admissionTimeout struct{ kind string }
+)
+
+// queuedActivation is an activation request that waits for the activations of
+// its kind to drop below their limits.
+type queuedActivation struct {
+	req      *ActivationRequest
+	respond  func(*ActivationResponse)
+	deadline time.Time
+}
+
+// admission tracks the activations of a kind with activation limits, and the
+// activation requests that wait for a free slot.
+type admission struct {
+	config KindConfig
+	// The activations of the kind on this member and on all the members.
+	local, total int
+	queue        []queuedActivation
+}
+
+func (ad *admission) admits() bool {
+	return (ad.config.maxActivations == 0 || ad.local < ad.config.maxActivations) &&
+		(ad.config.maxClusterActivations == 0 || ad.total < ad.config.maxClusterActivations)
+}
+
+// newAdmissions returns the admissions of the given kinds that have activation
+// limits.
+func newAdmissions(kinds []kind) map[string]*admission {
+	admissions := make(map[string]*admission)
+	for _, kind := range kinds {
+		if kind.config.maxActivations > 0 || kind.config.maxClusterActivations > 0 {
+			admissions[kind.name] = &admission{config: kind.config}
+		}
+	}
+	return admissions
+}
+
+// requestActivation activates the requested actor on this member if the
+// activation limits of its kind allow it. Otherwise the request waits in the
+// queue of the kind until they do, or is rejected if the kind has no queue or
+// it's full. The response is given to respond, right away or once the request
+// left the queue.
+func (a *Agent) requestActivation(req *ActivationRequest, respond func(*ActivationResponse)) {
+	ad, ok := a.admissions[req.Kind]
+	if !ok || ad.admits() {
+		respond(a.handleActivationRequest(req))
+		return
+	}
+	if len(ad.queue) >= ad.config.queueSize {
+		a.rejectActivation(req, respond)
+		return
+	}
+	ad.queue = append(ad.queue, queuedActivation{
+		req:      req,
+		respond:  respond,
+		deadline: time.Now().Add(ad.config.queueTimeout),
+	})
+	pid, engine := a.cluster.PID(), a.cluster.engine
+	time.AfterFunc(ad.config.queueTimeout, func() {
+		engine.Send(pid, admissionTimeout{kind: req.Kind})
+	})
+}
+
+func (a *Agent) rejectActivation(req *ActivationRequest, respond func(*ActivationResponse)) {
+	slog.Warn("activation rejected", "reason", "activation limit of the kind reached", "kind", req.Kind, "id", req.ID)
+	a.cluster.engine.BroadcastEvent(ActivationRejectedEvent{Kind: req.Kind, ID: req.ID})
+	respond(&ActivationResponse{Success: false})
+}
+
+// countActivation adds n to the activations of the kind of the given actor,
+// if the kind has activation limits.
+func (a *Agent) countActivation(id, host string, n int) {
+	kind, _, _ := strings.Cut(id, "/")
+	ad, ok := a.admissions[kind]
+	if !ok {
+		return
+	}
+	ad.total += n
+	if host == a.cluster.engine.Address() {
+		ad.local += n
+	}
+	if n < 0 && len(ad.queue) > 0 {
+		// The queue is handled once the agent is done with the change that
+		// freed the slot.
+		a.cluster.engine.Send(a.cluster.PID(), admitQueued{kind: kind})
+	}
+}
+
+// handleAdmitQueued activates the queued requests of the kind as long as its
+// activation limits allow it.
+func (a *Agent) handleAdmitQueued(msg admitQueued) {
+	ad := a.admissions[msg.kind]
+	for len(ad.queue) > 0 && ad.admits() {
+		queued := ad.queue[0]
+		ad.queue = ad.queue[1:]
+		queued.respond(a.handleActivationRequest(queued.req))
+	}
+}
+
+// handleAdmissionTimeout rejects the queued requests of the kind that waited
+// for too long.
+func (a *Agent) handleAdmissionTimeout(msg admissionTimeout) {
+	ad := a.admissions[msg.kind]
+	now := time.Now()
+	for len(ad.queue) > 0 && !ad.queue[0].deadline.After(now) {
+		queued := ad.queue[0]
+		ad.queue = ad.queue[1:]
+		a.rejectActivation(queued.req, queued.respond)
+	}
+}
@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxActivations(t *testing.T) {
+	c := makeSwimCluster(t, "A", fastSwimConfig())
+	c.RegisterKind("player", NewPlayer, NewKindConfig().WithMaxActivations(2))
+	c.Start()
+	defer c.Stop()
+	require.Eventually(t, func() bool {
+		return len(c.Members()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	rejected := make(chan ActivationRejectedEvent, 1)
+	eventPID := c.Engine().SpawnFunc(func(ctx *actor.Context) {
+		if msg, ok := ctx.Message().(ActivationRejectedEvent); ok {
+			rejected <- msg
+		}
+	}, "event")
+	c.Engine().Subscribe(eventPID)
+
+	pid := c.Activate("player", NewActivationConfig().WithID("1"))
+	require.NotNil(t, pid)
+	require.NotNil(t, c.Activate("player", NewActivationConfig().WithID("2")))
+	assert.Nil(t, c.Activate("player", NewActivationConfig().WithID("3")))
+	assert.Equal(t, ActivationRejectedEvent{Kind: "player", ID: "3"}, <-rejected)
+	assert.Error(t, c.GrainRef("player", "4").Send(&actor.Ping{}))
+	assert.Equal(t, ActivationRejectedEvent{Kind: "player", ID: "4"}, <-rejected)
+
+	c.Deactivate(pid)
+	require.Eventually(t, func() bool {
+		return c.GetActiveByID("player/1") == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.NotNil(t, c.Activate("player", NewActivationConfig().WithID("3")))
+}
+
+func TestActivationQueue(t *testing.T) {
+	c := makeSwimCluster(t, "A", fastSwimConfig())
+	config := NewKindConfig().WithMaxActivations(1).WithActivationQueue(1, 200*time.Millisecond)
+	c.RegisterKind("player", NewPlayer, config)
+	c.Start()
+	defer c.Stop()
+	require.Eventually(t, func() bool {
+		return len(c.Members()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	pid := c.Activate("player", NewActivationConfig().WithID("1"))
+	require.NotNil(t, pid)
+	queued := make(chan *actor.PID)
+	go func() {
+		queued <- c.Activate("player", NewActivationConfig().WithID("2"))
+	}()
+	time.Sleep(50 * time.Millisecond)
+	// The queue is full.
+	assert.Nil(t, c.Activate("player", NewActivationConfig().WithID("3")))
+
+	// The queued activation gets the slot that is freed.
+	c.Deactivate(pid)
+	select {
+	case pid := <-queued:
+		require.NotNil(t, pid)
+		assert.Equal(t, "player/2", pid.ID)
+	case <-time.After(time.Second):
+		t.Fatal("expected the queued activation")
+	}
+
+	start := time.Now()
+	assert.Nil(t, c.Activate("player", NewActivationConfig().WithID("4")))
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestMaxClusterActivations(t *testing.T) {
+	config := NewKindConfig().WithMaxClusterActivations(2)
+	a := makeSwimCluster(t, "A", fastSwimConfig())
+	a.RegisterKind("player", NewPlayer, config)
+	a.Start()
+	defer a.Stop()
+	b := makeSwimCluster(t, "B", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))
+	b.RegisterKind("player", NewPlayer, config)
+	b.Start()
+	defer b.Stop()
+	require.Eventually(t, func() bool {
+		return len(a.Members()) == 2 && len(b.Members()) == 2
+	}, 3*time.Second, 10*time.Millisecond)
+
+	local := NewActivationConfig().WithSelectMemberFunc(SelectLocalMember)
+	require.NotNil(t, a.Activate("player", local.WithID("1")))
+	require.NotNil(t, b.Activate("player", local.WithID("2")))
+	require.Eventually(t, func() bool {
+		return len(a.GetActiveByKind("player")) == 2 && len(b.GetActiveByKind("player")) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Nil(t, a.Activate("player", local.WithID("3")))
+	assert.Nil(t, b.Activate("player", local.WithID("3")))
+}
@@ -3,9 +3,11 @@ package cluster
 import (
 	"log/slog"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/remote"
 	"golang.org/x/exp/maps"
 )
 
@@ -14,10 +16,16 @@ type (
 		kind   string
 		config ActivationConfig
 	}
-	getMembers struct{}
+	getMembers           struct{}
+	getActivationDetails struct {
+		kind   string
+		config ActivationConfig
+	}
 	getKinds   struct{}
 	deactivate struct{ pid *actor.PID }
-	getActive  struct {
+	// registryLost is sent when the activation registry lost an actor.
+	registryLost struct{ pid *actor.PID }
+	getActive    struct {
 		id   string
 		kind string
 	}
@@ -32,6 +40,31 @@ type Agent struct {
 	localKinds map[string]kind
 	// All the actors that are available cluster wide.
 	activated map[string]*actor.PID
+	election  election
+	// The requests waiting for the grains this member activates, by the ID
+	// of the grain.
+	grains    map[string][]*actor.PID
+	ring      *hashRing
+	rebalance rebalance
+	// The IDs of the members that leave the cluster gracefully, which can
+	// include this member.
+	leaving map[string]bool
+	// The IDs of the members that drain, which can include this member.
+	draining   map[string]bool
+	leaveDone  chan struct{}
+	splitBrain splitBrain
+	// The leader of each role that members declared.
+	roles map[string]*Member
+	// The activation limits of the local kinds that have them.
+	admissions map[string]*admission
+	// The local subscribers of the topics.
+	topics map[string][]*actor.PID
+	stats  stats
+	// handoff is nil unless the state handed off is throttled.
+	handoff *handoffThrottle
+	// The members the sticky grains this member owns were placed on, by the
+	// ID of the grain.
+	affinity map[string]*Member
 }
 
 func NewAgent(c *Cluster) actor.Producer {
@@ -48,6 +81,18 @@ func NewAgent(c *Cluster) actor.Producer {
 			kinds:      kinds,
 			localKinds: localKinds,
 			activated:  make(map[string]*actor.PID),
+			grains:     make(map[string][]*actor.PID),
+			affinity:   make(map[string]*Member),
+			ring:       newHashRing(nil),
+			rebalance:  rebalance{moving: make(map[string]bool)},
+			leaving:    make(map[string]bool),
+			draining:   make(map[string]bool),
+			roles:      make(map[string]*Member),
+			splitBrain: splitBrain{lost: make(map[string]*Member)},
+			admissions: newAdmissions(c.kinds),
+			topics:     make(map[string][]*actor.PID),
+			stats:      stats{members: make(map[string]*MemberStats)},
+			handoff:    newHandoffThrottle(c.config.handoffRate),
 		}
 	}
 }
@@ -55,7 +100,20 @@ func NewAgent(c *Cluster) actor.Producer {
 func (a *Agent) Receive(c *actor.Context) {
 	switch msg := c.Message().(type) {
 	case actor.Started:
+		a.startStats(c.PID())
+		if registry, ok := a.cluster.config.registry.(FencedActivationRegistry); ok {
+			pid, engine := c.PID(), a.cluster.engine
+			registry.OnLost(func(lost *actor.PID) {
+				engine.Send(pid, registryLost{pid: lost})
+			})
+		}
 	case actor.Stopped:
+		a.stats.repeater.Stop()
+		if registry := a.cluster.config.registry; registry != nil {
+			if err := registry.Close(); err != nil {
+				slog.Error("failed to close the activation registry", "err", err)
+			}
+		}
 	case *ActorTopology:
 		a.handleActorTopology(msg)
 	case *Members:
@@ -63,15 +121,63 @@ func (a *Agent) Receive(c *actor.Context) {
 	case *Activation:
 		a.handleActivation(msg)
 	case activate:
-		pid := a.activate(msg.kind, msg.config)
-		c.Respond(pid)
+		sender := c.Sender()
+		a.activate(msg.kind, msg.config, func(pid *actor.PID) {
+			a.cluster.engine.Send(sender, pid)
+		})
 	case deactivate:
 		a.bcast(&Deactivation{PID: msg.pid})
+	case registryLost:
+		a.handleRegistryLost(msg)
 	case *Deactivation:
 		a.handleDeactivation(msg)
 	case *ActivationRequest:
-		resp := a.handleActivationRequest(msg)
-		c.Respond(resp)
+		sender := c.Sender()
+		a.requestActivation(msg, func(resp *ActivationResponse) {
+			a.cluster.engine.Send(sender, resp)
+		})
+	case admitQueued:
+		a.handleAdmitQueued(msg)
+	case admissionTimeout:
+		a.handleAdmissionTimeout(msg)
+	case getActivationDetails:
+		c.Respond(a.activationDetails(msg.kind, msg.config))
+	case *GrainActivation:
+		a.handleGrainActivation(c, msg)
+	case *GrainLookup:
+		a.handleGrainLookup(c, msg)
+	case grainActivated:
+		a.finishGrainActivation(msg.id, msg.resp)
+	case partitionMoved:
+		a.handlePartitionMoved(msg)
+	case leave:
+		a.handleLeave(msg)
+	case *MemberLeaving:
+		a.handleMemberLeaving(msg)
+	case drain:
+		a.handleDrain()
+	case *MemberDraining:
+		a.handleMemberDraining(msg)
+	case splitBrainTimeout:
+		a.handleSplitBrainTimeout(msg)
+	case *TopicMessage:
+		a.handleTopicMessage(c, msg)
+	case forwardEvent:
+		a.handleForwardEvent(msg)
+	case *ForwardedEvent:
+		a.handleForwardedEvent(msg)
+	case statsTick:
+		a.handleStatsTick()
+	case *MemberStatsRequest:
+		c.Respond(a.memberStats())
+	case *MemberStats:
+		a.handleMemberStats(msg)
+	case *ActivationsRequest:
+		c.Respond(a.localActivations(msg.Kind))
+	case subscribe:
+		a.handleSubscribe(msg)
+	case unsubscribe:
+		a.handleUnsubscribe(msg)
 	case getMembers:
 		c.Respond(a.members.Slice())
 	case getKinds:
@@ -84,6 +190,18 @@ func (a *Agent) Receive(c *actor.Context) {
 		c.Respond(kinds)
 	case getActive:
 		a.handleGetActive(c, msg)
+	case getLeader:
+		c.Respond(a.election.leader)
+	case getRoleLeader:
+		c.Respond(a.roles[msg.role])
+	case *Election:
+		a.handleElection(msg)
+	case *ElectionAlive:
+		a.handleElectionAlive(msg)
+	case *Coordinator:
+		a.handleCoordinator(msg)
+	case electionTimeout:
+		a.handleElectionTimeout(msg)
 	}
 }
 
@@ -115,11 +233,30 @@ func (a *Agent) handleActorTopology(msg *ActorTopology) {
 }
 
 func (a *Agent) handleDeactivation(msg *Deactivation) {
+	registry := a.cluster.config.registry
+	if registry != nil && msg.PID.Address == a.cluster.engine.Address() {
+		if err := registry.Unregister(msg.PID); err != nil {
+			slog.Error("failed to unregister activation", "err", err, "pid", msg.PID)
+		}
+	}
 	a.removeActivated(msg.PID)
 	a.cluster.engine.Poison(msg.PID)
 	a.cluster.engine.BroadcastEvent(DeactivationEvent{PID: msg.PID})
 }
 
+// handleRegistryLost deactivates an actor the registry lost. The actor is
+// stopped here first, as the other members might not be reachable.
+func (a *Agent) handleRegistryLost(msg registryLost) {
+	a.handleDeactivation(&Deactivation{PID: msg.pid})
+	self := a.cluster.ID()
+	a.members.ForEach(func(member *Member) bool {
+		if member.ID != self {
+			a.cluster.engine.Send(member.PID(), &Deactivation{PID: msg.pid})
+		}
+		return true
+	})
+}
+
 // A new kind is activated on this cluster.
 func (a *Agent) handleActivation(msg *Activation) {
 	a.addActivated(msg.PID)
@@ -131,9 +268,38 @@ func (a *Agent) handleActivationRequest(msg *ActivationRequest) *ActivationRespo
 		slog.Error("received activation request but kind not registered locally on this node", "kind", msg.Kind)
 		return &ActivationResponse{Success: false}
 	}
+	if a.leaving[a.cluster.ID()] {
+		slog.Warn("received activation request while leaving the cluster", "kind", msg.Kind, "id", msg.ID)
+		return &ActivationResponse{Success: false}
+	}
+
+	if registry := a.cluster.config.registry; registry != nil {
+		pid := actor.NewPID(a.cluster.engine.Address(), msg.Kind+"/"+msg.ID)
+		ok, err := registry.Register(pid)
+		if err != nil {
+			slog.Error("failed to register activation", "err", err, "pid", pid)
+			return &ActivationResponse{Success: false}
+		}
+		if !ok {
+			slog.Warn("activation failed", "err", "duplicated actor id across the cluster", "id", pid.ID)
+			return &ActivationResponse{Success: false}
+		}
+	}
 
 	kind := a.localKinds[msg.Kind]
 	pid := a.cluster.engine.Spawn(kind.producer, msg.Kind, actor.WithID(msg.ID))
+	if len(msg.StateType) > 0 {
+		// The actor gets the state it handed off on the member it moved
+		// from before any other message.
+		state, err := remote.DefaultSerializer{}.Deserialize(msg.State, msg.StateType)
+		if err != nil {
+			slog.Error("failed to deserialize handed off state", "err", err, "pid", pid)
+		} else {
+			a.cluster.engine.Send(pid, state)
+		}
+	}
+	// The actor counts towards the activation limits of its kind right away.
+	a.addActivated(pid)
 	resp := &ActivationResponse{
 		PID:     pid,
 		Success: true,
@@ -141,68 +307,105 @@ func (a *Agent) handleActivationRequest(msg *ActivationRequest) *ActivationRespo
 	return resp
 }
 
-func (a *Agent) activate(kind string, config ActivationConfig) *actor.PID {
+// activate activates the actor and gives its PID to respond, or nil if it
+// could not be activated. A local activation can wait in the queue of its
+// kind, in which case respond is invoked once it left the queue.
+func (a *Agent) activate(kind string, config ActivationConfig, respond func(*actor.PID)) {
 	// Make sure actors are unique across the whole cluster.
 	id := kind + "/" + config.id // the id part of the PID
 	if _, ok := a.activated[id]; ok {
 		slog.Warn("activation failed", "err", "duplicated actor id across the cluster", "id", id)
-		return nil
+		respond(nil)
+		return
 	}
-	members := a.members.FilterByKind(kind)
-	if len(members) == 0 {
+	details := a.activationDetails(kind, config)
+	if len(details.Members) == 0 {
 		slog.Warn("could not find any members with kind", "kind", kind)
-		return nil
+		respond(nil)
+		return
 	}
 	if config.selectMember == nil {
 		config.selectMember = SelectRandomMember
 	}
-	memberPID := config.selectMember(ActivationDetails{
-		Members: members,
-		Region:  config.region,
-		Kind:    kind,
-	})
+	memberPID := config.selectMember(details)
 	if memberPID == nil {
 		slog.Warn("activator did not found a member to activate on")
-		return nil
+		respond(nil)
+		return
 	}
 	req := &ActivationRequest{Kind: kind, ID: config.id}
 	activatorPID := actor.NewPID(memberPID.Host, "cluster/"+memberPID.ID)
 
-	var activationResp *ActivationResponse
-	// Local activation
-	if memberPID.Host == a.cluster.engine.Address() {
-		activationResp = a.handleActivationRequest(req)
-	} else {
-		// Remote activation
-		//
-		// TODO: topology hash
-		resp, err := a.cluster.engine.Request(activatorPID, req, a.cluster.config.requestTimeout).Result()
-		if err != nil {
-			slog.Error("failed activation request", "err", err)
-			return nil
-		}
-		r, ok := resp.(*ActivationResponse)
-		if !ok {
-			slog.Error("expected *ActivationResponse", "msg", reflect.TypeOf(resp))
-			return nil
-		}
+	finish := func(r *ActivationResponse) {
 		if !r.Success {
 			slog.Error("activation unsuccessful", "msg", r)
-			return nil
+			respond(nil)
+			return
 		}
-		activationResp = r
+		a.bcast(&Activation{
+			PID: r.PID,
+		})
+		respond(r.PID)
 	}
+	// Local activation
+	if memberPID.Host == a.cluster.engine.Address() {
+		a.requestActivation(req, finish)
+		return
+	}
+	// Remote activation
+	//
+	// TODO: topology hash
+	resp, err := a.cluster.engine.Request(activatorPID, req, a.cluster.config.requestTimeout).Result()
+	if err != nil {
+		slog.Error("failed activation request", "err", err)
+		respond(nil)
+		return
+	}
+	r, ok := resp.(*ActivationResponse)
+	if !ok {
+		slog.Error("expected *ActivationResponse", "msg", reflect.TypeOf(resp))
+		respond(nil)
+		return
+	}
+	finish(r)
+}
 
-	a.bcast(&Activation{
-		PID: activationResp.PID,
+func (a *Agent) activationDetails(kind string, config ActivationConfig) ActivationDetails {
+	members := slices.DeleteFunc(a.members.FilterByKind(kind), func(m *Member) bool {
+		return a.leaving[m.ID] || !m.HasRole(config.role)
 	})
-
-	return activationResp.PID
+	// The members that drain are only used when all the others do too.
+	if placeable := slices.DeleteFunc(slices.Clone(members), func(m *Member) bool {
+		return a.draining[m.ID]
+	}); len(placeable) > 0 {
+		members = placeable
+	}
+	hosts := make(map[string]string, len(members))
+	for _, member := range members {
+		hosts[member.Host] = member.ID
+	}
+	load := make(map[string]int, len(members))
+	for _, pid := range a.activated {
+		if id, ok := hosts[pid.Address]; ok {
+			load[id]++
+		}
+	}
+	return ActivationDetails{
+		Members: members,
+		Region:  config.region,
+		Role:    config.role,
+		Kind:    kind,
+		ID:      config.id,
+		Local:   a.cluster.Member(),
+		Load:    load,
+		Stats:   maps.Clone(a.stats.members),
+	}
 }
 
 func (a *Agent) handleMembers(members []*Member) {
 	joined := NewMemberSet(members...).Except(a.members.Slice())
 	left := a.members.Except(members)
+	a.trackSplitBrain(joined, left)
 
 	for _, member := range joined {
 		a.memberJoin(member)
@@ -210,6 +413,18 @@ func (a *Agent) handleMembers(members []*Member) {
 	for _, member := range left {
 		a.memberLeave(member)
 	}
+	if len(joined) > 0 || len(left) > 0 {
+		a.cluster.engine.BroadcastEvent(TopologyChangedEvent{
+			Members: a.members.Slice(),
+			Joined:  joined,
+			Left:    left,
+		})
+		a.ring = newHashRing(a.placeableMembers())
+		a.rebalancePartitions()
+	}
+	a.drainOldVersion(joined)
+	a.checkLeader(joined, left)
+	a.updateRoleLeaders()
 }
 
 func (a *Agent) memberJoin(member *Member) {
@@ -234,6 +449,9 @@ func (a *Agent) memberJoin(member *Member) {
 	if len(actorInfos) > 0 {
 		a.cluster.engine.Send(member.PID(), &ActorTopology{Actors: actorInfos})
 	}
+	if self := a.cluster.Member(); a.draining[self.ID] {
+		a.cluster.engine.Send(member.PID(), &MemberDraining{Member: self})
+	}
 
 	// Broadcast MemberJoinEvent
 	a.cluster.engine.BroadcastEvent(MemberJoinEvent{
@@ -250,6 +468,12 @@ func (a *Agent) memberJoin(member *Member) {
 
 func (a *Agent) memberLeave(member *Member) {
 	a.members.Remove(member)
+	delete(a.leaving, member.ID)
+	delete(a.draining, member.ID)
+	delete(a.stats.members, member.ID)
+	maps.DeleteFunc(a.affinity, func(_ string, m *Member) bool {
+		return m.ID == member.ID
+	})
 	a.rebuildKinds()
 
 	// Remove all the activeKinds that where running on the member that left the cluster.
@@ -272,14 +496,25 @@ func (a *Agent) bcast(msg any) {
 }
 
 func (a *Agent) addActivated(pid *actor.PID) {
-	if _, ok := a.activated[pid.ID]; !ok {
-		a.activated[pid.ID] = pid
-		slog.Debug("new actor available on cluster", "pid", pid)
+	current, ok := a.activated[pid.ID]
+	if ok && current.Equals(pid) {
+		return
+	}
+	if ok {
+		a.countActivation(current.ID, current.Address, -1)
 	}
+	a.activated[pid.ID] = pid
+	a.countActivation(pid.ID, pid.Address, 1)
+	slog.Debug("new actor available on cluster", "pid", pid)
 }
 
 func (a *Agent) removeActivated(pid *actor.PID) {
+	// The actor might have been activated again somewhere else already.
+	if current, ok := a.activated[pid.ID]; !ok || !current.Equals(pid) {
+		return
+	}
 	delete(a.activated, pid.ID)
+	a.countActivation(pid.ID, pid.Address, -1)
 	slog.Debug("actor removed from cluster", "pid", pid)
 }
 
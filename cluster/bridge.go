@@ -0,0 +1,384 @@
+package cluster
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/remote"
+	"github.com/zeebo/xxh3"
+)
+
+var (
+	errBridgeUnauthorized = errors.New("bridge: unauthorized")
+	errBridgeRateLimited  = errors.New("bridge: rate limit exceeded")
+	errNoBridge           = errors.New("no bridge to the cluster")
+	errQualifiedID        = errors.New("qualified identity needs to be cluster/kind/identity")
+)
+
+// BridgeConfig holds the configuration of a Bridge.
+type BridgeConfig struct {
+	name     string
+	peer     string
+	gateways []string
+	token    string
+	topics   []string
+	rate     float64
+	burst    int
+}
+
+// NewBridgeConfig returns the configuration of a bridge between the cluster of
+// the given name and its peer, the other cluster. The bridge of the peer is
+// configured the other way around.
+func NewBridgeConfig(name, peer string) BridgeConfig {
+	return BridgeConfig{
+		name: name,
+		peer: peer,
+	}
+}
+
+// WithGateway adds the address of a member of the peer that runs its bridge.
+// The grains and topics are spread across the gateways, and the messages for
+// the same grain or topic go through the same gateway, so they stay in order.
+func (config BridgeConfig) WithGateway(addr string) BridgeConfig {
+	config.gateways = append(slices.Clone(config.gateways), addr)
+	return config
+}
+
+// WithToken set's the token the bridges authenticate each other with. Both
+// bridges need the same token, and the messages with another token are
+// rejected.
+//
+// Defaults to no token.
+func (config BridgeConfig) WithToken(token string) BridgeConfig {
+	config.token = token
+	return config
+}
+
+// WithTopic adds a topic whose messages are replicated to the peer. The
+// messages published on the topic in this cluster are published on the same
+// topic in the peer, but not the other way around unless the bridge of the
+// peer replicates the topic as well.
+func (config BridgeConfig) WithTopic(topic string) BridgeConfig {
+	config.topics = append(slices.Clone(config.topics), topic)
+	return config
+}
+
+// WithRateLimit set's the number of messages per second the bridge accepts
+// from the peer, and how many it accepts at once before the rate applies. The
+// messages over the limit are rejected.
+//
+// Defaults to no limit.
+func (config BridgeConfig) WithRateLimit(rate float64, burst int) BridgeConfig {
+	config.rate = rate
+	config.burst = burst
+	return config
+}
+
+// Bridge connects this cluster to another, independent cluster, for example
+// in another region. Messages are sent to the grains of the peer by their
+// qualified identity, see Cluster.FederatedGrainRef, and the messages of the
+// selected topics are replicated to the peer.
+//
+// Both clusters run a bridge to each other. A bridge runs on a member of
+// its cluster, and only one member should replicate a topic, otherwise the
+// peer receives its messages more than once.
+type Bridge struct {
+	cluster *Cluster
+	config  BridgeConfig
+	pid     *actor.PID
+}
+
+// StartBridge starts the bridge to the peer of the given config on this
+// member.
+//
+//	config := cluster.NewBridgeConfig("us", "eu").
+//		WithGateway("10.1.0.1:4000").
+//		WithToken(token).
+//		WithTopic("orders")
+//	bridge := c.StartBridge(config)
+func (c *Cluster) StartBridge(config BridgeConfig) *Bridge {
+	b := &Bridge{cluster: c, config: config}
+	b.pid = c.engine.Spawn(newBridgeActor(b), "bridge", actor.WithID(config.peer))
+	c.bridgesMu.Lock()
+	c.bridges[config.peer] = b
+	c.bridgesMu.Unlock()
+	return b
+}
+
+// Stop stops the bridge.
+func (b *Bridge) Stop() {
+	c := b.cluster
+	c.bridgesMu.Lock()
+	if c.bridges[b.config.peer] == b {
+		delete(c.bridges, b.config.peer)
+	}
+	c.bridgesMu.Unlock()
+	<-c.engine.Poison(b.pid).Done()
+}
+
+// Peer returns the name of the cluster the bridge connects to.
+func (b *Bridge) Peer() string {
+	return b.config.peer
+}
+
+// gateway returns the bridge of the peer to send the messages with the given
+// key to.
+func (b *Bridge) gateway(key string) *actor.PID {
+	addr := b.config.gateways[xxh3.HashString(key)%uint64(len(b.config.gateways))]
+	return actor.NewPID(addr, "bridge/"+b.config.name)
+}
+
+func (b *Bridge) message(msg any) (*BridgeMessage, error) {
+	if len(b.config.gateways) == 0 {
+		return nil, fmt.Errorf("bridge to %s has no gateways", b.config.peer)
+	}
+	serializer := remote.DefaultSerializer{}
+	data, err := serializer.Serialize(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &BridgeMessage{
+		Token:    b.config.token,
+		Origin:   b.config.name,
+		Data:     data,
+		TypeName: serializer.TypeName(msg),
+	}, nil
+}
+
+// FederatedGrainRef is a reference to a grain of another cluster, which is
+// reached through the bridge to that cluster.
+type FederatedGrainRef struct {
+	cluster  *Cluster
+	peer     string
+	kind     string
+	identity string
+}
+
+// FederatedGrainRef returns a reference to the grain with the given qualified
+// identity, which is the name of its cluster, its kind and its identity,
+// separated by slashes. The qualified identity of the player bob in the eu
+// cluster is "eu/player/bob".
+func (c *Cluster) FederatedGrainRef(qualifiedID string) (FederatedGrainRef, error) {
+	parts := strings.SplitN(qualifiedID, "/", 3)
+	if len(parts) != 3 || slices.Contains(parts, "") {
+		return FederatedGrainRef{}, fmt.Errorf("%w: %q", errQualifiedID, qualifiedID)
+	}
+	return FederatedGrainRef{cluster: c, peer: parts[0], kind: parts[1], identity: parts[2]}, nil
+}
+
+// QualifiedID returns the qualified identity of the grain.
+func (g FederatedGrainRef) QualifiedID() string {
+	return g.peer + "/" + g.kind + "/" + g.identity
+}
+
+// Send sends the given message to the grain, which is activated in its
+// cluster if needed. The message needs to be a protobuf message or a type
+// registered with remote.RegisterCodec.
+func (g FederatedGrainRef) Send(msg any) error {
+	b, env, err := g.message(msg)
+	if err != nil {
+		return err
+	}
+	g.cluster.engine.Send(b.gateway(g.QualifiedID()), env)
+	return nil
+}
+
+// Request sends the given message to the grain, which is activated in its
+// cluster if needed, and waits for its response.
+func (g FederatedGrainRef) Request(msg any, timeout time.Duration) (any, error) {
+	b, env, err := g.message(msg)
+	if err != nil {
+		return nil, err
+	}
+	return g.cluster.engine.Request(b.gateway(g.QualifiedID()), env, timeout).Result()
+}
+
+func (g FederatedGrainRef) message(msg any) (*Bridge, *BridgeMessage, error) {
+	g.cluster.bridgesMu.RLock()
+	b, ok := g.cluster.bridges[g.peer]
+	g.cluster.bridgesMu.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("%w %s", errNoBridge, g.peer)
+	}
+	env, err := b.message(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	env.Kind, env.ID = g.kind, g.identity
+	return b, env, nil
+}
+
+type (
+	// bridgeDelivery is a message of the peer for a grain.
+	bridgeDelivery struct {
+		payload any
+		sender  *actor.PID
+	}
+	// bridgeResolved is sent once the grain of the deliveries was resolved.
+	bridgeResolved struct {
+		grain GrainRef
+		pid   *actor.PID
+	}
+)
+
+// bridgeActor receives the messages of the bridge of the peer, and replicates
+// the topics of this cluster to the peer.
+type bridgeActor struct {
+	bridge  *Bridge
+	topics  map[string]bool
+	limiter *bridgeLimiter
+	// pending holds the deliveries for the grains that are being resolved,
+	// which keeps the messages to a grain in order.
+	pending map[string][]bridgeDelivery
+}
+
+func newBridgeActor(b *Bridge) actor.Producer {
+	return func() actor.Receiver {
+		topics := make(map[string]bool, len(b.config.topics))
+		for _, topic := range b.config.topics {
+			topics[topic] = true
+		}
+		a := &bridgeActor{
+			bridge:  b,
+			topics:  topics,
+			pending: make(map[string][]bridgeDelivery),
+		}
+		if b.config.rate > 0 {
+			a.limiter = newBridgeLimiter(b.config.rate, b.config.burst)
+		}
+		return a
+	}
+}
+
+func (a *bridgeActor) Receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.Started:
+		if len(a.topics) > 0 {
+			c.Engine().Subscribe(c.PID())
+		}
+	case actor.Stopped:
+		if len(a.topics) > 0 {
+			c.Engine().Unsubscribe(c.PID())
+		}
+	case TopicEvent:
+		a.replicate(msg)
+	case *BridgeMessage:
+		a.handleMessage(c, msg)
+	case bridgeResolved:
+		a.deliver(msg)
+	}
+}
+
+// replicate sends a message that was published in this cluster to the peer.
+// The messages that were replicated from a peer are not sent back.
+func (a *bridgeActor) replicate(msg TopicEvent) {
+	if !a.topics[msg.Topic] || msg.Origin != "" {
+		return
+	}
+	env, err := a.bridge.message(msg.Message)
+	if err != nil {
+		slog.Error("failed to replicate topic message", "err", err, "topic", msg.Topic, "peer", a.bridge.config.peer)
+		return
+	}
+	env.Topic = msg.Topic
+	a.bridge.cluster.engine.Send(a.bridge.gateway(msg.Topic), env)
+}
+
+func (a *bridgeActor) handleMessage(c *actor.Context, msg *BridgeMessage) {
+	config := a.bridge.config
+	if msg.Origin != config.peer || subtle.ConstantTimeCompare([]byte(msg.Token), []byte(config.token)) != 1 {
+		slog.Warn("bridge rejected message", "err", errBridgeUnauthorized, "origin", msg.Origin, "sender", c.Sender())
+		a.reject(c, errBridgeUnauthorized)
+		return
+	}
+	if a.limiter != nil && !a.limiter.allow(time.Now()) {
+		slog.Warn("bridge rejected message", "err", errBridgeRateLimited, "origin", msg.Origin)
+		a.reject(c, errBridgeRateLimited)
+		return
+	}
+	payload, err := remote.DefaultSerializer{}.Deserialize(msg.Data, msg.TypeName)
+	if err != nil {
+		slog.Error("failed to deserialize bridge message", "err", err, "type", msg.TypeName)
+		a.reject(c, err)
+		return
+	}
+	cluster := a.bridge.cluster
+	if msg.Topic != "" {
+		if err := cluster.publish(msg.Topic, payload, msg.Origin); err != nil {
+			slog.Error("failed to publish replicated message", "err", err, "topic", msg.Topic)
+		}
+		return
+	}
+	id := msg.Kind + "/" + msg.ID
+	delivery := bridgeDelivery{payload: payload, sender: c.Sender()}
+	if pending, ok := a.pending[id]; ok {
+		a.pending[id] = append(pending, delivery)
+		return
+	}
+	a.pending[id] = []bridgeDelivery{delivery}
+	grain, self := cluster.GrainRef(msg.Kind, msg.ID), c.PID()
+	// Activating the grain takes requests to the agent, so it can't wait.
+	go func() {
+		cluster.engine.Send(self, bridgeResolved{grain: grain, pid: grain.PID()})
+	}()
+}
+
+// deliver sends the pending messages to the grain that was resolved. The
+// grain responds to the senders in the peer directly.
+func (a *bridgeActor) deliver(msg bridgeResolved) {
+	id := msg.grain.kind + "/" + msg.grain.Identity()
+	engine := a.bridge.cluster.engine
+	for _, delivery := range a.pending[id] {
+		switch {
+		case msg.pid != nil:
+			engine.SendWithSender(msg.pid, delivery.payload, delivery.sender)
+		case delivery.sender != nil:
+			engine.Send(delivery.sender, actor.NewResponseError(msg.grain.unavailable()))
+		}
+	}
+	delete(a.pending, id)
+}
+
+// reject responds with the error to the requests of the peer.
+func (a *bridgeActor) reject(c *actor.Context, err error) {
+	if c.Sender() != nil {
+		c.Respond(err)
+	}
+}
+
+// bridgeLimiter is a token bucket, which is refilled with rate tokens per
+// second up to burst tokens.
+type bridgeLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBridgeLimiter(rate float64, burst int) *bridgeLimiter {
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Max(1, math.Ceil(rate))
+	}
+	return &bridgeLimiter{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// allow takes a token if one is available.
+func (l *bridgeLimiter) allow(now time.Time) bool {
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+elapsed*l.rate)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
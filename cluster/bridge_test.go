@@ -0,0 +1,107 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeBridgedCluster(t *testing.T, id string) *Cluster {
+	// The clusters are independent, as the members have no seeds.
+	c := makeSwimCluster(t, id, fastSwimConfig())
+	c.RegisterKind("counter", newCounter, NewKindConfig())
+	c.Start()
+	return c
+}
+
+func TestBridge(t *testing.T) {
+	us := makeBridgedCluster(t, "A")
+	defer us.Stop()
+	eu := makeBridgedCluster(t, "B")
+	defer eu.Stop()
+	usBridge := us.StartBridge(NewBridgeConfig("us", "eu").
+		WithGateway(eu.Address()).
+		WithToken("secret").
+		WithTopic("orders"))
+	defer usBridge.Stop()
+	euBridge := eu.StartBridge(NewBridgeConfig("eu", "us").
+		WithGateway(us.Address()).
+		WithToken("secret").
+		WithTopic("orders"))
+	defer euBridge.Stop()
+
+	grain, err := us.FederatedGrainRef("eu/counter/1")
+	require.NoError(t, err)
+	assert.Equal(t, "eu/counter/1", grain.QualifiedID())
+	require.NoError(t, grain.Send(&counterAdd{N: 2}))
+	resp, err := grain.Request(&counterGet{}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, &counterState{N: 2}, resp)
+	pid := eu.GetActiveByID("counter/1")
+	require.NotNil(t, pid)
+	assert.Equal(t, eu.Address(), pid.Address)
+
+	// A topic is replicated to the peer, which does not send it back.
+	received := func(c *Cluster) chan TopicEvent {
+		ch := make(chan TopicEvent, 4)
+		pid := c.Engine().SpawnFunc(func(ctx *actor.Context) {
+			if msg, ok := ctx.Message().(TopicEvent); ok {
+				ch <- msg
+			}
+		}, "event")
+		c.Engine().Subscribe(pid)
+		return ch
+	}
+	usEvents, euEvents := received(us), received(eu)
+	require.NoError(t, us.Publish("orders", &counterAdd{N: 1}))
+	select {
+	case evt := <-euEvents:
+		assert.Equal(t, TopicEvent{Topic: "orders", Message: &counterAdd{N: 1}, Origin: "us"}, evt)
+	case <-time.After(time.Second):
+		t.Fatal("expected the topic message in the peer")
+	}
+	assert.Equal(t, TopicEvent{Topic: "orders", Message: &counterAdd{N: 1}}, <-usEvents)
+	select {
+	case evt := <-usEvents:
+		t.Fatalf("the topic message was sent back: %v", evt)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	_, err = us.FederatedGrainRef("eu/counter")
+	assert.ErrorIs(t, err, errQualifiedID)
+	grain, err = us.FederatedGrainRef("ap/counter/1")
+	require.NoError(t, err)
+	assert.ErrorIs(t, grain.Send(&counterAdd{N: 1}), errNoBridge)
+}
+
+func TestBridgeUnauthorized(t *testing.T) {
+	eu := makeBridgedCluster(t, "B")
+	defer eu.Stop()
+	euBridge := eu.StartBridge(NewBridgeConfig("eu", "us").WithToken("secret"))
+	defer euBridge.Stop()
+	us := makeBridgedCluster(t, "A")
+	defer us.Stop()
+	usBridge := us.StartBridge(NewBridgeConfig("us", "eu").WithGateway(eu.Address()).WithToken("guess"))
+	defer usBridge.Stop()
+
+	grain, err := us.FederatedGrainRef("eu/counter/1")
+	require.NoError(t, err)
+	_, err = grain.Request(&counterGet{}, time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), errBridgeUnauthorized.Error())
+	assert.Nil(t, eu.GetActiveByID("counter/1"))
+}
+
+func TestBridgeLimiter(t *testing.T) {
+	now := time.Now()
+	l := newBridgeLimiter(10, 2)
+	l.last = now
+	assert.True(t, l.allow(now))
+	assert.True(t, l.allow(now))
+	assert.False(t, l.allow(now))
+	assert.True(t, l.allow(now.Add(100*time.Millisecond)))
+	assert.False(t, l.allow(now.Add(100*time.Millisecond)))
+}
@@ -0,0 +1,217 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/remote"
+)
+
+var errNoGateways = errors.New("no gateways configured")
+
+// ClientConfig holds the configuration of a Client.
+type ClientConfig struct {
+	listenAddr     string
+	gateways       []MemberAddr
+	requestTimeout time.Duration
+}
+
+// NewClientConfig returns a ClientConfig that is initialized with default
+// values.
+func NewClientConfig() ClientConfig {
+	return ClientConfig{
+		listenAddr:     getRandomListenAddr(),
+		requestTimeout: defaultRequestTimeout,
+	}
+}
+
+// WithListenAddr set's the listen address of the remote of the client, which
+// the grains send their responses to.
+//
+// Defaults to a random port number.
+func (config ClientConfig) WithListenAddr(addr string) ClientConfig {
+	config.listenAddr = addr
+	return config
+}
+
+// WithGateway adds a member the client resolves grains through. Any member of
+// the cluster can be a gateway. The client moves on to the next gateway when
+// one does not respond.
+func (config ClientConfig) WithGateway(addr MemberAddr) ClientConfig {
+	config.gateways = append(slices.Clone(config.gateways), addr)
+	return config
+}
+
+// WithRequestTimeout set's the maximum duration of how long a gateway can
+// take to resolve a grain.
+//
+// Defaults to 1 second.
+func (config ClientConfig) WithRequestTimeout(d time.Duration) ClientConfig {
+	config.requestTimeout = d
+	return config
+}
+
+// Client sends messages to the grains of a cluster without joining it, for
+// CLI tools and external services. It does not take part in the membership
+// and does not host activations: the grains are resolved through the gateway
+// members, and the messages are then sent to them directly.
+type Client struct {
+	config ClientConfig
+	engine *actor.Engine
+	remote *remote.Remote
+	// next is the index of the gateway to ask first.
+	next atomic.Uint32
+}
+
+// NewClient returns a new client given a ClientConfig, which needs at least
+// one gateway.
+func NewClient(config ClientConfig) (*Client, error) {
+	if len(config.gateways) == 0 {
+		return nil, errNoGateways
+	}
+	r := remote.New(config.listenAddr, remote.NewConfig())
+	e, err := actor.NewEngine(actor.NewEngineConfig().WithRemote(r))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		config: config,
+		engine: e,
+		remote: r,
+	}, nil
+}
+
+// Engine returns the actor engine of the client, which can spawn the actors
+// that receive the responses of the grains.
+func (c *Client) Engine() *actor.Engine {
+	return c.engine
+}
+
+// Stop stops the remote of the client.
+func (c *Client) Stop() {
+	c.remote.Stop().Wait()
+}
+
+// GrainRef returns a reference to the grain of the given kind and identity.
+//
+//	player := client.GrainRef("player", "bob")
+//	resp, err := player.Request(&GetBalance{}, time.Second)
+func (c *Client) GrainRef(kind, identity string) ClientGrainRef {
+	return ClientGrainRef{
+		client:   c,
+		kind:     kind,
+		identity: identity,
+		region:   "default",
+	}
+}
+
+// lookup resolves the grain through the gateways, starting with the one after
+// the gateway of the previous lookup.
+func (c *Client) lookup(msg *GrainLookup) *actor.PID {
+	start := int(c.next.Add(1))
+	for i := range len(c.config.gateways) {
+		gateway := c.config.gateways[(start+i)%len(c.config.gateways)]
+		pid := actor.NewPID(gateway.ListenAddr, "cluster/"+gateway.ID)
+		// The gateway might need to activate the grain through its owner.
+		resp, err := c.engine.Request(pid, msg, 3*c.config.requestTimeout).Result()
+		if err != nil {
+			slog.Warn("gateway did not respond", "err", err, "gateway", gateway.ID)
+			continue
+		}
+		r, ok := resp.(*ActivationResponse)
+		if !ok {
+			slog.Error("expected *ActivationResponse", "msg", reflect.TypeOf(resp))
+			return nil
+		}
+		if !r.Success {
+			return nil
+		}
+		return r.PID
+	}
+	return nil
+}
+
+// ClientGrainRef is a reference to a grain that a Client uses, see GrainRef.
+type ClientGrainRef struct {
+	client   *Client
+	kind     string
+	identity string
+	region   string
+	role     string
+}
+
+// WithRegion set's the region on where the grain should be activated.
+//
+// Defaults to a "default".
+func (g ClientGrainRef) WithRegion(region string) ClientGrainRef {
+	g.region = region
+	return g
+}
+
+// WithRole set's the role the member the grain is activated on needs to have.
+//
+// Defaults to none.
+func (g ClientGrainRef) WithRole(role string) ClientGrainRef {
+	g.role = role
+	return g
+}
+
+// PID returns the PID of the grain, which a gateway activates if needed. It
+// returns nil if the grain could not be activated or no gateway responded.
+func (g ClientGrainRef) PID() *actor.PID {
+	return g.client.lookup(&GrainLookup{
+		Kind:   g.kind,
+		ID:     g.identity,
+		Region: g.region,
+		Role:   g.role,
+	})
+}
+
+// Send sends the given message to the grain, which is activated if needed.
+func (g ClientGrainRef) Send(msg any) error {
+	pid := g.PID()
+	if pid == nil {
+		return g.unavailable()
+	}
+	g.client.engine.Send(pid, msg)
+	return nil
+}
+
+// Request sends the given message to the grain, which is activated if needed,
+// and waits for its response.
+func (g ClientGrainRef) Request(msg any, timeout time.Duration) (any, error) {
+	pid := g.PID()
+	if pid == nil {
+		return nil, g.unavailable()
+	}
+	return g.client.engine.Request(pid, msg, timeout).Result()
+}
+
+func (g ClientGrainRef) unavailable() error {
+	return fmt.Errorf("grain %s/%s: %w", g.kind, g.identity, errGrainUnavailable)
+}
+
+// handleGrainLookup resolves a grain for a client. The grain is activated the
+// same way as for the members, through the owner of its identity, or of its
+// shard if this member knows the kind as sharded.
+func (a *Agent) handleGrainLookup(c *actor.Context, msg *GrainLookup) {
+	var g GrainRef
+	if sharding := a.localKinds[msg.Kind].config.sharding; sharding != nil {
+		g = a.cluster.ShardRegion(msg.Kind, *sharding).Entity(msg.ID)
+	} else {
+		g = a.cluster.GrainRef(msg.Kind, msg.ID)
+	}
+	g = g.WithRegion(msg.Region).WithRole(msg.Role)
+	sender, engine := c.Sender(), a.cluster.engine
+	// Activating the grain takes requests to the agent, so it can't wait.
+	go func() {
+		pid := g.PID()
+		engine.Send(sender, &ActivationResponse{PID: pid, Success: pid != nil})
+	}()
+}
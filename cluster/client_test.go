@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	_, err := NewClient(NewClientConfig())
+	require.ErrorIs(t, err, errNoGateways)
+
+	a := makeSwimCluster(t, "A", fastSwimConfig())
+	a.RegisterKind("counter", newCounter, NewKindConfig())
+	a.Start()
+	defer a.Stop()
+	b := makeSwimCluster(t, "B", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))
+	b.RegisterKind("counter", newCounter, NewKindConfig())
+	b.Start()
+	defer b.Stop()
+	require.Eventually(t, func() bool {
+		return len(a.Members()) == 2 && len(b.Members()) == 2
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// The first gateway is down, so the client moves on to A.
+	client, err := NewClient(NewClientConfig().
+		WithListenAddr(getRandomLocalhostAddr()).
+		WithRequestTimeout(200 * time.Millisecond).
+		WithGateway(MemberAddr{ListenAddr: getRandomLocalhostAddr(), ID: "X"}).
+		WithGateway(MemberAddr{ListenAddr: a.Address(), ID: "A"}))
+	require.NoError(t, err)
+	defer client.Stop()
+
+	grain := client.GrainRef("counter", "1")
+	for range 2 {
+		require.NoError(t, grain.Send(&counterAdd{N: 1}))
+	}
+	resp, err := grain.Request(&counterGet{}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, &counterState{N: 2}, resp)
+
+	// The client uses the same activation as the members, and is not one of
+	// them.
+	assert.True(t, a.GrainRef("counter", "1").PID().Equals(grain.PID()))
+	assert.Len(t, a.Members(), 2)
+
+	_, err = client.GrainRef("player", "1").Request(&counterGet{}, time.Second)
+	assert.ErrorIs(t, err, errGrainUnavailable)
+}
@@ -3,10 +3,12 @@ package cluster
 import (
 	fmt "fmt"
 	"log/slog"
+	"maps"
 	"math"
 	"math/rand"
 	"reflect"
 	"slices"
+	"sync"
 	"time"
 
 	"github.com/fertigai/hollywood/actor"
@@ -16,6 +18,10 @@ import (
 // pick a reasonable timeout so nodes of long distance networks (should) work.
 var defaultRequestTimeout = time.Second
 
+// defaultSplitBrainStableAfter is how long the membership needs to be stable
+// before the split brain resolver decides.
+var defaultSplitBrainStableAfter = 5 * time.Second
+
 // Producer is a function that produces an actor.Producer given a *cluster.Cluster.
 // Pretty simple, but yet powerfull tool to construct receivers that are depending on Cluster.
 type Producer func(c *Cluster) actor.Producer
@@ -25,19 +31,43 @@ type Config struct {
 	listenAddr     string
 	id             string
 	region         string
+	version        string
+	roles          []string
 	engine         *actor.Engine
 	provider       Producer
 	requestTimeout time.Duration
+	// registry is optional, see WithActivationRegistry.
+	registry ActivationRegistry
+	// splitBrainResolver is optional, see WithSplitBrainResolver.
+	splitBrainResolver    SplitBrainResolver
+	splitBrainStableAfter time.Duration
+	// topics holds the delivery of the topics that are not best effort.
+	topics map[string]Delivery
+	// drainOldVersions drains this member once a member with a newer
+	// version joins, see WithDrainOldVersions.
+	drainOldVersions bool
+	// The limits of a rebalance, zero if there are none.
+	rebalanceConcurrency int
+	handoffRate          int
+	// The aggregator of the deadletters, see WithDeadLetterGrain and
+	// WithDeadLetterTopic.
+	deadLetterKind  string
+	deadLetterID    string
+	deadLetterTopic string
+	// The patterns of the eventstream topics that are cluster-wide, see
+	// WithClusterEvents.
+	clusterEvents []string
 }
 
 // NewConfig returns a Config that is initialized with default values.
 func NewConfig() Config {
 	return Config{
-		listenAddr:     getRandomListenAddr(),
-		id:             fmt.Sprintf("%d", rand.Intn(math.MaxInt)),
-		region:         "default",
-		provider:       NewSelfManagedProvider(NewSelfManagedConfig()),
-		requestTimeout: defaultRequestTimeout,
+		listenAddr:            getRandomListenAddr(),
+		id:                    fmt.Sprintf("%d", rand.Intn(math.MaxInt)),
+		region:                "default",
+		provider:              NewSelfManagedProvider(NewSelfManagedConfig()),
+		requestTimeout:        defaultRequestTimeout,
+		splitBrainStableAfter: defaultSplitBrainStableAfter,
 	}
 }
 
@@ -59,6 +89,139 @@ func (config Config) WithProvider(p Producer) Config {
 	return config
 }
 
+// WithActivationRegistry set's the registry that makes sure an actor is
+// activated at most once across the cluster.
+//
+// Defaults to none, in which case the members only check the activations
+// they know of.
+func (config Config) WithActivationRegistry(r ActivationRegistry) Config {
+	config.registry = r
+	return config
+}
+
+// WithSplitBrainResolver set's the strategy that decides which side of a
+// network split survives, see SplitBrainResolver. The members on the other
+// sides terminate their actors and stop, so every actor stays activated once.
+//
+// Defaults to none, in which case the sides of a split keep running on their
+// own.
+func (config Config) WithSplitBrainResolver(r SplitBrainResolver) Config {
+	config.splitBrainResolver = r
+	return config
+}
+
+// WithSplitBrainStableAfter set's how long the membership needs to be stable
+// after members were lost before the split brain resolver decides. It should
+// be longer than it takes the provider to notice that the members are gone.
+//
+// Defaults to 5 seconds.
+func (config Config) WithSplitBrainStableAfter(d time.Duration) Config {
+	config.splitBrainStableAfter = d
+	return config
+}
+
+// WithTopicDelivery set's the delivery guarantee of the messages this member
+// publishes on the given topic.
+//
+// Defaults to DeliveryBestEffort.
+func (config Config) WithTopicDelivery(topic string, delivery Delivery) Config {
+	topics := maps.Clone(config.topics)
+	if topics == nil {
+		topics = make(map[string]Delivery)
+	}
+	topics[topic] = delivery
+	config.topics = topics
+	return config
+}
+
+// WithVersion set's the version of the application this member runs, which
+// placement can take into account during rolling upgrades, see
+// PreferMinVersion and RequireMinVersion. Versions are compared by their
+// numeric parts, like "1.10.2" or "v2.0".
+//
+// Defaults to an empty version, which is older than any other.
+func (config Config) WithVersion(version string) Config {
+	config.version = version
+	return config
+}
+
+// WithRoles set's the roles of this member, like "frontend" or "gpu", which
+// activations and singletons can be restricted to, see
+// ActivationConfig.WithRole and Cluster.RoleLeader.
+//
+// Defaults to no roles.
+func (config Config) WithRoles(roles ...string) Config {
+	config.roles = slices.Clone(roles)
+	return config
+}
+
+// WithDrainOldVersions set's whether this member drains once a member with a
+// newer version joins the cluster, see Cluster.Drain.
+//
+// Defaults to false.
+func (config Config) WithDrainOldVersions(drain bool) Config {
+	config.drainOldVersions = drain
+	return config
+}
+
+// WithRebalanceConcurrency set's how many actors move off this member at the
+// same time when it rebalances, the others wait for their turn. This keeps a
+// member that joins from causing a latency storm across the cluster.
+//
+// Defaults to no limit.
+func (config Config) WithRebalanceConcurrency(n int) Config {
+	config.rebalanceConcurrency = n
+	return config
+}
+
+// WithHandoffRate set's the number of bytes per second of state the actors
+// that move off this member hand off, across all of them.
+//
+// Defaults to no limit.
+func (config Config) WithHandoffRate(bytesPerSecond int) Config {
+	config.handoffRate = bytesPerSecond
+	return config
+}
+
+// WithDeadLetterGrain set's the grain the deadletters of the actors of the
+// cluster are forwarded to as a *DeadLetter, on top of their DeadLetterEvent.
+// The grain is activated like any other, so one grain collects the
+// deadletters of all the members that forward them to it.
+//
+// Defaults to none, in which case the deadletters are only published on the
+// eventstream of the member that generated them.
+func (config Config) WithDeadLetterGrain(kind, id string) Config {
+	config.deadLetterKind, config.deadLetterID = kind, id
+	return config
+}
+
+// WithDeadLetterTopic set's the topic the deadletters of the actors of the
+// cluster are published on as a *DeadLetter, see Cluster.Subscribe.
+//
+// Defaults to none.
+func (config Config) WithDeadLetterTopic(topic string) Config {
+	config.deadLetterTopic = topic
+	return config
+}
+
+// WithClusterEvents set's the patterns of the eventstream topics that are
+// cluster-wide, see actor.WithTopics. The events this member publishes on a
+// matching topic are forwarded to all the other members, which publish them on
+// their eventstream with actor.Engine.PublishForwarded, so their subscribers
+// receive them as well. The forwarded events are not forwarded again. The
+// events need to be protobuf messages or types registered with
+// remote.RegisterCodec.
+//
+//	config := cluster.NewConfig().WithClusterEvents("ops.*")
+//	...
+//	c.Engine().Publish("ops.deploy.finished", &DeployFinished{Version: "1.2"})
+//
+// Defaults to none.
+func (config Config) WithClusterEvents(patterns ...string) Config {
+	config.clusterEvents = patterns
+	return config
+}
+
 // WithEngine set's the internal actor engine that will be used
 // to power the actors running on the node.
 //
@@ -103,22 +266,36 @@ type Cluster struct {
 	providerPID *actor.PID
 	isStarted   bool
 	kinds       []kind
+	// remote is nil if the engine was given by the config.
+	remote    *remote.Remote
+	startedAt time.Time
+	// bridges holds the bridges to other clusters by their name.
+	bridgesMu sync.RWMutex
+	bridges   map[string]*Bridge
+	// deadLetterPID is nil if the deadletters are not forwarded.
+	deadLetterPID *actor.PID
+	// eventsPID is nil if there are no cluster-wide events.
+	eventsPID *actor.PID
 }
 
 // New returns a new cluster given a Config.
 func New(config Config) (*Cluster, error) {
+	var r *remote.Remote
 	if config.engine == nil {
-		remote := remote.New(config.listenAddr, remote.NewConfig())
-		e, err := actor.NewEngine(actor.NewEngineConfig().WithRemote(remote))
+		r = remote.New(config.listenAddr, remote.NewConfig())
+		e, err := actor.NewEngine(actor.NewEngineConfig().WithRemote(r))
 		if err != nil {
 			return nil, err
 		}
 		config.engine = e
 	}
 	c := &Cluster{
-		config: config,
-		engine: config.engine,
-		kinds:  make([]kind, 0),
+		config:    config,
+		engine:    config.engine,
+		kinds:     make([]kind, 0),
+		remote:    r,
+		startedAt: time.Now(),
+		bridges:   make(map[string]*Bridge),
 	}
 	return c, nil
 }
@@ -127,11 +304,24 @@ func New(config Config) (*Cluster, error) {
 func (c *Cluster) Start() {
 	c.agentPID = c.engine.Spawn(NewAgent(c), "cluster", actor.WithID(c.config.id))
 	c.providerPID = c.engine.Spawn(c.config.provider(c), "provider", actor.WithID(c.config.id))
+	if c.config.deadLetterKind != "" || c.config.deadLetterTopic != "" {
+		c.deadLetterPID = c.engine.Spawn(newDeadLetterForwarder(c), "deadletters", actor.WithID(c.config.id))
+	}
+	if len(c.config.clusterEvents) > 0 {
+		c.eventsPID = c.engine.Spawn(newEventForwarder(c), "events", actor.WithID(c.config.id))
+	}
+	c.engine.AddHealthCheck("cluster", c)
 	c.isStarted = true
 }
 
 // Stop will shutdown the cluster poisoning all its actors.
 func (c *Cluster) Stop() {
+	if c.eventsPID != nil {
+		<-c.engine.Poison(c.eventsPID).Done()
+	}
+	if c.deadLetterPID != nil {
+		<-c.engine.Poison(c.deadLetterPID).Done()
+	}
 	<-c.engine.Poison(c.agentPID).Done()
 	<-c.engine.Poison(c.providerPID).Done()
 }
@@ -187,6 +377,9 @@ func (c *Cluster) RegisterKind(kind string, producer actor.Producer, config Kind
 		slog.Warn("failed to register kind", "reason", "cluster already started", "kind", kind)
 		return
 	}
+	if config.sharding != nil && config.sharding.passivateAfter > 0 {
+		producer = newPassivatingEntity(c, producer, config.sharding.passivateAfter)
+	}
 	c.kinds = append(c.kinds, newKind(kind, producer, config))
 }
 
@@ -281,14 +474,36 @@ func (c *Cluster) Member() *Member {
 		kinds[i] = c.kinds[i].name
 	}
 	m := &Member{
-		ID:     c.config.id,
-		Host:   c.engine.Address(),
-		Kinds:  kinds,
-		Region: c.config.region,
+		ID:        c.config.id,
+		Host:      c.engine.Address(),
+		Kinds:     kinds,
+		Region:    c.config.region,
+		StartedAt: c.startedAt.UnixNano(),
+		Version:   c.config.version,
+		Roles:     c.config.roles,
 	}
 	return m
 }
 
+// Leader returns the leader of the cluster, which is the member with the
+// highest ID that is alive. It returns nil while the leader is elected.
+func (c *Cluster) Leader() *Member {
+	resp, err := c.engine.Request(c.agentPID, getLeader{}, c.config.requestTimeout).Result()
+	if err != nil {
+		return nil
+	}
+	if leader, ok := resp.(*Member); ok {
+		return leader
+	}
+	return nil
+}
+
+// IsLeader returns true whether this member is the leader of the cluster.
+func (c *Cluster) IsLeader() bool {
+	leader := c.Leader()
+	return leader != nil && leader.ID == c.config.id
+}
+
 // Engine returns the actor engine.
 func (c *Cluster) Engine() *actor.Engine {
 	return c.engine
@@ -328,17 +328,24 @@ func TestGetActiveByID(t *testing.T) {
 	c2 := makeCluster(t, c2Addr, "B", "eu")
 	c2.RegisterKind("player", NewPlayer, NewKindConfig())
 	c2.Start()
+	require.Eventually(t, func() bool {
+		return len(c1.Members()) == 2 && len(c2.Members()) == 2
+	}, time.Second, 10*time.Millisecond)
 
 	pid1 := c1.Activate("player", NewActivationConfig().WithID("1"))
+	require.NotNil(t, pid1)
 	pid2 := c2.Activate("player", NewActivationConfig().WithID("2"))
-	time.Sleep(time.Millisecond * 10)
+	require.NotNil(t, pid2)
+	require.Eventually(t, func() bool {
+		return c1.GetActiveByID("player/2") != nil
+	}, time.Second, 10*time.Millisecond)
 
 	pid := c1.GetActiveByID("player/1")
-	assert.NotNil(t, pid)
+	require.NotNil(t, pid)
 	assert.Equal(t, pid.ID, pid1.ID)
 
 	pid = c1.GetActiveByID("player/2")
-	assert.NotNil(t, pid)
+	require.NotNil(t, pid)
 	assert.Equal(t, pid.ID, pid2.ID)
 
 	pid = c1.GetActiveByID("player/3")
@@ -389,9 +396,15 @@ func TestCannotDuplicateActor(t *testing.T) {
 	c2 := makeCluster(t, c2Addr, "B", "eu")
 	c2.RegisterKind("player", NewPlayer, NewKindConfig())
 	c2.Start()
+	require.Eventually(t, func() bool {
+		return len(c1.Members()) == 2 && len(c2.Members()) == 2
+	}, time.Second, 10*time.Millisecond)
 
 	pid := c1.Activate("player", NewActivationConfig().WithID("1"))
-	time.Sleep(10 * time.Millisecond)
+	require.NotNil(t, pid)
+	require.Eventually(t, func() bool {
+		return c2.GetActiveByID("player/1") != nil
+	}, time.Second, 10*time.Millisecond)
 	// Lets make sure we spawn the actor on "our" node. Why?
 	// Because when we randomly selected the other node to spawn the actor
 	// with the same id on the test will pass.
@@ -399,8 +412,7 @@ func TestCannotDuplicateActor(t *testing.T) {
 	pid2 := c2.Activate("player", NewActivationConfig().WithID("1").WithSelectMemberFunc(func(_ ActivationDetails) *Member {
 		return c2.Member()
 	}))
-	fmt.Println(pid2)
-	time.Sleep(time.Millisecond * 10)
+	assert.Nil(t, pid2)
 
 	pids := c1.GetActiveByKind("player")
 	assert.Len(t, pids, 1)
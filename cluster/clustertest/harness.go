@@ -0,0 +1,238 @@
+// Package clustertest runs the members of a cluster in a single process over
+// an in-memory network, so tests can inject partitions, message delays and
+// crashes, and check how the cluster copes with them.
+//
+//	h := clustertest.New(t, clustertest.NewConfig())
+//	a := h.AddMember("A", cluster.NewConfig(), nil)
+//	b := h.AddMember("B", cluster.NewConfig(), nil)
+//	h.AwaitMembers("A", "B")
+//	h.Partition([]string{"A"}, []string{"B"})
+//	h.AwaitMembers("A")
+//	h.Heal()
+//
+// The faults take effect before the calls that inject them return, so a test
+// controls which messages they hit.
+package clustertest
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/cluster"
+	"github.com/fertigai/hollywood/remote"
+)
+
+const defaultAwaitTimeout = 5 * time.Second
+
+// Config holds the configuration of a Harness.
+type Config struct {
+	swim         cluster.SwimConfig
+	awaitTimeout time.Duration
+}
+
+// NewConfig returns a Config that is initialized with default values.
+func NewConfig() Config {
+	return Config{
+		swim: cluster.NewSwimConfig().
+			WithProbeInterval(50 * time.Millisecond).
+			WithProbeTimeout(20 * time.Millisecond).
+			WithSuspicionTimeout(200 * time.Millisecond).
+			WithSyncInterval(200 * time.Millisecond),
+		awaitTimeout: defaultAwaitTimeout,
+	}
+}
+
+// WithSwimConfig set's the configuration of the SWIM provider the members
+// discover each other with. The harness adds the members that are up as the
+// seeds of a new member.
+//
+// Defaults to a probe interval of 50 milliseconds, a probe timeout of 20
+// milliseconds and a suspicion timeout and sync interval of 200 milliseconds,
+// so failures are detected and healed quickly.
+func (config Config) WithSwimConfig(swim cluster.SwimConfig) Config {
+	config.swim = swim
+	return config
+}
+
+// WithAwaitTimeout set's how long AwaitMembers waits before it fails the
+// test.
+//
+// Defaults to 5 seconds.
+func (config Config) WithAwaitTimeout(d time.Duration) Config {
+	config.awaitTimeout = d
+	return config
+}
+
+type member struct {
+	cluster *cluster.Cluster
+	remote  *remote.Remote
+	crashed bool
+}
+
+// Harness runs the members of a cluster and injects faults into the network
+// between them. The members are stopped when the test finishes.
+type Harness struct {
+	t       testing.TB
+	config  Config
+	network *network
+
+	mu      sync.Mutex
+	members map[string]*member
+	order   []string
+}
+
+// New returns a new Harness for the given test.
+func New(t testing.TB, config Config) *Harness {
+	h := &Harness{
+		t:       t,
+		config:  config,
+		network: newNetwork(),
+		members: make(map[string]*member),
+	}
+	t.Cleanup(h.stop)
+	return h
+}
+
+// AddMember starts a member with the given ID, which joins the members that
+// are up. The harness sets the ID, the engine and the provider of the config.
+// Setup is invoked before the member starts, to register its kinds, and can
+// be nil.
+func (h *Harness) AddMember(id string, config cluster.Config, setup func(*cluster.Cluster)) *cluster.Cluster {
+	h.t.Helper()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.members[id]; ok {
+		h.t.Fatalf("clustertest: member %s already exists", id)
+	}
+	swim := h.config.swim
+	for _, other := range h.order {
+		if m := h.members[other]; !m.crashed {
+			swim = swim.WithSeed(cluster.MemberAddr{ListenAddr: m.cluster.Address(), ID: other})
+		}
+	}
+	r := remote.New(scheme+"://"+id, remote.NewConfig().WithTransport(scheme, h.network.transport(id)))
+	e, err := actor.NewEngine(actor.NewEngineConfig().WithRemote(r))
+	if err != nil {
+		h.t.Fatalf("clustertest: failed to create the engine of member %s: %v", id, err)
+	}
+	c, err := cluster.New(config.WithID(id).WithEngine(e).WithProvider(cluster.NewSwimProvider(swim)))
+	if err != nil {
+		h.t.Fatalf("clustertest: failed to create member %s: %v", id, err)
+	}
+	if setup != nil {
+		setup(c)
+	}
+	c.Start()
+	h.members[id] = &member{cluster: c, remote: r}
+	h.order = append(h.order, id)
+	return c
+}
+
+// Member returns the member with the given ID, or nil if there is none.
+func (h *Harness) Member(id string) *cluster.Cluster {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if m, ok := h.members[id]; ok {
+		return m.cluster
+	}
+	return nil
+}
+
+// Partition splits the members into the given groups of IDs, and the members
+// of a group only reach each other. The members that are in none of the
+// groups form a group of their own. Partitions add up until Heal.
+func (h *Harness) Partition(groups ...[]string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	group := make(map[string]int, len(h.order))
+	for i, ids := range groups {
+		for _, id := range ids {
+			group[id] = i + 1
+		}
+	}
+	for i, a := range h.order {
+		for _, b := range h.order[i+1:] {
+			if group[a] != group[b] {
+				h.network.block(a, b)
+			}
+		}
+	}
+}
+
+// Heal removes all the partitions. The members that crashed stay down.
+func (h *Harness) Heal() {
+	h.network.heal()
+}
+
+// Delay holds back the messages from one member to another for the given
+// duration, keeping their order. Zero removes the delay.
+func (h *Harness) Delay(from, to string, d time.Duration) {
+	h.network.setDelay(from, to, d)
+}
+
+// Crash stops the member with the given ID without leaving the cluster. The
+// member is cut from the network first, so the others only notice it's gone
+// when they fail to reach it, and the messages on the way are lost.
+func (h *Harness) Crash(id string) {
+	h.t.Helper()
+	h.mu.Lock()
+	m, ok := h.members[id]
+	if !ok || m.crashed {
+		h.mu.Unlock()
+		h.t.Fatalf("clustertest: no member %s that is up", id)
+	}
+	m.crashed = true
+	h.mu.Unlock()
+	h.network.crash(id)
+	m.cluster.Stop()
+	m.remote.Stop().Wait()
+}
+
+// AwaitMembers waits until every member with one of the given IDs has exactly
+// those members, and fails the test if they did not agree in time.
+func (h *Harness) AwaitMembers(ids ...string) {
+	h.t.Helper()
+	want := slices.Clone(ids)
+	slices.Sort(want)
+	deadline := time.Now().Add(h.config.awaitTimeout)
+	for _, id := range ids {
+		c := h.Member(id)
+		if c == nil {
+			h.t.Fatalf("clustertest: no member %s", id)
+		}
+		var got []string
+		for {
+			got = got[:0]
+			for _, m := range c.Members() {
+				got = append(got, m.ID)
+			}
+			slices.Sort(got)
+			if slices.Equal(got, want) {
+				break
+			}
+			if time.Now().After(deadline) {
+				h.t.Fatalf("clustertest: member %s has members %v, expected %v", id, got, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func (h *Harness) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// The network is cut first, so the members don't wait for each other.
+	for _, id := range h.order {
+		h.network.crash(id)
+	}
+	for _, id := range h.order {
+		if m := h.members[id]; !m.crashed {
+			m.crashed = true
+			m.cluster.Stop()
+			m.remote.Stop().Wait()
+		}
+	}
+}
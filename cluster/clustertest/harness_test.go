@@ -0,0 +1,83 @@
+package clustertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/cluster"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartition(t *testing.T) {
+	h := New(t, NewConfig())
+	h.AddMember("A", cluster.NewConfig(), nil)
+	h.AddMember("B", cluster.NewConfig(), nil)
+	h.AddMember("C", cluster.NewConfig(), nil)
+	h.AwaitMembers("A", "B", "C")
+
+	h.Partition([]string{"A"})
+	h.AwaitMembers("A")
+	h.AwaitMembers("B", "C")
+
+	// A message across the partition is lost.
+	received := make(chan any, 1)
+	pid := h.Member("B").Engine().SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*actor.Ping); ok {
+			received <- msg
+		}
+	}, "receiver")
+	h.Member("A").Engine().Send(pid, &actor.Ping{})
+	h.Member("C").Engine().Send(pid, &actor.Ping{})
+	<-received
+	select {
+	case <-received:
+		t.Fatal("a message crossed the partition")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	h.Heal()
+	h.AwaitMembers("A", "B", "C")
+}
+
+func TestDelay(t *testing.T) {
+	h := New(t, NewConfig().WithSwimConfig(cluster.NewSwimConfig()))
+	a := h.AddMember("A", cluster.NewConfig(), nil)
+	b := h.AddMember("B", cluster.NewConfig(), nil)
+	h.AwaitMembers("A", "B")
+
+	pid := b.Engine().SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(*actor.Ping); ok {
+			c.Respond(&actor.Pong{})
+		}
+	}, "ponger")
+	_, err := a.Engine().Request(pid, &actor.Ping{}, time.Second).Result()
+	require.NoError(t, err)
+
+	h.Delay("A", "B", 200*time.Millisecond)
+	start := time.Now()
+	_, err = a.Engine().Request(pid, &actor.Ping{}, time.Second).Result()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	h.Delay("A", "B", 0)
+	start = time.Now()
+	_, err = a.Engine().Request(pid, &actor.Ping{}, time.Second).Result()
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestCrash(t *testing.T) {
+	h := New(t, NewConfig())
+	h.AddMember("A", cluster.NewConfig(), nil)
+	h.AddMember("B", cluster.NewConfig(), nil)
+	h.AddMember("C", cluster.NewConfig(), nil)
+	h.AwaitMembers("A", "B", "C")
+
+	h.Crash("C")
+	h.AwaitMembers("A", "B")
+	// A member that joins later does not learn about the crashed member.
+	h.AddMember("D", cluster.NewConfig(), nil)
+	h.AwaitMembers("A", "B", "D")
+}
@@ -0,0 +1,299 @@
+package clustertest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/fertigai/hollywood/remote"
+)
+
+// scheme is the address scheme of the in-memory transport.
+const scheme = "mem"
+
+// connQueueSize is the number of writes a connection holds while they are
+// delayed, after which the writer blocks.
+const connQueueSize = 1024
+
+var (
+	errUnreachable = errors.New("clustertest: peer unreachable")
+	errRefused     = errors.New("clustertest: connection refused")
+	errInUse       = errors.New("clustertest: address in use")
+)
+
+// link is the direction from the member that writes to the member that reads.
+type link struct {
+	from, to string
+}
+
+// network connects the members of a harness over in-memory connections, and
+// injects the faults of the harness into them. The members are identified by
+// their address without the scheme.
+type network struct {
+	mu        sync.Mutex
+	listeners map[string]*listener
+	conns     map[*conn]struct{}
+	// blocked holds the links the messages can't pass.
+	blocked map[link]bool
+	delays  map[link]time.Duration
+	crashed map[string]bool
+}
+
+func newNetwork() *network {
+	return &network{
+		listeners: make(map[string]*listener),
+		conns:     make(map[*conn]struct{}),
+		blocked:   make(map[link]bool),
+		delays:    make(map[link]time.Duration),
+		crashed:   make(map[string]bool),
+	}
+}
+
+// transport returns the transport of the member with the given address.
+func (n *network) transport(addr string) remote.Transport {
+	return transport{network: n, addr: addr}
+}
+
+// block cuts the given members from each other, closing the connections
+// between them.
+func (n *network) block(a, b string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.blocked[link{a, b}] = true
+	n.blocked[link{b, a}] = true
+	n.closeLocked(func(l link) bool {
+		return l == link{a, b} || l == link{b, a}
+	})
+}
+
+// heal removes all the blocked links, but the crashed members stay down.
+func (n *network) heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	clear(n.blocked)
+}
+
+func (n *network) setDelay(from, to string, d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if d <= 0 {
+		delete(n.delays, link{from, to})
+		return
+	}
+	n.delays[link{from, to}] = d
+}
+
+func (n *network) delay(l link) time.Duration {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.delays[l]
+}
+
+// crash cuts the member from all the others for good, dropping the messages
+// that are still on the way.
+func (n *network) crash(addr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.crashed[addr] = true
+	n.closeLocked(func(l link) bool {
+		return l.from == addr || l.to == addr
+	})
+}
+
+func (n *network) closeLocked(match func(link) bool) {
+	for c := range n.conns {
+		if match(c.link) {
+			delete(n.conns, c)
+			// Closing the connection takes the lock to remove it.
+			go c.Close()
+		}
+	}
+}
+
+func (n *network) reachableLocked(l link) bool {
+	return !n.crashed[l.from] && !n.crashed[l.to] && !n.blocked[l]
+}
+
+func (n *network) listen(addr string) (net.Listener, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.listeners[addr]; ok {
+		return nil, errInUse
+	}
+	ln := &listener{
+		network: n,
+		addr:    addr,
+		conns:   make(chan net.Conn),
+		closech: make(chan struct{}),
+	}
+	n.listeners[addr] = ln
+	return ln, nil
+}
+
+func (n *network) dial(ctx context.Context, from, to string) (net.Conn, error) {
+	n.mu.Lock()
+	ln, ok := n.listeners[to]
+	if !ok {
+		n.mu.Unlock()
+		return nil, errRefused
+	}
+	if !n.reachableLocked(link{from, to}) {
+		n.mu.Unlock()
+		return nil, errUnreachable
+	}
+	client, server := net.Pipe()
+	c1, c2 := n.newConnLocked(client, link{from, to}), n.newConnLocked(server, link{to, from})
+	n.mu.Unlock()
+
+	var err error
+	select {
+	case ln.conns <- c2:
+		return c1, nil
+	case <-ln.closech:
+		err = errRefused
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	c1.Close()
+	c2.Close()
+	return nil, err
+}
+
+func (n *network) newConnLocked(pipe net.Conn, l link) *conn {
+	c := &conn{
+		Conn:    pipe,
+		network: n,
+		link:    l,
+		queue:   make(chan chunk, connQueueSize),
+		closech: make(chan struct{}),
+	}
+	n.conns[c] = struct{}{}
+	go c.relay()
+	return c
+}
+
+func (n *network) removeListener(ln *listener) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.listeners[ln.addr] == ln {
+		delete(n.listeners, ln.addr)
+	}
+}
+
+func (n *network) removeConn(c *conn) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.conns, c)
+}
+
+type transport struct {
+	network *network
+	addr    string
+}
+
+func (t transport) Listen(addr string) (net.Listener, error) {
+	return t.network.listen(addr)
+}
+
+func (t transport) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	return t.network.dial(ctx, t.addr, addr)
+}
+
+type listener struct {
+	network *network
+	addr    string
+	conns   chan net.Conn
+	closech chan struct{}
+	once    sync.Once
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closech:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *listener) Close() error {
+	l.once.Do(func() {
+		close(l.closech)
+		l.network.removeListener(l)
+	})
+	return nil
+}
+
+func (l *listener) Addr() net.Addr { return addr(l.addr) }
+
+// chunk is a write that is delivered to the reader once its time has come.
+type chunk struct {
+	data []byte
+	at   time.Time
+}
+
+// conn is one end of an in-memory connection. The writes go through a queue
+// that holds them back for the delay of the link, and are relayed to the
+// other end in order.
+type conn struct {
+	net.Conn
+	network *network
+	link    link
+	queue   chan chunk
+	closech chan struct{}
+	once    sync.Once
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	ch := chunk{data: bytes.Clone(b), at: time.Now().Add(c.network.delay(c.link))}
+	select {
+	case c.queue <- ch:
+		return len(b), nil
+	case <-c.closech:
+		return 0, net.ErrClosed
+	}
+}
+
+func (c *conn) relay() {
+	for {
+		select {
+		case ch := <-c.queue:
+			if d := time.Until(ch.at); d > 0 {
+				timer := time.NewTimer(d)
+				select {
+				case <-timer.C:
+				case <-c.closech:
+					timer.Stop()
+					return
+				}
+			}
+			if _, err := c.Conn.Write(ch.data); err != nil {
+				c.Close()
+				return
+			}
+		case <-c.closech:
+			return
+		}
+	}
+}
+
+// Close closes the connection, dropping the writes that were not delivered.
+func (c *conn) Close() error {
+	c.once.Do(func() {
+		close(c.closech)
+		c.Conn.Close()
+		c.network.removeConn(c)
+	})
+	return nil
+}
+
+func (c *conn) LocalAddr() net.Addr  { return addr(c.link.from) }
+func (c *conn) RemoteAddr() net.Addr { return addr(c.link.to) }
+
+type addr string
+
+func (a addr) Network() string { return scheme }
+func (a addr) String() string  { return string(a) }
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fertigai/hollywood/actor"
@@ -39,8 +40,10 @@ type ConsulProvider struct {
 	cluster   *Cluster
 	client    *api.Client
 	id        string
+	plan      *watch.Plan
 	prevIndex watch.BlockingParamVal
 	quitch    chan struct{}
+	ttlDone   chan struct{}
 }
 
 func (p *ConsulProvider) Receive(c *actor.Context) {
@@ -50,10 +53,25 @@ func (p *ConsulProvider) Receive(c *actor.Context) {
 		if err := p.registerService(); err != nil {
 			panic(err)
 		}
-		go p.watch()
+		plan, err := watch.Parse(map[string]any{
+			"type":        "service",
+			"service":     "hollywood_actor",
+			"passingonly": true,
+		})
+		if err != nil {
+			slog.Warn("consul provider", "err", err.Error())
+		} else {
+			p.plan = plan
+			go p.watch()
+		}
 		go p.updateTTL()
 	case actor.Stopped:
 		close(p.quitch)
+		<-p.ttlDone
+		if p.plan != nil {
+			p.plan.Stop()
+		}
+		p.deregisterService()
 	}
 }
 
@@ -72,6 +90,7 @@ func NewConsulProvider(config ConsulProviderConfig) Producer {
 				client:    client,
 				cluster:   c,
 				quitch:    make(chan struct{}),
+				ttlDone:   make(chan struct{}),
 			}
 		}
 	}
@@ -85,6 +104,8 @@ func (p *ConsulProvider) registerService() error {
 	)
 
 	meta["name"] = config.id
+	meta["version"] = config.version
+	meta["roles"] = strings.Join(config.roles, ",")
 	check := &api.AgentServiceCheck{
 		DeregisterCriticalServiceAfter: removeTTL.String(),
 		TLSSkipVerify:                  true,
@@ -114,24 +135,21 @@ func (p *ConsulProvider) registerService() error {
 	return p.client.Agent().ServiceRegisterOpts(reg, regopts)
 }
 
-func (p *ConsulProvider) watch() {
-	query := map[string]any{
-		"type":        "service",
-		"service":     "hollywood_actor",
-		"passingonly": true,
+// deregisterService removes the node from Consul, so the other members don't
+// have to wait for its health check to become critical.
+func (p *ConsulProvider) deregisterService() {
+	if err := p.client.Agent().ServiceDeregister(p.id); err != nil {
+		slog.Warn("failed to deregister from consul", "err", err.Error())
 	}
+}
 
-	plan, err := watch.Parse(query)
-	if err != nil {
-		slog.Warn("consul provider", "err", err.Error())
-		return
-	}
-	plan.HybridHandler = p.onUpdate
+func (p *ConsulProvider) watch() {
+	p.plan.HybridHandler = p.onUpdate
 
 	config := &api.Config{
 		Address: p.config.address,
 	}
-	if err := plan.RunWithConfig(config.Address, config); err != nil {
+	if err := p.plan.RunWithConfig(config.Address, config); err != nil {
 		panic(err)
 	}
 }
@@ -147,9 +165,11 @@ func (p *ConsulProvider) onUpdate(index watch.BlockingParamVal, msg any) {
 		if len(entry.Checks) > 0 && entry.Checks.AggregatedStatus() == api.HealthPassing {
 			port := strconv.Itoa(entry.Service.Port)
 			member := &Member{
-				ID:    entry.Service.Meta["name"],
-				Host:  entry.Service.Address + ":" + port,
-				Kinds: entry.Service.Tags,
+				ID:      entry.Service.Meta["name"],
+				Host:    entry.Service.Address + ":" + port,
+				Kinds:   entry.Service.Tags,
+				Version: entry.Service.Meta["version"],
+				Roles:   strings.FieldsFunc(entry.Service.Meta["roles"], func(r rune) bool { return r == ',' }),
 			}
 			members = append(members, member)
 		}
@@ -164,7 +184,9 @@ func (p *ConsulProvider) onUpdate(index watch.BlockingParamVal, msg any) {
 }
 
 func (p *ConsulProvider) updateTTL() {
+	defer close(p.ttlDone)
 	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
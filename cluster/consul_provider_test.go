@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConsul implements the parts of the agent and health API of Consul that
+// the provider uses.
+type fakeConsul struct {
+	mu       sync.Mutex
+	services map[string]*api.AgentServiceRegistration
+	index    int
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.URL.Path == "/v1/agent/service/register":
+		var reg api.AgentServiceRegistration
+		if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.services[reg.ID] = &reg
+		f.index++
+	case strings.HasPrefix(r.URL.Path, "/v1/agent/service/deregister/"):
+		delete(f.services, strings.TrimPrefix(r.URL.Path, "/v1/agent/service/deregister/"))
+		f.index++
+	case strings.HasPrefix(r.URL.Path, "/v1/agent/check/update/"):
+	case r.URL.Path == "/v1/health/service/hollywood_actor":
+		// Don't block, the watch polls instead.
+		time.Sleep(10 * time.Millisecond)
+		var entries []*api.ServiceEntry
+		for _, reg := range f.services {
+			entries = append(entries, &api.ServiceEntry{
+				Service: &api.AgentService{ID: reg.ID, Address: reg.Address, Port: reg.Port, Tags: reg.Tags, Meta: reg.Meta},
+				Checks:  api.HealthChecks{{Status: api.HealthPassing}},
+			})
+		}
+		w.Header().Set("X-Consul-Index", strconv.Itoa(f.index))
+		json.NewEncoder(w).Encode(entries)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeConsul) registered() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.services)
+}
+
+func TestConsulProvider(t *testing.T) {
+	consul := &fakeConsul{services: make(map[string]*api.AgentServiceRegistration)}
+	server := httptest.NewServer(consul)
+	defer server.Close()
+	config := NewConsulProviderConfig().WithAddress(strings.TrimPrefix(server.URL, "http://"))
+
+	var clusters []*Cluster
+	for _, id := range []string{"A", "B"} {
+		c, err := New(NewConfig().
+			WithID(id).
+			WithListenAddr(getRandomLocalhostAddr()).
+			WithProvider(NewConsulProvider(config)))
+		require.NoError(t, err)
+		c.RegisterKind("player"+id, NewPlayer, NewKindConfig())
+		c.Start()
+		clusters = append(clusters, c)
+	}
+	defer clusters[0].Stop()
+	for _, c := range clusters {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 2 && c.HasKind("playerA") && c.HasKind("playerB")
+		}, 2*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+
+	// B deregisters when it stops, so A doesn't wait for its check to fail.
+	clusters[1].Stop()
+	assert.Equal(t, 1, consul.registered())
+	assert.Eventually(t, func() bool {
+		return len(clusters[0].Members()) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+}
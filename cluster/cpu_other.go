@@ -0,0 +1,11 @@
+//go:build !unix
+
+package cluster
+
+import "time"
+
+// processCPUTime returns 0, as the CPU time of the process is not available
+// on this platform.
+func processCPUTime() time.Duration {
+	return 0
+}
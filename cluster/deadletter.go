@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/remote"
+)
+
+// Message returns the message that could not be delivered. It returns an
+// error if the message could not be serialized by the member that generated
+// the deadletter, or is of a type that is not registered here.
+func (d *DeadLetter) Message() (any, error) {
+	return remote.DefaultSerializer{}.Deserialize(d.Data, d.TypeName)
+}
+
+// deadLetterForwarder forwards the deadletters of the actors of the cluster
+// to the aggregator of the config. An actor is of the cluster if it's
+// identified by a kind one of the members has registered.
+type deadLetterForwarder struct {
+	cluster *Cluster
+	kinds   map[string]bool
+	grain   GrainRef
+}
+
+func newDeadLetterForwarder(c *Cluster) actor.Producer {
+	return func() actor.Receiver {
+		f := &deadLetterForwarder{
+			cluster: c,
+			kinds:   make(map[string]bool),
+		}
+		for _, kind := range c.kinds {
+			f.kinds[kind.name] = true
+		}
+		if c.config.deadLetterKind != "" {
+			f.grain = c.GrainRef(c.config.deadLetterKind, c.config.deadLetterID)
+		}
+		return f
+	}
+}
+
+func (f *deadLetterForwarder) Receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.Started:
+		c.Engine().Subscribe(c.PID())
+	case actor.Stopped:
+		c.Engine().Unsubscribe(c.PID())
+	case TopologyChangedEvent:
+		clear(f.kinds)
+		for _, member := range msg.Members {
+			for _, kind := range member.Kinds {
+				f.kinds[kind] = true
+			}
+		}
+	case actor.DeadLetterEvent:
+		if f.shouldForward(msg) {
+			f.forward(msg)
+		}
+	}
+}
+
+func (f *deadLetterForwarder) shouldForward(msg actor.DeadLetterEvent) bool {
+	if msg.Target == nil {
+		return false
+	}
+	// The deadletters of the aggregator would come back to it.
+	if _, ok := msg.Message.(*DeadLetter); ok {
+		return false
+	}
+	if f.grain.cluster != nil && msg.Target.ID == f.grain.kind+"/"+f.grain.Identity() {
+		return false
+	}
+	kind, _, ok := strings.Cut(msg.Target.ID, "/")
+	return ok && f.kinds[kind]
+}
+
+func (f *deadLetterForwarder) forward(msg actor.DeadLetterEvent) {
+	serializer := remote.DefaultSerializer{}
+	dl := &DeadLetter{
+		Target:   msg.Target,
+		Sender:   msg.Sender,
+		Member:   f.cluster.Member(),
+		TypeName: serializer.TypeName(msg.Message),
+	}
+	// The message is forwarded without its data if it's not serializable,
+	// like the internal messages of the engine.
+	if b, err := serializer.Serialize(msg.Message); err == nil {
+		dl.Data = b
+	}
+	if f.grain.cluster != nil {
+		if err := f.grain.Send(dl); err != nil {
+			slog.Warn("failed to forward deadletter", "err", err, "target", msg.Target)
+		}
+	}
+	if topic := f.cluster.config.deadLetterTopic; topic != "" {
+		if err := f.cluster.Publish(topic, dl); err != nil {
+			slog.Warn("failed to publish deadletter", "err", err, "topic", topic, "target", msg.Target)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type deadLetterCollector struct{ ch chan *DeadLetter }
+
+func (d deadLetterCollector) Receive(c *actor.Context) {
+	if msg, ok := c.Message().(*DeadLetter); ok {
+		d.ch <- msg
+	}
+}
+
+func TestDeadLetterAggregation(t *testing.T) {
+	grainCh := make(chan *DeadLetter, 10)
+	topicCh := make(chan *DeadLetter, 10)
+	newCollector := func() actor.Receiver { return deadLetterCollector{ch: grainCh} }
+
+	a := makeSwimCluster(t, "A", fastSwimConfig())
+	a.RegisterKind("collector", newCollector, NewKindConfig())
+	a.Start()
+	defer a.Stop()
+	b, err := New(NewConfig().
+		WithID("B").
+		WithListenAddr(getRandomLocalhostAddr()).
+		WithProvider(NewSwimProvider(fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))).
+		WithDeadLetterGrain("collector", "ops").
+		WithDeadLetterTopic("deadletters"))
+	require.NoError(t, err)
+	b.RegisterKind("player", NewPlayer, NewKindConfig())
+	b.Start()
+	defer b.Stop()
+	require.Eventually(t, func() bool {
+		return len(a.Members()) == 2 && len(b.Members()) == 2
+	}, 3*time.Second, 10*time.Millisecond)
+	sub := a.Engine().SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*DeadLetter); ok {
+			topicCh <- msg
+		}
+	}, "sub")
+	a.Subscribe("deadletters", sub)
+
+	pid := b.Activate("player", NewActivationConfig().WithID("1").WithSelectMemberFunc(SelectLocalMember))
+	require.NotNil(t, pid)
+	<-b.Engine().Poison(pid).Done()
+	// Only the actors of the cluster are of interest.
+	a.Engine().Send(actor.NewPID(b.Address(), "other/1"), &counterAdd{N: 1})
+	a.Engine().Send(pid, &counterAdd{N: 2})
+
+	for _, ch := range []chan *DeadLetter{grainCh, topicCh} {
+		select {
+		case dl := <-ch:
+			assert.True(t, pid.Equals(dl.Target))
+			assert.Equal(t, "B", dl.Member.ID)
+			msg, err := dl.Message()
+			require.NoError(t, err)
+			assert.Equal(t, &counterAdd{N: 2}, msg)
+		case <-time.After(3 * time.Second):
+			t.Fatal("deadletter was not forwarded")
+		}
+	}
+	select {
+	case dl := <-grainCh:
+		t.Fatalf("unexpected deadletter for %s", dl.Target)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,290 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+const defaultDNSInterval = 5 * time.Second
+
+type (
+	dnsResolve  struct{}
+	dnsResolved struct {
+		addrs map[string]bool
+	}
+)
+
+type DNSProviderConfig struct {
+	name     string
+	srv      bool
+	port     int
+	interval time.Duration
+	resolver *net.Resolver
+}
+
+// NewDNSProviderConfig returns a DNSProviderConfig that is initialized with
+// default values.
+func NewDNSProviderConfig() DNSProviderConfig {
+	return DNSProviderConfig{
+		interval: defaultDNSInterval,
+		resolver: net.DefaultResolver,
+	}
+}
+
+// WithName set's the DNS name that resolves to the members of the cluster,
+// for example the name of a headless service.
+func (c DNSProviderConfig) WithName(name string) DNSProviderConfig {
+	c.name = name
+	return c
+}
+
+// WithSRV set's whether the name is resolved with SRV records, which hold the
+// port of each member as well. Otherwise the A and AAAA records of the name
+// are resolved, and the members are expected to listen on the same port.
+//
+// Defaults to false.
+func (c DNSProviderConfig) WithSRV(srv bool) DNSProviderConfig {
+	c.srv = srv
+	return c
+}
+
+// WithPort set's the port the members listen on when the name is not resolved
+// with SRV records.
+//
+// Defaults to the port this node listens on.
+func (c DNSProviderConfig) WithPort(port int) DNSProviderConfig {
+	c.port = port
+	return c
+}
+
+// WithInterval set's the interval at which the name is resolved.
+//
+// Defaults to 5 seconds.
+func (c DNSProviderConfig) WithInterval(d time.Duration) DNSProviderConfig {
+	c.interval = d
+	return c
+}
+
+// WithResolver set's the resolver used to resolve the name.
+//
+// Defaults to net.DefaultResolver.
+func (c DNSProviderConfig) WithResolver(r *net.Resolver) DNSProviderConfig {
+	c.resolver = r
+	return c
+}
+
+// DNSProvider is a provider that discovers the members of the cluster by
+// resolving a DNS name periodically, as offered by the service discovery of
+// ECS, Nomad or Consul. Each resolved address is sent a handshake, so the
+// members learn about each other's IDs and kinds. A member leaves the cluster
+// when its address no longer resolves, or when it becomes unreachable, in
+// which case the name is resolved again right away.
+//
+// The members need to listen on the addresses the name resolves to.
+type DNSProvider struct {
+	config       DNSProviderConfig
+	cluster      *Cluster
+	pid          *actor.PID
+	discoveryPID *actor.PID
+	eventSubPID  *actor.PID
+	ticker       actor.SendRepeater
+
+	// addrs holds the addresses of the last resolve.
+	addrs map[string]bool
+	// members holds the members that answered our handshake, keyed by
+	// their address.
+	members   map[string]*Member
+	resolving bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewDNSProvider returns a provider that discovers the members of the cluster
+// with DNS.
+func NewDNSProvider(config DNSProviderConfig) Producer {
+	return func(c *Cluster) actor.Producer {
+		return func() actor.Receiver {
+			return &DNSProvider{
+				config:  config,
+				cluster: c,
+				addrs:   make(map[string]bool),
+				members: make(map[string]*Member),
+			}
+		}
+	}
+}
+
+func (p *DNSProvider) Receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.Started:
+		p.pid = c.PID()
+		p.ctx, p.cancel = context.WithCancel(context.Background())
+		p.discoveryPID = spawnDiscovery(p.cluster, c.PID())
+		p.eventSubPID = c.SpawnChildFunc(p.handleEventStream, "event")
+		p.cluster.engine.Subscribe(p.eventSubPID)
+		p.sendMembersToAgent()
+		p.resolve()
+		p.ticker = c.SendRepeat(c.PID(), dnsResolve{}, p.config.interval)
+	case actor.Stopped:
+		p.ticker.Stop()
+		p.cluster.engine.Unsubscribe(p.eventSubPID)
+		p.cluster.engine.Poison(p.discoveryPID)
+		p.cancel()
+	case dnsResolve:
+		p.resolve()
+	case dnsResolved:
+		p.resolving = false
+		p.updateAddrs(msg.addrs)
+	case memberLeave:
+		if _, ok := p.members[msg.ListenAddr]; ok {
+			delete(p.members, msg.ListenAddr)
+			p.sendMembersToAgent()
+			p.resolve()
+		}
+	case *Handshake:
+		// A new member might be the first of several to join, so look for
+		// the others right away.
+		if _, ok := p.members[msg.Member.Host]; !ok {
+			p.resolve()
+		}
+		p.addMembers(msg.Member)
+		p.send(c.Sender(), &Members{Members: []*Member{p.cluster.Member()}})
+	case *Members:
+		p.addMembers(msg.Members...)
+	case actor.Initialized:
+		_ = msg
+	default:
+		slog.Warn("received unhandled message", "msg", msg, "t", reflect.TypeOf(msg))
+	}
+}
+
+func (p *DNSProvider) handleEventStream(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.RemoteUnreachableEvent:
+		c.Send(p.pid, memberLeave{ListenAddr: msg.ListenAddr})
+	}
+}
+
+// resolve looks up the name in the background, unless a lookup is running
+// already.
+func (p *DNSProvider) resolve() {
+	if p.resolving {
+		return
+	}
+	p.resolving = true
+	go func() {
+		addrs, err := p.lookup()
+		if p.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Warn("dns provider", "err", err, "name", p.config.name)
+			// Keep the members of the previous resolve.
+			addrs = nil
+		}
+		p.cluster.engine.Send(p.pid, dnsResolved{addrs: addrs})
+	}()
+}
+
+func (p *DNSProvider) lookup() (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(p.ctx, p.config.interval)
+	defer cancel()
+	addrs := make(map[string]bool)
+	if !p.config.srv {
+		port := p.config.port
+		if port == 0 {
+			_, portstr, _ := net.SplitHostPort(p.cluster.Address())
+			port, _ = strconv.Atoi(portstr)
+		}
+		hosts, err := p.config.resolver.LookupHost(ctx, p.config.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range hosts {
+			addrs[net.JoinHostPort(host, strconv.Itoa(port))] = true
+		}
+		return addrs, nil
+	}
+	_, records, err := p.config.resolver.LookupSRV(ctx, "", "", p.config.name)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, record := range records {
+		hosts, err := p.config.resolver.LookupHost(ctx, strings.TrimSuffix(record.Target, "."))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, host := range hosts {
+			addrs[net.JoinHostPort(host, strconv.Itoa(int(record.Port)))] = true
+		}
+	}
+	if len(addrs) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return addrs, nil
+}
+
+// updateAddrs replaces the resolved addresses. The members whose address no
+// longer resolves leave the cluster, and the new addresses are sent a
+// handshake.
+func (p *DNSProvider) updateAddrs(addrs map[string]bool) {
+	if addrs == nil {
+		addrs = p.addrs
+	}
+	delete(addrs, p.cluster.Address())
+	changed := false
+	for addr := range p.members {
+		// A member can contact us before its address resolves for us.
+		if p.addrs[addr] && !addrs[addr] {
+			delete(p.members, addr)
+			changed = true
+		}
+	}
+	p.addrs = addrs
+	if changed {
+		p.sendMembersToAgent()
+	}
+	for addr := range p.addrs {
+		if _, ok := p.members[addr]; !ok {
+			p.send(discoveryPID(addr), &Handshake{Member: p.cluster.Member()})
+		}
+	}
+}
+
+func (p *DNSProvider) addMembers(members ...*Member) {
+	for _, member := range members {
+		if member.ID == p.cluster.ID() {
+			continue
+		}
+		p.members[member.Host] = member
+	}
+	p.sendMembersToAgent()
+}
+
+func (p *DNSProvider) send(pid *actor.PID, msg any) {
+	if pid == nil {
+		return
+	}
+	p.cluster.engine.SendWithSender(pid, msg, p.pid)
+}
+
+// sendMembersToAgent sends this member and the members that answered our
+// handshake to the local cluster agent.
+func (p *DNSProvider) sendMembersToAgent() {
+	members := []*Member{p.cluster.Member()}
+	for _, member := range p.members {
+		members = append(members, member)
+	}
+	p.cluster.engine.Send(p.cluster.PID(), &Members{Members: members})
+}
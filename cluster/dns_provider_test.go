@@ -0,0 +1,139 @@
+package cluster
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeDNS answers the SRV queries for a single name with the ports of its
+// members, which all resolve to 127.0.0.1.
+type fakeDNS struct {
+	conn net.PacketConn
+
+	mu    sync.Mutex
+	name  string
+	ports []int
+}
+
+func newFakeDNS(t *testing.T, name string) *fakeDNS {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	d := &fakeDNS{conn: conn, name: name + "."}
+	go d.serve()
+	return d
+}
+
+func (d *fakeDNS) setPorts(ports ...int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ports = ports
+}
+
+func (d *fakeDNS) resolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "udp", d.conn.LocalAddr().String())
+		},
+	}
+}
+
+func (d *fakeDNS) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := d.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+			continue
+		}
+		resp := d.answer(query)
+		b, err := resp.Pack()
+		if err != nil {
+			continue
+		}
+		d.conn.WriteTo(b, addr)
+	}
+}
+
+func (d *fakeDNS) answer(query dnsmessage.Message) dnsmessage.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	q := query.Questions[0]
+	resp := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+		Questions: query.Questions,
+	}
+	header := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 1}
+	switch {
+	case q.Type == dnsmessage.TypeSRV && q.Name.String() == d.name:
+		for _, port := range d.ports {
+			target := dnsmessage.MustNewName("m" + strconv.Itoa(port) + ".test.")
+			resp.Answers = append(resp.Answers, dnsmessage.Resource{
+				Header: header,
+				Body:   &dnsmessage.SRVResource{Port: uint16(port), Target: target},
+			})
+		}
+	case q.Type == dnsmessage.TypeA:
+		resp.Answers = append(resp.Answers, dnsmessage.Resource{
+			Header: header,
+			Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+		})
+	}
+	return resp
+}
+
+func TestDNSProvider(t *testing.T) {
+	dns := newFakeDNS(t, "_hollywood._tcp.game.test")
+	config := NewDNSProviderConfig().
+		WithName("_hollywood._tcp.game.test").
+		WithSRV(true).
+		WithInterval(50 * time.Millisecond).
+		WithResolver(dns.resolver())
+
+	var (
+		clusters []*Cluster
+		ports    []int
+	)
+	for _, id := range []string{"A", "B", "C"} {
+		addr := getRandomLocalhostAddr()
+		_, port, _ := net.SplitHostPort(addr)
+		p, _ := strconv.Atoi(port)
+		ports = append(ports, p)
+		c, err := New(NewConfig().
+			WithID(id).
+			WithListenAddr(addr).
+			WithProvider(NewDNSProvider(config)))
+		require.NoError(t, err)
+		c.RegisterKind("player"+id, NewPlayer, NewKindConfig())
+		clusters = append(clusters, c)
+	}
+	dns.setPorts(ports...)
+	for _, c := range clusters {
+		c.Start()
+		defer c.Stop()
+	}
+
+	for _, c := range clusters {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 3 && c.HasKind("playerA") && c.HasKind("playerC")
+		}, 2*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+
+	// B leaves once its address no longer resolves.
+	dns.setPorts(ports[0], ports[2])
+	assert.Eventually(t, func() bool {
+		return len(clusters[0].Members()) == 2 && !clusters[0].HasKind("playerB")
+	}, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,285 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultEtcdEndpoint = "http://127.0.0.1:2379"
+	defaultEtcdPrefix   = "/hollywood/"
+	defaultEtcdTTL      = 10 * time.Second
+)
+
+// errLeaseExpired is returned when a lease expired before it was kept alive.
+var errLeaseExpired = errors.New("etcd lease expired")
+
+// EtcdConfig holds the configuration of the etcd provider and registry.
+type EtcdConfig struct {
+	endpoint   string
+	prefix     string
+	ttl        time.Duration
+	fencing    bool
+	httpClient *http.Client
+}
+
+// NewEtcdConfig returns an EtcdConfig that is initialized with default
+// values.
+func NewEtcdConfig() EtcdConfig {
+	return EtcdConfig{
+		endpoint:   defaultEtcdEndpoint,
+		prefix:     defaultEtcdPrefix,
+		ttl:        defaultEtcdTTL,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// WithEndpoint set's the URL of the etcd server.
+//
+// Defaults to "http://127.0.0.1:2379".
+func (c EtcdConfig) WithEndpoint(endpoint string) EtcdConfig {
+	c.endpoint = strings.TrimSuffix(endpoint, "/")
+	return c
+}
+
+// WithPrefix set's the prefix of the keys, which allows several clusters to
+// share the same etcd.
+//
+// Defaults to "/hollywood/".
+func (c EtcdConfig) WithPrefix(prefix string) EtcdConfig {
+	c.prefix = prefix
+	return c
+}
+
+// WithTTL set's the TTL of the lease the keys of a member are bound to. When
+// a member fails, its keys are removed once the TTL expires.
+//
+// Defaults to 10 seconds.
+func (c EtcdConfig) WithTTL(d time.Duration) EtcdConfig {
+	c.ttl = d
+	return c
+}
+
+// WithFencing set's whether the registry deactivates the actors of this member
+// when it could not keep their lease alive, before the lease can expire and
+// another member can activate them. This keeps every actor activated at most
+// once even when this member is cut off from etcd, at the cost of the actors
+// of a member that can't reach etcd.
+//
+// Defaults to false, in which case the actors keep running and are registered
+// again once etcd can be reached.
+func (c EtcdConfig) WithFencing(fencing bool) EtcdConfig {
+	c.fencing = fencing
+	return c
+}
+
+// WithHTTPClient set's the client used to talk to etcd.
+//
+// Defaults to http.DefaultClient.
+func (c EtcdConfig) WithHTTPClient(client *http.Client) EtcdConfig {
+	c.httpClient = client
+	return c
+}
+
+// etcdClient talks to etcd with the JSON gateway of its v3 API, in which the
+// keys and values are base64 encoded and the 64 bit integers are strings.
+type etcdClient struct {
+	config EtcdConfig
+}
+
+type etcdKV struct {
+	Key         []byte `json:"key"`
+	Value       []byte `json:"value,omitempty"`
+	ModRevision int64  `json:"mod_revision,string,omitempty"`
+}
+
+type etcdHeader struct {
+	Revision int64 `json:"revision,string"`
+}
+
+type etcdEvent struct {
+	// The type is omitted for puts.
+	Type string `json:"type"`
+	Kv   etcdKV `json:"kv"`
+}
+
+func (e *etcdClient) call(ctx context.Context, path string, req, resp any) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := e.post(ctx, path, b)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	return json.NewDecoder(r.Body).Decode(resp)
+}
+
+func (e *etcdClient) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.config.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("etcd %s failed with %s: %s", path, resp.Status, b)
+	}
+	return resp, nil
+}
+
+// grant returns a new lease with the TTL of the config.
+func (e *etcdClient) grant(ctx context.Context) (int64, error) {
+	var resp struct {
+		ID int64 `json:"ID,string"`
+	}
+	ttl := int64(max(e.config.ttl/time.Second, 1))
+	if err := e.call(ctx, "/v3/lease/grant", map[string]any{"TTL": ttl}, &resp); err != nil {
+		return 0, err
+	}
+	return resp.ID, nil
+}
+
+// keepAlive renews the given lease once.
+func (e *etcdClient) keepAlive(ctx context.Context, lease int64) error {
+	var resp struct {
+		Result struct {
+			TTL int64 `json:"TTL,string"`
+		} `json:"result"`
+	}
+	if err := e.call(ctx, "/v3/lease/keepalive", map[string]any{"ID": lease}, &resp); err != nil {
+		return err
+	}
+	if resp.Result.TTL <= 0 {
+		return errLeaseExpired
+	}
+	return nil
+}
+
+// revoke revokes the given lease, which removes the keys bound to it.
+func (e *etcdClient) revoke(ctx context.Context, lease int64) error {
+	var resp struct{}
+	return e.call(ctx, "/v3/lease/revoke", map[string]any{"ID": lease}, &resp)
+}
+
+func (e *etcdClient) put(ctx context.Context, key string, value []byte, lease int64) error {
+	var resp struct{}
+	return e.call(ctx, "/v3/kv/put", map[string]any{
+		"key":   []byte(key),
+		"value": value,
+		"lease": lease,
+	}, &resp)
+}
+
+// create puts the key unless it exists already, in which case it returns
+// false.
+func (e *etcdClient) create(ctx context.Context, key string, value []byte, lease int64) (bool, error) {
+	var resp struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	err := e.call(ctx, "/v3/kv/txn", map[string]any{
+		"compare": []any{map[string]any{
+			"key":             []byte(key),
+			"target":          "CREATE",
+			"result":          "EQUAL",
+			"create_revision": 0,
+		}},
+		"success": []any{map[string]any{
+			"request_put": map[string]any{"key": []byte(key), "value": value, "lease": lease},
+		}},
+	}, &resp)
+	return resp.Succeeded, err
+}
+
+func (e *etcdClient) delete(ctx context.Context, key string) error {
+	var resp struct{}
+	return e.call(ctx, "/v3/kv/deleterange", map[string]any{"key": []byte(key)}, &resp)
+}
+
+// list returns the keys with the given prefix, and the revision of the store
+// they were read at.
+func (e *etcdClient) list(ctx context.Context, prefix string) ([]etcdKV, int64, error) {
+	var resp struct {
+		Header etcdHeader `json:"header"`
+		Kvs    []etcdKV   `json:"kvs"`
+	}
+	err := e.call(ctx, "/v3/kv/range", map[string]any{
+		"key":       []byte(prefix),
+		"range_end": prefixEnd(prefix),
+	}, &resp)
+	return resp.Kvs, resp.Header.Revision, err
+}
+
+// watch calls fn with the changes to the keys with the given prefix after the
+// given revision, until the context is done or the watch fails.
+func (e *etcdClient) watch(ctx context.Context, prefix string, revision int64, fn func(etcdEvent)) error {
+	b, err := json.Marshal(map[string]any{
+		"create_request": map[string]any{
+			"key":            []byte(prefix),
+			"range_end":      prefixEnd(prefix),
+			"start_revision": revision + 1,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := e.post(ctx, "/v3/watch", b)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Result struct {
+				Canceled     bool        `json:"canceled"`
+				CancelReason string      `json:"cancel_reason"`
+				Events       []etcdEvent `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("etcd watch failed: %s", msg.Error.Message)
+		}
+		if msg.Result.Canceled {
+			return fmt.Errorf("etcd watch canceled: %s", msg.Result.CancelReason)
+		}
+		for _, event := range msg.Result.Events {
+			fn(event)
+		}
+	}
+}
+
+// prefixEnd returns the end of the range of the keys with the given prefix.
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// The prefix is all 0xff, so the range is all the keys after it.
+	return []byte{0}
+}
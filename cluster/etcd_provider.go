@@ -0,0 +1,371 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+// EtcdProvider is a provider that keeps the membership of the cluster in etcd,
+// for strongly consistent membership rather than gossip. Each member puts its
+// key under a lease that it keeps alive, and watches the keys of the others.
+// When a member stops its lease is revoked, and when it fails its lease
+// expires after the TTL, either way its key is removed and it leaves the
+// cluster.
+type EtcdProvider struct {
+	config  EtcdConfig
+	client  *etcdClient
+	cluster *Cluster
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// lease is only accessed by the keep alive loop while it runs.
+	lease int64
+}
+
+// NewEtcdProvider returns a provider that manages the membership of the
+// cluster in etcd.
+func NewEtcdProvider(config EtcdConfig) Producer {
+	return func(c *Cluster) actor.Producer {
+		return func() actor.Receiver {
+			return &EtcdProvider{
+				config:  config,
+				client:  &etcdClient{config: config},
+				cluster: c,
+			}
+		}
+	}
+}
+
+func (p *EtcdProvider) Receive(c *actor.Context) {
+	switch c.Message().(type) {
+	case actor.Started:
+		p.ctx, p.cancel = context.WithCancel(context.Background())
+		p.wg.Add(2)
+		go p.keepAlive()
+		go p.watch()
+	case actor.Stopped:
+		p.cancel()
+		p.wg.Wait()
+		if p.lease != 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), p.config.ttl)
+			defer cancel()
+			if err := p.client.revoke(ctx, p.lease); err != nil {
+				slog.Warn("etcd provider", "err", err)
+			}
+		}
+	}
+}
+
+func (p *EtcdProvider) memberKey(id string) string {
+	return p.config.prefix + "members/" + id
+}
+
+// keepAlive registers this member under a lease and keeps the lease alive.
+// When the lease expires anyway, for example because etcd was not reachable
+// for longer than the TTL, the member is registered again.
+func (p *EtcdProvider) keepAlive() {
+	defer p.wg.Done()
+	interval := p.config.ttl / 3
+	for {
+		var err error
+		if p.lease == 0 {
+			err = p.register()
+		} else {
+			err = p.client.keepAlive(p.ctx, p.lease)
+			if errors.Is(err, errLeaseExpired) {
+				p.lease = 0
+				err = p.register()
+			}
+		}
+		if err != nil && p.ctx.Err() == nil {
+			slog.Warn("etcd provider", "err", err)
+		}
+		select {
+		case <-time.After(interval):
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *EtcdProvider) register() error {
+	b, err := p.cluster.Member().MarshalVT()
+	if err != nil {
+		return err
+	}
+	lease, err := p.client.grant(p.ctx)
+	if err != nil {
+		return err
+	}
+	p.lease = lease
+	return p.client.put(p.ctx, p.memberKey(p.cluster.ID()), b, lease)
+}
+
+// watch lists the members and watches them for changes until the provider is
+// stopped.
+func (p *EtcdProvider) watch() {
+	defer p.wg.Done()
+	prefix := p.memberKey("")
+	for {
+		kvs, revision, err := p.client.list(p.ctx, prefix)
+		if err == nil {
+			members := make(map[string]*Member)
+			for _, kv := range kvs {
+				p.applyPut(members, kv)
+			}
+			p.sendMembersToAgent(members)
+			err = p.client.watch(p.ctx, prefix, revision, func(event etcdEvent) {
+				if event.Type == "DELETE" {
+					delete(members, strings.TrimPrefix(string(event.Kv.Key), prefix))
+				} else {
+					p.applyPut(members, event.Kv)
+				}
+				p.sendMembersToAgent(members)
+			})
+		}
+		if p.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Warn("etcd provider", "err", err)
+		}
+		select {
+		case <-time.After(p.config.ttl / 3):
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *EtcdProvider) applyPut(members map[string]*Member, kv etcdKV) {
+	member := &Member{}
+	if err := member.UnmarshalVT(kv.Value); err != nil {
+		slog.Warn("etcd provider", "err", err, "key", string(kv.Key))
+		return
+	}
+	members[member.ID] = member
+}
+
+// sendMembersToAgent sends this member and the members in etcd to the local
+// cluster agent.
+func (p *EtcdProvider) sendMembersToAgent(members map[string]*Member) {
+	all := []*Member{p.cluster.Member()}
+	for id, member := range members {
+		if id != p.cluster.ID() {
+			all = append(all, member)
+		}
+	}
+	p.cluster.engine.Send(p.cluster.PID(), &Members{Members: all})
+}
+
+// EtcdRegistry is an ActivationRegistry that keeps the activated actors in
+// etcd. An actor is only activated if etcd has no actor with the same ID yet,
+// which holds even when members activate it concurrently. The actors of a
+// member are bound to a lease, so they are removed when the member fails.
+// With EtcdConfig.WithFencing, it also holds when members are partitioned.
+type EtcdRegistry struct {
+	config EtcdConfig
+	client *etcdClient
+
+	mu    sync.Mutex
+	lease int64
+	// renewed is when the last keep alive of the lease that succeeded was
+	// sent, so the lease can't expire before renewed plus the TTL.
+	renewed time.Time
+	actors  map[string]*actor.PID
+	lost    func(pid *actor.PID)
+	stopch  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewEtcdRegistry returns a registry that keeps the activated actors in etcd.
+func NewEtcdRegistry(config EtcdConfig) *EtcdRegistry {
+	return &EtcdRegistry{
+		config: config,
+		client: &etcdClient{config: config},
+		actors: make(map[string]*actor.PID),
+	}
+}
+
+func (r *EtcdRegistry) key(pid *actor.PID) string {
+	return r.config.prefix + "activations/" + pid.ID
+}
+
+func (r *EtcdRegistry) Register(pid *actor.PID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.ttl)
+	defer cancel()
+	if r.lease == 0 {
+		sent := time.Now()
+		lease, err := r.client.grant(ctx)
+		if err != nil {
+			return false, err
+		}
+		r.lease, r.renewed = lease, sent
+	}
+	if r.stopch == nil {
+		r.stopch = make(chan struct{})
+		r.wg.Add(1)
+		go r.keepAlive(r.stopch)
+	}
+	ok, err := r.create(ctx, pid)
+	if ok {
+		r.actors[pid.ID] = pid
+	}
+	return ok, err
+}
+
+func (r *EtcdRegistry) create(ctx context.Context, pid *actor.PID) (bool, error) {
+	b, err := pid.MarshalVT()
+	if err != nil {
+		return false, err
+	}
+	return r.client.create(ctx, r.key(pid), b, r.lease)
+}
+
+// OnLost sets the function that is called with the actors the registry lost.
+func (r *EtcdRegistry) OnLost(fn func(pid *actor.PID)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lost = fn
+}
+
+func (r *EtcdRegistry) Unregister(pid *actor.PID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.actors[pid.ID]; !ok {
+		return nil
+	}
+	delete(r.actors, pid.ID)
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.ttl)
+	defer cancel()
+	return r.client.delete(ctx, r.key(pid))
+}
+
+func (r *EtcdRegistry) Close() error {
+	r.mu.Lock()
+	stopch := r.stopch
+	r.stopch = nil
+	r.mu.Unlock()
+	if stopch == nil {
+		return nil
+	}
+	// The keep alive loop takes the lock as well.
+	close(stopch)
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lease == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.ttl)
+	defer cancel()
+	err := r.client.revoke(ctx, r.lease)
+	r.lease = 0
+	clear(r.actors)
+	return err
+}
+
+// keepAlive keeps the lease of the actors alive. When the lease expires
+// anyway, the actors are registered again under a new lease, unless another
+// member took over their ID in the meantime.
+//
+// With fencing, the actors are given up once the lease could not be kept
+// alive for two thirds of the TTL, which leaves the last third to deactivate
+// them before the lease can expire.
+func (r *EtcdRegistry) keepAlive(stopch chan struct{}) {
+	defer r.wg.Done()
+	for {
+		wait := r.config.ttl / 3
+		if r.config.fencing {
+			r.mu.Lock()
+			wait = min(wait, time.Until(r.fenceDeadline()))
+			r.mu.Unlock()
+		}
+		select {
+		case <-time.After(wait):
+		case <-stopch:
+			return
+		}
+		if err := r.tick(); err != nil {
+			slog.Warn("etcd registry", "err", err)
+		}
+	}
+}
+
+func (r *EtcdRegistry) tick() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lease == 0 {
+		return nil
+	}
+	deadline := time.Now().Add(r.config.ttl)
+	if r.config.fencing {
+		deadline = r.fenceDeadline()
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	sent := time.Now()
+	err := r.client.keepAlive(ctx, r.lease)
+	switch {
+	case err == nil:
+		r.renewed = sent
+	case errors.Is(err, errLeaseExpired):
+		ctx, cancel := context.WithTimeout(context.Background(), r.config.ttl)
+		defer cancel()
+		err = r.renew(ctx)
+	case r.config.fencing && !time.Now().Before(r.fenceDeadline()):
+		r.fence()
+	}
+	return err
+}
+
+func (r *EtcdRegistry) fenceDeadline() time.Time {
+	return r.renewed.Add(r.config.ttl * 2 / 3)
+}
+
+// fence gives up the lease and the actors bound to it.
+func (r *EtcdRegistry) fence() {
+	slog.Warn("etcd registry", "err", "could not keep the lease alive, deactivating the actors", "actors", len(r.actors))
+	r.lease = 0
+	for id, pid := range r.actors {
+		delete(r.actors, id)
+		r.lose(pid)
+	}
+}
+
+func (r *EtcdRegistry) lose(pid *actor.PID) {
+	if r.lost != nil {
+		r.lost(pid)
+	}
+}
+
+func (r *EtcdRegistry) renew(ctx context.Context) error {
+	sent := time.Now()
+	lease, err := r.client.grant(ctx)
+	if err != nil {
+		return err
+	}
+	r.lease, r.renewed = lease, sent
+	for id, pid := range r.actors {
+		ok, err := r.create(ctx, pid)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			slog.Warn("etcd registry", "err", "actor was registered by another member", "id", id)
+			delete(r.actors, id)
+			r.lose(pid)
+		}
+	}
+	return nil
+}
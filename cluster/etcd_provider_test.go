@@ -0,0 +1,344 @@
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEtcd implements the parts of the JSON gateway of etcd that the provider
+// and the registry use.
+type fakeEtcd struct {
+	mu       sync.Mutex
+	revision int64
+	leases   int64
+	kvs      map[string]fakeEtcdKV
+	events   []fakeEtcdEvent
+	changed  chan struct{}
+	// expired holds the leases that expired.
+	expired map[int64]bool
+	// down makes every request fail, as if etcd was unreachable.
+	down atomic.Bool
+}
+
+type fakeEtcdKV struct {
+	value []byte
+	lease int64
+}
+
+type fakeEtcdEvent struct {
+	typ      string
+	key      []byte
+	value    []byte
+	revision int64
+}
+
+type fakeEtcdRequest struct {
+	ID            int64  `json:"ID"`
+	Key           []byte `json:"key"`
+	RangeEnd      []byte `json:"range_end"`
+	Value         []byte `json:"value"`
+	Lease         int64  `json:"lease"`
+	CreateRequest *struct {
+		Key           []byte `json:"key"`
+		RangeEnd      []byte `json:"range_end"`
+		StartRevision int64  `json:"start_revision"`
+	} `json:"create_request"`
+	Success []struct {
+		RequestPut fakeEtcdPut `json:"request_put"`
+	} `json:"success"`
+}
+
+type fakeEtcdPut struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+	Lease int64  `json:"lease"`
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{kvs: make(map[string]fakeEtcdKV), changed: make(chan struct{}), expired: make(map[int64]bool)}
+}
+
+// expire expires all the leases, which removes their keys.
+func (f *fakeEtcd) expire() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for lease := int64(1); lease <= f.leases; lease++ {
+		f.expired[lease] = true
+	}
+	for key, kv := range f.kvs {
+		if kv.lease != 0 {
+			delete(f.kvs, key)
+			f.record("DELETE", key, nil)
+		}
+	}
+}
+
+// record must be called with the lock held.
+func (f *fakeEtcd) record(typ string, key string, value []byte) {
+	f.revision++
+	f.events = append(f.events, fakeEtcdEvent{typ: typ, key: []byte(key), value: value, revision: f.revision})
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.down.Load() {
+		http.Error(w, "etcd is down", http.StatusServiceUnavailable)
+		return
+	}
+	var req fakeEtcdRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.URL.Path == "/v3/watch" {
+		f.watch(w, r, req)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var resp any = map[string]any{}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.leases++
+		resp = map[string]any{"ID": strconv.FormatInt(f.leases, 10), "TTL": "10"}
+	case "/v3/lease/keepalive":
+		ttl := "10"
+		if f.expired[req.ID] {
+			ttl = "-1"
+		}
+		resp = map[string]any{"result": map[string]any{"ID": strconv.FormatInt(req.ID, 10), "TTL": ttl}}
+	case "/v3/lease/revoke":
+		for key, kv := range f.kvs {
+			if kv.lease == req.ID {
+				delete(f.kvs, key)
+				f.record("DELETE", key, nil)
+			}
+		}
+	case "/v3/kv/put":
+		f.kvs[string(req.Key)] = fakeEtcdKV{value: req.Value, lease: req.Lease}
+		f.record("PUT", string(req.Key), req.Value)
+	case "/v3/kv/txn":
+		put := req.Success[0].RequestPut
+		if _, ok := f.kvs[string(put.Key)]; ok {
+			break
+		}
+		f.kvs[string(put.Key)] = fakeEtcdKV{value: put.Value, lease: put.Lease}
+		f.record("PUT", string(put.Key), put.Value)
+		resp = map[string]any{"succeeded": true}
+	case "/v3/kv/deleterange":
+		if _, ok := f.kvs[string(req.Key)]; ok {
+			delete(f.kvs, string(req.Key))
+			f.record("DELETE", string(req.Key), nil)
+		}
+	case "/v3/kv/range":
+		var kvs []map[string]any
+		for key, kv := range f.kvs {
+			if key >= string(req.Key) && key < string(req.RangeEnd) {
+				kvs = append(kvs, map[string]any{"key": []byte(key), "value": kv.value})
+			}
+		}
+		resp = map[string]any{"header": map[string]any{"revision": strconv.FormatInt(f.revision, 10)}, "kvs": kvs}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeEtcd) watch(w http.ResponseWriter, r *http.Request, req fakeEtcdRequest) {
+	start, end := string(req.CreateRequest.Key), string(req.CreateRequest.RangeEnd)
+	next := req.CreateRequest.StartRevision
+	for {
+		f.mu.Lock()
+		var events []map[string]any
+		for _, event := range f.events {
+			key := string(event.key)
+			if event.revision >= next && key >= start && key < end {
+				e := map[string]any{"kv": map[string]any{"key": event.key, "value": event.value}}
+				if event.typ == "DELETE" {
+					e["type"] = "DELETE"
+				}
+				events = append(events, e)
+			}
+		}
+		next = f.revision + 1
+		changed := f.changed
+		f.mu.Unlock()
+		if len(events) > 0 {
+			json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{"events": events}})
+		}
+		w.(http.Flusher).Flush()
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (f *fakeEtcd) has(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.kvs[key]
+	return ok
+}
+
+func TestEtcdProvider(t *testing.T) {
+	etcd := newFakeEtcd()
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+	config := NewEtcdConfig().WithEndpoint(server.URL).WithTTL(time.Second)
+
+	var clusters []*Cluster
+	for _, id := range []string{"A", "B"} {
+		c, err := New(NewConfig().
+			WithID(id).
+			WithListenAddr(getRandomLocalhostAddr()).
+			WithProvider(NewEtcdProvider(config)).
+			WithActivationRegistry(NewEtcdRegistry(config)))
+		require.NoError(t, err)
+		c.RegisterKind("player", NewPlayer, NewKindConfig())
+		c.Start()
+		clusters = append(clusters, c)
+	}
+	defer clusters[0].Stop()
+	for _, c := range clusters {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 2
+		}, 2*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+
+	onB := func(details ActivationDetails) *Member {
+		for _, m := range details.Members {
+			if m.ID == "B" {
+				return m
+			}
+		}
+		return nil
+	}
+	pid := clusters[0].Activate("player", NewActivationConfig().WithID("1").WithSelectMemberFunc(onB))
+	require.NotNil(t, pid)
+	assert.True(t, etcd.has("/hollywood/activations/player/1"))
+
+	// B leaves as soon as it stops, and its actors are removed from etcd.
+	clusters[1].Stop()
+	assert.False(t, etcd.has("/hollywood/members/B"))
+	assert.Eventually(t, func() bool {
+		return len(clusters[0].Members()) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.False(t, etcd.has("/hollywood/activations/player/1"))
+}
+
+func TestEtcdRegistry(t *testing.T) {
+	server := httptest.NewServer(newFakeEtcd())
+	defer server.Close()
+	config := NewEtcdConfig().WithEndpoint(server.URL).WithTTL(time.Second)
+	r1, r2 := NewEtcdRegistry(config), NewEtcdRegistry(config)
+
+	ok, err := r1.Register(actor.NewPID("127.0.0.1:4000", "player/1"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = r2.Register(actor.NewPID("127.0.0.1:5000", "player/1"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// The actors of a registry are released when it's closed.
+	require.NoError(t, r1.Close())
+	ok, err = r2.Register(actor.NewPID("127.0.0.1:5000", "player/1"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, r2.Unregister(actor.NewPID("127.0.0.1:5000", "player/1")))
+	ok, err = r1.Register(actor.NewPID("127.0.0.1:4000", "player/1"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, r1.Close())
+}
+
+func TestEtcdRegistryLost(t *testing.T) {
+	etcd := newFakeEtcd()
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+	config := NewEtcdConfig().WithEndpoint(server.URL).WithTTL(300 * time.Millisecond).WithFencing(true)
+	r1, r2 := NewEtcdRegistry(config), NewEtcdRegistry(config)
+	defer r2.Close()
+	lost := make(chan *actor.PID, 1)
+	r1.OnLost(func(pid *actor.PID) { lost <- pid })
+
+	// The lease of r1 expires while another member takes over the actor.
+	pid := actor.NewPID("127.0.0.1:4000", "player/1")
+	ok, err := r1.Register(pid)
+	require.NoError(t, err)
+	require.True(t, ok)
+	etcd.expire()
+	ok, err = r2.Register(actor.NewPID("127.0.0.1:5000", "player/1"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	select {
+	case got := <-lost:
+		assert.Equal(t, pid, got)
+	case <-time.After(time.Second):
+		t.Fatal("expected the actor to be lost")
+	}
+
+	// r1 can't reach etcd, and gives up its actors before their lease can
+	// expire.
+	pid = actor.NewPID("127.0.0.1:4000", "player/2")
+	ok, err = r1.Register(pid)
+	require.NoError(t, err)
+	require.True(t, ok)
+	registered := time.Now()
+	etcd.down.Store(true)
+	select {
+	case got := <-lost:
+		assert.Equal(t, pid, got)
+		assert.Less(t, time.Since(registered), 300*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("expected the actor to be lost")
+	}
+	etcd.down.Store(false)
+	require.NoError(t, r1.Close())
+}
+
+func TestEtcdRegistryFencing(t *testing.T) {
+	etcd := newFakeEtcd()
+	server := httptest.NewServer(etcd)
+	defer server.Close()
+	config := NewEtcdConfig().WithEndpoint(server.URL).WithTTL(300 * time.Millisecond).WithFencing(true)
+	c, err := New(NewConfig().
+		WithID("A").
+		WithListenAddr(getRandomLocalhostAddr()).
+		WithActivationRegistry(NewEtcdRegistry(config)))
+	require.NoError(t, err)
+	c.RegisterKind("player", NewPlayer, NewKindConfig())
+	c.Start()
+	defer c.Stop()
+	require.Eventually(t, func() bool {
+		return len(c.Members()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	pid := c.Activate("player", NewActivationConfig().WithID("1"))
+	require.NotNil(t, pid)
+	etcd.down.Store(true)
+	defer etcd.down.Store(false)
+	// The actor is deactivated once the member is cut off from etcd.
+	assert.Eventually(t, func() bool {
+		return c.GetActiveByID("player/1") == nil && c.Engine().Registry.GetPID("player", "1") == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPrefixEnd(t *testing.T) {
+	assert.Equal(t, []byte("/hollywood0"), prefixEnd("/hollywood/"))
+	assert.Equal(t, []byte{'a', 0x01}, prefixEnd(string([]byte{'a', 0x00, 0xff})))
+	assert.Equal(t, []byte{0}, prefixEnd(string([]byte{0xff})))
+}
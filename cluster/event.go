@@ -1,6 +1,10 @@
 package cluster
 
-import "github.com/fertigai/hollywood/actor"
+import (
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
 
 // MemberJoinEvent gets triggered each time a new member enters the cluster.
 type MemberJoinEvent struct {
@@ -18,8 +22,109 @@ type ActivationEvent struct {
 	PID *actor.PID
 }
 
+// ActivationRejectedEvent gets triggered on the member an actor should be
+// activated on, when it rejects the activation because the kind of the actor
+// reached its activation limits.
+type ActivationRejectedEvent struct {
+	Kind string
+	ID   string
+}
+
 // DeactivationEvent gets triggered each time an actor gets deactivated somewhere on
 // the cluster.
 type DeactivationEvent struct {
 	PID *actor.PID
 }
+
+// LeaderChangedEvent gets triggered each time a new leader of the cluster is
+// elected. A member that was the leader before is no longer, so singletons
+// that run on the leader should be stopped there.
+type LeaderChangedEvent struct {
+	Leader *Member
+	Term   uint64
+}
+
+// RoleLeaderChangedEvent gets triggered each time the leader of a role
+// changes, see Cluster.RoleLeader. Leader is nil when no member has the role
+// anymore.
+type RoleLeaderChangedEvent struct {
+	Role   string
+	Leader *Member
+}
+
+// TopologyChangedEvent gets triggered each time members joined or left the
+// cluster, after the MemberJoinEvent and MemberLeaveEvent of each of them.
+type TopologyChangedEvent struct {
+	// All the members of the cluster after the change.
+	Members []*Member
+	Joined  []*Member
+	Left    []*Member
+}
+
+// RebalanceStartedEvent gets triggered when actors start to move off this
+// member, see RebalanceEvent.
+type RebalanceStartedEvent struct {
+	Moving int
+}
+
+// RebalanceFinishedEvent gets triggered when all the actors that moved off
+// this member were activated on their new owner, or failed to.
+type RebalanceFinishedEvent struct {
+	Moved  int
+	Failed int
+}
+
+// RebalanceEvent gets triggered when the actors of partitioned kinds move off
+// this member because their owner changed, and again each time one of them is
+// activated on its new owner or failed to. The rebalance is done once Moved
+// and Failed add up to Total.
+type RebalanceEvent struct {
+	Total  int
+	Moved  int
+	Failed int
+	// ETA is the estimated time until the remaining actors moved, from the
+	// pace of the actors that moved so far. It's zero until the first one
+	// did.
+	ETA time.Duration
+}
+
+// SplitBrainResolvedEvent gets triggered when the split brain resolver decided
+// whether the side of this member survives, after members were lost. If it
+// did not survive, the actors of this member are terminated and its cluster
+// is stopped.
+type SplitBrainResolvedEvent struct {
+	Survived bool
+	Members  []*Member
+	Lost     []*Member
+}
+
+// TopicEvent gets triggered on every member each time a message is published
+// on a topic of the cluster.
+type TopicEvent struct {
+	Topic   string
+	Message any
+	// Origin is the cluster a bridge replicated the message from, empty if
+	// it was published in this cluster.
+	Origin string
+}
+
+// MemberSuspectEvent gets triggered each time a member is suspected to have
+// failed by the SWIM provider. The member is removed from the cluster,
+// triggering a MemberLeaveEvent, unless it refutes the suspicion in time.
+type MemberSuspectEvent struct {
+	Member *Member
+}
+
+func (MemberJoinEvent) Topic() string         { return "cluster.member.joined" }
+func (MemberLeaveEvent) Topic() string        { return "cluster.member.left" }
+func (MemberSuspectEvent) Topic() string      { return "cluster.member.suspected" }
+func (TopologyChangedEvent) Topic() string    { return "cluster.member.topology_changed" }
+func (ActivationEvent) Topic() string         { return "cluster.activation.activated" }
+func (ActivationRejectedEvent) Topic() string { return "cluster.activation.rejected" }
+func (DeactivationEvent) Topic() string       { return "cluster.activation.deactivated" }
+func (LeaderChangedEvent) Topic() string      { return "cluster.leader.changed" }
+func (RoleLeaderChangedEvent) Topic() string  { return "cluster.leader.role_changed" }
+func (RebalanceStartedEvent) Topic() string   { return "cluster.rebalance.started" }
+func (RebalanceEvent) Topic() string          { return "cluster.rebalance.progress" }
+func (RebalanceFinishedEvent) Topic() string  { return "cluster.rebalance.finished" }
+func (SplitBrainResolvedEvent) Topic() string { return "cluster.split_brain.resolved" }
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/remote"
+)
+
+// forwardEvent is a cluster-wide event of this member that the agent forwards
+// to the other members.
+type forwardEvent struct {
+	event *ForwardedEvent
+}
+
+// eventForwarder hands the events of this member on the cluster-wide topics
+// to the agent, see Config.WithClusterEvents.
+type eventForwarder struct {
+	cluster *Cluster
+}
+
+func newEventForwarder(c *Cluster) actor.Producer {
+	return func() actor.Receiver {
+		return &eventForwarder{cluster: c}
+	}
+}
+
+func (f *eventForwarder) Receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.Started:
+		// The events that were forwarded to this member stay here.
+		c.Engine().Subscribe(c.PID(),
+			actor.WithTopics(f.cluster.config.clusterEvents...),
+			actor.WithLocalOnly(),
+			actor.WithEnvelope())
+	case actor.Stopped:
+		c.Engine().Unsubscribe(c.PID())
+	case actor.EventEnvelope:
+		name, b, err := encodeClusterEvent(msg.Event)
+		if err != nil {
+			slog.Warn("failed to forward cluster event", "err", err, "topic", msg.Topic, "type", name)
+			return
+		}
+		c.Send(f.cluster.agentPID, forwardEvent{event: &ForwardedEvent{
+			Topic:    msg.Topic,
+			TypeName: name,
+			Data:     b,
+		}})
+	}
+}
+
+// encodeClusterEvent encodes the event by its name of actor.RegisterEvent, or
+// with the serializer of the remote if its type is not registered as an event.
+func encodeClusterEvent(event any) (string, []byte, error) {
+	name, b, err := actor.EncodeEvent(event)
+	if !errors.Is(err, actor.ErrUnregisteredEvent) {
+		return name, b, err
+	}
+	serializer := remote.DefaultSerializer{}
+	b, err = serializer.Serialize(event)
+	return serializer.TypeName(event), b, err
+}
+
+// decodeClusterEvent decodes the event encoded by encodeClusterEvent. An event
+// of a type this member knows neither as an event nor for the remote is kept
+// as an actor.RawEvent, so it still reaches the subscribers and the journal.
+func decodeClusterEvent(name string, b []byte) (any, error) {
+	event, err := actor.DecodeEvent(name, b)
+	if _, ok := event.(actor.RawEvent); !ok {
+		return event, err
+	}
+	if event, err := (remote.DefaultSerializer{}).Deserialize(b, name); err == nil {
+		return event, nil
+	}
+	return actor.RawEvent{Name: name, Data: b}, nil
+}
+
+func (a *Agent) handleForwardEvent(msg forwardEvent) {
+	self := a.cluster.ID()
+	for _, member := range a.members.Slice() {
+		if member.ID != self {
+			a.cluster.engine.Send(member.PID(), msg.event)
+		}
+	}
+}
+
+// handleForwardedEvent publishes the event another member forwarded on the
+// eventstream.
+func (a *Agent) handleForwardedEvent(msg *ForwardedEvent) {
+	event, err := decodeClusterEvent(msg.TypeName, msg.Data)
+	if err != nil {
+		slog.Error("failed to deserialize cluster event", "err", err, "topic", msg.Topic, "type", msg.TypeName)
+		return
+	}
+	a.cluster.engine.PublishForwarded(msg.Topic, event)
+}
@@ -0,0 +1,84 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterEvents(t *testing.T) {
+	a, err := New(NewConfig().
+		WithID("A").
+		WithListenAddr(getRandomLocalhostAddr()).
+		WithProvider(NewSwimProvider(fastSwimConfig())).
+		WithClusterEvents("ops.*"))
+	require.NoError(t, err)
+	a.Start()
+	defer a.Stop()
+	b, err := New(NewConfig().
+		WithID("B").
+		WithListenAddr(getRandomLocalhostAddr()).
+		WithProvider(NewSwimProvider(fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))).
+		WithClusterEvents("ops.*"))
+	require.NoError(t, err)
+	b.Start()
+	defer b.Stop()
+	require.Eventually(t, func() bool {
+		return len(a.Members()) == 2 && len(b.Members()) == 2
+	}, 3*time.Second, 10*time.Millisecond)
+
+	aCh, bCh := make(chan int, 10), make(chan int, 10)
+	actor.SubscribeTyped(a.Engine(), func(msg *counterAdd) { aCh <- msg.N })
+	actor.SubscribeTyped(b.Engine(), func(msg *counterAdd) { bCh <- msg.N })
+	receive := func(ch chan int) int {
+		select {
+		case n := <-ch:
+			return n
+		case <-time.After(2 * time.Second):
+			t.Fatal("the event was not delivered")
+			return 0
+		}
+	}
+
+	a.Engine().Publish("ops.deploy", &counterAdd{N: 1})
+	assert.Equal(t, 1, receive(aCh))
+	assert.Equal(t, 1, receive(bCh))
+	b.Engine().Publish("ops.deploy", &counterAdd{N: 2})
+	assert.Equal(t, 2, receive(bCh))
+	assert.Equal(t, 2, receive(aCh))
+	// The other topics stay on their member, and the forwarded events are
+	// not sent back.
+	a.Engine().Publish("billing.invoice", &counterAdd{N: 3})
+	assert.Equal(t, 3, receive(aCh))
+	a.Engine().Publish("ops.deploy", &counterAdd{N: 4})
+	assert.Equal(t, 4, receive(aCh))
+	assert.Equal(t, 4, receive(bCh))
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, aCh)
+	assert.Empty(t, bCh)
+}
+
+func TestClusterEventCodec(t *testing.T) {
+	// A type that is neither an event nor a remote type can't be forwarded.
+	_, _, err := encodeClusterEvent(struct{ N int }{N: 1})
+	assert.Error(t, err)
+
+	name, b, err := encodeClusterEvent(&counterAdd{N: 1})
+	require.NoError(t, err)
+	event, err := decodeClusterEvent(name, b)
+	require.NoError(t, err)
+	assert.Equal(t, &counterAdd{N: 1}, event)
+
+	// The member that doesn't know the type passes the event on as it is.
+	event, err = decodeClusterEvent("ops.Rollback.v3", []byte{1, 2, 3})
+	require.NoError(t, err)
+	raw := actor.RawEvent{Name: "ops.Rollback.v3", Data: []byte{1, 2, 3}}
+	assert.Equal(t, raw, event)
+	name, b, err = encodeClusterEvent(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "ops.Rollback.v3", name)
+	assert.Equal(t, []byte{1, 2, 3}, b)
+}
@@ -0,0 +1,273 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+type grainActivated struct {
+	id   string
+	resp *ActivationResponse
+}
+
+// GrainRef is a reference to a virtual actor, a grain, which is identified by
+// its kind and identity rather than by a PID. The grain is activated on the
+// first use, on the member that is selected by the placement strategy, and it
+// is activated at most once across the cluster.
+//
+// Every identity is owned by the member that SelectHashMember selects among
+// the members with the kind of the grain. The owner activates the grain, so
+// members that use the same grain concurrently get the same activation. When
+// the owner changes while the grain is activated, for example because a member
+// joined, two activations can race. Configure an ActivationRegistry to rule
+// that out as well.
+type GrainRef struct {
+	cluster *Cluster
+	kind    string
+	config  ActivationConfig
+	// owner selects the owner of the identity, SelectHashMember if nil.
+	owner  SelectMemberFunc
+	sticky bool
+}
+
+// GrainRef returns a reference to the grain of the given kind and identity.
+//
+//	player := c.GrainRef("player", "bob").WithSelectMemberFunc(cluster.SelectLeastLoadedMember)
+//	player.Send(&Deposit{Amount: 10})
+func (c *Cluster) GrainRef(kind, identity string) GrainRef {
+	return GrainRef{
+		cluster: c,
+		kind:    kind,
+		config:  NewActivationConfig().WithID(identity),
+	}
+}
+
+// WithSelectMemberFunc set's the placement strategy, which selects the member
+// the grain is activated on.
+//
+// Defaults to SelectRandomMember.
+func (g GrainRef) WithSelectMemberFunc(fun SelectMemberFunc) GrainRef {
+	g.config = g.config.WithSelectMemberFunc(fun)
+	return g
+}
+
+// WithSticky set's whether the grain is activated on the member it was
+// activated on before, as long as that member is part of the cluster, rather
+// than on the member its placement strategy selects. The owner of the
+// identity remembers the member, so it's lost when the owner changes.
+//
+// With SelectLocalMember the grain stays on the member that called it first,
+// which saves a hop for the callers that are hosted alongside it.
+//
+//	session := c.GrainRef("session", "bob").
+//		WithSelectMemberFunc(cluster.SelectLocalMember).
+//		WithSticky(true)
+//
+// Defaults to false.
+func (g GrainRef) WithSticky(sticky bool) GrainRef {
+	g.sticky = sticky
+	return g
+}
+
+// WithRegion set's the region on where the grain should be activated.
+//
+// Defaults to a "default".
+func (g GrainRef) WithRegion(region string) GrainRef {
+	g.config = g.config.WithRegion(region)
+	return g
+}
+
+// WithRole set's the role the member the grain is activated on needs to have.
+// The owner of a partitioned grain hosts it whatever its role, so partitioned
+// kinds are restricted by registering them on the members with the role only.
+//
+// Defaults to none.
+func (g GrainRef) WithRole(role string) GrainRef {
+	g.config = g.config.WithRole(role)
+	return g
+}
+
+// Kind returns the kind of the grain.
+func (g GrainRef) Kind() string {
+	return g.kind
+}
+
+// Identity returns the identity of the grain.
+func (g GrainRef) Identity() string {
+	return g.config.id
+}
+
+// PID returns the PID of the grain, and activates it if needed. It returns nil
+// if the grain could not be activated.
+func (g GrainRef) PID() *actor.PID {
+	c := g.cluster
+	if pid := c.GetActiveByID(g.kind + "/" + g.config.id); pid != nil {
+		return pid
+	}
+	resp, err := c.engine.Request(c.agentPID, getActivationDetails{kind: g.kind, config: g.config}, c.config.requestTimeout).Result()
+	if err != nil {
+		slog.Error("grain activation failed", "err", err)
+		return nil
+	}
+	details, ok := resp.(ActivationDetails)
+	if !ok {
+		slog.Warn("grain activation expected response of ActivationDetails", "got", reflect.TypeOf(resp))
+		return nil
+	}
+	if len(details.Members) == 0 {
+		slog.Warn("could not find any members with kind", "kind", g.kind)
+		return nil
+	}
+	selectMember := g.config.selectMember
+	if selectMember == nil {
+		selectMember = SelectRandomMember
+	}
+	member := selectMember(details)
+	if member == nil {
+		slog.Warn("placement strategy did not find a member to activate on", "kind", g.kind, "id", g.config.id)
+		return nil
+	}
+	selectOwner := g.owner
+	if selectOwner == nil {
+		selectOwner = SelectHashMember
+	}
+	owner := selectOwner(details)
+	msg := &GrainActivation{
+		Kind:   g.kind,
+		ID:     g.config.id,
+		Region: g.config.region,
+		Role:   g.config.role,
+		Member: member,
+		Sticky: g.sticky,
+	}
+	// The owner requests the activation from the member in turn.
+	resp, err = c.engine.Request(owner.PID(), msg, 2*c.config.requestTimeout).Result()
+	if err != nil {
+		slog.Error("grain activation failed", "err", err, "owner", owner.ID)
+		return nil
+	}
+	r, ok := resp.(*ActivationResponse)
+	if !ok {
+		slog.Error("expected *ActivationResponse", "msg", reflect.TypeOf(resp))
+		return nil
+	}
+	if !r.Success {
+		slog.Error("grain activation unsuccessful", "kind", g.kind, "id", g.config.id)
+		return nil
+	}
+	return r.PID
+}
+
+// Send sends the given message to the grain, which is activated if needed.
+func (g GrainRef) Send(msg any) error {
+	pid := g.PID()
+	if pid == nil {
+		return g.unavailable()
+	}
+	g.cluster.engine.Send(pid, msg)
+	return nil
+}
+
+// Request sends the given message to the grain, which is activated if needed,
+// and waits for its response.
+func (g GrainRef) Request(msg any, timeout time.Duration) (any, error) {
+	pid := g.PID()
+	if pid == nil {
+		return nil, g.unavailable()
+	}
+	return g.cluster.engine.Request(pid, msg, timeout).Result()
+}
+
+func (g GrainRef) unavailable() error {
+	return fmt.Errorf("grain %s/%s: %w", g.kind, g.config.id, errGrainUnavailable)
+}
+
+var errGrainUnavailable = errors.New("could not be activated")
+
+// handleGrainActivation activates a grain this member owns. The requests for
+// a grain that is being activated wait for that activation, so the grain is
+// activated once.
+func (a *Agent) handleGrainActivation(c *actor.Context, msg *GrainActivation) {
+	id := msg.Kind + "/" + msg.ID
+	if pid, ok := a.activated[id]; ok {
+		c.Respond(&ActivationResponse{PID: pid, Success: true})
+		return
+	}
+	if waiting, ok := a.grains[id]; ok {
+		a.grains[id] = append(waiting, c.Sender())
+		return
+	}
+	member := msg.Member
+	if a.isPartitioned(msg.Kind) && !a.leaving[a.cluster.ID()] && !a.draining[a.cluster.ID()] {
+		// The owner of a partitioned actor hosts it.
+		member = a.cluster.Member()
+	} else if sticky, ok := a.affinity[id]; ok && msg.Sticky && a.canHost(sticky, msg.Kind, msg.Role) {
+		member = sticky
+	}
+	if !a.canHost(member, msg.Kind, msg.Role) {
+		// The caller does not agree on the members, the grain is placed
+		// on any member with the kind instead.
+		details := a.activationDetails(msg.Kind, NewActivationConfig().WithID(msg.ID).WithRegion(msg.Region).WithRole(msg.Role))
+		if len(details.Members) == 0 {
+			slog.Warn("could not find any members with kind", "kind", msg.Kind)
+			c.Respond(&ActivationResponse{Success: false})
+			return
+		}
+		member = SelectRandomMember(details)
+	}
+	if msg.Sticky {
+		a.affinity[id] = member
+	}
+	a.grains[id] = []*actor.PID{c.Sender()}
+	req := &ActivationRequest{
+		Kind:      msg.Kind,
+		ID:        msg.ID,
+		Region:    msg.Region,
+		State:     msg.State,
+		StateType: msg.StateType,
+	}
+	if member.Host == a.cluster.engine.Address() {
+		a.requestActivation(req, func(resp *ActivationResponse) {
+			a.finishGrainActivation(id, resp)
+		})
+		return
+	}
+	// The agent does not wait for the member, which might be waiting for
+	// this agent itself.
+	engine, agentPID, timeout := a.cluster.engine, a.cluster.PID(), a.cluster.config.requestTimeout
+	go func() {
+		resp, err := engine.Request(member.PID(), req, timeout).Result()
+		r, ok := resp.(*ActivationResponse)
+		if err != nil || !ok {
+			slog.Error("failed activation request", "err", err, "member", member.ID)
+			r = &ActivationResponse{Success: false}
+		}
+		engine.Send(agentPID, grainActivated{id: id, resp: r})
+	}()
+}
+
+// canHost returns true whether the given member can host an actor of the
+// given kind and role.
+func (a *Agent) canHost(member *Member, kind, role string) bool {
+	return member != nil && a.members.Contains(member) && member.HasKind(kind) && member.HasRole(role) && !a.leaving[member.ID] && !a.draining[member.ID]
+}
+
+func (a *Agent) finishGrainActivation(id string, resp *ActivationResponse) {
+	if resp.Success {
+		a.addActivated(resp.PID)
+		a.bcast(&Activation{PID: resp.PID})
+	} else {
+		delete(a.affinity, id)
+	}
+	for _, pid := range a.grains[id] {
+		if pid != nil {
+			a.cluster.engine.Send(pid, resp)
+		}
+	}
+	delete(a.grains, id)
+}
@@ -0,0 +1,196 @@
+package cluster
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pinger struct{}
+
+func newPinger() actor.Receiver {
+	return &pinger{}
+}
+
+func (p *pinger) Receive(c *actor.Context) {
+	if _, ok := c.Message().(*actor.Ping); ok {
+		c.Respond(&actor.Pong{From: c.PID()})
+	}
+}
+
+func TestGrainRef(t *testing.T) {
+	var clusters []*Cluster
+	seed := MemberAddr{}
+	for _, id := range []string{"A", "B", "C"} {
+		config := fastSwimConfig()
+		if id != "A" {
+			config = config.WithSeed(seed)
+		}
+		c := makeSwimCluster(t, id, config)
+		c.RegisterKind("pinger", newPinger, NewKindConfig())
+		c.Start()
+		defer c.Stop()
+		if id == "A" {
+			seed = MemberAddr{ListenAddr: c.Address(), ID: "A"}
+		}
+		clusters = append(clusters, c)
+	}
+	for _, c := range clusters {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 3
+		}, 3*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+
+	// The members that use the grain concurrently get the same activation.
+	pids := make([]*actor.PID, len(clusters))
+	var wg sync.WaitGroup
+	for i, c := range clusters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pids[i] = c.GrainRef("pinger", "bob").PID()
+		}()
+	}
+	wg.Wait()
+	require.NotNil(t, pids[0])
+	for _, pid := range pids[1:] {
+		assert.True(t, pids[0].Equals(pid), "%s != %s", pids[0], pid)
+	}
+	resp, err := clusters[1].GrainRef("pinger", "bob").Request(&actor.Ping{}, time.Second)
+	require.NoError(t, err)
+	require.IsType(t, &actor.Pong{}, resp)
+	assert.True(t, pids[0].Equals(resp.(*actor.Pong).From))
+
+	alice := clusters[2].GrainRef("pinger", "alice").WithSelectMemberFunc(SelectLocalMember)
+	pid := alice.PID()
+	require.NotNil(t, pid)
+	assert.Equal(t, clusters[2].Address(), pid.Address)
+	assert.Eventually(t, func() bool {
+		return pid.Equals(clusters[0].GetActiveByID("pinger/alice"))
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Error(t, clusters[0].GrainRef("unknown", "bob").Send(&actor.Ping{}))
+}
+
+func TestSelectMemberFuncs(t *testing.T) {
+	members := []*Member{
+		{ID: "A", Host: "127.0.0.1:4000"},
+		{ID: "B", Host: "127.0.0.1:5000"},
+		{ID: "C", Host: "127.0.0.1:6000"},
+	}
+	details := ActivationDetails{
+		Members: members,
+		Kind:    "player",
+		ID:      "1",
+		Local:   members[1],
+		Load:    map[string]int{"A": 3, "B": 2},
+	}
+	assert.Equal(t, "C", SelectLeastLoadedMember(details).ID)
+	assert.Equal(t, "B", SelectLocalMember(details).ID)
+	details.Local = &Member{ID: "D"}
+	assert.Contains(t, members, SelectLocalMember(details))
+
+	// All the members select the same member, which only changes for the
+	// actors of a member that leaves.
+	selected := SelectHashMember(details)
+	assert.Equal(t, selected, SelectHashMember(details))
+	moved := 0
+	for _, id := range []string{"1", "2", "3", "4", "5", "6", "7", "8"} {
+		details.Members, details.ID = members, id
+		before := SelectHashMember(details)
+		details.Members = members[:2]
+		after := SelectHashMember(details)
+		if before.ID != "C" {
+			assert.Equal(t, before, after)
+		} else {
+			moved++
+		}
+	}
+	assert.Less(t, moved, 8)
+}
+
+func TestSelectLowestLoadMember(t *testing.T) {
+	members := []*Member{{ID: "A"}, {ID: "B"}, {ID: "C"}}
+	details := ActivationDetails{
+		Members: members,
+		Load:    map[string]int{"A": 1, "B": 2, "C": 4},
+		Stats: map[string]*MemberStats{
+			"A": {Cpu: 0.5, MailboxBacklog: 10},
+			"B": {Cpu: 0.01, MailboxBacklog: 1},
+		},
+	}
+	// A scores 61, B 4 and C, without statistics, 4 as well.
+	assert.Equal(t, "B", SelectLowestLoadMember(details).ID)
+	details.Stats["B"].MailboxBacklog = 2
+	assert.Equal(t, "C", SelectLowestLoadMember(details).ID)
+
+	byActivations := SelectLowestScoreMember(LoadScore(LoadWeights{Activations: 1}))
+	assert.Equal(t, "A", byActivations(details).ID)
+	byCPU := SelectLowestScoreMember(func(member *Member, details ActivationDetails) float64 {
+		if stats, ok := details.Stats[member.ID]; ok {
+			return stats.Cpu
+		}
+		return 1
+	})
+	assert.Equal(t, "B", byCPU(details).ID)
+	assert.Nil(t, byCPU(ActivationDetails{}))
+}
+
+func TestStickyGrain(t *testing.T) {
+	var clusters []*Cluster
+	seed := MemberAddr{}
+	for _, id := range []string{"A", "B", "C"} {
+		config := fastSwimConfig()
+		if id != "A" {
+			config = config.WithSeed(seed)
+		}
+		c := makeSwimCluster(t, id, config)
+		c.RegisterKind("pinger", newPinger, NewKindConfig())
+		c.Start()
+		if id != "C" {
+			defer c.Stop()
+		}
+		if id == "A" {
+			seed = MemberAddr{ListenAddr: c.Address(), ID: "A"}
+		}
+		clusters = append(clusters, c)
+	}
+	for _, c := range clusters {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 3
+		}, 3*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+	a, c := clusters[0], clusters[2]
+
+	// The grain is placed on the member that calls it first.
+	pid := c.GrainRef("pinger", "bob").WithSelectMemberFunc(SelectLocalMember).WithSticky(true).PID()
+	require.NotNil(t, pid)
+	assert.Equal(t, c.Address(), pid.Address)
+
+	// And the grain returns to it, wherever it's called from next.
+	c.Deactivate(pid)
+	require.Eventually(t, func() bool {
+		for _, c := range clusters {
+			if c.GetActiveByID("pinger/bob") != nil {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+	pid = a.GrainRef("pinger", "bob").WithSelectMemberFunc(SelectLocalMember).WithSticky(true).PID()
+	require.NotNil(t, pid)
+	assert.Equal(t, c.Address(), pid.Address)
+
+	// Until the member leaves.
+	c.Stop()
+	require.Eventually(t, func() bool {
+		return len(a.Members()) == 2 && a.GetActiveByID("pinger/bob") == nil
+	}, 3*time.Second, 10*time.Millisecond)
+	pid = a.GrainRef("pinger", "bob").WithSelectMemberFunc(SelectLocalMember).WithSticky(true).PID()
+	require.NotNil(t, pid)
+	assert.Equal(t, a.Address(), pid.Address)
+}
@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/zeebo/xxh3"
+)
+
+// ringReplicas is the number of points of each member on the hash ring, which
+// spreads the keys evenly across the members.
+const ringReplicas = 100
+
+type ringPoint struct {
+	hash   uint64
+	member *Member
+}
+
+// hashRing is a consistent hash ring of the members. A key is owned by the
+// member of the first point after the hash of the key, so when a member joins
+// or leaves only the keys it owns move.
+type hashRing struct {
+	points []ringPoint
+}
+
+func newHashRing(members []*Member) *hashRing {
+	points := make([]ringPoint, 0, len(members)*ringReplicas)
+	for _, member := range members {
+		for i := range ringReplicas {
+			points = append(points, ringPoint{
+				hash:   xxh3.HashString(member.ID + "#" + strconv.Itoa(i)),
+				member: member,
+			})
+		}
+	}
+	slices.SortFunc(points, func(a, b ringPoint) int {
+		if c := cmp.Compare(a.hash, b.hash); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.member.ID, b.member.ID)
+	})
+	return &hashRing{points: points}
+}
+
+// owner returns the member that owns the given key among the members with the
+// given kind, which is the same member as on a ring of only those members.
+func (r *hashRing) owner(key, kind string) *Member {
+	if len(r.points) == 0 {
+		return nil
+	}
+	hash := xxh3.HashString(key)
+	start := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= hash
+	})
+	for i := range len(r.points) {
+		member := r.points[(start+i)%len(r.points)].member
+		if kind == "" || member.HasKind(kind) {
+			return member
+		}
+	}
+	return nil
+}
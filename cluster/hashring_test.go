@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRing(t *testing.T) {
+	members := []*Member{
+		{ID: "A", Kinds: []string{"player"}},
+		{ID: "B", Kinds: []string{"player", "inventory"}},
+		{ID: "C", Kinds: []string{"player"}},
+	}
+	ring := newHashRing(members)
+	smaller := newHashRing(members[:2])
+	inventory := newHashRing(members[1:2])
+
+	owned := make(map[string]int)
+	moved := 0
+	for i := range 3000 {
+		key := "player/" + strconv.Itoa(i)
+		owner := ring.owner(key, "")
+		owned[owner.ID]++
+		// Only the keys of the member that left move.
+		if owner.ID != "C" {
+			assert.Equal(t, owner, smaller.owner(key, ""))
+		} else {
+			moved++
+		}
+		// Skipping the members without the kind is the same as a ring of
+		// the members with the kind.
+		assert.Equal(t, inventory.owner(key, ""), ring.owner(key, "inventory"))
+	}
+	assert.Equal(t, owned["C"], moved)
+	for _, member := range members {
+		assert.InDelta(t, 1000, owned[member.ID], 300, "keys of %s", member.ID)
+	}
+	assert.Nil(t, ring.owner("player/1", "unknown"))
+	assert.Nil(t, newHashRing(nil).owner("player/1", ""))
+}
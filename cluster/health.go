@@ -0,0 +1,24 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+)
+
+// CheckHealth returns an error if the member did not join the cluster, or
+// left it, see actor.HealthChecker. The cluster adds it to the checks of its
+// engine once it started, see actor.Engine.Health.
+func (c *Cluster) CheckHealth(context.Context) error {
+	if c.agentPID == nil {
+		return errors.New("cluster is not started")
+	}
+	if _, ok := c.engine.ProcessInfo(c.agentPID); !ok {
+		return errors.New("cluster is stopped")
+	}
+	for _, member := range c.Members() {
+		if member.ID == c.config.id {
+			return nil
+		}
+	}
+	return errors.New("member did not join the cluster")
+}
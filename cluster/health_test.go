@@ -0,0 +1,30 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHealth(t *testing.T) {
+	c := makeCluster(t, getRandomLocalhostAddr(), "A", "eu-west")
+	assert.EqualError(t, c.CheckHealth(context.Background()), "cluster is not started")
+
+	c.Start()
+	require.Eventually(t, func() bool {
+		return c.Engine().Health().Ready()
+	}, time.Second, 10*time.Millisecond)
+	health := c.Engine().Health()
+	var names []string
+	for _, check := range health.Checks {
+		names = append(names, check.Name)
+	}
+	assert.Equal(t, []string{"cluster", "remote"}, names)
+
+	c.Stop()
+	assert.EqualError(t, c.CheckHealth(context.Background()), "cluster is stopped")
+	assert.False(t, c.Engine().Health().Ready())
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"cmp"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ActivationFilter reports whether ListActivations returns the given actor.
+type ActivationFilter func(*ActivationInfo) bool
+
+// ListActivations returns the actors of the given kind that are activated on
+// all the members, or the actors of all the kinds if the kind is empty, that
+// pass the filter if it's not nil. The members that did not respond in time
+// are left out. The actors are sorted by kind and ID.
+//
+//	busy := c.ListActivations("player", func(info *cluster.ActivationInfo) bool {
+//		return info.MailboxDepth > 100
+//	})
+func (c *Cluster) ListActivations(kind string, filter ActivationFilter) []*ActivationInfo {
+	members := c.Members()
+	all := make([][]*ActivationInfo, len(members))
+	var wg sync.WaitGroup
+	for i, member := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			all[i] = c.requestActivations(member, kind)
+		}()
+	}
+	wg.Wait()
+	var results []*ActivationInfo
+	for _, infos := range all {
+		for _, info := range infos {
+			if filter == nil || filter(info) {
+				results = append(results, info)
+			}
+		}
+	}
+	slices.SortFunc(results, func(a, b *ActivationInfo) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.ID, b.ID))
+	})
+	return results
+}
+
+func (c *Cluster) requestActivations(member *Member, kind string) []*ActivationInfo {
+	resp, err := c.engine.Request(member.PID(), &ActivationsRequest{Kind: kind}, c.config.requestTimeout).Result()
+	if err != nil {
+		slog.Warn("failed to request activations", "err", err, "member", member.ID)
+		return nil
+	}
+	r, ok := resp.(*ActivationsResponse)
+	if !ok {
+		slog.Warn("expected *ActivationsResponse", "got", reflect.TypeOf(resp))
+		return nil
+	}
+	return r.Activations
+}
+
+// Idle returns how long the actor did not receive a message.
+func (info *ActivationInfo) Idle() time.Duration {
+	return time.Duration(info.IdleMillis) * time.Millisecond
+}
+
+// localActivations returns the actors of the given kind, or of all the kinds,
+// that are hosted by this member.
+func (a *Agent) localActivations(kind string) *ActivationsResponse {
+	var (
+		self   = a.cluster.Member()
+		now    = time.Now()
+		engine = a.cluster.engine
+		resp   = &ActivationsResponse{}
+	)
+	for id, pid := range a.activated {
+		k, identity, _ := strings.Cut(id, "/")
+		if pid.Address != self.Host || (kind != "" && k != kind) {
+			continue
+		}
+		info, ok := engine.ProcessInfo(pid)
+		if !ok {
+			continue
+		}
+		resp.Activations = append(resp.Activations, &ActivationInfo{
+			PID:          pid,
+			Kind:         k,
+			ID:           identity,
+			Member:       self,
+			IdleMillis:   now.Sub(info.LastActive).Milliseconds(),
+			MailboxDepth: int64(info.MailboxLen),
+		})
+	}
+	return resp
+}
@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type blocker struct{ block chan struct{} }
+
+func (b blocker) Receive(c *actor.Context) {
+	if _, ok := c.Message().(*actor.Ping); ok {
+		<-b.block
+	}
+}
+
+func TestListActivations(t *testing.T) {
+	block := make(chan struct{})
+	newBlocker := func() actor.Receiver { return blocker{block: block} }
+
+	a := makeSwimCluster(t, "A", fastSwimConfig())
+	a.RegisterKind("player", NewPlayer, NewKindConfig())
+	a.RegisterKind("blocker", newBlocker, NewKindConfig())
+	a.Start()
+	defer a.Stop()
+	b := makeSwimCluster(t, "B", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))
+	b.RegisterKind("player", NewPlayer, NewKindConfig())
+	b.Start()
+	defer b.Stop()
+	// The blocker needs to be unblocked before the members stop.
+	defer close(block)
+	require.Eventually(t, func() bool {
+		return len(a.Members()) == 2 && len(b.Members()) == 2
+	}, 3*time.Second, 10*time.Millisecond)
+
+	for i := range 2 {
+		require.NotNil(t, a.Activate("player", NewActivationConfig().WithID(strconv.Itoa(i)).WithSelectMemberFunc(SelectLocalMember)))
+	}
+	require.NotNil(t, b.Activate("player", NewActivationConfig().WithID("2").WithSelectMemberFunc(SelectLocalMember)))
+	pid := a.Activate("blocker", NewActivationConfig().WithID("hot"))
+	require.NotNil(t, pid)
+	for range 4 {
+		a.Engine().Send(pid, &actor.Ping{})
+	}
+
+	require.Eventually(t, func() bool {
+		return len(b.ListActivations("player", nil)) == 3
+	}, 2*time.Second, 10*time.Millisecond)
+	players := b.ListActivations("player", nil)
+	for i, info := range players {
+		assert.Equal(t, "player", info.Kind)
+		assert.Equal(t, strconv.Itoa(i), info.ID)
+		assert.Equal(t, info.Member.Host, info.PID.Address)
+	}
+	assert.Equal(t, "A", players[0].Member.ID)
+	assert.Equal(t, "B", players[2].Member.ID)
+	assert.Len(t, b.ListActivations("", nil), 4)
+
+	// The blocker is processing the first ping, the others wait.
+	hot := func(info *ActivationInfo) bool { return info.MailboxDepth >= 3 }
+	require.Eventually(t, func() bool {
+		return len(b.ListActivations("", hot)) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "hot", b.ListActivations("", hot)[0].ID)
+
+	idle := func(info *ActivationInfo) bool { return info.Idle() >= 100*time.Millisecond }
+	require.Eventually(t, func() bool {
+		return len(a.ListActivations("player", idle)) == 3
+	}, time.Second, 10*time.Millisecond)
+}
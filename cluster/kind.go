@@ -1,15 +1,94 @@
 package cluster
 
-import "github.com/fertigai/hollywood/actor"
+import (
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
 
 // KindConfig holds configuration for a registered kind.
-type KindConfig struct{}
+type KindConfig struct {
+	partitioned bool
+	handoff     bool
+	// sharding is nil unless the kind is sharded.
+	sharding *ShardingConfig
+	// The activation limits, zero if there are none.
+	maxActivations        int
+	maxClusterActivations int
+	queueSize             int
+	queueTimeout          time.Duration
+}
 
 // NewKindConfig returns a default kind configuration.
 func NewKindConfig() KindConfig {
 	return KindConfig{}
 }
 
+// WithPartitioned set's whether the actors of the kind are partitioned across
+// the members with a consistent hash ring. Their grains are activated on the
+// member that owns their identity, whatever the placement strategy, and when
+// the members change the actors whose owner changed are activated again on
+// their new owner. Every member that registers the kind should partition it.
+//
+// Defaults to false.
+func (config KindConfig) WithPartitioned(partitioned bool) KindConfig {
+	config.partitioned = partitioned
+	return config
+}
+
+// WithSharding set's the sharding of the kind, whose entities are grouped into
+// shards that are spread across the members, see ShardRegion. The entities
+// move along with their shard when the members change.
+//
+// Defaults to none.
+func (config KindConfig) WithSharding(sharding ShardingConfig) KindConfig {
+	config.sharding = &sharding
+	return config
+}
+
+// WithHandoff set's whether the actors of the kind hand off their state when
+// they move to another member. Such actors are requested a Handoff before
+// they stop, and the state they respond with is sent to their new activation.
+//
+// Defaults to false.
+func (config KindConfig) WithHandoff(handoff bool) KindConfig {
+	config.handoff = handoff
+	return config
+}
+
+// WithMaxActivations set's the maximum number of actors of the kind this
+// member hosts. The activations beyond the limit are rejected, or wait in the
+// queue of the kind, see WithActivationQueue.
+//
+// Defaults to no limit.
+func (config KindConfig) WithMaxActivations(n int) KindConfig {
+	config.maxActivations = n
+	return config
+}
+
+// WithMaxClusterActivations set's the maximum number of actors of the kind
+// across the cluster. A member counts the actors of the other members once
+// they are announced, so concurrent activations on different members can
+// exceed the limit briefly.
+//
+// Defaults to no limit.
+func (config KindConfig) WithMaxClusterActivations(n int) KindConfig {
+	config.maxClusterActivations = n
+	return config
+}
+
+// WithActivationQueue set's how many activations beyond the limits of the
+// kind wait for an actor of the kind to be deactivated, and for how long at
+// most, before they are rejected. The timeout should be shorter than the
+// request timeout of the members that activate the kind.
+//
+// Defaults to no queue, the activations beyond the limits are rejected.
+func (config KindConfig) WithActivationQueue(size int, timeout time.Duration) KindConfig {
+	config.queueSize = size
+	config.queueTimeout = timeout
+	return config
+}
+
 // A kind is a type of actor that can be activated from any member of the cluster.
 type kind struct {
 	config   KindConfig
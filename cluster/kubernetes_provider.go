@@ -0,0 +1,427 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+const (
+	serviceAccountDir       = "/var/run/secrets/kubernetes.io/serviceaccount"
+	kubernetesPortName      = "hollywood"
+	kubernetesRetryInterval = 2 * time.Second
+	kubernetesWatchTimeout  = 5 * time.Minute
+)
+
+type (
+	// kubernetesPods holds the addresses of the pods that match the label
+	// selector, keyed by the pod name.
+	kubernetesPods struct {
+		pods map[string]string
+	}
+	kubernetesTick struct{}
+)
+
+type KubernetesProviderConfig struct {
+	apiServer     string
+	namespace     string
+	labelSelector string
+	portName      string
+	httpClient    *http.Client
+}
+
+// NewKubernetesProviderConfig returns a KubernetesProviderConfig that is
+// initialized with default values. The defaults are taken from the service
+// account of the pod, so the provider works out of the box inside a cluster.
+func NewKubernetesProviderConfig() KubernetesProviderConfig {
+	config := KubernetesProviderConfig{
+		namespace: "default",
+		portName:  kubernetesPortName,
+	}
+	if host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT"); host != "" {
+		config.apiServer = "https://" + net.JoinHostPort(host, port)
+	}
+	if b, err := os.ReadFile(serviceAccountDir + "/namespace"); err == nil {
+		config.namespace = strings.TrimSpace(string(b))
+	}
+	return config
+}
+
+// WithAPIServer set's the URL of the Kubernetes API server.
+//
+// Defaults to the API server of the cluster the pod runs in.
+func (c KubernetesProviderConfig) WithAPIServer(url string) KubernetesProviderConfig {
+	c.apiServer = url
+	return c
+}
+
+// WithNamespace set's the namespace in which the pods are listed.
+//
+// Defaults to the namespace of the pod.
+func (c KubernetesProviderConfig) WithNamespace(namespace string) KubernetesProviderConfig {
+	c.namespace = namespace
+	return c
+}
+
+// WithLabelSelector set's the label selector of the pods that are members of
+// the cluster, for example "app=game,tier=backend".
+//
+// Defaults to all the pods of the namespace.
+func (c KubernetesProviderConfig) WithLabelSelector(selector string) KubernetesProviderConfig {
+	c.labelSelector = selector
+	return c
+}
+
+// WithPortName set's the name of the container port the cluster listens on.
+// Pods without a port of that name are expected to listen on the same port as
+// this node.
+//
+// Defaults to "hollywood".
+func (c KubernetesProviderConfig) WithPortName(name string) KubernetesProviderConfig {
+	c.portName = name
+	return c
+}
+
+// WithHTTPClient set's the client used to talk to the API server.
+//
+// Defaults to a client that trusts the CA of the service account.
+func (c KubernetesProviderConfig) WithHTTPClient(client *http.Client) KubernetesProviderConfig {
+	c.httpClient = client
+	return c
+}
+
+// KubernetesProvider is a provider that discovers the members of the cluster
+// through the Kubernetes API. It watches the running pods that match the label
+// selector and sends each of them a handshake, so the members learn about each
+// other's kinds. A member leaves the cluster once its pod is deleted.
+//
+// The ID of each member needs to be the name of its pod, and it needs to
+// listen on the IP of its pod. Both are passed to the container with the
+// downward API:
+//
+//	env:
+//	  - name: POD_NAME
+//	    valueFrom:
+//	      fieldRef:
+//	        fieldPath: metadata.name
+//	  - name: POD_IP
+//	    valueFrom:
+//	      fieldRef:
+//	        fieldPath: status.podIP
+//
+// The service account of the pod needs the permission to list and watch pods.
+type KubernetesProvider struct {
+	config  KubernetesProviderConfig
+	cluster *Cluster
+	pid     *actor.PID
+	ticker  actor.SendRepeater
+
+	// pods holds the address of each pod as reported by the API server.
+	pods map[string]string
+	// members holds the members that answered our handshake.
+	members map[string]*Member
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewKubernetesProvider returns a provider that discovers the members of the
+// cluster with the Kubernetes API.
+func NewKubernetesProvider(config KubernetesProviderConfig) Producer {
+	return func(c *Cluster) actor.Producer {
+		return func() actor.Receiver {
+			return &KubernetesProvider{
+				config:  config,
+				cluster: c,
+				pods:    make(map[string]string),
+				members: make(map[string]*Member),
+			}
+		}
+	}
+}
+
+func (p *KubernetesProvider) Receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.Started:
+		p.pid = c.PID()
+		p.ctx, p.cancel = context.WithCancel(context.Background())
+		p.sendMembersToAgent()
+		p.ticker = c.SendRepeat(c.PID(), kubernetesTick{}, kubernetesRetryInterval)
+		if p.config.httpClient == nil {
+			client, err := serviceAccountClient()
+			if err != nil {
+				slog.Error("kubernetes provider", "err", err)
+				return
+			}
+			p.config.httpClient = client
+		}
+		go p.watch()
+	case actor.Stopped:
+		p.ticker.Stop()
+		p.cancel()
+	case kubernetesPods:
+		p.updatePods(msg.pods)
+	case kubernetesTick:
+		p.handshake()
+	case *Handshake:
+		p.addMembers(msg.Member)
+		p.send(c.Sender(), &Members{Members: []*Member{p.cluster.Member()}})
+	case *Members:
+		p.addMembers(msg.Members...)
+	case actor.Initialized:
+		_ = msg
+	default:
+		slog.Warn("received unhandled message", "msg", msg, "t", reflect.TypeOf(msg))
+	}
+}
+
+// updatePods replaces the known pods. The members of the pods that are gone,
+// or that got a new address, leave the cluster.
+func (p *KubernetesProvider) updatePods(pods map[string]string) {
+	delete(pods, p.cluster.ID())
+	changed := false
+	for id := range p.members {
+		addr, ok := pods[id]
+		if prev, known := p.pods[id]; !ok || known && addr != prev {
+			delete(p.members, id)
+			changed = true
+		}
+	}
+	p.pods = pods
+	if changed {
+		p.sendMembersToAgent()
+	}
+	p.handshake()
+}
+
+// handshake sends a handshake to the pods that did not answer yet, which
+// happens when the cluster of the pod was not started yet.
+func (p *KubernetesProvider) handshake() {
+	for name, addr := range p.pods {
+		if _, ok := p.members[name]; ok {
+			continue
+		}
+		p.send(actor.NewPID(addr, "provider/"+name), &Handshake{Member: p.cluster.Member()})
+	}
+}
+
+func (p *KubernetesProvider) addMembers(members ...*Member) {
+	for _, member := range members {
+		if member.ID == p.cluster.ID() {
+			continue
+		}
+		p.members[member.ID] = member
+	}
+	p.sendMembersToAgent()
+}
+
+func (p *KubernetesProvider) send(pid *actor.PID, msg any) {
+	if pid == nil {
+		return
+	}
+	p.cluster.engine.SendWithSender(pid, msg, p.pid)
+}
+
+// sendMembersToAgent sends this member and the members of the known pods to
+// the local cluster agent.
+func (p *KubernetesProvider) sendMembersToAgent() {
+	members := []*Member{p.cluster.Member()}
+	for id, member := range p.members {
+		if _, ok := p.pods[id]; ok {
+			members = append(members, member)
+		}
+	}
+	p.cluster.engine.Send(p.cluster.PID(), &Members{Members: members})
+}
+
+// watch lists the pods and watches them for changes until the provider is
+// stopped. The pods are listed again whenever the watch ends.
+func (p *KubernetesProvider) watch() {
+	for {
+		pods, version, err := p.listPods()
+		if err == nil {
+			p.cluster.engine.Send(p.pid, kubernetesPods{pods: maps.Clone(pods)})
+			err = p.watchPods(pods, version)
+		}
+		if p.ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			continue
+		}
+		slog.Warn("kubernetes provider", "err", err)
+		select {
+		case <-time.After(kubernetesRetryInterval):
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *KubernetesProvider) listPods() (map[string]string, string, error) {
+	resp, err := p.get(url.Values{})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	var list struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+		Items []kubernetesPod `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, "", fmt.Errorf("failed to decode the pods: %w", err)
+	}
+	pods := make(map[string]string)
+	for _, pod := range list.Items {
+		if addr := p.podAddress(pod); addr != "" {
+			pods[pod.Metadata.Name] = addr
+		}
+	}
+	return pods, list.Metadata.ResourceVersion, nil
+}
+
+// watchPods applies the changes to the pods since the given resource version,
+// until the API server ends the watch.
+func (p *KubernetesProvider) watchPods(pods map[string]string, version string) error {
+	resp, err := p.get(url.Values{
+		"watch":               {"true"},
+		"resourceVersion":     {version},
+		"allowWatchBookmarks": {"true"},
+		"timeoutSeconds":      {strconv.Itoa(int(kubernetesWatchTimeout.Seconds()))},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var event struct {
+			Type   string          `json:"type"`
+			Object json.RawMessage `json:"object"`
+		}
+		if err := dec.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to decode the watch event: %w", err)
+		}
+		if event.Type == "ERROR" {
+			// The resource version is too old, most likely.
+			return fmt.Errorf("watch failed: %s", event.Object)
+		}
+		var pod kubernetesPod
+		if err := json.Unmarshal(event.Object, &pod); err != nil {
+			return fmt.Errorf("failed to decode the pod: %w", err)
+		}
+		addr := p.podAddress(pod)
+		if event.Type == "DELETED" {
+			addr = ""
+		}
+		name := pod.Metadata.Name
+		if event.Type == "BOOKMARK" || pods[name] == addr {
+			continue
+		}
+		if addr == "" {
+			delete(pods, name)
+		} else {
+			pods[name] = addr
+		}
+		p.cluster.engine.Send(p.pid, kubernetesPods{pods: maps.Clone(pods)})
+	}
+}
+
+func (p *KubernetesProvider) get(query url.Values) (*http.Response, error) {
+	if p.config.labelSelector != "" {
+		query.Set("labelSelector", p.config.labelSelector)
+	}
+	u := fmt.Sprintf("%s/api/v1/namespaces/%s/pods?%s", p.config.apiServer, url.PathEscape(p.config.namespace), query.Encode())
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The token is read on every request, as the kubelet rotates it.
+	if token, err := os.ReadFile(serviceAccountDir + "/token"); err == nil {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := p.config.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("listing pods failed with %s: %s", resp.Status, b)
+	}
+	return resp, nil
+}
+
+// kubernetesPod holds the fields of a pod that are needed to reach its member.
+type kubernetesPod struct {
+	Metadata struct {
+		Name              string  `json:"name"`
+		DeletionTimestamp *string `json:"deletionTimestamp"`
+	} `json:"metadata"`
+	Spec struct {
+		Containers []struct {
+			Ports []struct {
+				Name          string `json:"name"`
+				ContainerPort int    `json:"containerPort"`
+			} `json:"ports"`
+		} `json:"containers"`
+	} `json:"spec"`
+	Status struct {
+		Phase string `json:"phase"`
+		PodIP string `json:"podIP"`
+	} `json:"status"`
+}
+
+// podAddress returns the address the member of the pod listens on, or an
+// empty string if the pod is not running.
+func (p *KubernetesProvider) podAddress(pod kubernetesPod) string {
+	if pod.Status.Phase != "Running" || pod.Status.PodIP == "" || pod.Metadata.DeletionTimestamp != nil {
+		return ""
+	}
+	_, port, _ := net.SplitHostPort(p.cluster.Address())
+	for _, container := range pod.Spec.Containers {
+		for _, cp := range container.Ports {
+			if cp.Name == p.config.portName {
+				port = strconv.Itoa(cp.ContainerPort)
+			}
+		}
+	}
+	return net.JoinHostPort(pod.Status.PodIP, port)
+}
+
+// serviceAccountClient returns a client that trusts the CA of the service
+// account.
+func serviceAccountClient() (*http.Client, error) {
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("invalid service account CA")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,89 @@
+package cluster
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makePod(name, addr string) map[string]any {
+	host, port, _ := net.SplitHostPort(addr)
+	containerPort, _ := strconv.Atoi(port)
+	return map[string]any{
+		"metadata": map[string]any{"name": name},
+		"spec": map[string]any{"containers": []any{map[string]any{
+			"ports": []any{map[string]any{"name": "hollywood", "containerPort": containerPort}},
+		}}},
+		"status": map[string]any{"phase": "Running", "podIP": host},
+	}
+}
+
+func TestKubernetesProvider(t *testing.T) {
+	addrA := getRandomLocalhostAddr()
+	addrB := getRandomLocalhostAddr()
+	deleted := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/namespaces/game/pods", r.URL.Path)
+		assert.Equal(t, "app=game", r.URL.Query().Get("labelSelector"))
+		if r.URL.Query().Get("watch") != "true" {
+			pods := []any{makePod("A", addrA), makePod("B", addrB), makePod("C", "127.0.0.1:1")}
+			select {
+			case <-deleted:
+				pods = pods[:1]
+			default:
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"metadata": map[string]any{"resourceVersion": "1"},
+				"items":    pods,
+			})
+			return
+		}
+		w.(http.Flusher).Flush()
+		select {
+		case <-deleted:
+			json.NewEncoder(w).Encode(map[string]any{"type": "DELETED", "object": makePod("B", addrB)})
+			w.(http.Flusher).Flush()
+		case <-r.Context().Done():
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	config := NewKubernetesProviderConfig().
+		WithAPIServer(server.URL).
+		WithNamespace("game").
+		WithLabelSelector("app=game").
+		WithHTTPClient(server.Client())
+	var clusters []*Cluster
+	for i, id := range []string{"A", "B"} {
+		c, err := New(NewConfig().
+			WithID(id).
+			WithListenAddr([]string{addrA, addrB}[i]).
+			WithProvider(NewKubernetesProvider(config)))
+		require.NoError(t, err)
+		c.RegisterKind("player"+id, NewPlayer, NewKindConfig())
+		c.Start()
+		defer c.Stop()
+		clusters = append(clusters, c)
+	}
+
+	// C is never started, so it does not become a member.
+	for _, c := range clusters {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 2 && c.HasKind("playerA") && c.HasKind("playerB")
+		}, 2*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+
+	// B leaves once its pod is deleted, although it is still running.
+	close(deleted)
+	assert.Eventually(t, func() bool {
+		return len(clusters[0].Members()) == 1 && !clusters[0].HasKind("playerB")
+	}, 2*time.Second, 10*time.Millisecond)
+}
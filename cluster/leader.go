@@ -0,0 +1,164 @@
+package cluster
+
+import (
+	"log/slog"
+	"time"
+)
+
+type (
+	getLeader       struct{}
+	electionTimeout struct{ seq uint64 }
+)
+
+// election holds the state of the leader election of the agent. The members
+// elect the leader with the bully algorithm: a member that notices the leader
+// is missing asks the members with a higher ID to take over. If none of them
+// answers in time, it becomes the leader and announces itself to all the
+// members. Hence the leader is the member with the highest ID that is alive.
+type election struct {
+	leader *Member
+	term   uint64
+	// running is true while this member takes part in an election.
+	running bool
+	// answered is true if a member with a higher ID took over.
+	answered bool
+	// seq identifies the current election, so the timeouts of the previous
+	// ones are ignored.
+	seq uint64
+}
+
+// checkLeader starts an election when the leader left, or when a member with
+// a higher ID joined.
+func (a *Agent) checkLeader(joined, left []*Member) {
+	elect := a.election.leader == nil
+	for _, member := range left {
+		if a.election.leader != nil && member.ID == a.election.leader.ID {
+			a.election.leader = nil
+			elect = true
+		}
+	}
+	for _, member := range joined {
+		if a.election.leader != nil && member.ID > a.election.leader.ID {
+			elect = true
+		}
+		// Tell the new member who the leader is.
+		if a.isLeader() {
+			a.send(member, &Coordinator{Leader: a.cluster.Member(), Term: a.election.term})
+		}
+	}
+	if elect {
+		a.startElection()
+	}
+}
+
+func (a *Agent) startElection() {
+	if a.election.running {
+		return
+	}
+	self := a.cluster.Member()
+	higher := make([]*Member, 0)
+	a.members.ForEach(func(member *Member) bool {
+		if member.ID > self.ID {
+			higher = append(higher, member)
+		}
+		return true
+	})
+	if len(higher) == 0 {
+		a.becomeLeader()
+		return
+	}
+	a.election.running = true
+	a.election.answered = false
+	a.election.seq++
+	for _, member := range higher {
+		a.send(member, &Election{Term: a.election.term, Candidate: self})
+	}
+	a.scheduleElectionTimeout(a.cluster.config.requestTimeout)
+}
+
+func (a *Agent) scheduleElectionTimeout(d time.Duration) {
+	seq, pid, engine := a.election.seq, a.cluster.PID(), a.cluster.engine
+	time.AfterFunc(d, func() {
+		engine.Send(pid, electionTimeout{seq: seq})
+	})
+}
+
+// handleElectionTimeout becomes the leader if no member with a higher ID
+// answered, and starts over if the member that answered did not announce
+// itself in time.
+func (a *Agent) handleElectionTimeout(msg electionTimeout) {
+	if !a.election.running || msg.seq != a.election.seq {
+		return
+	}
+	if !a.election.answered {
+		a.election.running = false
+		a.becomeLeader()
+		return
+	}
+	a.election.running = false
+	a.startElection()
+}
+
+// handleElection answers a member with a lower ID and takes over its
+// election.
+func (a *Agent) handleElection(msg *Election) {
+	a.election.term = max(a.election.term, msg.Term)
+	if msg.Candidate != nil {
+		a.send(msg.Candidate, &ElectionAlive{Term: a.election.term})
+	}
+	a.startElection()
+}
+
+func (a *Agent) handleElectionAlive(msg *ElectionAlive) {
+	a.election.term = max(a.election.term, msg.Term)
+	if a.election.running && !a.election.answered {
+		a.election.answered = true
+		// Give the member time to win its own election.
+		a.election.seq++
+		a.scheduleElectionTimeout(2 * a.cluster.config.requestTimeout)
+	}
+}
+
+// handleCoordinator accepts the announced leader, unless this member has a
+// higher ID, in which case it bullies the leader with an election of its own.
+func (a *Agent) handleCoordinator(msg *Coordinator) {
+	if msg.Leader == nil {
+		return
+	}
+	a.election.term = max(a.election.term, msg.Term)
+	if msg.Leader.ID < a.cluster.ID() {
+		a.startElection()
+		return
+	}
+	a.election.running = false
+	a.setLeader(msg.Leader)
+}
+
+func (a *Agent) becomeLeader() {
+	a.election.term++
+	self := a.cluster.Member()
+	a.members.ForEach(func(member *Member) bool {
+		if member.ID != self.ID {
+			a.send(member, &Coordinator{Leader: self, Term: a.election.term})
+		}
+		return true
+	})
+	a.setLeader(self)
+}
+
+func (a *Agent) setLeader(leader *Member) {
+	if a.election.leader != nil && a.election.leader.Equals(leader) {
+		return
+	}
+	a.election.leader = leader
+	a.cluster.engine.BroadcastEvent(LeaderChangedEvent{Leader: leader, Term: a.election.term})
+	slog.Debug("[CLUSTER] leader elected", "id", leader.ID, "host", leader.Host, "term", a.election.term)
+}
+
+func (a *Agent) isLeader() bool {
+	return a.election.leader != nil && a.election.leader.ID == a.cluster.ID()
+}
+
+func (a *Agent) send(member *Member, msg any) {
+	a.cluster.engine.SendWithSender(member.PID(), msg, a.cluster.PID())
+}
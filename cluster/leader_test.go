@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func leaderID(c *Cluster) string {
+	if leader := c.Leader(); leader != nil {
+		return leader.ID
+	}
+	return ""
+}
+
+func TestLeaderElection(t *testing.T) {
+	a := makeSwimCluster(t, "A", fastSwimConfig())
+	a.Start()
+	defer a.Stop()
+	seed := MemberAddr{ListenAddr: a.Address(), ID: "A"}
+	b := makeSwimCluster(t, "B", fastSwimConfig().WithSeed(seed))
+	b.Start()
+	defer b.Stop()
+	c := makeSwimCluster(t, "C", fastSwimConfig().WithSeed(seed))
+	c.Start()
+
+	// The member with the highest ID wins.
+	for _, cl := range []*Cluster{a, b, c} {
+		require.Eventually(t, func() bool {
+			return leaderID(cl) == "C"
+		}, 3*time.Second, 10*time.Millisecond, "leader of %s", cl.ID())
+	}
+	assert.True(t, c.IsLeader())
+	assert.False(t, a.IsLeader())
+
+	events := make(chan LeaderChangedEvent, 4)
+	eventPID := b.Engine().SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(LeaderChangedEvent); ok {
+			events <- msg
+		}
+	}, "event")
+	b.Engine().Subscribe(eventPID)
+
+	// B takes over once the leader failed.
+	c.Stop()
+	select {
+	case evt := <-events:
+		assert.Equal(t, "B", evt.Leader.ID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected a LeaderChangedEvent")
+	}
+	assert.True(t, b.IsLeader())
+	require.Eventually(t, func() bool {
+		return leaderID(a) == "B"
+	}, 3*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,144 @@
+package cluster
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// drainInterval is how often Leave checks whether the remote sent all the
+// messages that wait for the other members.
+const drainInterval = 10 * time.Millisecond
+
+type leave struct{ done chan struct{} }
+
+// Handoff is requested from the actors of the kinds with handoff before they
+// move to another member, see KindConfig.WithHandoff. The actor responds with
+// its state, which needs to be a protobuf message or a type registered with
+// remote.RegisterCodec, and its new activation receives that state right after
+// it started. An actor that responds with nil starts afresh.
+type Handoff struct {
+	// To is the member the actor moves to.
+	To *Member
+}
+
+// Leave leaves the cluster gracefully, which allows deploys without downtime.
+// The member stops accepting new activations, moves the actors it hosts to
+// the other members, waits until the remote sent all the pending messages
+// and then stops the cluster. The actors of partitioned kinds move to the new
+// owner of their identity and the others to a random member with their kind,
+// and the actors that no other member can host are deactivated.
+//
+// The engine drains from then on, so it's no longer ready, see
+// actor.Engine.SetDraining. If the context is done before, Leave returns its
+// error and the cluster is not stopped.
+func (c *Cluster) Leave(ctx context.Context) error {
+	c.engine.SetDraining(true)
+	done := make(chan struct{})
+	c.engine.Send(c.agentPID, leave{done: done})
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := c.drain(ctx); err != nil {
+		return err
+	}
+	c.Stop()
+	return nil
+}
+
+// drain waits until the remote has no messages waiting for the other members.
+func (c *Cluster) drain(ctx context.Context) error {
+	if c.remote == nil {
+		return nil
+	}
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	for {
+		pending := int64(0)
+		for _, peer := range c.remote.Metrics() {
+			pending += peer.QueueDepth
+		}
+		if pending == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (a *Agent) handleLeave(msg leave) {
+	self := a.cluster.Member()
+	if a.leaving[self.ID] {
+		// Leave was called already.
+		close(msg.done)
+		return
+	}
+	a.leaving[self.ID] = true
+	a.leaveDone = msg.done
+	a.bcast(&MemberLeaving{Member: self})
+	a.ring = newHashRing(a.placeableMembers())
+
+	moves := 0
+	for id, pid := range a.activated {
+		if pid.Address != self.Host || a.rebalance.moving[id] {
+			continue
+		}
+		kind, identity, _ := strings.Cut(id, "/")
+		var member *Member
+		if a.isPartitioned(kind) {
+			member = a.partitionOwner(kind, identity)
+		} else if details := a.activationDetails(kind, NewActivationConfig()); len(details.Members) > 0 {
+			member = SelectRandomMember(details)
+		}
+		if member == nil {
+			slog.Warn("no member to move actor to, deactivating it", "pid", pid)
+			a.bcast(&Deactivation{PID: pid})
+			continue
+		}
+		a.moveActor(pid, member)
+		moves++
+	}
+	slog.Debug("[CLUSTER] leaving", "id", self.ID, "moving", moves)
+	a.startRebalance(moves)
+	if len(a.rebalance.moving) == 0 {
+		a.finishLeave()
+	}
+}
+
+// finishLeave lets Leave continue once all the actors moved.
+func (a *Agent) finishLeave() {
+	if a.leaveDone != nil {
+		close(a.leaveDone)
+		a.leaveDone = nil
+	}
+}
+
+// handleMemberLeaving stops activating actors on the member that leaves. The
+// member stays in the cluster until its provider removes it.
+func (a *Agent) handleMemberLeaving(msg *MemberLeaving) {
+	if msg.Member == nil || !a.members.Contains(msg.Member) {
+		return
+	}
+	a.leaving[msg.Member.ID] = true
+	a.ring = newHashRing(a.placeableMembers())
+	slog.Debug("[CLUSTER] member leaving", "id", msg.Member.ID, "host", msg.Member.Host)
+}
+
+// placeableMembers returns the members actors can be activated on, which
+// leaves out the members that leave or drain.
+func (a *Agent) placeableMembers() []*Member {
+	members := make([]*Member, 0, a.members.Len())
+	a.members.ForEach(func(member *Member) bool {
+		if !a.leaving[member.ID] && !a.draining[member.ID] {
+			members = append(members, member)
+		}
+		return true
+	})
+	return members
+}
@@ -0,0 +1,91 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	counterAdd   struct{ N int }
+	counterGet   struct{}
+	counterState struct{ N int }
+)
+
+func init() {
+	remote.RegisterCodec[*counterAdd]("cluster.counterAdd", remote.JSONCodec[*counterAdd]{})
+	remote.RegisterCodec[*counterGet]("cluster.counterGet", remote.JSONCodec[*counterGet]{})
+	remote.RegisterCodec[*counterState]("cluster.counterState", remote.JSONCodec[*counterState]{})
+}
+
+type counter struct{ n int }
+
+func newCounter() actor.Receiver {
+	return &counter{}
+}
+
+func (c *counter) Receive(ctx *actor.Context) {
+	switch msg := ctx.Message().(type) {
+	case *counterAdd:
+		c.n += msg.N
+	case *counterGet:
+		ctx.Respond(&counterState{N: c.n})
+	case Handoff:
+		ctx.Respond(&counterState{N: c.n})
+	case *counterState:
+		c.n = msg.N
+	}
+}
+
+func TestLeave(t *testing.T) {
+	config := NewKindConfig().WithHandoff(true)
+	a := makeSwimCluster(t, "A", fastSwimConfig())
+	a.RegisterKind("counter", newCounter, config)
+	a.Start()
+	defer a.Stop()
+	b := makeSwimCluster(t, "B", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))
+	b.RegisterKind("counter", newCounter, config)
+	b.Start()
+	for _, c := range []*Cluster{a, b} {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 2
+		}, 3*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+
+	onB := func(details ActivationDetails) *Member {
+		for _, m := range details.Members {
+			if m.ID == "B" {
+				return m
+			}
+		}
+		return nil
+	}
+	grain := a.GrainRef("counter", "1").WithSelectMemberFunc(onB)
+	pid := grain.PID()
+	require.NotNil(t, pid)
+	require.Equal(t, b.Address(), pid.Address)
+	for range 3 {
+		require.NoError(t, grain.Send(&counterAdd{N: 1}))
+	}
+	resp, err := grain.Request(&counterGet{}, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, &counterState{N: 3}, resp)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	require.NoError(t, b.Leave(ctx))
+	assert.True(t, b.Engine().Health().Draining)
+
+	// The counter moved to A with its state.
+	moved := a.GetActiveByID("counter/1")
+	require.NotNil(t, moved)
+	assert.Equal(t, a.Address(), moved.Address)
+	resp, err = grain.Request(&counterGet{}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, &counterState{N: 3}, resp)
+}
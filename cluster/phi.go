@@ -0,0 +1,136 @@
+package cluster
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	defaultPhiThreshold = 8.0
+	defaultSampleWindow = 200
+	defaultMinStdDev    = 100 * time.Millisecond
+)
+
+// FailureDetectorConfig holds the configuration of the phi accrual failure
+// detector of the SWIM provider. The detector learns the distribution of the
+// intervals between the messages of each member, and computes phi, the
+// suspicion level of the member, from how long it has been silent. A phi of
+// 1 means the chance that the member is still alive is 10%, a phi of 2 1%,
+// and so on.
+type FailureDetectorConfig struct {
+	threshold    float64
+	sampleWindow int
+	minStdDev    time.Duration
+}
+
+// NewFailureDetectorConfig returns a FailureDetectorConfig that is
+// initialized with default values.
+func NewFailureDetectorConfig() FailureDetectorConfig {
+	return FailureDetectorConfig{
+		threshold:    defaultPhiThreshold,
+		sampleWindow: defaultSampleWindow,
+		minStdDev:    defaultMinStdDev,
+	}
+}
+
+// WithThreshold set's the phi above which a member that did not ack its probe
+// is suspected. A lower threshold detects failures faster, a higher one
+// suspects less members by mistake.
+//
+// Defaults to 8.
+func (config FailureDetectorConfig) WithThreshold(phi float64) FailureDetectorConfig {
+	config.threshold = phi
+	return config
+}
+
+// WithSampleWindow set's the number of the last intervals between the
+// messages of a member the distribution is estimated from.
+//
+// Defaults to 200.
+func (config FailureDetectorConfig) WithSampleWindow(n int) FailureDetectorConfig {
+	config.sampleWindow = n
+	return config
+}
+
+// WithMinStdDev set's the minimum standard deviation of the distribution, so
+// a member that sent its messages very regularly is not suspected as soon as
+// one of them is a little late, like on a jittery network.
+//
+// Defaults to 100 milliseconds.
+func (config FailureDetectorConfig) WithMinStdDev(d time.Duration) FailureDetectorConfig {
+	config.minStdDev = d
+	return config
+}
+
+// phiDetector is the phi accrual failure detector of a single member, see
+// Hayashibara et al., "The φ Accrual Failure Detector".
+type phiDetector struct {
+	config FailureDetectorConfig
+	last   time.Time
+	// The last intervals in milliseconds, a ring once the window is full.
+	intervals []float64
+	next      int
+	sum       float64
+	squares   float64
+}
+
+// newPhiDetector returns a detector that expects the first messages at the
+// given interval, until it learned the actual ones.
+func newPhiDetector(config FailureDetectorConfig, estimate time.Duration, now time.Time) *phiDetector {
+	d := &phiDetector{
+		config:    config,
+		last:      now,
+		intervals: make([]float64, 0, max(config.sampleWindow, 1)),
+	}
+	// Two samples around the estimate, with a standard deviation of a
+	// quarter of it.
+	mean := float64(estimate) / float64(time.Millisecond)
+	d.add(mean - mean/4)
+	d.add(mean + mean/4)
+	return d
+}
+
+// heartbeat records a message of the member.
+func (d *phiDetector) heartbeat(now time.Time) {
+	if interval := now.Sub(d.last); interval > 0 {
+		d.add(float64(interval) / float64(time.Millisecond))
+	}
+	d.last = now
+}
+
+func (d *phiDetector) add(interval float64) {
+	if len(d.intervals) < cap(d.intervals) {
+		d.intervals = append(d.intervals, interval)
+	} else {
+		old := d.intervals[d.next]
+		d.sum -= old
+		d.squares -= old * old
+		d.intervals[d.next] = interval
+		d.next = (d.next + 1) % len(d.intervals)
+	}
+	d.sum += interval
+	d.squares += interval * interval
+}
+
+// phi returns the suspicion level of the member at the given time.
+func (d *phiDetector) phi(now time.Time) float64 {
+	n := float64(len(d.intervals))
+	mean := d.sum / n
+	variance := max(d.squares/n-mean*mean, 0)
+	stdDev := max(math.Sqrt(variance), float64(d.config.minStdDev)/float64(time.Millisecond))
+	elapsed := float64(now.Sub(d.last)) / float64(time.Millisecond)
+	// The logistic approximation of the cumulative distribution function of
+	// the normal distribution.
+	y := (elapsed - mean) / stdDev
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if elapsed > mean {
+		return -math.Log10(e / (1 + e))
+	}
+	return -math.Log10(1 - 1/(1+e))
+}
+
+// suspicious returns true whether phi of the member exceeds the threshold at
+// the given time.
+func (d *phiDetector) suspicious(now time.Time) bool {
+	return d.phi(now) > d.config.threshold
+}
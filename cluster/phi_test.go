@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhiDetector(t *testing.T) {
+	config := NewFailureDetectorConfig().WithSampleWindow(20).WithMinStdDev(10 * time.Millisecond)
+	now := time.Now()
+	d := newPhiDetector(config, time.Second, now)
+	for range 50 {
+		now = now.Add(100 * time.Millisecond)
+		d.heartbeat(now)
+	}
+
+	// The detector learned the actual interval.
+	assert.Less(t, d.phi(now.Add(100*time.Millisecond)), 1.0)
+	assert.False(t, d.suspicious(now.Add(130*time.Millisecond)))
+	assert.True(t, d.suspicious(now.Add(300*time.Millisecond)))
+	assert.Greater(t, d.phi(now.Add(time.Second)), d.phi(now.Add(300*time.Millisecond)))
+
+	// A larger minimum standard deviation tolerates the jitter.
+	config = config.WithMinStdDev(100 * time.Millisecond)
+	d.config = config
+	assert.False(t, d.suspicious(now.Add(300*time.Millisecond)))
+	assert.True(t, d.suspicious(now.Add(time.Second)))
+}
+
+func TestPhiDetectorSampleWindow(t *testing.T) {
+	config := NewFailureDetectorConfig().WithSampleWindow(10).WithMinStdDev(10 * time.Millisecond)
+	now := time.Now()
+	d := newPhiDetector(config, time.Second, now)
+	for range 20 {
+		now = now.Add(time.Second)
+		d.heartbeat(now)
+	}
+	for range 10 {
+		now = now.Add(100 * time.Millisecond)
+		d.heartbeat(now)
+	}
+
+	// The intervals of a second dropped out of the window.
+	assert.Len(t, d.intervals, 10)
+	assert.InDelta(t, 1000, d.sum, 0.001)
+	assert.True(t, d.suspicious(now.Add(500*time.Millisecond)))
+}
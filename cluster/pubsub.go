@@ -0,0 +1,155 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/remote"
+)
+
+// publishAttempts is how many times an acked message is sent to a member
+// before Publish gives up on it.
+const publishAttempts = 3
+
+// Delivery is the delivery guarantee of the messages published on a topic.
+type Delivery int
+
+const (
+	// DeliveryBestEffort sends the messages once, without waiting for the
+	// members to receive them.
+	DeliveryBestEffort Delivery = iota
+	// DeliveryAcked waits until every member acknowledged the message, and
+	// sends it again to the members that did not in time. A member can
+	// receive a message more than once.
+	DeliveryAcked
+)
+
+type (
+	subscribe struct {
+		topic string
+		pid   *actor.PID
+	}
+	unsubscribe struct {
+		topic string
+		pid   *actor.PID
+	}
+)
+
+// Publish publishes the given message on the topic to all the members of the
+// cluster, which deliver it to their subscribers of the topic. The message
+// needs to be a protobuf message or a type registered with
+// remote.RegisterCodec. Besides the subscribers, every member publishes the
+// message on its eventstream as a TopicEvent.
+//
+// How the message is delivered depends on the delivery of the topic, see
+// Config.WithTopicDelivery. With DeliveryAcked, Publish returns an error if
+// a member did not acknowledge the message.
+//
+//	c.Publish("orders", &OrderPlaced{ID: "1"})
+func (c *Cluster) Publish(topic string, msg any) error {
+	return c.publish(topic, msg, "")
+}
+
+func (c *Cluster) publish(topic string, msg any, origin string) error {
+	serializer := remote.DefaultSerializer{}
+	b, err := serializer.Serialize(msg)
+	if err != nil {
+		return err
+	}
+	delivery := c.config.topics[topic]
+	tm := &TopicMessage{
+		Topic:    topic,
+		Data:     b,
+		TypeName: serializer.TypeName(msg),
+		Ack:      delivery == DeliveryAcked,
+		Origin:   origin,
+	}
+	members := c.Members()
+	if delivery == DeliveryBestEffort {
+		for _, member := range members {
+			c.engine.Send(member.PID(), tm)
+		}
+		return nil
+	}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, member := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.publishAcked(member, tm); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (c *Cluster) publishAcked(member *Member, msg *TopicMessage) error {
+	var err error
+	for range publishAttempts {
+		var resp any
+		resp, err = c.engine.Request(member.PID(), msg, c.config.requestTimeout).Result()
+		if err == nil {
+			if _, ok := resp.(*TopicAck); ok {
+				return nil
+			}
+			err = fmt.Errorf("expected *TopicAck, got %T", resp)
+		}
+	}
+	return fmt.Errorf("member %s did not acknowledge the message on topic %s: %w", member.ID, msg.Topic, err)
+}
+
+// Subscribe subscribes the given actor to the topic. The actor receives the
+// messages that are published on the topic from any member.
+func (c *Cluster) Subscribe(topic string, pid *actor.PID) {
+	c.engine.Send(c.agentPID, subscribe{topic: topic, pid: pid})
+}
+
+// Unsubscribe unsubscribes the given actor from the topic.
+func (c *Cluster) Unsubscribe(topic string, pid *actor.PID) {
+	c.engine.Send(c.agentPID, unsubscribe{topic: topic, pid: pid})
+}
+
+func (a *Agent) handleSubscribe(msg subscribe) {
+	subs := a.topics[msg.topic]
+	if !slices.ContainsFunc(subs, msg.pid.Equals) {
+		a.topics[msg.topic] = append(subs, msg.pid)
+	}
+}
+
+func (a *Agent) handleUnsubscribe(msg unsubscribe) {
+	subs := slices.DeleteFunc(a.topics[msg.topic], msg.pid.Equals)
+	if len(subs) == 0 {
+		delete(a.topics, msg.topic)
+		return
+	}
+	a.topics[msg.topic] = subs
+}
+
+// handleTopicMessage delivers the message to the local subscribers of the
+// topic and publishes it on the eventstream.
+func (a *Agent) handleTopicMessage(c *actor.Context, msg *TopicMessage) {
+	if msg.Ack {
+		defer c.Respond(&TopicAck{})
+	}
+	payload, err := remote.DefaultSerializer{}.Deserialize(msg.Data, msg.TypeName)
+	if err != nil {
+		slog.Error("failed to deserialize topic message", "err", err, "topic", msg.Topic, "type", msg.TypeName)
+		return
+	}
+	for _, pid := range a.topics[msg.Topic] {
+		a.cluster.engine.Send(pid, payload)
+	}
+	a.cluster.engine.BroadcastEvent(TopicEvent{Topic: msg.Topic, Message: payload, Origin: msg.Origin})
+}
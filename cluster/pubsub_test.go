@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublish(t *testing.T) {
+	a, err := New(NewConfig().
+		WithID("A").
+		WithListenAddr(getRandomLocalhostAddr()).
+		WithProvider(NewSwimProvider(fastSwimConfig())).
+		WithTopicDelivery("acked", DeliveryAcked))
+	require.NoError(t, err)
+	a.Start()
+	defer a.Stop()
+	b := makeSwimCluster(t, "B", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))
+	b.Start()
+	defer b.Stop()
+	for _, c := range []*Cluster{a, b} {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 2
+		}, 3*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+
+	received := make(chan any, 8)
+	sub := b.Engine().SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*counterState); ok {
+			received <- msg
+		}
+	}, "subscriber")
+	b.Subscribe("best-effort", sub)
+	b.Subscribe("acked", sub)
+	events := make(chan TopicEvent, 8)
+	eventPID := a.Engine().SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(TopicEvent); ok {
+			events <- msg
+		}
+	}, "event")
+	a.Engine().Subscribe(eventPID)
+
+	for _, topic := range []string{"best-effort", "acked"} {
+		require.NoError(t, a.Publish(topic, &counterState{N: 1}))
+		select {
+		case msg := <-received:
+			assert.Equal(t, &counterState{N: 1}, msg)
+		case <-time.After(time.Second):
+			t.Fatalf("expected the message on %s", topic)
+		}
+		// Every member publishes the message on its eventstream.
+		select {
+		case evt := <-events:
+			assert.Equal(t, topic, evt.Topic)
+			assert.Equal(t, &counterState{N: 1}, evt.Message)
+		case <-time.After(time.Second):
+			t.Fatalf("expected a TopicEvent for %s", topic)
+		}
+	}
+
+	b.Unsubscribe("acked", sub)
+	require.NoError(t, a.Publish("acked", &counterState{N: 2}))
+	select {
+	case msg := <-received:
+		t.Fatalf("unexpected message after unsubscribing: %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.Error(t, a.Publish("acked", "not serializable"))
+}
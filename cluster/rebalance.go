@@ -0,0 +1,268 @@
+package cluster
+
+import (
+	"cmp"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/remote"
+)
+
+type partitionMoved struct {
+	id      string
+	success bool
+}
+
+// rebalance tracks the actors that move off this member.
+type rebalance struct {
+	total  int
+	moved  int
+	failed int
+	moving map[string]bool
+	// The moves that wait for their turn, see Config.WithRebalanceConcurrency.
+	queue    []move
+	inFlight int
+	started  time.Time
+}
+
+// move is an actor that moves to another member.
+type move struct {
+	pid    *actor.PID
+	member *Member
+}
+
+// handoffThrottle spreads the state that is handed off over time. A handoff
+// reserves its bytes, and is sent once the bytes reserved before it passed.
+type handoffThrottle struct {
+	mu   sync.Mutex
+	rate float64
+	next time.Time
+}
+
+func newHandoffThrottle(bytesPerSecond int) *handoffThrottle {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &handoffThrottle{rate: float64(bytesPerSecond)}
+}
+
+// wait waits until n bytes can be sent.
+func (t *handoffThrottle) wait(n int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	start := time.Now()
+	if t.next.After(start) {
+		start = t.next
+	}
+	t.next = start.Add(time.Duration(float64(n) / t.rate * float64(time.Second)))
+	t.mu.Unlock()
+	time.Sleep(time.Until(start))
+}
+
+// isPartitioned returns true whether the actors of the given kind are hosted
+// by the owner of their identity, or of their shard if the kind is sharded.
+func (a *Agent) isPartitioned(name string) bool {
+	kind, ok := a.localKinds[name]
+	return ok && (kind.config.partitioned || kind.config.sharding != nil)
+}
+
+// partitionOwner returns the member that owns the actor with the given kind
+// and identity on the hash ring.
+func (a *Agent) partitionOwner(kind, identity string) *Member {
+	if sharding := a.localKinds[kind].config.sharding; sharding != nil {
+		return a.ring.owner(shardKey(kind, sharding.shard(identity)), kind)
+	}
+	return a.ring.owner(kind+"/"+identity, kind)
+}
+
+// rebalancePartitions moves the actors of partitioned kinds that are hosted by
+// this member, but are owned by another member on the hash ring, to their
+// owner. The actors are deactivated here and activated again on the owner.
+func (a *Agent) rebalancePartitions() {
+	self := a.cluster.Member()
+	moves := 0
+	for id, pid := range a.activated {
+		if pid.Address != self.Host || a.rebalance.moving[id] {
+			continue
+		}
+		kind, identity, ok := strings.Cut(id, "/")
+		if !ok || !a.isPartitioned(kind) {
+			continue
+		}
+		owner := a.partitionOwner(kind, identity)
+		if owner == nil || owner.ID == self.ID {
+			continue
+		}
+		a.moveActor(pid, owner)
+		moves++
+	}
+	a.startRebalance(moves)
+}
+
+func (a *Agent) startRebalance(moves int) {
+	if moves == 0 {
+		return
+	}
+	if a.rebalance.started.IsZero() {
+		a.rebalance.started = time.Now()
+		a.cluster.engine.BroadcastEvent(RebalanceStartedEvent{Moving: moves})
+	}
+	a.rebalance.total += moves
+	slog.Debug("[CLUSTER] rebalancing actors", "moving", moves)
+	a.broadcastRebalance()
+	a.startMoves()
+}
+
+// moveActor moves the given local actor to the given member once it's its
+// turn.
+func (a *Agent) moveActor(pid *actor.PID, member *Member) {
+	a.rebalance.moving[pid.ID] = true
+	a.rebalance.queue = append(a.rebalance.queue, move{pid: pid, member: member})
+}
+
+// startMoves starts the queued moves as long as the concurrency of the
+// rebalance allows it.
+func (a *Agent) startMoves() {
+	limit := a.cluster.config.rebalanceConcurrency
+	dropped := 0
+	for len(a.rebalance.queue) > 0 && (limit <= 0 || a.rebalance.inFlight < limit) {
+		m := a.rebalance.queue[0]
+		a.rebalance.queue = a.rebalance.queue[1:]
+		if !a.retarget(&m) {
+			delete(a.rebalance.moving, m.pid.ID)
+			dropped++
+			continue
+		}
+		a.rebalance.inFlight++
+		a.runMove(m.pid, m.member)
+	}
+	if dropped > 0 {
+		a.rebalance.total -= dropped
+		a.broadcastRebalance()
+		a.finishRebalance()
+	}
+}
+
+// retarget updates a move that waited for its turn to the current members,
+// and returns false if the actor does not need to move anymore.
+func (a *Agent) retarget(m *move) bool {
+	if current, ok := a.activated[m.pid.ID]; !ok || !current.Equals(m.pid) {
+		return false
+	}
+	kind, identity, _ := strings.Cut(m.pid.ID, "/")
+	if !a.isPartitioned(kind) {
+		return true
+	}
+	owner := a.partitionOwner(kind, identity)
+	if owner == nil || owner.ID == a.cluster.ID() {
+		return false
+	}
+	m.member = owner
+	return true
+}
+
+// runMove deactivates the given local actor, after it handed off its state if
+// its kind has handoff, and activates it again on the given member. The
+// activation goes through the owner of its identity like for any grain.
+func (a *Agent) runMove(pid *actor.PID, member *Member) {
+	kind, identity, _ := strings.Cut(pid.ID, "/")
+	var (
+		engine   = a.cluster.engine
+		agentPID = a.cluster.PID()
+		timeout  = a.cluster.config.requestTimeout
+		members  = a.members.Slice()
+		owner    = cmp.Or(a.partitionOwner(kind, identity), member)
+		handoff  = a.localKinds[kind].config.handoff
+		throttle = a.handoff
+		msg      = &GrainActivation{Kind: kind, ID: identity, Member: member}
+	)
+	go func() {
+		if handoff {
+			if err := handoffState(engine, pid, member, msg, timeout, throttle); err != nil {
+				slog.Error("failed to hand off actor state", "err", err, "pid", pid)
+			}
+		}
+		<-engine.Poison(pid).Done()
+		// The owner gets the deactivation before the activation, as both
+		// are sent by this member.
+		for _, m := range members {
+			engine.Send(m.PID(), &Deactivation{PID: pid})
+		}
+		resp, err := engine.Request(owner.PID(), msg, 2*timeout).Result()
+		r, ok := resp.(*ActivationResponse)
+		success := err == nil && ok && r.Success
+		if !success {
+			slog.Error("failed to move actor", "err", err, "pid", pid, "member", member.ID)
+		}
+		engine.Send(agentPID, partitionMoved{id: pid.ID, success: success})
+	}()
+}
+
+// handoffState requests the state of the actor and puts it in the activation,
+// once the throttle lets it through.
+func handoffState(engine *actor.Engine, pid *actor.PID, to *Member, msg *GrainActivation, timeout time.Duration, throttle *handoffThrottle) error {
+	state, err := engine.Request(pid, Handoff{To: to}, timeout).Result()
+	if err != nil || state == nil {
+		return err
+	}
+	serializer := remote.DefaultSerializer{}
+	b, err := serializer.Serialize(state)
+	if err != nil {
+		return err
+	}
+	throttle.wait(len(b))
+	msg.State, msg.StateType = b, serializer.TypeName(state)
+	return nil
+}
+
+func (a *Agent) handlePartitionMoved(msg partitionMoved) {
+	delete(a.rebalance.moving, msg.id)
+	a.rebalance.inFlight--
+	if msg.success {
+		a.rebalance.moved++
+	} else {
+		a.rebalance.failed++
+	}
+	a.broadcastRebalance()
+	a.startMoves()
+	a.finishRebalance()
+}
+
+// finishRebalance ends the rebalance once all the actors moved.
+func (a *Agent) finishRebalance() {
+	if len(a.rebalance.moving) > 0 || a.rebalance.started.IsZero() {
+		return
+	}
+	a.cluster.engine.BroadcastEvent(RebalanceFinishedEvent{
+		Moved:  a.rebalance.moved,
+		Failed: a.rebalance.failed,
+	})
+	a.rebalance.total, a.rebalance.moved, a.rebalance.failed = 0, 0, 0
+	a.rebalance.started = time.Time{}
+	a.finishLeave()
+}
+
+func (a *Agent) broadcastRebalance() {
+	a.cluster.engine.BroadcastEvent(RebalanceEvent{
+		Total:  a.rebalance.total,
+		Moved:  a.rebalance.moved,
+		Failed: a.rebalance.failed,
+		ETA:    a.rebalance.eta(time.Now()),
+	})
+}
+
+// eta estimates how long the remaining moves take at the pace of the moves so
+// far.
+func (r *rebalance) eta(now time.Time) time.Duration {
+	done := r.moved + r.failed
+	if done == 0 {
+		return 0
+	}
+	perMove := now.Sub(r.started) / time.Duration(done)
+	return perMove * time.Duration(r.total-done)
+}
@@ -0,0 +1,162 @@
+package cluster
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebalance(t *testing.T) {
+	config := NewKindConfig().WithPartitioned(true)
+	a := makeSwimCluster(t, "A", fastSwimConfig())
+	a.RegisterKind("pinger", newPinger, config)
+	a.Start()
+	defer a.Stop()
+
+	events := make(chan RebalanceEvent, 64)
+	started := make(chan RebalanceStartedEvent, 1)
+	finished := make(chan RebalanceFinishedEvent, 1)
+	eventPID := a.Engine().SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case RebalanceEvent:
+			events <- msg
+		case RebalanceStartedEvent:
+			started <- msg
+		case RebalanceFinishedEvent:
+			finished <- msg
+		}
+	}, "event")
+	a.Engine().Subscribe(eventPID)
+
+	// A owns all the actors while it is alone.
+	const n = 20
+	for i := range n {
+		pid := a.GrainRef("pinger", strconv.Itoa(i)).PID()
+		require.NotNil(t, pid)
+		assert.Equal(t, a.Address(), pid.Address)
+	}
+
+	b := makeSwimCluster(t, "B", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))
+	b.RegisterKind("pinger", newPinger, config)
+	b.Start()
+	defer b.Stop()
+
+	var last RebalanceEvent
+	for done := false; !done; {
+		select {
+		case last = <-events:
+			done = last.Moved+last.Failed == last.Total
+		case <-time.After(3 * time.Second):
+			t.Fatalf("rebalance did not finish: %+v", last)
+		}
+	}
+	assert.Zero(t, last.Failed)
+	assert.Greater(t, last.Moved, 0)
+	assert.Less(t, last.Moved, n)
+	assert.Equal(t, RebalanceStartedEvent{Moving: last.Total}, <-started)
+	assert.Equal(t, RebalanceFinishedEvent{Moved: last.Moved}, <-finished)
+
+	// Every actor is hosted by its owner, and both members agree on it.
+	details := ActivationDetails{Members: a.Members(), Kind: "pinger"}
+	for i := range n {
+		details.ID = strconv.Itoa(i)
+		owner := SelectHashMember(details)
+		id := "pinger/" + details.ID
+		require.Eventually(t, func() bool {
+			pa, pb := a.GetActiveByID(id), b.GetActiveByID(id)
+			return pa != nil && pb != nil && pa.Equals(pb) && pa.Address == owner.Host
+		}, time.Second, 10*time.Millisecond, id)
+	}
+}
+
+// slowHandoff hands off its state slowly, and tracks how many actors hand off
+// at the same time.
+type slowHandoff struct {
+	current, peak *atomic.Int32
+}
+
+func (s slowHandoff) Receive(c *actor.Context) {
+	if _, ok := c.Message().(Handoff); ok {
+		n := s.current.Add(1)
+		for {
+			peak := s.peak.Load()
+			if n <= peak || s.peak.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		s.current.Add(-1)
+		c.Respond(&counterState{})
+	}
+}
+
+func TestRebalanceConcurrency(t *testing.T) {
+	var current, peak atomic.Int32
+	newSlowHandoff := func() actor.Receiver { return slowHandoff{current: &current, peak: &peak} }
+	kind := NewKindConfig().WithPartitioned(true).WithHandoff(true)
+	a, err := New(NewConfig().
+		WithID("A").
+		WithListenAddr(getRandomLocalhostAddr()).
+		WithProvider(NewSwimProvider(fastSwimConfig())).
+		WithRebalanceConcurrency(2))
+	require.NoError(t, err)
+	a.RegisterKind("slow", newSlowHandoff, kind)
+	a.Start()
+	defer a.Stop()
+
+	events := make(chan RebalanceEvent, 64)
+	eventPID := a.Engine().SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(RebalanceEvent); ok {
+			events <- msg
+		}
+	}, "event")
+	a.Engine().Subscribe(eventPID)
+
+	for i := range 20 {
+		require.NotNil(t, a.GrainRef("slow", strconv.Itoa(i)).PID())
+	}
+	b := makeSwimCluster(t, "B", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))
+	b.RegisterKind("slow", newSlowHandoff, kind)
+	b.Start()
+	defer b.Stop()
+
+	var last RebalanceEvent
+	estimated := false
+	for done := false; !done; {
+		select {
+		case last = <-events:
+			done = last.Moved+last.Failed == last.Total
+			if !done && last.Moved > 0 {
+				estimated = estimated || last.ETA > 0
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("rebalance did not finish: %+v", last)
+		}
+	}
+	assert.Zero(t, last.Failed)
+	assert.Greater(t, last.Moved, 2)
+	assert.Equal(t, int32(2), peak.Load())
+	assert.True(t, estimated)
+}
+
+func TestHandoffThrottle(t *testing.T) {
+	throttle := newHandoffThrottle(1000)
+	start := time.Now()
+	// The first handoff goes right away, the others wait for the bytes
+	// before them.
+	for range 3 {
+		throttle.wait(100)
+	}
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 200*time.Millisecond)
+	assert.Less(t, elapsed, 300*time.Millisecond)
+
+	assert.Nil(t, newHandoffThrottle(0))
+	var disabled *handoffThrottle
+	disabled.wait(100)
+}
@@ -0,0 +1,52 @@
+package cluster
+
+type getRoleLeader struct{ role string }
+
+// RoleLeader returns the leader of the members with the given role, which is
+// the member with the role with the highest ID, or nil if no member has the
+// role. Every member agrees on the role leaders once they agree on the
+// members, so a singleton that needs a role runs on the member for which
+// IsRoleLeader returns true, and stops when it receives a
+// RoleLeaderChangedEvent for another leader.
+func (c *Cluster) RoleLeader(role string) *Member {
+	resp, err := c.engine.Request(c.agentPID, getRoleLeader{role: role}, c.config.requestTimeout).Result()
+	if err != nil {
+		return nil
+	}
+	if leader, ok := resp.(*Member); ok {
+		return leader
+	}
+	return nil
+}
+
+// IsRoleLeader returns true whether this member is the leader of the given
+// role.
+func (c *Cluster) IsRoleLeader(role string) bool {
+	leader := c.RoleLeader(role)
+	return leader != nil && leader.ID == c.config.id
+}
+
+// updateRoleLeaders elects the leader of every role after the members
+// changed, and publishes the changes on the eventstream.
+func (a *Agent) updateRoleLeaders() {
+	leaders := make(map[string]*Member)
+	a.members.ForEach(func(member *Member) bool {
+		for _, role := range member.Roles {
+			if leader, ok := leaders[role]; !ok || member.ID > leader.ID {
+				leaders[role] = member
+			}
+		}
+		return true
+	})
+	for role, leader := range leaders {
+		if current, ok := a.roles[role]; !ok || !current.Equals(leader) {
+			a.cluster.engine.BroadcastEvent(RoleLeaderChangedEvent{Role: role, Leader: leader})
+		}
+	}
+	for role := range a.roles {
+		if _, ok := leaders[role]; !ok {
+			a.cluster.engine.BroadcastEvent(RoleLeaderChangedEvent{Role: role})
+		}
+	}
+	a.roles = leaders
+}
@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeRolesCluster(t *testing.T, id string, swim SwimConfig, roles ...string) *Cluster {
+	c, err := New(NewConfig().
+		WithID(id).
+		WithRoles(roles...).
+		WithListenAddr(getRandomLocalhostAddr()).
+		WithProvider(NewSwimProvider(swim)))
+	require.NoError(t, err)
+	c.RegisterKind("player", NewPlayer, NewKindConfig())
+	return c
+}
+
+func TestRoles(t *testing.T) {
+	a := makeRolesCluster(t, "A", fastSwimConfig(), "frontend")
+	a.Start()
+	defer a.Stop()
+	b := makeRolesCluster(t, "B", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}), "worker", "gpu")
+	b.Start()
+	defer b.Stop()
+	c := makeRolesCluster(t, "C", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}), "worker")
+	c.Start()
+	defer c.Stop()
+	require.Eventually(t, func() bool {
+		return len(a.Members()) == 3 && len(b.Members()) == 3 && len(c.Members()) == 3
+	}, 3*time.Second, 10*time.Millisecond)
+
+	pid := a.Activate("player", NewActivationConfig().WithID("1").WithRole("gpu").WithSelectMemberFunc(SelectLocalMember))
+	require.NotNil(t, pid)
+	assert.Equal(t, b.Address(), pid.Address)
+	assert.Nil(t, a.Activate("player", NewActivationConfig().WithID("2").WithRole("tpu")))
+
+	pid = a.GrainRef("player", "3").WithRole("gpu").PID()
+	require.NotNil(t, pid)
+	assert.Equal(t, b.Address(), pid.Address)
+	pid = c.GrainRef("player", "4").WithRole("frontend").PID()
+	require.NotNil(t, pid)
+	assert.Equal(t, a.Address(), pid.Address)
+
+	// All the members agree on the leader of each role.
+	for _, member := range []*Cluster{a, b, c} {
+		assert.Equal(t, "A", member.RoleLeader("frontend").ID)
+		assert.Equal(t, "C", member.RoleLeader("worker").ID)
+		assert.Equal(t, "B", member.RoleLeader("gpu").ID)
+		assert.Nil(t, member.RoleLeader("tpu"))
+	}
+	assert.True(t, c.IsRoleLeader("worker"))
+	assert.False(t, b.IsRoleLeader("worker"))
+}
@@ -0,0 +1,180 @@
+package cluster
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/zeebo/xxh3"
+)
+
+const (
+	defaultShards         = 100
+	defaultPassivateAfter = 2 * time.Minute
+)
+
+var errNoEntityID = errors.New("no entity ID in the message")
+
+// ShardingConfig holds the configuration of a sharded kind. The members that
+// host the kind and the members that send messages to its entities need the
+// same number of shards.
+type ShardingConfig struct {
+	shards         int
+	entityID       func(msg any) string
+	passivateAfter time.Duration
+}
+
+// NewShardingConfig returns a ShardingConfig that is initialized with default
+// values.
+func NewShardingConfig() ShardingConfig {
+	return ShardingConfig{
+		shards:         defaultShards,
+		passivateAfter: defaultPassivateAfter,
+	}
+}
+
+// WithShards set's the number of shards the entities are grouped into. It
+// should be a lot larger than the number of members, so the shards spread
+// evenly.
+//
+// Defaults to 100.
+func (config ShardingConfig) WithShards(n int) ShardingConfig {
+	config.shards = n
+	return config
+}
+
+// WithEntityID set's the function that extracts the ID of the entity a
+// message is for, which returns an empty string if the message has none.
+func (config ShardingConfig) WithEntityID(fn func(msg any) string) ShardingConfig {
+	config.entityID = fn
+	return config
+}
+
+// WithPassivateAfter set's how long an entity can be idle before it's
+// deactivated. A passivated entity is activated again by its next message.
+// Zero disables passivation.
+//
+// Defaults to 2 minutes.
+func (config ShardingConfig) WithPassivateAfter(d time.Duration) ShardingConfig {
+	config.passivateAfter = d
+	return config
+}
+
+// shard returns the shard of the entity with the given ID.
+func (config ShardingConfig) shard(id string) int {
+	return int(xxh3.HashString(id) % uint64(max(config.shards, 1)))
+}
+
+// shardKey returns the key of the given shard on the hash ring.
+func shardKey(kind string, shard int) string {
+	return kind + "/shard-" + strconv.Itoa(shard)
+}
+
+// ShardRegion routes the messages for the entities of a sharded kind. The
+// entities are grouped into numbered shards, and each shard is hosted by the
+// member that owns it on the hash ring. An entity is activated on the member
+// of its shard on its first message, and it moves along with its shard when
+// the members change. See KindConfig.WithSharding to host the kind.
+type ShardRegion struct {
+	cluster *Cluster
+	kind    string
+	config  ShardingConfig
+}
+
+// ShardRegion returns the region of the given sharded kind.
+//
+//	config := cluster.NewShardingConfig().WithEntityID(func(msg any) string {
+//		if msg, ok := msg.(*Deposit); ok {
+//			return msg.Account
+//		}
+//		return ""
+//	})
+//	c.RegisterKind("account", NewAccount, cluster.NewKindConfig().WithSharding(config))
+//	c.ShardRegion("account", config).Send(&Deposit{Account: "bob", Amount: 10})
+func (c *Cluster) ShardRegion(kind string, config ShardingConfig) ShardRegion {
+	return ShardRegion{cluster: c, kind: kind, config: config}
+}
+
+// Shard returns the shard of the entity with the given ID.
+func (r ShardRegion) Shard(id string) int {
+	return r.config.shard(id)
+}
+
+// Entity returns a reference to the entity with the given ID.
+func (r ShardRegion) Entity(id string) GrainRef {
+	key := shardKey(r.kind, r.config.shard(id))
+	owner := func(details ActivationDetails) *Member {
+		return newHashRing(details.Members).owner(key, "")
+	}
+	g := r.cluster.GrainRef(r.kind, id).WithSelectMemberFunc(owner)
+	g.owner = owner
+	return g
+}
+
+// Send sends the message to the entity it is for.
+func (r ShardRegion) Send(msg any) error {
+	id := r.entityID(msg)
+	if id == "" {
+		return errNoEntityID
+	}
+	return r.Entity(id).Send(msg)
+}
+
+// Request sends the message to the entity it is for and waits for its
+// response.
+func (r ShardRegion) Request(msg any, timeout time.Duration) (any, error) {
+	id := r.entityID(msg)
+	if id == "" {
+		return nil, errNoEntityID
+	}
+	return r.Entity(id).Request(msg, timeout)
+}
+
+func (r ShardRegion) entityID(msg any) string {
+	if r.config.entityID == nil {
+		return ""
+	}
+	return r.config.entityID(msg)
+}
+
+type passivationTick struct{}
+
+// passivatingEntity deactivates the entity it wraps once it was idle for too
+// long.
+type passivatingEntity struct {
+	cluster  *Cluster
+	receiver actor.Receiver
+	after    time.Duration
+	last     time.Time
+	repeater actor.SendRepeater
+	// passivating is true once the entity asked to be deactivated.
+	passivating bool
+}
+
+func newPassivatingEntity(c *Cluster, producer actor.Producer, after time.Duration) actor.Producer {
+	return func() actor.Receiver {
+		return &passivatingEntity{
+			cluster:  c,
+			receiver: producer(),
+			after:    after,
+		}
+	}
+}
+
+func (e *passivatingEntity) Receive(c *actor.Context) {
+	switch c.Message().(type) {
+	case passivationTick:
+		if !e.passivating && time.Since(e.last) >= e.after {
+			e.passivating = true
+			e.cluster.Deactivate(c.PID())
+		}
+		return
+	case actor.Started:
+		e.repeater = c.Engine().SendRepeat(c.PID(), passivationTick{}, e.after/2)
+	case actor.Stopped:
+		e.repeater.Stop()
+	}
+	e.last = time.Now()
+	e.receiver.Receive(c)
+}
@@ -0,0 +1,102 @@
+package cluster
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	entityAdd struct {
+		Entity string
+		N      int
+	}
+	entityGet struct{ Entity string }
+)
+
+func init() {
+	remote.RegisterCodec[*entityAdd]("cluster.entityAdd", remote.JSONCodec[*entityAdd]{})
+	remote.RegisterCodec[*entityGet]("cluster.entityGet", remote.JSONCodec[*entityGet]{})
+}
+
+type entity struct{ n int }
+
+func newEntity() actor.Receiver {
+	return &entity{}
+}
+
+func (e *entity) Receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case *entityAdd:
+		e.n += msg.N
+	case *entityGet:
+		c.Respond(&counterState{N: e.n})
+	}
+}
+
+func entityID(msg any) string {
+	switch msg := msg.(type) {
+	case *entityAdd:
+		return msg.Entity
+	case *entityGet:
+		return msg.Entity
+	}
+	return ""
+}
+
+func TestSharding(t *testing.T) {
+	config := NewShardingConfig().
+		WithShards(16).
+		WithEntityID(entityID).
+		WithPassivateAfter(200 * time.Millisecond)
+	a := makeSwimCluster(t, "A", fastSwimConfig())
+	a.RegisterKind("entity", newEntity, NewKindConfig().WithSharding(config))
+	a.Start()
+	defer a.Stop()
+	b := makeSwimCluster(t, "B", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))
+	b.RegisterKind("entity", newEntity, NewKindConfig().WithSharding(config))
+	b.Start()
+	defer b.Stop()
+	for _, c := range []*Cluster{a, b} {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 2
+		}, 3*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+
+	region := a.ShardRegion("entity", config)
+	hosts := make(map[string]bool)
+	for i := range 10 {
+		id := strconv.Itoa(i)
+		require.NoError(t, region.Send(&entityAdd{Entity: id, N: i}))
+		resp, err := region.Request(&entityGet{Entity: id}, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, &counterState{N: i}, resp, id)
+		// B routes to the same entity.
+		resp, err = b.ShardRegion("entity", config).Request(&entityGet{Entity: id}, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, &counterState{N: i}, resp, id)
+
+		// The entity is hosted by the owner of its shard.
+		details := ActivationDetails{Members: a.Members(), Kind: "entity"}
+		owner := newHashRing(details.Members).owner(shardKey("entity", region.Shard(id)), "")
+		pid := a.GetActiveByID("entity/" + id)
+		require.NotNil(t, pid)
+		assert.Equal(t, owner.Host, pid.Address)
+		hosts[pid.Address] = true
+	}
+	assert.Len(t, hosts, 2)
+	assert.ErrorIs(t, region.Send(&counterAdd{N: 1}), errNoEntityID)
+
+	// Idle entities passivate, and start afresh with their next message.
+	require.Eventually(t, func() bool {
+		return a.GetActiveByID("entity/1") == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	resp, err := region.Request(&entityGet{Entity: "1"}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, &counterState{N: 0}, resp)
+}
@@ -0,0 +1,145 @@
+package cluster
+
+import (
+	"log/slog"
+	"time"
+)
+
+// SplitBrainResolver decides whether the side of a network split this member
+// is on survives. It's invoked on every side once the membership was stable
+// for a while after members were lost, and the sides need to come to
+// opposite decisions without talking to each other.
+type SplitBrainResolver func(SplitBrainDetails) bool
+
+// SplitBrainDetails holds the membership a SplitBrainResolver decides on.
+type SplitBrainDetails struct {
+	// The member that decides.
+	Self *Member
+	// The members that are still in the cluster, including this member.
+	Members []*Member
+	// The members that were lost since the last decision, either because
+	// they failed or because they are on another side of a split.
+	Lost []*Member
+}
+
+// KeepMajority keeps the side with the majority of the members. When the
+// sides are of equal size, the side with the member with the lowest ID
+// survives.
+func KeepMajority(details SplitBrainDetails) bool {
+	total := len(details.Members) + len(details.Lost)
+	if 2*len(details.Members) != total {
+		return 2*len(details.Members) > total
+	}
+	lowest := lowestMember(details.Members)
+	return lowest != nil && lowest.ID < lowestMember(details.Lost).ID
+}
+
+// KeepOldest keeps the side with the member that started first.
+func KeepOldest(details SplitBrainDetails) bool {
+	oldest := func(members []*Member) *Member {
+		var found *Member
+		for _, m := range members {
+			if found == nil || m.StartedAt < found.StartedAt || (m.StartedAt == found.StartedAt && m.ID < found.ID) {
+				found = m
+			}
+		}
+		return found
+	}
+	all := append(append([]*Member{}, details.Members...), details.Lost...)
+	return oldest(all) == oldest(details.Members)
+}
+
+// StaticQuorum returns a resolver that keeps the side with at least the given
+// number of members. The quorum should be a majority of the size of the
+// cluster, otherwise several sides survive.
+func StaticQuorum(size int) SplitBrainResolver {
+	return func(details SplitBrainDetails) bool {
+		return len(details.Members) >= size
+	}
+}
+
+// DownAll stops all the sides of a split, as well as the members that remain
+// when other members fail.
+func DownAll(SplitBrainDetails) bool {
+	return false
+}
+
+func lowestMember(members []*Member) *Member {
+	var lowest *Member
+	for _, m := range members {
+		if lowest == nil || m.ID < lowest.ID {
+			lowest = m
+		}
+	}
+	return lowest
+}
+
+type splitBrainTimeout struct{ seq uint64 }
+
+// splitBrain holds the members that were lost since the last decision of the
+// split brain resolver.
+type splitBrain struct {
+	lost map[string]*Member
+	// seq identifies the last change of the membership, so the decision
+	// waits until it was stable.
+	seq uint64
+}
+
+func (a *Agent) trackSplitBrain(joined, left []*Member) {
+	if a.cluster.config.splitBrainResolver == nil {
+		return
+	}
+	for _, member := range joined {
+		delete(a.splitBrain.lost, member.ID)
+	}
+	for _, member := range left {
+		// Members that left gracefully are not lost.
+		if !a.leaving[member.ID] {
+			a.splitBrain.lost[member.ID] = member
+		}
+	}
+	if len(a.splitBrain.lost) == 0 {
+		return
+	}
+	a.splitBrain.seq++
+	seq, pid, engine := a.splitBrain.seq, a.cluster.PID(), a.cluster.engine
+	time.AfterFunc(a.cluster.config.splitBrainStableAfter, func() {
+		engine.Send(pid, splitBrainTimeout{seq: seq})
+	})
+}
+
+// handleSplitBrainTimeout invokes the resolver once the membership was stable.
+// If this side does not survive, its actors are terminated and the cluster is
+// stopped.
+func (a *Agent) handleSplitBrainTimeout(msg splitBrainTimeout) {
+	if msg.seq != a.splitBrain.seq || len(a.splitBrain.lost) == 0 {
+		return
+	}
+	details := SplitBrainDetails{
+		Self:    a.cluster.Member(),
+		Members: a.members.Slice(),
+	}
+	for _, member := range a.splitBrain.lost {
+		details.Lost = append(details.Lost, member)
+	}
+	clear(a.splitBrain.lost)
+	survive := a.cluster.config.splitBrainResolver(details)
+	a.cluster.engine.BroadcastEvent(SplitBrainResolvedEvent{
+		Survived: survive,
+		Members:  details.Members,
+		Lost:     details.Lost,
+	})
+	if survive {
+		slog.Info("[CLUSTER] split brain resolved, this side survives", "members", len(details.Members), "lost", len(details.Lost))
+		return
+	}
+	slog.Warn("[CLUSTER] split brain resolved, this side is down", "members", len(details.Members), "lost", len(details.Lost))
+	for _, pid := range a.activated {
+		if pid.Address == a.cluster.engine.Address() {
+			a.removeActivated(pid)
+			a.cluster.engine.Poison(pid)
+		}
+	}
+	// The agent is stopped by Stop, so it can't wait for it.
+	go a.cluster.Stop()
+}
@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitBrainResolvers(t *testing.T) {
+	a := &Member{ID: "A", StartedAt: 3}
+	b := &Member{ID: "B", StartedAt: 1}
+	c := &Member{ID: "C", StartedAt: 2}
+	d := &Member{ID: "D", StartedAt: 4}
+	split := func(members, lost []*Member) SplitBrainDetails {
+		return SplitBrainDetails{Self: members[0], Members: members, Lost: lost}
+	}
+
+	assert.True(t, KeepMajority(split([]*Member{a, b, c}, []*Member{d})))
+	assert.False(t, KeepMajority(split([]*Member{d}, []*Member{a, b, c})))
+	// The side with the lowest ID wins a tie.
+	assert.True(t, KeepMajority(split([]*Member{a, d}, []*Member{b, c})))
+	assert.False(t, KeepMajority(split([]*Member{b, c}, []*Member{a, d})))
+
+	assert.True(t, KeepOldest(split([]*Member{b}, []*Member{a, c, d})))
+	assert.False(t, KeepOldest(split([]*Member{a, c, d}, []*Member{b})))
+
+	assert.True(t, StaticQuorum(2)(split([]*Member{c, d}, []*Member{a, b})))
+	assert.False(t, StaticQuorum(3)(split([]*Member{c, d}, []*Member{a, b})))
+
+	assert.False(t, DownAll(split([]*Member{a, b, c}, []*Member{d})))
+}
+
+func TestSplitBrainKeepMajority(t *testing.T) {
+	var clusters []*Cluster
+	events := make(map[string]chan SplitBrainResolvedEvent)
+	seed := MemberAddr{}
+	for _, id := range []string{"A", "B", "C"} {
+		swim := fastSwimConfig()
+		if id != "A" {
+			swim = swim.WithSeed(seed)
+		}
+		c, err := New(NewConfig().
+			WithID(id).
+			WithListenAddr(getRandomLocalhostAddr()).
+			WithProvider(NewSwimProvider(swim)).
+			WithSplitBrainResolver(KeepMajority).
+			WithSplitBrainStableAfter(300 * time.Millisecond))
+		require.NoError(t, err)
+		c.RegisterKind("player", NewPlayer, NewKindConfig())
+		c.Start()
+		defer c.Stop()
+		if id == "A" {
+			seed = MemberAddr{ListenAddr: c.Address(), ID: "A"}
+		}
+		ch := make(chan SplitBrainResolvedEvent, 1)
+		events[id] = ch
+		eventPID := c.Engine().SpawnFunc(func(ctx *actor.Context) {
+			if msg, ok := ctx.Message().(SplitBrainResolvedEvent); ok {
+				ch <- msg
+			}
+		}, "event")
+		c.Engine().Subscribe(eventPID)
+		clusters = append(clusters, c)
+	}
+	for _, c := range clusters {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 3
+		}, 3*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+	c := clusters[2]
+	pid := c.Activate("player", NewActivationConfig().WithID("1").WithSelectMemberFunc(SelectLocalMember))
+	require.NotNil(t, pid)
+	require.Equal(t, c.Address(), pid.Address)
+
+	// C no longer receives anything, so it's on its own side of the split.
+	c.remote.Stop().Wait()
+	for id, survived := range map[string]bool{"A": true, "B": true, "C": false} {
+		select {
+		case evt := <-events[id]:
+			assert.Equal(t, survived, evt.Survived, id)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected a SplitBrainResolvedEvent on %s", id)
+		}
+	}
+	// The losing side terminated its actors.
+	assert.Eventually(t, func() bool {
+		return c.Engine().Registry.GetPID("player", "1") == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Len(t, clusters[0].Members(), 2)
+}
@@ -0,0 +1,260 @@
+package cluster
+
+import (
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+const (
+	defaultJoinInitialBackoff = 500 * time.Millisecond
+	defaultJoinMaxBackoff     = 10 * time.Second
+)
+
+type staticJoin struct {
+	attempt int
+}
+
+type StaticProviderConfig struct {
+	seeds          []string
+	quorum         int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// NewStaticProviderConfig returns a StaticProviderConfig that is initialized
+// with default values.
+func NewStaticProviderConfig() StaticProviderConfig {
+	return StaticProviderConfig{
+		seeds:          make([]string, 0),
+		initialBackoff: defaultJoinInitialBackoff,
+		maxBackoff:     defaultJoinMaxBackoff,
+	}
+}
+
+// WithSeed adds the listen address of a seed member. All the members of the
+// cluster are expected to be configured with the same seeds, including the
+// seeds themselves.
+func (c StaticProviderConfig) WithSeed(addr string) StaticProviderConfig {
+	c.seeds = append(c.seeds, addr)
+	return c
+}
+
+// WithQuorum set's the number of seeds that need to respond before a member
+// joins the cluster. A seed counts itself.
+//
+// Defaults to the majority of the seeds.
+func (c StaticProviderConfig) WithQuorum(n int) StaticProviderConfig {
+	c.quorum = n
+	return c
+}
+
+// WithJoinBackoff set's the backoff between the attempts to join the cluster,
+// which starts at initial and doubles after each attempt up to max.
+//
+// Defaults to 500 milliseconds up to 10 seconds.
+func (c StaticProviderConfig) WithJoinBackoff(initial, max time.Duration) StaticProviderConfig {
+	c.initialBackoff = initial
+	c.maxBackoff = max
+	return c
+}
+
+// StaticProvider is a provider for small clusters with a fixed set of seed
+// members, which doesn't need an external discovery system. A member sends a
+// handshake to the seeds until a quorum of them responds, and only then
+// joins the cluster. The seeds tell the member about the others, which it
+// sends a handshake as well. A member that loses all the others starts over,
+// so it rejoins once the connectivity is restored.
+type StaticProvider struct {
+	config       StaticProviderConfig
+	cluster      *Cluster
+	pid          *actor.PID
+	discoveryPID *actor.PID
+	eventSubPID  *actor.PID
+
+	// members holds the other members, keyed by their address.
+	members map[string]*Member
+	// responded holds the seeds that responded while joining.
+	responded map[string]bool
+	joined    bool
+	timer     *time.Timer
+}
+
+// NewStaticProvider returns a provider that bootstraps the cluster from the
+// seeds of the config.
+func NewStaticProvider(config StaticProviderConfig) Producer {
+	if config.quorum <= 0 && len(config.seeds) > 0 {
+		config.quorum = len(config.seeds)/2 + 1
+	}
+	return func(c *Cluster) actor.Producer {
+		return func() actor.Receiver {
+			return &StaticProvider{
+				config:    config,
+				cluster:   c,
+				members:   make(map[string]*Member),
+				responded: make(map[string]bool),
+			}
+		}
+	}
+}
+
+func (p *StaticProvider) Receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.Started:
+		p.pid = c.PID()
+		p.discoveryPID = spawnDiscovery(p.cluster, c.PID())
+		p.eventSubPID = c.SpawnChildFunc(p.handleEventStream, "event")
+		p.cluster.engine.Subscribe(p.eventSubPID)
+		p.sendMembersToAgent()
+		p.join(0)
+	case actor.Stopped:
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+		p.cluster.engine.Unsubscribe(p.eventSubPID)
+		p.cluster.engine.Poison(p.discoveryPID)
+	case staticJoin:
+		p.join(msg.attempt)
+	case *Handshake:
+		if msg.Member.ID == p.cluster.ID() {
+			return
+		}
+		p.respond(c.Sender())
+		// No need to send a handshake back to the member.
+		p.members[msg.Member.Host] = msg.Member
+		p.sendMembersToAgent()
+		members := []*Member{p.cluster.Member()}
+		for _, member := range p.members {
+			members = append(members, member)
+		}
+		p.send(c.Sender(), &Members{Members: members})
+	case *Members:
+		p.respond(c.Sender())
+		p.addMembers(msg.Members...)
+	case memberLeave:
+		p.removeMember(msg.ListenAddr)
+	case actor.Initialized:
+		_ = msg
+	default:
+		slog.Warn("received unhandled message", "msg", msg, "t", reflect.TypeOf(msg))
+	}
+}
+
+func (p *StaticProvider) handleEventStream(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.RemoteUnreachableEvent:
+		c.Send(p.pid, memberLeave{ListenAddr: msg.ListenAddr})
+	}
+}
+
+// join sends a handshake to the seeds that did not respond yet, and tries
+// again after the backoff until the quorum is reached.
+func (p *StaticProvider) join(attempt int) {
+	if p.checkQuorum() {
+		return
+	}
+	for _, seed := range p.config.seeds {
+		if !p.responded[seed] && seed != p.cluster.Address() {
+			p.send(discoveryPID(seed), &Handshake{Member: p.cluster.Member()})
+		}
+	}
+	backoff := p.config.initialBackoff << min(attempt, 30)
+	if backoff <= 0 || backoff > p.config.maxBackoff {
+		backoff = p.config.maxBackoff
+	}
+	if attempt > 0 {
+		slog.Debug("[CLUSTER] waiting for the quorum of seeds", "attempt", attempt, "responded", len(p.responded), "quorum", p.config.quorum, "backoff", backoff)
+	}
+	pid, engine := p.pid, p.cluster.engine
+	p.timer = time.AfterFunc(backoff, func() {
+		engine.Send(pid, staticJoin{attempt: attempt + 1})
+	})
+}
+
+// respond records that the seed with the address of the given PID responded.
+func (p *StaticProvider) respond(pid *actor.PID) {
+	if pid == nil || p.joined {
+		return
+	}
+	for _, seed := range p.config.seeds {
+		if seed == pid.Address {
+			p.responded[seed] = true
+		}
+	}
+	p.checkQuorum()
+}
+
+// checkQuorum joins the cluster once the quorum of seeds responded, and
+// reports if the cluster is joined.
+func (p *StaticProvider) checkQuorum() bool {
+	if p.joined {
+		return true
+	}
+	responded := len(p.responded)
+	for _, seed := range p.config.seeds {
+		if seed == p.cluster.Address() && !p.responded[seed] {
+			responded++
+		}
+	}
+	if responded < p.config.quorum {
+		return false
+	}
+	p.joined = true
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.sendMembersToAgent()
+	return true
+}
+
+// addMembers adds the given members, and sends a handshake to the members we
+// didn't know about yet, so they learn about us as well.
+func (p *StaticProvider) addMembers(members ...*Member) {
+	for _, member := range members {
+		if member.ID == p.cluster.ID() {
+			continue
+		}
+		if _, ok := p.members[member.Host]; !ok {
+			p.send(memberToProviderPID(member), &Handshake{Member: p.cluster.Member()})
+		}
+		p.members[member.Host] = member
+	}
+	p.sendMembersToAgent()
+}
+
+// removeMember removes the member with the given address. If it was the last
+// one the member is isolated, so it starts over to join the cluster.
+func (p *StaticProvider) removeMember(addr string) {
+	if _, ok := p.members[addr]; !ok {
+		return
+	}
+	delete(p.members, addr)
+	p.sendMembersToAgent()
+	if len(p.members) == 0 && p.joined {
+		slog.Warn("[CLUSTER] isolated from all members, rejoining", "id", p.cluster.ID())
+		p.joined = false
+		clear(p.responded)
+		p.join(0)
+	}
+}
+
+func (p *StaticProvider) send(pid *actor.PID, msg any) {
+	if pid == nil {
+		return
+	}
+	p.cluster.engine.SendWithSender(pid, msg, p.pid)
+}
+
+// sendMembersToAgent sends the members to the local cluster agent. Until the
+// cluster is joined that is only this member.
+func (p *StaticProvider) sendMembersToAgent() {
+	members := []*Member{p.cluster.Member()}
+	if p.joined {
+		for _, member := range p.members {
+			members = append(members, member)
+		}
+	}
+	p.cluster.engine.Send(p.cluster.PID(), &Members{Members: members})
+}
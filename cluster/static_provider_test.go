@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeStaticCluster(t *testing.T, id, addr string, config StaticProviderConfig) (*Cluster, *remote.Remote) {
+	r := remote.New(addr, remote.NewConfig())
+	e, err := actor.NewEngine(actor.NewEngineConfig().WithRemote(r))
+	require.NoError(t, err)
+	c, err := New(NewConfig().
+		WithID(id).
+		WithEngine(e).
+		WithProvider(NewStaticProvider(config)))
+	require.NoError(t, err)
+	return c, r
+}
+
+func TestStaticProvider(t *testing.T) {
+	addrA, addrB, addrC := getRandomLocalhostAddr(), getRandomLocalhostAddr(), getRandomLocalhostAddr()
+	config := NewStaticProviderConfig().
+		WithSeed(addrA).
+		WithSeed(addrB).
+		WithSeed(addrC).
+		WithJoinBackoff(20*time.Millisecond, 100*time.Millisecond)
+
+	a, _ := makeStaticCluster(t, "A", addrA, config)
+	a.Start()
+	defer a.Stop()
+	// A doesn't join before a second seed responds.
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, a.Members(), 1)
+
+	b, _ := makeStaticCluster(t, "B", addrB, config)
+	b.Start()
+	defer b.Stop()
+	// D is not a seed, it learns about the others from the seeds.
+	d, _ := makeStaticCluster(t, "D", getRandomLocalhostAddr(), config)
+	d.Start()
+	defer d.Stop()
+	for _, c := range []*Cluster{a, b, d} {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 3
+		}, 2*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+}
+
+// A member that lost all the others joins again once they are back.
+func TestStaticProviderRejoin(t *testing.T) {
+	addrA, addrB := getRandomLocalhostAddr(), getRandomLocalhostAddr()
+	config := NewStaticProviderConfig().
+		WithSeed(addrA).
+		WithSeed(addrB).
+		WithQuorum(2).
+		WithJoinBackoff(20*time.Millisecond, 100*time.Millisecond)
+
+	a, _ := makeStaticCluster(t, "A", addrA, config)
+	a.Start()
+	defer a.Stop()
+	b, rb := makeStaticCluster(t, "B", addrB, config)
+	b.Start()
+	require.Eventually(t, func() bool {
+		return len(a.Members()) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	b.Stop()
+	rb.Stop().Wait()
+	require.Eventually(t, func() bool {
+		return len(a.Members()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	b, _ = makeStaticCluster(t, "B", addrB, config)
+	b.Start()
+	defer b.Stop()
+	for _, c := range []*Cluster{a, b} {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 2
+		}, 2*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+}
@@ -0,0 +1,139 @@
+package cluster
+
+import (
+	"log/slog"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+// statsInterval is how often the agent samples the message rates and the
+// load, and sends its statistics to the other members.
+const statsInterval = time.Second
+
+type statsTick struct{}
+
+// stats holds the last sample of the messages this member exchanged with the
+// other members and of the CPU time it used, from which the rates are
+// computed.
+type stats struct {
+	repeater actor.SendRepeater
+	at       time.Time
+	in, out  uint64
+	inRate   float64
+	outRate  float64
+	cpuTime  time.Duration
+	cpu      float64
+	// The last statistics each member sent, by the ID of the member.
+	members map[string]*MemberStats
+}
+
+// Stats returns the statistics of this member.
+func (c *Cluster) Stats() *MemberStats {
+	return c.requestStats(c.Member())
+}
+
+// MemberStats returns the statistics of all the members, leaving out the
+// members that did not respond in time.
+func (c *Cluster) MemberStats() []*MemberStats {
+	members := c.Members()
+	all := make([]*MemberStats, len(members))
+	var wg sync.WaitGroup
+	for i, member := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			all[i] = c.requestStats(member)
+		}()
+	}
+	wg.Wait()
+	results := make([]*MemberStats, 0, len(all))
+	for _, s := range all {
+		if s != nil {
+			results = append(results, s)
+		}
+	}
+	return results
+}
+
+func (c *Cluster) requestStats(member *Member) *MemberStats {
+	resp, err := c.engine.Request(member.PID(), &MemberStatsRequest{}, c.config.requestTimeout).Result()
+	if err != nil {
+		slog.Warn("failed to request member stats", "err", err, "member", member.ID)
+		return nil
+	}
+	s, ok := resp.(*MemberStats)
+	if !ok {
+		slog.Warn("expected *MemberStats", "got", reflect.TypeOf(resp))
+		return nil
+	}
+	return s
+}
+
+func (a *Agent) startStats(pid *actor.PID) {
+	a.stats.at = time.Now()
+	a.stats.in, a.stats.out = a.remoteMessages()
+	a.stats.cpuTime = processCPUTime()
+	a.stats.repeater = a.cluster.engine.SendRepeat(pid, statsTick{}, statsInterval)
+}
+
+// handleStatsTick samples the statistics and sends them to all the members,
+// which take the load of the members into account for placement.
+func (a *Agent) handleStatsTick() {
+	now := time.Now()
+	in, out := a.remoteMessages()
+	cpuTime := processCPUTime()
+	if elapsed := now.Sub(a.stats.at).Seconds(); elapsed > 0 {
+		a.stats.inRate = float64(in-a.stats.in) / elapsed
+		a.stats.outRate = float64(out-a.stats.out) / elapsed
+		a.stats.cpu = min((cpuTime-a.stats.cpuTime).Seconds()/(elapsed*float64(runtime.NumCPU())), 1)
+	}
+	a.stats.at, a.stats.in, a.stats.out, a.stats.cpuTime = now, in, out, cpuTime
+	a.bcast(a.memberStats())
+}
+
+func (a *Agent) handleMemberStats(msg *MemberStats) {
+	if msg.Member != nil && a.members.Contains(msg.Member) {
+		a.stats.members[msg.Member.ID] = msg
+	}
+}
+
+// remoteMessages returns the number of messages this member received from and
+// sent to the other members.
+func (a *Agent) remoteMessages() (in, out uint64) {
+	if a.cluster.remote == nil {
+		return 0, 0
+	}
+	for _, peer := range a.cluster.remote.Metrics() {
+		in += peer.MessagesReceived
+		out += peer.MessagesSent
+	}
+	return in, out
+}
+
+func (a *Agent) memberStats() *MemberStats {
+	host := a.cluster.engine.Address()
+	hosted := 0
+	for _, pid := range a.activated {
+		if pid.Address == host {
+			hosted++
+		}
+	}
+	s := &MemberStats{
+		Member:               a.cluster.Member(),
+		Activations:          int64(hosted),
+		MessagesInPerSecond:  a.stats.inRate,
+		MessagesOutPerSecond: a.stats.outRate,
+		LoadFactor:           1,
+		Cpu:                  a.stats.cpu,
+		MailboxBacklog:       int64(a.cluster.engine.MailboxBacklog()),
+	}
+	if len(a.activated) > 0 && a.members.Len() > 0 {
+		average := float64(len(a.activated)) / float64(a.members.Len())
+		s.LoadFactor = float64(hosted) / average
+	}
+	return s
+}
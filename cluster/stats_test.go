@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemberStats(t *testing.T) {
+	a := makeSwimCluster(t, "A", fastSwimConfig())
+	a.RegisterKind("player", NewPlayer, NewKindConfig())
+	a.Start()
+	defer a.Stop()
+
+	topology := make(chan TopologyChangedEvent, 4)
+	eventPID := a.Engine().SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(TopologyChangedEvent); ok {
+			topology <- msg
+		}
+	}, "event")
+	a.Engine().Subscribe(eventPID)
+
+	b := makeSwimCluster(t, "B", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))
+	b.RegisterKind("player", NewPlayer, NewKindConfig())
+	b.Start()
+	defer b.Stop()
+	// The first event is A joining itself.
+	for joined := false; !joined; {
+		select {
+		case evt := <-topology:
+			require.Len(t, evt.Joined, 1)
+			assert.Empty(t, evt.Left)
+			joined = evt.Joined[0].ID == "B"
+			if joined {
+				assert.Len(t, evt.Members, 2)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("expected a TopologyChangedEvent for B")
+		}
+	}
+	require.Eventually(t, func() bool {
+		return len(b.Members()) == 2
+	}, 3*time.Second, 10*time.Millisecond)
+
+	for i := range 3 {
+		require.NotNil(t, a.Activate("player", NewActivationConfig().WithID(strconv.Itoa(i)).WithSelectMemberFunc(SelectLocalMember)))
+	}
+	require.NotNil(t, b.Activate("player", NewActivationConfig().WithID("3").WithSelectMemberFunc(SelectLocalMember)))
+
+	require.Eventually(t, func() bool {
+		stats := a.MemberStats()
+		if len(stats) != 2 {
+			return false
+		}
+		byID := make(map[string]*MemberStats)
+		for _, s := range stats {
+			byID[s.Member.ID] = s
+		}
+		return byID["A"].Activations == 3 && byID["A"].LoadFactor == 1.5 &&
+			byID["B"].Activations == 1 && byID["B"].LoadFactor == 0.5
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The members send their statistics to each other for placement.
+	var details ActivationDetails
+	require.Eventually(t, func() bool {
+		resp, err := a.Engine().Request(a.PID(), getActivationDetails{kind: "player"}, time.Second).Result()
+		require.NoError(t, err)
+		details = resp.(ActivationDetails)
+		return len(details.Stats) == 2 && details.Stats["B"].Activations == 1
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.Equal(t, "B", SelectLowestLoadMember(details).ID)
+
+	// The members exchange messages all the time with SWIM.
+	require.Eventually(t, func() bool {
+		s := a.Stats()
+		return s.MessagesInPerSecond > 0 && s.MessagesOutPerSecond > 0
+	}, 3*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,552 @@
+package cluster
+
+import (
+	"cmp"
+	"log/slog"
+	"math"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+const (
+	defaultProbeInterval    = time.Second
+	defaultProbeTimeout     = 500 * time.Millisecond
+	defaultIndirectChecks   = 3
+	defaultSuspicionTimeout = 5 * time.Second
+	defaultRetransmitMult   = 4
+	defaultMaxGossip        = 16
+	defaultSyncInterval     = 30 * time.Second
+)
+
+type (
+	swimTick         struct{}
+	swimSyncTick     struct{}
+	swimProbeTimeout struct{ seq uint64 }
+)
+
+// SwimConfig holds the configuration of the SWIM provider.
+type SwimConfig struct {
+	seeds            []MemberAddr
+	probeInterval    time.Duration
+	probeTimeout     time.Duration
+	indirectChecks   int
+	suspicionTimeout time.Duration
+	retransmitMult   int
+	maxGossip        int
+	failureDetector  FailureDetectorConfig
+	syncInterval     time.Duration
+}
+
+// NewSwimConfig returns a SwimConfig that is initialized with default values.
+func NewSwimConfig() SwimConfig {
+	return SwimConfig{
+		seeds:            make([]MemberAddr, 0),
+		probeInterval:    defaultProbeInterval,
+		probeTimeout:     defaultProbeTimeout,
+		indirectChecks:   defaultIndirectChecks,
+		suspicionTimeout: defaultSuspicionTimeout,
+		retransmitMult:   defaultRetransmitMult,
+		maxGossip:        defaultMaxGossip,
+		failureDetector:  NewFailureDetectorConfig(),
+		syncInterval:     defaultSyncInterval,
+	}
+}
+
+// WithSeed adds a member that is contacted to join the cluster. Once joined,
+// the members learn about each other through gossip.
+func (c SwimConfig) WithSeed(member MemberAddr) SwimConfig {
+	c.seeds = append(c.seeds, member)
+	return c
+}
+
+// WithProbeInterval set's the interval at which a member probes another,
+// randomly selected member.
+//
+// Defaults to 1 second.
+func (c SwimConfig) WithProbeInterval(d time.Duration) SwimConfig {
+	c.probeInterval = d
+	return c
+}
+
+// WithProbeTimeout set's how long a member waits for the ack of a probe before
+// it asks other members to probe the target indirectly. It should be shorter
+// than the probe interval.
+//
+// Defaults to 500 milliseconds.
+func (c SwimConfig) WithProbeTimeout(d time.Duration) SwimConfig {
+	c.probeTimeout = d
+	return c
+}
+
+// WithIndirectChecks set's the number of members that are asked to probe a
+// target that did not ack a probe.
+//
+// Defaults to 3.
+func (c SwimConfig) WithIndirectChecks(n int) SwimConfig {
+	c.indirectChecks = n
+	return c
+}
+
+// WithSuspicionTimeout set's how long a member is suspected before it is
+// declared dead and removed from the cluster, unless it refutes the suspicion.
+//
+// Defaults to 5 seconds.
+func (c SwimConfig) WithSuspicionTimeout(d time.Duration) SwimConfig {
+	c.suspicionTimeout = d
+	return c
+}
+
+// WithFailureDetector set's the configuration of the failure detector, which
+// decides whether a member that did not ack its probe is suspected.
+//
+// Defaults to NewFailureDetectorConfig().
+func (c SwimConfig) WithFailureDetector(config FailureDetectorConfig) SwimConfig {
+	c.failureDetector = config
+	return c
+}
+
+// WithSyncInterval set's the interval at which a member exchanges all the
+// members it knows of with another, randomly selected member. The gossip
+// spreads the changes quickly, and the exchange makes sure that the members
+// agree in the end, for example when members that suspected each other by
+// mistake forgot about each other. Zero disables the exchange.
+//
+// Defaults to 30 seconds.
+func (c SwimConfig) WithSyncInterval(d time.Duration) SwimConfig {
+	c.syncInterval = d
+	return c
+}
+
+// swimMember is a member as known by the local node.
+type swimMember struct {
+	state *MemberState
+	// changed is the time of the last change of the status.
+	changed time.Time
+	// detector learns the intervals between the messages of the member.
+	detector *phiDetector
+}
+
+// swimGossip is an update that is piggybacked on the messages of the
+// protocol until it was sent often enough to have reached all members.
+type swimGossip struct {
+	state     *MemberState
+	transmits int
+}
+
+// swimProbe is the probe of the current protocol period.
+type swimProbe struct {
+	target *Member
+	seq    uint64
+	acked  bool
+}
+
+// swimForward is a probe sent on behalf of another member.
+type swimForward struct {
+	requester *actor.PID
+	seq       uint64
+	sent      time.Time
+}
+
+// Swim is a provider that manages the membership of the cluster with the SWIM
+// gossip protocol. Every probe interval a member pings another member. When
+// the ping is not acked in time, other members are asked to ping it as well,
+// and if none of them succeeds the member is suspected, once the phi accrual
+// failure detector considers its silence abnormal. A suspected member
+// that doesn't refute the suspicion within the suspicion timeout is declared
+// dead. The changes are piggybacked on the pings and acks, so they spread
+// through the cluster without a central registry.
+type Swim struct {
+	config  SwimConfig
+	cluster *Cluster
+	pid     *actor.PID
+	ticker  actor.SendRepeater
+	syncer  actor.SendRepeater
+
+	incarnation uint64
+	members     map[string]*swimMember
+	gossip      []*swimGossip
+
+	seq        uint64
+	probe      *swimProbe
+	probeOrder []string
+	forwards   map[uint64]swimForward
+}
+
+// NewSwimProvider returns a provider that discovers the members of the
+// cluster with SWIM gossip, starting from the seeds of the config.
+func NewSwimProvider(config SwimConfig) Producer {
+	return func(c *Cluster) actor.Producer {
+		return func() actor.Receiver {
+			return &Swim{
+				config:   config,
+				cluster:  c,
+				members:  make(map[string]*swimMember),
+				forwards: make(map[uint64]swimForward),
+			}
+		}
+	}
+}
+
+func (s *Swim) Receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.Started:
+		s.pid = c.PID()
+		s.queueGossip(s.selfState())
+		s.sendMembersToAgent()
+		for _, seed := range s.config.seeds {
+			seedPID := actor.NewPID(seed.ListenAddr, "provider/"+seed.ID)
+			s.send(seedPID, &Handshake{Member: s.cluster.Member()})
+		}
+		s.ticker = c.SendRepeat(c.PID(), swimTick{}, s.config.probeInterval)
+		if s.config.syncInterval > 0 {
+			s.syncer = c.SendRepeat(c.PID(), swimSyncTick{}, s.config.syncInterval)
+		}
+	case actor.Stopped:
+		s.ticker.Stop()
+		if s.config.syncInterval > 0 {
+			s.syncer.Stop()
+		}
+	case *Handshake:
+		s.handleJoin(c, msg.Member)
+	case *SwimSync:
+		s.merge(msg.Members)
+		s.heartbeat(c.Sender(), time.Now())
+		if msg.Pull {
+			s.send(c.Sender(), &SwimSync{Members: s.states()})
+		}
+	case swimSyncTick:
+		s.sync()
+	case *SwimPing:
+		s.merge(msg.Gossip)
+		s.heartbeat(c.Sender(), time.Now())
+		s.send(c.Sender(), &SwimAck{Seq: msg.Seq, Gossip: s.piggyback()})
+	case *SwimAck:
+		s.merge(msg.Gossip)
+		s.heartbeat(c.Sender(), time.Now())
+		s.handleAck(msg.Seq)
+	case *SwimPingRequest:
+		s.merge(msg.Gossip)
+		s.heartbeat(c.Sender(), time.Now())
+		s.handlePingRequest(c.Sender(), msg)
+	case swimTick:
+		s.tick(time.Now())
+	case swimProbeTimeout:
+		s.handleProbeTimeout(msg.seq)
+	case actor.Initialized:
+		_ = msg
+	default:
+		slog.Warn("received unhandled message", "msg", msg, "t", reflect.TypeOf(msg))
+	}
+}
+
+// handleJoin adds a member that contacted us to join the cluster and sends it
+// the members we know of.
+func (s *Swim) handleJoin(c *actor.Context, member *Member) {
+	// A member that rejoins under the same ID after it was declared dead
+	// starts counting its incarnations from zero again.
+	if m, ok := s.members[member.ID]; ok && m.state.Status == MemberStatus_DEAD {
+		delete(s.members, member.ID)
+	}
+	s.merge([]*MemberState{{Member: member, Status: MemberStatus_ALIVE}})
+	s.send(c.Sender(), &SwimSync{Members: s.states()})
+}
+
+// sync exchanges the members with a random member that is alive.
+func (s *Swim) sync() {
+	alive := make([]*Member, 0, len(s.members))
+	for _, m := range s.members {
+		if m.state.Status == MemberStatus_ALIVE {
+			alive = append(alive, m.state.Member)
+		}
+	}
+	if len(alive) == 0 {
+		return
+	}
+	target := alive[rand.Intn(len(alive))]
+	s.send(memberToProviderPID(target), &SwimSync{Members: s.states(), Pull: true})
+}
+
+// states returns the states of all the members we know of, including this
+// one.
+func (s *Swim) states() []*MemberState {
+	states := []*MemberState{s.selfState()}
+	for _, m := range s.members {
+		states = append(states, m.state)
+	}
+	return states
+}
+
+// tick starts a new protocol period. The target of the previous probe is
+// suspected if neither it nor one of the indirect probes acked, and the
+// failure detector finds it suspicious. A member whose ack got lost but that
+// keeps sending its messages is not suspected.
+func (s *Swim) tick(now time.Time) {
+	if s.probe != nil && !s.probe.acked {
+		if m, ok := s.members[s.probe.target.ID]; ok && m.state.Status == MemberStatus_ALIVE && m.detector.suspicious(now) {
+			s.apply(&MemberState{Member: m.state.Member, Status: MemberStatus_SUSPECT, Incarnation: m.state.Incarnation}, now)
+		}
+	}
+	s.probe = nil
+	for _, m := range s.members {
+		switch {
+		case m.state.Status == MemberStatus_SUSPECT && now.Sub(m.changed) > s.config.suspicionTimeout:
+			s.apply(&MemberState{Member: m.state.Member, Status: MemberStatus_DEAD, Incarnation: m.state.Incarnation}, now)
+		case m.state.Status == MemberStatus_DEAD && now.Sub(m.changed) > s.config.suspicionTimeout:
+			// Dead members are kept for a while, so stale gossip about
+			// them being alive is ignored.
+			delete(s.members, m.state.Member.ID)
+			s.dropGossip(m.state.Member.ID)
+		}
+	}
+	for seq, fw := range s.forwards {
+		if now.Sub(fw.sent) > s.config.probeInterval {
+			delete(s.forwards, seq)
+		}
+	}
+
+	target := s.nextTarget()
+	if target == nil {
+		return
+	}
+	s.seq++
+	s.probe = &swimProbe{target: target, seq: s.seq}
+	s.send(memberToProviderPID(target), &SwimPing{Seq: s.seq, Gossip: s.piggyback()})
+	seq, pid, engine := s.seq, s.pid, s.cluster.engine
+	time.AfterFunc(s.config.probeTimeout, func() {
+		engine.Send(pid, swimProbeTimeout{seq: seq})
+	})
+}
+
+// nextTarget returns the next member to probe. The members are probed in a
+// random order, each of them once per round.
+func (s *Swim) nextTarget() *Member {
+	for {
+		if len(s.probeOrder) == 0 {
+			for id, m := range s.members {
+				if m.state.Status != MemberStatus_DEAD {
+					s.probeOrder = append(s.probeOrder, id)
+				}
+			}
+			if len(s.probeOrder) == 0 {
+				return nil
+			}
+			rand.Shuffle(len(s.probeOrder), func(i, j int) {
+				s.probeOrder[i], s.probeOrder[j] = s.probeOrder[j], s.probeOrder[i]
+			})
+		}
+		id := s.probeOrder[0]
+		s.probeOrder = s.probeOrder[1:]
+		if m, ok := s.members[id]; ok && m.state.Status != MemberStatus_DEAD {
+			return m.state.Member
+		}
+	}
+}
+
+// handleProbeTimeout asks other members to probe the target of the current
+// probe, which did not ack in time.
+func (s *Swim) handleProbeTimeout(seq uint64) {
+	if s.probe == nil || s.probe.seq != seq || s.probe.acked {
+		return
+	}
+	candidates := make([]*Member, 0, len(s.members))
+	for _, m := range s.members {
+		if m.state.Status == MemberStatus_ALIVE && m.state.Member.ID != s.probe.target.ID {
+			candidates = append(candidates, m.state.Member)
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	for _, m := range candidates[:min(len(candidates), s.config.indirectChecks)] {
+		s.send(memberToProviderPID(m), &SwimPingRequest{Seq: seq, Target: s.probe.target, Gossip: s.piggyback()})
+	}
+}
+
+// handlePingRequest probes the target on behalf of the requester, the ack is
+// forwarded to it.
+func (s *Swim) handlePingRequest(requester *actor.PID, msg *SwimPingRequest) {
+	if requester == nil || msg.Target == nil {
+		return
+	}
+	s.seq++
+	s.forwards[s.seq] = swimForward{requester: requester, seq: msg.Seq, sent: time.Now()}
+	s.send(memberToProviderPID(msg.Target), &SwimPing{Seq: s.seq, Gossip: s.piggyback()})
+}
+
+func (s *Swim) handleAck(seq uint64) {
+	if s.probe != nil && s.probe.seq == seq {
+		s.probe.acked = true
+		// The ack can come through another member.
+		if m, ok := s.members[s.probe.target.ID]; ok {
+			m.detector.heartbeat(time.Now())
+		}
+		return
+	}
+	if fw, ok := s.forwards[seq]; ok {
+		delete(s.forwards, seq)
+		s.send(fw.requester, &SwimAck{Seq: fw.seq, Gossip: s.piggyback()})
+	}
+}
+
+// heartbeat records a message of the member of the given provider for its
+// failure detector.
+func (s *Swim) heartbeat(pid *actor.PID, now time.Time) {
+	if pid == nil {
+		return
+	}
+	id, ok := strings.CutPrefix(pid.ID, "provider/")
+	if !ok {
+		return
+	}
+	if m, ok := s.members[id]; ok {
+		m.detector.heartbeat(now)
+	}
+}
+
+func (s *Swim) merge(states []*MemberState) {
+	now := time.Now()
+	for _, state := range states {
+		if state.GetMember() != nil {
+			s.apply(state, now)
+		}
+	}
+}
+
+// apply applies the given state of a member, if it is newer than the one we
+// know of. An alive member overrides the states of older incarnations, a
+// suspected one those of the same incarnation and a dead one all of them. A
+// member declared dead by mistake comes back by refuting with a newer
+// incarnation.
+func (s *Swim) apply(state *MemberState, now time.Time) {
+	id := state.Member.ID
+	if id == s.cluster.ID() {
+		// Refute the suspicion or the death of this member with a newer
+		// incarnation.
+		if state.Status != MemberStatus_ALIVE && state.Incarnation >= s.incarnation {
+			s.incarnation = state.Incarnation + 1
+			s.queueGossip(s.selfState())
+		}
+		return
+	}
+	m, ok := s.members[id]
+	if !ok {
+		if state.Status == MemberStatus_DEAD {
+			return
+		}
+		m = &swimMember{
+			state:    state,
+			changed:  now,
+			detector: newPhiDetector(s.config.failureDetector, s.config.probeInterval, now),
+		}
+		s.members[id] = m
+		s.queueGossip(state)
+		s.sendMembersToAgent()
+		if state.Status == MemberStatus_SUSPECT {
+			s.cluster.engine.BroadcastEvent(MemberSuspectEvent{Member: state.Member})
+		}
+		return
+	}
+	cur := m.state
+	var newer bool
+	switch state.Status {
+	case MemberStatus_ALIVE:
+		newer = state.Incarnation > cur.Incarnation
+	case MemberStatus_SUSPECT:
+		newer = state.Incarnation > cur.Incarnation ||
+			(state.Incarnation == cur.Incarnation && cur.Status == MemberStatus_ALIVE)
+	case MemberStatus_DEAD:
+		newer = state.Incarnation >= cur.Incarnation && cur.Status != MemberStatus_DEAD
+	}
+	if !newer {
+		return
+	}
+	m.state = state
+	if state.Status != cur.Status {
+		m.changed = now
+	}
+	switch {
+	case state.Status == MemberStatus_ALIVE && cur.Status == MemberStatus_DEAD:
+		// The intervals from before the member was dead don't tell anything.
+		m.detector = newPhiDetector(s.config.failureDetector, s.config.probeInterval, now)
+	case state.Status == MemberStatus_ALIVE:
+		// The member refuted, which is as good as a message of its own.
+		m.detector.heartbeat(now)
+	}
+	s.queueGossip(state)
+	switch {
+	case state.Status == MemberStatus_SUSPECT && cur.Status != MemberStatus_SUSPECT:
+		slog.Debug("[CLUSTER] member suspected", "id", id, "host", state.Member.Host)
+		s.cluster.engine.BroadcastEvent(MemberSuspectEvent{Member: state.Member})
+	case state.Status == MemberStatus_DEAD || cur.Status == MemberStatus_DEAD:
+		s.sendMembersToAgent()
+	}
+}
+
+// queueGossip queues the given state to be piggybacked on the next messages,
+// replacing older states of the same member.
+func (s *Swim) queueGossip(state *MemberState) {
+	s.dropGossip(state.Member.ID)
+	s.gossip = append(s.gossip, &swimGossip{state: state})
+}
+
+// dropGossip drops the queued state of the given member. The death of a
+// member that is forgotten was spread by then, unless this member was cut
+// off, in which case it would declare the member dead on the other side once
+// the connection is back.
+func (s *Swim) dropGossip(id string) {
+	s.gossip = slices.DeleteFunc(s.gossip, func(g *swimGossip) bool {
+		return g.state.Member.ID == id
+	})
+}
+
+// piggyback returns the states to send along with a message, preferring the
+// ones that were sent the least. States that were sent often enough to have
+// reached every member with high probability are dropped.
+func (s *Swim) piggyback() []*MemberState {
+	limit := s.config.retransmitMult * int(math.Ceil(math.Log10(float64(len(s.members)+2))))
+	slices.SortStableFunc(s.gossip, func(a, b *swimGossip) int {
+		return cmp.Compare(a.transmits, b.transmits)
+	})
+	n := min(len(s.gossip), s.config.maxGossip)
+	states := make([]*MemberState, 0, n)
+	for _, g := range s.gossip[:n] {
+		g.transmits++
+		states = append(states, g.state)
+	}
+	s.gossip = slices.DeleteFunc(s.gossip, func(g *swimGossip) bool {
+		return g.transmits >= limit
+	})
+	return states
+}
+
+func (s *Swim) selfState() *MemberState {
+	return &MemberState{
+		Member:      s.cluster.Member(),
+		Status:      MemberStatus_ALIVE,
+		Incarnation: s.incarnation,
+	}
+}
+
+func (s *Swim) send(pid *actor.PID, msg any) {
+	if pid == nil {
+		return
+	}
+	s.cluster.engine.SendWithSender(pid, msg, s.pid)
+}
+
+// sendMembersToAgent sends the members that are not dead, including this
+// one, to the local cluster agent.
+func (s *Swim) sendMembersToAgent() {
+	members := []*Member{s.cluster.Member()}
+	for _, m := range s.members {
+		if m.state.Status != MemberStatus_DEAD {
+			members = append(members, m.state.Member)
+		}
+	}
+	s.cluster.engine.Send(s.cluster.PID(), &Members{Members: members})
+}
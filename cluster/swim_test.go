@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fastSwimConfig() SwimConfig {
+	return NewSwimConfig().
+		WithProbeInterval(50 * time.Millisecond).
+		WithProbeTimeout(20 * time.Millisecond).
+		WithSuspicionTimeout(200 * time.Millisecond).
+		WithSyncInterval(200 * time.Millisecond)
+}
+
+func makeSwimCluster(t *testing.T, id string, config SwimConfig) *Cluster {
+	c, err := New(NewConfig().
+		WithID(id).
+		WithListenAddr(getRandomLocalhostAddr()).
+		WithProvider(NewSwimProvider(config)))
+	require.NoError(t, err)
+	return c
+}
+
+func TestSwimMembership(t *testing.T) {
+	c1 := makeSwimCluster(t, "A", fastSwimConfig())
+	c1.Start()
+	defer c1.Stop()
+	seed := MemberAddr{ListenAddr: c1.Address(), ID: "A"}
+	c2 := makeSwimCluster(t, "B", fastSwimConfig().WithSeed(seed))
+	c2.Start()
+	defer c2.Stop()
+	c3 := makeSwimCluster(t, "C", fastSwimConfig().WithSeed(seed))
+
+	events := make(chan any, 16)
+	eventPID := c2.Engine().SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case MemberSuspectEvent, MemberLeaveEvent:
+			events <- msg
+		}
+	}, "event")
+	c2.Engine().Subscribe(eventPID)
+
+	c3.Start()
+	// B only learns about C through gossip.
+	for _, c := range []*Cluster{c1, c2, c3} {
+		require.Eventually(t, func() bool {
+			return len(c.Members()) == 3
+		}, 2*time.Second, 10*time.Millisecond, "members of %s", c.ID())
+	}
+
+	c3.Stop()
+	for _, expected := range []any{MemberSuspectEvent{}, MemberLeaveEvent{}} {
+		select {
+		case evt := <-events:
+			assert.IsType(t, expected, evt)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected a %T", expected)
+		}
+	}
+	assert.Eventually(t, func() bool {
+		return len(c1.Members()) == 2 && len(c2.Members()) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSwimApply(t *testing.T) {
+	c := makeSwimCluster(t, "A", fastSwimConfig())
+	c.Start()
+	defer c.Stop()
+	s := NewSwimProvider(fastSwimConfig())(c)().(*Swim)
+	now := time.Now()
+
+	// A suspicion of ourselves is refuted with a newer incarnation.
+	s.apply(&MemberState{Member: c.Member(), Status: MemberStatus_SUSPECT}, now)
+	assert.Equal(t, uint64(1), s.incarnation)
+	require.Len(t, s.gossip, 1)
+	assert.Equal(t, MemberStatus_ALIVE, s.gossip[0].state.Status)
+
+	b := &Member{ID: "B", Host: "127.0.0.1:4000"}
+	status := func() MemberStatus { return s.members["B"].state.Status }
+	steps := []struct {
+		status      MemberStatus
+		incarnation uint64
+		expected    MemberStatus
+	}{
+		{MemberStatus_ALIVE, 1, MemberStatus_ALIVE},
+		{MemberStatus_SUSPECT, 0, MemberStatus_ALIVE},
+		{MemberStatus_SUSPECT, 1, MemberStatus_SUSPECT},
+		{MemberStatus_ALIVE, 1, MemberStatus_SUSPECT},
+		{MemberStatus_ALIVE, 2, MemberStatus_ALIVE},
+		{MemberStatus_DEAD, 2, MemberStatus_DEAD},
+		{MemberStatus_ALIVE, 2, MemberStatus_DEAD},
+		{MemberStatus_ALIVE, 3, MemberStatus_ALIVE},
+	}
+	for _, step := range steps {
+		s.apply(&MemberState{Member: b, Status: step.status, Incarnation: step.incarnation}, now)
+		assert.Equal(t, step.expected, status(), "%s %d", step.status, step.incarnation)
+	}
+}
+
+func TestSwimFailureDetector(t *testing.T) {
+	c := makeSwimCluster(t, "A", fastSwimConfig())
+	c.Start()
+	defer c.Stop()
+	config := fastSwimConfig().WithFailureDetector(NewFailureDetectorConfig().WithMinStdDev(10 * time.Millisecond))
+	s := NewSwimProvider(config)(c)().(*Swim)
+	now := time.Now()
+	b := &Member{ID: "B", Host: "127.0.0.1:4000"}
+	s.apply(&MemberState{Member: b, Status: MemberStatus_ALIVE}, now)
+	pid := memberToProviderPID(b)
+	for range 10 {
+		now = now.Add(50 * time.Millisecond)
+		s.heartbeat(pid, now)
+	}
+
+	// A lost ack does not make B suspected as long as it keeps sending its
+	// messages.
+	s.probe = &swimProbe{target: b, seq: 1}
+	s.tick(now.Add(50 * time.Millisecond))
+	assert.Equal(t, MemberStatus_ALIVE, s.members["B"].state.Status)
+
+	s.probe = &swimProbe{target: b, seq: 2}
+	s.tick(now.Add(time.Second))
+	assert.Equal(t, MemberStatus_SUSPECT, s.members["B"].state.Status)
+}
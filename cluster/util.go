@@ -1,6 +1,8 @@
 package cluster
 
 import (
+	"slices"
+
 	"github.com/fertigai/hollywood/actor"
 )
 
@@ -10,6 +12,31 @@ func memberToProviderPID(m *Member) *actor.PID {
 	return actor.NewPID(m.Host, "provider/"+m.ID)
 }
 
+// The ID of a member is part of the PID of its provider, so the providers that
+// only know the addresses of the other members send their handshake to the
+// discovery actor, which has a well known PID and forwards the handshakes to
+// the provider.
+const (
+	discoveryName = "discovery"
+	discoveryID   = "provider"
+)
+
+// discoveryPID returns the PID of the discovery actor of the member with the
+// given address.
+func discoveryPID(addr string) *actor.PID {
+	return actor.NewPID(addr, discoveryName+"/"+discoveryID)
+}
+
+// spawnDiscovery spawns the discovery actor, which forwards the handshakes to
+// the given provider while keeping their sender.
+func spawnDiscovery(c *Cluster, provider *actor.PID) *actor.PID {
+	return c.engine.SpawnFunc(func(ctx *actor.Context) {
+		if msg, ok := ctx.Message().(*Handshake); ok {
+			c.engine.SendWithSender(provider, msg, ctx.Sender())
+		}
+	}, discoveryName, actor.WithID(discoveryID))
+}
+
 // NewCID returns a new Cluster ID.
 func NewCID(pid *actor.PID, kind, id, region string) *CID {
 	return &CID{
@@ -34,6 +61,12 @@ func (m *Member) Equals(other *Member) bool {
 	return m.Host == other.Host && m.ID == other.ID
 }
 
+// HasRole returns true whether the Member declared the given role. Every
+// member has the empty role.
+func (m *Member) HasRole(role string) bool {
+	return role == "" || slices.Contains(m.Roles, role)
+}
+
 // TODO: Maybe relocate this function.
 // HasKind returns true whether the Member has the given kind registered.
 func (m *Member) HasKind(kind string) bool {
@@ -44,3 +77,29 @@ func (m *Member) HasKind(kind string) bool {
 	}
 	return false
 }
+
+// The messages exchanged between the members are control messages, which the
+// remote sends over the priority lane so they don't wait behind the messages
+// of the actors.
+
+func (*Members) ControlMessage()            {}
+func (*MembersJoin) ControlMessage()        {}
+func (*MembersLeave) ControlMessage()       {}
+func (*Handshake) ControlMessage()          {}
+func (*Topology) ControlMessage()           {}
+func (*ActorTopology) ControlMessage()      {}
+func (*Activation) ControlMessage()         {}
+func (*Deactivation) ControlMessage()       {}
+func (*ActivationRequest) ControlMessage()  {}
+func (*ActivationResponse) ControlMessage() {}
+func (*SwimPing) ControlMessage()           {}
+func (*SwimAck) ControlMessage()            {}
+func (*SwimPingRequest) ControlMessage()    {}
+func (*SwimSync) ControlMessage()           {}
+func (*Election) ControlMessage()           {}
+func (*ElectionAlive) ControlMessage()      {}
+func (*Coordinator) ControlMessage()        {}
+func (*GrainActivation) ControlMessage()    {}
+func (*MemberLeaving) ControlMessage()      {}
+func (*MemberDraining) ControlMessage()     {}
+func (*GrainLookup) ControlMessage()        {}
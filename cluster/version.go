@@ -0,0 +1,125 @@
+package cluster
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type drain struct{}
+
+// CompareVersions compares two application versions by their numeric parts,
+// so "1.10" is newer than "1.9", and returns -1, 0 or +1 like cmp.Compare. A
+// leading "v" is ignored, missing parts count as zero and parts that are not
+// numbers are compared as strings.
+func CompareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := range max(len(as), len(bs)) {
+		x, y := "0", "0"
+		if i < len(as) && as[i] != "" {
+			x = as[i]
+		}
+		if i < len(bs) && bs[i] != "" {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		var c int
+		if xerr == nil && yerr == nil {
+			c = cmp.Compare(xn, yn)
+		} else {
+			c = cmp.Compare(x, y)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// PreferMinVersion returns a SelectMemberFunc that selects with next among the
+// members that run at least the given version, and among all the members if
+// none does. During a rolling upgrade new activations go to the upgraded
+// members while there are any.
+func PreferMinVersion(version string, next SelectMemberFunc) SelectMemberFunc {
+	return func(details ActivationDetails) *Member {
+		if members := minVersionMembers(details.Members, version); len(members) > 0 {
+			details.Members = members
+		}
+		return selectOrRandom(next, details)
+	}
+}
+
+// RequireMinVersion returns a SelectMemberFunc that selects with next among the
+// members that run at least the given version. If none does, no member is
+// selected and the activation fails.
+func RequireMinVersion(version string, next SelectMemberFunc) SelectMemberFunc {
+	return func(details ActivationDetails) *Member {
+		details.Members = minVersionMembers(details.Members, version)
+		if len(details.Members) == 0 {
+			return nil
+		}
+		return selectOrRandom(next, details)
+	}
+}
+
+func selectOrRandom(fn SelectMemberFunc, details ActivationDetails) *Member {
+	if fn == nil {
+		return SelectRandomMember(details)
+	}
+	return fn(details)
+}
+
+func minVersionMembers(members []*Member, version string) []*Member {
+	return slices.DeleteFunc(slices.Clone(members), func(m *Member) bool {
+		return CompareVersions(m.Version, version) < 0
+	})
+}
+
+// Drain stops activating new actors on this member, while it keeps hosting
+// the actors it has until it leaves. The actors of partitioned kinds move to
+// the other members right away, so they don't move back and forth between
+// the old and the new versions during a rolling upgrade. See
+// Config.WithDrainOldVersions to drain automatically.
+func (c *Cluster) Drain() {
+	c.engine.Send(c.agentPID, drain{})
+}
+
+func (a *Agent) handleDrain() {
+	self := a.cluster.Member()
+	if a.draining[self.ID] || a.leaving[self.ID] {
+		return
+	}
+	a.draining[self.ID] = true
+	a.bcast(&MemberDraining{Member: self})
+	a.ring = newHashRing(a.placeableMembers())
+	slog.Info("[CLUSTER] draining", "id", self.ID, "version", self.Version)
+	a.rebalancePartitions()
+}
+
+// drainOldVersion drains this member if it was configured to drain once a
+// member with a newer version joined.
+func (a *Agent) drainOldVersion(joined []*Member) {
+	if !a.cluster.config.drainOldVersions {
+		return
+	}
+	for _, member := range joined {
+		if CompareVersions(member.Version, a.cluster.config.version) > 0 {
+			a.handleDrain()
+			return
+		}
+	}
+}
+
+// handleMemberDraining stops activating actors on the member that drains.
+func (a *Agent) handleMemberDraining(msg *MemberDraining) {
+	if msg.Member == nil || !a.members.Contains(msg.Member) {
+		return
+	}
+	a.draining[msg.Member.ID] = true
+	a.ring = newHashRing(a.placeableMembers())
+	slog.Debug("[CLUSTER] member draining", "id", msg.Member.ID, "version", msg.Member.Version)
+}
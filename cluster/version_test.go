@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.2", "1.2.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"1.9", "1.10", -1},
+		{"2", "1.99.99", 1},
+		{"", "0.1", -1},
+		{"1.0.0-rc1", "1.0.0-rc2", -1},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CompareVersions(tt.a, tt.b), "%s <=> %s", tt.a, tt.b)
+	}
+}
+
+func TestSelectMinVersion(t *testing.T) {
+	details := ActivationDetails{
+		Members: []*Member{
+			{ID: "A", Version: "1.0.0"},
+			{ID: "B", Version: "1.1.0"},
+		},
+		Load: map[string]int{"B": 3},
+	}
+	assert.Equal(t, "B", PreferMinVersion("1.1", SelectLeastLoadedMember)(details).ID)
+	assert.Equal(t, "A", PreferMinVersion("2.0", SelectLeastLoadedMember)(details).ID)
+	assert.Equal(t, "B", RequireMinVersion("1.1", nil)(details).ID)
+	assert.Nil(t, RequireMinVersion("2.0", nil)(details))
+	// The members of the details are left as they are.
+	assert.Len(t, details.Members, 2)
+}
+
+func makeVersionedCluster(t *testing.T, id, version string, swim SwimConfig) *Cluster {
+	c, err := New(NewConfig().
+		WithID(id).
+		WithVersion(version).
+		WithDrainOldVersions(true).
+		WithListenAddr(getRandomLocalhostAddr()).
+		WithProvider(NewSwimProvider(swim)))
+	require.NoError(t, err)
+	c.RegisterKind("player", NewPlayer, NewKindConfig())
+	return c
+}
+
+func TestDrainOldVersions(t *testing.T) {
+	a := makeVersionedCluster(t, "A", "1.0.0", fastSwimConfig())
+	a.Start()
+	defer a.Stop()
+	old := a.Activate("player", NewActivationConfig().WithID("old"))
+	require.NotNil(t, old)
+
+	b := makeVersionedCluster(t, "B", "1.1.0", fastSwimConfig().WithSeed(MemberAddr{ListenAddr: a.Address(), ID: "A"}))
+	b.Start()
+	defer b.Stop()
+	require.Eventually(t, func() bool {
+		return len(a.Members()) == 2 && len(b.Members()) == 2
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// Once B joined, A drains and the new activations go to B, even the ones
+	// that prefer the local member on A.
+	for i, c := range []*Cluster{a, b, a, b} {
+		config := NewActivationConfig().WithID(strconv.Itoa(i)).WithSelectMemberFunc(SelectLocalMember)
+		pid := c.Activate("player", config)
+		require.NotNil(t, pid)
+		assert.Equal(t, b.Address(), pid.Address)
+	}
+	// The actors A hosted already stay.
+	assert.Equal(t, old, a.GetActiveByID("player/old"))
+}
@@ -0,0 +1,370 @@
+// Package dashboard serves a web dashboard, and the JSON API behind it, to
+// inspect a running engine: the tree of its actors with the depth of their
+// mailboxes and their message rates, the recent restarts, the peers of the
+// remote and the members of the cluster.
+//
+//	d := dashboard.New(e, dashboard.NewConfig().WithRemote(r).WithCommands(true))
+//	defer d.Close()
+//	http.Handle("/debug/actors/", http.StripPrefix("/debug/actors", d.Handler()))
+package dashboard
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/cluster"
+	"github.com/fertigai/hollywood/remote"
+)
+
+//go:embed index.html
+var index []byte
+
+const (
+	defaultInterval = time.Second
+	defaultRestarts = 50
+)
+
+// Config holds the configuration of a Dashboard.
+type Config struct {
+	remote   *remote.Remote
+	cluster  *cluster.Cluster
+	commands bool
+	interval time.Duration
+	restarts int
+}
+
+// NewConfig returns a Config that is initialized with default values.
+func NewConfig() Config {
+	return Config{
+		interval: defaultInterval,
+		restarts: defaultRestarts,
+	}
+}
+
+// WithRemote set's the remote of the engine, to show its peers and the
+// traffic with them.
+//
+// Defaults to nil, which shows no peers.
+func (config Config) WithRemote(r *remote.Remote) Config {
+	config.remote = r
+	return config
+}
+
+// WithCluster set's the cluster of the engine, to show its members and its
+// leader.
+//
+// Defaults to nil, which shows no members.
+func (config Config) WithCluster(c *cluster.Cluster) Config {
+	config.cluster = c
+	return config
+}
+
+// WithCommands set's whether the actors can be stopped and restarted from the
+// dashboard. Anyone that reaches the handler can then do so, so mount it
+// behind authentication.
+//
+// Defaults to false, which makes the dashboard read only.
+func (config Config) WithCommands(enabled bool) Config {
+	config.commands = enabled
+	return config
+}
+
+// WithInterval set's the interval the message rates of the actors are
+// measured over.
+//
+// Defaults to 1 second.
+func (config Config) WithInterval(d time.Duration) Config {
+	config.interval = d
+	return config
+}
+
+// WithRestarts set's the number of recent restarts the dashboard keeps.
+//
+// Defaults to 50.
+func (config Config) WithRestarts(n int) Config {
+	config.restarts = n
+	return config
+}
+
+// Actor is an actor in the tree of the dashboard.
+type Actor struct {
+	PID      string `json:"pid"`
+	ID       string `json:"id"`
+	Kind     string `json:"kind"`
+	Mailbox  int    `json:"mailbox"`
+	Messages uint64 `json:"messages"`
+	// Rate is the number of messages the actor handled per second over the
+	// last interval, see Config.WithInterval.
+	Rate       float64   `json:"rate"`
+	LastActive time.Time `json:"lastActive"`
+	Children   []*Actor  `json:"children,omitempty"`
+}
+
+// Restart is a restart of an actor.
+type Restart struct {
+	PID       string    `json:"pid"`
+	Kind      string    `json:"kind"`
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason"`
+	Restarts  int32     `json:"restarts"`
+	// LastMessages are the messages that led up to the restart, if the
+	// actor has a flight recorder, see actor.WithFlightRecorder.
+	LastMessages []Message `json:"lastMessages,omitempty"`
+}
+
+// Message is a message in the flight recorder of an actor.
+type Message struct {
+	Type      string    `json:"type"`
+	Sender    string    `json:"sender,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Duration  string    `json:"duration"`
+	Summary   string    `json:"summary,omitempty"`
+}
+
+func newMessages(records []actor.MessageRecord) []Message {
+	messages := make([]Message, len(records))
+	for i, r := range records {
+		messages[i] = Message{
+			Type:      r.Type,
+			Timestamp: r.Timestamp,
+			Duration:  r.Duration.String(),
+			Summary:   r.Summary,
+		}
+		if r.Sender != nil {
+			messages[i].Sender = r.Sender.String()
+		}
+	}
+	return messages
+}
+
+// Cluster is the topology of the cluster.
+type Cluster struct {
+	ID      string            `json:"id"`
+	Leader  string            `json:"leader,omitempty"`
+	Members []*cluster.Member `json:"members"`
+}
+
+// Dashboard samples the processes of an engine and records its restarts.
+// Close it to stop.
+type Dashboard struct {
+	engine *actor.Engine
+	config Config
+	sub    actor.Subscription
+	done   chan struct{}
+
+	mu       sync.Mutex
+	rates    map[string]float64
+	counts   map[string]uint64
+	sampled  time.Time
+	restarts []Restart
+}
+
+// New returns a Dashboard of the given engine.
+func New(e *actor.Engine, config Config) *Dashboard {
+	d := &Dashboard{
+		engine:  e,
+		config:  config,
+		done:    make(chan struct{}),
+		rates:   make(map[string]float64),
+		counts:  make(map[string]uint64),
+		sampled: time.Now(),
+	}
+	d.sub = actor.SubscribeTyped(e, d.restarted)
+	go d.sample()
+	return d
+}
+
+// Close stops sampling the processes and recording the restarts.
+func (d *Dashboard) Close() {
+	d.sub.Unsubscribe()
+	close(d.done)
+}
+
+// Handler returns the HTTP handler that serves the dashboard at its root and
+// the JSON API under /api, to mount on a server.
+//
+//	GET  /api/config               the address of the engine and whether the
+//	                               commands are enabled
+//	GET  /api/actors               the tree of the actors
+//	GET  /api/actors/messages/{id} the last messages of the actor with the
+//	                               given ID, see actor.WithFlightRecorder
+//	GET  /api/restarts             the recent restarts, newest first
+//	GET  /api/flows                the graph of the messages between the
+//	                               actors, see actor.Engine.FlowHandler
+//	GET  /api/remote               the peers of the remote
+//	GET  /api/cluster              the members of the cluster
+//	POST /api/actors/stop/{id}     poisons the actor with the given ID
+//	POST /api/actors/restart/{id}  restarts the actor with the given ID
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(index)
+	})
+	mux.HandleFunc("GET /api/config", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"address": d.engine.Address(), "commands": d.config.commands})
+	})
+	mux.HandleFunc("GET /api/actors", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, d.Actors())
+	})
+	mux.HandleFunc("GET /api/actors/messages/{id...}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, newMessages(d.engine.LastMessages(actor.NewPID(d.engine.Address(), r.PathValue("id")))))
+	})
+	mux.HandleFunc("GET /api/restarts", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, d.Restarts())
+	})
+	mux.Handle("GET /api/flows", d.engine.FlowHandler())
+	mux.HandleFunc("GET /api/remote", func(w http.ResponseWriter, r *http.Request) {
+		peers := []remote.PeerMetrics{}
+		if d.config.remote != nil {
+			peers = d.config.remote.Metrics()
+		}
+		writeJSON(w, peers)
+	})
+	mux.HandleFunc("GET /api/cluster", func(w http.ResponseWriter, r *http.Request) {
+		if d.config.cluster == nil {
+			http.Error(w, "no cluster", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, d.Cluster())
+	})
+	mux.HandleFunc("POST /api/actors/stop/{id...}", d.command(func(pid *actor.PID) { d.engine.Poison(pid) }))
+	mux.HandleFunc("POST /api/actors/restart/{id...}", d.command(d.engine.Restart))
+	return mux
+}
+
+// command returns the handler that invokes fn with the PID of the local actor
+// with the ID of the path.
+func (d *Dashboard) command(fn func(pid *actor.PID)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !d.config.commands {
+			http.Error(w, "commands are disabled", http.StatusForbidden)
+			return
+		}
+		pid := actor.NewPID(d.engine.Address(), r.PathValue("id"))
+		if _, ok := d.engine.ProcessInfo(pid); !ok {
+			http.Error(w, fmt.Sprintf("no actor %s", pid), http.StatusNotFound)
+			return
+		}
+		fn(pid)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// Actors returns the tree of the local actors: the actors that were spawned
+// by the engine, with their children, sorted by ID.
+func (d *Dashboard) Actors() []*Actor {
+	infos := d.engine.Processes()
+	d.mu.Lock()
+	actors := make(map[string]*Actor, len(infos))
+	for _, info := range infos {
+		actors[info.PID.ID] = &Actor{
+			PID:        info.PID.String(),
+			ID:         info.PID.ID,
+			Kind:       info.Kind,
+			Mailbox:    info.MailboxLen,
+			Messages:   info.Messages,
+			Rate:       d.rates[info.PID.ID],
+			LastActive: info.LastActive,
+		}
+	}
+	d.mu.Unlock()
+	roots := []*Actor{}
+	for _, a := range actors {
+		// The kind of a child is the ID of its parent and its name.
+		if i := strings.LastIndexByte(a.Kind, '/'); i >= 0 {
+			if parent, ok := actors[a.Kind[:i]]; ok {
+				parent.Children = append(parent.Children, a)
+				continue
+			}
+		}
+		roots = append(roots, a)
+	}
+	for _, a := range actors {
+		sortActors(a.Children)
+	}
+	sortActors(roots)
+	return roots
+}
+
+func sortActors(actors []*Actor) {
+	slices.SortFunc(actors, func(a, b *Actor) int { return cmp.Compare(a.ID, b.ID) })
+}
+
+// Restarts returns the recent restarts of the actors, newest first.
+func (d *Dashboard) Restarts() []Restart {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	restarts := make([]Restart, len(d.restarts))
+	for i, r := range d.restarts {
+		restarts[len(restarts)-1-i] = r
+	}
+	return restarts
+}
+
+// Cluster returns the topology of the cluster, see Config.WithCluster.
+func (d *Dashboard) Cluster() Cluster {
+	c := d.config.cluster
+	topology := Cluster{ID: c.ID(), Members: c.Members()}
+	if leader := c.Leader(); leader != nil {
+		topology.Leader = leader.ID
+	}
+	return topology
+}
+
+func (d *Dashboard) restarted(event actor.ActorRestartedEvent) {
+	info, _ := d.engine.ProcessInfo(event.PID)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.restarts = append(d.restarts, Restart{
+		PID:          event.PID.String(),
+		Kind:         info.Kind,
+		Timestamp:    event.Timestamp,
+		Reason:       fmt.Sprint(event.Reason),
+		Restarts:     event.Restarts,
+		LastMessages: newMessages(event.LastMessages),
+	})
+	if n := len(d.restarts) - d.config.restarts; n > 0 {
+		d.restarts = slices.Delete(d.restarts, 0, n)
+	}
+}
+
+// sample measures the message rates of the actors every interval.
+func (d *Dashboard) sample() {
+	ticker := time.NewTicker(d.config.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.done:
+			return
+		case now := <-ticker.C:
+			infos := d.engine.Processes()
+			d.mu.Lock()
+			elapsed := now.Sub(d.sampled).Seconds()
+			counts := make(map[string]uint64, len(infos))
+			clear(d.rates)
+			for _, info := range infos {
+				id := info.PID.ID
+				counts[id] = info.Messages
+				if prev, ok := d.counts[id]; ok && info.Messages >= prev {
+					d.rates[id] = float64(info.Messages-prev) / elapsed
+				}
+			}
+			d.counts, d.sampled = counts, now
+			d.mu.Unlock()
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,211 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ping struct{}
+
+func receive(c *actor.Context) {
+	switch c.Message().(type) {
+	case actor.Started:
+		c.SpawnChildFunc(func(*actor.Context) {}, "child", actor.WithID("1"))
+	case ping:
+		c.Respond(ping{})
+	}
+}
+
+func find(actors []*Actor, id string) *Actor {
+	for _, a := range actors {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+func get[T any](t *testing.T, srv *httptest.Server, path string) T {
+	res, err := http.Get(srv.URL + path)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var v T
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&v))
+	return v
+}
+
+func TestActors(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	d := New(e, NewConfig().WithInterval(10*time.Millisecond))
+	defer d.Close()
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	pid := e.SpawnFunc(receive, "pinger", actor.WithID("1"))
+	for range 3 {
+		_, err := e.Request(pid, ping{}, time.Second).Result()
+		require.NoError(t, err)
+	}
+
+	// The engine has an actor for its event stream besides the pinger.
+	pinger := find(get[[]*Actor](t, srv, "/api/actors"), "pinger/1")
+	require.NotNil(t, pinger)
+	assert.Equal(t, uint64(3), pinger.Messages)
+	require.Len(t, pinger.Children, 1)
+	assert.Equal(t, "pinger/1/child/1", pinger.Children[0].ID)
+	assert.Equal(t, "pinger/1/child", pinger.Children[0].Kind)
+
+	res, err := http.Get(srv.URL + "/")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+	assert.Empty(t, get[[]any](t, srv, "/api/remote"))
+	res, err = http.Get(srv.URL + "/api/cluster")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestCommands(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	d := New(e, NewConfig().WithCommands(true))
+	defer d.Close()
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	pid := e.SpawnFunc(receive, "pinger", actor.WithID("1"), actor.WithRestartDelay(time.Millisecond))
+	res, err := http.Post(srv.URL+"/api/actors/restart/pinger/1", "", nil)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Eventually(t, func() bool {
+		restarts := d.Restarts()
+		return len(restarts) == 1 && restarts[0].PID == pid.String() &&
+			restarts[0].Reason == actor.ErrRestartRequested.Error() && restarts[0].Kind == "pinger"
+	}, time.Second, 10*time.Millisecond)
+
+	res, err = http.Post(srv.URL+"/api/actors/stop/pinger/1", "", nil)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Eventually(t, func() bool {
+		_, ok := e.ProcessInfo(pid)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+
+	res, err = http.Post(srv.URL+"/api/actors/stop/pinger/1", "", nil)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestLastMessages(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	d := New(e, NewConfig())
+	defer d.Close()
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	pid := e.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(string); ok {
+			panic("crash")
+		}
+	}, "crasher", actor.WithID("1"), actor.WithFlightRecorder(2), actor.WithRestartDelay(time.Millisecond))
+	e.Send(pid, ping{})
+	e.Send(pid, "crash")
+	var restarts []Restart
+	require.Eventually(t, func() bool {
+		restarts = d.Restarts()
+		return len(restarts) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Len(t, restarts[0].LastMessages, 2)
+	assert.Equal(t, "dashboard.ping", restarts[0].LastMessages[0].Type)
+	assert.Equal(t, "string", restarts[0].LastMessages[1].Type)
+
+	messages := get[[]Message](t, srv, "/api/actors/messages/crasher/1")
+	require.Len(t, messages, 2)
+	assert.Equal(t, "string", messages[1].Type)
+	assert.True(t, restarts[0].LastMessages[1].Timestamp.Equal(messages[1].Timestamp))
+}
+
+func TestCommandsDisabled(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	d := New(e, NewConfig())
+	defer d.Close()
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	pid := e.SpawnFunc(receive, "pinger", actor.WithID("1"))
+	res, err := http.Post(srv.URL+"/api/actors/stop/pinger/1", "", nil)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	_, ok := e.ProcessInfo(pid)
+	assert.True(t, ok)
+}
+
+func TestFlows(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig().WithFlowSampling(1))
+	require.NoError(t, err)
+	d := New(e, NewConfig())
+	defer d.Close()
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	pong := e.SpawnFunc(receive, "pong")
+	pid := e.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(string); ok {
+			c.Send(pong, ping{})
+		}
+	}, "ping")
+	e.Send(pid, "start")
+	assert.Eventually(t, func() bool {
+		return len(e.Flows().Edges) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	graph := get[actor.FlowGraph](t, srv, "/api/flows")
+	assert.Equal(t, []actor.FlowEdge{{
+		From:     actor.FlowNode{Address: e.Address(), Kind: "ping"},
+		To:       actor.FlowNode{Address: e.Address(), Kind: "pong"},
+		Messages: 1,
+	}}, graph.Edges)
+}
+
+func TestRestartsLimit(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	d := New(e, NewConfig().WithRestarts(2))
+	defer d.Close()
+	for i := range 3 {
+		d.restarted(actor.ActorRestartedEvent{PID: actor.NewPID(e.Address(), "foo"), Restarts: int32(i)})
+	}
+	restarts := d.Restarts()
+	require.Len(t, restarts, 2)
+	assert.Equal(t, int32(2), restarts[0].Restarts)
+	assert.Equal(t, int32(1), restarts[1].Restarts)
+}
+
+func TestRate(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	d := New(e, NewConfig().WithInterval(20*time.Millisecond))
+	defer d.Close()
+	pid := e.SpawnFunc(receive, "pinger")
+	assert.Eventually(t, func() bool {
+		e.Send(pid, ping{})
+		pinger := find(d.Actors(), pid.ID)
+		return pinger != nil && pinger.Rate > 0
+	}, time.Second, 5*time.Millisecond)
+}
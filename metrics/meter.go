@@ -0,0 +1,102 @@
+package metrics
+
+import "sort"
+
+// InstrumentKind is the kind of an Instrument.
+type InstrumentKind int
+
+const (
+	// InstrumentCounter is a counter that only goes up.
+	InstrumentCounter InstrumentKind = iota
+	// InstrumentHistogram is the distribution of the recorded values.
+	InstrumentHistogram
+	// InstrumentGauge is a value that goes up and down.
+	InstrumentGauge
+)
+
+// Instrument describes a metric of an Exporter in a Meter. The names are dotted
+// and start with the namespace, like "hollywood.actor.spawns", and the units
+// follow UCUM, like the instruments of OpenTelemetry.
+type Instrument struct {
+	Name        string
+	Kind        InstrumentKind
+	Unit        string
+	Description string
+}
+
+// Attribute is an attribute of a value of an Instrument.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Meter records the metrics of an Exporter, see Config.WithMeter. It bridges
+// the exporter to a metrics API other than Prometheus, like the one of
+// OpenTelemetry, which pushes the metrics to a collector instead of having
+// them scraped. The values of the counters and the histograms are recorded as
+// they happen. The values of the observed instruments, which are the gauges
+// and the counters of the remote, are looked up with Exporter.Observe from the
+// callback of the observable instruments:
+//
+//	for _, inst := range exporter.Instruments() {
+//		// Create the instruments of the meter of OpenTelemetry by their names.
+//	}
+//	meter.RegisterCallback(func(ctx context.Context, o otelmetric.Observer) error {
+//		exporter.Observe(func(inst metrics.Instrument, v float64, attrs []metrics.Attribute) {
+//			o.ObserveFloat64(observables[inst.Name], v, otelmetric.WithAttributes(convert(attrs)...))
+//		})
+//		return nil
+//	}, observables...)
+type Meter interface {
+	// Add adds the given value to the counter.
+	Add(inst Instrument, value float64, attrs []Attribute)
+	// Record records the given value of the histogram.
+	Record(inst Instrument, value float64, attrs []Attribute)
+}
+
+// Instruments returns the instruments of the exporter, sorted by name.
+func (x *Exporter) Instruments() []Instrument {
+	metrics := []*metric{x.spawns, x.stops, x.restarts, x.deadletters, x.processed, x.duration, x.latency}
+	metrics = append(metrics, x.observedMetrics()...)
+	instruments := make([]Instrument, len(metrics))
+	for i, m := range metrics {
+		instruments[i] = m.Instrument
+	}
+	sort.Slice(instruments, func(i, j int) bool { return instruments[i].Name < instruments[j].Name })
+	return instruments
+}
+
+// Observe invokes fn with the current values of the observed instruments of
+// the exporter, with the attributes of the meter.
+func (x *Exporter) Observe(fn func(inst Instrument, value float64, attrs []Attribute)) {
+	x.collect(func(m *metric, label string, v float64) {
+		fn(m.Instrument, v, x.attributes(m, []string{label}))
+	})
+}
+
+func (x *Exporter) observedMetrics() []*metric {
+	return []*metric{x.actors, x.mailbox, x.peerMsgsSent, x.peerMsgsRecv, x.peerBytesSent, x.peerBytesRecv}
+}
+
+// tagAttributes returns the given tags as attributes, sorted by key.
+func tagAttributes(tags map[string]string) []Attribute {
+	attrs := make([]Attribute, 0, len(tags))
+	for k, v := range tags {
+		attrs = append(attrs, Attribute{Key: k, Value: v})
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
+
+// attributes returns the attributes of a value of the given metric in the
+// meter, which are its labels, the node and the tags.
+func (x *Exporter) attributes(m *metric, labels []string) []Attribute {
+	attrs := make([]Attribute, 0, len(labels)+1+len(x.tags))
+	for i, label := range m.labels {
+		if label != "kind" || !x.config.noKind {
+			attrs = append(attrs, Attribute{Key: label, Value: labels[i]})
+		}
+	}
+	attrs = append(attrs, Attribute{Key: "node", Value: x.node})
+	return append(attrs, x.tags...)
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type value struct {
+	kind  InstrumentKind
+	v     float64
+	attrs []Attribute
+}
+
+type testMeter struct {
+	mu     sync.Mutex
+	values map[string][]value
+}
+
+func (m *testMeter) Add(inst Instrument, v float64, attrs []Attribute) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[inst.Name] = append(m.values[inst.Name], value{inst.Kind, v, attrs})
+}
+
+func (m *testMeter) Record(inst Instrument, v float64, attrs []Attribute) {
+	m.Add(inst, v, attrs)
+}
+
+func (m *testMeter) get(name string) []value {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[name]
+}
+
+func TestMeter(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	meter := &testMeter{values: make(map[string][]value)}
+	x := New(e, NewConfig().WithMeter(meter).WithNode("node-1").WithTags(map[string]string{"region": "eu", "app": "shop"}))
+	defer x.Close()
+
+	pid := e.Spawn(newPinger, "pinger", actor.WithMiddleware(x.Middleware()))
+	_, err = e.Request(pid, ping{}, time.Second).Result()
+	require.NoError(t, err)
+
+	attrs := []Attribute{{"kind", "pinger"}, {"node", "node-1"}, {"app", "shop"}, {"region", "eu"}}
+	assert.Eventually(t, func() bool {
+		for _, spawn := range meter.get("hollywood.actor.spawns") {
+			if assert.ObjectsAreEqual(value{InstrumentCounter, 1, attrs}, spawn) {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return len(meter.get("hollywood.actor.messages.processed")) == 3
+	}, time.Second, 10*time.Millisecond)
+	durations := meter.get("hollywood.actor.message.processing")
+	require.NotEmpty(t, durations)
+	assert.Equal(t, InstrumentHistogram, durations[0].kind)
+
+	var actors float64
+	x.Observe(func(inst Instrument, v float64, attrs []Attribute) {
+		if inst.Name == "hollywood.actors" && attrs[0].Value == "pinger" {
+			actors = v
+		}
+	})
+	assert.Equal(t, 1.0, actors)
+
+	var names []string
+	for _, inst := range x.Instruments() {
+		names = append(names, inst.Name)
+	}
+	assert.Contains(t, names, "hollywood.remote.bytes.sent")
+	assert.IsIncreasing(t, names)
+}
+
+func TestWithoutKindLabel(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	meter := &testMeter{values: make(map[string][]value)}
+	x := New(e, NewConfig().WithMeter(meter).WithKindLabel(false))
+	defer x.Close()
+
+	e.Spawn(newPinger, "pinger")
+	assert.Eventually(t, func() bool {
+		spawns := meter.get("hollywood.actor.spawns")
+		return len(spawns) == 2 && assert.ObjectsAreEqual([]Attribute{{"node", e.Address()}}, spawns[0].attrs)
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,391 @@
+// Package metrics exports the metrics of an engine and of its actors to
+// Prometheus, and to other metrics APIs like OpenTelemetry through a Meter.
+//
+//	exporter := metrics.New(e, metrics.NewConfig())
+//	defer exporter.Close()
+//	pid := e.Spawn(newFoo, "foo", actor.WithMiddleware(exporter.Middleware()))
+//	http.Handle("/metrics", exporter.Handler())
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/remote"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const defaultNamespace = "hollywood"
+
+// defaultLatencyBuckets are the default buckets of the histogram of the time
+// the messages wait in the mailboxes, which is much shorter than the time the
+// actors take to process them, unless they are overloaded.
+var defaultLatencyBuckets = []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5}
+
+// Config holds the configuration of an Exporter.
+type Config struct {
+	namespace string
+	buckets   []float64
+	latency   []float64
+	remote    *remote.Remote
+	meter     Meter
+	node      string
+	tags      map[string]string
+	noKind    bool
+	sampler   *actor.Sampler
+}
+
+// NewConfig returns a Config that is initialized with default values.
+func NewConfig() Config {
+	return Config{
+		namespace: defaultNamespace,
+		buckets:   prometheus.DefBuckets,
+		latency:   defaultLatencyBuckets,
+	}
+}
+
+// WithNamespace set's the namespace that prefixes the names of the metrics.
+//
+// Defaults to "hollywood".
+func (config Config) WithNamespace(namespace string) Config {
+	config.namespace = namespace
+	return config
+}
+
+// WithBuckets set's the buckets, in seconds, of the histogram of the time the
+// actors take to process a message.
+//
+// Defaults to prometheus.DefBuckets.
+func (config Config) WithBuckets(buckets []float64) Config {
+	config.buckets = buckets
+	return config
+}
+
+// WithLatencyBuckets set's the buckets, in seconds, of the histogram of the
+// time the messages wait in the mailboxes of the actors before they are
+// processed.
+//
+// Defaults to 10µs up to 5s.
+func (config Config) WithLatencyBuckets(buckets []float64) Config {
+	config.latency = buckets
+	return config
+}
+
+// WithSampler set's the sampler of the messages whose processing time and
+// mailbox latency are measured by the middleware, which is most of its cost.
+// The processed messages are all counted regardless.
+//
+// Defaults to measuring all the messages.
+func (config Config) WithSampler(sampler actor.Sampler) Config {
+	config.sampler = &sampler
+	return config
+}
+
+// WithRemote set's the remote of the engine, to export the traffic between
+// the remote and its peers.
+//
+// Defaults to nil, which exports no remote metrics.
+func (config Config) WithRemote(r *remote.Remote) Config {
+	config.remote = r
+	return config
+}
+
+// WithMeter set's the meter the metrics are recorded in besides the registry
+// of Prometheus, to export them with another metrics API, like the one of
+// OpenTelemetry.
+//
+// Defaults to nil, which only exports the metrics to Prometheus.
+func (config Config) WithMeter(meter Meter) Config {
+	config.meter = meter
+	return config
+}
+
+// WithNode set's the value of the "node" attribute of the metrics that are
+// recorded in the meter, which tells the engines apart once the metrics are
+// pushed to a collector. Prometheus tells them apart by the target it scrapes.
+//
+// Defaults to the address of the engine.
+func (config Config) WithNode(node string) Config {
+	config.node = node
+	return config
+}
+
+// WithTags set's the labels that are added to all the metrics, like the
+// service or the region of the engine, and the attributes in the meter.
+//
+// Defaults to none.
+func (config Config) WithTags(tags map[string]string) Config {
+	config.tags = tags
+	return config
+}
+
+// WithKindLabel set's whether the metrics of the actors are labeled with the
+// kinds of the actors. Without them, the metrics are totals of all the actors.
+//
+// Defaults to true.
+func (config Config) WithKindLabel(enabled bool) Config {
+	config.noKind = !enabled
+	return config
+}
+
+// Exporter collects the metrics of an engine in a registry of its own, and
+// records them in a Meter if it has one. The actors are labeled with their
+// kind, and the children with the name they were spawned with, so the number
+// of series doesn't grow with the number of actors.
+//
+// The lifecycle of the actors and the deadletters are counted from the event
+// stream, the number of actors and their mailboxes are looked up when the
+// metrics are scraped. The messages are only counted for the actors that are
+// spawned with the middleware of the exporter, see Middleware.
+type Exporter struct {
+	engine   *actor.Engine
+	config   Config
+	registry *prometheus.Registry
+	sub      actor.Subscription
+	node     string
+	tags     []Attribute
+
+	spawns      *metric
+	stops       *metric
+	restarts    *metric
+	deadletters *metric
+	processed   *metric
+	duration    *metric
+	latency     *metric
+
+	actors        *metric
+	mailbox       *metric
+	peerMsgsSent  *metric
+	peerMsgsRecv  *metric
+	peerBytesSent *metric
+	peerBytesRecv *metric
+}
+
+// metric is an instrument of the exporter, in Prometheus and in the meter. The
+// counters and the histograms are recorded, the observed instruments are
+// collected when the metrics are scraped or observed.
+type metric struct {
+	Instrument
+	// labels are the labels of the instrument, like "kind" or "peer".
+	labels    []string
+	counter   *prometheus.CounterVec
+	histogram *prometheus.HistogramVec
+	desc      *prometheus.Desc
+}
+
+// New returns an Exporter of the metrics of the given engine. Close it to stop
+// counting the events of the engine.
+func New(e *actor.Engine, config Config) *Exporter {
+	x := &Exporter{
+		engine:   e,
+		config:   config,
+		registry: prometheus.NewRegistry(),
+		node:     config.node,
+		tags:     tagAttributes(config.tags),
+	}
+	if x.node == "" {
+		x.node = e.Address()
+	}
+	x.spawns = x.counter("actor_spawns_total", "actor.spawns", "{actor}", "Number of actors that were started.", "kind")
+	x.stops = x.counter("actor_stops_total", "actor.stops", "{actor}", "Number of actors that were stopped.", "kind")
+	x.restarts = x.counter("actor_restarts_total", "actor.restarts", "{actor}", "Number of actors that crashed and were restarted.", "kind")
+	x.deadletters = x.counter("deadletters_total", "deadletters", "{message}", "Number of messages that could not be delivered, by the kind of their target and their type.", "kind", "type")
+	x.processed = x.counter("actor_messages_processed_total", "actor.messages.processed", "{message}", "Number of messages the actors processed.", "kind")
+	x.duration = x.histogram("actor_message_processing_seconds", "actor.message.processing", "s", "Time the actors took to process a message.", x.config.buckets, "kind")
+	x.latency = x.histogram("actor_mailbox_latency_seconds", "actor.mailbox.latency", "s", "Time the messages waited in the mailboxes of the actors.", x.config.latency, "kind")
+	x.actors = x.observed(InstrumentGauge, "actors", "actors", "{actor}", "Number of local actors.", "kind")
+	x.mailbox = x.observed(InstrumentGauge, "actor_mailbox_messages", "actor.mailbox.messages", "{message}", "Number of messages that wait in the mailboxes of the actors.", "kind")
+	x.peerMsgsSent = x.observed(InstrumentCounter, "remote_messages_sent_total", "remote.messages.sent", "{message}", "Number of messages sent to a peer.", "peer")
+	x.peerMsgsRecv = x.observed(InstrumentCounter, "remote_messages_received_total", "remote.messages.received", "{message}", "Number of messages received from a peer.", "peer")
+	x.peerBytesSent = x.observed(InstrumentCounter, "remote_bytes_sent_total", "remote.bytes.sent", "By", "Number of payload bytes sent to a peer.", "peer")
+	x.peerBytesRecv = x.observed(InstrumentCounter, "remote_bytes_received_total", "remote.bytes.received", "By", "Number of payload bytes received from a peer.", "peer")
+	x.registry.MustRegister(x.spawns.counter, x.stops.counter, x.restarts.counter, x.deadletters.counter,
+		x.processed.counter, x.duration.histogram, x.latency.histogram, collector{x})
+	x.sub = actor.SubscribeTyped(e, x.observe, actor.WithTopics("actor.lifecycle.*", "actor.deadletter"))
+	return x
+}
+
+func (x *Exporter) instrument(kind InstrumentKind, name, unit, help string) Instrument {
+	return Instrument{Name: x.config.namespace + "." + name, Kind: kind, Unit: unit, Description: help}
+}
+
+func (x *Exporter) counter(prom, name, unit, help string, labels ...string) *metric {
+	return &metric{
+		Instrument: x.instrument(InstrumentCounter, name, unit, help),
+		labels:     labels,
+		counter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   x.config.namespace,
+			Name:        prom,
+			Help:        help,
+			ConstLabels: x.config.tags,
+		}, labels),
+	}
+}
+
+func (x *Exporter) histogram(prom, name, unit, help string, buckets []float64, labels ...string) *metric {
+	return &metric{
+		Instrument: x.instrument(InstrumentHistogram, name, unit, help),
+		labels:     labels,
+		histogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   x.config.namespace,
+			Name:        prom,
+			Help:        help,
+			ConstLabels: x.config.tags,
+			Buckets:     buckets,
+		}, labels),
+	}
+}
+
+func (x *Exporter) observed(kind InstrumentKind, prom, name, unit, help, label string) *metric {
+	return &metric{
+		Instrument: x.instrument(kind, name, unit, help),
+		labels:     []string{label},
+		desc:       prometheus.NewDesc(x.config.namespace+"_"+prom, help, []string{label}, x.config.tags),
+	}
+}
+
+// add adds the given value to the counter with the given values of its
+// labels.
+func (x *Exporter) add(m *metric, v float64, labels ...string) {
+	m.counter.WithLabelValues(labels...).Add(v)
+	if x.config.meter != nil {
+		x.config.meter.Add(m.Instrument, v, x.attributes(m, labels))
+	}
+}
+
+// record records the given value of the histogram with the given value of its
+// labels.
+func (x *Exporter) record(m *metric, v float64, labels ...string) {
+	m.histogram.WithLabelValues(labels...).Observe(v)
+	if x.config.meter != nil {
+		x.config.meter.Record(m.Instrument, v, x.attributes(m, labels))
+	}
+}
+
+// kindOf returns the label of the actor with the given PID, empty when the
+// metrics are not labeled with the kinds.
+func (x *Exporter) kindOf(pid *actor.PID) string {
+	if x.config.noKind {
+		return ""
+	}
+	return kindOf(pid)
+}
+
+// Registry returns the registry of the metrics, to register more collectors
+// or to gather the metrics with another handler.
+func (x *Exporter) Registry() *prometheus.Registry {
+	return x.registry
+}
+
+// Handler returns the HTTP handler that serves the metrics in the Prometheus
+// format, to mount on a server.
+func (x *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(x.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware returns the middleware that counts the messages an actor
+// processes, and measures how long they waited in its mailbox and how long it
+// takes to process them, see actor.WithMiddleware and Config.WithSampler. The
+// histograms of the kinds add up to the ones of the engine:
+//
+//	histogram_quantile(0.99, sum by (le) (rate(hollywood_actor_mailbox_latency_seconds_bucket[5m])))
+func (x *Exporter) Middleware() actor.MiddlewareFunc {
+	return func(next actor.ReceiveFunc) actor.ReceiveFunc {
+		return func(ctx *actor.Context) {
+			kind := x.kindOf(ctx.PID())
+			if x.config.sampler != nil && !x.config.sampler.Sample(kindOf(ctx.PID()), ctx.Message()) {
+				next(ctx)
+				x.add(x.processed, 1, kind)
+				return
+			}
+			if latency := ctx.MailboxLatency(); latency > 0 {
+				x.record(x.latency, latency.Seconds(), kind)
+			}
+			start := time.Now()
+			next(ctx)
+			x.record(x.duration, time.Since(start).Seconds(), kind)
+			x.add(x.processed, 1, kind)
+		}
+	}
+}
+
+// Close stops counting the events of the engine.
+func (x *Exporter) Close() {
+	x.sub.Unsubscribe()
+}
+
+func (x *Exporter) observe(event any) {
+	switch event := event.(type) {
+	case actor.ActorStartedEvent:
+		x.add(x.spawns, 1, x.kindOf(event.PID))
+	case actor.ActorStoppedEvent:
+		x.add(x.stops, 1, x.kindOf(event.PID))
+	case actor.ActorRestartedEvent:
+		x.add(x.restarts, 1, x.kindOf(event.PID))
+	case actor.DeadLetterEvent:
+		x.add(x.deadletters, 1, x.kindOf(event.Target), fmt.Sprintf("%T", event.Message))
+	}
+}
+
+// collect invokes fn with the values of the observed instruments and the
+// values of their label.
+func (x *Exporter) collect(fn func(m *metric, label string, v float64)) {
+	actors := make(map[string]int)
+	mailbox := make(map[string]int)
+	for _, info := range x.engine.Processes() {
+		var kind string
+		if !x.config.noKind {
+			kind = info.Kind[strings.LastIndexByte(info.Kind, '/')+1:]
+		}
+		actors[kind]++
+		mailbox[kind] += info.MailboxLen
+	}
+	for kind, n := range actors {
+		fn(x.actors, kind, float64(n))
+		fn(x.mailbox, kind, float64(mailbox[kind]))
+	}
+	if x.config.remote == nil {
+		return
+	}
+	for _, peer := range x.config.remote.Metrics() {
+		fn(x.peerMsgsSent, peer.Address, float64(peer.MessagesSent))
+		fn(x.peerMsgsRecv, peer.Address, float64(peer.MessagesReceived))
+		fn(x.peerBytesSent, peer.Address, float64(peer.BytesSent))
+		fn(x.peerBytesRecv, peer.Address, float64(peer.BytesReceived))
+	}
+}
+
+// collector collects the metrics that are looked up when they are scraped.
+type collector struct {
+	x *Exporter
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.x.observedMetrics() {
+		ch <- m.desc
+	}
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	c.x.collect(func(m *metric, label string, v float64) {
+		typ := prometheus.GaugeValue
+		if m.Kind == InstrumentCounter {
+			typ = prometheus.CounterValue
+		}
+		ch <- prometheus.MustNewConstMetric(m.desc, typ, v, label)
+	})
+}
+
+// kindOf returns the kind of the actor with the given PID, or the name of a
+// child, which is the part of its ID before the last "/".
+func kindOf(pid *actor.PID) string {
+	id := pid.GetID()
+	if i := strings.LastIndexByte(id, '/'); i >= 0 {
+		id = id[:i]
+	}
+	return id[strings.LastIndexByte(id, '/')+1:]
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ping struct{}
+
+type getChild struct{}
+
+// pinger spawns a child that has no middleware when it's started.
+type pinger struct {
+	child *actor.PID
+}
+
+func (p *pinger) Receive(ctx *actor.Context) {
+	switch ctx.Message().(type) {
+	case actor.Started:
+		p.child = ctx.SpawnChildFunc(func(*actor.Context) {}, "child", actor.WithID("a"))
+	case ping:
+		ctx.Respond(ping{})
+	case getChild:
+		ctx.Respond(p.child)
+	}
+}
+
+func newPinger() actor.Receiver { return &pinger{} }
+
+func TestExporter(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	x := New(e, NewConfig().WithNamespace("test"))
+	defer x.Close()
+
+	pid := e.Spawn(newPinger, "pinger", actor.WithID("1"), actor.WithMiddleware(x.Middleware()))
+	for range 3 {
+		_, err := e.Request(pid, ping{}, time.Second).Result()
+		require.NoError(t, err)
+	}
+	// Initialized, Started and the pings, the children have no middleware.
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(x.processed.counter.WithLabelValues("pinger")) == 5
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, testutil.CollectAndCount(x.duration.histogram))
+	// Initialized and Started were not in the mailbox.
+	assert.Contains(t, gather(t, x), `test_actor_mailbox_latency_seconds_count{kind="pinger"} 3`)
+	res, err := e.Request(pid, getChild{}, time.Second).Result()
+	require.NoError(t, err)
+	child := res.(*actor.PID)
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(x.spawns.counter.WithLabelValues("pinger")) == 1 &&
+			testutil.ToFloat64(x.spawns.counter.WithLabelValues("child")) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	body := gather(t, x)
+	assert.Contains(t, body, `test_actors{kind="pinger"} 1`)
+	assert.Contains(t, body, `test_actors{kind="child"} 1`)
+	assert.Contains(t, body, `test_actor_mailbox_messages{kind="pinger"} 0`)
+
+	<-e.Poison(child).Done()
+	e.Send(child, ping{})
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(x.stops.counter.WithLabelValues("child")) == 1 &&
+			testutil.ToFloat64(x.deadletters.counter.WithLabelValues("child", "metrics.ping")) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSampler(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	x := New(e, NewConfig().WithNamespace("test").WithSampler(actor.NewSampler().WithRatio(0).WithTypeRatio(ping{}, 1)))
+	defer x.Close()
+
+	pid := e.Spawn(newPinger, "pinger", actor.WithMiddleware(x.Middleware()))
+	for range 3 {
+		_, err := e.Request(pid, ping{}, time.Second).Result()
+		require.NoError(t, err)
+	}
+	_, err = e.Request(pid, getChild{}, time.Second).Result()
+	require.NoError(t, err)
+	// All the messages are counted, only the pings are measured.
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(x.processed.counter.WithLabelValues("pinger")) == 6
+	}, time.Second, 10*time.Millisecond)
+	body := gather(t, x)
+	assert.Contains(t, body, `test_actor_message_processing_seconds_count{kind="pinger"} 3`)
+	assert.Contains(t, body, `test_actor_mailbox_latency_seconds_count{kind="pinger"} 3`)
+}
+
+// gather returns the metrics the handler of the exporter serves.
+func gather(t *testing.T, x *Exporter) string {
+	rec := httptest.NewRecorder()
+	x.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestKindOf(t *testing.T) {
+	assert.Equal(t, "foo", kindOf(actor.NewPID("local", "foo/1")))
+	assert.Equal(t, "bar", kindOf(actor.NewPID("local", "foo/1/bar/2")))
+	assert.Equal(t, "foo", kindOf(actor.NewPID("local", "foo")))
+}
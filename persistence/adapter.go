@@ -0,0 +1,93 @@
+package persistence
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+// EventAdapter transforms an event of one version into another, so the
+// persistent actors can evolve the schemas of their events without migrating
+// their journals, see Config.WithUpcasters and Config.WithDowncasters.
+type EventAdapter struct {
+	// from is the type of the events the adapter transforms, or nil for the
+	// raw events of the name.
+	from  reflect.Type
+	name  string
+	adapt func(any) (any, error)
+}
+
+// Adapt returns an adapter that transforms the events of type From with the
+// given function. The old type stays registered with actor.RegisterEvent
+// under its old name, so the stored events can still be decoded.
+//
+//	persistence.Adapt(func(old OrderPlacedV1) OrderPlaced {
+//		return OrderPlaced{ID: old.ID, Currency: "EUR"}
+//	})
+func Adapt[From, To any](fn func(From) To) EventAdapter {
+	return EventAdapter{
+		from: reflect.TypeFor[From](),
+		adapt: func(event any) (any, error) {
+			return fn(event.(From)), nil
+		},
+	}
+}
+
+// AdaptRaw returns an adapter that decodes the stored events of the given
+// name itself, for the old types that were removed from the code. They reach
+// the adapter as the data of an actor.RawEvent.
+func AdaptRaw(name string, fn func(data []byte) (any, error)) EventAdapter {
+	return EventAdapter{
+		name: name,
+		adapt: func(event any) (any, error) {
+			return fn(event.(actor.RawEvent).Data)
+		},
+	}
+}
+
+func (a EventAdapter) matches(event any) bool {
+	if a.from == nil {
+		raw, ok := event.(actor.RawEvent)
+		return ok && raw.Name == a.name
+	}
+	return reflect.TypeOf(event) == a.from
+}
+
+// decodeEvent decrypts the data of an event of the actor with the given
+// persistence ID if there is an encryptor, decodes it and upcasts it with the
+// adapters.
+func decodeEvent(upcasters []EventAdapter, encryptor Encryptor, persistenceID, name string, data []byte) (any, error) {
+	if encryptor != nil {
+		var err error
+		if data, err = encryptor.Decrypt(persistenceID, data); err != nil {
+			return nil, err
+		}
+	}
+	event, err := actor.DecodeEvent(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return adaptEvent(upcasters, event)
+}
+
+// adaptEvent applies the adapters to the event one after the other, as long
+// as one of them matches, so the versions of an event can be chained.
+func adaptEvent(adapters []EventAdapter, event any) (any, error) {
+	for range len(adapters) + 1 {
+		i := 0
+		for ; i < len(adapters); i++ {
+			if adapters[i].matches(event) {
+				break
+			}
+		}
+		if i == len(adapters) {
+			return event, nil
+		}
+		var err error
+		if event, err = adapters[i].adapt(event); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("event adapters loop on %T", event)
+}
@@ -0,0 +1,62 @@
+package persistence
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// depositedV1 is the old version of deposited, in cents.
+type depositedV1 struct{ Cents int }
+
+func init() {
+	actor.RegisterEvent[depositedV1]("persistence.test.Deposited.v1", actor.JSONEventCodec[depositedV1]{})
+}
+
+func TestEventAdapters(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	journal := NewMemoryJournal()
+	require.NoError(t, journal.Append([]Record{
+		// The first version was an unregistered number of cents.
+		{PersistenceID: "account/1", Seq: 1, Name: "persistence.test.Deposited.v0", Data: []byte("300")},
+		{PersistenceID: "account/1", Seq: 2, Name: "persistence.test.Deposited.v1", Data: []byte(`{"Cents":500}`)},
+	}))
+	config := NewConfig().WithJournal(journal).
+		WithUpcasters(
+			AdaptRaw("persistence.test.Deposited.v0", func(data []byte) (any, error) {
+				cents, err := strconv.Atoi(string(data))
+				return depositedV1{Cents: cents}, err
+			}),
+			Adapt(func(old depositedV1) deposited {
+				return deposited{Amount: old.Cents / 100}
+			}),
+		).
+		WithDowncasters(Adapt(func(event deposited) depositedV1 {
+			return depositedV1{Cents: event.Amount * 100}
+		}))
+	pid := e.Spawn(newAccount, "account", actor.WithID("1"), actor.WithMiddleware(Middleware(config)))
+
+	assert.Equal(t, 8, request(t, e, pid, getBalance{}))
+	assert.Equal(t, 10, request(t, e, pid, deposit{Amount: 2}))
+	require.NoError(t, journal.Read("account/1", 3, func(record Record) error {
+		assert.Equal(t, "persistence.test.Deposited.v1", record.Name)
+		assert.JSONEq(t, `{"Cents":200}`, string(record.Data))
+		return nil
+	}))
+}
+
+func TestEventAdaptersLoop(t *testing.T) {
+	adapters := []EventAdapter{
+		Adapt(func(old depositedV1) deposited { return deposited{Amount: old.Cents} }),
+		Adapt(func(event deposited) depositedV1 { return depositedV1{Cents: event.Amount} }),
+	}
+	_, err := adaptEvent(adapters, deposited{Amount: 1})
+	assert.Error(t, err)
+	event, err := adaptEvent(adapters[:1], depositedV1{Cents: 1})
+	require.NoError(t, err)
+	assert.Equal(t, deposited{Amount: 1}, event)
+}
@@ -0,0 +1,144 @@
+package persistence
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// encryptionVersion is the first byte of the data that is encrypted by an
+// AESGCMEncryptor, for a later change of the format.
+const encryptionVersion = 1
+
+// Encryptor encrypts the data of the events and the snapshots before they are
+// stored, see Config.WithEncryptor. The persistence ID of the actor is
+// authenticated with the data, so the data of one actor can't be passed off as
+// the one of another. The names of the events are stored as they are.
+type Encryptor interface {
+	Encrypt(persistenceID string, data []byte) ([]byte, error)
+	Decrypt(persistenceID string, data []byte) ([]byte, error)
+}
+
+// KeyProvider provides the keys of an AESGCMEncryptor, like from a KMS. The ID
+// of a key is stored with the data it encrypted, so the keys can be rotated
+// while the data of the old ones can still be decrypted.
+type KeyProvider interface {
+	// CurrentKey returns the key the data is encrypted with and its ID.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given ID.
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider with a fixed set of keys.
+type StaticKeys struct {
+	// Current is the ID of the key the data is encrypted with.
+	Current string
+	// Keys are the keys by ID, AES keys of 16, 24 or 32 bytes.
+	Keys map[string][]byte
+}
+
+// CurrentKey implements KeyProvider.
+func (k StaticKeys) CurrentKey() (string, []byte, error) {
+	key, err := k.Key(k.Current)
+	return k.Current, key, err
+}
+
+// Key implements KeyProvider.
+func (k StaticKeys) Key(id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", id)
+	}
+	return key, nil
+}
+
+// AESGCMEncryptor is an Encryptor with AES-GCM. The encrypted data is its
+// version, the ID of the key, a random nonce and the sealed data.
+type AESGCMEncryptor struct {
+	keys KeyProvider
+	// aeads caches the ciphers by the ID of their key.
+	aeads sync.Map
+}
+
+// NewAESGCMEncryptor returns an encryptor with the keys of the given
+// provider.
+//
+//	keys := persistence.StaticKeys{Current: "2024-06", Keys: map[string][]byte{"2024-06": key}}
+//	config := persistence.NewConfig().
+//		WithJournal(journal).
+//		WithEncryptor(persistence.NewAESGCMEncryptor(keys))
+func NewAESGCMEncryptor(keys KeyProvider) *AESGCMEncryptor {
+	return &AESGCMEncryptor{keys: keys}
+}
+
+func (e *AESGCMEncryptor) aead(id string, key []byte) (cipher.AEAD, error) {
+	if aead, ok := e.aeads.Load(id); ok {
+		return aead.(cipher.AEAD), nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %q: %w", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	e.aeads.Store(id, aead)
+	return aead, nil
+}
+
+// Encrypt implements Encryptor.
+func (e *AESGCMEncryptor) Encrypt(persistenceID string, data []byte) ([]byte, error) {
+	id, key, err := e.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, fmt.Errorf("encryption key ID %q is longer than 255 bytes", id)
+	}
+	aead, err := e.aead(id, key)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, 2+len(id)+aead.NonceSize()+len(data)+aead.Overhead())
+	b = append(b, encryptionVersion, byte(len(id)))
+	b = append(b, id...)
+	nonce := b[len(b) : len(b)+aead.NonceSize()]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	b = b[:len(b)+aead.NonceSize()]
+	return aead.Seal(b, nonce, data, []byte(persistenceID)), nil
+}
+
+// Decrypt implements Encryptor.
+func (e *AESGCMEncryptor) Decrypt(persistenceID string, data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != encryptionVersion || len(data) < 2+int(data[1]) {
+		return nil, errors.New("data is not encrypted")
+	}
+	id := string(data[2 : 2+data[1]])
+	data = data[2+len(id):]
+	var aead cipher.AEAD
+	if cached, ok := e.aeads.Load(id); ok {
+		aead = cached.(cipher.AEAD)
+	} else {
+		key, err := e.keys.Key(id)
+		if err != nil {
+			return nil, err
+		}
+		if aead, err = e.aead(id, key); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+	b, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(persistenceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with key %q: %w", id, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,74 @@
+package persistence
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAESGCMEncryptor(t *testing.T) {
+	keys := StaticKeys{Current: "1", Keys: map[string][]byte{
+		"1": bytes.Repeat([]byte{1}, 32),
+		"2": bytes.Repeat([]byte{2}, 16),
+	}}
+	enc := NewAESGCMEncryptor(keys)
+	data, err := enc.Encrypt("account/1", []byte("secret"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	// The data of the old key is decrypted after the rotation.
+	keys.Current = "2"
+	enc = NewAESGCMEncryptor(keys)
+	b, err := enc.Decrypt("account/1", data)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(b))
+	rotated, err := enc.Encrypt("account/1", []byte("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(rotated[2:3]))
+
+	// The data is bound to its actor.
+	_, err = enc.Decrypt("account/2", data)
+	assert.Error(t, err)
+	_, err = enc.Decrypt("account/1", []byte("secret"))
+	assert.Error(t, err)
+	_, err = NewAESGCMEncryptor(StaticKeys{Current: "2", Keys: keys.Keys}).Decrypt("account/1",
+		append([]byte{encryptionVersion, 1, '3'}, data[3:]...))
+	assert.ErrorContains(t, err, `unknown encryption key "3"`)
+}
+
+func TestEncryptedPersistence(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	journal := NewMemoryJournal()
+	snapshots := NewMemorySnapshotStore()
+	keys := StaticKeys{Current: "1", Keys: map[string][]byte{"1": bytes.Repeat([]byte{1}, 32)}}
+	config := NewConfig().
+		WithJournal(journal).
+		WithSnapshotStore(snapshots).
+		WithSnapshotEvery(2).
+		WithEncryptor(NewAESGCMEncryptor(keys))
+	spawn := func() *actor.PID {
+		return e.Spawn(func() actor.Receiver { return &snapshotAccount{} }, "account", actor.WithID("1"),
+			actor.WithMiddleware(Middleware(config)))
+	}
+
+	pid := spawn()
+	for _, amount := range []int{10, 20, 30} {
+		request(t, e, pid, deposit{Amount: amount})
+	}
+	<-e.Poison(pid).Done()
+	require.NoError(t, journal.Read("account/1", 1, func(record Record) error {
+		assert.NotContains(t, string(record.Data), "Amount")
+		return nil
+	}))
+	snapshot, ok, err := snapshots.Load("account/1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.NotContains(t, string(snapshot.Data), "Amount")
+
+	pid = spawn()
+	assert.Equal(t, 60, request(t, e, pid, getBalance{}))
+}
@@ -0,0 +1,75 @@
+package persistence
+
+import (
+	"sync"
+	"time"
+)
+
+// Faults injects faults into the memory stores, to test how the persistent
+// actors handle a store that fails or is slow, without a database.
+//
+//	journal := persistence.NewMemoryJournal()
+//	journal.FailNextWrite(errors.New("disk full"))
+//	// The next PersistEvent fails, the ones after it succeed.
+type Faults struct {
+	mu sync.Mutex
+	// writes and reads hold the errors of the next writes and reads.
+	writes  []error
+	reads   []error
+	latency time.Duration
+}
+
+// FailNextWrite makes the next write of the store fail with the given error.
+// Each call fails one more write.
+func (f *Faults) FailNextWrite(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes = append(f.writes, err)
+}
+
+// FailNextRead makes the next read of the store fail with the given error.
+// Each call fails one more read.
+func (f *Faults) FailNextRead(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads = append(f.reads, err)
+}
+
+// SetLatency makes every read and write of the store take at least the given
+// duration, zero for none.
+func (f *Faults) SetLatency(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latency = d
+}
+
+// Reset removes the faults.
+func (f *Faults) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes, f.reads, f.latency = nil, nil, 0
+}
+
+func (f *Faults) write() error {
+	return f.inject(&f.writes)
+}
+
+func (f *Faults) read() error {
+	return f.inject(&f.reads)
+}
+
+// inject waits for the latency, and returns the next error of the given ones.
+func (f *Faults) inject(errs *[]error) error {
+	f.mu.Lock()
+	latency := f.latency
+	var err error
+	if len(*errs) > 0 {
+		err = (*errs)[0]
+		*errs = (*errs)[1:]
+	}
+	f.mu.Unlock()
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	return err
+}
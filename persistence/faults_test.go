@@ -0,0 +1,41 @@
+package persistence
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaults(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	journal := NewMemoryJournal()
+	config := NewConfig().WithJournal(journal)
+	spawn := func() *actor.PID {
+		return e.Spawn(newAccount, "account", actor.WithID("1"), actor.WithMiddleware(Middleware(config)),
+			actor.WithRestartDelay(time.Millisecond))
+	}
+
+	pid := spawn()
+	journal.FailNextWrite(errors.New("disk full"))
+	_, err = e.Request(pid, deposit{Amount: 10}, time.Second).Result()
+	assert.ErrorContains(t, err, "disk full")
+	assert.Equal(t, 5, request(t, e, pid, deposit{Amount: 5}))
+
+	journal.SetLatency(20 * time.Millisecond)
+	start := time.Now()
+	assert.Equal(t, 6, request(t, e, pid, deposit{Amount: 1}))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	journal.Reset()
+	<-e.Poison(pid).Done()
+
+	// The actor is restarted when it fails to recover, and recovers the
+	// second time.
+	journal.FailNextRead(errors.New("connection reset"))
+	pid = spawn()
+	assert.Equal(t, 6, request(t, e, pid, getBalance{}))
+}
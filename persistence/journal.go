@@ -0,0 +1,160 @@
+package persistence
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrConflict is returned by Journal.Append if the events don't follow the
+// last event of their actor, because another writer appended to it.
+var ErrConflict = errors.New("sequence number conflict in journal")
+
+// Record is an event of an actor that is stored in a journal.
+type Record struct {
+	PersistenceID string
+	// Seq numbers the events of the actor, from 1.
+	Seq  uint64
+	Time time.Time
+	// Name is the name the type of the event is registered with by
+	// actor.RegisterEvent, and Data the encoded event.
+	Name string
+	Data []byte
+	// Tags are the tags of the event, see Config.WithTagger, which the
+	// projections select the events by. They are kept by the journals that
+	// implement OrderedJournal.
+	Tags []string
+}
+
+// Journal stores the events of the persistent actors, with the events of
+// each actor in a stream of its own.
+type Journal interface {
+	// Append stores the records of an actor at the end of its stream. The
+	// first record needs to follow the last one of the stream, else the
+	// records are not stored and ErrConflict is returned.
+	Append(records []Record) error
+	// Read invokes the given function with the records of the actor from the
+	// given sequence number on, in order, until the end of its stream or the
+	// function returns an error.
+	Read(persistenceID string, from uint64, fn func(Record) error) error
+}
+
+// OrderedJournal is a Journal that also reads the records of all the actors
+// in the order they were appended, which the projections tail. Each record
+// has an offset in that order, from 1.
+type OrderedJournal interface {
+	Journal
+	// ReadAll invokes the given function with the records of all the actors
+	// from the given offset on, in order, until the end of the journal or the
+	// function returns an error.
+	ReadAll(from uint64, fn func(offset uint64, record Record) error) error
+}
+
+// MemoryJournal is an OrderedJournal that keeps the records in memory. Its
+// Faults fail its reads and writes for testing.
+type MemoryJournal struct {
+	Faults
+	mu      sync.RWMutex
+	streams map[string][]Record
+	// all holds the records of all the streams, by offset minus one. The
+	// deleted records are zero.
+	all []Record
+}
+
+// NewMemoryJournal returns an empty MemoryJournal.
+func NewMemoryJournal() *MemoryJournal {
+	return &MemoryJournal{streams: make(map[string][]Record)}
+}
+
+// Append implements Journal.
+func (j *MemoryJournal) Append(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := j.write(); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	id := records[0].PersistenceID
+	stream := j.streams[id]
+	var next uint64 = 1
+	if len(stream) > 0 {
+		next = stream[len(stream)-1].Seq + 1
+	}
+	for _, record := range records {
+		if record.PersistenceID != id || record.Seq != next {
+			return ErrConflict
+		}
+		record.Data = slices.Clone(record.Data)
+		record.Tags = slices.Clone(record.Tags)
+		stream = append(stream, record)
+		next++
+	}
+	j.streams[id] = stream
+	j.all = append(j.all, stream[len(stream)-len(records):]...)
+	return nil
+}
+
+// Read implements Journal.
+func (j *MemoryJournal) Read(persistenceID string, from uint64, fn func(Record) error) error {
+	if err := j.read(); err != nil {
+		return err
+	}
+	j.mu.RLock()
+	stream := j.streams[persistenceID]
+	j.mu.RUnlock()
+	for _, record := range stream {
+		if record.Seq < from {
+			continue
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadAll implements OrderedJournal.
+func (j *MemoryJournal) ReadAll(from uint64, fn func(offset uint64, record Record) error) error {
+	if err := j.read(); err != nil {
+		return err
+	}
+	j.mu.RLock()
+	all := j.all
+	j.mu.RUnlock()
+	for i := max(from, 1) - 1; i < uint64(len(all)); i++ {
+		if all[i].PersistenceID == "" {
+			continue
+		}
+		if err := fn(i+1, all[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteEvents implements EventDeleter.
+func (j *MemoryJournal) DeleteEvents(persistenceID string, toSeq uint64) error {
+	if err := j.write(); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	stream := j.streams[persistenceID]
+	i, _ := slices.BinarySearchFunc(stream, toSeq+1, func(record Record, seq uint64) int {
+		return cmp.Compare(record.Seq, seq)
+	})
+	// The records are copied, so the readers keep the stream they read.
+	j.streams[persistenceID] = slices.Clone(stream[i:])
+	all := slices.Clone(j.all)
+	for i, record := range all {
+		if record.PersistenceID == persistenceID && record.Seq <= toSeq {
+			all[i] = Record{}
+		}
+	}
+	j.all = all
+	return nil
+}
@@ -0,0 +1,282 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// KV is an embedded, ordered key-value store, like bbolt or Badger, that a
+// KVStore keeps the journal and the snapshots in. The package does not depend
+// on a store, a few lines wrap the one of your choice:
+//
+//	type boltKV struct{ db *bbolt.DB }
+//
+//	func (kv boltKV) Put(pairs ...persistence.KVPair) error {
+//		return kv.db.Update(func(tx *bbolt.Tx) error {
+//			b, err := tx.CreateBucketIfNotExists([]byte("hollywood"))
+//			...
+//			for _, pair := range pairs {
+//				err = b.Put(pair.Key, pair.Value)
+//				...
+type KV interface {
+	// Get returns the value of the key, or nil if there is none.
+	Get(key []byte) ([]byte, error)
+	// Put stores all the pairs, or none of them.
+	Put(pairs ...KVPair) error
+	// Scan invokes the given function with the pairs from the start key up to
+	// the end key, which is excluded, in the order of the keys, until the
+	// function returns an error.
+	Scan(start, end []byte, fn func(key, value []byte) error) error
+	// Sync commits the pairs to stable storage.
+	Sync() error
+}
+
+// KVPair is a key and its value in a KV.
+type KVPair struct {
+	Key   []byte
+	Value []byte
+}
+
+// KVConfig holds the configuration of a KVStore.
+type KVConfig struct {
+	syncOnWrite bool
+}
+
+// NewKVConfig returns a KVConfig that is initialized with default values.
+func NewKVConfig() KVConfig {
+	return KVConfig{syncOnWrite: true}
+}
+
+// WithSyncOnWrite set's whether the store syncs the KV after every append and
+// snapshot. Turning it off saves the writes of the flash storage of an edge
+// device, at the cost of the events of the last moments before a power loss.
+//
+// Defaults to true.
+func (config KVConfig) WithSyncOnWrite(sync bool) KVConfig {
+	config.syncOnWrite = sync
+	return config
+}
+
+// KVStore is an OrderedJournal, a SnapshotStore and an OffsetStore in an
+// embedded key-value store, for the devices where even SQLite is too heavy.
+// The store needs to be used by a single process.
+type KVStore struct {
+	kv     KV
+	config KVConfig
+	// mu makes reading the last sequence number or offset and appending
+	// atomic.
+	mu sync.Mutex
+}
+
+// NewKVStore returns a store in the given KV.
+func NewKVStore(kv KV, config KVConfig) *KVStore {
+	return &KVStore{kv: kv, config: config}
+}
+
+// The keys of an actor start with a prefix for the kind of the key and its
+// persistence ID, and end with a big endian sequence number, so they are
+// ordered by it.
+const (
+	kvRecordPrefix     = 'j'
+	kvSnapshotPrefix   = 's'
+	kvLastSeqPrefix    = 'l'
+	kvProjectionPrefix = 'p'
+)
+
+// The records of all the actors are indexed by their offset, with the key of
+// the record as the value, and the last offset is kept in its own key.
+const (
+	kvOffsetPrefix  = 'o'
+	kvLastOffsetKey = "n"
+)
+
+// kvReadAllBatch is the number of records ReadAll reads after a scan.
+const kvReadAllBatch = 256
+
+// errStopScan stops a scan of the KV before its end key.
+var errStopScan = errors.New("stop scan")
+
+func kvOffsetKey(offset uint64) []byte {
+	return binary.BigEndian.AppendUint64([]byte{kvOffsetPrefix}, offset)
+}
+
+func kvKey(prefix byte, persistenceID string, seq uint64) []byte {
+	key := make([]byte, 0, len(persistenceID)+10)
+	key = append(key, prefix)
+	key = append(key, persistenceID...)
+	key = append(key, 0)
+	return binary.BigEndian.AppendUint64(key, seq)
+}
+
+// kvEnd returns the key right after the keys of the actor with the prefix.
+func kvEnd(prefix byte, persistenceID string) []byte {
+	key := make([]byte, 0, len(persistenceID)+2)
+	key = append(key, prefix)
+	key = append(key, persistenceID...)
+	return append(key, 1)
+}
+
+func kvEncode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func kvDecode(b []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+// Append implements Journal.
+func (s *KVStore) Append(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := records[0].PersistenceID
+	lastKey := kvKey(kvLastSeqPrefix, id, 0)
+	last, err := s.kv.Get(lastKey)
+	if err != nil {
+		return err
+	}
+	var next uint64 = 1
+	if last != nil {
+		next = binary.BigEndian.Uint64(last) + 1
+	}
+	lastOffset, err := s.kv.Get([]byte(kvLastOffsetKey))
+	if err != nil {
+		return err
+	}
+	var offset uint64
+	if lastOffset != nil {
+		offset = binary.BigEndian.Uint64(lastOffset)
+	}
+	pairs := make([]KVPair, 0, 2*len(records)+2)
+	for _, record := range records {
+		if record.PersistenceID != id || record.Seq != next {
+			return ErrConflict
+		}
+		b, err := kvEncode(record)
+		if err != nil {
+			return err
+		}
+		key := kvKey(kvRecordPrefix, id, record.Seq)
+		offset++
+		pairs = append(pairs, KVPair{Key: key, Value: b}, KVPair{Key: kvOffsetKey(offset), Value: key})
+		next++
+	}
+	pairs = append(pairs,
+		KVPair{Key: lastKey, Value: binary.BigEndian.AppendUint64(nil, next-1)},
+		KVPair{Key: []byte(kvLastOffsetKey), Value: binary.BigEndian.AppendUint64(nil, offset)})
+	return s.put(pairs)
+}
+
+func (s *KVStore) put(pairs []KVPair) error {
+	if err := s.kv.Put(pairs...); err != nil {
+		return err
+	}
+	if s.config.syncOnWrite {
+		return s.kv.Sync()
+	}
+	return nil
+}
+
+// Read implements Journal.
+func (s *KVStore) Read(persistenceID string, from uint64, fn func(Record) error) error {
+	start := kvKey(kvRecordPrefix, persistenceID, from)
+	return s.kv.Scan(start, kvEnd(kvRecordPrefix, persistenceID), func(_, value []byte) error {
+		var record Record
+		if err := kvDecode(value, &record); err != nil {
+			return err
+		}
+		return fn(record)
+	})
+}
+
+// ReadAll implements OrderedJournal. The keys of the records are scanned in
+// batches, and the records read after each scan, so the KV is not read from
+// within a scan.
+func (s *KVStore) ReadAll(from uint64, fn func(offset uint64, record Record) error) error {
+	from = max(from, 1)
+	for {
+		var offsets []uint64
+		var keys [][]byte
+		err := s.kv.Scan(kvOffsetKey(from), []byte{kvOffsetPrefix + 1}, func(key, value []byte) error {
+			offsets = append(offsets, binary.BigEndian.Uint64(key[1:]))
+			keys = append(keys, slices.Clone(value))
+			if len(keys) == kvReadAllBatch {
+				return errStopScan
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopScan) {
+			return err
+		}
+		for i, key := range keys {
+			b, err := s.kv.Get(key)
+			if err != nil {
+				return err
+			}
+			if b == nil {
+				// The record was deleted.
+				continue
+			}
+			var record Record
+			if err := kvDecode(b, &record); err != nil {
+				return err
+			}
+			if err := fn(offsets[i], record); err != nil {
+				return err
+			}
+		}
+		if len(keys) < kvReadAllBatch {
+			return nil
+		}
+		from = offsets[len(offsets)-1] + 1
+	}
+}
+
+// Save implements SnapshotStore.
+func (s *KVStore) Save(snapshot Snapshot) error {
+	b, err := kvEncode(snapshot)
+	if err != nil {
+		return err
+	}
+	return s.put([]KVPair{{Key: kvKey(kvSnapshotPrefix, snapshot.PersistenceID, snapshot.Seq), Value: b}})
+}
+
+// Load implements SnapshotStore.
+func (s *KVStore) Load(persistenceID string) (Snapshot, bool, error) {
+	var last []byte
+	err := s.kv.Scan(kvKey(kvSnapshotPrefix, persistenceID, 0), kvEnd(kvSnapshotPrefix, persistenceID),
+		func(_, value []byte) error {
+			last = value
+			return nil
+		})
+	if err != nil || last == nil {
+		return Snapshot{}, false, err
+	}
+	var snapshot Snapshot
+	if err := kvDecode(last, &snapshot); err != nil {
+		return Snapshot{}, false, err
+	}
+	return snapshot, true, nil
+}
+
+// LoadOffset implements OffsetStore.
+func (s *KVStore) LoadOffset(projection string) (uint64, error) {
+	b, err := s.kv.Get(kvKey(kvProjectionPrefix, projection, 0))
+	if err != nil || b == nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// SaveOffset implements OffsetStore.
+func (s *KVStore) SaveOffset(projection string, offset uint64) error {
+	return s.put([]KVPair{{Key: kvKey(kvProjectionPrefix, projection, 0), Value: binary.BigEndian.AppendUint64(nil, offset)}})
+}
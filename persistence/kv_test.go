@@ -0,0 +1,114 @@
+package persistence
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryKV is an ordered KV in memory, which counts its syncs.
+type memoryKV struct {
+	pairs map[string][]byte
+	syncs int
+}
+
+func (kv *memoryKV) Get(key []byte) ([]byte, error) {
+	return kv.pairs[string(key)], nil
+}
+
+func (kv *memoryKV) Put(pairs ...KVPair) error {
+	for _, pair := range pairs {
+		kv.pairs[string(pair.Key)] = slices.Clone(pair.Value)
+	}
+	return nil
+}
+
+func (kv *memoryKV) Scan(start, end []byte, fn func(key, value []byte) error) error {
+	keys := make([]string, 0, len(kv.pairs))
+	for key := range kv.pairs {
+		if bytes.Compare([]byte(key), start) >= 0 && bytes.Compare([]byte(key), end) < 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn([]byte(key), kv.pairs[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (kv *memoryKV) Sync() error {
+	kv.syncs++
+	return nil
+}
+
+func TestKVStore(t *testing.T) {
+	kv := &memoryKV{pairs: make(map[string][]byte)}
+	s := NewKVStore(kv, NewKVConfig())
+	require.NoError(t, s.Append([]Record{
+		{PersistenceID: "a", Seq: 1, Name: "e", Data: []byte{1}},
+		{PersistenceID: "a", Seq: 2, Name: "e", Data: []byte{2}},
+	}))
+	// The events of an actor whose ID starts with the ID of another stay
+	// apart.
+	require.NoError(t, s.Append([]Record{{PersistenceID: "ab", Seq: 1, Name: "e", Data: []byte{3}}}))
+	assert.ErrorIs(t, s.Append([]Record{{PersistenceID: "a", Seq: 2}}), ErrConflict)
+	require.NoError(t, s.Append([]Record{{PersistenceID: "a", Seq: 3, Name: "e", Data: []byte{4}}}))
+
+	var data []byte
+	require.NoError(t, s.Read("a", 2, func(record Record) error {
+		data = append(data, record.Data...)
+		return nil
+	}))
+	assert.Equal(t, []byte{2, 4}, data)
+
+	_, ok, err := s.Load("a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	for _, seq := range []uint64{2, 300} {
+		require.NoError(t, s.Save(Snapshot{PersistenceID: "a", Seq: seq, Name: "state"}))
+	}
+	snapshot, ok, err := s.Load("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, uint64(300), snapshot.Seq)
+	assert.Equal(t, 5, kv.syncs)
+
+	s = NewKVStore(kv, NewKVConfig().WithSyncOnWrite(false))
+	require.NoError(t, s.Append([]Record{{PersistenceID: "a", Seq: 4, Name: "e"}}))
+	assert.Equal(t, 5, kv.syncs)
+}
+
+func TestKVStoreReadAll(t *testing.T) {
+	s := NewKVStore(&memoryKV{pairs: make(map[string][]byte)}, NewKVConfig())
+	require.NoError(t, s.Append([]Record{{PersistenceID: "a", Seq: 1, Name: "e", Tags: []string{"x"}}}))
+	require.NoError(t, s.Append([]Record{{PersistenceID: "b", Seq: 1, Name: "e"}}))
+	require.NoError(t, s.Append([]Record{{PersistenceID: "a", Seq: 2, Name: "e"}}))
+
+	var records []string
+	require.NoError(t, s.ReadAll(2, func(offset uint64, record Record) error {
+		records = append(records, fmt.Sprintf("%d:%s/%d", offset, record.PersistenceID, record.Seq))
+		return nil
+	}))
+	assert.Equal(t, []string{"2:b/1", "3:a/2"}, records)
+	err := s.ReadAll(0, func(offset uint64, record Record) error {
+		assert.Equal(t, []string{"x"}, record.Tags)
+		return errStopScan
+	})
+	assert.ErrorIs(t, err, errStopScan)
+
+	offset, err := s.LoadOffset("p")
+	require.NoError(t, err)
+	assert.Zero(t, offset)
+	require.NoError(t, s.SaveOffset("p", 3))
+	offset, err = s.LoadOffset("p")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), offset)
+}
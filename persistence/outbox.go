@@ -0,0 +1,251 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+const (
+	defaultRedeliverAfter    = time.Second
+	defaultMaxRedeliverDelay = time.Minute
+	defaultDeliverTimeout    = 5 * time.Second
+)
+
+// OutboxConfig holds the configuration of an Outbox.
+type OutboxConfig struct {
+	redeliverAfter    time.Duration
+	maxRedeliverDelay time.Duration
+}
+
+// NewOutboxConfig returns an OutboxConfig that is initialized with default
+// values.
+func NewOutboxConfig() OutboxConfig {
+	return OutboxConfig{
+		redeliverAfter:    defaultRedeliverAfter,
+		maxRedeliverDelay: defaultMaxRedeliverDelay,
+	}
+}
+
+// WithRedeliverAfter set's how long the outbox waits for the confirmation of a
+// message before it delivers it again. The delay doubles with every attempt.
+//
+// Defaults to 1 second.
+func (config OutboxConfig) WithRedeliverAfter(d time.Duration) OutboxConfig {
+	config.redeliverAfter = d
+	return config
+}
+
+// WithMaxRedeliverDelay set's the longest the delay between two attempts to
+// deliver a message grows to.
+//
+// Defaults to 1 minute.
+func (config OutboxConfig) WithMaxRedeliverDelay(d time.Duration) OutboxConfig {
+	config.maxRedeliverDelay = d
+	return config
+}
+
+// Delivery is a message that was delivered by an Outbox. The destination
+// receives it instead of the message itself, and confirms it once it handled
+// the message, else the message is delivered again. As a message can be
+// delivered more than once, its handling needs to be idempotent, for which
+// the ID of the delivery can help.
+type Delivery struct {
+	// ID is unique for the deliveries of the outbox.
+	ID      uint64
+	Message any
+	outbox  *actor.PID
+}
+
+// Confirm lets the outbox know the destination handled the message.
+func (d Delivery) Confirm(c *actor.Context) {
+	c.Send(d.outbox, confirmDelivery{ID: d.ID})
+}
+
+// Outbox delivers messages at least once, even when the process restarts
+// before the destination confirmed them. It's an actor that persists the
+// messages with its journal, and delivers them again with a backoff until
+// they are confirmed.
+type Outbox struct {
+	engine *actor.Engine
+	pid    *actor.PID
+}
+
+// NewOutbox spawns the outbox with the given ID, which recovers the messages
+// the outbox with the same ID did not get confirmed before.
+//
+//	outbox := persistence.NewOutbox(e, config, "payments", persistence.NewOutboxConfig())
+//	err := outbox.DeliverReliably(pid, ChargeCard{OrderID: "1"})
+func NewOutbox(e *actor.Engine, config Config, id string, outboxConfig OutboxConfig) *Outbox {
+	pid := e.Spawn(func() actor.Receiver {
+		return &outbox{config: outboxConfig, pending: make(map[uint64]*pendingDelivery)}
+	}, "outbox", actor.WithID(id), actor.WithMiddleware(Middleware(config)))
+	return &Outbox{engine: e, pid: pid}
+}
+
+// PID returns the PID of the actor of the outbox.
+func (o *Outbox) PID() *actor.PID {
+	return o.pid
+}
+
+// DeliverReliably persists the message and delivers it to the given PID in a
+// Delivery. It returns once the message is persisted, after which it's
+// delivered until it's confirmed. The type of the message needs to be
+// registered with actor.RegisterEvent.
+func (o *Outbox) DeliverReliably(pid *actor.PID, msg any) error {
+	resp, err := o.engine.Request(o.pid, deliver{pid: pid, msg: msg}, defaultDeliverTimeout).Result()
+	if err != nil {
+		return err
+	}
+	if err, ok := resp.(error); ok {
+		return err
+	}
+	return nil
+}
+
+type deliver struct {
+	pid *actor.PID
+	msg any
+}
+
+type confirmDelivery struct {
+	ID uint64
+}
+
+type redeliver struct {
+	id uint64
+}
+
+// deliveryRequested and deliveryConfirmed are the events of the outbox.
+type deliveryRequested struct {
+	ID      uint64
+	Address string
+	PID     string
+	Name    string
+	Data    []byte
+}
+
+type deliveryConfirmed struct {
+	ID uint64
+}
+
+// outboxState is the snapshot of an outbox, its pending deliveries.
+type outboxState struct {
+	Pending []deliveryRequested
+}
+
+func init() {
+	actor.RegisterEvent[deliveryRequested]("hollywood.persistence.DeliveryRequested", actor.JSONEventCodec[deliveryRequested]{})
+	actor.RegisterEvent[deliveryConfirmed]("hollywood.persistence.DeliveryConfirmed", actor.JSONEventCodec[deliveryConfirmed]{})
+	actor.RegisterEvent[outboxState]("hollywood.persistence.OutboxState", actor.JSONEventCodec[outboxState]{})
+}
+
+type pendingDelivery struct {
+	request  deliveryRequested
+	attempts int
+	timer    *time.Timer
+}
+
+type outbox struct {
+	Persistent
+	config  OutboxConfig
+	pending map[uint64]*pendingDelivery
+}
+
+func (o *outbox) Receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.Started:
+		// The deliveries that were not confirmed before the restart.
+		for _, d := range o.pending {
+			o.deliver(c, d)
+		}
+	case actor.Stopped:
+		for _, d := range o.pending {
+			if d.timer != nil {
+				d.timer.Stop()
+			}
+		}
+	case deliver:
+		name, data, err := actor.EncodeEvent(msg.msg)
+		if err != nil {
+			c.Respond(err)
+			return
+		}
+		request := deliveryRequested{
+			ID:      o.LastSeq() + 1,
+			Address: msg.pid.Address,
+			PID:     msg.pid.ID,
+			Name:    name,
+			Data:    data,
+		}
+		if err := o.PersistEvent(request); err != nil {
+			c.Respond(err)
+			return
+		}
+		o.RecoverEvent(request)
+		c.Respond(nil)
+		o.deliver(c, o.pending[request.ID])
+	case confirmDelivery:
+		d, ok := o.pending[msg.ID]
+		if !ok {
+			return
+		}
+		if err := o.PersistEvent(deliveryConfirmed(msg)); err != nil {
+			// The message is delivered again, and confirmed again.
+			return
+		}
+		if d.timer != nil {
+			d.timer.Stop()
+		}
+		o.RecoverEvent(deliveryConfirmed(msg))
+	case redeliver:
+		if d, ok := o.pending[msg.id]; ok {
+			o.deliver(c, d)
+		}
+	}
+}
+
+// deliver sends the message of the delivery, and schedules the next attempt.
+func (o *outbox) deliver(c *actor.Context, d *pendingDelivery) {
+	msg, err := actor.DecodeEvent(d.request.Name, d.request.Data)
+	if err != nil {
+		// The message can't be delivered by this version, it's kept for
+		// the next one.
+		return
+	}
+	pid := actor.NewPID(d.request.Address, d.request.PID)
+	c.Engine().SendWithSender(pid, Delivery{ID: d.request.ID, Message: msg, outbox: c.PID()}, c.PID())
+	delay := min(o.config.redeliverAfter<<d.attempts, o.config.maxRedeliverDelay)
+	if delay <= 0 {
+		// The shift overflowed.
+		delay = o.config.maxRedeliverDelay
+	}
+	d.attempts++
+	self, e, id := c.PID(), c.Engine(), d.request.ID
+	d.timer = time.AfterFunc(delay, func() {
+		e.Send(self, redeliver{id: id})
+	})
+}
+
+func (o *outbox) RecoverEvent(event any) {
+	switch event := event.(type) {
+	case deliveryRequested:
+		o.pending[event.ID] = &pendingDelivery{request: event}
+	case deliveryConfirmed:
+		delete(o.pending, event.ID)
+	}
+}
+
+func (o *outbox) Snapshot() any {
+	state := outboxState{Pending: make([]deliveryRequested, 0, len(o.pending))}
+	for _, d := range o.pending {
+		state.Pending = append(state.Pending, d.request)
+	}
+	return state
+}
+
+func (o *outbox) RecoverSnapshot(state any) {
+	for _, request := range state.(outboxState).Pending {
+		o.pending[request.ID] = &pendingDelivery{request: request}
+	}
+}
@@ -0,0 +1,71 @@
+package persistence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chargeCard struct{ OrderID string }
+
+func init() {
+	actor.RegisterEvent[chargeCard]("persistence.test.ChargeCard", actor.JSONEventCodec[chargeCard]{})
+}
+
+func TestOutbox(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		deliveries = make(chan Delivery, 10)
+		confirm    = make(chan bool, 10)
+	)
+	pid := e.SpawnFunc(func(c *actor.Context) {
+		if d, ok := c.Message().(Delivery); ok {
+			deliveries <- d
+			if <-confirm {
+				d.Confirm(c)
+			}
+		}
+	}, "payments")
+	config := NewConfig().WithJournal(NewMemoryJournal())
+	outboxConfig := NewOutboxConfig().WithRedeliverAfter(20 * time.Millisecond)
+	receive := func() Delivery {
+		select {
+		case d := <-deliveries:
+			return d
+		case <-time.After(time.Second):
+			t.Fatal("the message was not delivered")
+			return Delivery{}
+		}
+	}
+
+	outbox := NewOutbox(e, config, "payments", outboxConfig)
+	require.NoError(t, outbox.DeliverReliably(pid, chargeCard{OrderID: "1"}))
+	d := receive()
+	assert.Equal(t, chargeCard{OrderID: "1"}, d.Message)
+	// The message is delivered again until it's confirmed.
+	confirm <- false
+	assert.Equal(t, d.ID, receive().ID)
+	confirm <- true
+
+	require.NoError(t, outbox.DeliverReliably(pid, chargeCard{OrderID: "2"}))
+	d = receive()
+	assert.Equal(t, chargeCard{OrderID: "2"}, d.Message)
+	// The outbox restarts before the message is confirmed, and delivers it
+	// again once it's back.
+	<-e.Poison(outbox.PID()).Done()
+	confirm <- false
+	NewOutbox(e, config, "payments", outboxConfig)
+	assert.Equal(t, d.ID, receive().ID)
+	confirm <- true
+	select {
+	case d := <-deliveries:
+		t.Fatalf("confirmed delivery %d was delivered again", d.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.ErrorIs(t, outbox.DeliverReliably(pid, struct{}{}), actor.ErrUnregisteredEvent)
+}
@@ -0,0 +1,338 @@
+// Package persistence makes the state of actors durable with event sourcing.
+// A persistent actor embeds Persistent, persists the events that change its
+// state with PersistEvent, and gets them back through RecoverEvent when it's
+// spawned again, before it handles its first message.
+//
+//	type account struct {
+//		persistence.Persistent
+//		balance int
+//	}
+//
+//	func (a *account) Receive(c *actor.Context) {
+//		switch msg := c.Message().(type) {
+//		case deposit:
+//			if err := a.PersistEvent(deposited{Amount: msg.Amount}); err != nil {
+//				return
+//			}
+//			a.RecoverEvent(deposited{Amount: msg.Amount})
+//		}
+//	}
+//
+//	func (a *account) RecoverEvent(event any) {
+//		switch event := event.(type) {
+//		case deposited:
+//			a.balance += event.Amount
+//		}
+//	}
+//
+//	config := persistence.NewConfig().WithJournal(journal)
+//	e.Spawn(newAccount, "account", actor.WithID("1"),
+//		actor.WithMiddleware(persistence.Middleware(config)))
+//
+// The events are encoded with the codecs of their types, which need to be
+// registered with actor.RegisterEvent.
+//
+// An actor with a long history can save snapshots of its state with a
+// SnapshotStore, and recovers from its last snapshot and the events after it.
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+// ErrNotPersistent is returned by PersistEvent if the actor was not spawned
+// with the Middleware of this package.
+var ErrNotPersistent = errors.New("actor is not persistent")
+
+// Config holds the configuration of the persistence of actors.
+type Config struct {
+	journal        Journal
+	snapshots      SnapshotStore
+	snapshotEvery  int
+	recoveryPolicy RecoveryPolicy
+	upcasters      []EventAdapter
+	downcasters    []EventAdapter
+	tagger         func(event any) []string
+	encryptor      Encryptor
+	retention      RetentionConfig
+}
+
+// NewConfig returns a Config that is initialized with default values.
+func NewConfig() Config {
+	return Config{}
+}
+
+// WithJournal set's the journal the events of the actors are persisted in,
+// which is required.
+func (config Config) WithJournal(journal Journal) Config {
+	config.journal = journal
+	return config
+}
+
+// WithSnapshotStore set's the store the snapshots of the actors that
+// implement Snapshotter are saved in.
+func (config Config) WithSnapshotStore(store SnapshotStore) Config {
+	config.snapshots = store
+	return config
+}
+
+// WithSnapshotEvery set's the number of events after which an actor saves a
+// snapshot of its state, once it handled the message it persisted the last
+// of them for.
+//
+// Defaults to 0, which saves no snapshots.
+func (config Config) WithSnapshotEvery(n int) Config {
+	config.snapshotEvery = n
+	return config
+}
+
+// WithRecoveryPolicy set's what an actor does when it fails to recover.
+//
+// Defaults to RecoveryRetry.
+func (config Config) WithRecoveryPolicy(policy RecoveryPolicy) Config {
+	config.recoveryPolicy = policy
+	return config
+}
+
+// WithUpcasters set's the adapters that transform the recovered events and
+// snapshots of the old versions of their types into the current ones. The
+// adapters are chained, so an adapter from the first version to the second
+// and one from the second to the third recover the events of both old
+// versions as the third.
+func (config Config) WithUpcasters(adapters ...EventAdapter) Config {
+	config.upcasters = adapters
+	return config
+}
+
+// WithDowncasters set's the adapters that transform the persisted events of
+// the current versions of their types into older ones, for a rolling upgrade
+// while the actors of the old version still recover from the same journal.
+func (config Config) WithDowncasters(adapters ...EventAdapter) Config {
+	config.downcasters = adapters
+	return config
+}
+
+// WithTagger set's the function that returns the tags of the persisted
+// events, which the projections select the events by, like the name of the
+// aggregate of an event.
+func (config Config) WithTagger(fn func(event any) []string) Config {
+	config.tagger = fn
+	return config
+}
+
+// WithEncryptor set's the encryptor the data of the events and the snapshots
+// is encrypted with before it's stored, for the state with personal data.
+// The events of a journal are all encrypted or none of them.
+func (config Config) WithEncryptor(encryptor Encryptor) Config {
+	config.encryptor = encryptor
+	return config
+}
+
+// WithRetention set's which of the events and the snapshots of the actors are
+// deleted, by a worker in the background, so the journal does not grow
+// forever. The journal needs to implement EventDeleter, and the snapshot store
+// SnapshotDeleter to keep only the last snapshots.
+//
+// Defaults to keeping all of them.
+func (config Config) WithRetention(retention RetentionConfig) Config {
+	config.retention = retention
+	return config
+}
+
+// Recoverer is implemented by the persistent actors, to get their persisted
+// events back in the order they were persisted. The events of a name that is
+// not registered are passed as an actor.RawEvent.
+type Recoverer interface {
+	RecoverEvent(event any)
+}
+
+// Snapshotter is implemented by the persistent actors that save snapshots of
+// their state, see Config.WithSnapshotEvery. The state is encoded like the
+// events, so its type needs to be registered with actor.RegisterEvent.
+type Snapshotter interface {
+	// Snapshot returns the current state of the actor.
+	Snapshot() any
+	// RecoverSnapshot restores the state of the actor from its last
+	// snapshot, before the events after it are recovered.
+	RecoverSnapshot(state any)
+}
+
+// persistent is implemented by the receivers that embed Persistent.
+type persistent interface {
+	persistent() *Persistent
+}
+
+// Persistent is embedded by the receivers of the persistent actors. Its
+// methods are invoked from the Receive of the actor only.
+type Persistent struct {
+	id     string
+	config Config
+	seq    uint64
+	// snapshotSeq is the sequence number of the last snapshot.
+	snapshotSeq uint64
+	// failed is true if the actor stops as it failed to recover, see
+	// RecoveryStop.
+	failed bool
+}
+
+func (p *Persistent) persistent() *Persistent {
+	return p
+}
+
+// PersistenceID returns the ID the events of the actor are persisted under,
+// which is the ID of its PID.
+func (p *Persistent) PersistenceID() string {
+	return p.id
+}
+
+// LastSeq returns the sequence number of the last event the actor persisted or
+// recovered, or 0 if there is none.
+func (p *Persistent) LastSeq() uint64 {
+	return p.seq
+}
+
+// PersistEvent appends the event to the journal of the actor. The actor
+// applies the event to its state itself once it's persisted.
+func (p *Persistent) PersistEvent(event any) error {
+	return p.PersistEvents(event)
+}
+
+// PersistEvents appends the events to the journal of the actor at once, so
+// either all or none of them are persisted.
+func (p *Persistent) PersistEvents(events ...any) error {
+	if p.config.journal == nil {
+		return ErrNotPersistent
+	}
+	now := time.Now()
+	records := make([]Record, len(events))
+	for i, event := range events {
+		var tags []string
+		if p.config.tagger != nil {
+			tags = p.config.tagger(event)
+		}
+		event, err := adaptEvent(p.config.downcasters, event)
+		if err != nil {
+			return err
+		}
+		name, data, err := p.encode(event)
+		if err != nil {
+			return err
+		}
+		records[i] = Record{
+			PersistenceID: p.id,
+			Seq:           p.seq + uint64(i) + 1,
+			Time:          now,
+			Name:          name,
+			Data:          data,
+			Tags:          tags,
+		}
+	}
+	if err := p.config.journal.Append(records); err != nil {
+		return fmt.Errorf("failed to persist the events of %s: %w", p.id, err)
+	}
+	p.seq += uint64(len(records))
+	return nil
+}
+
+// encode encodes an event or a snapshot, and encrypts it.
+func (p *Persistent) encode(event any) (string, []byte, error) {
+	name, data, err := actor.EncodeEvent(event)
+	if err != nil || p.config.encryptor == nil {
+		return name, data, err
+	}
+	data, err = p.config.encryptor.Encrypt(p.id, data)
+	return name, data, err
+}
+
+// saveSnapshot saves a snapshot of the actor if it persisted enough events
+// since the last one.
+func (p *Persistent) saveSnapshot(s Snapshotter) error {
+	if p.config.snapshotEvery <= 0 || p.seq-p.snapshotSeq < uint64(p.config.snapshotEvery) {
+		return nil
+	}
+	name, data, err := p.encode(s.Snapshot())
+	if err != nil {
+		return err
+	}
+	snapshot := Snapshot{
+		PersistenceID: p.id,
+		Seq:           p.seq,
+		Time:          time.Now(),
+		Name:          name,
+		Data:          data,
+	}
+	if err := p.config.snapshots.Save(snapshot); err != nil {
+		return err
+	}
+	p.snapshotSeq = p.seq
+	return nil
+}
+
+// Middleware returns the middleware that makes the actors that embed
+// Persistent persistent. The actors recover when they are initialized, so the
+// messages that are sent to an actor wait in its inbox until it recovered.
+func Middleware(config Config) actor.MiddlewareFunc {
+	if config.journal == nil {
+		panic("persistence: no journal configured")
+	}
+	if config.snapshotEvery > 0 && config.snapshots == nil {
+		panic("persistence: snapshots configured without a snapshot store")
+	}
+	retention := config.retention
+	if _, ok := config.journal.(EventDeleter); !ok && (retention.deleteSnapshotted || retention.maxAge > 0) {
+		panic("persistence: retention of events configured with a journal that can't delete them")
+	}
+	if _, ok := config.snapshots.(SnapshotDeleter); !ok && retention.keepSnapshots > 0 {
+		panic("persistence: retention of snapshots configured with a store that can't delete them")
+	}
+	var (
+		once      sync.Once
+		compactor *actor.PID
+	)
+	return func(next actor.ReceiveFunc) actor.ReceiveFunc {
+		return func(c *actor.Context) {
+			rcv, ok := c.Receiver().(persistent)
+			if !ok {
+				next(c)
+				return
+			}
+			p := rcv.persistent()
+			snapshotter, _ := c.Receiver().(Snapshotter)
+			switch c.Message().(type) {
+			case actor.Initialized:
+				p.id = c.PID().ID
+				p.config = config
+				p.recover(c, snapshotter)
+				if !p.failed && retention.enabled() {
+					once.Do(func() {
+						compactor = c.Engine().Spawn(newCompactor(config), "compactor")
+					})
+					c.Send(compactor, registerCompaction{persistenceID: p.id})
+				}
+			case actor.Stopped:
+				if !p.failed && retention.enabled() {
+					c.Send(compactor, unregisterCompaction{persistenceID: p.id})
+				}
+			}
+			if p.failed {
+				// The actor stops without handling a message.
+				return
+			}
+			next(c)
+			if snapshotter != nil {
+				snapshotSeq := p.snapshotSeq
+				if err := p.saveSnapshot(snapshotter); err != nil {
+					slog.Warn("failed to save snapshot", "err", err, "pid", c.PID(), "seq", p.seq)
+				} else if p.snapshotSeq != snapshotSeq && retention.enabled() {
+					c.Send(compactor, compactSnapshot{persistenceID: p.id, snapshotSeq: p.snapshotSeq})
+				}
+			}
+		}
+	}
+}
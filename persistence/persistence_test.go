@@ -0,0 +1,181 @@
+package persistence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type deposit struct{ Amount int }
+
+type deposited struct{ Amount int }
+
+type getBalance struct{}
+
+func init() {
+	actor.RegisterEvent[deposited]("persistence.test.Deposited", actor.JSONEventCodec[deposited]{})
+}
+
+type account struct {
+	Persistent
+	balance int
+}
+
+func newAccount() actor.Receiver {
+	return &account{}
+}
+
+func (a *account) Receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case deposit:
+		if err := a.PersistEvent(deposited(msg)); err != nil {
+			c.Respond(err)
+			return
+		}
+		a.RecoverEvent(deposited(msg))
+		c.Respond(a.balance)
+	case getBalance:
+		c.Respond(a.balance)
+	}
+}
+
+func (a *account) RecoverEvent(event any) {
+	if event, ok := event.(deposited); ok {
+		a.balance += event.Amount
+	}
+}
+
+func request(t *testing.T, e *actor.Engine, pid *actor.PID, msg any) any {
+	res, err := e.Request(pid, msg, time.Second).Result()
+	require.NoError(t, err)
+	return res
+}
+
+func TestPersistent(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	journal := NewMemoryJournal()
+	spawn := func() *actor.PID {
+		return e.Spawn(newAccount, "account", actor.WithID("1"),
+			actor.WithMiddleware(Middleware(NewConfig().WithJournal(journal))))
+	}
+
+	pid := spawn()
+	assert.Equal(t, 10, request(t, e, pid, deposit{Amount: 10}))
+	assert.Equal(t, 15, request(t, e, pid, deposit{Amount: 5}))
+	<-e.Poison(pid).Done()
+
+	pid = spawn()
+	assert.Equal(t, 15, request(t, e, pid, getBalance{}))
+	assert.Equal(t, 18, request(t, e, pid, deposit{Amount: 3}))
+	var seqs []uint64
+	require.NoError(t, journal.Read("account/1", 2, func(record Record) error {
+		assert.Equal(t, "persistence.test.Deposited", record.Name)
+		seqs = append(seqs, record.Seq)
+		return nil
+	}))
+	assert.Equal(t, []uint64{2, 3}, seqs)
+
+	// The actors spawned without the middleware can't persist.
+	pid = e.Spawn(newAccount, "account", actor.WithID("2"))
+	_, err = e.Request(pid, deposit{Amount: 1}, time.Second).Result()
+	assert.ErrorContains(t, err, ErrNotPersistent.Error())
+}
+
+func TestMemoryJournalConflict(t *testing.T) {
+	j := NewMemoryJournal()
+	require.NoError(t, j.Append([]Record{
+		{PersistenceID: "a", Seq: 1},
+		{PersistenceID: "a", Seq: 2},
+	}))
+	// A second writer that recovered before the append.
+	assert.ErrorIs(t, j.Append([]Record{{PersistenceID: "a", Seq: 2}}), ErrConflict)
+	assert.ErrorIs(t, j.Append([]Record{{PersistenceID: "b", Seq: 2}}), ErrConflict)
+	require.NoError(t, j.Append([]Record{{PersistenceID: "a", Seq: 3}}))
+}
+
+// snapshotAccount saves its balance as a snapshot.
+type snapshotAccount struct {
+	account
+	recovered []any
+}
+
+type balance struct{ Amount int }
+
+func init() {
+	actor.RegisterEvent[balance]("persistence.test.Balance", actor.JSONEventCodec[balance]{})
+}
+
+func (a *snapshotAccount) RecoverEvent(event any) {
+	a.recovered = append(a.recovered, event)
+	a.account.RecoverEvent(event)
+}
+
+func (a *snapshotAccount) Snapshot() any {
+	return balance{Amount: a.balance}
+}
+
+func (a *snapshotAccount) RecoverSnapshot(state any) {
+	a.recovered = append(a.recovered, state)
+	a.balance = state.(balance).Amount
+}
+
+func (a *snapshotAccount) Receive(c *actor.Context) {
+	if _, ok := c.Message().(getRecovered); ok {
+		c.Respond(a.recovered)
+		return
+	}
+	a.account.Receive(c)
+}
+
+type getRecovered struct{}
+
+func TestSnapshotEvery(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	snapshots := NewMemorySnapshotStore()
+	config := NewConfig().
+		WithJournal(NewMemoryJournal()).
+		WithSnapshotStore(snapshots).
+		WithSnapshotEvery(2)
+	spawn := func() *actor.PID {
+		return e.Spawn(func() actor.Receiver { return &snapshotAccount{} }, "account", actor.WithID("1"),
+			actor.WithMiddleware(Middleware(config)))
+	}
+
+	pid := spawn()
+	for i := 1; i <= 5; i++ {
+		request(t, e, pid, deposit{Amount: i})
+	}
+	snapshot, ok, err := snapshots.Load("account/1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, uint64(4), snapshot.Seq)
+	<-e.Poison(pid).Done()
+
+	// The actor recovers from the snapshot after the fourth event, and the
+	// fifth event.
+	pid = spawn()
+	assert.Equal(t, []any{balance{Amount: 10}, deposited{Amount: 5}}, request(t, e, pid, getRecovered{}))
+	assert.Equal(t, 15, request(t, e, pid, getBalance{}))
+
+	assert.Panics(t, func() {
+		Middleware(NewConfig().WithJournal(NewMemoryJournal()).WithSnapshotEvery(2))
+	})
+}
+
+func TestPersistEvents(t *testing.T) {
+	config := NewConfig().WithJournal(NewMemoryJournal())
+	p := &Persistent{id: "account/1", config: config}
+	require.NoError(t, p.PersistEvents(deposited{Amount: 1}, deposited{Amount: 2}))
+	assert.Equal(t, uint64(2), p.LastSeq())
+
+	// Another instance of the actor that recovered before.
+	other := &Persistent{id: "account/1", config: config}
+	assert.ErrorIs(t, other.PersistEvents(deposited{Amount: 3}, deposited{Amount: 4}), ErrConflict)
+	assert.Equal(t, uint64(0), other.LastSeq())
+	assert.ErrorIs(t, p.PersistEvents(struct{}{}), actor.ErrUnregisteredEvent)
+}
@@ -0,0 +1,177 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// postgresUniqueViolation is the SQLSTATE of a violated unique
+	// constraint.
+	postgresUniqueViolation = "23505"
+	// postgresBatchSize keeps an insert below the limit of 65535 parameters.
+	postgresBatchSize = 1000
+)
+
+// postgresMigrations are the statements that create and upgrade the table
+// of the journal, in order. The position of a migration is its version, so
+// new ones are only ever appended.
+var postgresMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS %[1]s (
+		persistence_id TEXT NOT NULL,
+		seq BIGINT NOT NULL,
+		time TIMESTAMPTZ NOT NULL,
+		name TEXT NOT NULL,
+		data BYTEA NOT NULL,
+		PRIMARY KEY (persistence_id, seq)
+	)`,
+}
+
+// PostgresJournal is a Journal in a table of a PostgreSQL database. The
+// primary key of the sequence numbers of each actor makes the append of a
+// writer that is behind fail with ErrConflict, so two instances of an actor
+// on different nodes can't both write its events. The driver is up to the
+// caller, like github.com/jackc/pgx/v5/stdlib or github.com/lib/pq.
+type PostgresJournal struct {
+	db    *sql.DB
+	table string
+}
+
+// NewPostgresJournal returns a journal in the table with the given name,
+// which Migrate creates.
+//
+//	db, err := sql.Open("pgx", "postgres://localhost/app")
+//	...
+//	journal := persistence.NewPostgresJournal(db, "journal")
+//	err = journal.Migrate()
+func NewPostgresJournal(db *sql.DB, table string) *PostgresJournal {
+	return &PostgresJournal{db: db, table: table}
+}
+
+// Migrate creates the table of the journal, or upgrades it to the schema of
+// this version. The applied migrations are recorded in the table with the
+// "_migrations" suffix, and the nodes that migrate at the same time wait for
+// each other.
+func (j *PostgresJournal) Migrate() error {
+	tx, err := j.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, j.table); err != nil {
+		return err
+	}
+	migrations := j.table + "_migrations"
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS ` + migrations + ` (version INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+	var version int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM ` + migrations).Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(postgresMigrations); i++ {
+		if _, err := tx.Exec(fmt.Sprintf(postgresMigrations[i], j.table)); err != nil {
+			return fmt.Errorf("failed to migrate journal %s to version %d: %w", j.table, i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO `+migrations+` (version) VALUES ($1)`, i+1); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CheckHealth returns an error if the database can't be reached, see
+// actor.HealthChecker.
+func (j *PostgresJournal) CheckHealth(ctx context.Context) error {
+	return j.db.PingContext(ctx)
+}
+
+// Append implements Journal. The records are inserted in batches, in a single
+// transaction.
+func (j *PostgresJournal) Append(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	tx, err := j.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for start := 0; start < len(records); start += postgresBatchSize {
+		batch := records[start:min(start+postgresBatchSize, len(records))]
+		args := make([]any, 0, len(batch)*5)
+		for _, record := range batch {
+			args = append(args, record.PersistenceID, int64(record.Seq), record.Time, record.Name, record.Data)
+		}
+		if _, err := tx.Exec(insertStatement(j.table, len(batch)), args...); err != nil {
+			if isUniqueViolation(err) {
+				return ErrConflict
+			}
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// Read implements Journal.
+func (j *PostgresJournal) Read(persistenceID string, from uint64, fn func(Record) error) error {
+	rows, err := j.db.Query(`SELECT seq, time, name, data FROM `+j.table+`
+		WHERE persistence_id = $1 AND seq >= $2 ORDER BY seq`, persistenceID, int64(from))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		record := Record{PersistenceID: persistenceID}
+		var (
+			seq int64
+			t   time.Time
+		)
+		if err := rows.Scan(&seq, &t, &record.Name, &record.Data); err != nil {
+			return err
+		}
+		record.Seq = uint64(seq)
+		record.Time = t
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// DeleteEvents implements EventDeleter.
+func (j *PostgresJournal) DeleteEvents(persistenceID string, toSeq uint64) error {
+	_, err := j.db.Exec(`DELETE FROM `+j.table+` WHERE persistence_id = $1 AND seq <= $2`, persistenceID, int64(toSeq))
+	return err
+}
+
+// insertStatement returns the insert of n records into the table.
+func insertStatement(table string, n int) string {
+	var b strings.Builder
+	b.WriteString(`INSERT INTO ` + table + ` (persistence_id, seq, time, name, data) VALUES `)
+	for i := range n {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		p := i * 5
+		fmt.Fprintf(&b, "($%d, $%d, $%d, $%d, $%d)", p+1, p+2, p+3, p+4, p+5)
+	}
+	return b.String()
+}
+
+// isUniqueViolation returns true if the error of the driver is a violated
+// unique constraint. The errors of pgx and lib/pq both tell their SQLSTATE.
+func isUniqueViolation(err error) bool {
+	var state interface{ SQLState() string }
+	return errors.As(err, &state) && state.SQLState() == postgresUniqueViolation
+}
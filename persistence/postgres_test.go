@@ -0,0 +1,23 @@
+package persistence
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "ERROR (SQLSTATE " + string(e) + ")" }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestPostgresStatements(t *testing.T) {
+	assert.Equal(t,
+		"INSERT INTO journal (persistence_id, seq, time, name, data) VALUES ($1, $2, $3, $4, $5), ($6, $7, $8, $9, $10)",
+		insertStatement("journal", 2))
+
+	assert.True(t, isUniqueViolation(fmt.Errorf("failed to insert: %w", sqlStateError("23505"))))
+	assert.False(t, isUniqueViolation(sqlStateError("40001")))
+	assert.False(t, isUniqueViolation(fmt.Errorf("connection refused")))
+}
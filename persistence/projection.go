@@ -0,0 +1,314 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+const (
+	defaultPollInterval = 500 * time.Millisecond
+	// projectionBatchSize is the number of records a projection reads before
+	// it saves its offset and handles its other messages.
+	projectionBatchSize = 1000
+	projectionTimeout   = 5 * time.Second
+)
+
+// errBatchDone stops reading the journal once a projection read a batch.
+var errBatchDone = errors.New("batch done")
+
+// OffsetStore keeps the offsets of the projections, the offset of the last
+// record of the journal they handled.
+type OffsetStore interface {
+	// LoadOffset returns the offset of the projection, or 0 if it has none.
+	LoadOffset(projection string) (uint64, error)
+	SaveOffset(projection string, offset uint64) error
+}
+
+// MemoryOffsetStore is an OffsetStore that keeps the offsets in memory.
+type MemoryOffsetStore struct {
+	mu      sync.Mutex
+	offsets map[string]uint64
+}
+
+// NewMemoryOffsetStore returns an empty MemoryOffsetStore.
+func NewMemoryOffsetStore() *MemoryOffsetStore {
+	return &MemoryOffsetStore{offsets: make(map[string]uint64)}
+}
+
+// LoadOffset implements OffsetStore.
+func (s *MemoryOffsetStore) LoadOffset(projection string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offsets[projection], nil
+}
+
+// SaveOffset implements OffsetStore.
+func (s *MemoryOffsetStore) SaveOffset(projection string, offset uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offsets[projection] = offset
+	return nil
+}
+
+// ProjectedEvent is an event of the journal that is handled by a projection.
+type ProjectedEvent struct {
+	// Offset is the position of the event in the journal, see
+	// OrderedJournal.
+	Offset        uint64
+	PersistenceID string
+	Seq           uint64
+	Time          time.Time
+	Tags          []string
+	Event         any
+}
+
+// Projector builds a read model from the events of a projection.
+type Projector interface {
+	// Project applies the event to the read model. If it fails, the event is
+	// handled again at the next poll. As the offset of the projection is only
+	// saved after a batch of events, an event can be handled again after a
+	// restart as well, so Project needs to be idempotent.
+	Project(event ProjectedEvent) error
+}
+
+// Resetter is implemented by the projectors that can drop their read model,
+// which Projection.Rebuild does before it handles all the events again.
+type Resetter interface {
+	Reset() error
+}
+
+// ProjectionConfig holds the configuration of a Projection.
+type ProjectionConfig struct {
+	journal      OrderedJournal
+	offsets      OffsetStore
+	tag          string
+	idPrefix     string
+	pollInterval time.Duration
+	upcasters    []EventAdapter
+	encryptor    Encryptor
+}
+
+// NewProjectionConfig returns a ProjectionConfig that is initialized with
+// default values.
+func NewProjectionConfig() ProjectionConfig {
+	return ProjectionConfig{pollInterval: defaultPollInterval}
+}
+
+// WithJournal set's the journal the projection tails, which is required.
+func (config ProjectionConfig) WithJournal(journal OrderedJournal) ProjectionConfig {
+	config.journal = journal
+	return config
+}
+
+// WithOffsetStore set's the store the offset of the projection is kept in,
+// which is required.
+func (config ProjectionConfig) WithOffsetStore(store OffsetStore) ProjectionConfig {
+	config.offsets = store
+	return config
+}
+
+// WithTag set's the tag of the events the projection handles, see
+// Config.WithTagger.
+//
+// Defaults to "", which handles the events with any tags.
+func (config ProjectionConfig) WithTag(tag string) ProjectionConfig {
+	config.tag = tag
+	return config
+}
+
+// WithPersistenceIDPrefix set's the prefix of the persistence IDs whose events
+// the projection handles, like "order/" for the actors of the kind "order".
+//
+// Defaults to "", which handles the events of all the actors.
+func (config ProjectionConfig) WithPersistenceIDPrefix(prefix string) ProjectionConfig {
+	config.idPrefix = prefix
+	return config
+}
+
+// WithPollInterval set's how often the projection looks for new events in the
+// journal.
+//
+// Defaults to 500 milliseconds.
+func (config ProjectionConfig) WithPollInterval(d time.Duration) ProjectionConfig {
+	config.pollInterval = d
+	return config
+}
+
+// WithUpcasters set's the adapters the events are upcasted with before they
+// are projected, see Config.WithUpcasters.
+func (config ProjectionConfig) WithUpcasters(adapters ...EventAdapter) ProjectionConfig {
+	config.upcasters = adapters
+	return config
+}
+
+// WithEncryptor set's the encryptor the data of the events is decrypted with,
+// see Config.WithEncryptor.
+func (config ProjectionConfig) WithEncryptor(encryptor Encryptor) ProjectionConfig {
+	config.encryptor = encryptor
+	return config
+}
+
+// Projection tails the journal of the persistent actors with a Projector, to
+// derive a read model from their events. It's an actor that resumes from the
+// offset it saved when it's spawned again.
+type Projection struct {
+	engine *actor.Engine
+	pid    *actor.PID
+}
+
+// NewProjection spawns the projection with the given name, which its offset
+// is saved under.
+//
+//	config := persistence.NewProjectionConfig().
+//		WithJournal(store).
+//		WithOffsetStore(store).
+//		WithPersistenceIDPrefix("order/")
+//	projection := persistence.NewProjection(e, "order-totals", totals, config)
+func NewProjection(e *actor.Engine, name string, projector Projector, config ProjectionConfig) *Projection {
+	if config.journal == nil {
+		panic("persistence: no journal configured for the projection")
+	}
+	if config.offsets == nil {
+		panic("persistence: no offset store configured for the projection")
+	}
+	pid := e.Spawn(func() actor.Receiver {
+		return &projection{name: name, projector: projector, config: config}
+	}, "projection", actor.WithID(name))
+	return &Projection{engine: e, pid: pid}
+}
+
+// PID returns the PID of the actor of the projection.
+func (p *Projection) PID() *actor.PID {
+	return p.pid
+}
+
+// Offset returns the offset of the last record of the journal the projection
+// handled.
+func (p *Projection) Offset() (uint64, error) {
+	resp, err := p.engine.Request(p.pid, getOffset{}, projectionTimeout).Result()
+	if err != nil {
+		return 0, err
+	}
+	if err, ok := resp.(error); ok {
+		return 0, err
+	}
+	return resp.(uint64), nil
+}
+
+// Rebuild resets the read model, if the projector implements Resetter, and
+// handles all the events of the journal again.
+func (p *Projection) Rebuild() error {
+	resp, err := p.engine.Request(p.pid, rebuild{}, projectionTimeout).Result()
+	if err != nil {
+		return err
+	}
+	if err, ok := resp.(error); ok {
+		return err
+	}
+	return nil
+}
+
+type poll struct{}
+
+type getOffset struct{}
+
+type rebuild struct{}
+
+type projection struct {
+	name      string
+	projector Projector
+	config    ProjectionConfig
+	offset    uint64
+	repeater  actor.SendRepeater
+}
+
+func (p *projection) Receive(c *actor.Context) {
+	switch c.Message().(type) {
+	case actor.Started:
+		offset, err := p.config.offsets.LoadOffset(p.name)
+		if err != nil {
+			// The projection is restarted to load it again.
+			panic(fmt.Sprintf("failed to load the offset of projection %s: %v", p.name, err))
+		}
+		p.offset = offset
+		p.repeater = c.SendRepeat(c.PID(), poll{}, p.config.pollInterval)
+		c.Send(c.PID(), poll{})
+	case actor.Stopped:
+		p.repeater.Stop()
+	case poll:
+		p.poll(c)
+	case getOffset:
+		c.Respond(p.offset)
+	case rebuild:
+		if resetter, ok := p.projector.(Resetter); ok {
+			if err := resetter.Reset(); err != nil {
+				c.Respond(err)
+				return
+			}
+		}
+		if err := p.config.offsets.SaveOffset(p.name, 0); err != nil {
+			c.Respond(err)
+			return
+		}
+		p.offset = 0
+		c.Respond(nil)
+		c.Send(c.PID(), poll{})
+	}
+}
+
+// poll handles a batch of the records after the offset, and saves the offset
+// of the last one it handled. It polls again right away if there are more.
+func (p *projection) poll(c *actor.Context) {
+	n := 0
+	last := p.offset
+	err := p.config.journal.ReadAll(p.offset+1, func(offset uint64, record Record) error {
+		if n == projectionBatchSize {
+			return errBatchDone
+		}
+		n++
+		if p.matches(record) {
+			event, err := decodeEvent(p.config.upcasters, p.config.encryptor, record.PersistenceID, record.Name, record.Data)
+			if err != nil {
+				return fmt.Errorf("event %d of %s: %w", record.Seq, record.PersistenceID, err)
+			}
+			err = p.projector.Project(ProjectedEvent{
+				Offset:        offset,
+				PersistenceID: record.PersistenceID,
+				Seq:           record.Seq,
+				Time:          record.Time,
+				Tags:          record.Tags,
+				Event:         event,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		last = offset
+		return nil
+	})
+	if last != p.offset {
+		p.offset = last
+		if err := p.config.offsets.SaveOffset(p.name, last); err != nil {
+			slog.Warn("failed to save projection offset", "err", err, "projection", p.name, "offset", last)
+		}
+	}
+	if errors.Is(err, errBatchDone) {
+		c.Send(c.PID(), poll{})
+	} else if err != nil {
+		slog.Warn("projection failed", "err", err, "projection", p.name, "offset", p.offset)
+	}
+}
+
+func (p *projection) matches(record Record) bool {
+	if p.config.tag != "" && !slices.Contains(record.Tags, p.config.tag) {
+		return false
+	}
+	return strings.HasPrefix(record.PersistenceID, p.config.idPrefix)
+}
@@ -0,0 +1,119 @@
+package persistence
+
+import (
+	"maps"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// totals is a read model of the deposits by account.
+type totals struct {
+	mu        sync.Mutex
+	totals    map[string]int
+	projected int
+}
+
+func newTotals() *totals {
+	return &totals{totals: make(map[string]int)}
+}
+
+func (t *totals) Project(event ProjectedEvent) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := event.Event.(deposited); ok {
+		t.projected++
+		t.totals[event.PersistenceID] += e.Amount
+	}
+	return nil
+}
+
+func (t *totals) Reset() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.totals)
+	return nil
+}
+
+func (t *totals) get() (map[string]int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.totals), t.projected
+}
+
+func TestProjection(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	journal := NewMemoryJournal()
+	offsets := NewMemoryOffsetStore()
+	config := NewConfig().WithJournal(journal)
+	for _, id := range []string{"1", "2"} {
+		pid := e.Spawn(newAccount, "account", actor.WithID(id), actor.WithMiddleware(Middleware(config)))
+		request(t, e, pid, deposit{Amount: 10})
+	}
+	savings := e.Spawn(newAccount, "savings", actor.WithID("1"), actor.WithMiddleware(Middleware(config)))
+	request(t, e, savings, deposit{Amount: 100})
+
+	model := newTotals()
+	projectionConfig := NewProjectionConfig().
+		WithJournal(journal).
+		WithOffsetStore(offsets).
+		WithPersistenceIDPrefix("account/").
+		WithPollInterval(10 * time.Millisecond)
+	projection := NewProjection(e, "totals", model, projectionConfig)
+	want := map[string]int{"account/1": 10, "account/2": 10}
+	assert.Eventually(t, func() bool {
+		got, _ := model.get()
+		return assert.ObjectsAreEqual(want, got)
+	}, time.Second, 10*time.Millisecond)
+
+	// The projection resumes from its offset when it's spawned again.
+	<-e.Poison(projection.PID()).Done()
+	pid := e.Registry.GetPID("account", "1")
+	request(t, e, pid, deposit{Amount: 5})
+	projection = NewProjection(e, "totals", model, projectionConfig)
+	want["account/1"] = 15
+	assert.Eventually(t, func() bool {
+		got, projected := model.get()
+		return assert.ObjectsAreEqual(want, got) && projected == 3
+	}, time.Second, 10*time.Millisecond)
+	offset, err := projection.Offset()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), offset)
+
+	require.NoError(t, projection.Rebuild())
+	assert.Eventually(t, func() bool {
+		got, projected := model.get()
+		return assert.ObjectsAreEqual(want, got) && projected == 6
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestProjectionTag(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	journal := NewMemoryJournal()
+	config := NewConfig().WithJournal(journal).WithTagger(func(event any) []string {
+		if event, ok := event.(deposited); ok && event.Amount >= 100 {
+			return []string{"large"}
+		}
+		return nil
+	})
+	pid := e.Spawn(newAccount, "account", actor.WithID("1"), actor.WithMiddleware(Middleware(config)))
+	for _, amount := range []int{10, 100, 20, 200} {
+		request(t, e, pid, deposit{Amount: amount})
+	}
+
+	model := newTotals()
+	NewProjection(e, "large", model, NewProjectionConfig().
+		WithJournal(journal).
+		WithOffsetStore(NewMemoryOffsetStore()).
+		WithTag("large"))
+	assert.Eventually(t, func() bool {
+		got, projected := model.get()
+		return got["account/1"] == 300 && projected == 2
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,185 @@
+package persistence
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+// RecoveryPolicy decides what an actor does when it fails to recover, see
+// Config.WithRecoveryPolicy.
+type RecoveryPolicy int
+
+const (
+	// RecoveryRetry panics, so the actor is restarted with a fresh receiver
+	// and recovers again, as often as its maximum restarts allow, see
+	// actor.WithMaxRestarts and actor.WithRestartDelay.
+	RecoveryRetry RecoveryPolicy = iota
+	// RecoveryStop stops the actor, which drops the messages that wait in
+	// its inbox.
+	RecoveryStop
+	// RecoverySkipCorrupt skips the events that fail to decode, and a
+	// snapshot that fails to decode in favor of all the events, and retries
+	// when the journal or the snapshot store fail.
+	RecoverySkipCorrupt
+)
+
+func (policy RecoveryPolicy) String() string {
+	switch policy {
+	case RecoveryRetry:
+		return "retry"
+	case RecoveryStop:
+		return "stop"
+	case RecoverySkipCorrupt:
+		return "skip-corrupt"
+	}
+	return fmt.Sprintf("RecoveryPolicy(%d)", int(policy))
+}
+
+// RecoveryStartedEvent is broadcasted when a persistent actor starts to
+// recover.
+type RecoveryStartedEvent struct {
+	PID           *actor.PID
+	PersistenceID string
+}
+
+// RecoveryCompletedEvent is broadcasted when a persistent actor recovered,
+// before it handles its first message.
+type RecoveryCompletedEvent struct {
+	PID           *actor.PID
+	PersistenceID string
+	// SnapshotSeq is the sequence number of the snapshot the actor recovered
+	// from, or 0 if it had none.
+	SnapshotSeq uint64
+	// Events is the number of the events that were replayed after the
+	// snapshot, and Skipped the number of the ones among them that failed to
+	// decode, see RecoverySkipCorrupt.
+	Events   int
+	Skipped  int
+	Duration time.Duration
+}
+
+// RecoveryFailedEvent is broadcasted when a persistent actor failed to
+// recover, before the policy is applied.
+type RecoveryFailedEvent struct {
+	PID           *actor.PID
+	PersistenceID string
+	Err           error
+	Policy        RecoveryPolicy
+}
+
+func (e RecoveryStartedEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelDebug, "Recovery started", []any{"pid", e.PID, "id", e.PersistenceID}
+}
+
+func (e RecoveryCompletedEvent) Log() (slog.Level, string, []any) {
+	level := slog.LevelDebug
+	if e.Skipped > 0 {
+		level = slog.LevelWarn
+	}
+	return level, "Recovery completed", []any{"pid", e.PID, "id", e.PersistenceID, "snapshot", e.SnapshotSeq,
+		"events", e.Events, "skipped", e.Skipped, "duration", e.Duration}
+}
+
+func (e RecoveryFailedEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelError, "Recovery failed", []any{"pid", e.PID, "id", e.PersistenceID, "err", e.Err, "policy", e.Policy}
+}
+
+func (RecoveryStartedEvent) Topic() string   { return "persistence.recovery.started" }
+func (RecoveryCompletedEvent) Topic() string { return "persistence.recovery.completed" }
+func (RecoveryFailedEvent) Topic() string    { return "persistence.recovery.failed" }
+
+// recovery counts what an actor recovered.
+type recovery struct {
+	snapshotSeq uint64
+	events      int
+	skipped     int
+}
+
+// recover restores the state of the actor from its last snapshot, if it has
+// one, and the events after it, and applies the policy if it fails.
+func (p *Persistent) recover(c *actor.Context, snapshotter Snapshotter) {
+	start := time.Now()
+	c.Engine().BroadcastEvent(RecoveryStartedEvent{PID: c.PID(), PersistenceID: p.id})
+	var r recovery
+	err := p.restore(c.Receiver(), snapshotter, &r)
+	if err != nil {
+		policy := p.config.recoveryPolicy
+		c.Engine().BroadcastEvent(RecoveryFailedEvent{PID: c.PID(), PersistenceID: p.id, Err: err, Policy: policy})
+		if policy == RecoveryStop {
+			p.failed = true
+			c.Engine().Poison(c.PID())
+			return
+		}
+		panic(fmt.Sprintf("failed to recover %s: %v", p.id, err))
+	}
+	c.Engine().BroadcastEvent(RecoveryCompletedEvent{
+		PID:           c.PID(),
+		PersistenceID: p.id,
+		SnapshotSeq:   r.snapshotSeq,
+		Events:        r.events,
+		Skipped:       r.skipped,
+		Duration:      time.Since(start),
+	})
+}
+
+func (p *Persistent) restore(rcv actor.Receiver, snapshotter Snapshotter, r *recovery) error {
+	if snapshotter != nil && p.config.snapshots != nil {
+		if err := p.recoverSnapshot(snapshotter, r); err != nil {
+			return err
+		}
+	}
+	if recoverer, ok := rcv.(Recoverer); ok {
+		return p.replay(recoverer, r)
+	}
+	return nil
+}
+
+// recoverSnapshot restores the last snapshot of the actor.
+func (p *Persistent) recoverSnapshot(s Snapshotter, r *recovery) error {
+	snapshot, ok, err := p.config.snapshots.Load(p.id)
+	if err != nil || !ok {
+		return err
+	}
+	state, err := p.decode(snapshot.Name, snapshot.Data)
+	if err != nil {
+		if p.config.recoveryPolicy == RecoverySkipCorrupt {
+			slog.Warn("skipped corrupt snapshot", "err", err, "id", p.id, "seq", snapshot.Seq)
+			return nil
+		}
+		return err
+	}
+	s.RecoverSnapshot(state)
+	p.seq = snapshot.Seq
+	p.snapshotSeq = snapshot.Seq
+	r.snapshotSeq = snapshot.Seq
+	return nil
+}
+
+// replay replays the events of the journal after the snapshot through the
+// receiver.
+func (p *Persistent) replay(recoverer Recoverer, r *recovery) error {
+	return p.config.journal.Read(p.id, p.seq+1, func(record Record) error {
+		r.events++
+		event, err := p.decode(record.Name, record.Data)
+		if err != nil {
+			if p.config.recoveryPolicy != RecoverySkipCorrupt {
+				return fmt.Errorf("event %d: %w", record.Seq, err)
+			}
+			slog.Warn("skipped corrupt event", "err", err, "id", p.id, "seq", record.Seq)
+			r.skipped++
+		} else {
+			recoverer.RecoverEvent(event)
+		}
+		p.seq = record.Seq
+		return nil
+	})
+}
+
+// decode decrypts and decodes an event or a snapshot, and upcasts it to its
+// current version.
+func (p *Persistent) decode(name string, data []byte) (any, error) {
+	return decodeEvent(p.config.upcasters, p.config.encryptor, p.id, name, data)
+}
@@ -0,0 +1,96 @@
+package persistence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoveryEvents(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	completed := make(chan RecoveryCompletedEvent, 10)
+	actor.SubscribeTyped(e, func(event RecoveryCompletedEvent) {
+		completed <- event
+	})
+	journal := NewMemoryJournal()
+	config := NewConfig().
+		WithJournal(journal).
+		WithSnapshotStore(NewMemorySnapshotStore()).
+		WithSnapshotEvery(2)
+	spawn := func() *actor.PID {
+		return e.Spawn(func() actor.Receiver { return &snapshotAccount{} }, "account", actor.WithID("1"),
+			actor.WithMiddleware(Middleware(config)))
+	}
+
+	pid := spawn()
+	event := <-completed
+	assert.Equal(t, pid, event.PID)
+	assert.Equal(t, "account/1", event.PersistenceID)
+	assert.Zero(t, event.Events)
+	for i := 1; i <= 3; i++ {
+		request(t, e, pid, deposit{Amount: i})
+	}
+	<-e.Poison(pid).Done()
+
+	spawn()
+	event = <-completed
+	assert.Equal(t, uint64(2), event.SnapshotSeq)
+	assert.Equal(t, 1, event.Events)
+	assert.Zero(t, event.Skipped)
+}
+
+func TestRecoveryPolicy(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	journal := NewMemoryJournal()
+	require.NoError(t, journal.Append([]Record{
+		{PersistenceID: "account/1", Seq: 1, Name: "persistence.test.Deposited", Data: []byte(`{"Amount":2}`)},
+		{PersistenceID: "account/1", Seq: 2, Name: "persistence.test.Deposited", Data: []byte(`not json`)},
+		{PersistenceID: "account/1", Seq: 3, Name: "persistence.test.Deposited", Data: []byte(`{"Amount":3}`)},
+	}))
+	var (
+		completed = make(chan RecoveryCompletedEvent, 10)
+		failed    = make(chan RecoveryFailedEvent, 10)
+		stopped   = make(chan *actor.PID, 10)
+	)
+	actor.SubscribeTyped(e, func(event RecoveryCompletedEvent) { completed <- event })
+	actor.SubscribeTyped(e, func(event RecoveryFailedEvent) { failed <- event })
+	actor.SubscribeTyped(e, func(event actor.ActorStoppedEvent) { stopped <- event.PID })
+	spawn := func(policy RecoveryPolicy) *actor.PID {
+		config := NewConfig().WithJournal(journal).WithRecoveryPolicy(policy)
+		return e.Spawn(newAccount, "account", actor.WithID("1"), actor.WithMiddleware(Middleware(config)))
+	}
+
+	pid := spawn(RecoverySkipCorrupt)
+	event := <-completed
+	assert.Equal(t, 3, event.Events)
+	assert.Equal(t, 1, event.Skipped)
+	assert.Equal(t, 5, request(t, e, pid, getBalance{}))
+	<-e.Poison(pid).Done()
+	<-stopped
+
+	// The actor that stops doesn't handle a message.
+	pid = spawn(RecoveryStop)
+	e.Send(pid, deposit{Amount: 1})
+	failure := <-failed
+	assert.Equal(t, RecoveryStop, failure.Policy)
+	assert.ErrorContains(t, failure.Err, "event 2")
+	assert.Equal(t, pid, <-stopped)
+	var seqs []uint64
+	require.NoError(t, journal.Read("account/1", 1, func(record Record) error {
+		seqs = append(seqs, record.Seq)
+		return nil
+	}))
+	assert.Equal(t, []uint64{1, 2, 3}, seqs)
+
+	pid = e.Spawn(newAccount, "account", actor.WithID("1"), actor.WithMaxRestarts(1),
+		actor.WithRestartDelay(time.Millisecond),
+		actor.WithMiddleware(Middleware(NewConfig().WithJournal(journal))))
+	assert.Equal(t, RecoveryRetry, (<-failed).Policy)
+	assert.Equal(t, RecoveryRetry, (<-failed).Policy)
+	assert.Equal(t, pid, <-stopped)
+}
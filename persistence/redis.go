@@ -0,0 +1,417 @@
+package persistence
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRedisAddr    = "127.0.0.1:6379"
+	defaultRedisPrefix  = "hollywood:"
+	defaultRedisTimeout = 5 * time.Second
+	// redisReadCount is the number of entries of a stream read at once.
+	redisReadCount = 256
+)
+
+// redisAppendScript appends the records to the stream of an actor if the
+// first one follows the last entry, and refreshes the TTL of the stream. The
+// IDs of the entries are 0-<seq>, so Redis keeps them in order.
+const redisAppendScript = `
+local last = redis.call('XREVRANGE', KEYS[1], '+', '-', 'COUNT', 1)
+local seq = 0
+if #last > 0 then
+	seq = tonumber(string.match(last[1][1], '^0%-(%d+)$'))
+end
+if seq + 1 ~= tonumber(ARGV[2]) then
+	return redis.error_reply('CONFLICT')
+end
+for i = 2, #ARGV, 4 do
+	redis.call('XADD', KEYS[1], '0-' .. ARGV[i], 'time', ARGV[i+1], 'name', ARGV[i+2], 'data', ARGV[i+3])
+end
+if tonumber(ARGV[1]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return 0`
+
+// RedisConfig holds the configuration of a RedisStore.
+type RedisConfig struct {
+	addr     string
+	password string
+	db       int
+	prefix   string
+	ttl      time.Duration
+	timeout  time.Duration
+}
+
+// NewRedisConfig returns a RedisConfig that is initialized with default
+// values.
+func NewRedisConfig() RedisConfig {
+	return RedisConfig{
+		addr:    defaultRedisAddr,
+		prefix:  defaultRedisPrefix,
+		timeout: defaultRedisTimeout,
+	}
+}
+
+// WithAddr set's the address of the Redis server.
+//
+// Defaults to "127.0.0.1:6379".
+func (c RedisConfig) WithAddr(addr string) RedisConfig {
+	c.addr = addr
+	return c
+}
+
+// WithPassword set's the password the store authenticates with.
+func (c RedisConfig) WithPassword(password string) RedisConfig {
+	c.password = password
+	return c
+}
+
+// WithDB set's the number of the database of the store.
+//
+// Defaults to 0.
+func (c RedisConfig) WithDB(db int) RedisConfig {
+	c.db = db
+	return c
+}
+
+// WithPrefix set's the prefix of the keys, which allows several applications
+// to share the same Redis.
+//
+// Defaults to "hollywood:".
+func (c RedisConfig) WithPrefix(prefix string) RedisConfig {
+	c.prefix = prefix
+	return c
+}
+
+// WithTTL set's how long the events and the snapshot of an actor are kept
+// after its last write, for the entities that only live for a while. An
+// actor whose state expired starts over when it's spawned again.
+//
+// Defaults to 0, which keeps them forever.
+func (c RedisConfig) WithTTL(d time.Duration) RedisConfig {
+	c.ttl = d
+	return c
+}
+
+// WithTimeout set's how long the store waits for Redis to answer a command.
+//
+// Defaults to 5 seconds.
+func (c RedisConfig) WithTimeout(d time.Duration) RedisConfig {
+	c.timeout = d
+	return c
+}
+
+// RedisStore is a Journal and a SnapshotStore in Redis, with the events of
+// each actor in a stream and its last snapshot in a string. It talks to Redis
+// over a single connection, which is opened again after it failed.
+type RedisStore struct {
+	config RedisConfig
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewRedisStore returns a store in the Redis of the config. The connection is
+// opened by the first command.
+func NewRedisStore(config RedisConfig) *RedisStore {
+	return &RedisStore{config: config}
+}
+
+// Close closes the connection to Redis.
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *RedisStore) journalKey(persistenceID string) string {
+	return s.config.prefix + "journal:" + persistenceID
+}
+
+func (s *RedisStore) snapshotKey(persistenceID string) string {
+	return s.config.prefix + "snapshot:" + persistenceID
+}
+
+// CheckHealth returns an error if Redis doesn't answer a PING within the
+// timeout of the config, see actor.HealthChecker.
+func (s *RedisStore) CheckHealth(context.Context) error {
+	_, err := s.do("PING")
+	return err
+}
+
+// Append implements Journal.
+func (s *RedisStore) Append(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	id := records[0].PersistenceID
+	args := []any{"EVAL", redisAppendScript, 1, s.journalKey(id), s.config.ttl.Milliseconds()}
+	for i, record := range records {
+		if record.PersistenceID != id || record.Seq != records[0].Seq+uint64(i) {
+			return ErrConflict
+		}
+		args = append(args, record.Seq, record.Time.UnixNano(), record.Name, record.Data)
+	}
+	_, err := s.do(args...)
+	var rerr redisError
+	if errors.As(err, &rerr) && strings.HasPrefix(string(rerr), "CONFLICT") {
+		return ErrConflict
+	}
+	return err
+}
+
+// Read implements Journal.
+func (s *RedisStore) Read(persistenceID string, from uint64, fn func(Record) error) error {
+	for {
+		reply, err := s.do("XRANGE", s.journalKey(persistenceID), "0-"+strconv.FormatUint(from, 10), "+", "COUNT", redisReadCount)
+		if err != nil {
+			return err
+		}
+		entries, _ := reply.([]any)
+		for _, entry := range entries {
+			record, err := redisRecord(persistenceID, entry)
+			if err != nil {
+				return err
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+			from = record.Seq + 1
+		}
+		if len(entries) < redisReadCount {
+			return nil
+		}
+	}
+}
+
+// redisRecord returns the record of an entry of a stream, which is its ID and
+// its fields and values.
+func redisRecord(persistenceID string, entry any) (Record, error) {
+	parts, ok := entry.([]any)
+	if !ok || len(parts) != 2 {
+		return Record{}, fmt.Errorf("unexpected stream entry %v", entry)
+	}
+	id, _ := parts[0].([]byte)
+	seq, err := strconv.ParseUint(strings.TrimPrefix(string(id), "0-"), 10, 64)
+	if err != nil {
+		return Record{}, fmt.Errorf("unexpected stream entry ID %s: %w", id, err)
+	}
+	record := Record{PersistenceID: persistenceID, Seq: seq}
+	fields, _ := parts[1].([]any)
+	for i := 0; i+1 < len(fields); i += 2 {
+		field, _ := fields[i].([]byte)
+		value, _ := fields[i+1].([]byte)
+		switch string(field) {
+		case "time":
+			t, err := strconv.ParseInt(string(value), 10, 64)
+			if err != nil {
+				return Record{}, err
+			}
+			record.Time = time.Unix(0, t)
+		case "name":
+			record.Name = string(value)
+		case "data":
+			record.Data = value
+		}
+	}
+	return record, nil
+}
+
+// DeleteEvents implements EventDeleter. It needs Redis 6.2 or later.
+func (s *RedisStore) DeleteEvents(persistenceID string, toSeq uint64) error {
+	_, err := s.do("XTRIM", s.journalKey(persistenceID), "MINID", "0-"+strconv.FormatUint(toSeq+1, 10))
+	return err
+}
+
+// Save implements SnapshotStore. Only the last snapshot of an actor is kept.
+func (s *RedisStore) Save(snapshot Snapshot) error {
+	b, err := kvEncode(snapshot)
+	if err != nil {
+		return err
+	}
+	args := []any{"SET", s.snapshotKey(snapshot.PersistenceID), b}
+	if s.config.ttl > 0 {
+		args = append(args, "PX", s.config.ttl.Milliseconds())
+	}
+	_, err = s.do(args...)
+	return err
+}
+
+// Load implements SnapshotStore.
+func (s *RedisStore) Load(persistenceID string) (Snapshot, bool, error) {
+	reply, err := s.do("GET", s.snapshotKey(persistenceID))
+	if err != nil || reply == nil {
+		return Snapshot{}, false, err
+	}
+	b, ok := reply.([]byte)
+	if !ok {
+		return Snapshot{}, false, fmt.Errorf("unexpected snapshot %v", reply)
+	}
+	var snapshot Snapshot
+	if err := kvDecode(b, &snapshot); err != nil {
+		return Snapshot{}, false, err
+	}
+	return snapshot, true, nil
+}
+
+// DeleteSnapshots implements SnapshotDeleter. As only the last snapshot of an
+// actor is kept, there is nothing to delete.
+func (s *RedisStore) DeleteSnapshots(persistenceID string, keep int) error {
+	return nil
+}
+
+// redisError is an error reply of Redis, which leaves the connection usable.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// do sends the command to Redis and returns its reply.
+func (s *RedisStore) do(args ...any) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := s.roundTrip(args)
+	var rerr redisError
+	if err != nil && !errors.As(err, &rerr) {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return reply, err
+}
+
+func (s *RedisStore) roundTrip(args []any) (any, error) {
+	if err := s.conn.SetDeadline(time.Now().Add(s.config.timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := s.conn.Write(appendCommand(nil, args...)); err != nil {
+		return nil, err
+	}
+	return readReply(s.r)
+}
+
+func (s *RedisStore) connect() error {
+	conn, err := net.DialTimeout("tcp", s.config.addr, s.config.timeout)
+	if err != nil {
+		return err
+	}
+	s.conn, s.r = conn, bufio.NewReader(conn)
+	if s.config.password != "" {
+		if _, err := s.roundTrip([]any{"AUTH", s.config.password}); err != nil {
+			conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	if s.config.db != 0 {
+		if _, err := s.roundTrip([]any{"SELECT", s.config.db}); err != nil {
+			conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// appendCommand appends the command in the protocol of Redis, an array of
+// bulk strings.
+func appendCommand(b []byte, args ...any) []byte {
+	b = append(b, '*')
+	b = strconv.AppendInt(b, int64(len(args)), 10)
+	b = append(b, "\r\n"...)
+	for _, arg := range args {
+		var v []byte
+		switch arg := arg.(type) {
+		case string:
+			v = []byte(arg)
+		case []byte:
+			v = arg
+		case int:
+			v = strconv.AppendInt(nil, int64(arg), 10)
+		case int64:
+			v = strconv.AppendInt(nil, arg, 10)
+		case uint64:
+			v = strconv.AppendUint(nil, arg, 10)
+		default:
+			v = fmt.Append(nil, arg)
+		}
+		b = append(b, '$')
+		b = strconv.AppendInt(b, int64(len(v)), 10)
+		b = append(b, "\r\n"...)
+		b = append(b, v...)
+		b = append(b, "\r\n"...)
+	}
+	return b
+}
+
+// readReply reads a reply of Redis. The simple strings and the bulk strings
+// are returned as []byte, the integers as int64, the arrays as []any, and the
+// nil replies as nil.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = bytes.TrimSuffix(line, []byte("\r\n"))
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = readReply(r); err != nil {
+				// The error of an element is kept, so the rest of the
+				// array is still read.
+				var rerr redisError
+				if !errors.As(err, &rerr) {
+					return nil, err
+				}
+				values[i] = rerr
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
@@ -0,0 +1,116 @@
+package persistence
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis answers the commands it receives with the replies of the given
+// function, which are in the protocol of Redis.
+func fakeRedis(t *testing.T, reply func(cmd []string) string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					v, err := readReply(r)
+					if err != nil {
+						return
+					}
+					var cmd []string
+					for _, arg := range v.([]any) {
+						cmd = append(cmd, string(arg.([]byte)))
+					}
+					conn.Write([]byte(reply(cmd)))
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRedisStore(t *testing.T) {
+	commands := make(chan []string, 10)
+	addr := fakeRedis(t, func(cmd []string) string {
+		commands <- cmd
+		switch cmd[0] {
+		case "AUTH", "SET":
+			return "+OK\r\n"
+		case "EVAL":
+			if cmd[5] == "1" {
+				return ":0\r\n"
+			}
+			return "-CONFLICT\r\n"
+		case "XRANGE":
+			return "*2\r\n" +
+				"*2\r\n$3\r\n0-1\r\n*6\r\n$4\r\ntime\r\n$1\r\n5\r\n$4\r\nname\r\n$1\r\ne\r\n$4\r\ndata\r\n$1\r\na\r\n" +
+				"*2\r\n$3\r\n0-2\r\n*6\r\n$4\r\ntime\r\n$1\r\n6\r\n$4\r\nname\r\n$1\r\ne\r\n$4\r\ndata\r\n$1\r\nb\r\n"
+		case "GET":
+			return "$-1\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	})
+	s := NewRedisStore(NewRedisConfig().WithAddr(addr).WithPassword("secret").WithTTL(time.Minute))
+	defer s.Close()
+
+	require.NoError(t, s.Append([]Record{
+		{PersistenceID: "a", Seq: 1, Time: time.Unix(0, 5), Name: "e", Data: []byte("a")},
+		{PersistenceID: "a", Seq: 2, Time: time.Unix(0, 6), Name: "e", Data: []byte("b")},
+	}))
+	assert.Equal(t, []string{"AUTH", "secret"}, <-commands)
+	eval := <-commands
+	assert.Equal(t, []string{"1", "hollywood:journal:a", "60000", "1", "5", "e", "a", "2", "6", "e", "b"}, eval[2:])
+	assert.ErrorIs(t, s.Append([]Record{{PersistenceID: "a", Seq: 2}}), ErrConflict)
+	<-commands
+
+	var records []Record
+	require.NoError(t, s.Read("a", 1, func(record Record) error {
+		records = append(records, record)
+		return nil
+	}))
+	assert.Equal(t, []Record{
+		{PersistenceID: "a", Seq: 1, Time: time.Unix(0, 5), Name: "e", Data: []byte("a")},
+		{PersistenceID: "a", Seq: 2, Time: time.Unix(0, 6), Name: "e", Data: []byte("b")},
+	}, records)
+	assert.Equal(t, []string{"XRANGE", "hollywood:journal:a", "0-1", "+", "COUNT", "256"}, <-commands)
+
+	require.NoError(t, s.Save(Snapshot{PersistenceID: "a", Seq: 2}))
+	set := <-commands
+	assert.Equal(t, []string{"SET", "hollywood:snapshot:a"}, set[:2])
+	assert.Equal(t, []string{"PX", "60000"}, set[3:])
+	_, ok, err := s.Load("a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRedisCheckHealth(t *testing.T) {
+	addr := fakeRedis(t, func(cmd []string) string {
+		if cmd[0] == "PING" {
+			return "+PONG\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	})
+	s := NewRedisStore(NewRedisConfig().WithAddr(addr))
+	defer s.Close()
+	assert.NoError(t, s.CheckHealth(context.Background()))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ln.Close()
+	down := NewRedisStore(NewRedisConfig().WithAddr(ln.Addr().String()))
+	assert.Error(t, down.CheckHealth(context.Background()))
+}
@@ -0,0 +1,182 @@
+package persistence
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+const defaultRetentionInterval = time.Hour
+
+// errOldEnough stops reading the journal at the first record that is kept.
+var errOldEnough = errors.New("old enough")
+
+// EventDeleter is implemented by the journals that can delete the records of
+// an actor, see Config.WithRetention.
+type EventDeleter interface {
+	// DeleteEvents deletes the records of the actor up to the given sequence
+	// number, included.
+	DeleteEvents(persistenceID string, toSeq uint64) error
+}
+
+// SnapshotDeleter is implemented by the snapshot stores that can delete the
+// old snapshots of an actor, see RetentionConfig.WithKeepSnapshots.
+type SnapshotDeleter interface {
+	// DeleteSnapshots deletes the snapshots of the actor but the last keep
+	// ones.
+	DeleteSnapshots(persistenceID string, keep int) error
+}
+
+// RetentionConfig decides which of the events and the snapshots of the actors
+// are deleted, see Config.WithRetention. The last event of an actor is always
+// kept, so the journal keeps numbering its events after it.
+type RetentionConfig struct {
+	deleteSnapshotted bool
+	keepSnapshots     int
+	maxAge            time.Duration
+	interval          time.Duration
+}
+
+// NewRetentionConfig returns a RetentionConfig that is initialized with
+// default values, which delete nothing.
+func NewRetentionConfig() RetentionConfig {
+	return RetentionConfig{interval: defaultRetentionInterval}
+}
+
+// WithDeleteSnapshottedEvents set's whether the events that are older than the
+// last snapshot of an actor are deleted once it's saved, as the actor no
+// longer needs them to recover.
+//
+// Defaults to false.
+func (config RetentionConfig) WithDeleteSnapshottedEvents(delete bool) RetentionConfig {
+	config.deleteSnapshotted = delete
+	return config
+}
+
+// WithKeepSnapshots set's the number of the last snapshots of an actor that
+// are kept when it saves one, so it can still recover from an older one if
+// the last one is corrupt.
+//
+// Defaults to 0, which keeps all of them.
+func (config RetentionConfig) WithKeepSnapshots(n int) RetentionConfig {
+	config.keepSnapshots = n
+	return config
+}
+
+// WithMaxAge set's the age from which the events of the running actors are
+// deleted, even when no snapshot has them, for the state that only needs a
+// recent history. The events are looked for every interval, see
+// WithInterval.
+//
+// Defaults to 0, which keeps the events whatever their age.
+func (config RetentionConfig) WithMaxAge(d time.Duration) RetentionConfig {
+	config.maxAge = d
+	return config
+}
+
+// WithInterval set's how often the events that are older than the max age are
+// looked for.
+//
+// Defaults to 1 hour.
+func (config RetentionConfig) WithInterval(d time.Duration) RetentionConfig {
+	config.interval = d
+	return config
+}
+
+func (config RetentionConfig) enabled() bool {
+	return config.deleteSnapshotted || config.keepSnapshots > 0 || config.maxAge > 0
+}
+
+// The messages of the compactor. An actor registers when it recovered, and
+// asks to compact once it saved a snapshot.
+type (
+	registerCompaction   struct{ persistenceID string }
+	unregisterCompaction struct{ persistenceID string }
+	compactSnapshot      struct {
+		persistenceID string
+		snapshotSeq   uint64
+	}
+	sweep struct{}
+)
+
+// compactor deletes the events and the snapshots of the actors of a config in
+// the background, so the actors don't wait for it.
+type compactor struct {
+	config   Config
+	actors   map[string]bool
+	repeater actor.SendRepeater
+}
+
+func newCompactor(config Config) actor.Producer {
+	return func() actor.Receiver {
+		return &compactor{config: config, actors: make(map[string]bool)}
+	}
+}
+
+func (c *compactor) Receive(ctx *actor.Context) {
+	switch msg := ctx.Message().(type) {
+	case actor.Started:
+		if c.config.retention.maxAge > 0 {
+			c.repeater = ctx.SendRepeat(ctx.PID(), sweep{}, c.config.retention.interval)
+		}
+	case actor.Stopped:
+		if c.config.retention.maxAge > 0 {
+			c.repeater.Stop()
+		}
+	case registerCompaction:
+		c.actors[msg.persistenceID] = true
+	case unregisterCompaction:
+		delete(c.actors, msg.persistenceID)
+	case compactSnapshot:
+		c.compact(msg)
+	case sweep:
+		for id := range c.actors {
+			if err := c.deleteOld(id); err != nil {
+				slog.Warn("failed to delete old events", "err", err, "id", id)
+			}
+		}
+	}
+}
+
+func (c *compactor) compact(msg compactSnapshot) {
+	retention := c.config.retention
+	if retention.deleteSnapshotted && msg.snapshotSeq > 1 {
+		// The event of the snapshot is the last one, or there are newer
+		// ones.
+		deleter := c.config.journal.(EventDeleter)
+		if err := deleter.DeleteEvents(msg.persistenceID, msg.snapshotSeq-1); err != nil {
+			slog.Warn("failed to delete snapshotted events", "err", err, "id", msg.persistenceID, "seq", msg.snapshotSeq)
+		}
+	}
+	if retention.keepSnapshots > 0 {
+		deleter := c.config.snapshots.(SnapshotDeleter)
+		if err := deleter.DeleteSnapshots(msg.persistenceID, retention.keepSnapshots); err != nil {
+			slog.Warn("failed to delete old snapshots", "err", err, "id", msg.persistenceID)
+		}
+	}
+}
+
+// deleteOld deletes the events of the actor that are older than the max age,
+// but the last one.
+func (c *compactor) deleteOld(persistenceID string) error {
+	cutoff := time.Now().Add(-c.config.retention.maxAge)
+	var old, last uint64
+	err := c.config.journal.Read(persistenceID, 1, func(record Record) error {
+		if !record.Time.Before(cutoff) {
+			return errOldEnough
+		}
+		old, last = last, record.Seq
+		return nil
+	})
+	if errors.Is(err, errOldEnough) {
+		old = last
+	} else if err != nil {
+		return err
+	}
+	if old == 0 {
+		return nil
+	}
+	return c.config.journal.(EventDeleter).DeleteEvents(persistenceID, old)
+}
@@ -0,0 +1,85 @@
+package persistence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readSeqs(t *testing.T, journal Journal, id string) []uint64 {
+	var seqs []uint64
+	require.NoError(t, journal.Read(id, 1, func(record Record) error {
+		seqs = append(seqs, record.Seq)
+		return nil
+	}))
+	return seqs
+}
+
+func TestRetentionSnapshotted(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	journal := NewMemoryJournal()
+	snapshots := NewMemorySnapshotStore()
+	config := NewConfig().
+		WithJournal(journal).
+		WithSnapshotStore(snapshots).
+		WithSnapshotEvery(2).
+		WithRetention(NewRetentionConfig().WithDeleteSnapshottedEvents(true).WithKeepSnapshots(1))
+	spawn := func() *actor.PID {
+		return e.Spawn(func() actor.Receiver { return &snapshotAccount{} }, "account", actor.WithID("1"),
+			actor.WithMiddleware(Middleware(config)))
+	}
+
+	pid := spawn()
+	for range 5 {
+		request(t, e, pid, deposit{Amount: 1})
+	}
+	// The snapshot of the 4th event keeps the events after the 3rd.
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]uint64{4, 5}, readSeqs(t, journal, "account/1"))
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		snapshots.mu.RLock()
+		defer snapshots.mu.RUnlock()
+		return len(snapshots.snapshots["account/1"]) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	<-e.Poison(pid).Done()
+	pid = spawn()
+	assert.Equal(t, 5, request(t, e, pid, getBalance{}))
+	assert.Equal(t, 6, request(t, e, pid, deposit{Amount: 1}))
+}
+
+func TestRetentionMaxAge(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	journal := NewMemoryJournal()
+	config := NewConfig().
+		WithJournal(journal).
+		WithRetention(NewRetentionConfig().WithMaxAge(50 * time.Millisecond).WithInterval(10 * time.Millisecond))
+	pid := e.Spawn(newAccount, "account", actor.WithID("1"), actor.WithMiddleware(Middleware(config)))
+	for range 3 {
+		request(t, e, pid, deposit{Amount: 1})
+	}
+	// The last event is kept.
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]uint64{3}, readSeqs(t, journal, "account/1"))
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 4, request(t, e, pid, deposit{Amount: 1}))
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]uint64{4}, readSeqs(t, journal, "account/1"))
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRetentionUnsupported(t *testing.T) {
+	journal := struct{ Journal }{NewMemoryJournal()}
+	assert.Panics(t, func() {
+		Middleware(NewConfig().WithJournal(journal).WithRetention(NewRetentionConfig().WithMaxAge(time.Hour)))
+	})
+	assert.Panics(t, func() {
+		Middleware(NewConfig().WithJournal(NewMemoryJournal()).WithRetention(NewRetentionConfig().WithKeepSnapshots(1)))
+	})
+}
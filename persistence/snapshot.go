@@ -0,0 +1,85 @@
+package persistence
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Snapshot is the state of an actor after the event with the sequence number
+// Seq, which is stored in a snapshot store.
+type Snapshot struct {
+	PersistenceID string
+	Seq           uint64
+	Time          time.Time
+	// Name is the name the type of the state is registered with by
+	// actor.RegisterEvent, and Data the encoded state.
+	Name string
+	Data []byte
+}
+
+// SnapshotStore stores the snapshots of the persistent actors.
+type SnapshotStore interface {
+	// Save stores the snapshot of an actor.
+	Save(snapshot Snapshot) error
+	// Load returns the snapshot of the actor with the highest sequence
+	// number, and false if it has none.
+	Load(persistenceID string) (Snapshot, bool, error)
+}
+
+// MemorySnapshotStore is a SnapshotStore that keeps the snapshots in memory.
+// Its Faults fail its reads and writes for testing.
+type MemorySnapshotStore struct {
+	Faults
+	mu        sync.RWMutex
+	snapshots map[string][]Snapshot
+}
+
+// NewMemorySnapshotStore returns an empty MemorySnapshotStore.
+func NewMemorySnapshotStore() *MemorySnapshotStore {
+	return &MemorySnapshotStore{snapshots: make(map[string][]Snapshot)}
+}
+
+// Save implements SnapshotStore.
+func (s *MemorySnapshotStore) Save(snapshot Snapshot) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot.Data = slices.Clone(snapshot.Data)
+	snapshots := append(s.snapshots[snapshot.PersistenceID], snapshot)
+	slices.SortStableFunc(snapshots, func(a, b Snapshot) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+	s.snapshots[snapshot.PersistenceID] = snapshots
+	return nil
+}
+
+// Load implements SnapshotStore.
+func (s *MemorySnapshotStore) Load(persistenceID string) (Snapshot, bool, error) {
+	if err := s.read(); err != nil {
+		return Snapshot{}, false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshots := s.snapshots[persistenceID]
+	if len(snapshots) == 0 {
+		return Snapshot{}, false, nil
+	}
+	return snapshots[len(snapshots)-1], true, nil
+}
+
+// DeleteSnapshots implements SnapshotDeleter.
+func (s *MemorySnapshotStore) DeleteSnapshots(persistenceID string, keep int) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snapshots := s.snapshots[persistenceID]; len(snapshots) > keep {
+		s.snapshots[persistenceID] = slices.Clone(snapshots[len(snapshots)-keep:])
+	}
+	return nil
+}
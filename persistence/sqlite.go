@@ -0,0 +1,314 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLiteStore is an OrderedJournal, a SnapshotStore and an OffsetStore in a
+// single SQLite database file, for a single node that needs its actors to be durable without a
+// database server. The driver is up to the caller, like
+// github.com/mattn/go-sqlite3 or modernc.org/sqlite.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// sqliteBusyTimeout is how long a write waits for the lock of the database,
+// which a single connection holds at a time, before it fails with
+// SQLITE_BUSY.
+const sqliteBusyTimeout = 5 * time.Second
+
+// NewSQLiteStore returns a store in the given database, which it switches to
+// the WAL mode, so the actors that recover don't block the ones that persist.
+// The tables are created if they don't exist, or upgraded.
+//
+// The connections of the pool of the database write in turns: the writes wait
+// for the lock of the database for up to 5 seconds, and the appends take it
+// before they read the last sequence number, with BEGIN IMMEDIATE, so the
+// actors that persist at once don't fail with SQLITE_BUSY. The reads don't
+// wait in the WAL mode.
+//
+//	db, err := sql.Open("sqlite3", "actors.db")
+//	...
+//	store, err := persistence.NewSQLiteStore(db)
+//	config := persistence.NewConfig().WithJournal(store).WithSnapshotStore(store)
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	var mode string
+	if err := db.QueryRow(`PRAGMA journal_mode=WAL`).Scan(&mode); err != nil {
+		return nil, fmt.Errorf("failed to enable the WAL mode: %w", err)
+	}
+	s := &SQLiteStore{db: db}
+	if err := s.writeTx(migrateSQLite); err != nil {
+		return nil, fmt.Errorf("failed to create the persistence tables: %w", err)
+	}
+	return s, nil
+}
+
+// writeConn returns a connection of the pool that waits for the lock of the
+// database instead of failing right away while another one writes. The busy
+// timeout is a setting of the connection, so it's set on each one it returns.
+func (s *SQLiteStore) writeConn(ctx context.Context) (*sql.Conn, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA busy_timeout = %d", sqliteBusyTimeout.Milliseconds())); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// write executes the given statement, waiting for the lock of the database.
+func (s *SQLiteStore) write(query string, args ...any) error {
+	ctx := context.Background()
+	conn, err := s.writeConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, query, args...)
+	return err
+}
+
+// writeTx invokes fn in a transaction that holds the lock of the database from
+// its start, so the rows fn reads don't change before it writes. It's
+// committed if fn returns nil, and rolled back otherwise.
+func (s *SQLiteStore) writeTx(fn func(ctx context.Context, conn *sql.Conn) error) (err error) {
+	ctx := context.Background()
+	conn, err := s.writeConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return err
+	}
+	if err := fn(ctx, conn); err != nil {
+		conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
+	_, err = conn.ExecContext(ctx, "COMMIT")
+	return err
+}
+
+// migrateSQLite creates the tables, and adds the position of the records in
+// the order of all the actors and their tags to a journal of a version
+// before them. The last position is kept in a table of its own, so the
+// positions of deleted records are not reused.
+func migrateSQLite(ctx context.Context, tx *sql.Conn) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS journal (
+			persistence_id TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			time INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			data BLOB NOT NULL,
+			position INTEGER,
+			tags TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (persistence_id, seq)
+		);
+		CREATE TABLE IF NOT EXISTS snapshots (
+			persistence_id TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			time INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			data BLOB NOT NULL,
+			PRIMARY KEY (persistence_id, seq)
+		);
+		CREATE TABLE IF NOT EXISTS projection_offsets (
+			projection TEXT NOT NULL PRIMARY KEY,
+			"offset" INTEGER NOT NULL
+		)`)
+	if err != nil {
+		return err
+	}
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('journal') WHERE name = 'position'`).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		_, err := tx.ExecContext(ctx, `
+			ALTER TABLE journal ADD COLUMN position INTEGER;
+			ALTER TABLE journal ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+			UPDATE journal SET position = rowid`)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS journal_position ON journal (position);
+		CREATE TABLE IF NOT EXISTS journal_last_position (position INTEGER NOT NULL);
+		INSERT INTO journal_last_position SELECT COALESCE((SELECT MAX(position) FROM journal), 0)
+			WHERE NOT EXISTS (SELECT 1 FROM journal_last_position)`)
+	return err
+}
+
+func encodeTags(tags []string) (string, error) {
+	if len(tags) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(tags)
+	return string(b), err
+}
+
+func decodeTags(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var tags []string
+	err := json.Unmarshal([]byte(s), &tags)
+	return tags, err
+}
+
+// CheckHealth returns an error if the database can't be reached, see
+// actor.HealthChecker.
+func (s *SQLiteStore) CheckHealth(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Append implements Journal. The records are inserted in a single
+// transaction.
+func (s *SQLiteStore) Append(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return s.writeTx(func(ctx context.Context, tx *sql.Conn) error {
+		id := records[0].PersistenceID
+		var last sql.NullInt64
+		if err := tx.QueryRowContext(ctx, `SELECT MAX(seq) FROM journal WHERE persistence_id = ?`, id).Scan(&last); err != nil {
+			return err
+		}
+		next := uint64(last.Int64) + 1
+		var position int64
+		if err := tx.QueryRowContext(ctx, `SELECT position FROM journal_last_position`).Scan(&position); err != nil {
+			return err
+		}
+		for _, record := range records {
+			if record.PersistenceID != id || record.Seq != next {
+				return ErrConflict
+			}
+			tags, err := encodeTags(record.Tags)
+			if err != nil {
+				return err
+			}
+			position++
+			_, err = tx.ExecContext(ctx, `INSERT INTO journal (persistence_id, seq, time, name, data, position, tags) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				record.PersistenceID, int64(record.Seq), record.Time.UnixNano(), record.Name, record.Data, position, tags)
+			if err != nil {
+				return err
+			}
+			next++
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE journal_last_position SET position = ?`, position)
+		return err
+	})
+}
+
+// Read implements Journal.
+func (s *SQLiteStore) Read(persistenceID string, from uint64, fn func(Record) error) error {
+	rows, err := s.db.Query(`SELECT seq, time, name, data FROM journal
+		WHERE persistence_id = ? AND seq >= ? ORDER BY seq`, persistenceID, int64(from))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		record := Record{PersistenceID: persistenceID}
+		var seq, t int64
+		if err := rows.Scan(&seq, &t, &record.Name, &record.Data); err != nil {
+			return err
+		}
+		record.Seq = uint64(seq)
+		record.Time = time.Unix(0, t)
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ReadAll implements OrderedJournal.
+func (s *SQLiteStore) ReadAll(from uint64, fn func(offset uint64, record Record) error) error {
+	rows, err := s.db.Query(`SELECT position, persistence_id, seq, time, name, data, tags FROM journal
+		WHERE position >= ? ORDER BY position`, int64(from))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			record           Record
+			position, seq, t int64
+			tags             string
+		)
+		if err := rows.Scan(&position, &record.PersistenceID, &seq, &t, &record.Name, &record.Data, &tags); err != nil {
+			return err
+		}
+		record.Seq = uint64(seq)
+		record.Time = time.Unix(0, t)
+		if record.Tags, err = decodeTags(tags); err != nil {
+			return err
+		}
+		if err := fn(uint64(position), record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Save implements SnapshotStore.
+func (s *SQLiteStore) Save(snapshot Snapshot) error {
+	return s.write(`INSERT OR REPLACE INTO snapshots (persistence_id, seq, time, name, data) VALUES (?, ?, ?, ?, ?)`,
+		snapshot.PersistenceID, int64(snapshot.Seq), snapshot.Time.UnixNano(), snapshot.Name, snapshot.Data)
+}
+
+// Load implements SnapshotStore.
+func (s *SQLiteStore) Load(persistenceID string) (Snapshot, bool, error) {
+	snapshot := Snapshot{PersistenceID: persistenceID}
+	var seq, t int64
+	err := s.db.QueryRow(`SELECT seq, time, name, data FROM snapshots
+		WHERE persistence_id = ? ORDER BY seq DESC LIMIT 1`, persistenceID).
+		Scan(&seq, &t, &snapshot.Name, &snapshot.Data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Snapshot{}, false, nil
+	}
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	snapshot.Seq = uint64(seq)
+	snapshot.Time = time.Unix(0, t)
+	return snapshot, true, nil
+}
+
+// LoadOffset implements OffsetStore.
+func (s *SQLiteStore) LoadOffset(projection string) (uint64, error) {
+	var offset int64
+	err := s.db.QueryRow(`SELECT "offset" FROM projection_offsets WHERE projection = ?`, projection).Scan(&offset)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return uint64(offset), err
+}
+
+// SaveOffset implements OffsetStore.
+func (s *SQLiteStore) SaveOffset(projection string, offset uint64) error {
+	return s.write(`INSERT OR REPLACE INTO projection_offsets (projection, "offset") VALUES (?, ?)`,
+		projection, int64(offset))
+}
+
+// DeleteEvents implements EventDeleter.
+func (s *SQLiteStore) DeleteEvents(persistenceID string, toSeq uint64) error {
+	return s.write(`DELETE FROM journal WHERE persistence_id = ? AND seq <= ?`, persistenceID, int64(toSeq))
+}
+
+// DeleteSnapshots implements SnapshotDeleter.
+func (s *SQLiteStore) DeleteSnapshots(persistenceID string, keep int) error {
+	return s.write(`DELETE FROM snapshots WHERE persistence_id = ? AND seq < (
+		SELECT seq FROM snapshots WHERE persistence_id = ? ORDER BY seq DESC LIMIT 1 OFFSET ?)`,
+		persistenceID, persistenceID, keep-1)
+}
@@ -0,0 +1,200 @@
+package persistence
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "actors.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLiteStore(t *testing.T) {
+	s, err := NewSQLiteStore(openSQLite(t))
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, s.Append([]Record{
+		{PersistenceID: "a", Seq: 1, Time: now, Name: "e", Data: []byte{1}},
+		{PersistenceID: "a", Seq: 2, Time: now, Name: "e", Data: []byte{2}},
+	}))
+	assert.ErrorIs(t, s.Append([]Record{{PersistenceID: "a", Seq: 2, Name: "e", Data: []byte{}}}), ErrConflict)
+	assert.ErrorIs(t, s.Append([]Record{{PersistenceID: "b", Seq: 2, Name: "e", Data: []byte{}}}), ErrConflict)
+	// The records of the batch that conflicted were not appended.
+	assert.ErrorIs(t, s.Append([]Record{
+		{PersistenceID: "a", Seq: 3, Name: "e", Data: []byte{3}},
+		{PersistenceID: "a", Seq: 5, Name: "e", Data: []byte{5}},
+	}), ErrConflict)
+	require.NoError(t, s.Append([]Record{{PersistenceID: "a", Seq: 3, Name: "e", Data: []byte{4}}}))
+
+	var records []Record
+	require.NoError(t, s.Read("a", 2, func(record Record) error {
+		records = append(records, record)
+		return nil
+	}))
+	require.Len(t, records, 2)
+	assert.Equal(t, uint64(2), records[0].Seq)
+	assert.Equal(t, []byte{2}, records[0].Data)
+	assert.True(t, now.Equal(records[0].Time))
+	assert.Equal(t, []byte{4}, records[1].Data)
+
+	require.NoError(t, s.DeleteEvents("a", 2))
+	var seqs []uint64
+	require.NoError(t, s.Read("a", 0, func(record Record) error {
+		seqs = append(seqs, record.Seq)
+		return nil
+	}))
+	assert.Equal(t, []uint64{3}, seqs)
+	// The actor keeps numbering its events after the deleted ones.
+	assert.ErrorIs(t, s.Append([]Record{{PersistenceID: "a", Seq: 1, Name: "e", Data: []byte{}}}), ErrConflict)
+}
+
+func TestSQLiteStoreReadAll(t *testing.T) {
+	s, err := NewSQLiteStore(openSQLite(t))
+	require.NoError(t, err)
+	require.NoError(t, s.Append([]Record{{PersistenceID: "b", Seq: 1, Name: "e", Data: []byte{}, Tags: []string{"x"}}}))
+	require.NoError(t, s.Append([]Record{{PersistenceID: "a", Seq: 1, Name: "e", Data: []byte{}}}))
+	require.NoError(t, s.Append([]Record{{PersistenceID: "b", Seq: 2, Name: "e", Data: []byte{}}}))
+
+	var records []string
+	require.NoError(t, s.ReadAll(2, func(offset uint64, record Record) error {
+		records = append(records, fmt.Sprintf("%d:%s/%d", offset, record.PersistenceID, record.Seq))
+		return nil
+	}))
+	// The records are read in the order they were appended in, whichever
+	// actor appended them.
+	assert.Equal(t, []string{"2:a/1", "3:b/2"}, records)
+	err = s.ReadAll(0, func(offset uint64, record Record) error {
+		assert.Equal(t, []string{"x"}, record.Tags)
+		return errStopScan
+	})
+	assert.ErrorIs(t, err, errStopScan)
+
+	// The positions of the deleted records are not reused.
+	require.NoError(t, s.DeleteEvents("b", 2))
+	require.NoError(t, s.Append([]Record{{PersistenceID: "c", Seq: 1, Name: "e", Data: []byte{}}}))
+	records = nil
+	require.NoError(t, s.ReadAll(3, func(offset uint64, record Record) error {
+		records = append(records, fmt.Sprintf("%d:%s/%d", offset, record.PersistenceID, record.Seq))
+		return nil
+	}))
+	assert.Equal(t, []string{"4:c/1"}, records)
+}
+
+func TestSQLiteStoreSnapshots(t *testing.T) {
+	s, err := NewSQLiteStore(openSQLite(t))
+	require.NoError(t, err)
+	_, ok, err := s.Load("a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	for _, seq := range []uint64{2, 10, 300} {
+		require.NoError(t, s.Save(Snapshot{PersistenceID: "a", Seq: seq, Name: "state", Data: []byte{byte(seq)}}))
+	}
+	require.NoError(t, s.Save(Snapshot{PersistenceID: "b", Seq: 1, Name: "state", Data: []byte{}}))
+	snapshot, ok, err := s.Load("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, uint64(300), snapshot.Seq)
+	assert.Equal(t, "state", snapshot.Name)
+	assert.Equal(t, []byte{byte(300 % 256)}, snapshot.Data)
+
+	require.NoError(t, s.DeleteSnapshots("a", 1))
+	var n int
+	require.NoError(t, s.db.QueryRow(`SELECT COUNT(*) FROM snapshots WHERE persistence_id = 'a'`).Scan(&n))
+	assert.Equal(t, 1, n)
+	snapshot, ok, err = s.Load("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, uint64(300), snapshot.Seq)
+	// The snapshots of the other actors are kept.
+	_, ok, err = s.Load("b")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestSQLiteStoreOffsets(t *testing.T) {
+	s, err := NewSQLiteStore(openSQLite(t))
+	require.NoError(t, err)
+	offset, err := s.LoadOffset("p")
+	require.NoError(t, err)
+	assert.Zero(t, offset)
+	require.NoError(t, s.SaveOffset("p", 3))
+	require.NoError(t, s.SaveOffset("p", 7))
+	offset, err = s.LoadOffset("p")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), offset)
+}
+
+func TestSQLiteStoreMigrate(t *testing.T) {
+	db := openSQLite(t)
+	// The journal of a version before the positions and the tags.
+	_, err := db.Exec(`
+		CREATE TABLE journal (
+			persistence_id TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			time INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			data BLOB NOT NULL,
+			PRIMARY KEY (persistence_id, seq)
+		);
+		INSERT INTO journal VALUES ('b', 1, 0, 'e', x'');
+		INSERT INTO journal VALUES ('a', 1, 0, 'e', x'');
+		INSERT INTO journal VALUES ('b', 2, 0, 'e', x'')`)
+	require.NoError(t, err)
+
+	s, err := NewSQLiteStore(db)
+	require.NoError(t, err)
+	require.NoError(t, s.Append([]Record{{PersistenceID: "a", Seq: 2, Name: "e", Data: []byte{}}}))
+	var records []string
+	require.NoError(t, s.ReadAll(0, func(offset uint64, record Record) error {
+		records = append(records, fmt.Sprintf("%d:%s/%d", offset, record.PersistenceID, record.Seq))
+		return nil
+	}))
+	assert.Equal(t, []string{"1:b/1", "2:a/1", "3:b/2", "4:a/2"}, records)
+
+	// Upgrading the journal again changes nothing.
+	_, err = NewSQLiteStore(db)
+	require.NoError(t, err)
+	var last int
+	require.NoError(t, db.QueryRow(`SELECT position FROM journal_last_position`).Scan(&last))
+	assert.Equal(t, 4, last)
+}
+
+func TestSQLiteStoreConcurrentAppend(t *testing.T) {
+	db := openSQLite(t)
+	s, err := NewSQLiteStore(db)
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := fmt.Sprintf("actor-%d", i)
+			for seq := uint64(1); seq <= 20; seq++ {
+				assert.NoError(t, s.Append([]Record{{PersistenceID: id, Seq: seq, Name: "e", Data: []byte{}}}))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var offsets []uint64
+	require.NoError(t, s.ReadAll(0, func(offset uint64, record Record) error {
+		offsets = append(offsets, offset)
+		return nil
+	}))
+	require.Len(t, offsets, 160)
+	for i, offset := range offsets {
+		assert.Equal(t, uint64(i+1), offset)
+	}
+}
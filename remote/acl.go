@@ -0,0 +1,133 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+
+	"storj.io/drpc/drpcctx"
+)
+
+var errPeerRejected = errors.New("peer rejected")
+
+// PeerInfo describes a peer that connects to the remote.
+type PeerInfo struct {
+	// Address is the listen address of the peer, as sent in its handshake.
+	// It is empty for peers running a version without the handshake.
+	Address string
+	// RemoteAddr is the network address the connection comes from.
+	RemoteAddr net.Addr
+	// Certificates holds the certificate chain the peer presented, if the
+	// connection is secured by TLS with client certificates. The first
+	// certificate is the one of the peer.
+	Certificates []*x509.Certificate
+}
+
+// ACL decides which peers are allowed to deliver messages to the remote. It
+// is evaluated once per connection, at the handshake. A peer needs to pass
+// all the rules that are set.
+type ACL struct {
+	// Allow holds the network ranges peers may connect from. Empty allows
+	// all the ranges.
+	Allow []netip.Prefix
+	// Deny holds the network ranges peers may not connect from, even when
+	// they are in an allowed range.
+	Deny []netip.Prefix
+	// SANs holds the subject alternative names of which the certificate of
+	// a peer must hold at least one, like DNS names, IPs or SPIFFE URIs.
+	// This requires the TLS config of the remote to verify the client
+	// certificates.
+	SANs []string
+	// Authorize is called for the peers passing all the other rules. A
+	// returned error rejects the peer.
+	Authorize func(PeerInfo) error
+}
+
+func (a ACL) enabled() bool {
+	return len(a.Allow) > 0 || len(a.Deny) > 0 || len(a.SANs) > 0 || a.Authorize != nil
+}
+
+// check returns an error describing why the given peer is not allowed.
+func (a ACL) check(peer PeerInfo) error {
+	if len(a.Allow) > 0 || len(a.Deny) > 0 {
+		ip, ok := remoteIP(peer.RemoteAddr)
+		contains := func(p netip.Prefix) bool { return ok && p.Contains(ip) }
+		if slices.ContainsFunc(a.Deny, contains) {
+			return fmt.Errorf("%s is in a denied range", peer.RemoteAddr)
+		}
+		if len(a.Allow) > 0 && !slices.ContainsFunc(a.Allow, contains) {
+			return fmt.Errorf("%s is not in an allowed range", peer.RemoteAddr)
+		}
+	}
+	if len(a.SANs) > 0 {
+		if len(peer.Certificates) == 0 {
+			return errors.New("no client certificate")
+		}
+		if !slices.ContainsFunc(subjectAltNames(peer.Certificates[0]), func(name string) bool {
+			return slices.Contains(a.SANs, name)
+		}) {
+			return errors.New("client certificate does not hold an allowed name")
+		}
+	}
+	if a.Authorize != nil {
+		return a.Authorize(peer)
+	}
+	return nil
+}
+
+// remoteIP returns the IP of the given address, false if it has none, like
+// the address of a unix socket.
+func remoteIP(addr net.Addr) (netip.Addr, bool) {
+	if addr == nil {
+		return netip.Addr{}, false
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
+
+func subjectAltNames(cert *x509.Certificate) []string {
+	names := slices.Clone(cert.DNSNames)
+	names = append(names, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
+
+// tlsConn is implemented by the connections of the transports secured by
+// TLS.
+type tlsConn interface {
+	ConnectionState() tls.ConnectionState
+}
+
+// peerInfo returns the info of the peer at the other end of the connection
+// the stream with the given context runs on.
+func peerInfo(ctx context.Context, address string) PeerInfo {
+	peer := PeerInfo{Address: address}
+	tr, ok := drpcctx.Transport(ctx)
+	if !ok {
+		return peer
+	}
+	if conn, ok := tr.(net.Conn); ok {
+		peer.RemoteAddr = conn.RemoteAddr()
+	}
+	if conn, ok := tr.(tlsConn); ok {
+		peer.Certificates = conn.ConnectionState().PeerCertificates
+	}
+	return peer
+}
@@ -0,0 +1,150 @@
+package remote
+
+import (
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestACLCheck(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 5), Port: 4000}
+	spiffe, _ := url.Parse("spiffe://example.org/node")
+	cert := &x509.Certificate{DNSNames: []string{"node-1.example.org"}, URIs: []*url.URL{spiffe}}
+
+	tests := []struct {
+		name    string
+		acl     ACL
+		peer    PeerInfo
+		allowed bool
+	}{
+		{"allowed range", ACL{Allow: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}, PeerInfo{RemoteAddr: addr}, true},
+		{"other range", ACL{Allow: []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")}}, PeerInfo{RemoteAddr: addr}, false},
+		{"denied range", ACL{
+			Allow: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			Deny:  []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")},
+		}, PeerInfo{RemoteAddr: addr}, false},
+		{"mapped ipv4", ACL{Allow: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}, PeerInfo{RemoteAddr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 5).To16()}}, true},
+		{"unix socket", ACL{Allow: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}, PeerInfo{RemoteAddr: &net.UnixAddr{Name: "/tmp/sock", Net: "unix"}}, false},
+		{"dns san", ACL{SANs: []string{"node-1.example.org"}}, PeerInfo{Certificates: []*x509.Certificate{cert}}, true},
+		{"uri san", ACL{SANs: []string{"spiffe://example.org/node"}}, PeerInfo{Certificates: []*x509.Certificate{cert}}, true},
+		{"other san", ACL{SANs: []string{"node-2.example.org"}}, PeerInfo{Certificates: []*x509.Certificate{cert}}, false},
+		{"no certificate", ACL{SANs: []string{"node-1.example.org"}}, PeerInfo{}, false},
+		{"callback", ACL{Authorize: func(p PeerInfo) error {
+			if p.Address != "10.0.0.5:4000" {
+				return errors.New("unknown peer")
+			}
+			return nil
+		}}, PeerInfo{Address: "10.0.0.5:4000"}, true},
+		{"callback rejects", ACL{Authorize: func(PeerInfo) error { return errors.New("unknown peer") }}, PeerInfo{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.allowed, test.acl.check(test.peer) == nil)
+		})
+	}
+}
+
+func TestACLRejectsPeer(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	acl := ACL{Deny: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}}
+	b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithACL(acl))
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	rejected := make(chan PeerRejectedEvent, 1)
+	b.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case PeerRejectedEvent:
+			rejected <- msg
+		}
+	}, "listener")
+	received := make(chan struct{}, 1)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(*TestMessage); ok {
+			received <- struct{}{}
+		}
+	}, "receiver")
+	time.Sleep(10 * time.Millisecond)
+
+	a.Send(pid, &TestMessage{Data: []byte("foo")})
+	select {
+	case evt := <-rejected:
+		assert.Equal(t, ra.Address(), evt.Address)
+		assert.NotEmpty(t, evt.RemoteAddr)
+		assert.Contains(t, evt.Reason, "denied")
+	case <-time.After(time.Second):
+		t.Fatal("expected a PeerRejectedEvent")
+	}
+	select {
+	case <-received:
+		t.Fatal("message of a rejected peer was delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestACLAuthorize(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	peers := make(chan PeerInfo, 1)
+	acl := ACL{Authorize: func(p PeerInfo) error {
+		peers <- p
+		return nil
+	}}
+	b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithACL(acl))
+	require.NoError(t, err)
+	defer rb.Stop()
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			c.Respond(msg)
+		}
+	}, "echo")
+
+	_, err = a.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	require.NoError(t, err)
+	peer := <-peers
+	assert.Equal(t, ra.Address(), peer.Address)
+	require.NotNil(t, peer.RemoteAddr)
+	ip, ok := remoteIP(peer.RemoteAddr)
+	assert.True(t, ok)
+	assert.True(t, ip.IsLoopback())
+}
+
+// The names in the client certificate of a peer are checked against the SANs
+// of the ACL.
+func TestACLCertificateSANs(t *testing.T) {
+	tlsConfig, err := generateTLSConfig()
+	require.NoError(t, err)
+	a, ra, err := makeRemoteEngineTls(getRandomLocalhostAddr(), tlsConfig.peer1Config)
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	for _, test := range []struct {
+		san     string
+		allowed bool
+	}{{"localhost", true}, {"other.example.org", false}} {
+		config := NewConfig().WithTLS(tlsConfig.peer2Config).WithACL(ACL{SANs: []string{test.san}})
+		b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+		require.NoError(t, err)
+		pid := b.SpawnFunc(func(c *actor.Context) {
+			if msg, ok := c.Message().(*TestMessage); ok {
+				c.Respond(msg)
+			}
+		}, "echo")
+		_, err = a.Request(pid, &TestMessage{Data: []byte("foo")}, 500*time.Millisecond).Result()
+		assert.Equal(t, test.allowed, err == nil, "san %s: %v", test.san, err)
+		rb.Stop().Wait()
+	}
+}
@@ -0,0 +1,59 @@
+package remote
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// advertisedAddress returns the address the remote advertises to its peers,
+// which is the address in the PIDs of the actors of its engine. Only the
+// parts of the listen address that are overridden by the config change,
+// hence the scheme and the path of the listen address are kept.
+func advertisedAddress(listenAddr string, config Config) (string, error) {
+	if len(config.AdvertisedAddr) == 0 && config.PortMapping == nil {
+		return listenAddr, nil
+	}
+	if strings.Contains(config.AdvertisedAddr, schemeSeparator) {
+		return config.AdvertisedAddr, nil
+	}
+	scheme, hostport := splitScheme(listenAddr)
+	if scheme == unixScheme {
+		return listenAddr, nil
+	}
+	var path string
+	if scheme == wsScheme || scheme == wssScheme {
+		if i := strings.Index(hostport, "/"); i >= 0 {
+			hostport, path = hostport[:i], hostport[i:]
+		}
+	}
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", fmt.Errorf("invalid listen address %s: %w", listenAddr, err)
+	}
+	if len(config.AdvertisedAddr) > 0 {
+		if advHost, advPort, err := net.SplitHostPort(config.AdvertisedAddr); err == nil {
+			// An explicit port is advertised as is.
+			return joinAddress(scheme, advHost, advPort, path), nil
+		}
+		// The advertised address only holds the host.
+		host = config.AdvertisedAddr
+	}
+	if config.PortMapping != nil {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return "", fmt.Errorf("invalid port in listen address %s: %w", listenAddr, err)
+		}
+		port = strconv.Itoa(config.PortMapping(p))
+	}
+	return joinAddress(scheme, host, port, path), nil
+}
+
+func joinAddress(scheme, host, port, path string) string {
+	addr := net.JoinHostPort(host, port) + path
+	if len(scheme) > 0 {
+		addr = scheme + schemeSeparator + addr
+	}
+	return addr
+}
@@ -0,0 +1,62 @@
+package remote
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdvertisedAddress(t *testing.T) {
+	plus1000 := func(port int) int { return port + 1000 }
+	tests := []struct {
+		listenAddr string
+		config     Config
+		expected   string
+	}{
+		{"0.0.0.0:4000", NewConfig(), "0.0.0.0:4000"},
+		{"0.0.0.0:4000", NewConfig().WithAdvertisedAddress("10.0.0.1:5000"), "10.0.0.1:5000"},
+		{"0.0.0.0:4000", NewConfig().WithAdvertisedAddress("10.0.0.1"), "10.0.0.1:4000"},
+		{"0.0.0.0:4000", NewConfig().WithPortMapping(plus1000), "0.0.0.0:5000"},
+		{"0.0.0.0:4000", NewConfig().WithAdvertisedAddress("node.example.com").WithPortMapping(plus1000), "node.example.com:5000"},
+		{"0.0.0.0:4000", NewConfig().WithAdvertisedAddress("node.example.com:6000").WithPortMapping(plus1000), "node.example.com:6000"},
+		{"quic://0.0.0.0:4000", NewConfig().WithAdvertisedAddress("10.0.0.1"), "quic://10.0.0.1:4000"},
+		{"ws://0.0.0.0:4000/hollywood", NewConfig().WithAdvertisedAddress("10.0.0.1"), "ws://10.0.0.1:4000/hollywood"},
+		{"ws://0.0.0.0:4000", NewConfig().WithAdvertisedAddress("wss://proxy.example.com/hollywood"), "wss://proxy.example.com/hollywood"},
+		{"0.0.0.0:4000", NewConfig().WithAdvertisedAddress("::1"), "[::1]:4000"},
+		{"unix:///tmp/a.sock", NewConfig().WithAdvertisedAddress("10.0.0.1"), "unix:///tmp/a.sock"},
+	}
+	for _, test := range tests {
+		addr, err := advertisedAddress(test.listenAddr, test.config)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, addr, test.listenAddr)
+	}
+
+	_, err := advertisedAddress("foo", NewConfig().WithAdvertisedAddress("10.0.0.1"))
+	assert.Error(t, err)
+}
+
+func TestRequestResponseAdvertisedAddress(t *testing.T) {
+	listenAddr := getRandomLocalhostAddr()
+	port := listenAddr[strings.LastIndex(listenAddr, ":")+1:]
+	config := NewConfig().WithAdvertisedAddress("127.0.0.1")
+	a, ra, err := makeRemoteEngineWithConfig(strings.Replace(listenAddr, "localhost", "0.0.0.0", 1), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	assert.Equal(t, "127.0.0.1:"+port, a.Address())
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			c.Respond(msg)
+		}
+	}, "echo")
+	resp, err := a.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo"), resp.(*TestMessage).Data)
+}
@@ -0,0 +1,73 @@
+package remote
+
+import (
+	"sync"
+	"time"
+)
+
+// BandwidthConfig configures a token bucket that limits the rate of the bytes
+// sent to a peer. The limit is shared by all the streams to the peer.
+type BandwidthConfig struct {
+	// BytesPerSecond is the number of bytes sent to the peer per second.
+	// Zero disables the limit.
+	BytesPerSecond int
+	// Burst is the number of bytes that may be sent at once, before the rate
+	// applies. It defaults to BytesPerSecond.
+	Burst int
+}
+
+// bandwidthLimits holds the throttles of the peers of a remote.
+type bandwidthLimits struct {
+	config BandwidthConfig
+	peers  map[string]BandwidthConfig
+
+	mu        sync.Mutex
+	throttles map[string]*throttle
+}
+
+func newBandwidthLimits(config Config) *bandwidthLimits {
+	return &bandwidthLimits{
+		config:    config.Bandwidth,
+		peers:     config.PeerBandwidth,
+		throttles: make(map[string]*throttle),
+	}
+}
+
+// throttle returns the throttle of the peer with the given address, creating
+// it if needed. It returns nil when the bandwidth of the peer is not limited.
+func (b *bandwidthLimits) throttle(address string) *throttle {
+	config, ok := b.peers[address]
+	if !ok {
+		config = b.config
+	}
+	if config.BytesPerSecond <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.throttles[address]
+	if !ok {
+		limit := RateLimitConfig{Rate: float64(config.BytesPerSecond), Burst: config.Burst}
+		t = &throttle{limiter: newRateLimiter(limit, time.Now())}
+		b.throttles[address] = t
+	}
+	return t
+}
+
+// throttle limits the rate of the bytes sent to a single peer.
+type throttle struct {
+	mu      sync.Mutex
+	limiter *rateLimiter
+}
+
+// reserve takes n bytes of the bucket and returns the time to wait before
+// sending them. Envelopes larger than the burst are sent once the bucket
+// has been refilled by their size.
+func (t *throttle) reserve(n int) time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.limiter.reserve(time.Now(), float64(n))
+}
@@ -0,0 +1,105 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthLimits(t *testing.T) {
+	config := NewConfig().
+		WithBandwidth(BandwidthConfig{BytesPerSecond: 1000}).
+		WithPeerBandwidth("127.0.0.1:4001", BandwidthConfig{}).
+		WithPeerBandwidth("127.0.0.1:4002", BandwidthConfig{BytesPerSecond: 10, Burst: 100})
+	b := newBandwidthLimits(config)
+
+	assert.Nil(t, b.throttle("127.0.0.1:4001"))
+	assert.Zero(t, (*throttle)(nil).reserve(1<<20))
+	th := b.throttle("127.0.0.1:4000")
+	require.NotNil(t, th)
+	assert.Same(t, th, b.throttle("127.0.0.1:4000"))
+	assert.Zero(t, th.reserve(1000))
+	assert.InDelta(t, 500*time.Millisecond, th.reserve(500), float64(10*time.Millisecond))
+
+	// Envelopes larger than the burst wait for the bucket to refill.
+	th = b.throttle("127.0.0.1:4002")
+	assert.Zero(t, th.reserve(100))
+	assert.InDelta(t, 10*time.Second, th.reserve(100), float64(100*time.Millisecond))
+}
+
+func TestBandwidthThrottlesSender(t *testing.T) {
+	config := NewConfig().WithBandwidth(BandwidthConfig{BytesPerSecond: 100_000, Burst: 10_000})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	const n = 10
+	received := make(chan struct{}, n)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(*TestMessage); ok {
+			received <- struct{}{}
+		}
+	}, "receiver")
+
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		a.Send(pid, &TestMessage{Data: make([]byte, 10_000)})
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("throttled messages were not delivered")
+		}
+	}
+	// 100KB at 100KB/s, of which the burst is sent right away.
+	assert.Greater(t, time.Since(start), 700*time.Millisecond)
+}
+
+// A priority message is sent while a large message waits for the bandwidth
+// limit, instead of after it.
+func TestBandwidthPriorityWhileThrottled(t *testing.T) {
+	config := NewConfig().WithBandwidth(BandwidthConfig{BytesPerSecond: 10_000, Burst: 1_000})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	received := make(chan int, 3)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- len(msg.Data)
+		}
+	}, "receiver")
+	// Connect first, so the large message is throttled by the writer.
+	a.Send(pid, &TestMessage{Data: []byte("foo")})
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("message was not delivered")
+	}
+
+	a.Send(pid, &TestMessage{Data: make([]byte, 20_000)})
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	a.SendPriority(pid, &TestMessage{Data: []byte("bar")})
+	for _, expected := range []int{3, 20_000} {
+		select {
+		case size := <-received:
+			assert.Equal(t, expected, size)
+		case <-time.After(5 * time.Second):
+			t.Fatal("message was not delivered")
+		}
+		if expected == 3 {
+			assert.Less(t, time.Since(start), time.Second)
+		}
+	}
+}
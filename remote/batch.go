@@ -0,0 +1,105 @@
+package remote
+
+import (
+	"log/slog"
+	"time"
+)
+
+const defaultBatchSize = 64 * 1024
+
+// BatchConfig configures the batching of the messages sent to a peer. The
+// messages are held back until enough of them are collected, so they are
+// sent in a single envelope and write.
+type BatchConfig struct {
+	// MaxSize is the number of payload bytes at which the collected
+	// messages are sent. It defaults to 64KB.
+	MaxSize int
+	// MaxDelay is the longest time a message is held back. Zero disables
+	// batching.
+	MaxDelay time.Duration
+}
+
+func (c BatchConfig) enabled() bool {
+	return c.MaxDelay > 0
+}
+
+func (c BatchConfig) maxSize() int {
+	if c.MaxSize <= 0 {
+		return defaultBatchSize
+	}
+	return c.MaxSize
+}
+
+// holdBatch sends the batch once it is large enough, otherwise it makes sure
+// it is sent after the maximum delay. The caller must hold the lock.
+func (s *streamWriter) holdBatch() error {
+	if len(s.batch.messages) == 0 {
+		// None of the messages made it into the batch.
+		s.forget(s.batched)
+		s.batched = nil
+		return nil
+	}
+	if s.batch.size >= s.batching.maxSize() {
+		return s.flushBatch()
+	}
+	if s.batchTimer == nil {
+		s.batchTimer = time.AfterFunc(s.batching.MaxDelay, s.flushBatchTimer)
+	}
+	return nil
+}
+
+// flushBatch sends the batch. The caller must hold the lock.
+func (s *streamWriter) flushBatch() error {
+	if err := s.send(s.batch); err != nil {
+		return err
+	}
+	s.forget(s.batched)
+	s.batched = nil
+	s.stopBatchTimer()
+	return nil
+}
+
+func (s *streamWriter) flushBatchTimer() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waitWrite()
+	s.batchTimer = nil
+	if s.stream == nil || s.closed {
+		return
+	}
+	if err := s.flushBatch(); err != nil {
+		slog.Error("stream writer failed sending batch", "err", err, "remote", s.writeToAddr)
+		s.buffer(s.takeBatch())
+		s.disconnectLocked(err)
+	}
+}
+
+func (s *streamWriter) stopBatchTimer() {
+	if s.batchTimer != nil {
+		s.batchTimer.Stop()
+		s.batchTimer = nil
+	}
+}
+
+// takeBatch removes the messages of the batch, which were not sent, and
+// returns their deliveries. The caller must hold the lock.
+func (s *streamWriter) takeBatch() []*streamDeliver {
+	if s.batch == nil {
+		return nil
+	}
+	deliveries := s.batched
+	s.batched = nil
+	s.batch.reset()
+	s.stopBatchTimer()
+	return deliveries
+}
+
+// unsent returns the deliveries to buffer after writing the given ones
+// failed. With batching those are part of the batch, together with the ones
+// held back before. The caller must hold the lock.
+func (s *streamWriter) unsent(deliveries []*streamDeliver) []*streamDeliver {
+	if s.batch == nil {
+		return deliveries
+	}
+	return s.takeBatch()
+}
@@ -0,0 +1,76 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Small messages are held back until the maximum delay passed, large ones are
+// sent right away.
+func TestBatchingHoldsBackMessages(t *testing.T) {
+	config := NewConfig().WithBatching(BatchConfig{MaxSize: 1024, MaxDelay: 200 * time.Millisecond})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	received := make(chan int, 1)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- len(msg.Data)
+		}
+	}, "receiver")
+	wait := func(size int) time.Duration {
+		start := time.Now()
+		a.Send(pid, &TestMessage{Data: make([]byte, size)})
+		select {
+		case n := <-received:
+			assert.Equal(t, size, n)
+		case <-time.After(time.Second):
+			t.Fatal("batched message was not delivered")
+		}
+		return time.Since(start)
+	}
+
+	// The first message also waits for the connection.
+	wait(10)
+	assert.GreaterOrEqual(t, wait(10), 150*time.Millisecond)
+	assert.Less(t, wait(2048), 150*time.Millisecond)
+}
+
+func TestBatchingKeepsOrder(t *testing.T) {
+	config := NewConfig().WithBatching(BatchConfig{MaxDelay: time.Millisecond})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	const n = 10000
+	done := make(chan struct{})
+	next := 0
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			assert.Equal(t, next, int(msg.Data[0])|int(msg.Data[1])<<8)
+			next++
+			if next == n {
+				close(done)
+			}
+		}
+	}, "receiver")
+	for i := 0; i < n; i++ {
+		a.Send(pid, &TestMessage{Data: []byte{byte(i), byte(i >> 8)}})
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not all messages were received")
+	}
+}
@@ -0,0 +1,162 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownKey is returned by a KeyProvider that doesn't know the requested key.
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// errNotEncrypted is the error of the messages that were sent without being
+// encrypted to a remote with encryption.
+var errNotEncrypted = errors.New("message is not encrypted")
+
+// KeyProvider provides the AES keys that are used to encrypt the payloads of
+// the messages. A key must be 16, 24 or 32 bytes long.
+type KeyProvider interface {
+	// CurrentKey returns the key that is used to encrypt outbound messages
+	// and its id. The id is sent along with the message.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given id, which is used to decrypt
+	// inbound messages.
+	Key(id string) ([]byte, error)
+}
+
+// KeyRing is a KeyProvider that holds a set of keys in memory. Messages are
+// encrypted with the current key, while the keys that were rotated out can
+// still be used to decrypt messages until they are removed.
+type KeyRing struct {
+	mu      sync.RWMutex
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyRing returns a KeyRing with the given current key.
+func NewKeyRing(id string, key []byte) *KeyRing {
+	return &KeyRing{
+		current: id,
+		keys:    map[string][]byte{id: key},
+	}
+}
+
+// Rotate makes the given key the current key. The previous keys are kept, so
+// messages that are in flight, or sent by peers that did not rotate yet, can
+// still be decrypted.
+func (k *KeyRing) Rotate(id string, key []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[id] = key
+	k.current = id
+}
+
+// Remove removes the key with the given id, unless it is the current key.
+func (k *KeyRing) Remove(id string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if id != k.current {
+		delete(k.keys, id)
+	}
+}
+
+func (k *KeyRing) CurrentKey() (string, []byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current, k.keys[k.current], nil
+}
+
+func (k *KeyRing) Key(id string) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// payloadCipher encrypts and decrypts message payloads with AES-GCM. The
+// type name of the message is authenticated along with the payload.
+type payloadCipher struct {
+	keys KeyProvider
+
+	mu    sync.RWMutex
+	aeads map[string]cachedAEAD
+}
+
+type cachedAEAD struct {
+	key  []byte
+	aead cipher.AEAD
+}
+
+func newPayloadCipher(keys KeyProvider) *payloadCipher {
+	if keys == nil {
+		return nil
+	}
+	return &payloadCipher{
+		keys:  keys,
+		aeads: make(map[string]cachedAEAD),
+	}
+}
+
+// encrypt returns the encrypted data, prefixed with the nonce, and the id of
+// the key it is encrypted with.
+func (c *payloadCipher) encrypt(data []byte, tname string) ([]byte, string, error) {
+	id, key, err := c.keys.CurrentKey()
+	if err != nil {
+		return nil, "", err
+	}
+	aead, err := c.aead(id, key)
+	if err != nil {
+		return nil, "", err
+	}
+	out := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(out); err != nil {
+		return nil, "", err
+	}
+	return aead.Seal(out, out, data, []byte(tname)), id, nil
+}
+
+func (c *payloadCipher) decrypt(data []byte, tname, keyID string) ([]byte, error) {
+	key, err := c.keys.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := c.aead(keyID, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted payload too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(tname))
+}
+
+// aead returns the cipher of the given key, which is cached by the id of the
+// key as long as the key of the id doesn't change.
+func (c *payloadCipher) aead(id string, key []byte) (cipher.AEAD, error) {
+	c.mu.RLock()
+	cached, ok := c.aeads[id]
+	c.mu.RUnlock()
+	if ok && bytes.Equal(cached.key, key) {
+		return cached.aead, nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %q: %w", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.aeads[id] = cachedAEAD{key: bytes.Clone(key), aead: aead}
+	c.mu.Unlock()
+	return aead, nil
+}
@@ -0,0 +1,111 @@
+package remote
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestPayloadCipher(t *testing.T) {
+	keys := NewKeyRing("1", testKey(1))
+	c := newPayloadCipher(keys)
+	data := []byte("secret message")
+
+	encrypted, keyID, err := c.encrypt(data, "remote.TestMessage")
+	require.NoError(t, err)
+	assert.Equal(t, "1", keyID)
+	assert.False(t, bytes.Contains(encrypted, data))
+
+	decrypted, err := c.decrypt(encrypted, "remote.TestMessage", keyID)
+	require.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+
+	// The type name is authenticated.
+	_, err = c.decrypt(encrypted, "remote.Other", keyID)
+	assert.Error(t, err)
+
+	// Messages encrypted with a rotated key can be decrypted until the key
+	// is removed.
+	keys.Rotate("2", testKey(2))
+	_, keyID2, err := c.encrypt(data, "remote.TestMessage")
+	require.NoError(t, err)
+	assert.Equal(t, "2", keyID2)
+	_, err = c.decrypt(encrypted, "remote.TestMessage", keyID)
+	require.NoError(t, err)
+
+	keys.Remove("1")
+	_, err = c.decrypt(encrypted, "remote.TestMessage", keyID)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestPayloadCipherInvalidKey(t *testing.T) {
+	c := newPayloadCipher(NewKeyRing("1", []byte("too short")))
+	_, _, err := c.encrypt([]byte("foo"), "remote.TestMessage")
+	assert.Error(t, err)
+}
+
+func TestEncryption(t *testing.T) {
+	config := NewConfig().WithEncryption(NewKeyRing("1", testKey(1))).WithChunking(100)
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithEncryption(NewKeyRing("1", testKey(1))))
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	received := make(chan []byte, 2)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- msg.Data
+		}
+	}, "receiver")
+
+	large := bytes.Repeat([]byte("foo"), 1000)
+	a.Send(pid, &TestMessage{Data: []byte("small")})
+	a.Send(pid, &TestMessage{Data: large})
+	for _, expected := range [][]byte{[]byte("small"), large} {
+		select {
+		case data := <-received:
+			assert.Equal(t, expected, data)
+		case <-time.After(time.Second):
+			t.Fatal("message was not received")
+		}
+	}
+}
+
+// A remote with encryption rejects the messages of a peer without it.
+func TestEncryptionRejectsPlaintext(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithEncryption(NewKeyRing("1", testKey(1))))
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	received := make(chan *TestMessage, 1)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- msg
+		}
+	}, "receiver")
+
+	_, err = a.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	var rejected MessageRejectedEvent
+	require.ErrorAs(t, err, &rejected)
+	assert.Equal(t, NackReason_Undecryptable, rejected.Reason)
+	assert.Equal(t, errNotEncrypted.Error(), rejected.Err)
+	assert.Equal(t, []byte("foo"), rejected.Message.(*TestMessage).Data)
+	select {
+	case msg := <-received:
+		t.Fatalf("the receiver received %v", msg)
+	default:
+	}
+}
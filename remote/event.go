@@ -0,0 +1,229 @@
+package remote
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+// MessageDroppedEvent gets published when a message for a remote is dropped
+// because the buffer that holds the messages while the remote is not
+// connected is full.
+type MessageDroppedEvent struct {
+	// The listen address of the remote the message was sent to.
+	Address string
+	Target  *actor.PID
+	Sender  *actor.PID
+	Message any
+}
+
+func (e MessageDroppedEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelWarn, "Remote message dropped", []any{"remote", e.Address, "target", e.Target}
+}
+
+// MessageTooLargeEvent gets published when a serialized message exceeds the
+// maximum message size of the remote. The event is also sent to the sender of
+// the message, if any. It implements the error interface, hence it is
+// returned as the error of Response.Result for requests.
+type MessageTooLargeEvent struct {
+	// The listen address of the remote the message was sent to.
+	Address string
+	Target  *actor.PID
+	Sender  *actor.PID
+	Message any
+	// Size is the size of the serialized message in bytes.
+	Size    int
+	MaxSize int
+}
+
+func (e MessageTooLargeEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelError, "Remote message too large", []any{"remote", e.Address, "target", e.Target, "size", e.Size, "max", e.MaxSize}
+}
+
+func (e MessageTooLargeEvent) Error() string {
+	return fmt.Sprintf("message of %d bytes exceeds the maximum message size of %d bytes", e.Size, e.MaxSize)
+}
+
+// MessageRejectedEvent is broadcasted when the remote a message was sent to
+// could not deliver it, for example because the target doesn't exist. The
+// message is also published as a DeadLetterEvent. Like the
+// MessageTooLargeEvent it implements the error interface, and it's sent to the
+// senders that are requests, so they fail right away. The actors that want to
+// know about the rejections of their messages subscribe to it.
+type MessageRejectedEvent struct {
+	// The listen address of the remote the message was sent to.
+	Address string
+	Target  *actor.PID
+	Sender  *actor.PID
+	// Message is the original message, nil if it could not be decoded or
+	// was too large to be sent back.
+	Message any
+	Reason  NackReason
+	// Err is the error of the remote, if any.
+	Err string
+}
+
+func (e MessageRejectedEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelWarn, "Remote message rejected", []any{"remote", e.Address, "target", e.Target, "reason", e.Reason, "err", e.Err}
+}
+
+func (e MessageRejectedEvent) Error() string {
+	if e.Err != "" {
+		return fmt.Sprintf("message rejected by %s: %s: %s", e.Address, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("message rejected by %s: %s", e.Address, e.Reason)
+}
+
+// ProtocolMismatchEvent gets published when a peer runs a version of the
+// wire protocol that is not compatible with ours, see
+// Config.WithMinProtocolVersion. No messages are exchanged with such a peer.
+type ProtocolMismatchEvent struct {
+	// The listen address of the peer.
+	Address          string
+	LocalVersion     int32
+	LocalMinVersion  int32
+	RemoteVersion    int32
+	RemoteMinVersion int32
+}
+
+func (e ProtocolMismatchEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelError, "Remote protocol version mismatch", []any{
+		"remote", e.Address,
+		"version", e.LocalVersion, "min_version", e.LocalMinVersion,
+		"remote_version", e.RemoteVersion, "remote_min_version", e.RemoteMinVersion,
+	}
+}
+
+// FlowControlExceededEvent gets published when a message is rejected because
+// the flow control window of the remote is exhausted. Like the
+// MessageTooLargeEvent it is also sent to the sender of the message, if any.
+type FlowControlExceededEvent struct {
+	// The listen address of the remote the message was sent to.
+	Address string
+	Target  *actor.PID
+	Sender  *actor.PID
+	Message any
+	// Window is the number of messages that may be in flight to the remote.
+	Window int
+}
+
+func (e FlowControlExceededEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelWarn, "Remote flow control window exceeded", []any{"remote", e.Address, "target", e.Target, "window", e.Window}
+}
+
+func (e FlowControlExceededEvent) Error() string {
+	return fmt.Sprintf("flow control window of %d messages to %s exceeded", e.Window, e.Address)
+}
+
+// PeerRejectedEvent gets published when a peer that connects is not allowed by
+// the ACL of the remote. The peer cannot deliver any messages.
+type PeerRejectedEvent struct {
+	// The listen address of the peer, as sent in its handshake.
+	Address string
+	// The network address the connection comes from.
+	RemoteAddr string
+	Reason     string
+}
+
+func (e PeerRejectedEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelWarn, "Remote peer rejected", []any{"remote", e.Address, "remoteAddr", e.RemoteAddr, "reason", e.Reason}
+}
+
+// RateLimitExceededEvent gets published when a message received from a peer
+// exceeds a rate limit and is dropped, or the connection it was received on
+// is closed.
+type RateLimitExceededEvent struct {
+	// The listen address of the peer that sent the message, if known.
+	Address string
+	Target  *actor.PID
+	Sender  *actor.PID
+	// PerTarget is set when the limit of the target was exceeded, rather
+	// than the limit of the connection.
+	PerTarget bool
+	// Rate is the exceeded limit in messages per second.
+	Rate   float64
+	Action RateLimitAction
+}
+
+func (e RateLimitExceededEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelWarn, "Remote rate limit exceeded", []any{"remote", e.Address, "target", e.Target, "perTarget", e.PerTarget, "rate", e.Rate, "action", e.Action}
+}
+
+// RemoteConnectedEvent gets published when a connection to a peer is
+// established and the handshake succeeded, including reconnects.
+type RemoteConnectedEvent struct {
+	// The listen address of the peer.
+	Address string
+	// ProtocolVersion is the version agreed on in the handshake.
+	ProtocolVersion int32
+}
+
+func (e RemoteConnectedEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelDebug, "Remote connected", []any{"remote", e.Address, "version", e.ProtocolVersion}
+}
+
+// RemoteDisconnectedEvent gets published when an established connection to a
+// peer is lost. The connection is reestablished in the background, unless the
+// remote is stopping.
+type RemoteDisconnectedEvent struct {
+	// The listen address of the peer.
+	Address string
+	// Err is the reason the connection was closed, empty if it was closed
+	// without an error, for example by the peer.
+	Err string
+}
+
+func (e RemoteDisconnectedEvent) Log() (slog.Level, string, []any) {
+	if e.Err == "" {
+		return slog.LevelDebug, "Remote disconnected", []any{"remote", e.Address}
+	}
+	return slog.LevelWarn, "Remote disconnected", []any{"remote", e.Address, "err", e.Err}
+}
+
+// HandshakeFailedEvent gets published when a peer could be dialed, but the
+// handshake with it failed. Incompatible protocol versions are additionally
+// published as a ProtocolMismatchEvent.
+type HandshakeFailedEvent struct {
+	// The listen address of the peer.
+	Address string
+	Err     string
+}
+
+func (e HandshakeFailedEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelError, "Remote handshake failed", []any{"remote", e.Address, "err", e.Err}
+}
+
+// ConnectFailedEvent gets published for every failed attempt to connect to a
+// peer. Once the attempts are exhausted the peer is considered unreachable:
+// Backoff is zero and a RemoteUnreachableEvent follows.
+type ConnectFailedEvent struct {
+	// The listen address of the peer.
+	Address string
+	// Attempt is the number of the failed attempt, starting at 1.
+	Attempt int
+	// MaxAttempts is the configured maximum number of attempts, see
+	// ReconnectConfig.
+	MaxAttempts int
+	// Backoff is the time until the next attempt.
+	Backoff time.Duration
+	Err     string
+}
+
+func (e ConnectFailedEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelError, "Remote connect failed", []any{"remote", e.Address, "attempt", e.Attempt, "max", e.MaxAttempts, "backoff", e.Backoff, "err", e.Err}
+}
+
+func (MessageDroppedEvent) Topic() string      { return "remote.message.dropped" }
+func (MessageTooLargeEvent) Topic() string     { return "remote.message.too_large" }
+func (MessageRejectedEvent) Topic() string     { return "remote.message.rejected" }
+func (FlowControlExceededEvent) Topic() string { return "remote.message.flow_control_exceeded" }
+func (RateLimitExceededEvent) Topic() string   { return "remote.message.rate_limited" }
+func (ProtocolMismatchEvent) Topic() string    { return "remote.conn.protocol_mismatch" }
+func (PeerRejectedEvent) Topic() string        { return "remote.conn.rejected" }
+func (RemoteConnectedEvent) Topic() string     { return "remote.conn.connected" }
+func (RemoteDisconnectedEvent) Topic() string  { return "remote.conn.lost" }
+func (HandshakeFailedEvent) Topic() string     { return "remote.conn.handshake_failed" }
+func (ConnectFailedEvent) Topic() string       { return "remote.conn.connect_failed" }
+func (PeerMetricsEvent) Topic() string         { return "remote.metrics.peer" }
@@ -0,0 +1,137 @@
+package remote
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FlowControlConfig configures the credit based flow control of the messages
+// sent to a peer. Every message takes a credit of the window of its peer,
+// which is given back once the peer reports it has enqueued the message in
+// the mailbox of its target. It bounds the messages on the wire and in the
+// stream of the peer, not the ones waiting in the mailboxes of its actors.
+type FlowControlConfig struct {
+	// Window is the maximum number of messages that are sent to the peer
+	// but not yet enqueued by it. Zero disables flow control.
+	Window int
+	// Block makes Send wait for a credit when the window is exhausted.
+	// Otherwise the message is rejected with a FlowControlExceededEvent.
+	Block bool
+	// Timeout is the maximum time Send blocks before the message is
+	// rejected. Zero waits until a credit is available.
+	Timeout time.Duration
+}
+
+// flowControl holds the windows of the peers of a remote.
+type flowControl struct {
+	config FlowControlConfig
+	peers  map[string]FlowControlConfig
+
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+func newFlowControl(config Config) *flowControl {
+	return &flowControl{
+		config:  config.FlowControl,
+		peers:   config.PeerFlowControl,
+		windows: make(map[string]*window),
+	}
+}
+
+// window returns the window of the peer with the given address, creating it
+// if needed. It returns nil when flow control is disabled for the peer.
+func (f *flowControl) window(address string) *window {
+	config, ok := f.peers[address]
+	if !ok {
+		config = f.config
+	}
+	if config.Window <= 0 {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w, ok := f.windows[address]
+	if !ok {
+		w = &window{config: config, credits: make(chan struct{}, config.Window)}
+		f.windows[address] = w
+	}
+	return w
+}
+
+// window is a semaphore holding the credits of a single peer. A credit is
+// taken by putting a token in the channel and given back by removing it.
+type window struct {
+	config  FlowControlConfig
+	credits chan struct{}
+}
+
+// acquire takes a credit, waiting for one if the window is configured to
+// block. It returns false when no credit could be taken.
+func (w *window) acquire() bool {
+	if w == nil {
+		return true
+	}
+	select {
+	case w.credits <- struct{}{}:
+		return true
+	default:
+	}
+	if !w.config.Block {
+		return false
+	}
+	if w.config.Timeout <= 0 {
+		w.credits <- struct{}{}
+		return true
+	}
+	timer := time.NewTimer(w.config.Timeout)
+	defer timer.Stop()
+	select {
+	case w.credits <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// release gives back n credits. Giving back more credits than were taken is
+// harmless, the window never grows beyond its size.
+func (w *window) release(n int) {
+	if w == nil {
+		return
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case <-w.credits:
+		default:
+			return
+		}
+	}
+}
+
+// takeInflight subtracts at most n from the given number of messages in
+// flight and returns the amount that was subtracted.
+func takeInflight(inflight *atomic.Int64, n int64) int64 {
+	for {
+		cur := inflight.Load()
+		if n > cur {
+			n = cur
+		}
+		if inflight.CompareAndSwap(cur, cur-n) {
+			return n
+		}
+	}
+}
+
+// enqueuedMessages returns the number of messages the receiver of the given
+// envelope reports as enqueued. The chunks of a message count as one.
+func enqueuedMessages(env *Envelope) int {
+	n := 0
+	for _, msg := range env.Messages {
+		if !msg.Partial {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowControlRejectsWhenWindowExhausted(t *testing.T) {
+	addr := getRandomLocalhostAddr()
+	ln := listenUnresponsive(t, addr)
+	defer ln.Close()
+
+	config := NewConfig().WithFlowControl(FlowControlConfig{Window: 2})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	target := actor.NewPID(addr, "foo/bar")
+	a.Send(target, &TestMessage{Data: []byte("1")})
+	a.Send(target, &TestMessage{Data: []byte("2")})
+
+	_, err = a.Request(target, &TestMessage{Data: []byte("3")}, time.Second).Result()
+	var exceeded FlowControlExceededEvent
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, addr, exceeded.Address)
+	assert.Equal(t, 2, exceeded.Window)
+}
+
+func TestFlowControlBlockTimeout(t *testing.T) {
+	addr := getRandomLocalhostAddr()
+	ln := listenUnresponsive(t, addr)
+	defer ln.Close()
+
+	config := NewConfig().WithFlowControl(FlowControlConfig{
+		Window:  1,
+		Block:   true,
+		Timeout: 50 * time.Millisecond,
+	})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	target := actor.NewPID(addr, "foo/bar")
+	a.Send(target, &TestMessage{Data: []byte("1")})
+
+	start := time.Now()
+	_, err = a.Request(target, &TestMessage{Data: []byte("2")}, time.Second).Result()
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	var exceeded FlowControlExceededEvent
+	require.ErrorAs(t, err, &exceeded)
+}
+
+// The receiver reports the messages it processed, which gives the credits
+// back to the sender, so all messages get through a small window.
+func TestFlowControlCreditsReturned(t *testing.T) {
+	config := NewConfig().WithFlowControl(FlowControlConfig{Window: 2, Block: true, Timeout: time.Second})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	const n = 100
+	received := make(chan struct{}, n)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(*TestMessage); ok {
+			received <- struct{}{}
+		}
+	}, "receiver")
+
+	for i := 0; i < n; i++ {
+		a.Send(pid, &TestMessage{Data: []byte("foo")})
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatalf("received %d of %d messages", i, n)
+		}
+	}
+}
+
+func TestPeerFlowControl(t *testing.T) {
+	config := NewConfig().
+		WithFlowControl(FlowControlConfig{Window: 10}).
+		WithPeerFlowControl("127.0.0.1:4000", FlowControlConfig{Window: 2}).
+		WithPeerFlowControl("127.0.0.1:5000", FlowControlConfig{})
+	flow := newFlowControl(config)
+
+	assert.Equal(t, 10, cap(flow.window("127.0.0.1:3000").credits))
+	assert.Equal(t, 2, cap(flow.window("127.0.0.1:4000").credits))
+	assert.Nil(t, flow.window("127.0.0.1:5000"))
+	assert.Same(t, flow.window("127.0.0.1:4000"), flow.window("127.0.0.1:4000"))
+}
+
+func TestWindowRelease(t *testing.T) {
+	w := &window{config: FlowControlConfig{Window: 2}, credits: make(chan struct{}, 2)}
+	assert.True(t, w.acquire())
+	assert.True(t, w.acquire())
+	assert.False(t, w.acquire())
+
+	w.release(5)
+	assert.True(t, w.acquire())
+	assert.True(t, w.acquire())
+	assert.False(t, w.acquire())
+}
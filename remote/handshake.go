@@ -0,0 +1,112 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ProtocolVersion is the version of the wire protocol spoken by this version
+// of the remote. It is bumped whenever the protocol changes in a way older
+// versions don't understand.
+//
+//   - 1: the protocol before the handshake was introduced.
+//   - 2: adds the handshake.
+//   - 3: adds the negative acknowledgements of undeliverable messages.
+//   - 4: adds the priority lane.
+const ProtocolVersion = 4
+
+const (
+	// legacyProtocolVersion is the version of peers that don't send a handshake.
+	legacyProtocolVersion = 1
+	// nackProtocolVersion is the first version that supports nacks.
+	nackProtocolVersion = 3
+	// laneProtocolVersion is the first version that reassembles the chunks
+	// of the priority lane separately.
+	laneProtocolVersion = 4
+)
+
+const handshakeTimeout = 5 * time.Second
+
+var errProtocolMismatch = errors.New("incompatible protocol version")
+
+// handshake returns the handshake of a remote with the given address.
+func (c Config) handshake(address string) *Handshake {
+	min := c.MinProtocolVersion
+	if min <= 0 {
+		min = legacyProtocolVersion
+	}
+	return &Handshake{
+		Version:    ProtocolVersion,
+		MinVersion: min,
+		Address:    address,
+	}
+}
+
+// compatible reports whether both ends support the version of the other.
+// The version spoken is then the lower of both versions.
+func compatible(local, remote *Handshake) bool {
+	return remote.Version >= local.MinVersion && local.Version >= remote.MinVersion
+}
+
+func negotiatedVersion(local, remote *Handshake) int32 {
+	return min(local.Version, remote.Version)
+}
+
+func newProtocolMismatchEvent(address string, local, remote *Handshake) ProtocolMismatchEvent {
+	return ProtocolMismatchEvent{
+		Address:          address,
+		LocalVersion:     local.Version,
+		LocalMinVersion:  local.MinVersion,
+		RemoteVersion:    remote.Version,
+		RemoteMinVersion: remote.MinVersion,
+	}
+}
+
+// legacyHandshake is the handshake of a peer that doesn't support it. Such a
+// peer answers our handshake as if it was a heartbeat.
+func legacyHandshake(address string) *Handshake {
+	return &Handshake{
+		Version:    legacyProtocolVersion,
+		MinVersion: legacyProtocolVersion,
+		Address:    address,
+	}
+}
+
+// handshakeTimeout is the time the remote has to answer the handshake. With
+// heartbeats enabled that is the heartbeat timeout.
+func (s *streamWriter) handshakeTimeout() time.Duration {
+	if s.heartbeat > 0 {
+		return s.heartbeatTimeout
+	}
+	return handshakeTimeout
+}
+
+// handshake exchanges the handshake with the remote over a newly opened
+// stream and returns the negotiated version. If the remote is not compatible
+// a ProtocolMismatchEvent is broadcasted and errProtocolMismatch is returned,
+// if its ACL does not allow us errPeerRejected is returned.
+func (s *streamWriter) handshake(stream DRPCRemote_ReceiveStream) (int32, error) {
+	local := s.local
+	if err := stream.Send(&Envelope{Handshake: local}); err != nil {
+		return 0, err
+	}
+	env, err := stream.Recv()
+	if err != nil {
+		return 0, fmt.Errorf("handshake: %w", err)
+	}
+	remote := env.Handshake
+	if remote.GetRejected() {
+		return 0, errPeerRejected
+	}
+	if remote == nil {
+		remote = legacyHandshake(s.writeToAddr)
+	}
+	if !compatible(local, remote) {
+		s.engine.BroadcastEvent(newProtocolMismatchEvent(s.writeToAddr, local, remote))
+		return 0, errProtocolMismatch
+	}
+	version := negotiatedVersion(local, remote)
+	s.stats.protocolVersion.Store(version)
+	return version, nil
+}
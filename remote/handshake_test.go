@@ -0,0 +1,155 @@
+package remote
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"storj.io/drpc/drpcconn"
+)
+
+func TestCompatible(t *testing.T) {
+	tests := []struct {
+		local, remote *Handshake
+		compatible    bool
+	}{
+		{&Handshake{Version: 2, MinVersion: 1}, &Handshake{Version: 2, MinVersion: 1}, true},
+		{&Handshake{Version: 2, MinVersion: 1}, legacyHandshake(""), true},
+		{&Handshake{Version: 2, MinVersion: 2}, legacyHandshake(""), false},
+		{&Handshake{Version: 2, MinVersion: 1}, &Handshake{Version: 4, MinVersion: 2}, true},
+		{&Handshake{Version: 2, MinVersion: 1}, &Handshake{Version: 4, MinVersion: 3}, false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.compatible, compatible(test.local, test.remote), "%v %v", test.local, test.remote)
+		assert.Equal(t, test.compatible, compatible(test.remote, test.local), "%v %v", test.remote, test.local)
+	}
+	assert.Equal(t, int32(2), negotiatedVersion(&Handshake{Version: 2}, &Handshake{Version: 4}))
+}
+
+func TestHandshakeNegotiatesVersion(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			c.Respond(msg)
+		}
+	}, "echo")
+	_, err = a.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	require.NoError(t, err)
+
+	m, ok := ra.PeerMetrics(rb.Address())
+	require.True(t, ok)
+	assert.Equal(t, int32(ProtocolVersion), m.ProtocolVersion)
+}
+
+// Both ends publish a ProtocolMismatchEvent when one requires a newer
+// version than the other speaks, and no messages are delivered.
+func TestProtocolMismatch(t *testing.T) {
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithMinProtocolVersion(ProtocolVersion+1))
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	mismatches := make(chan ProtocolMismatchEvent, 2)
+	subscriber := func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case ProtocolMismatchEvent:
+			mismatches <- msg
+		}
+	}
+	a.SpawnFunc(subscriber, "listener")
+	b.SpawnFunc(subscriber, "listener")
+	received := make(chan struct{}, 1)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(*TestMessage); ok {
+			received <- struct{}{}
+		}
+	}, "receiver")
+	time.Sleep(10 * time.Millisecond)
+
+	a.Send(pid, &TestMessage{Data: []byte("foo")})
+	for i := 0; i < 2; i++ {
+		select {
+		case evt := <-mismatches:
+			assert.Equal(t, int32(ProtocolVersion), evt.LocalVersion)
+			assert.Equal(t, int32(ProtocolVersion), evt.RemoteVersion)
+		case <-time.After(time.Second):
+			t.Fatal("expected a ProtocolMismatchEvent")
+		}
+	}
+	select {
+	case <-received:
+		t.Fatal("message was delivered to an incompatible peer")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// A peer that doesn't send the handshake speaks the legacy version, which is
+// rejected once the remote requires a newer one.
+func TestProtocolMismatchLegacyPeer(t *testing.T) {
+	b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithMinProtocolVersion(legacyProtocolVersion+1))
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	mismatches := make(chan ProtocolMismatchEvent, 1)
+	b.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case ProtocolMismatchEvent:
+			mismatches <- msg
+		}
+	}, "listener")
+	received := make(chan struct{}, 1)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(*TestMessage); ok {
+			received <- struct{}{}
+		}
+	}, "receiver")
+	time.Sleep(10 * time.Millisecond)
+
+	rawconn, err := net.Dial("tcp", rb.Address())
+	require.NoError(t, err)
+	require.NoError(t, rawconn.SetDeadline(time.Now().Add(time.Second)))
+	conn := drpcconn.New(rawconn)
+	defer conn.Close()
+	stream, err := NewDRPCRemoteClient(conn).Receive(context.Background())
+	require.NoError(t, err)
+	data, err := DefaultSerializer{}.Serialize(&TestMessage{Data: []byte("foo")})
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&Envelope{
+		Targets:   []*actor.PID{pid},
+		Senders:   []*actor.PID{actor.NewPID("127.0.0.1:1", "sender")},
+		TypeNames: []string{"remote.TestMessage"},
+		Messages:  []*Message{{Data: data}},
+	}))
+	_, err = stream.Recv()
+	assert.Error(t, err)
+
+	select {
+	case evt := <-mismatches:
+		assert.Equal(t, "127.0.0.1:1", evt.Address)
+		assert.Equal(t, int32(legacyProtocolVersion), evt.RemoteVersion)
+		assert.Equal(t, int32(legacyProtocolVersion+1), evt.LocalMinVersion)
+	case <-time.After(time.Second):
+		t.Fatal("expected a ProtocolMismatchEvent")
+	}
+	select {
+	case <-received:
+		t.Fatal("message was delivered to an incompatible peer")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
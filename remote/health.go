@@ -0,0 +1,21 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CheckHealth returns an error if the remote is not running or one of its
+// listeners stopped serving, see actor.HealthChecker. The engine of the remote
+// checks it by itself, see actor.Engine.Health.
+func (r *Remote) CheckHealth(context.Context) error {
+	if r.state.Load() != stateRunning {
+		return errors.New("remote is not running")
+	}
+	want := len(r.ListenAddresses())
+	if down := want - int(r.serving.Load()); down > 0 {
+		return fmt.Errorf("%d of the %d listeners of the remote are down", down, want)
+	}
+	return nil
+}
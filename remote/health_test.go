@@ -0,0 +1,23 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHealth(t *testing.T) {
+	e, r, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	health := e.Health()
+	assert.True(t, health.Ready())
+	require.Len(t, health.Checks, 1)
+	assert.Equal(t, "remote", health.Checks[0].Name)
+	assert.Empty(t, health.Checks[0].Error)
+
+	r.Stop().Wait()
+	health = e.Health()
+	assert.False(t, health.Ready())
+	assert.Equal(t, "remote is not running", health.Checks[0].Error)
+}
@@ -0,0 +1,119 @@
+package remote
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/require"
+)
+
+// unresponsiveListener accepts connections, but never responds, like a
+// remote that hangs.
+type unresponsiveListener struct {
+	net.Listener
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func listenUnresponsive(t *testing.T, addr string) *unresponsiveListener {
+	ln, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+	l := &unresponsiveListener{Listener: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			l.mu.Lock()
+			l.conns = append(l.conns, conn)
+			l.mu.Unlock()
+			go io.Copy(io.Discard, conn)
+		}
+	}()
+	return l
+}
+
+func (l *unresponsiveListener) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	return l.Listener.Close()
+}
+
+func TestHeartbeatUnreachableAndRestored(t *testing.T) {
+	config := NewConfig().
+		WithHeartbeat(20*time.Millisecond, 100*time.Millisecond).
+		WithReconnect(ReconnectConfig{MaxAttempts: -1, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	events := make(chan any, 10)
+	a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case actor.RemoteUnreachableEvent, actor.RemoteRestoredEvent:
+			events <- msg
+		}
+	}, "listener")
+	time.Sleep(10 * time.Millisecond)
+
+	bAddr := getRandomLocalhostAddr()
+	ln := listenUnresponsive(t, bAddr)
+	a.Send(actor.NewPID(bAddr, "foo"), &TestMessage{Data: []byte("foo")})
+
+	select {
+	case evt := <-events:
+		require.Equal(t, actor.RemoteUnreachableEvent{ListenAddr: bAddr}, evt)
+	case <-time.After(time.Second):
+		t.Fatal("expected a RemoteUnreachableEvent")
+	}
+
+	ln.Close()
+	_, rb, err := makeRemoteEngine(bAddr)
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	select {
+	case evt := <-events:
+		require.Equal(t, actor.RemoteRestoredEvent{ListenAddr: bAddr}, evt)
+	case <-time.After(time.Second):
+		t.Fatal("expected a RemoteRestoredEvent")
+	}
+}
+
+func TestHeartbeatHealthyRemote(t *testing.T) {
+	config := NewConfig().WithHeartbeat(10*time.Millisecond, 50*time.Millisecond)
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	events := make(chan any, 10)
+	a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case actor.RemoteUnreachableEvent:
+			events <- msg
+		}
+	}, "listener")
+	pid := b.SpawnFunc(func(c *actor.Context) {}, "sink")
+	a.Send(pid, &TestMessage{Data: []byte("foo")})
+
+	select {
+	case evt := <-events:
+		t.Fatalf("unexpected %v", evt)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
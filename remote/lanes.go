@@ -0,0 +1,42 @@
+package remote
+
+import "github.com/fertigai/hollywood/actor"
+
+// ControlMessage is implemented by the messages of the control plane, like
+// the membership gossip of a cluster. Control messages are sent over the
+// priority lane of a connection, so they don't queue up behind bulk data.
+type ControlMessage interface {
+	ControlMessage()
+}
+
+// prioritized reports whether the delivery is sent over the priority lane.
+// That is the case for messages sent with SendPriority, the watch messages
+// of the engine and control messages.
+//
+// Messages on different lanes are not ordered relative to each other.
+func (d *streamDeliver) prioritized() bool {
+	if d.priority {
+		return true
+	}
+	switch d.msg.(type) {
+	case *actor.Watch, *actor.Unwatch, *actor.Terminated, ControlMessage:
+		return true
+	}
+	return false
+}
+
+// reassembly holds the chunks of a message of a lane until its last chunk is
+// received.
+type reassembly struct {
+	chunks []byte
+	// dropping is set once the chunks exceed the maximum message size.
+	dropping bool
+}
+
+// laneIndex returns the index of the lane of the given envelope.
+func laneIndex(env *Envelope) int {
+	if env.Priority {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package remote
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrioritized(t *testing.T) {
+	assert.False(t, (&streamDeliver{msg: &TestMessage{}}).prioritized())
+	assert.True(t, (&streamDeliver{msg: &TestMessage{}, priority: true}).prioritized())
+	assert.True(t, (&streamDeliver{msg: &actor.Watch{}}).prioritized())
+	assert.True(t, (&streamDeliver{msg: &actor.Terminated{}}).prioritized())
+}
+
+// A priority message overtakes a large message that is being chunked, while
+// both messages are chunked and reassembled correctly.
+func TestPriorityLaneOvertakesChunkedMessage(t *testing.T) {
+	config := NewConfig().WithChunking(1024)
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	received := make(chan []byte, 2)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- msg.Data
+		}
+	}, "receiver")
+
+	bulk := bytes.Repeat([]byte("b"), 8*1024*1024)
+	control := bytes.Repeat([]byte("c"), 4*1024)
+	a.Send(pid, &TestMessage{Data: bulk})
+	a.SendPriority(pid, &TestMessage{Data: control})
+
+	for _, expected := range [][]byte{control, bulk} {
+		select {
+		case data := <-received:
+			assert.True(t, bytes.Equal(expected, data), "expected %d bytes of %q, got %d bytes", len(expected), expected[0], len(data))
+		case <-time.After(5 * time.Second):
+			t.Fatal("message was not delivered")
+		}
+	}
+}
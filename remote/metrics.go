@@ -0,0 +1,201 @@
+package remote
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/fertigai/hollywood/safemap"
+)
+
+// requestTTL is how long we wait for the response of a request before we
+// stop tracking its latency.
+const requestTTL = time.Minute
+
+// PeerMetrics holds the counters of the traffic between the remote and a
+// single peer.
+type PeerMetrics struct {
+	// Address is the listen address of the peer.
+	Address          string
+	MessagesSent     uint64
+	MessagesReceived uint64
+	// BytesSent and BytesReceived count the serialized message payloads.
+	BytesSent     uint64
+	BytesReceived uint64
+	// SerializationTime is the total time spent serializing the messages
+	// sent to the peer, DeserializationTime the total time spent
+	// deserializing the messages received from the peer.
+	SerializationTime   time.Duration
+	DeserializationTime time.Duration
+	// QueueDepth is the number of messages that are waiting to be sent to
+	// the peer, including the messages that are buffered while reconnecting.
+	QueueDepth int64
+	// Reconnects is the number of times the connection to the peer was lost
+	// and had to be reestablished.
+	Reconnects uint64
+	// Requests is the number of requests that were answered by the peer and
+	// RequestLatency is their average round-trip time.
+	Requests       uint64
+	RequestLatency time.Duration
+	// ProtocolVersion is the version of the wire protocol spoken with the
+	// peer, zero if we never connected to it.
+	ProtocolVersion int32
+}
+
+// PeerMetricsEvent is broadcasted periodically, see Config.WithMetricsInterval.
+type PeerMetricsEvent struct {
+	Peers []PeerMetrics
+}
+
+type peerStats struct {
+	address             string
+	messagesSent        atomic.Uint64
+	messagesReceived    atomic.Uint64
+	bytesSent           atomic.Uint64
+	bytesReceived       atomic.Uint64
+	serializationTime   atomic.Int64
+	deserializationTime atomic.Int64
+	queued              atomic.Int64
+	reconnects          atomic.Uint64
+	requests            atomic.Uint64
+	requestTime         atomic.Int64
+	protocolVersion     atomic.Int32
+}
+
+func (p *peerStats) sent(size int, took time.Duration) {
+	p.messagesSent.Add(1)
+	p.bytesSent.Add(uint64(size))
+	p.serializationTime.Add(int64(took))
+}
+
+func (p *peerStats) received(size int, took time.Duration) {
+	p.messagesReceived.Add(1)
+	p.bytesReceived.Add(uint64(size))
+	p.deserializationTime.Add(int64(took))
+}
+
+func (p *peerStats) snapshot() PeerMetrics {
+	m := PeerMetrics{
+		Address:             p.address,
+		MessagesSent:        p.messagesSent.Load(),
+		MessagesReceived:    p.messagesReceived.Load(),
+		BytesSent:           p.bytesSent.Load(),
+		BytesReceived:       p.bytesReceived.Load(),
+		SerializationTime:   time.Duration(p.serializationTime.Load()),
+		DeserializationTime: time.Duration(p.deserializationTime.Load()),
+		QueueDepth:          p.queued.Load(),
+		Reconnects:          p.reconnects.Load(),
+		Requests:            p.requests.Load(),
+		ProtocolVersion:     p.protocolVersion.Load(),
+	}
+	if m.Requests > 0 {
+		m.RequestLatency = time.Duration(p.requestTime.Load() / int64(m.Requests))
+	}
+	return m
+}
+
+type pendingRequest struct {
+	peer *peerStats
+	sent time.Time
+}
+
+// metrics keeps the stats of all the peers of a remote.
+type metrics struct {
+	mu    sync.RWMutex
+	peers map[string]*peerStats
+	// requests maps the lookup key of the response pid of a pending request
+	// to the peer the request was sent to.
+	requests *safemap.SafeMap[uint64, pendingRequest]
+	// expired holds the unix nano time requests were last expired.
+	expired atomic.Int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		peers:    make(map[string]*peerStats),
+		requests: safemap.New[uint64, pendingRequest](),
+	}
+}
+
+// peer returns the stats of the peer with the given address, creating them
+// if needed.
+func (m *metrics) peer(address string) *peerStats {
+	m.mu.RLock()
+	p, ok := m.peers[address]
+	m.mu.RUnlock()
+	if ok {
+		return p
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.peers[address]; ok {
+		return p
+	}
+	p = &peerStats{address: address}
+	m.peers[address] = p
+	return p
+}
+
+// lookup returns the stats of the peer with the given address, if any.
+func (m *metrics) lookup(address string) (*peerStats, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.peers[address]
+	return p, ok
+}
+
+// requestSent starts tracking the round-trip time if the message is a request,
+// which is the case when the sender is the pid of a local Response.
+func (m *metrics) requestSent(p *peerStats, sender *actor.PID, localAddr string) {
+	if sender == nil || sender.Address != localAddr || !strings.HasPrefix(sender.ID, "response/") {
+		return
+	}
+	now := time.Now()
+	m.requests.Set(sender.LookupKey(), pendingRequest{peer: p, sent: now})
+	if last := m.expired.Load(); now.UnixNano()-last > int64(requestTTL) && m.expired.CompareAndSwap(last, now.UnixNano()) {
+		m.expireRequests()
+	}
+}
+
+// responseReceived completes the request that belongs to the given target, if
+// any, and returns the peer that responded.
+func (m *metrics) responseReceived(target *actor.PID) (*peerStats, bool) {
+	key := target.LookupKey()
+	req, ok := m.requests.Get(key)
+	if !ok {
+		return nil, false
+	}
+	m.requests.Delete(key)
+	req.peer.requests.Add(1)
+	req.peer.requestTime.Add(int64(time.Since(req.sent)))
+	return req.peer, true
+}
+
+// expireRequests stops tracking the requests that were never answered.
+func (m *metrics) expireRequests() {
+	var expired []uint64
+	m.requests.ForEach(func(key uint64, req pendingRequest) {
+		if time.Since(req.sent) > requestTTL {
+			expired = append(expired, key)
+		}
+	})
+	for _, key := range expired {
+		m.requests.Delete(key)
+	}
+}
+
+func (m *metrics) snapshot() []PeerMetrics {
+	m.mu.RLock()
+	peers := make([]PeerMetrics, 0, len(m.peers))
+	for _, p := range m.peers {
+		peers = append(peers, p.snapshot())
+	}
+	m.mu.RUnlock()
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Address < peers[j].Address
+	})
+	return peers
+}
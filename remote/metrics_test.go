@@ -0,0 +1,84 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerMetrics(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			c.Respond(msg)
+		}
+	}, "echo")
+
+	_, err = a.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	require.NoError(t, err)
+
+	m, ok := ra.PeerMetrics(rb.Address())
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), m.MessagesSent)
+	assert.Equal(t, uint64(1), m.MessagesReceived)
+	assert.Greater(t, m.BytesSent, uint64(0))
+	assert.Greater(t, m.BytesReceived, uint64(0))
+	assert.Equal(t, int64(0), m.QueueDepth)
+	assert.Equal(t, uint64(1), m.Requests)
+	assert.Greater(t, m.RequestLatency, time.Duration(0))
+
+	m, ok = rb.PeerMetrics(ra.Address())
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), m.MessagesReceived)
+	assert.Equal(t, uint64(1), m.MessagesSent)
+
+	_, ok = ra.PeerMetrics("127.0.0.1:1")
+	assert.False(t, ok)
+	assert.Len(t, ra.Metrics(), 1)
+
+	// The engine has the traffic in its stats.
+	assert.Equal(t, []actor.PeerStats{{Address: rb.Address(), MessagesSent: 1, MessagesReceived: 1}}, a.Stats().Peers)
+}
+
+func TestPeerMetricsEvent(t *testing.T) {
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithMetricsInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	events := make(chan PeerMetricsEvent, 100)
+	a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case PeerMetricsEvent:
+			events <- msg
+		}
+	}, "listener")
+	pid := b.SpawnFunc(func(c *actor.Context) {}, "sink")
+	a.Send(pid, &TestMessage{Data: []byte("foo")})
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case evt := <-events:
+			if len(evt.Peers) == 1 && evt.Peers[0].MessagesSent == 1 {
+				assert.Equal(t, rb.Address(), evt.Peers[0].Address)
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected a PeerMetricsEvent")
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The sending engine publishes a message to a remote actor that doesn't exist
+// to its own deadletter, with the original payload.
+func TestNackNotFound(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	_, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	deadLetters := make(chan actor.DeadLetterEvent, 1)
+	a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case actor.DeadLetterEvent:
+			deadLetters <- msg
+		}
+	}, "listener")
+	time.Sleep(10 * time.Millisecond)
+
+	target := actor.NewPID(rb.Address(), "foo/bar")
+	a.Send(target, &TestMessage{Data: []byte("foo")})
+	select {
+	case evt := <-deadLetters:
+		assert.Equal(t, target.ID, evt.Target.ID)
+		assert.Equal(t, []byte("foo"), evt.Message.(*TestMessage).Data)
+	case <-time.After(time.Second):
+		t.Fatal("expected a DeadLetterEvent")
+	}
+}
+
+func TestNackFailsRequest(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	_, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	start := time.Now()
+	_, err = a.Request(actor.NewPID(rb.Address(), "foo/bar"), &TestMessage{Data: []byte("foo")}, 5*time.Second).Result()
+	var rejected MessageRejectedEvent
+	require.ErrorAs(t, err, &rejected)
+	assert.Equal(t, NackReason_NotFound, rejected.Reason)
+	assert.Equal(t, []byte("foo"), rejected.Message.(*TestMessage).Data)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// The rejections are broadcasted, only the requests get them in their inbox.
+func TestNackNotSentToActors(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	_, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	events := make(chan MessageRejectedEvent, 1)
+	sub := actor.SubscribeTyped(a, func(evt MessageRejectedEvent) { events <- evt })
+	defer sub.Unsubscribe()
+	unexpected := make(chan any, 1)
+	target := actor.NewPID(rb.Address(), "foo/bar")
+	sender := a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case string:
+			c.Send(target, &TestMessage{Data: []byte(msg)})
+		case MessageRejectedEvent:
+			unexpected <- msg
+		}
+	}, "sender")
+	a.Send(sender, "foo")
+
+	select {
+	case evt := <-events:
+		assert.Equal(t, sender, evt.Sender)
+		assert.Equal(t, NackReason_NotFound, evt.Reason)
+	case <-time.After(time.Second):
+		t.Fatal("expected a MessageRejectedEvent")
+	}
+	select {
+	case msg := <-unexpected:
+		t.Fatalf("the sender received %v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// The payload of a message the receiver could not decrypt is decrypted again
+// by the sender.
+func TestNackUndecryptable(t *testing.T) {
+	config := NewConfig().WithEncryption(NewKeyRing("1", testKey(1)))
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+	pid := b.SpawnFunc(func(c *actor.Context) {}, "receiver")
+
+	_, err = a.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	var rejected MessageRejectedEvent
+	require.ErrorAs(t, err, &rejected)
+	assert.Equal(t, NackReason_Undecryptable, rejected.Reason)
+	assert.NotEmpty(t, rejected.Err)
+	assert.Equal(t, []byte("foo"), rejected.Message.(*TestMessage).Data)
+}
+
+func TestNackTooLarge(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithMaxMessageSize(100))
+	require.NoError(t, err)
+	defer rb.Stop()
+	pid := b.SpawnFunc(func(c *actor.Context) {}, "receiver")
+
+	_, err = a.Request(pid, &TestMessage{Data: make([]byte, 1000)}, time.Second).Result()
+	var rejected MessageRejectedEvent
+	require.ErrorAs(t, err, &rejected)
+	assert.Equal(t, NackReason_TooLarge, rejected.Reason)
+	assert.Nil(t, rejected.Message)
+}
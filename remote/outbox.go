@@ -0,0 +1,243 @@
+package remote
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// OutboxStore persists the messages that are buffered while their peer is not
+// connected, so they survive a restart of the process. The messages are
+// removed once they are sent to the peer.
+type OutboxStore interface {
+	// Append stores the given message and returns its sequence number,
+	// which is greater than zero.
+	Append(msg *OutboxMessage) (uint64, error)
+	// Delete removes the message with the given sequence number, that was
+	// sent to the peer with the given address.
+	Delete(address string, seq uint64) error
+	// Load returns all the stored messages. The messages of a peer are
+	// returned in the order they were appended.
+	Load() ([]*OutboxMessage, error)
+}
+
+// FileOutbox is an OutboxStore that keeps each message in a file of its own,
+// in a directory per peer.
+type FileOutbox struct {
+	dir string
+
+	mu   sync.Mutex
+	next uint64
+}
+
+// NewFileOutbox returns a FileOutbox that stores the messages in the given
+// directory, which is created if it does not exist.
+func NewFileOutbox(dir string) (*FileOutbox, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	o := &FileOutbox{dir: dir, next: 1}
+	msgs, err := o.Load()
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range msgs {
+		o.next = max(o.next, msg.Seq+1)
+	}
+	return o, nil
+}
+
+func (o *FileOutbox) Append(msg *OutboxMessage) (uint64, error) {
+	o.mu.Lock()
+	seq := o.next
+	o.next++
+	o.mu.Unlock()
+
+	msg.Seq = seq
+	b, err := msg.MarshalVT()
+	if err != nil {
+		return 0, err
+	}
+	dir := o.peerDir(msg.Target.GetAddress())
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return 0, err
+	}
+	// Write to a temporary file first, so Load never sees a partial message.
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, seqFileName(seq))); err != nil {
+		return 0, err
+	}
+	return seq, nil
+}
+
+func (o *FileOutbox) Delete(address string, seq uint64) error {
+	err := os.Remove(filepath.Join(o.peerDir(address), seqFileName(seq)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (o *FileOutbox) Load() ([]*OutboxMessage, error) {
+	peers, err := os.ReadDir(o.dir)
+	if err != nil {
+		return nil, err
+	}
+	var msgs []*OutboxMessage
+	for _, peer := range peers {
+		if !peer.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(o.dir, peer.Name()))
+		if err != nil {
+			return nil, err
+		}
+		// The names are zero padded sequence numbers, hence ReadDir returns
+		// them in order.
+		for _, file := range files {
+			if _, err := strconv.ParseUint(file.Name(), 10, 64); err != nil {
+				continue
+			}
+			b, err := os.ReadFile(filepath.Join(o.dir, peer.Name(), file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			msg := &OutboxMessage{}
+			if err := msg.UnmarshalVT(b); err != nil {
+				return nil, fmt.Errorf("outbox message %s: %w", file.Name(), err)
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+func (o *FileOutbox) peerDir(address string) string {
+	return filepath.Join(o.dir, url.PathEscape(address))
+}
+
+func seqFileName(seq uint64) string {
+	return fmt.Sprintf("%020d", seq)
+}
+
+// persist stores the given delivery in the outbox, unless it is stored
+// already. With encryption, its payload is stored encrypted, as it would be
+// sent. When that fails, the delivery is only buffered in memory.
+func (s *streamWriter) persist(d *streamDeliver) {
+	if s.outbox == nil || d.stored != 0 {
+		return
+	}
+	b, tname, raw, err := s.serialize(d.msg)
+	if err != nil {
+		slog.Error("outbox serialize", "err", err, "remote", s.writeToAddr)
+		return
+	}
+	var keyID string
+	if s.cipher != nil {
+		b, keyID, err = s.cipher.encrypt(b, tname)
+		if err != nil {
+			slog.Error("outbox encrypt", "err", err, "remote", s.writeToAddr)
+			return
+		}
+	}
+	seq, err := s.outbox.Append(&OutboxMessage{
+		Target:   d.target,
+		Sender:   d.sender,
+		TypeName: tname,
+		Data:     b,
+		Raw:      raw,
+		Priority: d.priority,
+		KeyID:    keyID,
+	})
+	if err != nil {
+		slog.Error("outbox append", "err", err, "remote", s.writeToAddr)
+		return
+	}
+	d.stored = seq
+}
+
+// forget removes the given deliveries from the outbox, once they are sent or
+// given up on.
+func (s *streamWriter) forget(deliveries []*streamDeliver) {
+	if s.outbox == nil {
+		return
+	}
+	for _, d := range deliveries {
+		if d.stored == 0 {
+			continue
+		}
+		if err := s.outbox.Delete(s.writeToAddr, d.stored); err != nil {
+			slog.Error("outbox delete", "err", err, "remote", s.writeToAddr)
+			continue
+		}
+		d.stored = 0
+	}
+}
+
+// restore sends the messages of the outbox that were not sent before the
+// process stopped. They are buffered until their peer is connected. The
+// messages that can't be decrypted or deserialized anymore are dropped.
+func (r *Remote) restore() error {
+	msgs, err := r.config.Outbox.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load the outbox: %w", err)
+	}
+	slices.SortStableFunc(msgs, func(a, b *OutboxMessage) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+	cipher := newPayloadCipher(r.config.Encryption)
+	for _, m := range msgs {
+		data := m.Data
+		if m.KeyID != "" {
+			if cipher == nil {
+				err = errors.New("encryption is not configured")
+			} else {
+				data, err = cipher.decrypt(data, m.TypeName, m.KeyID)
+			}
+			if err != nil {
+				slog.Error("outbox decrypt", "err", err, "type", m.TypeName, "key", m.KeyID)
+				_ = r.config.Outbox.Delete(m.Target.GetAddress(), m.Seq)
+				continue
+			}
+		}
+		var msg any = &RawMessage{Type: m.TypeName, Data: data}
+		if !m.Raw {
+			msg, err = DefaultSerializer{}.Deserialize(data, m.TypeName)
+			if err != nil {
+				slog.Error("outbox deserialize", "err", err, "type", m.TypeName)
+				_ = r.config.Outbox.Delete(m.Target.GetAddress(), m.Seq)
+				continue
+			}
+		}
+		r.engine.Send(r.streamRouterPID, &streamDeliver{
+			target:   m.Target,
+			sender:   m.Sender,
+			msg:      msg,
+			priority: m.Priority,
+			stored:   m.Seq,
+		})
+	}
+	return nil
+}
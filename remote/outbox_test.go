@@ -0,0 +1,184 @@
+package remote
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileOutbox(t *testing.T) {
+	dir := t.TempDir()
+	o, err := NewFileOutbox(dir)
+	require.NoError(t, err)
+
+	foo := actor.NewPID("127.0.0.1:4000", "foo")
+	bar := actor.NewPID("unix:///tmp/peer.sock", "bar")
+	for _, target := range []*actor.PID{foo, bar, foo} {
+		_, err := o.Append(&OutboxMessage{Target: target, TypeName: "remote.TestMessage", Data: []byte(target.ID)})
+		require.NoError(t, err)
+	}
+	msgs, err := o.Load()
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	require.NoError(t, o.Delete(foo.Address, 1))
+	require.NoError(t, o.Delete(foo.Address, 1))
+
+	// The sequence numbers continue after a restart.
+	o, err = NewFileOutbox(dir)
+	require.NoError(t, err)
+	seq, err := o.Append(&OutboxMessage{Target: bar})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), seq)
+	msgs, err = o.Load()
+	require.NoError(t, err)
+	var seqs []uint64
+	for _, msg := range msgs {
+		seqs = append(seqs, msg.Seq)
+	}
+	assert.ElementsMatch(t, []uint64{2, 3, 4}, seqs)
+}
+
+// Messages buffered for a peer that is down are stored in the outbox, and
+// removed once they are sent.
+func TestOutboxBuffersWhilePeerIsDown(t *testing.T) {
+	outbox, err := NewFileOutbox(t.TempDir())
+	require.NoError(t, err)
+	config := NewConfig().WithOutbox(outbox).WithReconnect(ReconnectConfig{
+		MaxAttempts:    -1,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+	})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	addr := getRandomLocalhostAddr()
+	a.Send(actor.NewPID(addr, "receiver/1"), &TestMessage{Data: []byte("foo")})
+	require.Eventually(t, func() bool {
+		msgs, err := outbox.Load()
+		return err == nil && len(msgs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	received := make(chan []byte, 1)
+	b, rb, err := makeRemoteEngine(addr)
+	require.NoError(t, err)
+	defer rb.Stop()
+	b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- msg.Data
+		}
+	}, "receiver", actor.WithID("1"))
+
+	select {
+	case data := <-received:
+		assert.Equal(t, []byte("foo"), data)
+	case <-time.After(2 * time.Second):
+		t.Fatal("buffered message was not delivered")
+	}
+	require.Eventually(t, func() bool {
+		msgs, err := outbox.Load()
+		return err == nil && len(msgs) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+// The messages left in the outbox by a previous process are sent when the
+// remote starts.
+func TestOutboxRestoresMessages(t *testing.T) {
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+	received := make(chan []byte, 2)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case *TestMessage:
+			received <- msg.Data
+		case *RawMessage:
+			received <- msg.Data
+		}
+	}, "receiver")
+
+	outbox, err := NewFileOutbox(t.TempDir())
+	require.NoError(t, err)
+	raw, err := NewRawMessage(&TestMessage{Data: []byte("foo")})
+	require.NoError(t, err)
+	_, err = outbox.Append(&OutboxMessage{Target: pid, TypeName: raw.Type, Data: raw.Data})
+	require.NoError(t, err)
+	_, err = outbox.Append(&OutboxMessage{Target: pid, TypeName: "opaque", Data: []byte("bar"), Raw: true})
+	require.NoError(t, err)
+
+	_, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithOutbox(outbox))
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	for _, expected := range []string{"foo", "bar"} {
+		select {
+		case data := <-received:
+			assert.Equal(t, expected, string(data))
+		case <-time.After(time.Second):
+			t.Fatal("restored message was not delivered")
+		}
+	}
+	require.Eventually(t, func() bool {
+		msgs, err := outbox.Load()
+		return err == nil && len(msgs) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+// With encryption, the messages are stored encrypted, and decrypted when they
+// are restored.
+func TestOutboxEncryption(t *testing.T) {
+	keys := NewKeyRing("1", testKey(1))
+	outbox, err := NewFileOutbox(t.TempDir())
+	require.NoError(t, err)
+	config := NewConfig().WithOutbox(outbox).WithEncryption(keys).WithReconnect(ReconnectConfig{
+		MaxAttempts:    -1,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+	})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	addr := getRandomLocalhostAddr()
+	a.Send(actor.NewPID(addr, "receiver/1"), &TestMessage{Data: []byte("secret")})
+	var msgs []*OutboxMessage
+	require.Eventually(t, func() bool {
+		msgs, err = outbox.Load()
+		return err == nil && len(msgs) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "1", msgs[0].KeyID)
+	assert.False(t, bytes.Contains(msgs[0].Data, []byte("secret")))
+
+	// The message left by a previous process is restored by the next one.
+	restored, err := NewFileOutbox(t.TempDir())
+	require.NoError(t, err)
+	_, err = restored.Append(msgs[0])
+	require.NoError(t, err)
+	_, rc, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config.WithOutbox(restored))
+	require.NoError(t, err)
+	defer rc.Stop()
+
+	received := make(chan []byte, 2)
+	b, rb, err := makeRemoteEngineWithConfig(addr, NewConfig().WithEncryption(keys))
+	require.NoError(t, err)
+	defer rb.Stop()
+	b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- msg.Data
+		}
+	}, "receiver", actor.WithID("1"))
+
+	// Both the buffered and the restored message are delivered.
+	for range 2 {
+		select {
+		case data := <-received:
+			assert.Equal(t, []byte("secret"), data)
+		case <-time.After(2 * time.Second):
+			t.Fatal("stored message was not delivered")
+		}
+	}
+}
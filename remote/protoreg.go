@@ -8,14 +8,23 @@ import (
 
 var registry = map[string]VTUnmarshaler{}
 
-func RegisterType(v VTUnmarshaler) {
+// RegisterVTType registers a vtproto message for the VTProtoSerializer.
+func RegisterVTType(v VTUnmarshaler) {
 	tname := string(proto.MessageName(v))
 	registry[tname] = v
 }
 
+// RegisterType registers a vtproto message for the VTProtoSerializer.
+//
+// Deprecated: Use RegisterVTType, or RegisterCodec for the types without a
+// protobuf definition.
+func RegisterType(v VTUnmarshaler) {
+	RegisterVTType(v)
+}
+
 func registryGetType(t string) (VTUnmarshaler, error) {
 	if m, ok := registry[t]; ok {
 		return m, nil
 	}
-	return nil, fmt.Errorf("given type (%s) is not registered. Did you forget to register your type with remote.RegisterType(&instance{})?", t)
+	return nil, fmt.Errorf("given type (%s) is not registered. Did you forget to register your type with remote.RegisterVTType(&instance{})?", t)
 }
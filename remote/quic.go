@@ -0,0 +1,260 @@
+package remote
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+const (
+	// quicScheme is the address scheme that selects the QUIC transport.
+	// Example: remote.New("quic://0.0.0.0:4000", remote.NewConfig())
+	quicScheme = "quic"
+	// quicNextProto is the ALPN protocol negotiated between QUIC peers.
+	quicNextProto = "hollywood"
+
+	defaultQUICKeepAlive   = 5 * time.Second
+	defaultQUICIdleTimeout = 30 * time.Second
+)
+
+// quicTransport runs each remote stream as a QUIC stream. All streams to the
+// same peer are multiplexed on a single QUIC connection, which means one lost
+// packet only stalls the stream it belongs to instead of the whole connection.
+// Session tickets are cached so reconnects to a known peer can use 0-RTT.
+type quicTransport struct {
+	tlsConfig  *tls.Config
+	quicConfig *quic.Config
+	resolver   Resolver
+
+	mu       sync.Mutex
+	conns    map[string]quic.EarlyConnection
+	sessions map[string]tls.ClientSessionCache
+}
+
+func newQUICTransport(tlsConfig *tls.Config, quicConfig *quic.Config, resolver Resolver) *quicTransport {
+	if quicConfig == nil {
+		quicConfig = &quic.Config{}
+	} else {
+		quicConfig = quicConfig.Clone()
+	}
+	quicConfig.Allow0RTT = true
+	if quicConfig.KeepAlivePeriod == 0 {
+		quicConfig.KeepAlivePeriod = defaultQUICKeepAlive
+	}
+	if quicConfig.MaxIdleTimeout == 0 {
+		quicConfig.MaxIdleTimeout = defaultQUICIdleTimeout
+	}
+	return &quicTransport{
+		tlsConfig:  tlsConfig,
+		quicConfig: quicConfig,
+		resolver:   resolver,
+		conns:      make(map[string]quic.EarlyConnection),
+		sessions:   make(map[string]tls.ClientSessionCache),
+	}
+}
+
+func (t *quicTransport) Listen(addr string) (net.Listener, error) {
+	tlsConfig, err := t.serverTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	ln, err := quic.ListenAddrEarly(addr, tlsConfig, t.quicConfig)
+	if err != nil {
+		return nil, err
+	}
+	ql := &quicListener{
+		ln:      ln,
+		streams: make(chan net.Conn),
+		closech: make(chan struct{}),
+	}
+	go ql.acceptLoop()
+	return ql, nil
+}
+
+func (t *quicTransport) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	conn, err := t.connection(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		// The cached connection might be dead already, drop it so the
+		// next dial creates a fresh one.
+		t.mu.Lock()
+		if t.conns[addr] == conn {
+			delete(t.conns, addr)
+		}
+		t.mu.Unlock()
+		return nil, err
+	}
+	return &quicStreamConn{Stream: stream, conn: conn}, nil
+}
+
+func (t *quicTransport) connection(ctx context.Context, addr string) (quic.EarlyConnection, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if conn, ok := t.conns[addr]; ok && conn.Context().Err() == nil {
+		return conn, nil
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	tlsConfig := withServerName(t.clientTLSConfig(addr), host)
+	conn, err := dialResolved(ctx, t.resolver, addr, func(ctx context.Context, addr string) (quic.EarlyConnection, error) {
+		return quic.DialAddrEarly(ctx, addr, tlsConfig, t.quicConfig)
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.conns[addr] = conn
+	return conn, nil
+}
+
+func (t *quicTransport) serverTLSConfig() (*tls.Config, error) {
+	var config *tls.Config
+	if t.tlsConfig != nil {
+		config = t.tlsConfig.Clone()
+	} else {
+		// QUIC is always encrypted. Without a user provided TLS config
+		// we fall back to an ephemeral self-signed certificate, which
+		// offers the same trust level as the plain TCP transport.
+		cert, err := selfSignedCertificate()
+		if err != nil {
+			return nil, err
+		}
+		config = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	config.NextProtos = []string{quicNextProto}
+	return config, nil
+}
+
+// clientTLSConfig returns the TLS config used to dial the given address.
+// The caller must hold the lock.
+func (t *quicTransport) clientTLSConfig(addr string) *tls.Config {
+	var config *tls.Config
+	if t.tlsConfig != nil {
+		config = t.tlsConfig.Clone()
+	} else {
+		config = &tls.Config{InsecureSkipVerify: true}
+	}
+	config.NextProtos = []string{quicNextProto}
+	if config.ClientSessionCache == nil {
+		// Session tickets are keyed by server name, which does not include
+		// the port. Keep a cache per address so a ticket issued by one peer
+		// is never offered to another peer on the same host.
+		cache, ok := t.sessions[addr]
+		if !ok {
+			cache = tls.NewLRUClientSessionCache(1)
+			t.sessions[addr] = cache
+		}
+		config.ClientSessionCache = cache
+	}
+	return config
+}
+
+// quicListener turns the streams that are opened on the accepted QUIC
+// connections into a net.Listener that can be served by drpc.
+type quicListener struct {
+	ln        *quic.EarlyListener
+	streams   chan net.Conn
+	closech   chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *quicListener) acceptLoop() {
+	for {
+		conn, err := l.ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		go l.acceptStreams(conn)
+	}
+}
+
+func (l *quicListener) acceptStreams(conn quic.EarlyConnection) {
+	for {
+		stream, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		select {
+		case l.streams <- &quicStreamConn{Stream: stream, conn: conn}:
+		case <-l.closech:
+			stream.CancelRead(0)
+			stream.CancelWrite(0)
+			return
+		}
+	}
+}
+
+func (l *quicListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.streams:
+		return conn, nil
+	case <-l.closech:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *quicListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.closech)
+		err = l.ln.Close()
+	})
+	return err
+}
+
+func (l *quicListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// quicStreamConn implements net.Conn on top of a single QUIC stream.
+type quicStreamConn struct {
+	quic.Stream
+	conn quic.EarlyConnection
+}
+
+func (c *quicStreamConn) Close() error {
+	c.Stream.CancelRead(0)
+	return c.Stream.Close()
+}
+
+func (c *quicStreamConn) LocalAddr() net.Addr  { return c.conn.LocalAddr() }
+func (c *quicStreamConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+func (c *quicStreamConn) ConnectionState() tls.ConnectionState {
+	return c.conn.ConnectionState().TLS
+}
+
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"hollywood"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,73 @@
+package remote
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend_QUIC(t *testing.T) {
+	const msgs = 10
+	a, ra, err := makeRemoteEngine(getRandomQUICAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomQUICAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+	wg := &sync.WaitGroup{}
+
+	wg.Add(msgs)
+	pid := a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case *TestMessage:
+			assert.Equal(t, msg.Data, []byte("foo"))
+			wg.Done()
+		}
+	}, "dfoo")
+	assert.Equal(t, ra.Address(), pid.Address)
+
+	for i := 0; i < msgs; i++ {
+		b.Send(pid, &TestMessage{Data: []byte("foo")})
+	}
+	wg.Wait()
+}
+
+func TestRequestResponse_QUIC(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomQUICAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomQUICAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	pid := a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case *TestMessage:
+			c.Respond(&TestMessage{Data: msg.Data})
+		}
+	}, "test")
+
+	resp, err := b.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	require.NoError(t, err)
+	assert.Equal(t, resp.(*TestMessage).Data, []byte("foo"))
+}
+
+func TestSplitScheme(t *testing.T) {
+	scheme, host := splitScheme("quic://127.0.0.1:4000")
+	assert.Equal(t, "quic", scheme)
+	assert.Equal(t, "127.0.0.1:4000", host)
+
+	scheme, host = splitScheme("127.0.0.1:4000")
+	assert.Equal(t, "", scheme)
+	assert.Equal(t, "127.0.0.1:4000", host)
+}
+
+func getRandomQUICAddr() string {
+	return fmt.Sprintf("quic://127.0.0.1:%d", rand.Intn(50000)+10000)
+}
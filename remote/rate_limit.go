@@ -0,0 +1,199 @@
+package remote
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+)
+
+// RateLimitAction is what the remote does with a received message that
+// exceeds a rate limit.
+type RateLimitAction int
+
+const (
+	// RateLimitDelay stops reading from the connection until the message is
+	// within the limit, which slows down the peer.
+	RateLimitDelay RateLimitAction = iota
+	// RateLimitDrop drops the message and publishes a RateLimitExceededEvent.
+	// The sender gets a nack, like for messages that cannot be delivered.
+	RateLimitDrop
+	// RateLimitClose closes the connection the message was received on and
+	// publishes a RateLimitExceededEvent. The peer has to reconnect.
+	RateLimitClose
+)
+
+func (a RateLimitAction) String() string {
+	switch a {
+	case RateLimitDelay:
+		return "delay"
+	case RateLimitDrop:
+		return "drop"
+	case RateLimitClose:
+		return "close"
+	}
+	return "unknown"
+}
+
+// RateLimitConfig configures a token bucket that limits the rate of the
+// messages received from a peer.
+type RateLimitConfig struct {
+	// Rate is the number of messages per second. Zero disables the limit.
+	Rate float64
+	// Burst is the number of messages that may be received at once, before
+	// the rate applies. It defaults to Rate, and at least 1.
+	Burst int
+	// Action is applied to the messages that exceed the limit.
+	Action RateLimitAction
+}
+
+func (c RateLimitConfig) enabled() bool {
+	return c.Rate > 0
+}
+
+func (c RateLimitConfig) burst() float64 {
+	if c.Burst > 0 {
+		return float64(c.Burst)
+	}
+	return math.Max(1, math.Ceil(c.Rate))
+}
+
+// maxTargetLimiters bounds the number of per target limiters of a connection.
+const maxTargetLimiters = 4096
+
+var errRateLimited = errors.New("rate limit exceeded")
+
+// rateLimiter is a token bucket, which is refilled with rate tokens per
+// second up to burst tokens.
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(config RateLimitConfig, now time.Time) *rateLimiter {
+	return &rateLimiter{
+		rate:   config.Rate,
+		burst:  config.burst(),
+		tokens: config.burst(),
+		last:   now,
+	}
+}
+
+func (l *rateLimiter) advance(now time.Time) {
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+elapsed*l.rate)
+	}
+	l.last = now
+}
+
+// allow takes a token if one is available.
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.advance(now)
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// reserve takes n tokens, even if they are not available, and returns the
+// time to wait until they would have been.
+func (l *rateLimiter) reserve(now time.Time, n float64) time.Duration {
+	l.advance(now)
+	l.tokens -= n
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// take takes a token of the limiter according to the action of the config.
+// It returns the time to wait for the token when delaying, or false when the
+// message exceeds the limit.
+func (l *rateLimiter) take(config RateLimitConfig, now time.Time) (time.Duration, bool) {
+	if config.Action == RateLimitDelay {
+		return l.reserve(now, 1), true
+	}
+	return 0, l.allow(now)
+}
+
+// exceededLimit is the limit a message exceeded.
+type exceededLimit struct {
+	config    RateLimitConfig
+	perTarget bool
+}
+
+// inboundLimits holds the rate limiters of a connection, the one of the
+// connection itself and those of the targets of its messages.
+type inboundLimits struct {
+	conn    RateLimitConfig
+	target  RateLimitConfig
+	limiter *rateLimiter
+	targets map[uint64]*rateLimiter
+}
+
+// newInboundLimits returns the limits of a new connection, nil if rate
+// limiting is disabled.
+func newInboundLimits(config Config, now time.Time) *inboundLimits {
+	if !config.RateLimit.enabled() && !config.TargetRateLimit.enabled() {
+		return nil
+	}
+	l := &inboundLimits{
+		conn:    config.RateLimit,
+		target:  config.TargetRateLimit,
+		targets: make(map[uint64]*rateLimiter),
+	}
+	if l.conn.enabled() {
+		l.limiter = newRateLimiter(l.conn, now)
+	}
+	return l
+}
+
+// limit applies the limits to a message to the given target. It returns the
+// time to wait before the message is delivered, or the limit that the message
+// exceeded.
+func (l *inboundLimits) limit(target *actor.PID, now time.Time) (time.Duration, *exceededLimit) {
+	var wait time.Duration
+	if l.limiter != nil {
+		d, ok := l.limiter.take(l.conn, now)
+		if !ok {
+			return 0, &exceededLimit{config: l.conn}
+		}
+		wait = d
+	}
+	if !l.target.enabled() {
+		return wait, nil
+	}
+	key := target.LookupKey()
+	limiter, ok := l.targets[key]
+	if !ok {
+		if len(l.targets) >= maxTargetLimiters {
+			l.prune(now)
+		}
+		limiter = newRateLimiter(l.target, now)
+		l.targets[key] = limiter
+	}
+	d, ok := limiter.take(l.target, now)
+	if !ok {
+		return 0, &exceededLimit{config: l.target, perTarget: true}
+	}
+	return max(wait, d), nil
+}
+
+// prune removes the limiters of the targets that are idle long enough to be
+// refilled, as they behave like new ones. If there are none, all of them are
+// removed.
+func (l *inboundLimits) prune(now time.Time) {
+	for key, limiter := range l.targets {
+		limiter.advance(now)
+		if limiter.tokens >= limiter.burst {
+			delete(l.targets, key)
+		}
+	}
+	if len(l.targets) >= maxTargetLimiters {
+		clear(l.targets)
+	}
+}
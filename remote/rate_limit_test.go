@@ -0,0 +1,156 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := newRateLimiter(RateLimitConfig{Rate: 10, Burst: 2}, now)
+	assert.True(t, l.allow(now))
+	assert.True(t, l.allow(now))
+	assert.False(t, l.allow(now))
+	assert.True(t, l.allow(now.Add(100*time.Millisecond)))
+
+	l = newRateLimiter(RateLimitConfig{Rate: 10, Burst: 1}, now)
+	assert.Equal(t, time.Duration(0), l.reserve(now, 1))
+	assert.Equal(t, 100*time.Millisecond, l.reserve(now, 1))
+	assert.Equal(t, 200*time.Millisecond, l.reserve(now, 1))
+}
+
+func TestInboundLimitsPerTarget(t *testing.T) {
+	now := time.Now()
+	config := NewConfig().WithTargetRateLimit(RateLimitConfig{Rate: 1, Action: RateLimitDrop})
+	limits := newInboundLimits(config, now)
+	require.NotNil(t, limits)
+	foo := actor.NewPID("127.0.0.1:4000", "foo")
+	bar := actor.NewPID("127.0.0.1:4000", "bar")
+
+	_, exceeded := limits.limit(foo, now)
+	assert.Nil(t, exceeded)
+	_, exceeded = limits.limit(foo, now)
+	require.NotNil(t, exceeded)
+	assert.True(t, exceeded.perTarget)
+	_, exceeded = limits.limit(bar, now)
+	assert.Nil(t, exceeded)
+
+	assert.Nil(t, newInboundLimits(NewConfig(), now))
+}
+
+func TestRateLimitDrop(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	config := NewConfig().WithTargetRateLimit(RateLimitConfig{Rate: 0.1, Burst: 2, Action: RateLimitDrop})
+	b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	events := make(chan RateLimitExceededEvent, 10)
+	b.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case RateLimitExceededEvent:
+			events <- msg
+		}
+	}, "listener")
+	time.Sleep(10 * time.Millisecond)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			c.Respond(msg)
+		}
+	}, "echo")
+
+	for i := 0; i < 2; i++ {
+		_, err := a.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+		require.NoError(t, err)
+	}
+	_, err = a.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	var rejected MessageRejectedEvent
+	require.ErrorAs(t, err, &rejected)
+	assert.Equal(t, NackReason_RateLimited, rejected.Reason)
+	assert.Equal(t, []byte("foo"), rejected.Message.(*TestMessage).Data)
+
+	select {
+	case evt := <-events:
+		assert.True(t, evt.PerTarget)
+		assert.Equal(t, RateLimitDrop, evt.Action)
+		assert.Equal(t, ra.Address(), evt.Address)
+		assert.Equal(t, pid.ID, evt.Target.ID)
+	case <-time.After(time.Second):
+		t.Fatal("expected a RateLimitExceededEvent")
+	}
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	config := NewConfig().WithRateLimit(RateLimitConfig{Rate: 20, Burst: 1, Action: RateLimitDelay})
+	b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	received := make(chan struct{}, 5)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(*TestMessage); ok {
+			received <- struct{}{}
+		}
+	}, "receiver")
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		a.Send(pid, &TestMessage{Data: []byte("foo")})
+	}
+	for i := 0; i < 5; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatal("delayed message was not delivered")
+		}
+	}
+	// 4 messages exceed the burst and are delayed by 50ms each.
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestRateLimitClose(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	config := NewConfig().WithRateLimit(RateLimitConfig{Rate: 0.1, Burst: 1, Action: RateLimitClose})
+	b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	events := make(chan RateLimitExceededEvent, 10)
+	b.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case RateLimitExceededEvent:
+			events <- msg
+		}
+	}, "listener")
+	time.Sleep(10 * time.Millisecond)
+	pid := b.SpawnFunc(func(c *actor.Context) {}, "receiver")
+
+	a.Send(pid, &TestMessage{Data: []byte("foo")})
+	a.Send(pid, &TestMessage{Data: []byte("bar")})
+	select {
+	case evt := <-events:
+		assert.False(t, evt.PerTarget)
+		assert.Equal(t, RateLimitClose, evt.Action)
+	case <-time.After(time.Second):
+		t.Fatal("expected a RateLimitExceededEvent")
+	}
+	require.Eventually(t, func() bool {
+		m, ok := ra.PeerMetrics(rb.Address())
+		return ok && m.Reconnects > 0
+	}, time.Second, 10*time.Millisecond)
+}
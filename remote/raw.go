@@ -0,0 +1,30 @@
+package remote
+
+import "google.golang.org/protobuf/proto"
+
+// RawMessage is a message with an opaque payload that is sent over the wire
+// without serializing it. The receiver gets a *RawMessage with the same type
+// and data, so proxies and relays can forward payloads without unmarshaling
+// and marshaling them again.
+//
+// Type is an arbitrary tag that tells the final receiver how to decode the
+// data, for example the name of a protobuf message.
+type RawMessage struct {
+	Type string
+	Data []byte
+}
+
+// NewRawMessage serializes the given protobuf message into a RawMessage.
+func NewRawMessage(msg proto.Message) (*RawMessage, error) {
+	b, err := ProtoSerializer{}.Serialize(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &RawMessage{Type: ProtoSerializer{}.TypeName(msg), Data: b}, nil
+}
+
+// Decode deserializes a RawMessage that holds a protobuf message, like the
+// ones created by NewRawMessage.
+func (m *RawMessage) Decode() (any, error) {
+	return DefaultSerializer{}.Deserialize(m.Data, m.Type)
+}
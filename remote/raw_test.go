@@ -0,0 +1,75 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A relay forwards the raw messages it receives without decoding them, the
+// final receiver decodes the payload.
+func TestRawMessageRelay(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+	c, rc, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rc.Stop()
+
+	received := make(chan any, 1)
+	receiver := c.SpawnFunc(func(ctx *actor.Context) {
+		if msg, ok := ctx.Message().(*RawMessage); ok {
+			assert.Equal(t, "remote.TestMessage", msg.Type)
+			decoded, err := msg.Decode()
+			assert.NoError(t, err)
+			received <- decoded
+		}
+	}, "receiver")
+	relay := b.SpawnFunc(func(ctx *actor.Context) {
+		if _, ok := ctx.Message().(*RawMessage); ok {
+			ctx.Forward(receiver)
+		}
+	}, "relay")
+
+	raw, err := NewRawMessage(&TestMessage{Data: []byte("foo")})
+	require.NoError(t, err)
+	a.Send(relay, raw)
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, []byte("foo"), msg.(*TestMessage).Data)
+	case <-time.After(time.Second):
+		t.Fatal("raw message was not relayed")
+	}
+}
+
+func TestRawMessageOpaquePayload(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	received := make(chan *RawMessage, 1)
+	pid := b.SpawnFunc(func(ctx *actor.Context) {
+		if msg, ok := ctx.Message().(*RawMessage); ok {
+			received <- msg
+		}
+	}, "receiver")
+
+	a.Send(pid, &RawMessage{Type: "json", Data: []byte(`{"foo":"bar"}`)})
+	select {
+	case msg := <-received:
+		assert.Equal(t, "json", msg.Type)
+		assert.Equal(t, []byte(`{"foo":"bar"}`), msg.Data)
+	case <-time.After(time.Second):
+		t.Fatal("raw message was not received")
+	}
+}
@@ -3,13 +3,18 @@ package remote
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/fertigai/hollywood/actor"
+	"github.com/quic-go/quic-go"
+	"storj.io/drpc/drpcctx"
 	"storj.io/drpc/drpcmanager"
 	"storj.io/drpc/drpcmux"
 	"storj.io/drpc/drpcserver"
@@ -18,14 +23,92 @@ import (
 
 // Config holds the remote configuration.
 type Config struct {
-	TLSConfig *tls.Config
-	BuffSize  int
+	TLSConfig  *tls.Config
+	QUICConfig *quic.Config
+	BuffSize   int
+	// Reconnect configures reconnecting to peers and the buffering of
+	// messages while a peer is not connected.
+	Reconnect ReconnectConfig
+	// Streams is the number of parallel streams opened to each peer.
+	// Messages with the same sender and target share a stream and are
+	// delivered in order, while a slow or large message on one stream
+	// doesn't hold up the messages on the others.
+	Streams int
+	// MaxMessageSize is the maximum size of a serialized message in bytes.
+	// Larger messages are rejected with a MessageTooLargeEvent. Zero means
+	// there is no limit other than the buffer size of the stream reader.
+	MaxMessageSize int
+	// ChunkSize enables chunking when set. Messages larger than ChunkSize
+	// are split in chunks of ChunkSize bytes, which are reassembled by the
+	// receiver.
+	ChunkSize int
+	// HeartbeatInterval is the interval at which a ping is sent to each
+	// peer. Zero disables the heartbeats.
+	HeartbeatInterval time.Duration
+	// HeartbeatTimeout is the time without a response from a peer after
+	// which the peer is considered unreachable.
+	HeartbeatTimeout time.Duration
+	// MetricsInterval is the interval at which a PeerMetricsEvent is
+	// broadcasted. Zero disables publishing the metrics.
+	MetricsInterval time.Duration
+	// AdvertisedAddr is the address that is advertised to the peers, in
+	// case it differs from the listen address, like in containers or behind
+	// a NAT. It can hold the host only, in which case the port of the listen
+	// address is used.
+	AdvertisedAddr string
+	// PortMapping maps the port of the listen address to the advertised port.
+	// It is not used when AdvertisedAddr holds a port.
+	PortMapping func(port int) int
+	// Resolver resolves the host names of peer addresses. If nil, the
+	// default resolver of the net package is used.
+	Resolver Resolver
+	// MinProtocolVersion is the oldest version of the wire protocol that is
+	// accepted from peers, see WithMinProtocolVersion.
+	MinProtocolVersion int32
+	// ListenAddrs are the addresses the remote listens on in addition to
+	// the address given to New, see WithListenAddresses.
+	ListenAddrs []string
+	// UnixSocketMode holds the file permissions of the socket file created
+	// when listening on a "unix://" address.
+	UnixSocketMode os.FileMode
+	// FlowControl limits the number of messages in flight to each peer.
+	FlowControl FlowControlConfig
+	// PeerFlowControl overrides FlowControl for the peers with the given
+	// listen addresses.
+	PeerFlowControl map[string]FlowControlConfig
+	// Encryption provides the keys to encrypt the payloads of the messages
+	// with. Nil disables encryption.
+	Encryption KeyProvider
+	// RateLimit limits the rate of the messages received over each
+	// connection.
+	RateLimit RateLimitConfig
+	// TargetRateLimit limits the rate of the messages received over each
+	// connection for each target actor.
+	TargetRateLimit RateLimitConfig
+	// ACL decides which peers may deliver messages, see WithACL.
+	ACL ACL
+	// Outbox persists the messages buffered for peers that are not
+	// connected. Nil keeps them in memory only.
+	Outbox OutboxStore
+	// Bandwidth limits the rate of the bytes sent to each peer.
+	Bandwidth BandwidthConfig
+	// PeerBandwidth overrides Bandwidth for the peers with the given
+	// listen addresses.
+	PeerBandwidth map[string]BandwidthConfig
+	// Transports holds custom transports by the address scheme they serve,
+	// see WithTransport.
+	Transports map[string]Transport
+	// Batching holds back the messages sent to a peer, so more of them are
+	// sent at once.
+	Batching BatchConfig
 	// Wg        *sync.WaitGroup
 }
 
 // NewConfig returns a new default remote configuration.
 func NewConfig() Config {
-	return Config{}
+	return Config{
+		Reconnect: NewReconnectConfig(),
+	}
 }
 
 // WithTLS sets the TLS config of the remote which will set
@@ -35,6 +118,261 @@ func (c Config) WithTLS(tlsconf *tls.Config) Config {
 	return c
 }
 
+// WithQUICConfig sets the configuration of the QUIC transport, which is used
+// for addresses with the "quic://" scheme. If not provided, sane defaults
+// with keep-alives and 0-RTT reconnects are used.
+func (c Config) WithQUICConfig(conf *quic.Config) Config {
+	c.QUICConfig = conf
+	return c
+}
+
+// WithUnixSocketMode sets the file permissions of the socket file that is
+// created when the remote listens on a "unix://" address.
+// If not provided, only the owner of the process can connect (0600).
+func (c Config) WithUnixSocketMode(mode os.FileMode) Config {
+	c.UnixSocketMode = mode
+	return c
+}
+
+// WithReconnect sets how the remote reconnects to peers it lost the
+// connection with and how many outbound messages are buffered meanwhile.
+//
+// Defaults to NewReconnectConfig().
+func (c Config) WithReconnect(rc ReconnectConfig) Config {
+	c.Reconnect = rc
+	return c
+}
+
+// WithStreams sets the number of parallel streams opened to each peer.
+// If not provided, a single stream is used.
+func (c Config) WithStreams(n int) Config {
+	c.Streams = n
+	return c
+}
+
+func (c Config) streams() int {
+	if c.Streams < 1 {
+		return 1
+	}
+	return c.Streams
+}
+
+// WithMaxMessageSize sets the maximum size of a serialized message. Messages
+// that are larger are not sent. Instead, a MessageTooLargeEvent is sent to
+// the sender of the message and broadcasted to the event stream.
+func (c Config) WithMaxMessageSize(size int) Config {
+	c.MaxMessageSize = size
+	return c
+}
+
+// WithChunking splits the messages that are larger than the given size in
+// chunks, so a single large message doesn't exceed the buffer size of the
+// stream reader.
+func (c Config) WithChunking(chunkSize int) Config {
+	c.ChunkSize = chunkSize
+	return c
+}
+
+// WithHeartbeat sends a ping to each peer every interval. A peer that doesn't
+// respond within the timeout is considered unreachable: a RemoteUnreachableEvent
+// is broadcasted and the connection is reestablished. Once the peer responds
+// again a RemoteRestoredEvent is broadcasted. If the timeout is zero, it
+// defaults to three times the interval.
+//
+// The peers must run a version of the remote that responds to the pings.
+func (c Config) WithHeartbeat(interval, timeout time.Duration) Config {
+	c.HeartbeatInterval = interval
+	c.HeartbeatTimeout = timeout
+	return c
+}
+
+func (c Config) heartbeatTimeout() time.Duration {
+	if c.HeartbeatTimeout <= 0 {
+		return 3 * c.HeartbeatInterval
+	}
+	return c.HeartbeatTimeout
+}
+
+// WithMetricsInterval periodically broadcasts a PeerMetricsEvent with the
+// metrics of all the peers to the event stream.
+func (c Config) WithMetricsInterval(interval time.Duration) Config {
+	c.MetricsInterval = interval
+	return c
+}
+
+// WithAdvertisedAddress sets the address that is advertised to the peers,
+// which is the address of the PIDs of the engine. The remote still listens
+// on the address given to New. The address can be a host or a host:port.
+func (c Config) WithAdvertisedAddress(addr string) Config {
+	c.AdvertisedAddr = addr
+	return c
+}
+
+// WithResolver sets the resolver of the host names in the addresses of
+// peers. Host names are resolved every time the remote connects to a peer,
+// and each address the host resolves to is tried until one accepts the
+// connection. This keeps messages to addresses like Kubernetes services
+// flowing when the pods behind them are replaced. Applies to the TCP and
+// QUIC transports.
+func (c Config) WithResolver(resolver Resolver) Config {
+	c.Resolver = resolver
+	return c
+}
+
+// WithMinProtocolVersion sets the oldest version of the wire protocol the
+// remote accepts from its peers. Peers that are older, or that require a newer
+// version than ProtocolVersion, are rejected with a ProtocolMismatchEvent
+// instead of exchanging messages they might not understand. If not provided,
+// all versions since the first are accepted, which allows rolling upgrades.
+func (c Config) WithMinProtocolVersion(version int32) Config {
+	c.MinProtocolVersion = version
+	return c
+}
+
+// WithListenAddresses makes the remote listen on the given addresses as well
+// as on the address given to New, for example on both IPv4 and IPv6, or on a
+// TCP port and a Unix socket. The addresses can use any of the transports.
+// The peers are still given the address given to New, or the advertised
+// address, while the other addresses can be dialed by peers that cannot reach
+// that one.
+func (c Config) WithListenAddresses(addrs ...string) Config {
+	c.ListenAddrs = addrs
+	return c
+}
+
+// WithPortMapping sets a function that maps the port of the listen address
+// to the port that is advertised to the peers, for hosts where the ports are
+// forwarded, like containers with published ports.
+func (c Config) WithPortMapping(f func(port int) int) Config {
+	c.PortMapping = f
+	return c
+}
+
+// WithFlowControl enables credit based flow control for all peers, so a fast
+// sender cannot overwhelm a slow peer. At most Window messages are in flight
+// to a peer; Send blocks or rejects the message with a FlowControlExceededEvent
+// once they are, until the peer reports it has enqueued some of them in the
+// mailboxes of their targets.
+//
+// The peers must run a version of the remote that reports the enqueued messages.
+func (c Config) WithFlowControl(fc FlowControlConfig) Config {
+	c.FlowControl = fc
+	return c
+}
+
+// WithPeerFlowControl sets the flow control of the peer with the given listen
+// address, overriding the one set with WithFlowControl. A zero Window disables
+// flow control for the peer.
+func (c Config) WithPeerFlowControl(address string, fc FlowControlConfig) Config {
+	peers := make(map[string]FlowControlConfig, len(c.PeerFlowControl)+1)
+	for addr, pfc := range c.PeerFlowControl {
+		peers[addr] = pfc
+	}
+	peers[address] = fc
+	c.PeerFlowControl = peers
+	return c
+}
+
+// WithEncryption encrypts the payloads of the messages with AES-GCM, using
+// the keys of the given provider. This protects the contents of the messages
+// when TLS is terminated by proxies that should not see them. The envelope,
+// holding the type names and PIDs, is not encrypted.
+//
+// The peers need the keys as well, messages they cannot decrypt are dropped,
+// and so are the messages they receive without encryption.
+func (c Config) WithEncryption(keys KeyProvider) Config {
+	c.Encryption = keys
+	return c
+}
+
+// WithRateLimit limits the rate of the messages received over each connection,
+// so a misbehaving peer cannot flood the actors of this node. The Action of the
+// config is applied to the messages exceeding the limit.
+//
+// Delaying stops reading from the connection, which also delays the heartbeats
+// of the peer. Use a heartbeat timeout that is long enough to cover the delays.
+func (c Config) WithRateLimit(rl RateLimitConfig) Config {
+	c.RateLimit = rl
+	return c
+}
+
+// WithTargetRateLimit limits the rate of the messages received over each
+// connection for each target actor, so a peer cannot flood a single actor
+// while the other actors of this node are reachable. It can be combined with
+// WithRateLimit.
+func (c Config) WithTargetRateLimit(rl RateLimitConfig) Config {
+	c.TargetRateLimit = rl
+	return c
+}
+
+// WithACL only accepts the connections of the peers that pass the rules of the
+// given ACL. It is evaluated when a peer connects; rejected peers are reported
+// with a PeerRejectedEvent and don't retry connecting.
+func (c Config) WithACL(acl ACL) Config {
+	c.ACL = acl
+	return c
+}
+
+// WithOutbox persists the messages that are buffered while their peer is not
+// connected in the given store, so they survive a restart of the process and
+// are sent once the peer is reachable. Use NewFileOutbox to store them on disk.
+//
+// Combine it with a Reconnect config that retries forever, otherwise the
+// messages are dropped once the peer is considered unreachable. With
+// encryption, the messages are stored encrypted, and restored as long as
+// their keys are provided, see WithEncryption.
+func (c Config) WithOutbox(store OutboxStore) Config {
+	c.Outbox = store
+	return c
+}
+
+// WithBandwidth limits the rate of the bytes sent to each peer, so bulk
+// traffic to a peer cannot saturate a link that is shared with latency
+// sensitive traffic. The limit holds for all the streams to a peer together.
+// Envelopes that exceed it wait until the bucket is refilled, only the
+// messages of the priority lane are sent right away.
+func (c Config) WithBandwidth(bw BandwidthConfig) Config {
+	c.Bandwidth = bw
+	return c
+}
+
+// WithPeerBandwidth sets the bandwidth limit of the peer with the given listen
+// address, overriding the one set with WithBandwidth. A zero BytesPerSecond
+// disables the limit for the peer.
+func (c Config) WithPeerBandwidth(address string, bw BandwidthConfig) Config {
+	peers := make(map[string]BandwidthConfig, len(c.PeerBandwidth)+1)
+	for addr, pbw := range c.PeerBandwidth {
+		peers[addr] = pbw
+	}
+	peers[address] = bw
+	c.PeerBandwidth = peers
+	return c
+}
+
+// WithTransport registers a custom transport for the addresses with the given
+// scheme, like "shm" for "shm://node-1". It replaces the built-in transport
+// of the scheme, if any; the empty scheme is the one of plain addresses that
+// use TCP otherwise. The peers need to register the transport as well.
+func (c Config) WithTransport(scheme string, t Transport) Config {
+	transports := make(map[string]Transport, len(c.Transports)+1)
+	for s, tr := range c.Transports {
+		transports[s] = tr
+	}
+	transports[scheme] = t
+	c.Transports = transports
+	return c
+}
+
+// WithBatching holds back the messages sent to a peer until MaxSize bytes of
+// them are collected or MaxDelay passed, and sends them in a single envelope.
+// This trades a bit of latency for less framing and fewer syscalls when
+// sending many small messages. Messages of the priority lane are not held
+// back.
+func (c Config) WithBatching(bc BatchConfig) Config {
+	c.Batching = bc
+	return c
+}
+
 // Set the buffer size of the stream reader.
 // If not provided, the default buffer size is 4MB
 // defined by drpc package
@@ -45,12 +383,19 @@ func (c Config) WithBufferSize(size int) Config {
 
 type Remote struct {
 	addr            string
+	advertisedAddr  string
 	engine          *actor.Engine
 	config          Config
 	streamRouterPID *actor.PID
 	stopCh          chan struct{} // Stop closes this channel to signal the remote to stop listening.
 	stopWg          *sync.WaitGroup
 	state           atomic.Uint32
+	transports      map[string]Transport
+	metrics         *metrics
+	flow            *flowControl
+	bandwidth       *bandwidthLimits
+	// serving is the number of listeners that serve, see CheckHealth.
+	serving atomic.Int32
 }
 
 const (
@@ -61,11 +406,37 @@ const (
 )
 
 // New creates a new "Remote" object given a Config.
+//
+// The scheme of the given address selects the transport. Addresses without
+// a scheme ("127.0.0.1:4000") use TCP, "quic://127.0.0.1:4000" uses QUIC and
+// "ws://127.0.0.1:4000/path" or "wss://127.0.0.1:4000/path" use WebSockets and
+// "unix:///tmp/hollywood.sock" uses a Unix domain socket. Other schemes are
+// served by the transports registered with WithTransport.
 func New(addr string, config Config) *Remote {
 	r := &Remote{
-		addr:   addr,
-		config: config,
+		addr:    addr,
+		config:  config,
+		metrics: newMetrics(),
+		flow:    newFlowControl(config),
+		// The throttles are shared by the streams to a peer.
+		bandwidth: newBandwidthLimits(config),
+		transports: map[string]Transport{
+			"":         tcpTransport{tlsConfig: config.TLSConfig, resolver: config.Resolver},
+			quicScheme: newQUICTransport(config.TLSConfig, config.QUICConfig, config.Resolver),
+			wsScheme:   wsTransport{},
+			wssScheme:  wsTransport{secure: true, tlsConfig: config.TLSConfig},
+			unixScheme: unixTransport{tlsConfig: config.TLSConfig, mode: config.UnixSocketMode},
+		},
+	}
+	for scheme, t := range config.Transports {
+		r.transports[scheme] = t
 	}
+	advertised, err := advertisedAddress(addr, config)
+	if err != nil {
+		slog.Error("failed to determine advertised address", "err", err, "addr", addr)
+		advertised = addr
+	}
+	r.advertisedAddr = advertised
 	r.state.Store(stateInitialized)
 	return r
 }
@@ -76,19 +447,10 @@ func (r *Remote) Start(e *actor.Engine) error {
 	}
 	r.state.Store(stateRunning)
 	r.engine = e
-	var ln net.Listener
-	var err error
-	switch r.config.TLSConfig {
-	case nil:
-		ln, err = net.Listen("tcp", r.addr)
-	default:
-		slog.Debug("remote using TLS for listening")
-		ln, err = tls.Listen("tcp", r.addr, r.config.TLSConfig)
-	}
+	lns, err := r.listen()
 	if err != nil {
-		return fmt.Errorf("remote failed to listen: %w", err)
+		return err
 	}
-	slog.Debug("listening", "addr", r.addr)
 	mux := drpcmux.New()
 	err = DRPCRegisterRemote(mux, newStreamReader(r))
 	if err != nil {
@@ -103,30 +465,145 @@ func (r *Remote) Start(e *actor.Engine) error {
 	})
 
 	r.streamRouterPID = r.engine.Spawn(
-		newStreamRouter(r.engine, r.config.TLSConfig, r.config.BuffSize),
+		newStreamRouter(r.engine, r.dial, r.config, r.metrics, r.flow, r.bandwidth),
 		"router", actor.WithInboxSize(1024*1024))
 	slog.Debug("server started", "listenAddr", r.addr)
 	r.stopWg = &sync.WaitGroup{}
-	r.stopWg.Add(1)
+	r.stopWg.Add(len(lns))
 	r.stopCh = make(chan struct{})
 	ctx, cancel := context.WithCancel(context.Background())
-	go func() {
-		defer r.stopWg.Done()
-		err := s.Serve(ctx, ln)
-		if err != nil {
-			slog.Error("drpcserver", "err", err)
-		} else {
-			slog.Debug("drpcserver stopped")
-		}
-	}()
+	r.serving.Add(int32(len(lns)))
+	for _, ln := range lns {
+		go func(ln net.Listener) {
+			defer r.stopWg.Done()
+			defer r.serving.Add(-1)
+			err := serve(ctx, s, ln)
+			if err != nil {
+				slog.Error("drpcserver", "err", err)
+			} else {
+				slog.Debug("drpcserver stopped")
+			}
+		}(ln)
+	}
 	// wait for stopCh to be closed
 	go func() {
 		<-r.stopCh
 		cancel()
 	}()
+	if r.config.MetricsInterval > 0 {
+		go r.publishMetrics(ctx)
+	}
+	if r.config.Outbox != nil {
+		return r.restore()
+	}
 	return nil
 }
 
+// listen opens a listener for each of the listen addresses. If one of them
+// fails, the listeners that were already opened are closed.
+func (r *Remote) listen() ([]net.Listener, error) {
+	addrs := r.ListenAddresses()
+	lns := make([]net.Listener, 0, len(addrs))
+	closeAll := func() {
+		for _, ln := range lns {
+			ln.Close()
+		}
+	}
+	for _, addr := range addrs {
+		t, taddr, err := r.transportFor(addr)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		ln, err := t.Listen(taddr)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("remote failed to listen on %s: %w", addr, err)
+		}
+		slog.Debug("listening", "addr", addr)
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// serve serves the connections accepted by the given listener until the
+// context is done. Unlike drpcserver.Serve it adds the connection to the
+// context of its streams, so the stream reader knows which peer it talks to.
+func serve(ctx context.Context, s *drpcserver.Server, ln net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var nerr net.Error
+			if errors.As(err, &nerr) && nerr.Timeout() {
+				continue
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.ServeOne(drpcctx.WithTransport(ctx, conn), conn); err != nil {
+				slog.Debug("drpcserver connection closed", "err", err)
+			}
+		}()
+	}
+}
+
+func (r *Remote) publishMetrics(ctx context.Context) {
+	ticker := time.NewTicker(r.config.MetricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.engine.BroadcastEvent(PeerMetricsEvent{Peers: r.Metrics()})
+		}
+	}
+}
+
+// Metrics returns the metrics of all the peers the remote has exchanged
+// messages with, sorted by address.
+func (r *Remote) Metrics() []PeerMetrics {
+	return r.metrics.snapshot()
+}
+
+// PeerStats returns the traffic with the peers of the remote for the stats of
+// its engine, see actor.Engine.Stats.
+func (r *Remote) PeerStats() []actor.PeerStats {
+	peers := r.Metrics()
+	stats := make([]actor.PeerStats, len(peers))
+	for i, p := range peers {
+		stats[i] = actor.PeerStats{
+			Address:          p.Address,
+			MessagesSent:     p.MessagesSent,
+			MessagesReceived: p.MessagesReceived,
+			QueueDepth:       p.QueueDepth,
+		}
+	}
+	return stats
+}
+
+// PeerMetrics returns the metrics of the peer with the given listen address.
+func (r *Remote) PeerMetrics(address string) (PeerMetrics, bool) {
+	p, ok := r.metrics.lookup(address)
+	if !ok {
+		return PeerMetrics{}, false
+	}
+	return p.snapshot(), true
+}
+
 // Stop will stop the remote from listening.
 func (r *Remote) Stop() *sync.WaitGroup {
 	if r.state.Load() != stateRunning {
@@ -143,29 +620,73 @@ func (r *Remote) Stop() *sync.WaitGroup {
 // message.
 // Sending will work even if the remote is stopped. Receiving however, will not work.
 func (r *Remote) Send(pid *actor.PID, msg any, sender *actor.PID) {
+	msg, trace := untrace(msg)
+	if !r.acquire(pid, msg, sender) {
+		return
+	}
 	r.engine.Send(r.streamRouterPID, &streamDeliver{
 		target: pid,
 		sender: sender,
 		msg:    msg,
+		trace:  trace,
 	})
 }
 
 // SendPriority sends a priority message to the process with the given pid over the network.
 // Priority messages are placed at the front of the recipient's mailbox.
 func (r *Remote) SendPriority(pid *actor.PID, msg any, sender *actor.PID) {
+	msg, trace := untrace(msg)
+	if !r.acquire(pid, msg, sender) {
+		return
+	}
 	r.engine.Send(r.streamRouterPID, &streamDeliver{
 		target:   pid,
 		sender:   sender,
 		msg:      msg,
+		trace:    trace,
 		priority: true,
 	})
 }
 
-// Address returns the listen address of the remote.
+// acquire takes a credit of the flow control window of the peer of the given
+// pid. When there is none, the message is rejected with a FlowControlExceededEvent.
+func (r *Remote) acquire(pid *actor.PID, msg any, sender *actor.PID) bool {
+	w := r.flow.window(pid.Address)
+	if w.acquire() {
+		return true
+	}
+	evt := FlowControlExceededEvent{
+		Address: pid.Address,
+		Target:  pid,
+		Sender:  sender,
+		Message: msg,
+		Window:  w.config.Window,
+	}
+	if sender != nil {
+		r.engine.Send(sender, evt)
+	}
+	r.engine.BroadcastEvent(evt)
+	return false
+}
+
+// Address returns the address of the remote that is advertised to its
+// peers. Unless configured otherwise this is the listen address.
 func (r *Remote) Address() string {
+	return r.advertisedAddr
+}
+
+// ListenAddress returns the address the remote listens on, which is the
+// address given to New.
+func (r *Remote) ListenAddress() string {
 	return r.addr
 }
 
+// ListenAddresses returns all the addresses the remote listens on, starting
+// with the address given to New.
+func (r *Remote) ListenAddresses() []string {
+	return append([]string{r.addr}, r.config.ListenAddrs...)
+}
+
 func init() {
-	RegisterType(&actor.PID{})
+	RegisterVTType(&actor.PID{})
 }
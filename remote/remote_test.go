@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -15,7 +16,7 @@ import (
 
 func init() {
 	// Needed for now when having the VTProtoserializer
-	RegisterType(&TestMessage{})
+	RegisterVTType(&TestMessage{})
 }
 
 type dlactor struct {
@@ -51,7 +52,7 @@ func (a *dlactor) Receive(c *actor.Context) {
 // remote.
 func TestRemoteUnreachableMessagesEndUpInDeadletter(t *testing.T) {
 	n := 10
-	a, _, err := makeRemoteEngine(getRandomLocalhostAddr())
+	a, _, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), fastReconnectConfig())
 	assert.Nil(t, err)
 
 	wg := &sync.WaitGroup{}
@@ -216,7 +217,7 @@ func TestWeird(t *testing.T) {
 }
 
 func TestStreamWriterRemoteUnreachableEvent(t *testing.T) {
-	a, _, err := makeRemoteEngine(getRandomLocalhostAddr())
+	a, _, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), fastReconnectConfig())
 	assert.NoError(t, err)
 	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
 	assert.NoError(t, err)
@@ -269,3 +270,155 @@ func tcpPing(addr string) error {
 	defer conn.Close()
 	return nil
 }
+
+func TestSpawnRemote(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	b.RegisterKind("echo", func() actor.Receiver { return &echoer{} })
+	pid, err := a.SpawnRemote(rb.Address(), "echo", "1")
+	require.NoError(t, err)
+	assert.Equal(t, rb.Address(), pid.Address)
+	assert.Equal(t, "echo/1", pid.ID)
+
+	resp, err := a.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo"), resp.(*TestMessage).Data)
+
+	_, err = a.SpawnRemote(rb.Address(), "echo", "1")
+	assert.ErrorContains(t, err, "already exists")
+	_, err = a.SpawnRemote(rb.Address(), "foo", "1")
+	assert.ErrorContains(t, err, "not registered")
+}
+
+type echoer struct{}
+
+func (echoer) Receive(c *actor.Context) {
+	if msg, ok := c.Message().(*TestMessage); ok {
+		c.Respond(msg)
+	}
+}
+
+type codedError struct{}
+
+func (codedError) Error() string { return "not found" }
+func (codedError) Code() int32   { return 404 }
+
+func TestRequestResponseError(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			if string(msg.Data) == "panic" {
+				panic("oops")
+			}
+			c.Respond(codedError{})
+		}
+	}, "failing", actor.WithMaxRestarts(1))
+
+	_, err = a.Request(pid, &TestMessage{Data: []byte("error")}, time.Second).Result()
+	var rerr *actor.ResponseError
+	require.ErrorAs(t, err, &rerr)
+	assert.Equal(t, "remote.codedError", rerr.Type)
+	assert.Equal(t, "not found", rerr.Message)
+	assert.Equal(t, int32(404), rerr.Code)
+
+	_, err = a.Request(pid, &TestMessage{Data: []byte("panic")}, time.Second).Result()
+	require.ErrorAs(t, err, &rerr)
+	assert.True(t, rerr.Panicked())
+}
+
+func TestOpenStream(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	var (
+		received []byte
+		done     = make(chan *actor.StreamEOF, 1)
+	)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case *actor.StreamChunk:
+			received = append(received, msg.Data...)
+		case *actor.StreamEOF:
+			done <- msg
+		}
+	}, "receiver")
+
+	data := make([]byte, 1024*1024)
+	rand.Read(data)
+	s := a.OpenStream(pid, actor.WithStreamChunkSize(32*1024), actor.WithStreamWindow(4))
+	_, err = s.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	select {
+	case eof := <-done:
+		assert.Empty(t, eof.Error)
+		assert.Equal(t, data, received)
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream was not received")
+	}
+}
+
+func TestMultipleListenAddresses(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 is not available:", err)
+	}
+	ipv6Addr := ln.Addr().String()
+	ln.Close()
+	unixAddr := "unix://" + filepath.Join(t.TempDir(), "b.sock")
+
+	addr := getRandomLocalhostAddr()
+	b, rb, err := makeRemoteEngineWithConfig(addr, NewConfig().WithListenAddresses(ipv6Addr, unixAddr))
+	require.NoError(t, err)
+	defer rb.Stop()
+	assert.Equal(t, addr, rb.Address())
+	assert.Equal(t, []string{addr, ipv6Addr, unixAddr}, rb.ListenAddresses())
+
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			c.Respond(msg)
+		}
+	}, "echo")
+	assert.Equal(t, addr, pid.Address)
+
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	for _, listenAddr := range rb.ListenAddresses() {
+		target := actor.NewPID(listenAddr, pid.ID)
+		resp, err := a.Request(target, &TestMessage{Data: []byte(listenAddr)}, time.Second).Result()
+		require.NoError(t, err, listenAddr)
+		assert.Equal(t, []byte(listenAddr), resp.(*TestMessage).Data)
+	}
+}
+
+func TestMultipleListenAddressesFailure(t *testing.T) {
+	addr := getRandomLocalhostAddr()
+	_, rb, err := makeRemoteEngine(addr)
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	// The second address is in use, hence the first one is released again.
+	other := getRandomLocalhostAddr()
+	_, _, err = makeRemoteEngineWithConfig(other, NewConfig().WithListenAddresses(addr))
+	require.Error(t, err)
+	ln, err := net.Listen("tcp", other)
+	require.NoError(t, err)
+	ln.Close()
+}
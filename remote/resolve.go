@@ -0,0 +1,65 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+)
+
+// dialAddrTimeout is the time we try to connect to a single address a host
+// resolves to, before we fail over to the next one.
+const dialAddrTimeout = 5 * time.Second
+
+// Resolver resolves the host names of the addresses of peers. It is
+// implemented by *net.Resolver.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// dialResolved resolves the host of the given address and dials the
+// addresses it resolves to one after the other, until one of them accepts
+// the connection. As the host is resolved on every dial, reconnects follow
+// the changes of the DNS records, like the pods behind a Kubernetes service
+// being replaced. Addresses holding an IP are dialed as is.
+func dialResolved[T any](ctx context.Context, resolver Resolver, addr string, dial func(ctx context.Context, addr string) (T, error)) (T, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return dial(ctx, addr)
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	var zero T
+	hosts, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return zero, err
+	}
+	var errs []error
+	for _, h := range hosts {
+		dctx, cancel := context.WithTimeout(ctx, dialAddrTimeout)
+		conn, err := dial(dctx, net.JoinHostPort(h, port))
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return zero, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	return zero, errors.Join(errs...)
+}
+
+// withServerName returns a copy of the given TLS config that verifies the
+// certificate against host, so dialing the resolved IP of a host still checks
+// the certificate of the host.
+func withServerName(config *tls.Config, host string) *tls.Config {
+	if config.ServerName != "" {
+		return config
+	}
+	config = config.Clone()
+	config.ServerName = host
+	return config
+}
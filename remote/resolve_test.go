@@ -0,0 +1,118 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver struct {
+	mu    sync.Mutex
+	hosts map[string][]string
+}
+
+func (r *fakeResolver) set(host string, addrs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hosts[host] = addrs
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	addrs, ok := r.hosts[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "not found", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func TestDialResolved(t *testing.T) {
+	resolver := &fakeResolver{hosts: map[string][]string{"peer.test": {"10.0.0.1", "10.0.0.2"}}}
+	var dialed []string
+	dial := func(_ context.Context, addr string) (string, error) {
+		dialed = append(dialed, addr)
+		if addr == "10.0.0.1:4000" {
+			return "", errors.New("connection refused")
+		}
+		return addr, nil
+	}
+
+	conn, err := dialResolved(context.Background(), resolver, "peer.test:4000", dial)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2:4000", conn)
+	assert.Equal(t, []string{"10.0.0.1:4000", "10.0.0.2:4000"}, dialed)
+
+	// IP addresses are not resolved.
+	dialed = nil
+	_, err = dialResolved(context.Background(), resolver, "10.0.0.3:4000", dial)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.3:4000"}, dialed)
+
+	_, err = dialResolved(context.Background(), resolver, "unknown.test:4000", dial)
+	var dnsErr *net.DNSError
+	assert.ErrorAs(t, err, &dnsErr)
+}
+
+// Messages to a host name keep being delivered when the remote behind it is
+// replaced by one with another IP.
+func TestResolverFailover(t *testing.T) {
+	port := rand.Intn(50000) + 10000
+	resolver := &fakeResolver{hosts: make(map[string][]string)}
+	resolver.set("peer.test", "127.0.0.1", "127.0.0.2")
+
+	config := NewConfig().WithResolver(resolver).WithReconnect(ReconnectConfig{
+		MaxAttempts:    -1,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+	})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	received := make(chan string, 2)
+	receiver := func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- string(msg.Data)
+		}
+	}
+	// Nothing listens on the first address the host resolves to.
+	b, rb, err := makeRemoteEngine(fmt.Sprintf("127.0.0.2:%d", port))
+	require.NoError(t, err)
+	b.SpawnFunc(receiver, "receiver", actor.WithID("1"))
+
+	target := actor.NewPID(fmt.Sprintf("peer.test:%d", port), "receiver/1")
+	a.Send(target, &TestMessage{Data: []byte("first")})
+	select {
+	case msg := <-received:
+		assert.Equal(t, "first", msg)
+	case <-time.After(time.Second):
+		t.Fatal("message was not delivered to the second address")
+	}
+
+	rb.Stop().Wait()
+	// Wait for the writer to notice the connection is gone.
+	time.Sleep(50 * time.Millisecond)
+	resolver.set("peer.test", "127.0.0.3")
+	c, rc, err := makeRemoteEngine(fmt.Sprintf("127.0.0.3:%d", port))
+	require.NoError(t, err)
+	defer rc.Stop()
+	c.SpawnFunc(receiver, "receiver", actor.WithID("1"))
+
+	a.Send(target, &TestMessage{Data: []byte("second")})
+	select {
+	case msg := <-received:
+		assert.Equal(t, "second", msg)
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not delivered after the host was resolved again")
+	}
+}
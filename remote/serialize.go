@@ -1,6 +1,8 @@
 package remote
 
 import (
+	"fmt"
+
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
@@ -25,30 +27,36 @@ type VTUnmarshaler interface {
 	UnmarshalVT([]byte) error
 }
 
-// Todo: delete this or state why it isn't deleted.
-// type DefaultSerializer struct{}
-
-// func (DefaultSerializer) Serialize(msg any) ([]byte, error) {
-// 	switch msg.(type) {
-// 	case VTMarshaler:
-// 		return VTProtoSerializer{}.Serialize(msg)
-// 	case proto.Message:
-// 		return ProtoSerializer{}.Serialize(msg)
-// 	default:
-// 		return nil, fmt.Errorf("unsupported message type (%v) for serialization", reflect.TypeOf(msg))
-// 	}
-// }
-
-// func (DefaultSerializer) Deserialize(data []byte, mtype string) (any, error) {
-// 	switch msg.(type) {
-// 	case VTMarshaler:
-// 		return VTProtoSerializer{}.Serialize(msg)
-// 	case proto.Message:
-// 		return ProtoSerializer{}.Serialize(msg)
-// 	default:
-// 		return nil, fmt.Errorf("unsupported message type (%v) for serialization", reflect.TypeOf(msg))
-// 	}
-// }
+// DefaultSerializer serializes the types registered with RegisterCodec with
+// their codec, and protobuf messages otherwise.
+type DefaultSerializer struct{}
+
+func (DefaultSerializer) Serialize(msg any) ([]byte, error) {
+	if rt, ok := lookupType(msg); ok {
+		return rt.encode(msg)
+	}
+	if _, ok := msg.(proto.Message); !ok {
+		return nil, fmt.Errorf("unsupported message type (%T) for serialization, register it with remote.RegisterCodec", msg)
+	}
+	return ProtoSerializer{}.Serialize(msg)
+}
+
+func (DefaultSerializer) Deserialize(data []byte, tname string) (any, error) {
+	if rt, ok := lookupTypeByName(tname); ok {
+		return rt.decode(data)
+	}
+	return ProtoSerializer{}.Deserialize(data, tname)
+}
+
+func (DefaultSerializer) TypeName(msg any) string {
+	if rt, ok := lookupType(msg); ok {
+		return rt.name
+	}
+	if _, ok := msg.(proto.Message); !ok {
+		return fmt.Sprintf("%T", msg)
+	}
+	return ProtoSerializer{}.TypeName(msg)
+}
 
 type ProtoSerializer struct{}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/fertigai/hollywood/actor"
 )
@@ -13,18 +14,37 @@ type streamReader struct {
 
 	remote       *Remote
 	deserializer Deserializer
+	cipher       *payloadCipher
 }
 
 func newStreamReader(r *Remote) *streamReader {
 	return &streamReader{
 		remote:       r,
-		deserializer: ProtoSerializer{},
+		deserializer: DefaultSerializer{},
+		cipher:       newPayloadCipher(r.config.Encryption),
 	}
 }
 
 func (r *streamReader) Receive(stream DRPCRemote_ReceiveStream) error {
 	defer slog.Debug("streamreader terminated")
 
+	var (
+		maxSize = r.remote.config.MaxMessageSize
+		// lanes holds the chunked messages of the regular and the priority
+		// lane, as their chunks may be interleaved.
+		lanes [2]reassembly
+		// peerVersion is the protocol version of the peer, as sent in its
+		// handshake.
+		peerVersion int32 = legacyProtocolVersion
+		// peer is the listen address of the peer, as sent in its handshake.
+		peer   string
+		limits = newInboundLimits(r.remote.config, time.Now())
+		// admitted is set once the peer passed the ACL.
+		admitted bool
+		// negotiated is set once the version of the peer is known to be
+		// compatible.
+		negotiated bool
+	)
 	for {
 		envelope, err := stream.Recv()
 		if err != nil {
@@ -35,26 +55,254 @@ func (r *streamReader) Receive(stream DRPCRemote_ReceiveStream) error {
 			return err
 		}
 
-		for _, msg := range envelope.Messages {
-			tname := envelope.TypeNames[msg.TypeNameIndex]
-			payload, err := r.deserializer.Deserialize(msg.Data, tname)
+		// Peers without the handshake are checked at their first envelope.
+		if !admitted {
+			if err := r.admit(stream, envelope.Handshake); err != nil {
+				return err
+			}
+			admitted = true
+		}
+
+		if hs := envelope.Handshake; hs != nil {
+			local := r.remote.config.handshake(r.remote.Address())
+			if err := stream.Send(&Envelope{Handshake: local}); err != nil {
+				slog.Error("streamReader handshake", "err", err)
+				return err
+			}
+			if !compatible(local, hs) {
+				r.remote.engine.BroadcastEvent(newProtocolMismatchEvent(hs.Address, local, hs))
+				return errProtocolMismatch
+			}
+			peerVersion = hs.Version
+			peer = hs.Address
+			negotiated = true
+			continue
+		}
 
-			if err != nil {
-				slog.Error("streamReader deserialize", "err", err)
+		// Peers without the handshake speak the legacy version, which the
+		// remote may not accept anymore.
+		if !negotiated {
+			local := r.remote.config.handshake(r.remote.Address())
+			if legacy := legacyHandshake(envelopeAddress(envelope)); !compatible(local, legacy) {
+				r.remote.engine.BroadcastEvent(newProtocolMismatchEvent(legacy.Address, local, legacy))
+				return errProtocolMismatch
+			}
+			negotiated = true
+		}
+
+		// An envelope without messages is a heartbeat, which we answer so
+		// the peer knows we are still alive.
+		if len(envelope.Messages) == 0 {
+			if err := stream.Send(&Envelope{}); err != nil {
+				slog.Error("streamReader heartbeat", "err", err)
 				return err
 			}
+			continue
+		}
+
+		var nacks []*Nack
+		lane := &lanes[laneIndex(envelope)]
+		for _, msg := range envelope.Messages {
+			data := msg.Data
+			tooLarge := false
+			if msg.Partial || lane.chunks != nil {
+				if !lane.dropping {
+					lane.chunks = append(lane.chunks, data...)
+				}
+				if maxSize > 0 && len(lane.chunks) > maxSize {
+					lane.dropping = true
+					lane.chunks = lane.chunks[:0]
+				}
+				if msg.Partial {
+					continue
+				}
+				data, lane.chunks = lane.chunks, nil
+				if lane.dropping {
+					lane.dropping = false
+					tooLarge = true
+					slog.Error("streamReader dropped chunked message exceeding the maximum message size", "max", maxSize)
+				}
+			}
+			if !tooLarge && maxSize > 0 && len(data) > maxSize {
+				tooLarge = true
+				slog.Error("streamReader dropped message exceeding the maximum message size", "size", len(data), "max", maxSize)
+			}
+
+			tname := envelope.TypeNames[msg.TypeNameIndex]
 			target := envelope.Targets[msg.TargetIndex]
 			var sender *actor.PID
 			if len(envelope.Senders) > 0 {
 				sender = envelope.Senders[msg.SenderIndex]
 			}
-			if msg.Priority {
-				r.remote.engine.SendPriorityLocal(target, payload, sender)
+			nack := func(reason NackReason, err error) {
+				n := &Nack{Target: target, Sender: sender, TypeName: tname, KeyID: msg.KeyID, Raw: msg.Raw, Reason: reason}
+				if reason != NackReason_TooLarge {
+					n.Data = data
+				}
+				if err != nil {
+					n.Error = err.Error()
+				}
+				nacks = append(nacks, n)
+			}
+			if tooLarge {
+				nack(NackReason_TooLarge, nil)
+				continue
+			}
+			if limits != nil {
+				wait, exceeded := limits.limit(target, time.Now())
+				if exceeded != nil {
+					r.rateLimited(peer, target, sender, exceeded)
+					if exceeded.config.Action == RateLimitClose {
+						return errRateLimited
+					}
+					nack(NackReason_RateLimited, nil)
+					continue
+				}
+				if wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-stream.Context().Done():
+						return nil
+					}
+				}
+			}
+
+			start := time.Now()
+			plain := data
+			switch {
+			case msg.KeyID != "":
+				plain, err = r.decrypt(data, tname, msg.KeyID)
+				if err != nil {
+					slog.Error("streamReader dropped message that could not be decrypted", "err", err, "key", msg.KeyID)
+					nack(NackReason_Undecryptable, err)
+					continue
+				}
+			case r.cipher != nil:
+				// A peer without the keys must not get its messages through
+				// a remote that only accepts encrypted ones.
+				slog.Error("streamReader dropped message that was not encrypted", "type", tname)
+				nack(NackReason_Undecryptable, errNotEncrypted)
+				continue
+			}
+			var payload any
+			if msg.Raw {
+				payload = &RawMessage{Type: tname, Data: plain}
 			} else {
-				r.remote.engine.SendLocal(target, payload, sender)
+				payload, err = r.deserializer.Deserialize(plain, tname)
+				if err != nil {
+					slog.Error("streamReader deserialize", "err", err)
+					nack(NackReason_Undeserializable, err)
+					continue
+				}
+			}
+			r.record(target, sender, len(plain), time.Since(start))
+			if !r.remote.engine.TrySendLocal(target, traced(payload, msg.Trace), sender, msg.Priority) {
+				r.remote.engine.BroadcastEvent(actor.DeadLetterEvent{
+					Target:  target,
+					Message: payload,
+					Sender:  sender,
+				})
+				nack(NackReason_NotFound, nil)
+			}
+		}
+
+		var resp Envelope
+		// The sender uses flow control and waits for us to report the
+		// messages we enqueued, or rejected, before it sends more.
+		if envelope.Ack {
+			resp.Credits = int32(enqueuedMessages(envelope))
+		}
+		// Older peers don't expect nacks.
+		if peerVersion >= nackProtocolVersion {
+			resp.Nacks = nacks
+		}
+		if resp.Credits > 0 || len(resp.Nacks) > 0 {
+			if err := stream.Send(&resp); err != nil {
+				slog.Error("streamReader respond", "err", err)
+				return err
 			}
 		}
 	}
 
 	return nil
 }
+
+// admit checks the peer of the stream against the ACL of the remote. A peer
+// that is not allowed is answered with a rejected handshake, if it sent one,
+// and a PeerRejectedEvent is broadcasted.
+func (r *streamReader) admit(stream DRPCRemote_ReceiveStream, hs *Handshake) error {
+	acl := r.remote.config.ACL
+	if !acl.enabled() {
+		return nil
+	}
+	var address string
+	if hs != nil {
+		address = hs.Address
+	}
+	peer := peerInfo(stream.Context(), address)
+	err := acl.check(peer)
+	if err == nil {
+		return nil
+	}
+	evt := PeerRejectedEvent{Address: address, Reason: err.Error()}
+	if peer.RemoteAddr != nil {
+		evt.RemoteAddr = peer.RemoteAddr.String()
+	}
+	r.remote.engine.BroadcastEvent(evt)
+	if hs != nil {
+		local := r.remote.config.handshake(r.remote.Address())
+		local.Rejected = true
+		if err := stream.Send(&Envelope{Handshake: local}); err != nil {
+			return err
+		}
+	}
+	return errPeerRejected
+}
+
+// envelopeAddress returns the address of the peer that sent the given envelope
+// without a handshake, the one of its senders, if it has any.
+func envelopeAddress(envelope *Envelope) string {
+	for _, sender := range envelope.Senders {
+		if sender != nil {
+			return sender.Address
+		}
+	}
+	return ""
+}
+
+// rateLimited publishes a RateLimitExceededEvent for a message that exceeded
+// the given limit.
+func (r *streamReader) rateLimited(peer string, target, sender *actor.PID, exceeded *exceededLimit) {
+	if peer == "" && sender != nil {
+		peer = sender.Address
+	}
+	r.remote.engine.BroadcastEvent(RateLimitExceededEvent{
+		Address:   peer,
+		Target:    target,
+		Sender:    sender,
+		PerTarget: exceeded.perTarget,
+		Rate:      exceeded.config.Rate,
+		Action:    exceeded.config.Action,
+	})
+}
+
+func (r *streamReader) decrypt(data []byte, tname, keyID string) ([]byte, error) {
+	if r.cipher == nil {
+		return nil, errors.New("encryption is not configured")
+	}
+	return r.cipher.decrypt(data, tname, keyID)
+}
+
+// record updates the metrics of the peer that sent the message. Responses
+// are sent without a sender, hence they are attributed to the peer the
+// request was sent to.
+func (r *streamReader) record(target, sender *actor.PID, size int, took time.Duration) {
+	peer, ok := r.remote.metrics.responseReceived(target)
+	if !ok {
+		if sender == nil {
+			return
+		}
+		peer = r.remote.metrics.peer(sender.Address)
+	}
+	peer.received(size, took)
+}
@@ -1,8 +1,9 @@
 package remote
 
 import (
-	"crypto/tls"
+	"context"
 	"log/slog"
+	"net"
 
 	"github.com/fertigai/hollywood/actor"
 )
@@ -12,24 +13,57 @@ type streamDeliver struct {
 	target   *actor.PID
 	msg      any
 	priority bool
+	// trace is the trace context of the message, see Message.Trace.
+	trace []string
+	// stored is the sequence number of the message in the outbox, zero if
+	// it is not stored.
+	stored uint64
+}
+
+// dialFunc creates a new raw connection to the given remote address.
+type dialFunc func(ctx context.Context, address string) (net.Conn, error)
+
+// streamClosed is sent by a stream writer to the router once it has given up
+// on its remote.
+type streamClosed struct {
+	address string
+	pid     *actor.PID
+}
+
+// streamHealth is sent by a stream writer to the router when its remote
+// misses its heartbeats, or responds again after it did.
+type streamHealth struct {
+	address string
+	healthy bool
 }
 
 type streamRouter struct {
 	engine *actor.Engine
-	// streams is a map of remote address to stream writer pid.
-	streams   map[string]*actor.PID
-	pid       *actor.PID
-	tlsConfig *tls.Config
-	buffSize  int
+	// streams is a map of remote address to the pids of its stream writers.
+	// A slot is nil until a message is routed over it.
+	streams map[string][]*actor.PID
+	// unreachable holds the addresses of the remotes that missed their
+	// heartbeats, so we only publish the changes of their state.
+	unreachable map[string]bool
+	pid         *actor.PID
+	dial        dialFunc
+	config      Config
+	metrics     *metrics
+	flow        *flowControl
+	bandwidth   *bandwidthLimits
 }
 
-func newStreamRouter(e *actor.Engine, tlsConfig *tls.Config, buffSize int) actor.Producer {
+func newStreamRouter(e *actor.Engine, dial dialFunc, config Config, m *metrics, flow *flowControl, bw *bandwidthLimits) actor.Producer {
 	return func() actor.Receiver {
 		return &streamRouter{
-			streams:   make(map[string]*actor.PID),
-			engine:    e,
-			tlsConfig: tlsConfig,
-			buffSize:  buffSize,
+			streams:     make(map[string][]*actor.PID),
+			unreachable: make(map[string]bool),
+			engine:      e,
+			dial:        dial,
+			config:      config,
+			metrics:     m,
+			flow:        flow,
+			bandwidth:   bw,
 		}
 	}
 }
@@ -40,32 +74,83 @@ func (s *streamRouter) Receive(ctx *actor.Context) {
 		s.pid = ctx.PID()
 	case *streamDeliver:
 		s.deliverStream(msg)
-	case actor.RemoteUnreachableEvent:
+	case streamClosed:
 		s.handleTerminateStream(msg)
+	case streamHealth:
+		s.handleStreamHealth(msg)
+	}
+}
+
+func (s *streamRouter) handleStreamHealth(msg streamHealth) {
+	if msg.healthy == !s.unreachable[msg.address] {
+		return
+	}
+	if msg.healthy {
+		delete(s.unreachable, msg.address)
+		s.engine.BroadcastEvent(actor.RemoteRestoredEvent{ListenAddr: msg.address})
+		return
 	}
+	s.unreachable[msg.address] = true
+	s.engine.BroadcastEvent(actor.RemoteUnreachableEvent{ListenAddr: msg.address})
 }
 
-func (s *streamRouter) handleTerminateStream(msg actor.RemoteUnreachableEvent) {
-	streamWriterPID := s.streams[msg.ListenAddr]
-	delete(s.streams, msg.ListenAddr)
+// handleTerminateStream removes the closed stream writer. Once all the
+// streams to the remote are closed the remote is considered unreachable.
+func (s *streamRouter) handleTerminateStream(msg streamClosed) {
 	slog.Debug("stream terminated",
-		"remote", msg.ListenAddr,
-		"pid", streamWriterPID,
+		"remote", msg.address,
+		"pid", msg.pid,
 	)
+	streams, ok := s.streams[msg.address]
+	if !ok {
+		return
+	}
+	open := 0
+	for i, pid := range streams {
+		if pid != nil && pid.Equals(msg.pid) {
+			streams[i] = nil
+		}
+		if streams[i] != nil {
+			open++
+		}
+	}
+	if open > 0 {
+		return
+	}
+	delete(s.streams, msg.address)
+	// The event was already published if the remote missed its heartbeats.
+	if s.unreachable[msg.address] {
+		delete(s.unreachable, msg.address)
+		return
+	}
+	s.engine.BroadcastEvent(actor.RemoteUnreachableEvent{ListenAddr: msg.address})
 }
 
 func (s *streamRouter) deliverStream(msg *streamDeliver) {
-	var (
-		swpid   *actor.PID
-		ok      bool
-		address = msg.target.Address
-	)
-
-	swpid, ok = s.streams[address]
+	address := msg.target.Address
+	streams, ok := s.streams[address]
 	if !ok {
-		swpid = s.engine.SpawnProc(newStreamWriter(s.engine, s.pid, address, s.tlsConfig, s.buffSize))
-		s.streams[address] = swpid
+		streams = make([]*actor.PID, s.config.streams())
+		s.streams[address] = streams
 	}
 
-	s.engine.Send(swpid, msg)
+	// Messages between the same sender and target always go over the same
+	// stream, which keeps them in order.
+	i := streamIndex(msg, len(streams))
+	if streams[i] == nil {
+		streams[i] = s.engine.SpawnProc(newStreamWriter(s.engine, s.pid, address, i, s.dial, s.config, s.metrics, s.flow.window(address), s.bandwidth.throttle(address)))
+	}
+
+	s.engine.Send(streams[i], msg)
+}
+
+func streamIndex(msg *streamDeliver, n int) int {
+	if n == 1 {
+		return 0
+	}
+	key := msg.target.LookupKey()
+	if msg.sender != nil {
+		key = key*31 + msg.sender.LookupKey()
+	}
+	return int(key % uint64(n))
 }
@@ -0,0 +1,100 @@
+package remote
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultipleStreamsKeepOrderPerSender(t *testing.T) {
+	const (
+		senders = 8
+		n       = 100
+	)
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithStreams(4))
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	var (
+		mu   sync.Mutex
+		last = make(map[string]int)
+		wg   sync.WaitGroup
+	)
+	wg.Add(senders * n)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		msg, ok := c.Message().(*TestMessage)
+		if !ok {
+			return
+		}
+		seq, err := strconv.Atoi(string(msg.Data))
+		require.NoError(t, err)
+		mu.Lock()
+		sender := c.Sender().String()
+		assert.Equal(t, last[sender], seq, "out of order message from %s", sender)
+		last[sender] = seq + 1
+		mu.Unlock()
+		wg.Done()
+	}, "receiver")
+
+	for i := 0; i < senders; i++ {
+		sender := actor.NewPID(a.Address(), fmt.Sprintf("sender/%d", i))
+		go func() {
+			for j := 0; j < n; j++ {
+				a.SendWithSender(pid, &TestMessage{Data: []byte(strconv.Itoa(j))}, sender)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The senders should be spread over more than one stream.
+	used := make(map[int]bool)
+	for i := 0; i < senders; i++ {
+		sender := actor.NewPID(a.Address(), fmt.Sprintf("sender/%d", i))
+		used[streamIndex(&streamDeliver{target: pid, sender: sender}, 4)] = true
+	}
+	assert.Greater(t, len(used), 1)
+}
+
+func TestMultipleStreamsRemoteUnreachable(t *testing.T) {
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), fastReconnectConfig().WithStreams(4))
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	events := make(chan actor.RemoteUnreachableEvent, 10)
+	a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case actor.RemoteUnreachableEvent:
+			events <- msg
+		}
+	}, "listener")
+	time.Sleep(10 * time.Millisecond)
+
+	target := actor.NewPID("127.0.0.1:4001", "foo/bar")
+	for i := 0; i < 8; i++ {
+		sender := actor.NewPID(a.Address(), fmt.Sprintf("sender/%d", i))
+		a.SendWithSender(target, &TestMessage{Data: []byte("foo")}, sender)
+	}
+	select {
+	case evt := <-events:
+		assert.Equal(t, target.Address, evt.ListenAddr)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a RemoteUnreachableEvent")
+	}
+	// The event is broadcasted once, after all the streams are closed.
+	select {
+	case <-events:
+		t.Fatal("expected a single RemoteUnreachableEvent")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
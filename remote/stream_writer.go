@@ -2,11 +2,13 @@ package remote
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
-	"io"
 	"log/slog"
+	"math/rand"
 	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fertigai/hollywood/actor"
@@ -22,141 +24,476 @@ const (
 
 type streamWriter struct {
 	writeToAddr string
-	rawconn     net.Conn
-	conn        *drpcconn.Conn
-	stream      DRPCRemote_ReceiveStream
 	engine      *actor.Engine
 	routerPID   *actor.PID
 	pid         *actor.PID
 	inbox       actor.Inboxer
 	serializer  Serializer
-	tlsConfig   *tls.Config
+	dial        dialFunc
 	buffSize    int
+	// cipher encrypts the serialized messages, nil if encryption is disabled.
+	cipher *payloadCipher
+	// maxMessageSize and chunkSize are the limits of a serialized message,
+	// see the MaxMessageSize and ChunkSize fields of Config.
+	maxMessageSize int
+	chunkSize      int
+	reconnect      ReconnectConfig
+	metrics        *metrics
+	stats          *peerStats
+	// heartbeat is the interval of the pings, zero if disabled.
+	heartbeat        time.Duration
+	heartbeatTimeout time.Duration
+	// lastSeen holds the unix nano time of the last response of the remote.
+	lastSeen atomic.Int64
+	// unreachable is set when the remote missed its heartbeats.
+	unreachable atomic.Bool
+	// local is the handshake we send to the remote.
+	local *Handshake
+	// window holds the flow control credits of the remote, nil if disabled.
+	window *window
+	// throttle limits the bandwidth to the remote, nil if disabled.
+	throttle *throttle
+	// batch holds back the messages of the regular lane until the batching
+	// config says to send them, nil if batching is disabled. batched are
+	// their deliveries.
+	batching   BatchConfig
+	batch      *envelopeBuilder
+	batched    []*streamDeliver
+	batchTimer *time.Timer
+	// outbox persists the buffered messages, nil if they are kept in memory
+	// only.
+	outbox OutboxStore
+
+	// mu guards the connection state below. It is held while writing to
+	// the stream, so buffered messages are flushed before new ones, but for
+	// the time a write waits for the bandwidth limit, see waitThrottled.
+	mu      sync.Mutex
+	rawconn net.Conn
+	conn    *drpcconn.Conn
+	stream  DRPCRemote_ReceiveStream
+	// pending holds the messages that are accepted while we are not
+	// connected to the remote. They are flushed once we are (re)connected.
+	pending    []*streamDeliver
+	connecting bool
+	closed     bool
+	// inflight is the number of messages sent over the current connection
+	// that the remote has not reported as enqueued yet.
+	inflight *atomic.Int64
+	// disconnectErr is the reason we closed the current connection for.
+	disconnectErr error
+	// lanes is set when the remote reassembles the chunks of both lanes
+	// separately, so priority messages can be sent in between the chunks
+	// of a large message.
+	lanes bool
+	// throttled is set while a write waits for the bandwidth limit without
+	// the lock. The others wait for it to be cleared before they write to
+	// the stream or replace it, see waitWrite, and writable is signaled
+	// once it is.
+	throttled bool
+	writable  *sync.Cond
+
+	// priorityMu guards the queue of the priority lane. The messages of the
+	// priority lane don't go through the inbox, so they can be sent while a
+	// batch of regular messages is written.
+	priorityMu sync.Mutex
+	priority   []*streamDeliver
+	// priorityQueued wakes up a write waiting for the bandwidth limit once a
+	// message is queued on the priority lane.
+	priorityQueued chan struct{}
 }
 
-func newStreamWriter(e *actor.Engine, rpid *actor.PID, address string, tlsConfig *tls.Config, buffSize int) actor.Processer {
-	return &streamWriter{
-		writeToAddr: address,
-		engine:      e,
-		routerPID:   rpid,
-		inbox:       actor.NewInbox(streamWriterBatchSize),
-		pid:         actor.NewPID(e.Address(), "stream"+"/"+address),
-		serializer:  ProtoSerializer{},
-		tlsConfig:   tlsConfig,
-		buffSize:    buffSize,
+// priorityReady wakes up the writer once a message is queued on the priority
+// lane.
+type priorityReady struct{}
+
+func newStreamWriter(e *actor.Engine, rpid *actor.PID, address string, index int, dial dialFunc, config Config, m *metrics, w *window, t *throttle) actor.Processer {
+	id := "stream" + "/" + address
+	if index > 0 {
+		id += "/" + strconv.Itoa(index)
 	}
+	s := &streamWriter{
+		writeToAddr:      address,
+		engine:           e,
+		routerPID:        rpid,
+		inbox:            actor.NewInbox(streamWriterBatchSize),
+		pid:              actor.NewPID(e.Address(), id),
+		serializer:       DefaultSerializer{},
+		cipher:           newPayloadCipher(config.Encryption),
+		dial:             dial,
+		buffSize:         config.BuffSize,
+		maxMessageSize:   config.MaxMessageSize,
+		chunkSize:        config.ChunkSize,
+		reconnect:        config.Reconnect.withDefaults(),
+		metrics:          m,
+		stats:            m.peer(address),
+		heartbeat:        config.HeartbeatInterval,
+		heartbeatTimeout: config.heartbeatTimeout(),
+		window:           w,
+		throttle:         t,
+		batching:         config.Batching,
+		outbox:           config.Outbox,
+		local:            config.handshake(e.Address()),
+		priorityQueued:   make(chan struct{}, 1),
+	}
+	s.writable = sync.NewCond(&s.mu)
+	if config.Batching.enabled() {
+		s.batch = newEnvelopeBuilder(streamWriterBatchSize)
+	}
+	return s
 }
 
 func (s *streamWriter) PID() *actor.PID { return s.pid }
 func (s *streamWriter) Send(_ *actor.PID, msg any, sender *actor.PID) {
+	s.stats.queued.Add(1)
+	if d, ok := msg.(*streamDeliver); ok && d.prioritized() {
+		s.priorityMu.Lock()
+		s.priority = append(s.priority, d)
+		s.priorityMu.Unlock()
+		select {
+		case s.priorityQueued <- struct{}{}:
+		default:
+		}
+		s.inbox.SendPriority(actor.Envelope{Msg: priorityReady{}})
+		return
+	}
 	s.inbox.Send(actor.Envelope{Msg: msg, Sender: sender})
 }
-func (s *streamWriter) SendPriority(_ *actor.PID, msg any, sender *actor.PID) {
-	s.inbox.SendPriority(actor.Envelope{Msg: msg, Sender: sender})
+
+// SendPriority is the same as Send, the lane of a message is decided by the
+// delivery itself.
+func (s *streamWriter) SendPriority(pid *actor.PID, msg any, sender *actor.PID) {
+	s.Send(pid, msg, sender)
 }
 
 func (s *streamWriter) Invoke(msgs []actor.Envelope) {
-	var (
-		typeLookup   = make(map[string]int32)
-		typeNames    = make([]string, 0)
-		senderLookup = make(map[uint64]int32)
-		senders      = make([]*actor.PID, 0)
-		targetLookup = make(map[uint64]int32)
-		targets      = make([]*actor.PID, 0)
-		messages     = make([]*Message, len(msgs))
-	)
-
+	deliveries := make([]*streamDeliver, 0, len(msgs))
 	for i := 0; i < len(msgs); i++ {
-		var (
-			stream   = msgs[i].Msg.(*streamDeliver)
-			typeID   int32
-			senderID int32
-			targetID int32
-		)
-		typeID, typeNames = lookupTypeName(typeLookup, s.serializer.TypeName(stream.msg), typeNames)
-		senderID, senders = lookupPIDs(senderLookup, stream.sender, senders)
-		targetID, targets = lookupPIDs(targetLookup, stream.target, targets)
-
-		b, err := s.serializer.Serialize(stream.msg)
+		if d, ok := msgs[i].Msg.(*streamDeliver); ok {
+			deliveries = append(deliveries, d)
+		}
+	}
+	s.stats.queued.Add(-int64(len(deliveries)))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waitWrite()
+
+	switch {
+	case s.closed:
+		s.deadLetter(append(s.takePriority(), deliveries...))
+	case s.stream == nil:
+		s.buffer(append(s.takePriority(), deliveries...))
+		s.reconnectLocked()
+	default:
+		if err := s.write(deliveries); err != nil {
+			slog.Error("stream writer failed sending message", "err", err, "remote", s.writeToAddr)
+			// We don't know whether the remote received the envelope, hence
+			// we buffer it and send it again once we are reconnected.
+			s.buffer(s.unsent(deliveries))
+			s.disconnectLocked(err)
+		}
+	}
+}
+
+// takePriority removes the queued messages of the priority lane.
+func (s *streamWriter) takePriority() []*streamDeliver {
+	s.priorityMu.Lock()
+	defer s.priorityMu.Unlock()
+	deliveries := s.priority
+	s.priority = nil
+	s.stats.queued.Add(-int64(len(deliveries)))
+	return deliveries
+}
+
+// writePriority writes the queued messages of the priority lane. When that
+// fails they are buffered. The caller must hold the lock.
+func (s *streamWriter) writePriority() error {
+	deliveries := s.takePriority()
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := s.writeLane(deliveries, true); err != nil {
+		s.buffer(deliveries)
+		return err
+	}
+	return nil
+}
+
+// write sends the queued messages of the priority lane followed by the given
+// messages. The caller must hold the lock.
+func (s *streamWriter) write(deliveries []*streamDeliver) error {
+	if s.batch != nil {
+		s.batched = append(s.batched, deliveries...)
+	}
+	if err := s.writePriority(); err != nil {
+		return err
+	}
+	return s.writeLane(deliveries, false)
+}
+
+// writeLane serializes the given messages into an envelope and sends it over
+// the stream. Messages larger than the chunk size are split over multiple
+// envelopes. The caller must hold the lock.
+func (s *streamWriter) writeLane(deliveries []*streamDeliver, priority bool) error {
+	batching := !priority && s.batch != nil
+	env := newEnvelopeBuilder(len(deliveries))
+	if batching {
+		env = s.batch
+	}
+	env.priority = priority
+	for _, stream := range deliveries {
+		start := time.Now()
+		b, tname, raw, err := s.serialize(stream.msg)
 		if err != nil {
 			slog.Error("serialize", "err", err)
+			s.window.release(1)
 			continue
 		}
-
-		messages[i] = &Message{
-			Data:          b,
-			TypeNameIndex: typeID,
-			SenderIndex:   senderID,
-			TargetIndex:   targetID,
-			Priority:      stream.priority,
+		var keyID string
+		if s.cipher != nil {
+			b, keyID, err = s.cipher.encrypt(b, tname)
+			if err != nil {
+				slog.Error("encrypt", "err", err, "remote", s.writeToAddr)
+				s.window.release(1)
+				continue
+			}
+		}
+		if s.maxMessageSize > 0 && len(b) > s.maxMessageSize {
+			s.rejectTooLarge(stream, len(b))
+			continue
 		}
+		s.stats.sent(len(b), time.Since(start))
+		s.metrics.requestSent(s.stats, stream.sender, s.engine.Address())
+		if s.chunkSize > 0 && len(b) > s.chunkSize {
+			// Send the messages before this one first, so every chunk gets an
+			// envelope of its own.
+			if err := s.send(env); err != nil {
+				return err
+			}
+			for len(b) > s.chunkSize {
+				env.add(stream, tname, &Message{Data: b[:s.chunkSize], Partial: true, KeyID: keyID, Raw: raw})
+				if err := s.send(env); err != nil {
+					return err
+				}
+				b = b[s.chunkSize:]
+			}
+		}
+		env.add(stream, tname, &Message{Data: b, KeyID: keyID, Raw: raw, Trace: stream.trace})
 	}
+	if batching {
+		return s.holdBatch()
+	}
+	if err := s.send(env); err != nil {
+		return err
+	}
+	s.forget(deliveries)
+	return nil
+}
 
-	env := &Envelope{
-		Senders:   senders,
-		Targets:   targets,
-		TypeNames: typeNames,
-		Messages:  messages,
+// serialize returns the serialized message and its type name. The payload of
+// a RawMessage is passed through as is, in which case raw is true.
+func (s *streamWriter) serialize(msg any) (b []byte, tname string, raw bool, err error) {
+	if m, ok := msg.(*RawMessage); ok {
+		return m.Data, m.Type, true, nil
 	}
+	b, err = s.serializer.Serialize(msg)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return b, s.serializer.TypeName(msg), false, nil
+}
 
-	if err := s.stream.Send(env); err != nil {
-		if errors.Is(err, io.EOF) {
-			_ = s.conn.Close()
-			return
+// send sends the messages collected by the builder, if any, and resets it.
+// The queued messages of the priority lane are sent before the envelopes of
+// the regular lane. Remotes that don't support lanes only get them in between
+// two messages, not in between the chunks of a message.
+func (s *streamWriter) send(b *envelopeBuilder) error {
+	if len(b.messages) == 0 {
+		return nil
+	}
+	interleave := !b.priority && (s.lanes || !b.partial)
+	if interleave {
+		if err := s.writePriority(); err != nil {
+			return err
 		}
-		slog.Error("stream writer failed sending message",
-			"err", err,
-		)
+	}
+	env := b.envelope()
+	b.partial = env.Messages[len(env.Messages)-1].Partial
+	b.reset()
+	env.Ack = s.window != nil
+	if wait := s.throttle.reserve(env.SizeVT()); wait > 0 && !b.priority {
+		if err := s.waitThrottled(wait, interleave); err != nil {
+			return err
+		}
+	}
+	if err := s.stream.Send(env); err != nil {
+		return err
+	}
+	if env.Ack {
+		s.inflight.Add(int64(enqueuedMessages(env)))
 	}
 	// refresh the connection deadline.
 	err := s.rawconn.SetDeadline(time.Now().Add(connIdleTimeout))
 	if err != nil {
 		slog.Error("failed to set context deadline", "err", err)
 	}
+	return nil
 }
 
-func (s *streamWriter) init() {
-	var (
-		rawconn    net.Conn
-		err        error
-		delay      time.Duration = time.Millisecond * 500
-		maxRetries               = 3
-	)
-	for i := 0; i < maxRetries; i++ {
-		// Here we try to connect to the remote address.
-		switch s.tlsConfig {
-		case nil:
-			rawconn, err = net.Dial("tcp", s.writeToAddr)
-			if err != nil {
-				d := time.Duration(delay * time.Duration(i*2))
-				slog.Error("net.Dial", "err", err, "remote", s.writeToAddr, "retry", i, "max", maxRetries, "delay", d)
-				time.Sleep(d)
+// waitThrottled releases the lock for the given time a write of the regular
+// lane waits for the bandwidth limit, so the heartbeats and the credits of the
+// remote keep flowing. The messages queued on the priority lane in the
+// meantime are written right away if interleave is set, as they are not
+// throttled. The caller must hold the lock.
+func (s *streamWriter) waitThrottled(wait time.Duration, interleave bool) error {
+	s.throttled = true
+	defer func() {
+		s.throttled = false
+		s.writable.Broadcast()
+	}()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		s.mu.Unlock()
+		select {
+		case <-timer.C:
+			s.mu.Lock()
+			return nil
+		case <-s.priorityQueued:
+			s.mu.Lock()
+			if !interleave {
 				continue
 			}
-		default:
-			slog.Debug("remote using TLS for writing")
-			rawconn, err = tls.Dial("tcp", s.writeToAddr, s.tlsConfig)
-			if err != nil {
-				d := time.Duration(delay * time.Duration(i*2))
-				slog.Error("tls.Dial", "err", err, "remote", s.writeToAddr, "retry", i, "max", maxRetries, "delay", d)
-				time.Sleep(d)
-				continue
+			if err := s.writePriority(); err != nil {
+				return err
 			}
 		}
-		break
 	}
-	// We could not reach the remote after retrying N times. Hence, shutdown the stream writer.
-	// and notify RemoteUnreachableEvent.
-	if rawconn == nil {
-		s.Shutdown()
+}
+
+// waitWrite waits for the write that waits for the bandwidth limit, if any, so
+// the stream isn't written to or replaced in the middle of it. The caller must
+// hold the lock.
+func (s *streamWriter) waitWrite() {
+	for s.throttled {
+		s.writable.Wait()
+	}
+}
+
+func (s *streamWriter) rejectTooLarge(d *streamDeliver, size int) {
+	evt := MessageTooLargeEvent{
+		Address: s.writeToAddr,
+		Target:  d.target,
+		Sender:  d.sender,
+		Message: d.msg,
+		Size:    size,
+		MaxSize: s.maxMessageSize,
+	}
+	if d.sender != nil {
+		s.engine.Send(d.sender, evt)
+	}
+	s.engine.BroadcastEvent(evt)
+	s.window.release(1)
+}
+
+// buffer adds the given messages to the pending messages. Messages that do
+// not fit in the buffer anymore are dropped and a MessageDroppedEvent is
+// broadcasted for each of them. The caller must hold the lock.
+func (s *streamWriter) buffer(deliveries []*streamDeliver) {
+	for _, d := range deliveries {
+		if len(s.pending) >= s.reconnect.BufferSize {
+			s.engine.BroadcastEvent(MessageDroppedEvent{
+				Address: s.writeToAddr,
+				Target:  d.target,
+				Sender:  d.sender,
+				Message: d.msg,
+			})
+			s.window.release(1)
+			s.forget([]*streamDeliver{d})
+			continue
+		}
+		s.persist(d)
+		s.pending = append(s.pending, d)
+		s.stats.queued.Add(1)
+	}
+}
+
+func (s *streamWriter) deadLetter(deliveries []*streamDeliver) {
+	s.window.release(len(deliveries))
+	for _, d := range deliveries {
+		s.engine.BroadcastEvent(actor.DeadLetterEvent{
+			Target:  d.target,
+			Message: d.msg,
+			Sender:  d.sender,
+		})
+	}
+}
+
+// reconnectLocked starts connecting to the remote in the background, unless
+// we are already doing so. The caller must hold the lock.
+func (s *streamWriter) reconnectLocked() {
+	if s.connecting || s.closed {
 		return
 	}
+	s.connecting = true
+	go s.connect()
+}
 
-	s.rawconn = rawconn
-	err = rawconn.SetDeadline(time.Now().Add(connIdleTimeout))
+// disconnectLocked closes the current connection because of the given
+// error. The watcher of the connection will take care of reconnecting. The
+// caller must hold the lock.
+func (s *streamWriter) disconnectLocked(err error) {
+	if s.conn != nil {
+		// Closing the stream ourselves is no error.
+		if s.disconnectErr == nil && !s.closed {
+			s.disconnectErr = err
+		}
+		_ = s.conn.Close()
+	}
+}
+
+// connect dials the remote until it succeeds or the maximum number of
+// attempts is reached, waiting an exponentially growing, jittered backoff
+// between the attempts. Once connected, the pending messages are flushed.
+func (s *streamWriter) connect() {
+	for attempt := 1; ; attempt++ {
+		err := s.open()
+		if err == nil {
+			return
+		}
+		evt := ConnectFailedEvent{
+			Address:     s.writeToAddr,
+			Attempt:     attempt,
+			MaxAttempts: s.reconnect.MaxAttempts,
+			Err:         err.Error(),
+		}
+		// Retrying won't help, the remote has to be upgraded or has to
+		// allow us first.
+		giveUp := errors.Is(err, errProtocolMismatch) || errors.Is(err, errPeerRejected) ||
+			(s.reconnect.MaxAttempts > 0 && attempt >= s.reconnect.MaxAttempts)
+		if !giveUp {
+			evt.Backoff = s.reconnect.backoff(attempt)
+		}
+		s.engine.BroadcastEvent(evt)
+		if giveUp {
+			break
+		}
+		time.Sleep(evt.Backoff)
+	}
+	// We could not reach the remote after retrying N times. Hence, shutdown the stream writer.
+	// and notify RemoteUnreachableEvent.
+	s.Shutdown()
+}
+
+func (s *streamWriter) open() error {
+	rawconn, err := s.dial(context.Background(), s.writeToAddr)
 	if err != nil {
-		slog.Error("failed to set deadline on raw connection", "err", err)
-		return
+		return err
+	}
+	err = rawconn.SetDeadline(time.Now().Add(s.handshakeTimeout()))
+	if err != nil {
+		rawconn.Close()
+		return err
 	}
 
 	conn := drpcconn.NewWithOptions(rawconn, drpcconn.Options{
@@ -170,49 +507,373 @@ func (s *streamWriter) init() {
 
 	stream, err := client.Receive(context.Background())
 	if err != nil {
-		slog.Error("receive", "err", err, "remote", s.writeToAddr)
-		s.Shutdown()
-		return
+		conn.Close()
+		return err
+	}
+	version, err := s.handshake(stream)
+	if err != nil {
+		conn.Close()
+		s.engine.BroadcastEvent(HandshakeFailedEvent{Address: s.writeToAddr, Err: err.Error()})
+		// The handshake is the first heartbeat, a remote that accepts the
+		// connection but doesn't answer is unreachable.
+		if s.heartbeat > 0 && !errors.Is(err, errProtocolMismatch) && !errors.Is(err, errPeerRejected) && s.unreachable.CompareAndSwap(false, true) {
+			s.engine.Send(s.routerPID, streamHealth{address: s.writeToAddr, healthy: false})
+		}
+		return err
+	}
+	if s.unreachable.CompareAndSwap(true, false) {
+		s.engine.Send(s.routerPID, streamHealth{address: s.writeToAddr, healthy: true})
+	}
+	if err := rawconn.SetDeadline(time.Now().Add(connIdleTimeout)); err != nil {
+		conn.Close()
+		return err
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waitWrite()
+	s.connecting = false
+	if s.closed {
+		conn.Close()
+		return nil
+	}
+	s.rawconn = rawconn
 	s.stream = stream
 	s.conn = conn
+	s.inflight = &atomic.Int64{}
+	s.lanes = version >= laneProtocolVersion
 
-	slog.Debug("connected",
-		"remote", s.writeToAddr,
-	)
+	s.engine.BroadcastEvent(RemoteConnectedEvent{Address: s.writeToAddr, ProtocolVersion: version})
 
-	go func() {
-		<-s.conn.Closed()
-		slog.Debug("lost connection",
-			"remote", s.writeToAddr,
-		)
-		s.Shutdown()
-	}()
+	go s.watch(conn)
+	s.lastSeen.Store(time.Now().UnixNano())
+	go s.receive(stream, s.inflight)
+	if s.heartbeat > 0 {
+		go s.keepalive(conn)
+	}
+
+	if len(s.pending) > 0 {
+		pending := s.pending
+		s.pending = nil
+		s.stats.queued.Add(-int64(len(pending)))
+		if err := s.write(pending); err != nil {
+			slog.Error("stream writer failed flushing buffered messages", "err", err, "remote", s.writeToAddr)
+			// Priority messages that failed are buffered already.
+			pending = s.unsent(pending)
+			s.pending = append(s.pending, pending...)
+			s.stats.queued.Add(int64(len(pending)))
+			s.disconnectLocked(err)
+		}
+	}
+	return nil
 }
 
-// TODO: is there a way that stream router can listen to event stream
-// instead of sending the event itself?
-func (s *streamWriter) Shutdown() {
-	evt := actor.RemoteUnreachableEvent{ListenAddr: s.writeToAddr}
-	s.engine.Send(s.routerPID, evt)
+// watch waits for the given connection to be closed and tries to reconnect.
+func (s *streamWriter) watch(conn *drpcconn.Conn) {
+	<-conn.Closed()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waitWrite()
+	if s.conn != conn {
+		return
+	}
+	evt := RemoteDisconnectedEvent{Address: s.writeToAddr}
+	if s.disconnectErr != nil {
+		evt.Err = s.disconnectErr.Error()
+	}
 	s.engine.BroadcastEvent(evt)
+	s.disconnectErr = nil
+	// The batch was meant for this connection, we send it once reconnected.
+	s.buffer(s.takeBatch())
+	s.conn = nil
+	s.stream = nil
+	s.rawconn = nil
+	// We will never hear back about the messages in flight, so we give
+	// their credits back.
+	s.window.release(int(s.inflight.Swap(0)))
+	if !s.closed {
+		s.stats.reconnects.Add(1)
+	}
+	s.reconnectLocked()
+}
+
+// receive reads the responses to the heartbeats, the flow control credits and
+// the nacks of the given stream.
+func (s *streamWriter) receive(stream DRPCRemote_ReceiveStream, inflight *atomic.Int64) {
+	for {
+		env, err := stream.Recv()
+		if err != nil {
+			// The remote ended the stream, like when it closes the
+			// connection of a peer exceeding its rate limit. A canceled
+			// stream means the remote stopped, which is no error.
+			if errors.Is(err, context.Canceled) {
+				err = nil
+			}
+			s.mu.Lock()
+			if s.stream == stream {
+				s.disconnectLocked(err)
+			}
+			s.mu.Unlock()
+			return
+		}
+		if env.Credits > 0 {
+			s.window.release(int(takeInflight(inflight, int64(env.Credits))))
+		}
+		if len(env.Nacks) > 0 {
+			s.rejected(env.Nacks)
+		}
+		s.lastSeen.Store(time.Now().UnixNano())
+		if s.unreachable.CompareAndSwap(true, false) {
+			s.engine.Send(s.routerPID, streamHealth{address: s.writeToAddr, healthy: true})
+		}
+	}
+}
+
+// rejected publishes the messages the remote could not deliver with their
+// original payload as a DeadLetterEvent and a MessageRejectedEvent, which is
+// sent to the senders that are requests too. The other senders don't expect
+// it in their inbox.
+func (s *streamWriter) rejected(nacks []*Nack) {
+	for _, n := range nacks {
+		msg := s.decode(n)
+		s.engine.BroadcastEvent(actor.DeadLetterEvent{
+			Target:  n.Target,
+			Message: msg,
+			Sender:  n.Sender,
+		})
+		evt := MessageRejectedEvent{
+			Address: s.writeToAddr,
+			Target:  n.Target,
+			Sender:  n.Sender,
+			Message: msg,
+			Reason:  n.Reason,
+			Err:     n.Error,
+		}
+		s.engine.BroadcastEvent(evt)
+		if actor.IsResponsePID(n.Sender) && n.Sender.Address == s.engine.Address() {
+			s.engine.Send(n.Sender, evt)
+		}
+	}
+}
+
+// decode returns the original message of the nack, nil if it cannot be
+// decoded.
+func (s *streamWriter) decode(n *Nack) any {
+	data := n.Data
+	if len(data) == 0 {
+		return nil
+	}
+	if n.KeyID != "" {
+		if s.cipher == nil {
+			return nil
+		}
+		var err error
+		if data, err = s.cipher.decrypt(data, n.TypeName, n.KeyID); err != nil {
+			return nil
+		}
+	}
+	if n.Raw {
+		return &RawMessage{Type: n.TypeName, Data: data}
+	}
+	msg, err := DefaultSerializer{}.Deserialize(data, n.TypeName)
+	if err != nil {
+		return nil
+	}
+	return msg
+}
+
+var errMissedHeartbeats = errors.New("remote missed heartbeats")
+
+// keepalive pings the remote over the given connection until it is closed.
+// When the remote doesn't respond in time the connection is closed, which
+// makes the watcher reconnect.
+func (s *streamWriter) keepalive(conn *drpcconn.Conn) {
+	ticker := time.NewTicker(s.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-conn.Closed():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		if s.conn != conn {
+			s.mu.Unlock()
+			return
+		}
+		if time.Since(time.Unix(0, s.lastSeen.Load())) > s.heartbeatTimeout {
+			slog.Warn("remote missed heartbeats", "remote", s.writeToAddr, "timeout", s.heartbeatTimeout)
+			if s.unreachable.CompareAndSwap(false, true) {
+				s.engine.Send(s.routerPID, streamHealth{address: s.writeToAddr, healthy: false})
+			}
+			s.disconnectLocked(errMissedHeartbeats)
+			s.mu.Unlock()
+			return
+		}
+		if err := s.stream.Send(&Envelope{}); err != nil {
+			slog.Error("stream writer failed sending heartbeat", "err", err, "remote", s.writeToAddr)
+			s.disconnectLocked(err)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *streamWriter) Shutdown() {
+	s.mu.Lock()
+	s.waitWrite()
+	s.closed = true
+	if s.stream != nil && s.batch != nil {
+		if err := s.flushBatch(); err != nil {
+			slog.Error("stream writer failed sending batch", "err", err, "remote", s.writeToAddr)
+		}
+	}
+	pending := append(s.pending, s.takePriority()...)
+	pending = append(pending, s.takeBatch()...)
+	s.pending = nil
+	s.stats.queued.Add(-int64(len(pending)))
 	if s.stream != nil {
 		s.stream.Close()
+		s.window.release(int(s.inflight.Swap(0)))
 	}
-	s.inbox.Stop()
+	s.mu.Unlock()
+
 	s.engine.Registry.Remove(s.PID())
+	s.engine.Send(s.routerPID, streamClosed{address: s.writeToAddr, pid: s.pid})
+	s.deadLetter(pending)
+	s.forget(pending)
+	s.inbox.Stop()
 }
 
 func (s *streamWriter) Start() {
 	s.inbox.Start(s)
-	s.init()
+	s.mu.Lock()
+	s.reconnectLocked()
+	s.mu.Unlock()
 }
 
 func (s *streamWriter) ClearMailbox() {
 	s.inbox.Clear()
 }
 
+// ReconnectConfig configures how a remote reconnects to a peer it lost the
+// connection with, and how many messages are buffered in the meantime.
+type ReconnectConfig struct {
+	// MaxAttempts is the number of times we try to connect before the
+	// peer is considered unreachable. A negative value retries forever.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. The delay
+	// doubles after each failed attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+	// BufferSize is the maximum number of outbound messages that are
+	// buffered while the peer is not connected.
+	BufferSize int
+}
+
+const (
+	defaultReconnectAttempts = 3
+	defaultInitialBackoff    = 500 * time.Millisecond
+	defaultMaxBackoff        = 10 * time.Second
+	defaultReconnectBuffer   = 1024 * 16
+)
+
+// NewReconnectConfig returns a ReconnectConfig initialized with the default values.
+func NewReconnectConfig() ReconnectConfig {
+	return ReconnectConfig{
+		MaxAttempts:    defaultReconnectAttempts,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		BufferSize:     defaultReconnectBuffer,
+	}
+}
+
+func (c ReconnectConfig) withDefaults() ReconnectConfig {
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = defaultReconnectAttempts
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = defaultInitialBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = defaultReconnectBuffer
+	}
+	return c
+}
+
+// backoff returns the delay before the given attempt with "equal jitter",
+// which is somewhere between half and the full exponential delay. The jitter
+// prevents all peers of a restarted node from reconnecting at the same time.
+func (c ReconnectConfig) backoff(attempt int) time.Duration {
+	d := c.InitialBackoff << (attempt - 1)
+	if d > c.MaxBackoff || d <= 0 {
+		d = c.MaxBackoff
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// envelopeBuilder collects messages into an envelope. The senders, targets
+// and type names are deduplicated and referenced by index.
+type envelopeBuilder struct {
+	typeLookup   map[string]int32
+	typeNames    []string
+	senderLookup map[uint64]int32
+	senders      []*actor.PID
+	targetLookup map[uint64]int32
+	targets      []*actor.PID
+	messages     []*Message
+	// priority is set for the envelopes of the priority lane.
+	priority bool
+	// partial is set when the last envelope sent ended with a chunk, that
+	// is we are in the middle of sending a chunked message.
+	partial bool
+	// size is the number of payload bytes of the collected messages.
+	size int
+}
+
+func newEnvelopeBuilder(size int) *envelopeBuilder {
+	b := &envelopeBuilder{messages: make([]*Message, 0, size)}
+	b.reset()
+	return b
+}
+
+// add adds the given message of the delivery to the envelope, setting the
+// indices of its type name, sender and target.
+func (b *envelopeBuilder) add(d *streamDeliver, tname string, msg *Message) {
+	msg.TypeNameIndex, b.typeNames = lookupTypeName(b.typeLookup, tname, b.typeNames)
+	msg.SenderIndex, b.senders = lookupPIDs(b.senderLookup, d.sender, b.senders)
+	msg.TargetIndex, b.targets = lookupPIDs(b.targetLookup, d.target, b.targets)
+	msg.Priority = d.priority
+	b.messages = append(b.messages, msg)
+	b.size += len(msg.Data)
+}
+
+func (b *envelopeBuilder) envelope() *Envelope {
+	return &Envelope{
+		Senders:   b.senders,
+		Targets:   b.targets,
+		TypeNames: b.typeNames,
+		Messages:  b.messages,
+		Priority:  b.priority,
+	}
+}
+
+func (b *envelopeBuilder) reset() {
+	b.typeLookup = make(map[string]int32)
+	b.typeNames = make([]string, 0)
+	b.senderLookup = make(map[uint64]int32)
+	b.senders = make([]*actor.PID, 0)
+	b.targetLookup = make(map[uint64]int32)
+	b.targets = make([]*actor.PID, 0)
+	b.messages = make([]*Message, 0, cap(b.messages))
+	b.size = 0
+}
+
 func lookupPIDs(m map[uint64]int32, pid *actor.PID, pids []*actor.PID) (int32, []*actor.PID) {
 	if pid == nil {
 		return 0, pids
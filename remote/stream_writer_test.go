@@ -0,0 +1,264 @@
+package remote
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// When the remote goes away we keep the messages in a buffer, reconnect and
+// flush the buffer once the remote is back up again.
+func TestStreamWriterReconnectFlushesBuffer(t *testing.T) {
+	config := NewConfig().WithReconnect(ReconnectConfig{
+		MaxAttempts:    -1,
+		InitialBackoff: 20 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+	})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	bAddr := getRandomLocalhostAddr()
+	received := make(chan string, 10)
+	receiver := func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- string(msg.Data)
+		}
+	}
+	b, rb, err := makeRemoteEngine(bAddr)
+	require.NoError(t, err)
+	pid := b.SpawnFunc(receiver, "test", actor.WithID("1"))
+
+	a.Send(pid, &TestMessage{Data: []byte("before")})
+	assert.Equal(t, "before", <-received)
+
+	rb.Stop().Wait()
+	time.Sleep(50 * time.Millisecond)
+	a.Send(pid, &TestMessage{Data: []byte("during")})
+	time.Sleep(50 * time.Millisecond)
+
+	b, rb, err = makeRemoteEngine(bAddr)
+	require.NoError(t, err)
+	defer rb.Stop()
+	b.SpawnFunc(receiver, "test", actor.WithID("1"))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "during", msg)
+	case <-time.After(2 * time.Second):
+		t.Fatal("buffered message was not delivered after reconnecting")
+	}
+	m, ok := ra.PeerMetrics(bAddr)
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), m.Reconnects)
+}
+
+func TestStreamWriterBufferOverflow(t *testing.T) {
+	config := NewConfig().WithReconnect(ReconnectConfig{
+		MaxAttempts:    -1,
+		InitialBackoff: time.Minute,
+		BufferSize:     2,
+	})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	wg := sync.WaitGroup{}
+	wg.Add(3)
+	a.SpawnFunc(func(c *actor.Context) {
+		switch c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case MessageDroppedEvent:
+			wg.Done()
+		}
+	}, "listener")
+	time.Sleep(10 * time.Millisecond)
+
+	target := actor.NewPID("127.0.0.1:4001", "foo/bar")
+	for i := 0; i < 5; i++ {
+		a.Send(target, &TestMessage{Data: []byte("foo")})
+	}
+	wg.Wait()
+}
+
+// The transport health of a peer is published on the eventstream: the
+// connection, the loss of it and every failed attempt to reconnect.
+func TestStreamWriterLifecycleEvents(t *testing.T) {
+	config := NewConfig().WithReconnect(ReconnectConfig{
+		MaxAttempts:    2,
+		InitialBackoff: 10 * time.Millisecond,
+	})
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+
+	events := make(chan any, 10)
+	a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case RemoteConnectedEvent, RemoteDisconnectedEvent, ConnectFailedEvent:
+			events <- msg
+		}
+	}, "listener")
+	time.Sleep(10 * time.Millisecond)
+	next := func() any {
+		select {
+		case evt := <-events:
+			return evt
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected a lifecycle event")
+			return nil
+		}
+	}
+
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			c.Respond(msg)
+		}
+	}, "echo")
+	_, err = a.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	require.NoError(t, err)
+	assert.Equal(t, RemoteConnectedEvent{Address: rb.Address(), ProtocolVersion: ProtocolVersion}, next())
+
+	rb.Stop().Wait()
+	disconnected, ok := next().(RemoteDisconnectedEvent)
+	require.True(t, ok)
+	assert.Equal(t, rb.Address(), disconnected.Address)
+
+	failed, ok := next().(ConnectFailedEvent)
+	require.True(t, ok)
+	assert.Equal(t, 1, failed.Attempt)
+	assert.Equal(t, 2, failed.MaxAttempts)
+	assert.Positive(t, failed.Backoff)
+	assert.NotEmpty(t, failed.Err)
+	failed, ok = next().(ConnectFailedEvent)
+	require.True(t, ok)
+	assert.Equal(t, 2, failed.Attempt)
+	assert.Zero(t, failed.Backoff)
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	config := ReconnectConfig{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+	for i := 0; i < 10; i++ {
+		d := config.backoff(1)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 100*time.Millisecond)
+
+		d = config.backoff(3)
+		assert.GreaterOrEqual(t, d, 200*time.Millisecond)
+		assert.LessOrEqual(t, d, 400*time.Millisecond)
+
+		d = config.backoff(100)
+		assert.GreaterOrEqual(t, d, 500*time.Millisecond)
+		assert.LessOrEqual(t, d, time.Second)
+	}
+}
+
+// fastReconnectConfig gives up on unreachable remotes quickly, to keep the
+// tests fast.
+func fastReconnectConfig() Config {
+	return NewConfig().WithReconnect(ReconnectConfig{InitialBackoff: 10 * time.Millisecond})
+}
+
+func makeRemoteEngineWithConfig(listenAddr string, config Config) (*actor.Engine, *Remote, error) {
+	r := New(listenAddr, config)
+	e, err := actor.NewEngine(actor.NewEngineConfig().WithRemote(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, r, nil
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithMaxMessageSize(1024))
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			c.Respond(msg)
+		}
+	}, "echo")
+
+	resp, err := a.Request(pid, &TestMessage{Data: make([]byte, 512)}, time.Second).Result()
+	require.NoError(t, err)
+	assert.Len(t, resp.(*TestMessage).Data, 512)
+
+	_, err = a.Request(pid, &TestMessage{Data: make([]byte, 2048)}, time.Second).Result()
+	var tooLarge MessageTooLargeEvent
+	require.ErrorAs(t, err, &tooLarge)
+	assert.Greater(t, tooLarge.Size, 2048)
+	assert.Equal(t, 1024, tooLarge.MaxSize)
+}
+
+func TestChunking(t *testing.T) {
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithChunking(1000))
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	received := make(chan []byte, 3)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- msg.Data
+		}
+	}, "receiver")
+
+	large := make([]byte, 100_000)
+	for i := range large {
+		large[i] = byte(i)
+	}
+	a.Send(pid, &TestMessage{Data: []byte("first")})
+	a.Send(pid, &TestMessage{Data: large})
+	a.Send(pid, &TestMessage{Data: []byte("last")})
+
+	for _, expected := range [][]byte{[]byte("first"), large, []byte("last")} {
+		select {
+		case data := <-received:
+			assert.Equal(t, expected, data)
+		case <-time.After(time.Second):
+			t.Fatal("message was not received")
+		}
+	}
+}
+
+func TestChunkingExceedsReceiverMaxMessageSize(t *testing.T) {
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithChunking(100))
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), NewConfig().WithMaxMessageSize(1000))
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	received := make(chan []byte, 2)
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- msg.Data
+		}
+	}, "receiver")
+
+	a.Send(pid, &TestMessage{Data: make([]byte, 5000)})
+	a.Send(pid, &TestMessage{Data: []byte("small")})
+	select {
+	case data := <-received:
+		assert.Equal(t, []byte("small"), data)
+	case <-time.After(time.Second):
+		t.Fatal("message was not received")
+	}
+}
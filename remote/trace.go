@@ -0,0 +1,30 @@
+package remote
+
+import "github.com/fertigai/hollywood/actor"
+
+// untrace returns the message of an actor.TracedMessage and its trace
+// context, as the pairs of keys and values of Message.Trace.
+func untrace(msg any) (any, []string) {
+	m, ok := msg.(actor.TracedMessage)
+	if !ok {
+		return msg, nil
+	}
+	pairs := make([]string, 0, 2*len(m.Trace))
+	for k, v := range m.Trace {
+		pairs = append(pairs, k, v)
+	}
+	return m.Message, pairs
+}
+
+// traced returns the given message as an actor.TracedMessage when the pairs of
+// keys and values of its trace context are set.
+func traced(msg any, pairs []string) any {
+	if len(pairs) < 2 {
+		return msg
+	}
+	trace := make(actor.TraceContext, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		trace[pairs[i]] = pairs[i+1]
+	}
+	return actor.TracedMessage{Message: msg, Trace: trace}
+}
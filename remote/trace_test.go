@@ -0,0 +1,64 @@
+package remote
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parentTracer reports the trace contexts the messages are sent with, and
+// propagates a trace context of its own.
+type parentTracer chan actor.TraceContext
+
+func (t parentTracer) Start(ctx context.Context, span actor.Span) (context.Context, func()) {
+	if _, ok := span.Message.(*TestMessage); ok {
+		t <- span.Parent
+	}
+	return ctx, func() {}
+}
+
+func (t parentTracer) Inject(context.Context) actor.TraceContext {
+	return actor.TraceContext{"traceparent": "00-b"}
+}
+
+func TestTraceContextOverRemote(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	tracer := make(parentTracer, 1)
+	rb := New(getRandomLocalhostAddr(), NewConfig())
+	b, err := actor.NewEngine(actor.NewEngineConfig().WithRemote(rb).WithTracer(tracer))
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			c.Respond(msg)
+		}
+	}, "echo")
+	trace := actor.TraceContext{"traceparent": "00-a", "tracestate": "x=1"}
+	res, err := a.Request(pid, actor.TracedMessage{Message: &TestMessage{Data: []byte("foo")}, Trace: trace}, time.Second).Result()
+	require.NoError(t, err)
+	// The response comes back with the trace context of b.
+	assert.Equal(t, []byte("foo"), res.(*TestMessage).Data)
+	select {
+	case parent := <-tracer:
+		assert.Equal(t, trace, parent)
+	case <-time.After(time.Second):
+		t.Fatal("message not traced")
+	}
+}
+
+func TestTraceMessageEncoding(t *testing.T) {
+	m := &Message{Data: []byte("foo"), Trace: []string{"traceparent", "00-a"}}
+	b, err := m.MarshalVT()
+	require.NoError(t, err)
+	var decoded Message
+	require.NoError(t, decoded.UnmarshalVT(b))
+	assert.Equal(t, m.Trace, decoded.Trace)
+	assert.True(t, m.EqualVT(m.CloneVT()))
+}
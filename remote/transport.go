@@ -0,0 +1,101 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+const schemeSeparator = "://"
+
+// Transport creates the connections the remote runs its streams on. Besides
+// the built-in TCP, QUIC, WebSocket and Unix socket transports, custom ones
+// are registered for an address scheme with Config.WithTransport. The remote
+// picks the transport by the scheme of its listen addresses and of the
+// addresses of the peers it sends messages to. The address given to Listen
+// and Dial is the address without its scheme, "node-1" for "shm://node-1".
+//
+// A connection carries a single stream: a reliable, ordered, bidirectional
+// byte stream, like a TCP connection. The remote frames the messages itself,
+// so the transport does not need to preserve message boundaries. A new
+// connection is dialed for every stream to a peer (see WithStreams) and for
+// every reconnect, hence transports that multiplex many streams over a single
+// connection, like QUIC, do so in Dial and Accept.
+//
+// The remote limits the handshake and idle connections with SetDeadline.
+// Closing a connection must unblock its pending reads and writes, and closing
+// a listener must unblock Accept, which is how the remote stops. The
+// RemoteAddr of the accepted connections is checked against the IP ranges of
+// the ACL; connections secured by TLS can implement
+// ConnectionState() tls.ConnectionState so the SANs of the peer certificate
+// are checked as well.
+type Transport interface {
+	// Listen returns a listener accepting the connections of the peers on
+	// the given address.
+	Listen(addr string) (net.Listener, error)
+	// Dial opens a new connection to the peer listening on the given
+	// address.
+	Dial(ctx context.Context, addr string) (net.Conn, error)
+}
+
+// splitScheme splits the given address in its scheme and the host part.
+// Addresses without a scheme, like "127.0.0.1:4000", have an empty scheme
+// and are served by the default TCP transport.
+func splitScheme(addr string) (string, string) {
+	scheme, host, ok := strings.Cut(addr, schemeSeparator)
+	if !ok {
+		return "", addr
+	}
+	return scheme, host
+}
+
+// transportFor returns the transport that is capable of handling the
+// given address together with the address stripped of its scheme.
+func (r *Remote) transportFor(addr string) (Transport, string, error) {
+	scheme, host := splitScheme(addr)
+	t, ok := r.transports[scheme]
+	if !ok {
+		return nil, "", fmt.Errorf("no transport registered for scheme (%s) of address %s", scheme, addr)
+	}
+	return t, host, nil
+}
+
+// dial creates a new raw connection to the given remote address.
+func (r *Remote) dial(ctx context.Context, addr string) (net.Conn, error) {
+	t, host, err := r.transportFor(addr)
+	if err != nil {
+		return nil, err
+	}
+	return t.Dial(ctx, host)
+}
+
+type tcpTransport struct {
+	tlsConfig *tls.Config
+	resolver  Resolver
+}
+
+func (t tcpTransport) Listen(addr string) (net.Listener, error) {
+	if t.tlsConfig == nil {
+		return net.Listen("tcp", addr)
+	}
+	slog.Debug("remote using TLS for listening")
+	return tls.Listen("tcp", addr, t.tlsConfig)
+}
+
+func (t tcpTransport) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	if t.tlsConfig == nil {
+		var d net.Dialer
+		return dialResolved(ctx, t.resolver, addr, func(ctx context.Context, addr string) (net.Conn, error) {
+			return d.DialContext(ctx, "tcp", addr)
+		})
+	}
+	slog.Debug("remote using TLS for writing")
+	host, _, _ := net.SplitHostPort(addr)
+	d := tls.Dialer{Config: withServerName(t.tlsConfig, host)}
+	return dialResolved(ctx, t.resolver, addr, func(ctx context.Context, addr string) (net.Conn, error) {
+		return d.DialContext(ctx, "tcp", addr)
+	})
+}
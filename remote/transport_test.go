@@ -0,0 +1,113 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memTransport connects the remotes of a process over in-memory pipes.
+type memTransport struct {
+	mu        sync.Mutex
+	listeners map[string]*memListener
+}
+
+func newMemTransport() *memTransport {
+	return &memTransport{listeners: make(map[string]*memListener)}
+}
+
+func (t *memTransport) Listen(addr string) (net.Listener, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.listeners[addr]; ok {
+		return nil, errors.New("address in use")
+	}
+	ln := &memListener{addr: addr, conns: make(chan net.Conn), closech: make(chan struct{}), transport: t}
+	t.listeners[addr] = ln
+	return ln, nil
+}
+
+func (t *memTransport) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	t.mu.Lock()
+	ln, ok := t.listeners[addr]
+	t.mu.Unlock()
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	select {
+	case ln.conns <- server:
+		return client, nil
+	case <-ln.closech:
+		return nil, errors.New("connection refused")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type memListener struct {
+	addr      string
+	conns     chan net.Conn
+	closech   chan struct{}
+	once      sync.Once
+	transport *memTransport
+}
+
+func (l *memListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closech:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *memListener) Close() error {
+	l.once.Do(func() {
+		close(l.closech)
+		l.transport.mu.Lock()
+		delete(l.transport.listeners, l.addr)
+		l.transport.mu.Unlock()
+	})
+	return nil
+}
+
+func (l *memListener) Addr() net.Addr { return memAddr(l.addr) }
+
+type memAddr string
+
+func (a memAddr) Network() string { return "mem" }
+func (a memAddr) String() string  { return string(a) }
+
+func TestCustomTransport(t *testing.T) {
+	config := NewConfig().WithTransport("mem", newMemTransport())
+	a, ra, err := makeRemoteEngineWithConfig("mem://a", config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngineWithConfig("mem://b", config)
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			c.Respond(msg)
+		}
+	}, "echo")
+	assert.Equal(t, "mem://b", pid.Address)
+
+	resp, err := a.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo"), resp.(*TestMessage).Data)
+}
+
+func TestUnknownTransport(t *testing.T) {
+	_, _, err := makeRemoteEngine("shm://a")
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec encodes and decodes the values of a Go type for the wire.
+type Codec[T any] interface {
+	Encode(T) ([]byte, error)
+	Decode([]byte) (T, error)
+}
+
+// JSONCodec is a Codec that encodes values as JSON.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Decode(b []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
+
+// registeredType holds the wire name and the codec of a registered type.
+type registeredType struct {
+	typ    reflect.Type
+	name   string
+	encode func(any) ([]byte, error)
+	decode func([]byte) (any, error)
+}
+
+var types = struct {
+	sync.RWMutex
+	byName map[string]registeredType
+	byType map[reflect.Type]registeredType
+}{
+	byName: make(map[string]registeredType),
+	byType: make(map[reflect.Type]registeredType),
+}
+
+// RegisterCodec registers the Go type T under the given wire name, so values
+// of T can be sent to remote actors without a protobuf definition. The
+// receiving node needs to register the same type under the same name. The
+// receiver gets a value of type T, so register a pointer type to send and
+// receive pointers.
+//
+//	remote.RegisterCodec[*Greeting]("app.Greeting", remote.JSONCodec[*Greeting]{})
+//
+// It panics if the name is already registered for another type.
+func RegisterCodec[T any](name string, codec Codec[T]) {
+	t := reflect.TypeFor[T]()
+	types.Lock()
+	defer types.Unlock()
+	if other, ok := types.byName[name]; ok && other.typ != t {
+		panic(fmt.Sprintf("remote: type name %s is already registered for %s", name, other.typ))
+	}
+	rt := registeredType{
+		typ:    t,
+		name:   name,
+		encode: func(v any) ([]byte, error) { return codec.Encode(v.(T)) },
+		decode: func(b []byte) (any, error) { return codec.Decode(b) },
+	}
+	types.byName[name] = rt
+	types.byType[t] = rt
+}
+
+func lookupTypeByName(name string) (registeredType, bool) {
+	types.RLock()
+	defer types.RUnlock()
+	rt, ok := types.byName[name]
+	return rt, ok
+}
+
+func lookupType(v any) (registeredType, bool) {
+	types.RLock()
+	defer types.RUnlock()
+	rt, ok := types.byType[reflect.TypeOf(v)]
+	return rt, ok
+}
@@ -0,0 +1,70 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type greeting struct {
+	Name string
+	Tags []string
+}
+
+type unregistered struct{}
+
+func init() {
+	RegisterCodec[*greeting]("remote.test.greeting", JSONCodec[*greeting]{})
+}
+
+func TestDefaultSerializerRegisteredType(t *testing.T) {
+	msg := &greeting{Name: "foo", Tags: []string{"bar"}}
+	b, err := DefaultSerializer{}.Serialize(msg)
+	require.NoError(t, err)
+	tname := DefaultSerializer{}.TypeName(msg)
+	assert.Equal(t, "remote.test.greeting", tname)
+
+	decoded, err := DefaultSerializer{}.Deserialize(b, tname)
+	require.NoError(t, err)
+	assert.Equal(t, msg, decoded)
+
+	// Protobuf messages are still supported.
+	b, err = DefaultSerializer{}.Serialize(&TestMessage{Data: []byte("foo")})
+	require.NoError(t, err)
+	decoded, err = DefaultSerializer{}.Deserialize(b, "remote.TestMessage")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo"), decoded.(*TestMessage).Data)
+
+	_, err = DefaultSerializer{}.Serialize(unregistered{})
+	assert.Error(t, err)
+}
+
+func TestRegisterCodecNameConflict(t *testing.T) {
+	// Registering the same type again is fine.
+	RegisterCodec[*greeting]("remote.test.greeting", JSONCodec[*greeting]{})
+	assert.Panics(t, func() {
+		RegisterCodec[greeting]("remote.test.greeting", JSONCodec[greeting]{})
+	})
+}
+
+func TestSendRegisteredType(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	pid := b.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*greeting); ok {
+			c.Respond(&greeting{Name: "hello " + msg.Name})
+		}
+	}, "greeter")
+
+	resp, err := a.Request(pid, &greeting{Name: "foo"}, time.Second).Result()
+	require.NoError(t, err)
+	assert.Equal(t, &greeting{Name: "hello foo"}, resp)
+}
@@ -0,0 +1,78 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+// unixScheme is the address scheme that selects the Unix domain socket transport.
+// Example: remote.New("unix:///tmp/hollywood.sock", remote.NewConfig())
+const unixScheme = "unix"
+
+// defaultUnixSocketMode only allows the user running the engine to connect.
+const defaultUnixSocketMode os.FileMode = 0o600
+
+// unixTransport runs the remote streams over Unix domain sockets. This is
+// meant for actor communication between processes on the same host, where
+// access is controlled with file system permissions instead of the network.
+type unixTransport struct {
+	tlsConfig *tls.Config
+	mode      os.FileMode
+}
+
+func (t unixTransport) Listen(path string) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	mode := t.mode
+	if mode == 0 {
+		mode = defaultUnixSocketMode
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set permissions of unix socket: %w", err)
+	}
+	if t.tlsConfig != nil {
+		ln = tls.NewListener(ln, t.tlsConfig)
+	}
+	return ln, nil
+}
+
+func (t unixTransport) Dial(ctx context.Context, path string) (net.Conn, error) {
+	if t.tlsConfig == nil {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+	d := tls.Dialer{Config: t.tlsConfig}
+	return d.DialContext(ctx, "unix", path)
+}
+
+// removeStaleSocket removes the socket file at the given path if it is left
+// over by a process that is not running anymore. A socket that still accepts
+// connections is left untouched so listening on it will fail.
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s already exists and is not a unix socket", path)
+	}
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("unix socket %s is already in use", path)
+	}
+	return os.Remove(path)
+}
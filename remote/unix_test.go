@@ -0,0 +1,62 @@
+package remote
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestResponse_Unix(t *testing.T) {
+	dir := t.TempDir()
+	aPath := filepath.Join(dir, "a.sock")
+	a, ra, err := makeRemoteEngine("unix://" + aPath)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine("unix://" + filepath.Join(dir, "b.sock"))
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	info, err := os.Stat(aPath)
+	require.NoError(t, err)
+	assert.Equal(t, defaultUnixSocketMode, info.Mode().Perm())
+
+	pid := a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case *TestMessage:
+			c.Respond(&TestMessage{Data: msg.Data})
+		}
+	}, "test")
+
+	resp, err := b.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+	require.NoError(t, err)
+	assert.Equal(t, resp.(*TestMessage).Data, []byte("foo"))
+}
+
+func TestUnixSocketInUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.sock")
+	_, ra, err := makeRemoteEngine("unix://" + path)
+	require.NoError(t, err)
+	defer ra.Stop()
+
+	_, _, err = makeRemoteEngine("unix://" + path)
+	assert.Error(t, err)
+}
+
+func TestUnixRemoveStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stale.sock")
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	// Leave the socket file behind, like a crashed process would.
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	_, ra, err := makeRemoteEngine("unix://" + path)
+	require.NoError(t, err)
+	ra.Stop()
+}
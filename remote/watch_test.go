@@ -0,0 +1,83 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spawnWatcher(e *actor.Engine, target *actor.PID) <-chan *actor.Terminated {
+	terminated := make(chan *actor.Terminated, 1)
+	e.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case actor.Started:
+			c.Watch(target)
+		case *actor.Terminated:
+			terminated <- msg
+		}
+	}, "watcher")
+	return terminated
+}
+
+func TestWatchRemoteStopped(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	target := b.SpawnFunc(func(c *actor.Context) {}, "target")
+	terminated := spawnWatcher(a, target)
+	time.Sleep(50 * time.Millisecond)
+
+	<-b.Poison(target).Done()
+	select {
+	case msg := <-terminated:
+		assert.True(t, msg.Pid.Equals(target))
+		assert.Equal(t, actor.TerminatedReason_Stopped, msg.Reason)
+	case <-time.After(time.Second):
+		t.Fatal("expected a Terminated message")
+	}
+}
+
+func TestWatchRemoteNotFound(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer ra.Stop()
+	_, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	terminated := spawnWatcher(a, actor.NewPID(rb.Address(), "foo/bar"))
+	select {
+	case msg := <-terminated:
+		assert.Equal(t, actor.TerminatedReason_NotFound, msg.Reason)
+	case <-time.After(time.Second):
+		t.Fatal("expected a Terminated message")
+	}
+}
+
+func TestWatchRemoteUnreachable(t *testing.T) {
+	a, ra, err := makeRemoteEngineWithConfig(getRandomLocalhostAddr(), fastReconnectConfig())
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+
+	target := b.SpawnFunc(func(c *actor.Context) {}, "target")
+	terminated := spawnWatcher(a, target)
+	time.Sleep(50 * time.Millisecond)
+
+	rb.Stop().Wait()
+	select {
+	case msg := <-terminated:
+		assert.True(t, msg.Pid.Equals(target))
+		assert.Equal(t, actor.TerminatedReason_Unreachable, msg.Reason)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a Terminated message")
+	}
+}
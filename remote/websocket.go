@@ -0,0 +1,161 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// wsScheme and wssScheme are the address schemes that select the
+	// WebSocket transport, plain and over TLS respectively.
+	// Example: remote.New("ws://0.0.0.0:4000/hollywood", remote.NewConfig())
+	wsScheme  = "ws"
+	wssScheme = "wss"
+)
+
+// wsTransport runs the remote streams over WebSocket connections. The
+// envelopes are sent as binary frames, which allows clients that can only
+// reach us over HTTP(S), like browsers or agents behind HTTP-only egress,
+// to participate as actor nodes.
+type wsTransport struct {
+	secure    bool
+	tlsConfig *tls.Config
+}
+
+func (t wsTransport) Listen(addr string) (net.Listener, error) {
+	host, path := splitPath(addr)
+	if t.secure && t.tlsConfig == nil {
+		return nil, errors.New("the wss transport requires a TLS config")
+	}
+	ln, err := net.Listen("tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if t.secure {
+		ln = tls.NewListener(ln, t.tlsConfig)
+	}
+	wl := &wsListener{
+		ln:      ln,
+		conns:   make(chan net.Conn),
+		closech: make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, websocket.Server{Handler: wl.handle})
+	wl.server = &http.Server{Handler: mux}
+	go func() {
+		_ = wl.server.Serve(ln)
+	}()
+	return wl, nil
+}
+
+func (t wsTransport) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	host, path := splitPath(addr)
+	scheme, origin := wsScheme, "http://"+host
+	if t.secure {
+		scheme, origin = wssScheme, "https://"+host
+	}
+	config, err := websocket.NewConfig(scheme+schemeSeparator+host+path, origin)
+	if err != nil {
+		return nil, err
+	}
+	config.TlsConfig = t.tlsConfig
+	ws, err := config.DialContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	return ws, nil
+}
+
+// splitPath splits "host:port/path" in the host and the path of the
+// WebSocket endpoint. The path defaults to "/".
+func splitPath(addr string) (string, string) {
+	i := strings.Index(addr, "/")
+	if i < 0 {
+		return addr, "/"
+	}
+	return addr[:i], addr[i:]
+}
+
+// wsListener hands out the WebSocket connections that are upgraded by the
+// underlying http.Server as a net.Listener.
+type wsListener struct {
+	ln        net.Listener
+	server    *http.Server
+	conns     chan net.Conn
+	closech   chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *wsListener) handle(ws *websocket.Conn) {
+	ws.PayloadType = websocket.BinaryFrame
+	conn := &wsServerConn{Conn: ws, done: make(chan struct{})}
+	select {
+	case l.conns <- conn:
+	case <-l.closech:
+		return
+	}
+	// The websocket package closes the connection as soon as the handler
+	// returns, hence we need to block until the stream is done with it.
+	select {
+	case <-conn.done:
+	case <-l.closech:
+	}
+}
+
+func (l *wsListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closech:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *wsListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.closech)
+		err = l.server.Close()
+	})
+	return err
+}
+
+func (l *wsListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+type wsServerConn struct {
+	*websocket.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *wsServerConn) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return c.Conn.Close()
+}
+
+// RemoteAddr returns the address of the client, the websocket package returns
+// the origin of the request instead.
+func (c *wsServerConn) RemoteAddr() net.Addr {
+	addr, err := net.ResolveTCPAddr("tcp", c.Request().RemoteAddr)
+	if err != nil {
+		return c.Conn.RemoteAddr()
+	}
+	return addr
+}
+
+func (c *wsServerConn) ConnectionState() tls.ConnectionState {
+	if state := c.Request().TLS; state != nil {
+		return *state
+	}
+	return tls.ConnectionState{}
+}
@@ -0,0 +1,72 @@
+package remote
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fertigai/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestResponse_WebSocket(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomWebSocketAddr("ws"))
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngine(getRandomWebSocketAddr("ws"))
+	require.NoError(t, err)
+	defer rb.Stop()
+
+	pid := a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case *TestMessage:
+			c.Respond(&TestMessage{Data: msg.Data})
+		}
+	}, "test")
+	assert.Equal(t, ra.Address(), pid.Address)
+
+	for i := 0; i < 3; i++ {
+		resp, err := b.Request(pid, &TestMessage{Data: []byte("foo")}, time.Second).Result()
+		require.NoError(t, err)
+		assert.Equal(t, resp.(*TestMessage).Data, []byte("foo"))
+	}
+}
+
+func TestSend_SecureWebSocket(t *testing.T) {
+	const msgs = 10
+	tlsTestConfig, err := generateTLSConfig()
+	require.NoError(t, err)
+	a, ra, err := makeRemoteEngineTls(getRandomWebSocketAddr("wss"), tlsTestConfig.peer1Config)
+	require.NoError(t, err)
+	defer ra.Stop()
+	b, rb, err := makeRemoteEngineTls(getRandomWebSocketAddr("wss"), tlsTestConfig.peer2Config)
+	require.NoError(t, err)
+	defer rb.Stop()
+	wg := &sync.WaitGroup{}
+
+	wg.Add(msgs)
+	pid := a.SpawnFunc(func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case *TestMessage:
+			assert.Equal(t, msg.Data, []byte("foo"))
+			wg.Done()
+		}
+	}, "test")
+
+	for i := 0; i < msgs; i++ {
+		b.Send(pid, &TestMessage{Data: []byte("foo")})
+	}
+	wg.Wait()
+}
+
+func TestSecureWebSocketRequiresTLS(t *testing.T) {
+	_, _, err := makeRemoteEngine(getRandomWebSocketAddr("wss"))
+	assert.Error(t, err)
+}
+
+func getRandomWebSocketAddr(scheme string) string {
+	return fmt.Sprintf("%s://localhost:%d/hollywood", scheme, rand.Intn(50000)+10000)
+}
@@ -14,6 +14,8 @@ type RingBuffer[T any] struct {
 	len     int64
 	content *buffer[T]
 	mu      sync.Mutex
+	// overwrite drops the oldest items instead of growing, see NewOverwrite.
+	overwrite bool
 }
 
 func New[T any](size int64) *RingBuffer[T] {
@@ -28,9 +30,29 @@ func New[T any](size int64) *RingBuffer[T] {
 	}
 }
 
+// NewOverwrite returns a ring buffer in overwrite mode, which holds at most
+// size items: once it's full, Push drops the oldest item to make room for the
+// new one, and PushFront drops the newest one.
+func NewOverwrite[T any](size int64) *RingBuffer[T] {
+	// One slot stays empty to tell a full buffer from an empty one.
+	rb := New[T](size + 1)
+	rb.overwrite = true
+	return rb
+}
+
 func (rb *RingBuffer[T]) Push(item T) {
 	rb.mu.Lock()
 	rb.content.tail = (rb.content.tail + 1) % rb.content.mod
+	if rb.content.tail == rb.content.head && rb.overwrite {
+		// The slot after the head holds the oldest item, it becomes the
+		// empty slot.
+		rb.content.head = (rb.content.head + 1) % rb.content.mod
+		var t T
+		rb.content.items[rb.content.head] = t
+		rb.content.items[rb.content.tail] = item
+		rb.mu.Unlock()
+		return
+	}
 	if rb.content.tail == rb.content.head {
 		size := rb.content.mod * 2
 		newBuff := make([]T, size)
@@ -76,6 +98,14 @@ func (rb *RingBuffer[T]) Pop() (T, bool) {
 func (rb *RingBuffer[T]) PushFront(item T) {
 	rb.mu.Lock()
 
+	// Drop the newest item to make room in overwrite mode.
+	if rb.overwrite && rb.len >= rb.content.mod-1 {
+		var t T
+		rb.content.items[rb.content.tail] = t
+		rb.content.tail = (rb.content.tail - 1 + rb.content.mod) % rb.content.mod
+		atomic.AddInt64(&rb.len, -1)
+	}
+
 	// Check if buffer is full (need to grow before inserting)
 	if rb.len >= rb.content.mod-1 {
 		size := rb.content.mod * 2
@@ -128,6 +158,18 @@ func (rb *RingBuffer[T]) PopN(n int64) ([]T, bool) {
 	return items, true
 }
 
+// Items returns the items of the buffer, from the first one to be popped to
+// the last one, without removing them.
+func (rb *RingBuffer[T]) Items() []T {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	items := make([]T, rb.len)
+	for i := range items {
+		items[i] = rb.content.items[(rb.content.head+1+int64(i))%rb.content.mod]
+	}
+	return items
+}
+
 // Clear removes all items from the ring buffer.
 func (rb *RingBuffer[T]) Clear() {
 	rb.mu.Lock()
@@ -183,3 +183,29 @@ func TestPopThreadSafety(t *testing.T) {
 		}
 	})
 }
+
+func TestOverwrite(t *testing.T) {
+	rb := NewOverwrite[int](3)
+	for i := 0; i < 5; i++ {
+		rb.Push(i)
+	}
+	if rb.Len() != 3 {
+		t.Fatalf("expected len 3, got %d", rb.Len())
+	}
+	items := rb.Items()
+	if len(items) != 3 || items[0] != 2 || items[1] != 3 || items[2] != 4 {
+		t.Fatalf("expected the last 3 items, got %v", items)
+	}
+	if rb.Len() != 3 {
+		t.Fatal("expected Items to keep the items")
+	}
+
+	rb.PushFront(1) // Drops 4
+	items, _ = rb.PopN(3)
+	if len(items) != 3 || items[0] != 1 || items[1] != 2 || items[2] != 3 {
+		t.Fatalf("expected 1, 2 and 3, got %v", items)
+	}
+	if _, ok := rb.Pop(); ok {
+		t.Fatal("expected the buffer to be empty")
+	}
+}